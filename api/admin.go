@@ -1,6 +1,7 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -10,10 +11,12 @@ import (
 	"finance/adminauth"
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
 	"github.com/xuri/excelize/v2"
 	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
 )
 
 func setAdminCookie(c *gin.Context, name, value string, maxAge int, httpOnly bool) {
@@ -59,19 +62,25 @@ func getCurrentUser(c *gin.Context) (*models.User, error) {
 type AdminLoginRequest struct {
 	Username string `json:"username" binding:"required"` // 可为用户名或邮箱
 	Password string `json:"password" binding:"required"`
+	// 同一账号或 IP 连续失败达到阈值后必填，通过 GET /admin/captcha 获取
+	CaptchaID   string `json:"captcha_id"`
+	CaptchaCode string `json:"captcha_code"`
 }
 
 // AdminLogin 管理员登录（使用 session/cookie 方式）
 // @Summary 管理员登录
 // @Description 管理员使用用户名和密码登录，登录成功后设置 Cookie。只有状态为 active 的用户可以登录。
+// @Description 同一账号或 IP 连续失败达到阈值后需携带 captcha_id、captcha_code，否则返回 400 且 captcha_required 为 true。
+// @Description 已启用两步验证的账号密码正确时返回 2fa_required 为 true 且不设置登录 Cookie，需在 5 分钟内调用 POST /admin/2fa/verify。
 // @Tags 后台管理
 // @Accept json
 // @Produce json
 // @Param request body AdminLoginRequest true "登录信息"
 // @Success 200 {object} map[string]interface{} "登录成功，返回用户信息"
-// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 400 {object} map[string]interface{} "请求参数错误或验证码错误"
 // @Failure 401 {object} map[string]interface{} "用户名或密码错误"
 // @Failure 403 {object} map[string]interface{} "账号已锁定"
+// @Failure 429 {object} map[string]interface{} "失败次数过多，账号或 IP 已被临时锁定（响应头 Retry-After 为剩余秒数）"
 // @Router /admin/login [post]
 func (h *AdminHandler) AdminLogin(c *gin.Context) {
 	var req AdminLoginRequest
@@ -80,14 +89,36 @@ func (h *AdminHandler) AdminLogin(c *gin.Context) {
 		return
 	}
 
+	// 账号或 IP 连续失败被锁定时直接拒绝
+	ip := c.ClientIP()
+	if checkLoginLocked(c, req.Username, ip) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": loginLockedMessage})
+		return
+	}
+
+	// 连续失败达到阈值后先校验验证码，不通过时不查库、不校验密码
+	guard := adminLoginGuard()
+	if guard.CaptchaRequired(req.Username, ip) {
+		if req.CaptchaID == "" || req.CaptchaCode == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请输入验证码", "captcha_required": true})
+			return
+		}
+		if !service.VerifyCaptcha(req.CaptchaID, req.CaptchaCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "验证码错误或已过期", "captcha_required": true})
+			return
+		}
+	}
+
 	// 查找用户（支持用户名或邮箱）
 	var user models.User
 	if err := database.DB.Where("username = ? OR email = ?", req.Username, req.Username).First(&user).Error; err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户名或密码错误"})
+		required := guard.RecordFailure(req.Username, ip)
+		recordLoginFailure("后台", req.Username, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户名或密码错误", "captcha_required": required})
 		return
 	}
 
-	// 仅正常用户可登录
+	// 仅正常用户可登录；先于密码校验，被锁定账号无论密码对错都返回同样的提示，不暴露密码是否正确
 	if user.Status != models.UserStatusActive {
 		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "账号已锁定，请联系管理员解锁"})
 		return
@@ -95,11 +126,30 @@ func (h *AdminHandler) AdminLogin(c *gin.Context) {
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户名或密码错误"})
+		required := guard.RecordFailure(req.Username, ip)
+		recordLoginFailure("后台", req.Username, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "用户名或密码错误", "captcha_required": required})
+		return
+	}
+	guard.Reset(req.Username, ip)
+	loginLimiter().ResetUser(req.Username)
+
+	// 已启用两步验证时先不设置登录 Cookie，等待 POST /admin/2fa/verify 校验验证码
+	if user.TOTPEnabled {
+		setTwoFactorPending(c, user.ID)
+		c.JSON(http.StatusOK, gin.H{
+			"success":      true,
+			"message":      "请输入两步验证码",
+			"2fa_required": true,
+		})
 		return
 	}
 
-	// 设置 Cookie（admin_user_id、admin_is_admin 使用签名防篡改）
+	completeAdminLogin(c, &user)
+}
+
+// completeAdminLogin 设置登录 Cookie 并返回用户信息（admin_user_id、admin_is_admin 使用签名防篡改）
+func completeAdminLogin(c *gin.Context, user *models.User) {
 	setSignedAdminCookie(c, "admin_user_id", fmt.Sprintf("%d", user.ID), 86400, true)
 	setAdminCookie(c, "admin_username", user.Username, 86400, false)
 	setSignedAdminCookie(c, "admin_is_admin", fmt.Sprintf("%t", user.IsAdmin), 86400, false)
@@ -255,7 +305,7 @@ func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
 
 	var req ImpersonateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -287,6 +337,12 @@ func (h *AdminHandler) ImpersonateUser(c *gin.Context) {
 	setAdminCookie(c, "admin_username", targetUser.Username, 86400, false)
 	setSignedAdminCookie(c, "admin_is_admin", fmt.Sprintf("%t", targetUser.IsAdmin), 86400, false)
 
+	// 操作人为原始管理员，对象为被模拟用户
+	recordAuditLog(c, currentUser, models.AuditActionImpersonate, models.AuditTargetUser, targetUser.ID, models.JSONMap{
+		"admin_username":  currentUser.Username,
+		"target_username": targetUser.Username,
+	})
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": fmt.Sprintf("已模拟登录用户：%s", targetUser.Username),
@@ -343,16 +399,18 @@ func (h *AdminHandler) ExitImpersonation(c *gin.Context) {
 
 // GetAllExpenses 获取消费记录（管理员看全部，非管理员只看自己的）
 // @Summary 获取消费记录列表
-// @Description 获取消费记录列表，支持分页、时间范围、类别、用户名筛选。管理员可查看所有记录并可按用户ID筛选，非管理员只能查看自己的记录。
+// @Description 获取消费记录列表，支持分页、时间范围、类别、用户名筛选。管理员可查看所有记录并可按用户ID筛选，返回中附带 admin_note；非管理员只能查看自己的记录，不返回 admin_note。
 // @Tags 后台管理-消费记录
 // @Produce json
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页数量，默认20"
 // @Param start_time query string false "开始时间 (YYYY-MM-DD)"
 // @Param end_time query string false "结束时间 (YYYY-MM-DD)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
 // @Param category query string false "类别筛选"
 // @Param username query string false "用户名筛选（模糊匹配）"
 // @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Param q query string false "按描述搜索（忽略大小写，最多100字），每条记录附带 match_snippet"
 // @Success 200 {object} map[string]interface{} "获取成功，返回分页数据"
 // @Failure 401 {object} map[string]interface{} "未登录"
 // @Router /admin/expenses [get]
@@ -373,71 +431,145 @@ func (h *AdminHandler) GetAllExpenses(c *gin.Context) {
 		fmt.Sscanf(ps, "%d", &pageSize)
 	}
 
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
-	category := c.Query("category")
-	username := c.Query("username")
-	userIDFilter := c.Query("user_id") // 管理员可以按用户ID筛选
+	query, err := adminExpenseListQuery(c, database.DB, currentUser)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 
-	query := database.DB.Model(&models.Expense{}).
-		Select("expenses.*, users.username").
-		Joins("LEFT JOIN users ON expenses.user_id = users.id")
+	// 计算总数
+	var total int64
+	query.Count(&total)
 
-	// 权限过滤：非管理员只能看自己的数据
-	if !currentUser.IsAdmin {
-		query = query.Where("expenses.user_id = ?", currentUser.ID)
-	} else {
-		// 管理员可以按用户ID筛选
-		if userIDFilter != "" {
-			if uid, err := strconv.ParseUint(userIDFilter, 10, 32); err == nil {
-				query = query.Where("expenses.user_id = ?", uint(uid))
+	// 查询数据
+	var expenses []ExpenseWithUser
+	offset := (page - 1) * pageSize
+	query.Order("expenses.expense_time DESC").Offset(offset).Limit(pageSize).Scan(&expenses)
+
+	// 管理员备注只对管理员返回
+	var list interface{} = expenses
+	if currentUser.IsAdmin {
+		list = expensesWithAdminFields(expenses)
+	}
+	// 按描述搜索时附带命中片段
+	if q := strings.TrimSpace(c.Query("q")); q != "" {
+		records := make([]interface{}, len(expenses))
+		descriptions := make([]string, len(expenses))
+		for i, e := range expenses {
+			records[i], descriptions[i] = e, e.Description
+		}
+		if withFields, ok := list.([]withAdminFields); ok {
+			for i, w := range withFields {
+				records[i] = w
 			}
 		}
+		list = withMatchSnippets(records, descriptions, q)
 	}
 
-	// 筛选条件
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewPageResponse(total, page, pageSize, list),
+	})
+}
+
+// adminExpenseFilter 后台消费记录列表与 Excel 导出共用的筛选条件，保证导出结果与列表一致
+type adminExpenseFilter struct {
+	StartStr string // 开始日期（YYYY-MM-DD），为空表示不限
+	EndStr   string // 结束日期（YYYY-MM-DD，含当天），为空表示不限
+	Category string
+	Username string // 用户名模糊匹配
+	UserID   uint   // 按用户ID筛选，0 表示不筛选；仅管理员生效
+	Query    string // 描述搜索关键词
+}
+
+// parseAdminExpenseFilter 解析 period/start_time/end_time/category/username/user_id/q 查询参数，
+// period 按 now 计算；日期格式、起止顺序或用户ID非法时返回可直接展示的错误
+func parseAdminExpenseFilter(c *gin.Context, now time.Time) (*adminExpenseFilter, error) {
+	startTime, endTime, err := resolvePeriodQuery(c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		return nil, err
+	}
 	if startTime != "" {
-		if t, err := time.ParseInLocation("2006-01-02", startTime, time.Local); err == nil {
-			query = query.Where("expenses.expense_time >= ?", t)
+		if _, err := time.ParseInLocation("2006-01-02", startTime, time.Local); err != nil {
+			return nil, errors.New("开始时间格式错误，应为: 2006-01-02")
 		}
 	}
 	if endTime != "" {
-		if t, err := time.ParseInLocation("2006-01-02", endTime, time.Local); err == nil {
-			t = t.Add(24*time.Hour - time.Second)
-			query = query.Where("expenses.expense_time <= ?", t)
+		if _, err := time.ParseInLocation("2006-01-02", endTime, time.Local); err != nil {
+			return nil, errors.New("结束时间格式错误，应为: 2006-01-02")
 		}
 	}
-	if category != "" {
-		query = query.Where("expenses.category = ?", category)
+	if startTime != "" && endTime != "" && startTime > endTime {
+		return nil, errors.New("开始时间不能晚于结束时间")
 	}
-	if username != "" {
-		escaped := escapeLikeValue(username)
-		query = query.Where("users.username LIKE ?", "%"+escaped+"%")
+
+	q, err := normalizeDescriptionQuery(c.Query("q"))
+	if err != nil {
+		return nil, err
 	}
 
-	// 计算总数
-	var total int64
-	query.Count(&total)
+	filter := &adminExpenseFilter{
+		StartStr: startTime,
+		EndStr:   endTime,
+		Category: c.Query("category"),
+		Username: c.Query("username"),
+		Query:    q,
+	}
+	if userIDFilter := c.Query("user_id"); userIDFilter != "" {
+		uid, err := strconv.ParseUint(userIDFilter, 10, 32)
+		if err != nil {
+			return nil, errors.New("无效的用户ID")
+		}
+		filter.UserID = uint(uid)
+	}
+	return filter, nil
+}
 
-	// 查询数据
-	type ExpenseWithUser struct {
-		models.Expense
-		Username string `json:"username"`
+// query 构建关联用户名与录入人的消费记录查询，应用权限过滤（非管理员只看自己的）及各筛选条件，
+// 返回的查询尚未分页排序
+func (f *adminExpenseFilter) query(db *gorm.DB, currentUser *models.User) *gorm.DB {
+	query := db.Model(&models.Expense{}).
+		Select("expenses.*, users.username, creators.username AS created_by_name").
+		Joins("LEFT JOIN users ON expenses.user_id = users.id").
+		Joins("LEFT JOIN users creators ON expenses.created_by = creators.id")
+
+	// 权限过滤：非管理员只能看自己的数据，管理员可以按用户ID筛选
+	if !currentUser.IsAdmin {
+		query = query.Where("expenses.user_id = ?", currentUser.ID)
+	} else if f.UserID != 0 {
+		query = query.Where("expenses.user_id = ?", f.UserID)
 	}
 
-	var expenses []ExpenseWithUser
-	offset := (page - 1) * pageSize
-	query.Order("expenses.expense_time DESC").Offset(offset).Limit(pageSize).Scan(&expenses)
+	// 筛选条件
+	if f.StartStr != "" {
+		t, _ := time.ParseInLocation("2006-01-02", f.StartStr, time.Local)
+		query = query.Where("expenses.expense_time >= ?", t)
+	}
+	if f.EndStr != "" {
+		t, _ := time.ParseInLocation("2006-01-02", f.EndStr, time.Local)
+		query = query.Where("expenses.expense_time <= ?", t.Add(24*time.Hour-time.Second))
+	}
+	if f.Category != "" {
+		query = query.Where("expenses.category = ?", f.Category)
+	}
+	if f.Username != "" {
+		escaped := escapeLikeValue(f.Username)
+		query = query.Where("users.username LIKE ?"+likeEscape(query), "%"+escaped+"%")
+	}
+	return applyDescriptionSearch(query, "expenses.description", f.Query)
+}
 
-	c.JSON(http.StatusOK, gin.H{
-		"success": true,
-		"data": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-			"list":      expenses,
-		},
-	})
+// adminExpenseListQuery 后台消费记录列表的公共查询：按查询参数解析 adminExpenseFilter 并构建查询
+func adminExpenseListQuery(c *gin.Context, db *gorm.DB, currentUser *models.User) (*gorm.DB, error) {
+	now, err := requestNow(c)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := parseAdminExpenseFilter(c, now)
+	if err != nil {
+		return nil, err
+	}
+	return filter.query(db, currentUser), nil
 }
 
 // GetAllUsers 获取所有用户列表
@@ -445,6 +577,9 @@ func (h *AdminHandler) GetAllExpenses(c *gin.Context) {
 // @Description 获取系统中所有用户列表（包含软删除的用户）
 // @Tags 后台管理-用户管理
 // @Produce json
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
 // @Success 200 {object} map[string]interface{} "获取成功，返回用户列表"
 // @Failure 401 {object} map[string]interface{} "未登录"
 // @Router /admin/users [get]
@@ -462,8 +597,14 @@ func (h *AdminHandler) GetAllUsers(c *gin.Context) {
 		return
 	}
 
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
 	var users []models.User
-	database.DB.Find(&users)
+	created.Filter(database.DB, "created_at").Order(created.Order("created_at", "id ASC")).Find(&users)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -513,7 +654,7 @@ func (h *AdminHandler) UpdateUserPassword(c *gin.Context) {
 
 	var req UpdateUserPasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -535,6 +676,7 @@ func (h *AdminHandler) UpdateUserPassword(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
+	recordAuditLog(c, currentUser, models.AuditActionUpdateUserPassword, models.AuditTargetUser, user.ID, models.JSONMap{"username": user.Username})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -591,6 +733,7 @@ func (h *AdminHandler) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
 		return
 	}
+	recordAuditLog(c, currentUser, models.AuditActionDeleteUser, models.AuditTargetUser, user.ID, models.JSONMap{"username": user.Username})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -646,7 +789,7 @@ func (h *AdminHandler) SetAdmin(c *gin.Context) {
 
 	var req SetAdminRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -662,11 +805,17 @@ func (h *AdminHandler) SetAdmin(c *gin.Context) {
 		return
 	}
 
+	wasAdmin := user.IsAdmin
 	user.IsAdmin = req.IsAdmin
 	if err := database.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
+	recordAuditLog(c, currentUser, models.AuditActionSetAdmin, models.AuditTargetUser, user.ID, models.JSONMap{
+		"username": user.Username,
+		"from":     wasAdmin,
+		"to":       req.IsAdmin,
+	})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -718,7 +867,7 @@ func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
 
 	var req UpdateUserStatusRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -734,11 +883,33 @@ func (h *AdminHandler) UpdateUserStatus(c *gin.Context) {
 		return
 	}
 
-	user.Status = status
-	if err := database.DB.Save(&user).Error; err != nil {
+	oldStatus := user.Status
+	// 锁定时自增 token_version 并吊销全部登录会话，使其已签发的 token 立即失效
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{"status": status}
+		if status == models.UserStatusLocked && user.Status != models.UserStatusLocked {
+			updates["token_version"] = gorm.Expr("token_version + 1")
+		}
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return err
+		}
+		if status == models.UserStatusLocked {
+			return tx.Model(&models.Session{}).
+				Where("user_id = ? AND revoked_at IS NULL", user.ID).
+				Update("revoked_at", time.Now()).Error
+		}
+		return nil
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
+	recordAuditLog(c, currentUser, models.AuditActionUpdateUserStatus, models.AuditTargetUser, user.ID, models.JSONMap{
+		"username": user.Username,
+		"from":     oldStatus,
+		"to":       status,
+	})
+	database.DB.First(&user, user.ID)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -850,7 +1021,7 @@ func (h *AdminHandler) UpdateUserRole(c *gin.Context) {
 
 	var req UpdateUserRoleRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -967,7 +1138,8 @@ func (h *AdminHandler) UpdateUserEmail(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Model(&user).Update("email", email).Error; err != nil {
+	// 绑定时已校验验证码；解绑时清除验证状态
+	if err := database.DB.Model(&user).Updates(map[string]interface{}{"email": email, "email_verified": email != ""}).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "更新失败"})
 		return
 	}
@@ -993,10 +1165,15 @@ func (h *AdminHandler) UpdateUserEmail(c *gin.Context) {
 // GetStatistics 获取统计数据
 // @Summary 获取统计数据
 // @Description 获取支出和收入的统计数据，包括总金额、总记录数、类别统计等。管理员可查看所有数据，非管理员只能查看自己的数据。
+// @Description 金额按汇率折算为本位币后合计，currency_totals / income_currency_totals 给出支出、收入各币种原币合计，缺少汇率的币种不计入合计并列在 unconverted_currencies 中。
+// @Description 不传 period/start_time/end_time 时默认统计本月（按 tz 计算），响应中的 start_time/end_time 回显实际使用的范围。
 // @Tags 后台管理-统计
 // @Produce json
 // @Param start_time query string false "开始时间 (YYYY-MM-DD)"
 // @Param end_time query string false "结束时间 (YYYY-MM-DD)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
 // @Success 200 {object} map[string]interface{} "获取成功"
 // @Failure 401 {object} map[string]interface{} "未登录"
 // @Router /admin/statistics [get]
@@ -1008,8 +1185,17 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 		return
 	}
 
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	startTime, endTime, err := resolvePeriodQuery(c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	startTime, endTime = defaultToThisMonth(startTime, endTime, now)
 
 	query := database.DB.Model(&models.Expense{})
 	incomeQuery := database.DB.Model(&models.Income{})
@@ -1019,6 +1205,11 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 		query = query.Where("user_id = ?", currentUser.ID)
 		incomeQuery = incomeQuery.Where("user_id = ?", currentUser.ID)
 	}
+	// 默认排除内部转账类别
+	withTransfer := includeTransfer(c)
+	if !withTransfer {
+		query = excludeTransferCategories(query, "category")
+	}
 
 	if startTime != "" {
 		if t, err := time.ParseInLocation("2006-01-02", startTime, time.Local); err == nil {
@@ -1034,46 +1225,24 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 		}
 	}
 
-	// 总金额和总记录数
-	var totalAmount float64
-	var totalCount int64
-	query.Select("COALESCE(SUM(amount), 0)").Scan(&totalAmount)
-	query.Count(&totalCount)
-
-	// 收入总金额和总记录数
-	var totalIncome float64
-	var incomeCount int64
-	incomeQuery.Select("COALESCE(SUM(amount), 0)").Scan(&totalIncome)
-	incomeQuery.Count(&incomeCount)
-
-	// 按类别统计（使用已过滤的query）
-	type CategoryStat struct {
-		Category string  `json:"category"`
-		Total    float64 `json:"total"`
-		Count    int64   `json:"count"`
-	}
-	var categoryStats []CategoryStat
-	// 重新构建查询以应用相同的过滤条件
-	categoryQuery := database.DB.Model(&models.Expense{})
-	if !currentUser.IsAdmin {
-		categoryQuery = categoryQuery.Where("user_id = ?", currentUser.ID)
+	// 支出按类别、收入按类型与币种汇总，折算为本位币
+	rates, err := loadExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
 	}
-	if startTime != "" {
-		if t, err := time.ParseInLocation("2006-01-02", startTime, time.Local); err == nil {
-			categoryQuery = categoryQuery.Where("expense_time >= ?", t)
-		}
+	expenseRows, err := sumByCategoryAndCurrency(query, "category")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
 	}
-	if endTime != "" {
-		if t, err := time.ParseInLocation("2006-01-02", endTime, time.Local); err == nil {
-			t = t.Add(24*time.Hour - time.Second)
-			categoryQuery = categoryQuery.Where("expense_time <= ?", t)
-		}
+	incomeRows, err := sumByCategoryAndCurrency(incomeQuery, "type")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
 	}
-	categoryQuery.
-		Select("category, SUM(amount) as total, COUNT(*) as count").
-		Group("category").
-		Order("total DESC").
-		Scan(&categoryStats)
+	expenseSummary := convertCurrencyStats(expenseRows, rates)
+	incomeSummary := convertCurrencyStats(incomeRows, rates)
 
 	// 用户数量（仅管理员可见）
 	var userCount int64
@@ -1084,12 +1253,18 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"total_amount":   totalAmount,
-			"total_count":    totalCount,
-			"total_income":   totalIncome,
-			"income_count":   incomeCount,
-			"user_count":     userCount,
-			"category_stats": categoryStats,
+			"start_time":             startTime,
+			"end_time":               endTime,
+			"base_currency":          baseCurrency(),
+			"total_amount":           expenseSummary.Total,
+			"total_count":            expenseSummary.Count,
+			"total_income":           incomeSummary.Total,
+			"income_count":           incomeSummary.Count,
+			"user_count":             userCount,
+			"category_stats":         expenseSummary.Categories,
+			"currency_totals":        expenseSummary.Currencies,
+			"income_currency_totals": incomeSummary.Currencies,
+			"unconverted_currencies": mergeCurrencyCodes(expenseSummary.Unconverted, incomeSummary.Unconverted),
 		},
 	})
 }
@@ -1097,15 +1272,18 @@ func (h *AdminHandler) GetStatistics(c *gin.Context) {
 // GetDetailedStatistics 获取详细消费统计（支持月/年/自定义时间范围和多个类别筛选）
 // @Summary 获取详细消费统计
 // @Description 获取详细的消费统计数据，支持按月、按年或自定义时间范围统计，支持多个类别筛选。管理员可按用户ID筛选，非管理员只能查看自己的数据。
+// @Description 金额按汇率折算为本位币后合计，currency_totals 给出各币种原币合计，缺少汇率的币种不计入合计并列在 unconverted_currencies 中。
 // @Tags 后台管理-统计
 // @Produce json
-// @Param range_type query string true "时间范围类型：month(按月)、year(按年)、custom(自定义)"
-// @Param year_month query string false "当range_type=month时必填，格式：2024-01"
-// @Param year query string false "当range_type=year时必填，格式：2024"
-// @Param start_time query string false "当range_type=custom时必填，格式：2024-01-01"
-// @Param end_time query string false "当range_type=custom时必填，格式：2024-12-31"
+// @Param range_type query string false "时间范围类型：month(按月，默认)、year(按年)、custom(自定义)"
+// @Param year_month query string false "range_type=month时使用，格式：2024-01，默认本月"
+// @Param year query string false "range_type=year时使用，格式：2024，默认今年"
+// @Param start_time query string false "range_type=custom时使用，格式：2024-01-01；与end_time都不传时默认本月1日至今天"
+// @Param end_time query string false "range_type=custom时使用，格式：2024-12-31"
+// @Param tz query string false "IANA 时区，默认服务器时区"
 // @Param categories query string false "类别筛选，多个类别用逗号分隔，如：餐饮,交通"
 // @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
 // @Success 200 {object} map[string]interface{} "获取成功，包含总金额、总记录数、类别统计等"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 401 {object} map[string]interface{} "未登录"
@@ -1118,9 +1296,15 @@ func (h *AdminHandler) GetDetailedStatistics(c *gin.Context) {
 		return
 	}
 
-	rangeType := c.Query("range_type")
-	if rangeType == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "range_type参数必填，可选值：month、year、custom"})
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	// 不传 range_type 时按月统计
+	r, err := parseStatisticsRange(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
@@ -1138,142 +1322,40 @@ func (h *AdminHandler) GetDetailedStatistics(c *gin.Context) {
 		}
 	}
 
-	var startTime, endTime time.Time
-
-	// 根据时间范围类型设置时间范围
-	switch rangeType {
-	case "month":
-		yearMonth := c.Query("year_month")
-		if yearMonth == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "range_type=month时，year_month参数必填（格式：2024-01）"})
-			return
-		}
-		startTime, err = time.ParseInLocation("2006-01", yearMonth, time.Local)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "year_month格式错误，应为：2024-01"})
-			return
-		}
-		startTime = time.Date(startTime.Year(), startTime.Month(), 1, 0, 0, 0, 0, time.Local)
-		endTime = startTime.AddDate(0, 1, 0).Add(-time.Second)
-
-	case "year":
-		yearStr := c.Query("year")
-		if yearStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "range_type=year时，year参数必填（格式：2024）"})
-			return
-		}
-		year, err := strconv.Atoi(yearStr)
-		if err != nil || year < 2000 || year > 2100 {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "year格式错误，应为4位数字（如：2024）"})
-			return
-		}
-		startTime = time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
-		endTime = time.Date(year, 12, 31, 23, 59, 59, 0, time.Local)
-
-	case "custom":
-		startTimeStr := c.Query("start_time")
-		endTimeStr := c.Query("end_time")
-		if startTimeStr == "" || endTimeStr == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "range_type=custom时，start_time和end_time参数必填（格式：2024-01-01）"})
-			return
-		}
-		startTime, err = time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "start_time格式错误，应为：2024-01-01"})
-			return
-		}
-		endTime, err = time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "end_time格式错误，应为：2024-12-31"})
-			return
-		}
-		endTime = endTime.Add(24*time.Hour - time.Second)
-
-	default:
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "range_type参数值错误，可选值：month、year、custom"})
-		return
-	}
-
 	// 应用时间范围筛选
-	query = query.Where("expense_time >= ? AND expense_time <= ?", startTime, endTime)
+	query = query.Where("expense_time >= ? AND expense_time <= ?", r.Start, r.End)
 
-	// 类别筛选（支持多个类别）
-	categoriesStr := c.Query("categories")
-	if categoriesStr != "" {
-		categories := strings.Split(categoriesStr, ",")
-		for i := range categories {
-			categories[i] = strings.TrimSpace(categories[i])
-		}
-		if len(categories) > 0 {
-			query = query.Where("category IN ?", categories)
-		}
+	// 默认排除内部转账类别
+	withTransfer := includeTransfer(c)
+	if !withTransfer {
+		query = excludeTransferCategories(query, "category")
 	}
 
-	// 总金额和总记录数
-	var totalAmount float64
-	var totalCount int64
-	// 先获取总数（需要在Select之前）
-	query.Count(&totalCount)
-	// 再获取总金额
-	query.Select("COALESCE(SUM(amount), 0)").Scan(&totalAmount)
-
-	// 按类别统计
-	type CategoryStat struct {
-		Category   string  `json:"category"`
-		Total      float64 `json:"total"`
-		Count      int64   `json:"count"`
-		Percentage float64 `json:"percentage"`
-	}
-	var categoryStats []CategoryStat
-
-	// 构建类别统计查询
-	categoryQuery := database.DB.Model(&models.Expense{}).
-		Select("category, SUM(amount) as total, COUNT(*) as count").
-		Where("expense_time >= ? AND expense_time <= ?", startTime, endTime)
-
-	// 权限过滤：非管理员只能看自己的数据
-	if !currentUser.IsAdmin {
-		categoryQuery = categoryQuery.Where("user_id = ?", currentUser.ID)
-	} else {
-		// 管理员可以按用户ID筛选
-		if userIDFilter := c.Query("user_id"); userIDFilter != "" {
-			if uid, err := strconv.ParseUint(userIDFilter, 10, 32); err == nil {
-				categoryQuery = categoryQuery.Where("user_id = ?", uint(uid))
-			}
-		}
-	}
-
-	// 应用类别筛选
-	if categoriesStr != "" {
-		categories := strings.Split(categoriesStr, ",")
-		for i := range categories {
-			categories[i] = strings.TrimSpace(categories[i])
-		}
-		if len(categories) > 0 {
-			categoryQuery = categoryQuery.Where("category IN ?", categories)
-		}
+	// 类别筛选（支持多个类别）
+	if categories := splitQueryList(c.Query("categories")); len(categories) > 0 {
+		query = query.Where("category IN ?", categories)
 	}
 
-	categoryQuery.Group("category").Order("total DESC").Scan(&categoryStats)
-
-	// 计算每个类别的占比
-	for i := range categoryStats {
-		if totalAmount > 0 {
-			categoryStats[i].Percentage = (categoryStats[i].Total / totalAmount) * 100
-		} else {
-			categoryStats[i].Percentage = 0
-		}
+	// 按类别与币种汇总，折算为本位币后计算总金额、总记录数与各类别占比
+	summary, err := queryCurrencySummary(query, "category")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
 	}
+	categoryStats := withCategoryPercentage(summary.Categories, summary.Total)
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"range_type":     rangeType,
-			"start_time":     startTime.Format("2006-01-02 15:04:05"),
-			"end_time":       endTime.Format("2006-01-02 15:04:05"),
-			"total_amount":   totalAmount,
-			"total_count":    totalCount,
-			"category_stats": categoryStats,
+			"range_type":             r.Type,
+			"start_time":             models.FormatTime(r.Start),
+			"end_time":               models.FormatTime(r.End),
+			"base_currency":          baseCurrency(),
+			"total_amount":           summary.Total,
+			"total_count":            summary.Count,
+			"category_stats":         categoryStats,
+			"currency_totals":        summary.Currencies,
+			"unconverted_currencies": summary.Unconverted,
 		},
 	})
 }
@@ -1282,6 +1364,7 @@ func (h *AdminHandler) GetDetailedStatistics(c *gin.Context) {
 type AdminCreateExpenseRequest struct {
 	UserID      uint    `json:"user_id" binding:"required"`
 	Amount      float64 `json:"amount" binding:"required,gt=0"`
+	Currency    string  `json:"currency"` // 币种（ISO 4217），不传时为本位币，需在 currency.allowed 中
 	Category    string  `json:"category" binding:"required"`
 	Description string  `json:"description"`
 	ExpenseTime string  `json:"expense_time" binding:"required"` // 格式: 2006-01-02 15:04:05
@@ -1309,8 +1392,21 @@ func (h *AdminHandler) CreateExpense(c *gin.Context) {
 	}
 
 	var req AdminCreateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if err := validateAmount(req.Amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := validateDescription(req.Description); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	currency, err := resolveCurrency(req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
@@ -1333,9 +1429,12 @@ func (h *AdminHandler) CreateExpense(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "时间格式错误，应为: 2006-01-02 15:04:05"})
 		return
 	}
+	if err := validateRecordTime("消费时间", expenseTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 
 	// 校验类别是否存在（来源于数据库）
-	req.Category = strings.TrimSpace(req.Category)
 	if req.Category == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "类别不能为空"})
 		return
@@ -1348,11 +1447,14 @@ func (h *AdminHandler) CreateExpense(c *gin.Context) {
 
 	// 创建消费记录
 	expense := models.Expense{
-		UserID:      req.UserID,
-		Amount:      req.Amount,
-		Category:    req.Category,
-		Description: req.Description,
-		ExpenseTime: expenseTime,
+		UserID:       req.UserID,
+		Amount:       req.Amount,
+		Currency:     currency,
+		Category:     req.Category,
+		Description:  req.Description,
+		ExpenseTime:  expenseTime,
+		CreatedBy:    currentUser.ID,
+		RecordSource: recordSourceFrom(c),
 	}
 
 	if err := database.DB.Create(&expense).Error; err != nil {
@@ -1373,11 +1475,13 @@ type AdminUpdateExpenseRequest struct {
 	Category    string  `json:"category"`
 	Description string  `json:"description"`
 	ExpenseTime string  `json:"expense_time"` // 格式: 2006-01-02 15:04:05
+	// AdminNote 管理员内部备注（仅管理员可写，最多 500 字符），传空字符串表示清除；不传则不修改
+	AdminNote *string `json:"admin_note"`
 }
 
 // UpdateExpense 更新消费记录
 // @Summary 更新消费记录
-// @Description 更新指定的消费记录。管理员可以更新任何记录，非管理员只能更新自己的记录。
+// @Description 更新指定的消费记录。管理员可以更新任何记录并填写内部备注 admin_note（不改动用户的 description），非管理员只能更新自己的记录且不能填写 admin_note。
 // @Tags 后台管理-消费记录
 // @Accept json
 // @Produce json
@@ -1417,22 +1521,21 @@ func (h *AdminHandler) UpdateExpense(c *gin.Context) {
 	}
 
 	var req AdminUpdateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 
 	// 更新字段
 	updates := make(map[string]interface{})
 	if req.Amount > 0 {
+		if err := validateAmount(req.Amount); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		updates["amount"] = req.Amount
 	}
 	if req.Category != "" {
-		req.Category = strings.TrimSpace(req.Category)
-		if req.Category == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "类别不能为空"})
-			return
-		}
 		var cat models.ExpenseCategory
 		if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的消费类别，请先在“消费类别”中维护"})
@@ -1441,6 +1544,10 @@ func (h *AdminHandler) UpdateExpense(c *gin.Context) {
 		updates["category"] = req.Category
 	}
 	if req.Description != "" {
+		if err := validateDescription(req.Description); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		updates["description"] = req.Description
 	}
 	if req.ExpenseTime != "" {
@@ -1449,8 +1556,23 @@ func (h *AdminHandler) UpdateExpense(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "时间格式错误，应为: 2006-01-02 15:04:05"})
 			return
 		}
+		if err := validateRecordTime("消费时间", expenseTime); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		updates["expense_time"] = expenseTime
 	}
+	if req.AdminNote != nil {
+		if !currentUser.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，仅管理员可填写备注"})
+			return
+		}
+		if err := validateAdminNote(*req.AdminNote); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["admin_note"] = *req.AdminNote
+	}
 
 	if err := database.DB.Model(&expense).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
@@ -1460,10 +1582,15 @@ func (h *AdminHandler) UpdateExpense(c *gin.Context) {
 	// 重新获取更新后的记录
 	database.DB.First(&expense, expense.ID)
 
+	var data interface{} = expense
+	if currentUser.IsAdmin {
+		data = withAdminFields{record: expense, adminNote: expense.AdminNote, source: expense.RecordSource}
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "更新成功",
-		"data":    expense,
+		"data":    data,
 	})
 }
 
@@ -1505,7 +1632,7 @@ func (h *AdminHandler) DeleteExpense(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&expense).Error; err != nil {
+	if err := models.SoftDeleteExpenses(database.DB.Model(&expense), currentUser.ID).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
 		return
 	}
@@ -1520,14 +1647,26 @@ func (h *AdminHandler) DeleteExpense(c *gin.Context) {
 
 // ExportExcel 导出 Excel
 // @Summary 导出消费记录为Excel
-// @Description 根据时间范围导出消费记录为Excel文件。管理员可导出所有用户数据，普通用户只能导出自己的数据。
+// @Description 按与消费记录列表（GET /admin/expenses）完全相同的筛选参数导出当前筛选结果为Excel文件。管理员可导出所有用户数据，普通用户只能导出自己的数据。
+// @Description 不传任何时间条件（start_time/end_time/period）时导出本月（按 tz 计算），实际范围由响应头 X-Range-Start/X-Range-End 回显，未限制的一端为空。
 // @Tags 后台管理-导出
 // @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
-// @Param start_time query string true "开始时间 (YYYY-MM-DD)"
-// @Param end_time query string true "结束时间 (YYYY-MM-DD)"
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，可只传一端；时间条件都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)，可只传一端；时间条件都不传时默认今天"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param category query string false "类别筛选"
+// @Param username query string false "用户名筛选（模糊匹配）"
+// @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param formatted query bool false "金额列使用货币数字格式（仍为数值，可计算），默认裸数字"
+// @Param currency query string false "默认货币代码（formatted=true 时生效，每条记录按自身币种显示，币种不在列表中时用该值）：CNY/USD/EUR/GBP/HKD/JPY，默认 CNY"
+// @Param locale query string false "区域（formatted=true 时生效）：zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR，默认 zh-CN"
+// @Param include_deleted query bool false "是否包含已软删除的记录（仅管理员，用于审计），包含时行尾追加删除时间、删除者两列；默认不包含"
 // @Success 200 {file} file "Excel文件"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "非管理员请求包含已删除记录"
 // @Router /admin/export/excel [get]
 func (h *AdminHandler) ExportExcel(c *gin.Context) {
 	// 获取当前登录用户
@@ -1537,139 +1676,68 @@ func (h *AdminHandler) ExportExcel(c *gin.Context) {
 		return
 	}
 
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
-
-	if startTime == "" || endTime == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请提供开始时间和结束时间"})
+	// 筛选条件与消费记录列表一致（非管理员只导出自己的数据）；未指定任何时间条件时默认导出本月
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
-
-	start, err := time.ParseInLocation("2006-01-02", startTime, time.Local)
+	filter, err := parseAdminExpenseFilter(c, now)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "开始时间格式错误"})
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
+	filter.StartStr, filter.EndStr = defaultToThisMonth(filter.StartStr, filter.EndStr, now)
 
-	end, err := time.ParseInLocation("2006-01-02", endTime, time.Local)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "结束时间格式错误"})
+	includeDeleted := c.Query("include_deleted") == "true"
+	if includeDeleted && !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "仅管理员可导出已删除的记录"})
 		return
 	}
-	end = end.Add(24*time.Hour - time.Second)
 
-	// 查询数据
-	type ExpenseWithUser struct {
-		models.Expense
-		Username string
+	money, err := resolveMoneyFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
 	}
 
+	// 查询数据；默认排除软删除记录，审计导出时用 Unscoped 一并查出
+	db := database.DB
+	if includeDeleted {
+		db = db.Unscoped()
+	}
 	var expenses []ExpenseWithUser
-	query := database.DB.Model(&models.Expense{}).
-		Select("expenses.*, users.username").
-		Joins("LEFT JOIN users ON expenses.user_id = users.id").
-		Where("expenses.expense_time >= ? AND expenses.expense_time <= ?", start, end)
-
-	// 如果不是管理员，只导出当前用户的数据
-	if !currentUser.IsAdmin {
-		query = query.Where("expenses.user_id = ?", currentUser.ID)
+	query := filter.query(db, currentUser)
+	if !includeTransfer(c) {
+		query = excludeTransferCategories(query, "expenses.category")
 	}
 
 	query.Order("expenses.expense_time DESC").Scan(&expenses)
 
+	rates, err := loadExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询汇率失败")})
+		return
+	}
+
+	var deleterNames map[uint]string
+	if includeDeleted {
+		deleterNames = loadDeleterNames(expenses)
+	}
+
 	// 创建 Excel 文件
 	f := excelize.NewFile()
 	defer f.Close()
 
-	sheetName := "消费记录"
-	f.SetSheetName("Sheet1", sheetName)
-
-	// 设置表头样式
-	headerStyle, _ := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true, Size: 12, Color: "FFFFFF"},
-		Fill:      excelize.Fill{Type: "pattern", Color: []string{"4F81BD"}, Pattern: 1},
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "000000", Style: 1},
-			{Type: "top", Color: "000000", Style: 1},
-			{Type: "bottom", Color: "000000", Style: 1},
-			{Type: "right", Color: "000000", Style: 1},
-		},
-	})
-
-	// 数据样式
-	dataStyle, _ := f.NewStyle(&excelize.Style{
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "000000", Style: 1},
-			{Type: "top", Color: "000000", Style: 1},
-			{Type: "bottom", Color: "000000", Style: 1},
-			{Type: "right", Color: "000000", Style: 1},
-		},
-	})
+	f.SetSheetName("Sheet1", expenseSheetName)
 
-	// 设置列宽
-	f.SetColWidth(sheetName, "A", "A", 10)
-	f.SetColWidth(sheetName, "B", "B", 15)
-	f.SetColWidth(sheetName, "C", "C", 12)
-	f.SetColWidth(sheetName, "D", "D", 12)
-	f.SetColWidth(sheetName, "E", "E", 30)
-	f.SetColWidth(sheetName, "F", "F", 20)
-	f.SetColWidth(sheetName, "G", "G", 20)
-
-	// 写入表头
-	headers := []string{"ID", "用户名", "金额", "类别", "描述", "消费时间", "创建时间"}
-	for i, header := range headers {
-		cell := fmt.Sprintf("%c1", 'A'+i)
-		f.SetCellValue(sheetName, cell, header)
-		f.SetCellStyle(sheetName, cell, cell, headerStyle)
-	}
-
-	// 写入数据
-	var totalAmount float64
-	for i, expense := range expenses {
-		row := i + 2
-		f.SetCellValue(sheetName, fmt.Sprintf("A%d", row), expense.ID)
-		f.SetCellValue(sheetName, fmt.Sprintf("B%d", row), expense.Username)
-		f.SetCellValue(sheetName, fmt.Sprintf("C%d", row), expense.Amount)
-		f.SetCellValue(sheetName, fmt.Sprintf("D%d", row), expense.Category)
-		f.SetCellValue(sheetName, fmt.Sprintf("E%d", row), expense.Description)
-		f.SetCellValue(sheetName, fmt.Sprintf("F%d", row), expense.ExpenseTime.Format("2006-01-02 15:04:05"))
-		f.SetCellValue(sheetName, fmt.Sprintf("G%d", row), expense.CreatedAt.Format("2006-01-02 15:04:05"))
-
-		// 设置数据样式
-		f.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("G%d", row), dataStyle)
-		totalAmount += expense.Amount
-	}
-
-	// 添加汇总行
-	summaryRow := len(expenses) + 2
-	summaryStyle, _ := f.NewStyle(&excelize.Style{
-		Font:      &excelize.Font{Bold: true, Size: 11},
-		Fill:      excelize.Fill{Type: "pattern", Color: []string{"FFC000"}, Pattern: 1},
-		Alignment: &excelize.Alignment{Horizontal: "center", Vertical: "center"},
-		Border: []excelize.Border{
-			{Type: "left", Color: "000000", Style: 1},
-			{Type: "top", Color: "000000", Style: 1},
-			{Type: "bottom", Color: "000000", Style: 1},
-			{Type: "right", Color: "000000", Style: 1},
-		},
-	})
-
-	f.SetCellValue(sheetName, fmt.Sprintf("A%d", summaryRow), "合计")
-	f.MergeCell(sheetName, fmt.Sprintf("A%d", summaryRow), fmt.Sprintf("B%d", summaryRow))
-	f.SetCellValue(sheetName, fmt.Sprintf("C%d", summaryRow), totalAmount)
-	f.SetCellValue(sheetName, fmt.Sprintf("D%d", summaryRow), fmt.Sprintf("共 %d 条记录", len(expenses)))
-	f.MergeCell(sheetName, fmt.Sprintf("D%d", summaryRow), fmt.Sprintf("G%d", summaryRow))
-	f.SetCellStyle(sheetName, fmt.Sprintf("A%d", summaryRow), fmt.Sprintf("G%d", summaryRow), summaryStyle)
+	// 金额列样式：formatted 时按每条记录的币种使用货币数字格式，单元格仍为数值
+	writeExpenseSheet(f, expenseSheetName, expenses, money, rates, includeDeleted, deleterNames)
 
 	// 设置响应头
-	filename := fmt.Sprintf("消费记录_%s_%s.xlsx", startTime, endTime)
-	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
-	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", filename))
-
-	// 写入响应
-	if err := f.Write(c.Writer); err != nil {
+	filename := fmt.Sprintf("消费记录_%s_%s.xlsx", rangeLabel(filter.StartStr, "最早"), rangeLabel(filter.EndStr, "至今"))
+	setRangeHeaders(c, filter.StartStr, filter.EndStr)
+	if err := writeExcelResponse(c, f, filename); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成 Excel 失败"})
 		return
 	}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/adminauth"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// twoFactorPendingCookie 密码校验通过、等待两步验证时的签名 Cookie，值为 "用户ID:过期时间戳"
+const twoFactorPendingCookie = "admin_2fa_pending"
+
+// twoFactorPendingTTL 密码校验通过后输入两步验证码的时限
+const twoFactorPendingTTL = 5 * time.Minute
+
+// TwoFactorCodeRequest 两步验证码请求
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"` // 验证器显示的 6 位验证码
+}
+
+// setTwoFactorPending 记录密码已校验通过的用户，等待 POST /admin/2fa/verify
+func setTwoFactorPending(c *gin.Context, userID uint) {
+	exp := time.Now().Add(twoFactorPendingTTL).Unix()
+	setSignedAdminCookie(c, twoFactorPendingCookie, fmt.Sprintf("%d:%d", userID, exp), int(twoFactorPendingTTL/time.Second), true)
+}
+
+// twoFactorPendingUserID 校验签名与有效期，返回等待两步验证的用户 ID
+func twoFactorPendingUserID(c *gin.Context) (uint, bool) {
+	raw, err := c.Cookie(twoFactorPendingCookie)
+	if err != nil {
+		return 0, false
+	}
+	v, err := adminauth.VerifyCookieValue(raw)
+	if err != nil {
+		return 0, false
+	}
+	idStr, expStr, ok := strings.Cut(v, ":")
+	if !ok {
+		return 0, false
+	}
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		return 0, false
+	}
+	exp, err := strconv.ParseInt(expStr, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// checkTOTP 用用户已保存的密钥校验验证码，通过时返回命中的周期序号
+func checkTOTP(user *models.User, code string) (int64, bool, error) {
+	secret, err := service.DecryptSecret(user.TOTPSecret)
+	if err != nil {
+		return 0, false, err
+	}
+	step, ok := service.VerifyTOTP(secret, code, time.Now(), user.TOTPLastStep)
+	return step, ok, nil
+}
+
+// SetupTwoFactor 生成两步验证密钥
+// @Summary 生成两步验证密钥
+// @Description 为当前登录账号生成 TOTP 密钥（加密保存），返回密钥与 otpauth:// 地址供验证器扫码；
+// @Description 需再调用 POST /admin/2fa/enable 校验一次验证码后才会启用。已启用时需先关闭
+// @Tags 后台管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "生成成功，data 含 secret、otpauth_uri"
+// @Failure 400 {object} map[string]interface{} "已启用两步验证"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/2fa/setup [post]
+func (h *AdminHandler) SetupTwoFactor(c *gin.Context) {
+	user, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "已启用两步验证，如需更换请先关闭"})
+		return
+	}
+
+	secret, err := service.GenerateTOTPSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成密钥失败"})
+		return
+	}
+	encrypted, err := service.EncryptSecret(secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成密钥失败"})
+		return
+	}
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_secret": encrypted, "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "保存失败")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "请用验证器扫码后输入验证码完成启用",
+		"data": gin.H{
+			"secret":      secret,
+			"otpauth_uri": service.TOTPURI(user.Username, secret),
+		},
+	})
+}
+
+// EnableTwoFactor 校验验证码并启用两步验证
+// @Summary 启用两步验证
+// @Description 用 POST /admin/2fa/setup 生成的密钥校验一次验证码，通过后启用；之后登录需额外输入验证码
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "启用成功"
+// @Failure 400 {object} map[string]interface{} "未生成密钥、已启用或验证码错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/2fa/enable [post]
+func (h *AdminHandler) EnableTwoFactor(c *gin.Context) {
+	user, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "已启用两步验证"})
+		return
+	}
+	if user.TOTPSecret == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请先生成两步验证密钥"})
+		return
+	}
+
+	step, ok, err := checkTOTP(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "校验失败")})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "验证码错误"})
+		return
+	}
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_enabled": true, "totp_last_step": step}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "启用失败")})
+		return
+	}
+	recordAuditLog(c, user, models.AuditActionEnable2FA, models.AuditTargetUser, user.ID, models.JSONMap{"username": user.Username})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "两步验证已启用"})
+}
+
+// DisableTwoFactor 关闭两步验证
+// @Summary 关闭两步验证
+// @Description 校验当前验证码后关闭两步验证并清除密钥
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "关闭成功"
+// @Failure 400 {object} map[string]interface{} "未启用或验证码错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/2fa/disable [post]
+func (h *AdminHandler) DisableTwoFactor(c *gin.Context) {
+	user, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if !user.TOTPEnabled {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "未启用两步验证"})
+		return
+	}
+
+	_, ok, err := checkTOTP(user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "校验失败")})
+		return
+	}
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "验证码错误"})
+		return
+	}
+	if err := database.DB.Model(user).Updates(map[string]interface{}{"totp_enabled": false, "totp_secret": "", "totp_last_step": 0}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "关闭失败")})
+		return
+	}
+	recordAuditLog(c, user, models.AuditActionDisable2FA, models.AuditTargetUser, user.ID, models.JSONMap{"username": user.Username})
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "两步验证已关闭"})
+}
+
+// VerifyTwoFactor 登录第二步：校验两步验证码
+// @Summary 两步验证登录
+// @Description 管理员登录返回 2fa_required 后，在 5 分钟内提交验证器中的验证码，通过后设置登录 Cookie。
+// @Description 验证码错误计入登录失败次数，连续失败达到阈值后账号与 IP 被临时锁定
+// @Tags 后台管理
+// @Accept json
+// @Produce json
+// @Param request body TwoFactorCodeRequest true "验证码"
+// @Success 200 {object} map[string]interface{} "登录成功，返回用户信息"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "验证码错误或登录已过期"
+// @Failure 403 {object} map[string]interface{} "账号已锁定"
+// @Failure 429 {object} map[string]interface{} "失败次数过多"
+// @Router /admin/2fa/verify [post]
+func (h *AdminHandler) VerifyTwoFactor(c *gin.Context) {
+	var req TwoFactorCodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "参数错误"})
+		return
+	}
+
+	userID, ok := twoFactorPendingUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "登录已过期，请重新输入用户名和密码"})
+		return
+	}
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil || !user.TOTPEnabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "登录已过期，请重新输入用户名和密码"})
+		return
+	}
+	ip := c.ClientIP()
+	if checkLoginLocked(c, user.Username, ip) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": loginLockedMessage})
+		return
+	}
+	if user.Status != models.UserStatusActive {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "账号已锁定，请联系管理员解锁"})
+		return
+	}
+
+	step, ok, err := checkTOTP(&user, req.Code)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "校验失败")})
+		return
+	}
+	if !ok {
+		recordLoginFailure("后台两步验证", user.Username, ip)
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "验证码错误"})
+		return
+	}
+	// 条件更新防止同一验证码被并发请求重复使用
+	res := database.DB.Model(&models.User{}).Where("id = ? AND totp_last_step < ?", user.ID, step).Update("totp_last_step", step)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(res.Error, "登录失败")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "验证码已使用，请等待下一个验证码"})
+		return
+	}
+	loginLimiter().ResetUser(user.Username)
+
+	setAdminCookie(c, twoFactorPendingCookie, "", -1, true)
+	completeAdminLogin(c, &user)
+}
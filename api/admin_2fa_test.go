@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+	"finance/models"
+	"finance/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+// currentTOTP 按 RFC 6238 计算当前周期的 6 位验证码
+func currentTOTP(t *testing.T, secret string) string {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	require.NoError(t, err)
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(time.Now().Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:offset+4])&0x7fffffff)%1000000)
+}
+
+// serveTwoFactorVerify 携带等待两步验证的 Cookie 请求 POST /admin/2fa/verify
+func serveTwoFactorVerify(pending, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.POST("/admin/2fa/verify", NewAdminHandler().VerifyTwoFactor)
+	req := httptest.NewRequest("POST", "/admin/2fa/verify", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	if pending != "" {
+		req.AddCookie(&http.Cookie{Name: twoFactorPendingCookie, Value: adminauth.SignCookieValue(pending)})
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// stringCapture 记录 SQL 参数的实际值，供断言写入的内容
+type stringCapture struct{ dest *string }
+
+func (s stringCapture) Match(v driver.Value) bool {
+	str, ok := v.(string)
+	*s.dest = str
+	return ok
+}
+
+func captureString(dest *string) sqlmock.Argument {
+	return stringCapture{dest: dest}
+}
+
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, ck := range w.Result().Cookies() {
+		if ck.Name == name {
+			return ck
+		}
+	}
+	return nil
+}
+
+func TestAdminHandler_AdminLogin_TwoFactorRequired(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("admin123"), bcrypt.MinCost)
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WithArgs("totpadmin", "totpadmin").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "is_admin", "status", "totp_enabled"}).
+			AddRow(5, "totpadmin", string(hashed), true, models.UserStatusActive, true))
+
+	router := gin.New()
+	router.POST("/admin/login", NewAdminHandler().AdminLogin)
+	req := httptest.NewRequest("POST", "/admin/login", bytes.NewBufferString(`{"username":"totpadmin","password":"admin123"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"2fa_required":true`)
+	assert.Nil(t, responseCookie(w, "admin_user_id"), "两步验证通过前不能设置登录 Cookie")
+	pending := responseCookie(w, twoFactorPendingCookie)
+	require.NotNil(t, pending)
+	assert.True(t, pending.HttpOnly)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_VerifyTwoFactor_Success(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	encrypted, err := service.EncryptSecret(testTOTPSecret)
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin", "status", "totp_secret", "totp_enabled", "totp_last_step"}).
+			AddRow(5, "totpadmin", true, models.UserStatusActive, encrypted, true, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `totp_last_step`=\\?,`updated_at`=\\? WHERE \\(id = \\? AND totp_last_step < \\?\\)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	pending := fmt.Sprintf("5:%d", time.Now().Add(time.Minute).Unix())
+	w := serveTwoFactorVerify(pending, fmt.Sprintf(`{"code":"%s"}`, currentTOTP(t, testTOTPSecret)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	ck := responseCookie(w, "admin_user_id")
+	require.NotNil(t, ck)
+	id, err := adminauth.VerifyCookieValue(ck.Value)
+	require.NoError(t, err)
+	assert.Equal(t, "5", id)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_VerifyTwoFactor_Rejected(t *testing.T) {
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	t.Run("未经过密码校验", func(t *testing.T) {
+		w := serveTwoFactorVerify("", `{"code":"123456"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("等待时间已过", func(t *testing.T) {
+		w := serveTwoFactorVerify(fmt.Sprintf("5:%d", time.Now().Add(-time.Second).Unix()), `{"code":"123456"}`)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("验证码错误", func(t *testing.T) {
+		mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		encrypted, err := service.EncryptSecret(testTOTPSecret)
+		require.NoError(t, err)
+		mock.ExpectQuery("SELECT \\* FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status", "totp_secret", "totp_enabled"}).
+				AddRow(6, "wrongcode", models.UserStatusActive, encrypted, true))
+
+		w := serveTwoFactorVerify(fmt.Sprintf("6:%d", time.Now().Add(time.Minute).Unix()), `{"code":"000000x"}`)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Nil(t, responseCookie(w, "admin_user_id"))
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestAdminHandler_EnableTwoFactor(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	encrypted, err := service.EncryptSecret(testTOTPSecret)
+	require.NoError(t, err)
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin", "totp_secret", "totp_enabled"}).
+			AddRow(1, "admin", true, encrypted, false))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `totp_enabled`=\\?,`totp_last_step`=\\?").
+		WithArgs(true, sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `audit_logs`").
+		WithArgs(1, models.AuditActionEnable2FA, models.AuditTargetUser, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("POST", "/admin/2fa/enable", "/admin/2fa/enable", NewAdminHandler().EnableTwoFactor,
+		fmt.Sprintf(`{"code":"%s"}`, currentTOTP(t, testTOTPSecret)))
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_SetupTwoFactor_StoresEncryptedSecret(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	var stored string
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `totp_last_step`=\\?,`totp_secret`=\\?").
+		WithArgs(0, captureString(&stored), sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("POST", "/admin/2fa/setup", "/admin/2fa/setup", NewAdminHandler().SetupTwoFactor, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "otpauth://totp/")
+	assert.True(t, service.IsEncryptedSecret(stored), "密钥应加密保存")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
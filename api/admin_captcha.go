@@ -0,0 +1,64 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http"
+	"sync"
+	"time"
+
+	"finance/config"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	adminLoginGuardOnce sync.Once
+	adminLoginGuardInst *service.LoginGuard
+)
+
+// adminLoginGuard 后台登录失败计数器，阈值与统计窗口取自 login 配置
+func adminLoginGuard() *service.LoginGuard {
+	adminLoginGuardOnce.Do(func() {
+		threshold := config.DefaultLoginCaptchaThreshold
+		window := config.DefaultLoginFailureWindowMinutes
+		if config.GlobalConfig != nil {
+			if config.GlobalConfig.Login.CaptchaThreshold > 0 {
+				threshold = config.GlobalConfig.Login.CaptchaThreshold
+			}
+			if config.GlobalConfig.Login.FailureWindowMinutes > 0 {
+				window = config.GlobalConfig.Login.FailureWindowMinutes
+			}
+		}
+		adminLoginGuardInst = service.NewLoginGuard(threshold, time.Duration(window)*time.Minute)
+	})
+	return adminLoginGuardInst
+}
+
+// GetCaptcha 获取图形验证码
+// @Summary 获取图形验证码
+// @Description 返回验证码 ID 和 base64 PNG 图片，5 分钟内有效且只能使用一次。后台登录连续失败达到阈值后必须携带验证码
+// @Tags 后台管理
+// @Produce json
+// @Success 200 {object} map[string]interface{} "captcha_id、image（data URL）"
+// @Failure 429 {object} map[string]interface{} "验证码请求过多"
+// @Failure 500 {object} map[string]interface{} "生成失败"
+// @Router /admin/captcha [get]
+func (h *AdminHandler) GetCaptcha(c *gin.Context) {
+	id, img, err := service.NewCaptcha()
+	if err == service.ErrCaptchaBusy {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成验证码失败"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data": gin.H{
+			"captcha_id": id,
+			"image":      "data:image/png;base64," + base64.StdEncoding.EncodeToString(img),
+		},
+	})
+}
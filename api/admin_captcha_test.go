@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func postAdminLogin(router *gin.Engine, ip, body string) (int, map[string]interface{}) {
+	req := httptest.NewRequest("POST", "/admin/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w.Code, resp
+}
+
+func TestAdminHandler_AdminLogin_CaptchaRequiredAfterFailures(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	const ip = "10.9.9.1"
+	guard := adminLoginGuard()
+	defer guard.Reset("bruteuser", ip)
+	defer loginLimiter().Reset("bruteuser", ip)
+
+	router := gin.New()
+	router.POST("/admin/login", NewAdminHandler().AdminLogin)
+	body := `{"username":"bruteuser","password":"wrong"}`
+
+	// 达到阈值前：正常查库，返回 401
+	for i := 1; i <= 3; i++ {
+		mock.ExpectQuery("SELECT .* FROM `users`").
+			WithArgs("bruteuser", "bruteuser").
+			WillReturnError(gorm.ErrRecordNotFound)
+		code, resp := postAdminLogin(router, ip, body)
+		assert.Equal(t, 401, code)
+		assert.Equal(t, i >= 3, resp["captcha_required"], "第 %d 次失败", i)
+	}
+
+	// 达到阈值后：不带验证码直接拒绝，且不再查库
+	code, resp := postAdminLogin(router, ip, body)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, true, resp["captcha_required"])
+
+	// 验证码错误同样拒绝
+	code, resp = postAdminLogin(router, ip, `{"username":"bruteuser","password":"wrong","captcha_id":"nope","captcha_code":"ABCD"}`)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, true, resp["captcha_required"])
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_AdminLogin_CaptchaRequiredByIP(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	const ip = "10.9.9.2"
+	guard := adminLoginGuard()
+	defer guard.Reset("", ip)
+	for i := 0; i < 3; i++ {
+		guard.RecordFailure("user"+string(rune('a'+i)), ip)
+	}
+	defer func() {
+		for i := 0; i < 3; i++ {
+			guard.Reset("user"+string(rune('a'+i)), ip)
+		}
+	}()
+
+	router := gin.New()
+	router.POST("/admin/login", NewAdminHandler().AdminLogin)
+
+	// 换一个账号也需要验证码，防止同一 IP 轮换用户名爆破
+	code, resp := postAdminLogin(router, ip, `{"username":"another","password":"x"}`)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, true, resp["captcha_required"])
+}
+
+func TestAdminHandler_GetCaptcha(t *testing.T) {
+	router := gin.New()
+	router.GET("/admin/captcha", NewAdminHandler().GetCaptcha)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/admin/captcha", nil))
+
+	assert.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			CaptchaID string `json:"captcha_id"`
+			Image     string `json:"image"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data.CaptchaID, 32)
+	assert.True(t, strings.HasPrefix(resp.Data.Image, "data:image/png;base64,"))
+}
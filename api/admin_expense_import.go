@@ -0,0 +1,312 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+	"gorm.io/gorm"
+)
+
+// 后台导入 CSV 的列，表头可用英文列名或导出文件的中文列名
+const (
+	importColumnAmount      = "amount"
+	importColumnCategory    = "category"
+	importColumnDescription = "description"
+	importColumnTime        = "expense_time"
+)
+
+// importColumnAliases 表头列名（小写）→ 列；包含 CSV 导出的中文表头，导出文件可直接导入，ID、创建时间等其他列忽略
+var importColumnAliases = map[string]string{
+	"amount":       importColumnAmount,
+	"金额":           importColumnAmount,
+	"category":     importColumnCategory,
+	"类别":           importColumnCategory,
+	"description":  importColumnDescription,
+	"描述":           importColumnDescription,
+	"expense_time": importColumnTime,
+	"消费时间":         importColumnTime,
+}
+
+// ImportLineFailure 导入失败的行
+type ImportLineFailure struct {
+	Line   int    `json:"line"` // 文件中的行号（从 1 开始，含表头）
+	Reason string `json:"reason"`
+}
+
+// AdminExpenseImportResult 后台导入消费记录结果
+type AdminExpenseImportResult struct {
+	Total        int                 `json:"total"`         // 数据行数（不含表头）
+	ValidCount   int                 `json:"valid_count"`   // 通过校验的行数
+	SuccessCount int                 `json:"success_count"` // 实际写入的行数
+	FailCount    int                 `json:"fail_count"`    // 未通过校验的行数
+	AllowPartial bool                `json:"allow_partial"`
+	Committed    bool                `json:"committed"` // 是否已写入；未开启 allow_partial 且有失败行时整批不写入
+	Failures     []ImportLineFailure `json:"failures"`
+}
+
+// importCSVRow CSV 数据行及其在文件中的行号
+type importCSVRow struct {
+	Line   int
+	Fields map[string]string
+}
+
+// readAdminImportCSV 读取后台导入用 CSV（去掉 UTF-8 BOM），返回带行号的数据行。
+// 首行包含已知列名时视为表头并按列名取值（可直接导入 CSV 导出文件），否则按 金额,类别,描述,消费时间 的顺序取值
+func readAdminImportCSV(r io.Reader, opts *csvOptions) ([]importCSVRow, error) {
+	if opts.Encoding == CSVEncodingGBK {
+		r = transform.NewReader(r, simplifiedchinese.GBK.NewDecoder())
+	}
+	reader := csv.NewReader(r)
+	reader.Comma = opts.Delimiter
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	columns := []string{importColumnAmount, importColumnCategory, importColumnDescription, importColumnTime}
+	var rows []importCSVRow
+	first := true
+	for {
+		rec, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.New("CSV 解析失败: " + err.Error())
+		}
+		line, _ := reader.FieldPos(0)
+		if first {
+			first = false
+			if len(rec) > 0 {
+				rec[0] = strings.TrimPrefix(rec[0], utf8BOM)
+			}
+			if header, ok := parseImportHeader(rec); ok {
+				if err := checkImportHeader(header); err != nil {
+					return nil, err
+				}
+				columns = header
+				continue
+			}
+		}
+		fields := make(map[string]string, len(columns))
+		for i, col := range columns {
+			if col != "" && i < len(rec) {
+				fields[col] = strings.TrimSpace(rec[i])
+			}
+		}
+		rows = append(rows, importCSVRow{Line: line, Fields: fields})
+		if len(rows) > maxImportRows {
+			return nil, fmt.Errorf("单次最多导入 %d 条记录", maxImportRows)
+		}
+	}
+	if len(rows) == 0 {
+		return nil, errors.New("CSV 中没有数据行")
+	}
+	return rows, nil
+}
+
+// parseImportHeader 识别表头行：任一单元格为已知列名即视为表头，返回每列对应的字段（未知列为空字符串）
+func parseImportHeader(rec []string) ([]string, bool) {
+	columns := make([]string, len(rec))
+	found := false
+	for i, cell := range rec {
+		if col, ok := importColumnAliases[strings.ToLower(strings.TrimSpace(cell))]; ok {
+			columns[i] = col
+			found = true
+		}
+	}
+	return columns, found
+}
+
+// checkImportHeader 表头必须包含金额、类别、消费时间列
+func checkImportHeader(columns []string) error {
+	has := map[string]bool{}
+	for _, col := range columns {
+		has[col] = true
+	}
+	var missing []string
+	for _, col := range []string{importColumnAmount, importColumnCategory, importColumnTime} {
+		if !has[col] {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.New("CSV 表头缺少列: " + strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// buildImportExpense 校验一行数据并构造消费记录；categories 为已存在的消费类别名称
+func buildImportExpense(row importCSVRow, categories map[string]bool) (*models.Expense, error) {
+	category := row.Fields[importColumnCategory]
+	if category == "" {
+		return nil, errors.New("类别不能为空")
+	}
+	if !categories[category] {
+		return nil, fmt.Errorf("类别「%s」不存在", category)
+	}
+	amount, err := strconv.ParseFloat(strings.ReplaceAll(row.Fields[importColumnAmount], ",", ""), 64)
+	if err != nil {
+		return nil, errors.New("金额格式错误")
+	}
+	amount = math.Round(amount*100) / 100
+	if err := validateAmount(amount); err != nil {
+		return nil, err
+	}
+	desc := row.Fields[importColumnDescription]
+	if err := validateDescription(desc); err != nil {
+		return nil, err
+	}
+	expenseTime, err := parseImportTime("消费时间", row.Fields[importColumnTime])
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRecordTime("消费时间", expenseTime); err != nil {
+		return nil, err
+	}
+	return &models.Expense{
+		Amount:      amount,
+		Currency:    baseCurrency(),
+		Category:    category,
+		Description: desc,
+		ExpenseTime: expenseTime,
+	}, nil
+}
+
+// importAdminExpenseRows 逐行校验并写入。默认整批原子：有任一失败行时不写入任何记录；
+// allowPartial 时只写入通过校验的行。template 提供用户、录入人与提交来源
+func importAdminExpenseRows(rows []importCSVRow, categories map[string]bool, template models.Expense, allowPartial bool) (*AdminExpenseImportResult, error) {
+	result := &AdminExpenseImportResult{Total: len(rows), AllowPartial: allowPartial, Failures: []ImportLineFailure{}}
+	var pending []models.Expense
+	for _, row := range rows {
+		e, err := buildImportExpense(row, categories)
+		if err != nil {
+			result.Failures = append(result.Failures, ImportLineFailure{Line: row.Line, Reason: err.Error()})
+			continue
+		}
+		e.UserID = template.UserID
+		e.CreatedBy = template.CreatedBy
+		e.RecordSource = template.RecordSource
+		pending = append(pending, *e)
+	}
+	result.ValidCount = len(pending)
+	result.FailCount = len(result.Failures)
+
+	if len(pending) == 0 || (result.FailCount > 0 && !allowPartial) {
+		return result, nil
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&pending, 100).Error
+	}); err != nil {
+		return nil, err
+	}
+	result.SuccessCount = len(pending)
+	result.Committed = true
+	return result, nil
+}
+
+// ImportExpenses 从 CSV 导入消费记录
+// @Summary 从 CSV 导入消费记录
+// @Description 上传 CSV 为指定用户批量导入消费记录，单次最多 1000 行、文件不超过 2MB。列为 金额,类别,描述,消费时间；首行为表头时按列名取值（amount/category/description/expense_time 或 CSV 导出的中文表头），因此导出文件可直接导入，ID、创建时间等其他列忽略。
+// @Description 每行校验：类别必须已存在、金额大于 0、消费时间可解析（2006-01-02 15:04:05 / 2006-01-02 15:04 / 2006-01-02）。默认整批原子写入：只要有一行失败就不写入任何记录；allow_partial=true 时只写入通过校验的行。
+// @Description 返回成功/失败数及失败行的行号（从 1 开始，含表头）与原因。管理员可为任意用户导入，非管理员只能为自己导入
+// @Tags 后台管理-消费记录
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV 文件"
+// @Param user_id formData int false "目标用户ID，默认当前用户"
+// @Param allow_partial query bool false "是否允许部分导入，默认 false"
+// @Param delimiter query string false "CSV 分隔符：comma/semicolon/tab，默认 comma"
+// @Param encoding query string false "CSV 编码：utf8-bom/utf8/gbk，默认 UTF-8（自动去掉 BOM）"
+// @Success 200 {object} map[string]interface{} "导入完成，data 为 AdminExpenseImportResult"
+// @Failure 400 {object} map[string]interface{} "参数错误或文件格式错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /admin/expenses/import [post]
+func (h *AdminHandler) ImportExpenses(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	targetUserID := currentUser.ID
+	if v := c.PostForm("user_id"); v != "" {
+		uid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的用户ID"})
+			return
+		}
+		targetUserID = uint(uid)
+	}
+	// 权限检查：非管理员只能为自己导入
+	if !currentUser.IsAdmin && targetUserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，只能为自己创建记录"})
+		return
+	}
+	var user models.User
+	if err := database.DB.First(&user, targetUserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "用户不存在"})
+		return
+	}
+
+	opts, err := resolveCSVOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请上传 CSV 文件"})
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "文件不能超过 2MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	rows, err := readAdminImportCSV(file, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	categories, err := loadExpenseCategoryNames()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询类别失败")})
+		return
+	}
+
+	template := models.Expense{UserID: targetUserID, CreatedBy: currentUser.ID, RecordSource: recordSourceFrom(c)}
+	result, err := importAdminExpenseRows(rows, categories, template, c.Query("allow_partial") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "导入消费记录失败")})
+		return
+	}
+
+	message := "导入完成"
+	if !result.Committed && result.FailCount > 0 {
+		message = "存在校验失败的行，未导入任何记录"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": message,
+		"data":    result,
+	})
+}
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var defaultImportCSVOptions = &csvOptions{Delimiter: ',', Encoding: CSVEncodingUTF8BOM}
+
+func TestReadAdminImportCSV_ExportHeader(t *testing.T) {
+	// CSV 导出文件：带 BOM，含 ID、创建时间列，描述中有换行
+	data := "\xEF\xBB\xBFID,金额,类别,描述,消费时间,创建时间\n" +
+		"1,12.50,餐饮,\"午饭\n加蛋\",2024-03-01 12:00:00,2024-03-01 12:01:00\n" +
+		"2,30,交通,打车,2024-03-02 08:00:00,2024-03-02 08:01:00\n"
+	rows, err := readAdminImportCSV(strings.NewReader(data), defaultImportCSVOptions)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	assert.Equal(t, 2, rows[0].Line)
+	assert.Equal(t, "12.50", rows[0].Fields[importColumnAmount])
+	assert.Equal(t, "午饭\n加蛋", rows[0].Fields[importColumnDescription])
+	assert.Equal(t, 4, rows[1].Line)
+	assert.Equal(t, "交通", rows[1].Fields[importColumnCategory])
+	assert.Equal(t, "2024-03-02 08:00:00", rows[1].Fields[importColumnTime])
+}
+
+func TestReadAdminImportCSV_NoHeaderAndDelimiter(t *testing.T) {
+	data := "12.5;餐饮;午饭;2024-03-01\n"
+	rows, err := readAdminImportCSV(strings.NewReader(data), &csvOptions{Delimiter: ';', Encoding: CSVEncodingUTF8})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, 1, rows[0].Line)
+	assert.Equal(t, map[string]string{
+		importColumnAmount: "12.5", importColumnCategory: "餐饮", importColumnDescription: "午饭", importColumnTime: "2024-03-01",
+	}, rows[0].Fields)
+}
+
+func TestReadAdminImportCSV_Invalid(t *testing.T) {
+	_, err := readAdminImportCSV(strings.NewReader("amount,category,description\n1,餐饮,x\n"), defaultImportCSVOptions)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "expense_time")
+
+	_, err = readAdminImportCSV(strings.NewReader("amount,category,description,expense_time\n"), defaultImportCSVOptions)
+	assert.Error(t, err)
+}
+
+func adminImportRows(t *testing.T) []importCSVRow {
+	data := "amount,category,description,expense_time\n" +
+		"12.5,餐饮,午饭,2024-03-01 12:00:00\n" +
+		"-3,餐饮,退款,2024-03-01\n" +
+		"20,不存在,,2024-03-02\n" +
+		"8,交通,地铁,2024/03/02\n" +
+		"30,交通,打车,2024-03-02\n"
+	rows, err := readAdminImportCSV(strings.NewReader(data), defaultImportCSVOptions)
+	require.NoError(t, err)
+	return rows
+}
+
+func TestImportAdminExpenseRows_AllOrNothing(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	categories := map[string]bool{"餐饮": true, "交通": true}
+	result, err := importAdminExpenseRows(adminImportRows(t), categories, models.Expense{UserID: 2, CreatedBy: 1}, false)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, 2, result.ValidCount)
+	assert.Equal(t, 0, result.SuccessCount)
+	assert.Equal(t, 3, result.FailCount)
+	assert.False(t, result.Committed)
+	require.Len(t, result.Failures, 3)
+	assert.Equal(t, 3, result.Failures[0].Line)
+	assert.Equal(t, 4, result.Failures[1].Line)
+	assert.Contains(t, result.Failures[1].Reason, "不存在")
+	assert.Equal(t, 5, result.Failures[2].Line)
+	// 未开启部分导入时有失败行则不写库
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportAdminExpenseRows_AllowPartial(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	categories := map[string]bool{"餐饮": true, "交通": true}
+	result, err := importAdminExpenseRows(adminImportRows(t), categories, models.Expense{UserID: 2, CreatedBy: 1}, true)
+	require.NoError(t, err)
+
+	assert.True(t, result.Committed)
+	assert.Equal(t, 2, result.SuccessCount)
+	assert.Equal(t, 3, result.FailCount)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
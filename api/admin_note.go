@@ -0,0 +1,54 @@
+package api
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"finance/models"
+)
+
+// maxAdminNoteLength 管理员备注最大字符数，与 admin_note 列宽一致
+const maxAdminNoteLength = 500
+
+// validateAdminNote 校验管理员备注长度（按字符计）
+func validateAdminNote(note string) error {
+	if utf8.RuneCountInString(note) > maxAdminNoteLength {
+		return fmt.Errorf("管理员备注不能超过 %d 个字符", maxAdminNoteLength)
+	}
+	return nil
+}
+
+// withAdminFields 在记录原有 JSON 上附加仅管理员可见的 admin_note、source_ip、user_agent。
+// 这些字段在模型上不参与序列化，只有管理员请求的后台响应才用它包装
+type withAdminFields struct {
+	record    interface{}
+	adminNote string
+	source    models.RecordSource
+}
+
+// MarshalJSON 按记录自身格式序列化，再合并管理员可见字段
+func (w withAdminFields) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(w.record, map[string]interface{}{
+		"admin_note": w.adminNote,
+		"source_ip":  w.source.SourceIP,
+		"user_agent": w.source.UserAgent,
+	})
+}
+
+// expensesWithAdminFields 后台消费列表附带管理员备注与提交来源
+func expensesWithAdminFields(list []ExpenseWithUser) []withAdminFields {
+	out := make([]withAdminFields, len(list))
+	for i, e := range list {
+		out[i] = withAdminFields{record: e, adminNote: e.AdminNote, source: e.RecordSource}
+	}
+	return out
+}
+
+// incomesWithAdminFields 后台收入列表附带管理员备注与提交来源
+func incomesWithAdminFields(list []IncomeWithUser) []withAdminFields {
+	out := make([]withAdminFields, len(list))
+	for i, in := range list {
+		out[i] = withAdminFields{record: in, adminNote: in.AdminNote, source: in.RecordSource}
+	}
+	return out
+}
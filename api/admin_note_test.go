@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"finance/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAdminNote(t *testing.T) {
+	assert.NoError(t, validateAdminNote(""))
+	assert.NoError(t, validateAdminNote(strings.Repeat("疑", maxAdminNoteLength)))
+	assert.Error(t, validateAdminNote(strings.Repeat("疑", maxAdminNoteLength+1)))
+}
+
+func TestAdminNote_HiddenFromModelJSON(t *testing.T) {
+	raw, err := json.Marshal(models.Expense{ID: 1, Description: "午饭", AdminNote: "金额可疑",
+		RecordSource: models.RecordSource{SourceIP: "203.0.113.5", UserAgent: "okhttp/4.12"}})
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "admin_note")
+	assert.NotContains(t, string(raw), "金额可疑")
+	assert.NotContains(t, string(raw), "source_ip")
+	assert.NotContains(t, string(raw), "203.0.113.5")
+	assert.NotContains(t, string(raw), "okhttp")
+
+	raw, err = json.Marshal(IncomeWithUser{Income: models.Income{ID: 1, AdminNote: "重复入账"}, Username: "alice"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(raw), "admin_note")
+}
+
+func TestExpensesWithAdminFields(t *testing.T) {
+	list := expensesWithAdminFields([]ExpenseWithUser{
+		{Expense: models.Expense{ID: 1, Description: "午饭", AdminNote: "金额可疑",
+			RecordSource: models.RecordSource{SourceIP: "203.0.113.5", UserAgent: "okhttp/4.12"}}, Username: "alice"},
+	})
+	raw, err := json.Marshal(list)
+	require.NoError(t, err)
+
+	var got []map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.Len(t, got, 1)
+	assert.Equal(t, "金额可疑", got[0]["admin_note"])
+	assert.Equal(t, "203.0.113.5", got[0]["source_ip"])
+	assert.Equal(t, "okhttp/4.12", got[0]["user_agent"])
+	assert.Equal(t, "午饭", got[0]["description"])
+	assert.Equal(t, "alice", got[0]["username"])
+	assert.Contains(t, got[0], "expense_time")
+}
+
+func TestIncomesWithAdminFields(t *testing.T) {
+	raw, err := json.Marshal(incomesWithAdminFields([]IncomeWithUser{
+		{Income: models.Income{ID: 2, Type: "工资"}, Username: "bob"},
+	}))
+	require.NoError(t, err)
+
+	var got []map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	require.Len(t, got, 1)
+	// 没有备注时也返回空字符串，方便后台直接编辑
+	assert.Equal(t, "", got[0]["admin_note"])
+	assert.Equal(t, "", got[0]["source_ip"])
+	assert.Equal(t, "bob", got[0]["username"])
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"finance/config"
+	"finance/database"
 	"finance/models"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -68,3 +69,51 @@ func TestAdminHandler_AdminLogin_AccountLocked(t *testing.T) {
 	assert.Equal(t, 403, w.Code)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestParseAdminExpenseFilter(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+	parse := func(rawQuery string) (*adminExpenseFilter, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/admin/expenses?"+rawQuery, nil)
+		return parseAdminExpenseFilter(c, now)
+	}
+
+	f, err := parse("start_time=2024-01-01&category=餐饮&username=al&user_id=3")
+	require.NoError(t, err)
+	assert.Equal(t, adminExpenseFilter{StartStr: "2024-01-01", Category: "餐饮", Username: "al", UserID: 3}, *f)
+
+	f, err = parse("period=this_month")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01", f.StartStr)
+	assert.Equal(t, "2024-03-15", f.EndStr)
+
+	for _, bad := range []string{
+		"start_time=2024/01/01",
+		"end_time=bad",
+		"start_time=2024-02-01&end_time=2024-01-01",
+		"user_id=abc",
+		"period=this_month&start_time=2024-01-01",
+	} {
+		_, err := parse(bad)
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestAdminExpenseFilter_Query(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 非管理员传 user_id 无效，只能查到自己的记录
+	mock.ExpectQuery("SELECT expenses\\.\\*, users\\.username, creators\\.username AS created_by_name FROM `expenses` "+
+		"LEFT JOIN users ON expenses.user_id = users.id LEFT JOIN users creators ON expenses.created_by = creators.id "+
+		"WHERE expenses.user_id = \\? AND expenses.expense_time >= \\? AND expenses.category = \\? AND users.username LIKE \\?").
+		WithArgs(7, sqlmock.AnyArg(), "餐饮", "%al%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "username"}).AddRow(1, 7, 12.5, "alice"))
+
+	f := &adminExpenseFilter{StartStr: "2024-01-01", Category: "餐饮", Username: "al", UserID: 3}
+	var list []ExpenseWithUser
+	require.NoError(t, f.query(database.DB, &models.User{ID: 7}).Scan(&list).Error)
+	require.Len(t, list, 1)
+	assert.Equal(t, "alice", list[0].Username)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
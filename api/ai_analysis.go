@@ -1,7 +1,6 @@
 package api
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
 	"fmt"
@@ -9,7 +8,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"finance/database"
 	"finance/models"
@@ -28,7 +26,22 @@ func NewAIAnalysisHandler() *AIAnalysisHandler {
 // ExpenseWithUser 带用户名的消费记录
 type ExpenseWithUser struct {
 	models.Expense
-	Username string `json:"username"`
+	Username      string `json:"username"`
+	CreatedByName string `json:"created_by_name"` // 录入人用户名，仅后台列表查询时填充
+}
+
+// MarshalJSON 保留 models.Expense 的统一时间格式并附带 username 与录入人信息
+func (e ExpenseWithUser) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(e.Expense, map[string]interface{}{
+		"username":        e.Username,
+		"created_by_name": e.CreatedByName,
+		"proxy_entry":     isProxyEntry(e.UserID, e.CreatedBy),
+	})
+}
+
+// isProxyEntry 记录是否由他人（管理员）代录；录入人未知（0）时按本人录入处理
+func isProxyEntry(userID, createdBy uint) bool {
+	return createdBy != 0 && createdBy != userID
 }
 
 // AnalysisRequest AI分析请求
@@ -37,11 +50,14 @@ type AnalysisRequest struct {
 	StartTime string `json:"start_time" binding:"required" example:"2024-01-01"`
 	EndTime   string `json:"end_time" binding:"required" example:"2024-12-31"`
 	UserID    *uint  `json:"user_id,omitempty" example:"1"` // 可选，仅管理员可用，用于筛选指定用户的账单
+	Language  string `json:"language" example:"zh"`         // 回答语言：zh（默认）/en/ja
 }
 
 type sseAnalysisFrame struct {
-	Type    string `json:"type"`              // delta | done | error
-	Content string `json:"content,omitempty"` // delta内容或错误信息
+	Type     string `json:"type"`               // queued | delta | done | error
+	Content  string `json:"content,omitempty"`  // delta内容、排队提示或错误信息
+	Model    string `json:"model,omitempty"`    // done 帧：实际使用的模型（可能为备用模型）
+	Position int    `json:"position,omitempty"` // queued 帧：当前排队人数（含自己）
 }
 
 func writeAnalysisSSE(c *gin.Context, v any) {
@@ -55,19 +71,25 @@ func writeAnalysisSSE(c *gin.Context, v any) {
 
 // AnalyzeExpenses 分析消费记录（流式输出）
 // @Summary AI分析消费记录（流式）
-// @Description 选择时间范围和AI模型，对消费记录进行AI分析，SSE流式返回JSON帧（delta/done/error）。管理员可分析所有记录或指定用户的记录（通过user_id参数），非管理员只能分析自己的记录。分析结束后会保存到历史记录。
+// @Description 选择时间范围和AI模型，对消费记录进行AI分析，SSE流式返回JSON帧（queued/delta/done/error）；模型并发已满时先返回 queued 帧并排队等待。管理员可分析所有记录或指定用户的记录（通过user_id参数），非管理员只能分析自己的记录。分析结束后会保存到历史记录。
 // @Tags 后台管理-AI分析
 // @Accept json
 // @Produce text/event-stream
 // @Param request body AnalysisRequest true "分析请求（user_id字段仅管理员可用）"
 // @Success 200 {string} string "SSE流：data: {\"type\":\"delta\",\"content\":\"...\"}"
-// @Failure 400 {object} map[string]interface{} "参数错误或该时间范围内没有消费记录"
+// @Failure 400 {object} map[string]interface{} "参数错误、时间范围超过上限或该时间范围内没有消费记录"
 // @Failure 404 {object} map[string]interface{} "AI模型不存在"
+// @Failure 429 {object} map[string]interface{} "今日 AI 调用次数已达上限（非管理员）"
 // @Router /admin/ai-analysis [post]
 func (h *AIAnalysisHandler) AnalyzeExpenses(c *gin.Context) {
 	var req AnalysisRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
+		return
+	}
+	lang, err := resolveAILanguage(req.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
@@ -84,6 +106,10 @@ func (h *AIAnalysisHandler) AnalyzeExpenses(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "时间格式错误"})
 		return
 	}
+	if err := validateAnalysisRange(startTime, endTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 
 	// 获取当前用户
 	currentUser, err := getCurrentUser(c)
@@ -117,126 +143,133 @@ func (h *AIAnalysisHandler) AnalyzeExpenses(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "该时间范围内没有消费记录"})
 		return
 	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "查询汇率失败"})
+		return
+	}
+	if !checkAIQuotaAdmin(c, currentUser) {
+		return
+	}
 
 	// 构建分析提示词
-	prompt := h.buildAnalysisPrompt(expenses, req.StartTime, req.EndTime)
+	prompt := h.buildAnalysisPrompt(expenses, rates, req.StartTime, req.EndTime, lang)
 
 	// 调用AI模型API（流式）
 	// 保存历史记录时使用当前登录用户的ID
-	if err := h.callAIModelStreamAndStore(c, aiModel, currentUser.ID, req.StartTime, req.EndTime, prompt); err != nil {
+	if err := h.callAIModelStreamAndStore(c, aiModel, currentUser.ID, req.StartTime, req.EndTime, lang, prompt); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "AI分析失败")})
 		return
 	}
 }
 
-// buildAnalysisPrompt 构建分析提示词
-func (h *AIAnalysisHandler) buildAnalysisPrompt(expenses []ExpenseWithUser, startTime, endTime string) string {
+// buildAnalysisPrompt 构建分析提示词，lang 为回答语言；模板可在后台自定义（见 PromptKeyAIAnalysis）。
+// 合计与类别统计按 rates 折算为本位币，缺少汇率的币种不计入并在类别统计末尾注明
+func (h *AIAnalysisHandler) buildAnalysisPrompt(expenses []ExpenseWithUser, rates exchangeRates, startTime, endTime, lang string) string {
 	// 统计信息
 	var totalAmount float64
 	categoryStats := make(map[string]float64)
 	categoryCount := make(map[string]int)
+	var unconverted []string
 
 	for _, exp := range expenses {
-		totalAmount += exp.Amount
-		categoryStats[exp.Category] += exp.Amount
+		amount, ok := rates.ToBase(exp.Amount, exp.Currency)
+		if !ok {
+			unconverted = append(unconverted, exp.Currency)
+			continue
+		}
+		totalAmount += amount
+		categoryStats[exp.Category] += amount
 		categoryCount[exp.Category]++
 	}
 
-	// 构建提示词
-	prompt := fmt.Sprintf(`请分析以下消费记录数据，并提供详细的总结和建议：
-
-时间范围：%s 至 %s
-总记录数：%d 条
-总消费金额：%.2f 元
-
-消费类别统计：
-`, startTime, endTime, len(expenses), totalAmount)
-
+	var categoryLines []string
 	for category, amount := range categoryStats {
-		prompt += fmt.Sprintf("- %s: %.2f 元 (%d 条记录)\n", category, amount, categoryCount[category])
+		categoryLines = append(categoryLines, fmt.Sprintf("- %s: %.2f 元 (%d 条记录)", category, amount, categoryCount[category]))
+	}
+	if len(unconverted) > 0 {
+		categoryLines = append(categoryLines, fmt.Sprintf("- 另有 %s 币种记录缺少汇率，未计入以上统计", strings.Join(mergeCurrencyCodes(unconverted), "/")))
 	}
 
-	prompt += "\n详细消费记录（最近20条）：\n"
 	maxRecords := 20
 	if len(expenses) < maxRecords {
 		maxRecords = len(expenses)
 	}
+	var recordLines []string
 	for i := 0; i < maxRecords; i++ {
 		exp := expenses[i]
-		prompt += fmt.Sprintf("- %s: %s 在 %s 消费 %.2f 元，类别：%s",
+		line := fmt.Sprintf("- %s: %s 在 %s 消费 %s，类别：%s",
 			exp.ExpenseTime.Format("2006-01-02 15:04"),
 			exp.Username,
 			exp.ExpenseTime.Format("2006-01-02 15:04:05"),
-			exp.Amount,
+			promptAmount(exp.Amount, exp.Currency),
 			exp.Category)
 		if exp.Description != "" {
-			prompt += fmt.Sprintf("，说明：%s", exp.Description)
+			line += fmt.Sprintf("，说明：%s", exp.Description)
 		}
-		prompt += "\n"
+		recordLines = append(recordLines, line)
 	}
 
-	prompt += `\n请提供：
-1. 消费趋势分析
-2. 主要消费类别分析
-3. 消费习惯总结
-4. 优化建议和理财建议
-
-请用中文回答，内容要详细、专业、实用。`
+	prompt := renderPromptTemplate(loadPromptTemplate(PromptKeyAIAnalysis), map[string]string{
+		"date_range":     startTime + " 至 " + endTime,
+		"start_time":     startTime,
+		"end_time":       endTime,
+		"record_count":   strconv.Itoa(len(expenses)),
+		"total_amount":   fmt.Sprintf("%.2f", totalAmount),
+		"category_stats": strings.Join(categoryLines, "\n"),
+		"recent_records": strings.Join(recordLines, "\n"),
+	})
+	prompt += aiLanguageInstruction(lang)
 
 	return prompt
 }
 
+// promptAmount 提示词中的单笔金额：本位币记为"元"，其他币种附币种代码
+func promptAmount(amount float64, currency string) string {
+	if currency == "" || currency == baseCurrency() {
+		return fmt.Sprintf("%.2f 元", amount)
+	}
+	return fmt.Sprintf("%.2f %s", amount, currency)
+}
+
 // callAIModelStreamAndStore 调用AI模型API（流式输出），并在结束后保存分析历史（软删除支持）
-func (h *AIAnalysisHandler) callAIModelStreamAndStore(c *gin.Context, aiModel models.AIModel, userID uint, startDate, endDate, prompt string) error {
+func (h *AIAnalysisHandler) callAIModelStreamAndStore(c *gin.Context, aiModel models.AIModel, userID uint, startDate, endDate, lang, prompt string) error {
 	// 设置SSE响应头
 	c.Header("Content-Type", "text/event-stream")
 	c.Header("Cache-Control", "no-cache")
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no") // 禁用nginx缓冲
 
-	// 构建请求体（兼容OpenAI格式）
-	requestBody := map[string]interface{}{
-		"model": aiModel.Name, // 可以根据模型配置调整
-		"messages": []map[string]string{
-			{"role": "system", "content": "你是一个专业、友好、简洁的个人财务助手。请用中文回答。"},
-			{
-				"role":    "user",
-				"content": prompt,
-			},
-		},
-		"stream":      true,
-		"temperature": 0.3,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		return fmt.Errorf("构建请求失败: %w", err)
-	}
-
-	// 创建HTTP请求
-	req, err := http.NewRequest("POST", strings.TrimRight(aiModel.BaseURL, "/")+"/chat/completions", bytes.NewBuffer(jsonData))
+	// 模型并发已满时排队等待，并先推送 queued 帧供前端提示
+	queued := false
+	release, err := acquireAIModelSlot(c.Request.Context(), aiModel, aiQueueTimeout(), func(position int) {
+		queued = true
+		writeAnalysisSSE(c, sseAnalysisFrame{Type: "queued", Content: "AI服务繁忙，正在排队", Position: position})
+	})
 	if err != nil {
-		return fmt.Errorf("创建请求失败: %w", err)
+		// 只有排队后才会失败，此时响应已开始，错误以 error 帧下发
+		writeAnalysisSSE(c, sseAnalysisFrame{Type: "error", Content: err.Error()})
+		return nil
 	}
+	defer release()
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+aiModel.APIKey)
-
-	// 发送请求
-	client := &http.Client{Timeout: 300 * time.Second} // 5分钟超时
-	resp, err := client.Do(req)
+	// 请求AI模型（主模型失败时自动切换备用模型）
+	stream, err := openAIStreamWithFallback(c.Request.Context(), aiModel, []map[string]string{
+		{"role": "system", "content": aiSystemPromptFor(lang)},
+		{"role": "user", "content": prompt},
+	})
 	if err != nil {
-		return fmt.Errorf("请求AI服务失败: %w", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("AI服务返回错误: %d, %s", resp.StatusCode, string(body))
+		if queued {
+			writeAnalysisSSE(c, sseAnalysisFrame{Type: "error", Content: SafeErrorMessage(err, "AI分析失败")})
+			return nil
+		}
+		return err
 	}
+	defer stream.Close()
+	c.Header("X-AI-Model-ID", strconv.FormatUint(uint64(stream.Model.ID), 10))
 
 	// 使用带缓冲的读取器，逐行读取
-	reader := bufio.NewReader(resp.Body)
+	reader := stream.Reader
 
 	// 创建上下文用于检查客户端连接
 	ctx := c.Request.Context()
@@ -281,15 +314,16 @@ func (h *AIAnalysisHandler) callAIModelStreamAndStore(c *gin.Context, aiModel mo
 	// 存储历史（只有正常结束且客户端未断开才保存）
 	if finished {
 		his := models.AIAnalysisHistory{
-			AIModelID: aiModel.ID,
-			UserID:    userID,
-			StartDate: startDate,
-			EndDate:   endDate,
-			Result:    out.String(),
+			AIModelID:   aiModel.ID,
+			AIModelName: aiModel.Name,
+			UserID:      userID,
+			StartDate:   startDate,
+			EndDate:     endDate,
+			Result:      out.String(),
 		}
 		_ = database.DB.Create(&his).Error
-		// 确保前端一定收到 done
-		writeAnalysisSSE(c, sseAnalysisFrame{Type: "done"})
+		// 确保前端一定收到 done，并标注实际使用的模型
+		writeAnalysisSSE(c, sseAnalysisFrame{Type: "done", Model: stream.Model.Name})
 	}
 
 	return nil
@@ -299,7 +333,12 @@ func (h *AIAnalysisHandler) callAIModelStreamAndStore(c *gin.Context, aiModel mo
 func (h *AIAnalysisHandler) analyzeExpensesScoped(c *gin.Context, userID uint) {
 	var req AnalysisRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
+		return
+	}
+	lang, err := resolveAILanguage(req.Language)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
@@ -314,6 +353,10 @@ func (h *AIAnalysisHandler) analyzeExpensesScoped(c *gin.Context, userID uint) {
 		BadRequest(c, "时间格式错误")
 		return
 	}
+	if err := validateAnalysisRange(startTime, endTime); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	var expenses []ExpenseWithUser
 	if err := database.DB.Model(&models.Expense{}).
@@ -330,9 +373,17 @@ func (h *AIAnalysisHandler) analyzeExpensesScoped(c *gin.Context, userID uint) {
 		BadRequest(c, "该时间范围内没有消费记录")
 		return
 	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, "查询汇率失败")
+		return
+	}
+	if !checkAIQuotaApp(c, userID) {
+		return
+	}
 
-	prompt := h.buildAnalysisPrompt(expenses, req.StartTime, req.EndTime)
-	if err := h.callAIModelStreamAndStore(c, aiModel, userID, req.StartTime, req.EndTime, prompt); err != nil {
+	prompt := h.buildAnalysisPrompt(expenses, rates, req.StartTime, req.EndTime, lang)
+	if err := h.callAIModelStreamAndStore(c, aiModel, userID, req.StartTime, req.EndTime, lang, prompt); err != nil {
 		InternalError(c, SafeErrorMessage(err, "AI分析失败"))
 		return
 	}
@@ -381,12 +432,7 @@ func (h *AIAnalysisHandler) listAnalysisHistoryScoped(c *gin.Context, userID uin
 		InternalError(c, SafeErrorMessage(err, "查询失败"))
 		return
 	}
-	Success(c, gin.H{
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-		"list":      list,
-	})
+	Success(c, NewPageResponse(total, page, pageSize, list))
 }
 
 // processAnalysisLineToJSON 解析上游SSE行，向前端输出 JSON 帧；返回增量文本与是否结束
@@ -400,7 +446,7 @@ func (h *AIAnalysisHandler) processAnalysisLineToJSON(c *gin.Context, line []byt
 	}
 	data := bytes.TrimPrefix(line, []byte("data: "))
 	if string(data) == "[DONE]" {
-		writeAnalysisSSE(c, sseAnalysisFrame{Type: "done"})
+		// done 帧由调用方统一发送（附带实际使用的模型）
 		return "", true
 	}
 	var streamData map[string]interface{}
@@ -432,6 +478,9 @@ func (h *AIAnalysisHandler) processAnalysisLineToJSON(c *gin.Context, line []byt
 // @Param model_id query int true "AI模型ID"
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页条数，默认20，最大100"
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
 // @Success 200 {object} map[string]interface{} "获取成功，返回分页数据"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Router /admin/ai-analysis/history [get]
@@ -464,25 +513,27 @@ func (h *AIAnalysisHandler) ListAnalysisHistory(c *gin.Context) {
 		pageSize = 100
 	}
 
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
 	query := database.DB.Model(&models.AIAnalysisHistory{}).Where("ai_model_id = ?", modelID)
+	query = created.Filter(query, "created_at")
 	var total int64
 	query.Count(&total)
 
 	var list []models.AIAnalysisHistory
 	offset := (page - 1) * pageSize
-	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+	if err := query.Order(created.Order("created_at", "created_at DESC")).Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-			"list":      list,
-		},
+		"data":    NewPageResponse(total, page, pageSize, list),
 	})
 }
 
@@ -1,13 +1,16 @@
 package api
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
+	"finance/config"
 	"finance/database"
 	"finance/middleware"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -16,16 +19,17 @@ import (
 
 // AnalyzeExpensesApp AI分析（App端，流式）
 // @Summary AI分析（流式）
-// @Description 选择时间范围与AI模型，对当前用户在该时间范围内的消费记录进行AI分析，SSE流式返回 JSON 帧（delta/done/error）。分析结束后会保存到历史记录。
+// @Description 选择时间范围与AI模型，对当前用户在该时间范围内的消费记录进行AI分析，SSE流式返回 JSON 帧（queued/delta/done/error）；模型并发已满时先返回 queued 帧并排队等待。分析结束后会保存到历史记录。
 // @Tags AI
 // @Accept json
 // @Produce text/event-stream
 // @Security BearerAuth
 // @Param request body AnalysisRequest true "分析请求"
 // @Success 200 {string} string "SSE流：data: {\"type\":\"delta\",\"content\":\"...\"}"
-// @Failure 400 {object} Response "参数错误"
+// @Failure 400 {object} Response "参数错误或时间范围超过 ai.max_analysis_days"
 // @Failure 401 {object} Response "未授权"
 // @Failure 403 {object} Response "账号锁定或无权限"
+// @Failure 429 {object} Response{data=AIQuotaStatus} "今日 AI 调用次数已达上限"
 // @Router /api/v1/ai-analysis [post]
 func (h *AIAnalysisHandler) AnalyzeExpensesApp(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
@@ -84,9 +88,67 @@ func (h *AIAnalysisHandler) DeleteAnalysisHistoryApp(c *gin.Context) {
 	SuccessWithMessage(c, "删除成功", nil)
 }
 
+// ExportAnalysisToFeishuDocApp 将AI分析结果导出为飞书云文档（App端，仅可导出自己的）
+// @Summary 导出AI分析到飞书文档
+// @Description 以飞书应用身份创建云文档并写入分析结果，同时授予当前用户绑定的飞书账号完全访问权限，返回文档链接。未绑定飞书时返回 400
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "历史记录ID"
+// @Success 200 {object} Response "导出成功，data.url 为文档链接"
+// @Failure 400 {object} Response "未绑定飞书或未配置飞书应用"
+// @Failure 401 {object} Response "未授权"
+// @Failure 403 {object} Response "无权限"
+// @Failure 404 {object} Response "记录不存在"
+// @Router /api/v1/ai-analysis/history/{id}/to-feishu-doc [post]
+func (h *AIAnalysisHandler) ExportAnalysisToFeishuDocApp(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id64, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var his models.AIAnalysisHistory
+	if err := database.DB.First(&his, uint(id64)).Error; err != nil {
+		NotFound(c, "记录不存在")
+		return
+	}
+	if his.UserID != 0 && his.UserID != userID {
+		Error(c, http.StatusForbidden, "无权限")
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		Unauthorized(c, "用户不存在")
+		return
+	}
+	if user.FeishuOpenID == nil || *user.FeishuOpenID == "" {
+		BadRequest(c, "当前账号未绑定飞书，请先绑定飞书后再导出")
+		return
+	}
+
+	feishuCfg := config.GetConfig().Feishu
+	if feishuCfg.AppID == "" || feishuCfg.AppSecret == "" {
+		BadRequest(c, "系统未配置飞书应用，无法导出")
+		return
+	}
+
+	title := fmt.Sprintf("AI 账单分析 %s ~ %s", his.StartDate, his.EndDate)
+	docURL, err := service.GetFeishuDocClient(feishuCfg.AppID, feishuCfg.AppSecret).
+		CreateDocument(title, his.Result, feishuCfg.DocFolderToken, *user.FeishuOpenID)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "导出飞书文档失败"))
+		return
+	}
+	SuccessWithMessage(c, "导出成功", gin.H{"url": docURL})
+}
+
 // ChatStreamApp AI聊天（App端，流式）
 // @Summary AI聊天（流式）
 // @Description 选择AI模型，与AI进行对话，SSE流式返回 JSON 帧（delta/done/error）。结束后保存聊天记录。
+// @Description include_budget=true 时把当前用户本月各类别的预算、已花、剩余和建议每日可用额度作为额外的 system 消息附上；当月没有预算时不附带
+// @Description 传 conversation_id 时附带该会话最近的对话（轮数与估算 token 受 ai.chat_history_messages / ai.chat_history_max_tokens 限制），本轮记录归入该会话
 // @Tags AI
 // @Accept json
 // @Produce text/event-stream
@@ -95,19 +157,22 @@ func (h *AIAnalysisHandler) DeleteAnalysisHistoryApp(c *gin.Context) {
 // @Success 200 {string} string "SSE流：data: {\"type\":\"delta\",\"content\":\"...\"}"
 // @Failure 400 {object} Response "参数错误"
 // @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "AI模型或会话不存在"
+// @Failure 429 {object} Response{data=AIQuotaStatus} "今日 AI 调用次数已达上限"
 // @Router /api/v1/ai-chat [post]
 func (h *AIChatHandler) ChatStreamApp(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	h.chatStreamScoped(c, userID)
 }
 
-// ChatHistoryApp 获取聊天历史（App端，按模型分页）
+// ChatHistoryApp 获取聊天历史（App端，按模型或会话分页）
 // @Summary 获取AI聊天历史
-// @Description 获取当前用户的AI聊天历史记录，按 model_id 分页返回（软删除不返回）。
+// @Description 获取当前用户的AI聊天历史记录，按 model_id 或 conversation_id 分页返回（软删除不返回）。
 // @Tags AI
 // @Produce json
 // @Security BearerAuth
-// @Param model_id query int true "AI模型ID"
+// @Param model_id query int false "AI模型ID，不传 conversation_id 时必填"
+// @Param conversation_id query int false "会话ID，传入时返回该会话的记录（不限模型）"
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页条数，默认20，最大100"
 // @Success 200 {object} Response "获取成功"
@@ -155,7 +220,7 @@ func (h *AIChatHandler) DeleteChatHistoryApp(c *gin.Context) {
 
 // ListAIModelsApp 获取可用AI模型列表（App端）
 // @Summary 获取AI模型列表
-// @Description 获取系统可用的AI模型配置列表（不包含APIKey），用于前端选择模型。
+// @Description 获取系统可用的AI模型配置列表（不包含 API Key 及其脱敏值），用于前端选择模型。
 // @Tags AI
 // @Produce json
 // @Security BearerAuth
@@ -168,6 +233,10 @@ func (h *AIModelHandler) ListAIModelsApp(c *gin.Context) {
 		InternalError(c, SafeErrorMessage(err, "查询失败"))
 		return
 	}
+	// 脱敏后的密钥也只给后台看
+	for i := range list {
+		list[i].APIKeyHint = ""
+	}
 	Success(c, list)
 }
 
@@ -190,3 +259,25 @@ func parseDateRange(startStr, endStr string) (time.Time, time.Time, error) {
 	endTime = endTime.Add(24*time.Hour - time.Second)
 	return startTime, endTime, nil
 }
+
+// maxAnalysisDays AI 分析允许的最大跨度（天）；未加载配置时使用默认值
+func maxAnalysisDays() int {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.AI.MaxAnalysisDays > 0 {
+		return cfg.AI.MaxAnalysisDays
+	}
+	return config.DefaultAIMaxAnalysisDays
+}
+
+// validateAnalysisRange 校验 parseDateRange 得到的时间范围：结束不早于开始，跨度（含首尾）不超过上限。
+// 跨度过大时提示词超长且扫描大量记录，直接拒绝而不是截断
+func validateAnalysisRange(start, end time.Time) error {
+	if end.Before(start) {
+		return fmt.Errorf("结束日期不能早于开始日期")
+	}
+	// end 为结束当天 23:59:59，四舍五入到天即为含首尾的天数，跨夏令时也不受影响
+	days := int(end.Sub(start).Round(24*time.Hour) / (24 * time.Hour))
+	if limit := maxAnalysisDays(); days > limit {
+		return fmt.Errorf("时间范围不能超过 %d 天，请缩小范围", limit)
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func analysisHistoryRows(userID uint) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "ai_model_id", "user_id", "start_date", "end_date", "result", "created_at"}).
+		AddRow(5, 1, userID, "2024-01-01", "2024-01-31", "# 分析", time.Now())
+}
+
+func TestExportAnalysisToFeishuDocApp_NotBound(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `ai_analysis_histories`").
+		WillReturnRows(analysisHistoryRows(1))
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "feishu_open_id"}).AddRow(1, "alice", nil))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ai-analysis/history/:id/to-feishu-doc", NewAIAnalysisHandler().ExportAnalysisToFeishuDocApp)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/ai-analysis/history/5/to-feishu-doc", nil))
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "未绑定飞书")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportAnalysisToFeishuDocApp_Forbidden(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `ai_analysis_histories`").
+		WillReturnRows(analysisHistoryRows(2))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ai-analysis/history/:id/to-feishu-doc", NewAIAnalysisHandler().ExportAnalysisToFeishuDocApp)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/ai-analysis/history/5/to-feishu-doc", nil))
+
+	assert.Equal(t, 403, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateAnalysisRange(t *testing.T) {
+	config.GlobalConfig = nil
+
+	start, end, err := parseDateRange("2024-01-01", "2024-12-31")
+	require.NoError(t, err)
+	assert.NoError(t, validateAnalysisRange(start, end)) // 闰年整年 366 天
+
+	start, end, err = parseDateRange("2024-01-01", "2025-01-01")
+	require.NoError(t, err)
+	assert.Error(t, validateAnalysisRange(start, end))
+
+	start, end, err = parseDateRange("2024-03-10", "2024-03-10")
+	require.NoError(t, err)
+	assert.NoError(t, validateAnalysisRange(start, end))
+
+	start, end, err = parseDateRange("2024-03-10", "2024-03-09")
+	require.NoError(t, err)
+	assert.Error(t, validateAnalysisRange(start, end))
+
+	config.GlobalConfig = &config.Config{AI: config.AIConfig{MaxAnalysisDays: 31}}
+	defer func() { config.GlobalConfig = nil }()
+	start, end, _ = parseDateRange("2024-01-01", "2024-01-31")
+	assert.NoError(t, validateAnalysisRange(start, end))
+	start, end, _ = parseDateRange("2024-01-01", "2024-02-01")
+	assert.Error(t, validateAnalysisRange(start, end))
+}
+
+func TestAnalyzeExpensesApp_RangeTooLong(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	config.GlobalConfig = nil
+
+	mock.ExpectQuery("SELECT \\* FROM `ai_models`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "gpt"))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ai-analysis", NewAIAnalysisHandler().AnalyzeExpensesApp)
+
+	body := `{"model_id":1,"start_time":"2000-01-01","end_time":"2099-12-31"}`
+	req := httptest.NewRequest("POST", "/ai-analysis", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "不能超过 366 天")
+	// 超出跨度时不查询消费记录
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,37 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"finance/models"
+)
+
+// budgetLevelLabels 预算等级在 AI 上下文中的描述
+var budgetLevelLabels = map[string]string{
+	models.BudgetLevelNormal:   "正常",
+	models.BudgetLevelWarning:  "接近预算",
+	models.BudgetLevelExceeded: "已超支",
+}
+
+// buildBudgetContext 生成附加给 AI 的当月预算上下文；用户当月没有预算时返回空串
+func buildBudgetContext(userID uint, now time.Time) (string, error) {
+	month, days := monthRemainingDays(now)
+	items, err := loadBudgetAllowances(userID, month, days)
+	if err != nil || len(items) == 0 {
+		return "", err
+	}
+	return formatBudgetContext(month, days, items), nil
+}
+
+// formatBudgetContext 每个预算类别一行，金额保留两位小数
+func formatBudgetContext(month string, days int, items []BudgetDailyAllowance) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "以下是该用户 %s 的预算使用情况（本月含今天还剩 %d 天），回答与预算、还能花多少相关的问题时请以这些数据为准：\n", month, days)
+	for _, it := range items {
+		fmt.Fprintf(&b, "- %s：预算 %.2f，已花 %.2f，剩余 %.2f，使用率 %.2f%%（%s），建议每日可用 %.2f\n",
+			it.Category, it.LimitAmount, it.Spent, it.Remaining, it.UsagePercent, budgetLevelLabels[it.Level], it.DailyAllowance)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
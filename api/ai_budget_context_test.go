@@ -0,0 +1,40 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatBudgetContext(t *testing.T) {
+	items := []BudgetDailyAllowance{
+		{BudgetInfo: BudgetInfo{Category: "餐饮", LimitAmount: 2000, Spent: 1700, Remaining: 300, UsagePercent: 85, Level: models.BudgetLevelWarning}, DailyAllowance: 42.85},
+		{BudgetInfo: BudgetInfo{Category: "交通", LimitAmount: 500, Spent: 600, Remaining: -100, UsagePercent: 120, Level: models.BudgetLevelExceeded}, DailyAllowance: -14.29},
+	}
+	got := formatBudgetContext("2024-01", 7, items)
+
+	assert.Contains(t, got, "2024-01")
+	assert.Contains(t, got, "还剩 7 天")
+	assert.Contains(t, got, "- 餐饮：预算 2000.00，已花 1700.00，剩余 300.00，使用率 85.00%（接近预算），建议每日可用 42.85")
+	assert.Contains(t, got, "- 交通：预算 500.00，已花 600.00，剩余 -100.00，使用率 120.00%（已超支），建议每日可用 -14.29")
+	assert.NotContains(t, got, "\n\n")
+}
+
+func TestBuildBudgetContext_NoBudget(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `budgets`").
+		WithArgs(1, "2024-01").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	got, err := buildBudgetContext(1, time.Date(2024, 1, 25, 12, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.Empty(t, got)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
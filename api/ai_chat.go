@@ -1,10 +1,8 @@
 package api
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
 	"strconv"
@@ -20,6 +18,7 @@ import (
 type sseChatFrame struct {
 	Type    string `json:"type"`              // delta | done | error
 	Content string `json:"content,omitempty"` // delta内容或错误信息
+	Model   string `json:"model,omitempty"`   // done 帧：实际使用的模型（可能为备用模型）
 }
 
 func writeSSEJSON(c *gin.Context, v any) {
@@ -40,8 +39,13 @@ func NewAIChatHandler() *AIChatHandler {
 
 // AIChatRequest AI聊天请求
 type AIChatRequest struct {
-	ModelID uint   `json:"model_id" binding:"required"`
-	Message string `json:"message" binding:"required,min=1"`
+	ModelID  uint   `json:"model_id" binding:"required"`
+	Message  string `json:"message" binding:"required,min=1"`
+	Language string `json:"language" example:"zh"` // 回答语言：zh（默认）/en/ja
+	// 是否附带当前用户本月的预算使用情况作为上下文（仅 App 端），默认不附带以节省 token
+	IncludeBudget bool `json:"include_budget" example:"false"`
+	// 所属会话（仅 App 端）：传入时附带该会话最近的对话作为上下文，并把本轮记入会话；不传为单轮聊天
+	ConversationID uint `json:"conversation_id" example:"1"`
 }
 
 // ChatStream AI聊天（SSE流式返回），结束后写入聊天记录
@@ -54,11 +58,17 @@ type AIChatRequest struct {
 // @Success 200 {string} string "SSE流：data: {\"type\":\"delta\",\"content\":\"...\"}"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Failure 404 {object} map[string]interface{} "AI模型不存在"
+// @Failure 429 {object} map[string]interface{} "今日 AI 调用次数已达上限（非管理员）"
 // @Router /admin/ai-chat [post]
 func (h *AIChatHandler) ChatStream(c *gin.Context) {
 	var req AIChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
+		return
+	}
+	lang, err := resolveAILanguage(req.Language)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
@@ -68,6 +78,14 @@ func (h *AIChatHandler) ChatStream(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "AI模型不存在"})
 		return
 	}
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !checkAIQuotaAdmin(c, currentUser) {
+		return
+	}
 
 	// SSE响应头
 	c.Header("Content-Type", "text/event-stream")
@@ -75,51 +93,21 @@ func (h *AIChatHandler) ChatStream(c *gin.Context) {
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	// 构建请求（OpenAI兼容 chat/completions）
-	requestBody := map[string]interface{}{
-		"model": aiModel.Name,
-		"messages": []map[string]string{
-			{"role": "system", "content": "你是一个专业、友好、简洁的个人财务助手。请用中文回答。"},
-			{"role": "user", "content": req.Message},
-		},
-		"stream":      true,
-		"temperature": 0.3,
-	}
-
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: "构建请求失败"})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
-
-	httpReq, err := http.NewRequest("POST", strings.TrimRight(aiModel.BaseURL, "/")+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: "创建请求失败"})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+aiModel.APIKey)
-
-	client := &http.Client{Timeout: 300 * time.Second}
-	resp, err := client.Do(httpReq)
+	// 请求AI模型（主模型失败时自动切换备用模型）
+	stream, err := openAIStreamWithFallback(c.Request.Context(), aiModel, []map[string]string{
+		{"role": "system", "content": aiSystemPromptFor(lang)},
+		{"role": "user", "content": req.Message},
+	})
 	if err != nil {
 		writeSSEJSON(c, sseChatFrame{Type: "error", Content: SafeErrorMessage(err, "请求AI服务失败")})
 		writeSSEJSON(c, sseChatFrame{Type: "done"})
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: fmt.Sprintf("AI服务返回错误: %d %s", resp.StatusCode, string(body))})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
+	defer stream.Close()
+	c.Header("X-AI-Model-ID", strconv.FormatUint(uint64(stream.Model.ID), 10))
 
 	ctx := c.Request.Context()
-	reader := bufio.NewReader(resp.Body)
+	reader := stream.Reader
 	var aiText strings.Builder
 
 	finishedNormally := false
@@ -168,7 +156,7 @@ func (h *AIChatHandler) ChatStream(c *gin.Context) {
 				AIText:   aiText.String(),
 			}
 			_ = database.DB.Create(&msg).Error
-			writeSSEJSON(c, sseChatFrame{Type: "done"})
+			writeSSEJSON(c, sseChatFrame{Type: "done", Model: stream.Model.Name})
 			break
 		}
 
@@ -211,7 +199,7 @@ func (h *AIChatHandler) ChatStream(c *gin.Context) {
 			AIText:   aiText.String(),
 		}
 		_ = database.DB.Create(&msg).Error
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
+		writeSSEJSON(c, sseChatFrame{Type: "done", Model: stream.Model.Name})
 	}
 }
 
@@ -219,7 +207,12 @@ func (h *AIChatHandler) ChatStream(c *gin.Context) {
 func (h *AIChatHandler) chatStreamScoped(c *gin.Context, userID uint) {
 	var req AIChatRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
+		return
+	}
+	lang, err := resolveAILanguage(req.Language)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
@@ -229,6 +222,48 @@ func (h *AIChatHandler) chatStreamScoped(c *gin.Context, userID uint) {
 		NotFound(c, "AI模型不存在")
 		return
 	}
+	// 多轮对话：会话必须属于当前用户，在扣减配额前校验
+	var conv models.AIConversation
+	if req.ConversationID > 0 {
+		if err := database.DB.Where("id = ? AND user_id = ?", req.ConversationID, userID).First(&conv).Error; err != nil {
+			NotFound(c, "会话不存在")
+			return
+		}
+	}
+	if !checkAIQuotaApp(c, userID) {
+		return
+	}
+
+	messages := []map[string]string{{"role": "system", "content": aiSystemPromptFor(lang)}}
+	// 预算上下文：查询失败或当月无预算时不附带，照常对话
+	if req.IncludeBudget {
+		if budgetContext, err := buildBudgetContext(userID, time.Now()); err == nil && budgetContext != "" {
+			messages = append(messages, map[string]string{"role": "system", "content": budgetContext})
+		}
+	}
+	// 会话历史：查询失败时按单轮对话处理
+	if conv.ID > 0 {
+		if history, err := loadChatHistory(conv.ID, userID); err == nil {
+			messages = append(messages, history...)
+		}
+	}
+	messages = append(messages, map[string]string{"role": "user", "content": req.Message})
+
+	// 保存本轮聊天记录；属于会话时同时刷新会话
+	saveMessage := func(aiText string) {
+		msg := models.AIChatMessage{
+			ConversationID: conv.ID,
+			AIModelID:      req.ModelID,
+			AIModelName:    aiModel.Name,
+			UserID:         userID,
+			UserText:       req.Message,
+			AIText:         aiText,
+		}
+		_ = database.DB.Create(&msg).Error
+		if conv.ID > 0 {
+			touchConversation(&conv, req.Message)
+		}
+	}
 
 	// SSE响应头
 	c.Header("Content-Type", "text/event-stream")
@@ -236,49 +271,18 @@ func (h *AIChatHandler) chatStreamScoped(c *gin.Context, userID uint) {
 	c.Header("Connection", "keep-alive")
 	c.Header("X-Accel-Buffering", "no")
 
-	requestBody := map[string]interface{}{
-		"model": aiModel.Name,
-		"messages": []map[string]string{
-			{"role": "system", "content": "你是一个专业、友好、简洁的个人财务助手。请用中文回答。"},
-			{"role": "user", "content": req.Message},
-		},
-		"stream":      true,
-		"temperature": 0.3,
-	}
-	jsonData, err := json.Marshal(requestBody)
-	if err != nil {
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: "构建请求失败"})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
-
-	httpReq, err := http.NewRequest("POST", strings.TrimRight(aiModel.BaseURL, "/")+"/chat/completions", bytes.NewBuffer(jsonData))
-	if err != nil {
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: "创建请求失败"})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
-	httpReq.Header.Set("Content-Type", "application/json")
-	httpReq.Header.Set("Authorization", "Bearer "+aiModel.APIKey)
-
-	client := &http.Client{Timeout: 300 * time.Second}
-	resp, err := client.Do(httpReq)
+	// 请求AI模型（主模型失败时自动切换备用模型）
+	stream, err := openAIStreamWithFallback(c.Request.Context(), aiModel, messages)
 	if err != nil {
 		writeSSEJSON(c, sseChatFrame{Type: "error", Content: SafeErrorMessage(err, "请求AI服务失败")})
 		writeSSEJSON(c, sseChatFrame{Type: "done"})
 		return
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		writeSSEJSON(c, sseChatFrame{Type: "error", Content: fmt.Sprintf("AI服务返回错误: %d %s", resp.StatusCode, string(body))})
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
-		return
-	}
+	defer stream.Close()
+	c.Header("X-AI-Model-ID", strconv.FormatUint(uint64(stream.Model.ID), 10))
 
 	ctx := c.Request.Context()
-	reader := bufio.NewReader(resp.Body)
+	reader := stream.Reader
 	var aiText strings.Builder
 	finishedNormally := false
 
@@ -306,15 +310,8 @@ func (h *AIChatHandler) chatStreamScoped(c *gin.Context, userID uint) {
 		}
 		data := bytes.TrimPrefix(line, []byte("data: "))
 		if string(data) == "[DONE]" {
+			// 结束：统一在循环外落库并发送 done，避免重复保存
 			finishedNormally = true
-			msg := models.AIChatMessage{
-				AIModelID: req.ModelID,
-				UserID:    userID,
-				UserText:  req.Message,
-				AIText:    aiText.String(),
-			}
-			_ = database.DB.Create(&msg).Error
-			writeSSEJSON(c, sseChatFrame{Type: "done"})
 			break
 		}
 
@@ -340,30 +337,35 @@ func (h *AIChatHandler) chatStreamScoped(c *gin.Context, userID uint) {
 	}
 
 	if finishedNormally {
-		msg := models.AIChatMessage{
-			AIModelID: req.ModelID,
-			UserID:    userID,
-			UserText:  req.Message,
-			AIText:    aiText.String(),
-		}
-		_ = database.DB.Create(&msg).Error
-		writeSSEJSON(c, sseChatFrame{Type: "done"})
+		saveMessage(aiText.String())
+		writeSSEJSON(c, sseChatFrame{Type: "done", Model: stream.Model.Name})
 	}
 }
 
-// chatHistoryScoped App端：按用户+模型分页返回（Response 结构）
+// chatHistoryScoped App端：按用户+模型（或会话）分页返回（Response 结构）
 func (h *AIChatHandler) chatHistoryScoped(c *gin.Context, userID uint, requireUser bool) {
-	modelIDStr := c.Query("model_id")
-	if modelIDStr == "" {
-		BadRequest(c, "缺少 model_id")
-		return
-	}
-	modelID64, err := strconv.ParseUint(modelIDStr, 10, 32)
-	if err != nil {
-		BadRequest(c, "无效的 model_id")
-		return
+	query := database.DB.Model(&models.AIChatMessage{})
+	if convStr := c.Query("conversation_id"); convStr != "" {
+		// 按会话查询时不需要 model_id，会话内可能切换过模型
+		convID, err := strconv.ParseUint(convStr, 10, 32)
+		if err != nil {
+			BadRequest(c, "无效的 conversation_id")
+			return
+		}
+		query = query.Where("conversation_id = ?", uint(convID))
+	} else {
+		modelIDStr := c.Query("model_id")
+		if modelIDStr == "" {
+			BadRequest(c, "缺少 model_id")
+			return
+		}
+		modelID64, err := strconv.ParseUint(modelIDStr, 10, 32)
+		if err != nil {
+			BadRequest(c, "无效的 model_id")
+			return
+		}
+		query = query.Where("ai_model_id = ?", uint(modelID64))
 	}
-	modelID := uint(modelID64)
 
 	page := 1
 	pageSize := 20
@@ -381,7 +383,6 @@ func (h *AIChatHandler) chatHistoryScoped(c *gin.Context, userID uint, requireUs
 		pageSize = 100
 	}
 
-	query := database.DB.Model(&models.AIChatMessage{}).Where("ai_model_id = ?", modelID)
 	if requireUser {
 		query = query.Where("user_id = ?", userID)
 	}
@@ -394,12 +395,7 @@ func (h *AIChatHandler) chatHistoryScoped(c *gin.Context, userID uint, requireUs
 		InternalError(c, SafeErrorMessage(err, "查询失败"))
 		return
 	}
-	Success(c, gin.H{
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-		"list":      list,
-	})
+	Success(c, NewPageResponse(total, page, pageSize, list))
 }
 
 // ChatHistory 获取聊天历史（按模型分页）
@@ -410,6 +406,9 @@ func (h *AIChatHandler) chatHistoryScoped(c *gin.Context, userID uint, requireUs
 // @Param model_id query int true "AI模型ID"
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页条数，默认20，最大100"
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
 // @Success 200 {object} map[string]interface{} "获取成功，返回分页数据"
 // @Failure 400 {object} map[string]interface{} "参数错误"
 // @Router /admin/ai-chat/history [get]
@@ -456,25 +455,27 @@ func (h *AIChatHandler) ChatHistory(c *gin.Context) {
 		pageSize = 100
 	}
 
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
 	query := database.DB.Model(&models.AIChatMessage{}).Where("ai_model_id = ?", modelID)
+	query = created.Filter(query, "created_at")
 	var total int64
 	query.Count(&total)
 
 	var list []models.AIChatMessage
 	offset := (page - 1) * pageSize
-	if err := query.Order("created_at DESC").Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
+	if err := query.Order(created.Order("created_at", "created_at DESC")).Offset(offset).Limit(pageSize).Find(&list).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-			"list":      list,
-		},
+		"data":    NewPageResponse(total, page, pageSize, list),
 	})
 }
 
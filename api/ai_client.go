@@ -0,0 +1,320 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+)
+
+// aiSystemPromptBase 分析/聊天共用的内置系统提示词（不含语言指令，见 aiSystemPromptFor），可在后台覆盖
+const aiSystemPromptBase = "你是一个专业、友好、简洁的个人财务助手。"
+
+// parseProxyURL 校验并解析代理地址：仅支持 http/https/socks5，必须带主机和端口，不能带路径
+func parseProxyURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return nil, errors.New("代理地址格式错误")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, errors.New("代理地址仅支持 http、https、socks5 协议")
+	}
+	if u.Hostname() == "" || u.Port() == "" {
+		return nil, errors.New("代理地址需包含主机和端口，如 http://127.0.0.1:7890")
+	}
+	if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return nil, errors.New("代理地址不能包含路径或参数")
+	}
+	return u, nil
+}
+
+// validateProxyURL 校验代理地址，空字符串表示不使用代理
+func validateProxyURL(raw string) error {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	_, err := parseProxyURL(raw)
+	return err
+}
+
+// newAIHTTPClient 构建访问 AI 服务的 http.Client：模型配置了代理时走模型代理，否则走全局 ai.proxy_url，
+// 都未配置时与默认客户端一致（遵循 HTTP_PROXY 等环境变量）
+func newAIHTTPClient(aiModel models.AIModel, timeout time.Duration) (*http.Client, error) {
+	proxy := strings.TrimSpace(aiModel.ProxyURL)
+	if proxy == "" && config.GlobalConfig != nil {
+		proxy = strings.TrimSpace(config.GlobalConfig.AI.ProxyURL)
+	}
+
+	// 建连（含 TLS 握手）超时：上游不可达时尽快失败，而不是等满整体超时
+	connectTimeout := aiUpstreamSettings().ConnectTimeout
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = (&net.Dialer{Timeout: connectTimeout, KeepAlive: 30 * time.Second}).DialContext
+	transport.TLSHandshakeTimeout = connectTimeout
+	if proxy != "" {
+		u, err := parseProxyURL(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("AI 代理配置无效: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(u)
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}, nil
+}
+
+// aiUpstreamPolicy AI 上游请求的超时与重试设置
+type aiUpstreamPolicy struct {
+	ConnectTimeout   time.Duration // 建连超时
+	FirstByteTimeout time.Duration // 发出请求到收到首帧的最长等待
+	MaxRetries       int           // 429/5xx 时的最多重试次数
+	RetryBackoff     time.Duration // 首次重试前的等待，之后每次翻倍
+}
+
+// maxAIRetryWait 单次重试等待的上限（含上游 Retry-After 要求的等待）
+const maxAIRetryWait = 30 * time.Second
+
+// aiUpstreamSettings 读取 ai.connect_timeout_seconds / first_byte_timeout_seconds / max_retries / retry_backoff_ms
+func aiUpstreamSettings() aiUpstreamPolicy {
+	p := aiUpstreamPolicy{
+		ConnectTimeout:   time.Duration(config.DefaultAIConnectTimeoutSeconds) * time.Second,
+		FirstByteTimeout: time.Duration(config.DefaultAIFirstByteTimeoutSeconds) * time.Second,
+		MaxRetries:       config.DefaultAIMaxRetries,
+		RetryBackoff:     time.Duration(config.DefaultAIRetryBackoffMs) * time.Millisecond,
+	}
+	if config.GlobalConfig == nil {
+		return p
+	}
+	ai := config.GlobalConfig.AI
+	if ai.ConnectTimeoutSeconds > 0 {
+		p.ConnectTimeout = time.Duration(ai.ConnectTimeoutSeconds) * time.Second
+	}
+	if ai.FirstByteTimeoutSeconds > 0 {
+		p.FirstByteTimeout = time.Duration(ai.FirstByteTimeoutSeconds) * time.Second
+	}
+	// 0 表示不重试
+	p.MaxRetries = ai.MaxRetries
+	if p.MaxRetries < 0 {
+		p.MaxRetries = 0
+	}
+	if ai.RetryBackoffMs > 0 {
+		p.RetryBackoff = time.Duration(ai.RetryBackoffMs) * time.Millisecond
+	}
+	return p
+}
+
+// aiRetryableError 可重试的上游错误（429/5xx）；RetryAfter 为上游通过 Retry-After 要求的等待时间
+type aiRetryableError struct {
+	err        error
+	RetryAfter time.Duration
+}
+
+func (e *aiRetryableError) Error() string { return e.err.Error() }
+func (e *aiRetryableError) Unwrap() error { return e.err }
+
+// aiStream 已建立的上游流式连接（OpenAI 兼容 chat/completions）
+type aiStream struct {
+	Model    models.AIModel // 实际使用的模型（主模型失败时为备用模型）
+	FellBack bool           // 是否切换到了备用模型
+	Reader   *bufio.Reader
+	body     io.ReadCloser
+	cancel   context.CancelFunc
+}
+
+// Close 关闭上游连接
+func (s *aiStream) Close() error {
+	s.cancel()
+	return s.body.Close()
+}
+
+// aiChatRequestBody 按模型配置构建 OpenAI 兼容的流式请求体；未配置 max_tokens 时不传该字段
+func aiChatRequestBody(aiModel models.AIModel, messages []map[string]string) map[string]interface{} {
+	body := map[string]interface{}{
+		"model":       aiModel.RequestModelName(),
+		"messages":    messages,
+		"stream":      true,
+		"temperature": aiModel.RequestTemperature(),
+	}
+	if aiModel.MaxTokens > 0 {
+		body["max_tokens"] = aiModel.MaxTokens
+	}
+	return body
+}
+
+// openAIStream 向指定模型发起流式请求，并等待首帧到达；建连失败、非 200 或首帧读取失败都返回错误。
+// 上游返回 429/5xx 时按退避重试（见 aiUpstreamSettings）；ctx 取消（如客户端断开）时立即中止上游请求
+func openAIStream(ctx context.Context, aiModel models.AIModel, messages []map[string]string) (*aiStream, error) {
+	jsonData, err := json.Marshal(aiChatRequestBody(aiModel, messages))
+	if err != nil {
+		return nil, fmt.Errorf("构建请求失败: %w", err)
+	}
+
+	client, err := newAIHTTPClient(aiModel, 300*time.Second) // 5分钟超时
+	if err != nil {
+		return nil, err
+	}
+
+	policy := aiUpstreamSettings()
+	for attempt := 0; ; attempt++ {
+		stream, err := openAIStreamOnce(ctx, client, aiModel, jsonData, policy.FirstByteTimeout)
+		if err == nil {
+			return stream, nil
+		}
+		var retryable *aiRetryableError
+		if !errors.As(err, &retryable) || attempt >= policy.MaxRetries {
+			return nil, err
+		}
+
+		wait := policy.RetryBackoff << attempt
+		if retryable.RetryAfter > wait {
+			wait = retryable.RetryAfter
+		}
+		if wait > maxAIRetryWait {
+			wait = maxAIRetryWait
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, err
+		case <-timer.C:
+		}
+	}
+}
+
+// openAIStreamOnce 发起一次流式请求；首帧超过 firstByteTimeout 未到达时中止请求
+func openAIStreamOnce(ctx context.Context, client *http.Client, aiModel models.AIModel, jsonData []byte, firstByteTimeout time.Duration) (*aiStream, error) {
+	// API 密钥加密存储，只在发起上游请求时解密
+	apiKey, err := service.DecryptSecret(aiModel.APIKey)
+	if err != nil {
+		return nil, err
+	}
+	reqCtx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(reqCtx, "POST", strings.TrimRight(aiModel.BaseURL, "/")+"/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+apiKey)
+
+	// 首帧期限：到期时取消请求，阻塞中的 Do/Peek 随之返回
+	deadline := time.AfterFunc(firstByteTimeout, cancel)
+	timedOut := func() bool { return !deadline.Stop() && ctx.Err() == nil }
+
+	resp, err := client.Do(req)
+	if err != nil {
+		expired := timedOut()
+		cancel()
+		if expired {
+			return nil, fmt.Errorf("AI服务 %d 秒内无响应", int(firstByteTimeout.Seconds()))
+		}
+		return nil, fmt.Errorf("请求AI服务失败: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		deadline.Stop()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		resp.Body.Close()
+		cancel()
+		err := fmt.Errorf("AI服务返回错误: %d, %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, &aiRetryableError{err: err, RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
+		}
+		return nil, err
+	}
+
+	// 等待首帧：连接成功但首帧就失败的情况也视为模型不可用
+	reader := bufio.NewReader(resp.Body)
+	if _, err := reader.Peek(1); err != nil {
+		expired := timedOut()
+		resp.Body.Close()
+		cancel()
+		if expired {
+			return nil, fmt.Errorf("AI服务 %d 秒内无响应", int(firstByteTimeout.Seconds()))
+		}
+		return nil, fmt.Errorf("读取AI首帧失败: %w", err)
+	}
+	if timedOut() {
+		resp.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("AI服务 %d 秒内无响应", int(firstByteTimeout.Seconds()))
+	}
+
+	return &aiStream{Model: aiModel, Reader: reader, body: resp.Body, cancel: cancel}, nil
+}
+
+// parseRetryAfter 解析以秒为单位的 Retry-After，无法解析时返回 0
+func parseRetryAfter(v string) time.Duration {
+	seconds, err := strconv.Atoi(strings.TrimSpace(v))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// openAIStreamWithFallback 主模型建连或首帧失败时，切换到 fallback 模型重试一次。
+// 只跟随一跳，不会沿备用链继续切换，避免环路或无限重试；ctx 已取消时不再切换
+func openAIStreamWithFallback(ctx context.Context, aiModel models.AIModel, messages []map[string]string) (*aiStream, error) {
+	stream, err := openAIStream(ctx, aiModel, messages)
+	if err == nil {
+		return stream, nil
+	}
+	if ctx.Err() != nil || aiModel.FallbackModelID == nil || *aiModel.FallbackModelID == aiModel.ID {
+		return nil, err
+	}
+
+	var fallback models.AIModel
+	if e := database.DB.First(&fallback, *aiModel.FallbackModelID).Error; e != nil {
+		return nil, err
+	}
+
+	stream, fbErr := openAIStream(ctx, fallback, messages)
+	if fbErr != nil {
+		return nil, fmt.Errorf("%w；备用模型 %s 也失败: %v", err, fallback.Name, fbErr)
+	}
+	stream.FellBack = true
+	return stream, nil
+}
+
+// validateFallbackModel 校验备用模型：必须存在、不能指向自己、沿备用链不能回到自己（成环）
+func validateFallbackModel(selfID, fallbackID uint) error {
+	if fallbackID == selfID {
+		return fmt.Errorf("备用模型不能是自己")
+	}
+
+	visited := map[uint]bool{selfID: true}
+	next := fallbackID
+	for next != 0 {
+		if visited[next] {
+			return fmt.Errorf("备用模型形成循环")
+		}
+		visited[next] = true
+
+		var m models.AIModel
+		if err := database.DB.First(&m, next).Error; err != nil {
+			if next == fallbackID {
+				return fmt.Errorf("备用模型不存在")
+			}
+			return nil
+		}
+		if m.FallbackModelID == nil {
+			return nil
+		}
+		next = *m.FallbackModelID
+	}
+	return nil
+}
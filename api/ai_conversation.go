@@ -0,0 +1,177 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// conversationTitleRunes 自动生成会话标题时截取首条提问的字数
+const conversationTitleRunes = 20
+
+// aiChatHistoryLimits 多轮对话附带历史的轮数与估算 token 上限（ai.chat_history_messages / ai.chat_history_max_tokens）
+func aiChatHistoryLimits() (int, int) {
+	messages, tokens := config.DefaultAIChatHistoryMessages, config.DefaultAIChatHistoryMaxTokens
+	if config.GlobalConfig != nil {
+		if config.GlobalConfig.AI.ChatHistoryMessages > 0 {
+			messages = config.GlobalConfig.AI.ChatHistoryMessages
+		}
+		if config.GlobalConfig.AI.ChatHistoryMaxTokens > 0 {
+			tokens = config.GlobalConfig.AI.ChatHistoryMaxTokens
+		}
+	}
+	return messages, tokens
+}
+
+// estimateTokens 粗略估算文本的 token 数：ASCII 约 4 个字符 1 个 token，其他字符（中文等）按 1 字 1 个 token
+func estimateTokens(s string) int {
+	ascii, other := 0, 0
+	for _, r := range s {
+		if r < utf8.RuneSelf {
+			ascii++
+		} else {
+			other++
+		}
+	}
+	return other + (ascii+3)/4
+}
+
+// chatHistoryMessages 把会话历史（按时间倒序）转换为上游 messages，从最近一轮往前累加，
+// 估算 token 超过 maxTokens 时丢弃更早的轮次；返回按时间正序的 user/assistant 消息
+func chatHistoryMessages(history []models.AIChatMessage, maxTokens int) []map[string]string {
+	kept := 0
+	used := 0
+	for _, m := range history {
+		cost := estimateTokens(m.UserText) + estimateTokens(m.AIText)
+		if used+cost > maxTokens {
+			break
+		}
+		used += cost
+		kept++
+	}
+
+	messages := make([]map[string]string, 0, kept*2)
+	for i := kept - 1; i >= 0; i-- {
+		messages = append(messages,
+			map[string]string{"role": "user", "content": history[i].UserText},
+			map[string]string{"role": "assistant", "content": history[i].AIText},
+		)
+	}
+	return messages
+}
+
+// loadChatHistory 读取会话最近的聊天记录并按上限裁剪为上游 messages
+func loadChatHistory(conversationID, userID uint) ([]map[string]string, error) {
+	limit, maxTokens := aiChatHistoryLimits()
+	var history []models.AIChatMessage
+	if err := database.DB.Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Order("id DESC").Limit(limit).Find(&history).Error; err != nil {
+		return nil, err
+	}
+	return chatHistoryMessages(history, maxTokens), nil
+}
+
+// touchConversation 对话完成后刷新会话的最近对话时间；尚无标题时用本次提问生成标题
+func touchConversation(conv *models.AIConversation, userText string) {
+	updates := map[string]interface{}{"updated_at": time.Now()}
+	if conv.Title == "" {
+		updates["title"] = conversationTitle(userText)
+	}
+	database.DB.Model(conv).Updates(updates)
+}
+
+// conversationTitle 取提问的前 conversationTitleRunes 个字作为标题（去掉换行）
+func conversationTitle(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if utf8.RuneCountInString(text) <= conversationTitleRunes {
+		return text
+	}
+	return string([]rune(text)[:conversationTitleRunes]) + "…"
+}
+
+// CreateConversationRequest 新建会话请求
+type CreateConversationRequest struct {
+	// Title 会话标题，不传时取首条提问的前 20 个字
+	Title string `json:"title" binding:"omitempty,max=100" example:"三月消费复盘"`
+}
+
+// CreateConversationApp 新建AI对话会话（App端）
+// @Summary 新建AI对话会话
+// @Description 新建一个多轮对话会话，之后聊天时传 conversation_id，AI 会参考该会话最近的对话内容作答
+// @Tags AI
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateConversationRequest false "会话信息"
+// @Success 200 {object} Response{data=models.AIConversation} "创建成功"
+// @Failure 400 {object} Response "参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/ai-chat/conversations [post]
+func (h *AIChatHandler) CreateConversationApp(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var req CreateConversationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			BindError(c, err)
+			return
+		}
+	}
+
+	conv := models.AIConversation{UserID: userID, Title: strings.TrimSpace(req.Title)}
+	if err := database.DB.Create(&conv).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建会话失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", conv)
+}
+
+// ListConversationsApp 获取AI对话会话列表（App端）
+// @Summary 获取AI对话会话列表
+// @Description 获取当前用户的多轮对话会话，按最近对话时间倒序分页返回；会话内的聊天记录通过 /api/v1/ai-chat/history?conversation_id= 查询
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Success 200 {object} Response "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/ai-chat/conversations [get]
+func (h *AIChatHandler) ListConversationsApp(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		if v, e := strconv.Atoi(p); e == nil && v > 0 {
+			page = v
+		}
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		if v, e := strconv.Atoi(ps); e == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	query := database.DB.Model(&models.AIConversation{}).Where("user_id = ?", userID)
+	var total int64
+	query.Count(&total)
+
+	var list []models.AIConversation
+	if err := query.Order("updated_at DESC, id DESC").Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, NewPageResponse(total, page, pageSize, list))
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimateTokens(t *testing.T) {
+	assert.Equal(t, 0, estimateTokens(""))
+	assert.Equal(t, 2, estimateTokens("hello")) // 5 个 ASCII 字符向上取整
+	assert.Equal(t, 4, estimateTokens("本月消费"))
+	assert.Equal(t, 3, estimateTokens("午餐 ok"))
+}
+
+func TestChatHistoryMessages(t *testing.T) {
+	// 按时间倒序传入：最近一轮在前
+	history := []models.AIChatMessage{
+		{UserText: "第三问", AIText: "第三答"},
+		{UserText: "第二问", AIText: "第二答"},
+		{UserText: strings.Repeat("长", 100), AIText: "第一答"},
+	}
+
+	messages := chatHistoryMessages(history, 1000)
+	require.Len(t, messages, 6)
+	assert.Equal(t, "user", messages[0]["role"])
+	assert.Equal(t, strings.Repeat("长", 100), messages[0]["content"])
+	assert.Equal(t, "assistant", messages[5]["role"])
+	assert.Equal(t, "第三答", messages[5]["content"])
+
+	// 超出 token 上限时丢弃最早的轮次
+	messages = chatHistoryMessages(history, 20)
+	require.Len(t, messages, 4)
+	assert.Equal(t, "第二问", messages[0]["content"])
+	assert.Equal(t, "第三答", messages[3]["content"])
+
+	assert.Empty(t, chatHistoryMessages(history, 5))
+}
+
+func TestConversationTitle(t *testing.T) {
+	assert.Equal(t, "本月花了 多少", conversationTitle("  本月花了\n多少 "))
+	assert.Equal(t, strings.Repeat("字", 20)+"…", conversationTitle(strings.Repeat("字", 30)))
+}
+
+func TestAIChatHandler_CreateConversationApp(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `ai_conversations`").
+		WithArgs(1, "三月复盘", sqlmock.AnyArg(), sqlmock.AnyArg(), nil).
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ai-chat/conversations", NewAIChatHandler().CreateConversationApp)
+
+	req := httptest.NewRequest("POST", "/ai-chat/conversations", bytes.NewBufferString(`{"title":" 三月复盘 "}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data models.AIConversation `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, uint(5), resp.Data.ID)
+	assert.Equal(t, "三月复盘", resp.Data.Title)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAIChatHandler_ListConversationsApp(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `ai_conversations` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `ai_conversations` WHERE user_id = \\? .* ORDER BY updated_at DESC, id DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(5, 1, "三月复盘", time.Now(), time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/ai-chat/conversations", NewAIChatHandler().ListConversationsApp)
+
+	req := httptest.NewRequest("GET", "/ai-chat/conversations", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"title":"三月复盘"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAIChatHandler_ChatStreamApp_ConversationNotFound(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `ai_models`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "base_url", "api_key"}).AddRow(1, "m", "http://ai.invalid", "k"))
+	// 别人的会话按不存在处理，不扣配额也不请求模型
+	mock.ExpectQuery("SELECT \\* FROM `ai_conversations` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(9, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ai-chat", NewAIChatHandler().ChatStreamApp)
+
+	req := httptest.NewRequest("POST", "/ai-chat", bytes.NewBufferString(`{"model_id":1,"message":"你好","conversation_id":9}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
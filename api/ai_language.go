@@ -0,0 +1,47 @@
+package api
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// defaultAILanguage AI 回答的默认语言
+const defaultAILanguage = "zh"
+
+// aiLanguageInstructions AI 回答语言 → 提示词中的语言指令；支持新语言只需在此添加一项
+var aiLanguageInstructions = map[string]string{
+	"zh": "请用中文回答。",
+	"en": "Please answer in English.",
+	"ja": "日本語で回答してください。",
+}
+
+// resolveAILanguage 规范化请求中的 language（忽略大小写），空值使用默认语言，不支持时返回错误
+func resolveAILanguage(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return defaultAILanguage, nil
+	}
+	if _, ok := aiLanguageInstructions[lang]; !ok {
+		supported := make([]string, 0, len(aiLanguageInstructions))
+		for k := range aiLanguageInstructions {
+			supported = append(supported, k)
+		}
+		sort.Strings(supported)
+		return "", fmt.Errorf("不支持的语言 %s，可选：%s", lang, strings.Join(supported, "/"))
+	}
+	return lang, nil
+}
+
+// aiLanguageInstruction 返回语言指令，未知语言按默认语言处理
+func aiLanguageInstruction(lang string) string {
+	if s, ok := aiLanguageInstructions[lang]; ok {
+		return s
+	}
+	return aiLanguageInstructions[defaultAILanguage]
+}
+
+// aiSystemPromptFor 分析/聊天共用的系统提示词（带回答语言指令），提示词可在后台自定义
+func aiSystemPromptFor(lang string) string {
+	return loadPromptTemplate(PromptKeyAISystem) + aiLanguageInstruction(lang)
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveAILanguage(t *testing.T) {
+	lang, err := resolveAILanguage("")
+	require.NoError(t, err)
+	assert.Equal(t, "zh", lang)
+
+	lang, err = resolveAILanguage(" EN ")
+	require.NoError(t, err)
+	assert.Equal(t, "en", lang)
+
+	_, err = resolveAILanguage("xx")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "en/ja/zh")
+}
+
+func TestAISystemPromptFor(t *testing.T) {
+	assert.True(t, strings.HasSuffix(aiSystemPromptFor("zh"), "请用中文回答。"))
+	assert.True(t, strings.HasSuffix(aiSystemPromptFor("en"), "Please answer in English."))
+	assert.Equal(t, aiSystemPromptFor("zh"), aiSystemPromptFor("unknown"))
+}
+
+func TestBuildAnalysisPrompt_Language(t *testing.T) {
+	expenses := []ExpenseWithUser{{Expense: models.Expense{Amount: 30, Category: "餐饮", ExpenseTime: time.Now()}, Username: "alice"}}
+	h := NewAIAnalysisHandler()
+	assert.True(t, strings.HasSuffix(h.buildAnalysisPrompt(expenses, exchangeRates{"CNY": 1}, "2024-01-01", "2024-01-31", "en"), "Please answer in English."))
+	assert.NotContains(t, h.buildAnalysisPrompt(expenses, exchangeRates{"CNY": 1}, "2024-01-01", "2024-01-31", "en"), "请用中文回答")
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,8 +11,10 @@ import (
 
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // AIModelHandler AI模型管理处理器
@@ -27,6 +30,18 @@ type CreateAIModelRequest struct {
 	Name    string `json:"name" binding:"required,min=1,max=100" example:"OpenAI GPT-4"`
 	BaseURL string `json:"base_url" binding:"required,url" example:"https://api.openai.com/v1"`
 	APIKey  string `json:"api_key" binding:"required,min=1" example:"sk-..."`
+	// FallbackModelID 备用模型ID，主模型建连或首帧失败时自动切换
+	FallbackModelID *uint `json:"fallback_model_id" example:"2"`
+	// ProxyURL 代理地址（http/https/socks5），为空时使用全局 ai.proxy_url
+	ProxyURL string `json:"proxy_url" example:"http://127.0.0.1:7890"`
+	// MaxConcurrent 同时进行的上游请求上限，超出的请求排队；0 表示使用全局 ai.max_concurrent_per_model
+	MaxConcurrent int `json:"max_concurrent" binding:"omitempty,min=0,max=100" example:"3"`
+	// ModelName 上游请求中的 model 参数（如 gpt-4o），为空时使用 Name
+	ModelName string `json:"model_name" binding:"omitempty,max=100" example:"gpt-4o"`
+	// Temperature 采样温度 0~2，不传时使用 0.3
+	Temperature *float64 `json:"temperature" binding:"omitempty,min=0,max=2" example:"0.3"`
+	// MaxTokens 单次回复的最大 token 数，0 表示不限制
+	MaxTokens int `json:"max_tokens" binding:"omitempty,min=0,max=131072" example:"2048"`
 }
 
 // UpdateAIModelRequest 更新AI模型请求
@@ -34,6 +49,20 @@ type UpdateAIModelRequest struct {
 	Name    string `json:"name" binding:"omitempty,min=1,max=100"`
 	BaseURL string `json:"base_url" binding:"omitempty,url"`
 	APIKey  string `json:"api_key" binding:"omitempty,min=1"`
+	// FallbackModelID 备用模型ID，传 0 表示清除备用模型
+	FallbackModelID *uint `json:"fallback_model_id"`
+	// ProxyURL 代理地址，传空字符串表示清除；不传则不修改
+	ProxyURL *string `json:"proxy_url"`
+	// MaxConcurrent 并发上限，传 0 表示恢复使用全局配置；不传则不修改
+	MaxConcurrent *int `json:"max_concurrent" binding:"omitempty,min=0,max=100"`
+	// ModelName 上游模型标识，传空字符串表示恢复使用 Name；不传则不修改
+	ModelName *string `json:"model_name" binding:"omitempty,max=100"`
+	// Temperature 采样温度 0~2；不传则不修改
+	Temperature *float64 `json:"temperature" binding:"omitempty,min=0,max=2"`
+	// ResetTemperature 为 true 时清除温度配置，恢复默认 0.3
+	ResetTemperature bool `json:"reset_temperature"`
+	// MaxTokens 最大 token 数，传 0 表示不限制；不传则不修改
+	MaxTokens *int `json:"max_tokens" binding:"omitempty,min=0,max=131072"`
 }
 
 // CreateAIModel 创建AI模型配置
@@ -60,7 +89,7 @@ func (h *AIModelHandler) CreateAIModel(c *gin.Context) {
 
 	var req CreateAIModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -71,21 +100,50 @@ func (h *AIModelHandler) CreateAIModel(c *gin.Context) {
 		return
 	}
 
+	if err := validateProxyURL(req.ProxyURL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	// 校验备用模型（新模型尚无ID，只需确认存在且不成环）
+	if req.FallbackModelID != nil && *req.FallbackModelID > 0 {
+		if err := validateFallbackModel(0, *req.FallbackModelID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+	} else {
+		req.FallbackModelID = nil
+	}
+
+	encryptedKey, keyHint, err := service.SealAIModelKey(req.APIKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "加密 API 密钥失败"})
+		return
+	}
+
 	// 新模型排在最后
 	var maxOrder int
 	database.DB.Model(&models.AIModel{}).Select("COALESCE(MAX(sort_order), -1)").Scan(&maxOrder)
 
 	aiModel := models.AIModel{
-		Name:      req.Name,
-		BaseURL:   req.BaseURL,
-		APIKey:    req.APIKey,
-		SortOrder: maxOrder + 1,
+		Name:            req.Name,
+		BaseURL:         req.BaseURL,
+		APIKey:          encryptedKey,
+		APIKeyHint:      keyHint,
+		SortOrder:       maxOrder + 1,
+		FallbackModelID: req.FallbackModelID,
+		ProxyURL:        strings.TrimSpace(req.ProxyURL),
+		MaxConcurrent:   req.MaxConcurrent,
+		ModelName:       strings.TrimSpace(req.ModelName),
+		Temperature:     req.Temperature,
+		MaxTokens:       req.MaxTokens,
 	}
 
 	if err := database.DB.Create(&aiModel).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "创建失败")})
 		return
 	}
+	recordAuditLog(c, user, models.AuditActionAIModelKeyChange, models.AuditTargetAIModel, aiModel.ID, models.JSONMap{"name": aiModel.Name, "created": true})
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -96,7 +154,7 @@ func (h *AIModelHandler) CreateAIModel(c *gin.Context) {
 
 // GetAllAIModels 获取所有AI模型列表
 // @Summary 获取AI模型列表
-// @Description 获取系统中所有AI模型配置列表（不包含APIKey），仅管理员
+// @Description 获取系统中所有AI模型配置列表，API Key 只返回脱敏值（api_key，如 sk-****abcd），仅管理员
 // @Tags 后台管理-AI模型
 // @Produce json
 // @Success 200 {object} map[string]interface{} "获取成功，返回模型列表"
@@ -127,7 +185,7 @@ func (h *AIModelHandler) GetAllAIModels(c *gin.Context) {
 
 // GetAIModel 获取单个AI模型
 // @Summary 获取单个AI模型
-// @Description 根据ID获取AI模型配置详情（不包含APIKey），仅管理员
+// @Description 根据ID获取AI模型配置详情，API Key 只返回脱敏值（api_key，如 sk-****abcd），仅管理员
 // @Tags 后台管理-AI模型
 // @Produce json
 // @Param id path int true "AI模型ID"
@@ -205,7 +263,7 @@ func (h *AIModelHandler) UpdateAIModel(c *gin.Context) {
 
 	var req UpdateAIModelRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -227,13 +285,54 @@ func (h *AIModelHandler) UpdateAIModel(c *gin.Context) {
 		updates["base_url"] = req.BaseURL
 	}
 	if req.APIKey != "" {
-		updates["api_key"] = req.APIKey
+		encryptedKey, keyHint, err := service.SealAIModelKey(req.APIKey)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "加密 API 密钥失败"})
+			return
+		}
+		updates["api_key"] = encryptedKey
+		updates["api_key_hint"] = keyHint
+	}
+	if req.FallbackModelID != nil {
+		if *req.FallbackModelID == 0 {
+			updates["fallback_model_id"] = nil
+		} else {
+			if err := validateFallbackModel(aiModel.ID, *req.FallbackModelID); err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+				return
+			}
+			updates["fallback_model_id"] = *req.FallbackModelID
+		}
+	}
+	if req.ProxyURL != nil {
+		if err := validateProxyURL(*req.ProxyURL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["proxy_url"] = strings.TrimSpace(*req.ProxyURL)
+	}
+	if req.MaxConcurrent != nil {
+		updates["max_concurrent"] = *req.MaxConcurrent
+	}
+	if req.ModelName != nil {
+		updates["model_name"] = strings.TrimSpace(*req.ModelName)
+	}
+	if req.ResetTemperature {
+		updates["temperature"] = nil
+	} else if req.Temperature != nil {
+		updates["temperature"] = *req.Temperature
+	}
+	if req.MaxTokens != nil {
+		updates["max_tokens"] = *req.MaxTokens
 	}
 
 	if err := database.DB.Model(&aiModel).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
+	if req.APIKey != "" {
+		recordAuditLog(c, user, models.AuditActionAIModelKeyChange, models.AuditTargetAIModel, aiModel.ID, models.JSONMap{"name": aiModel.Name})
+	}
 
 	// 重新获取更新后的记录
 	database.DB.First(&aiModel, aiModel.ID)
@@ -283,7 +382,7 @@ func (h *AIModelHandler) TestAIModel(c *gin.Context) {
 
 	// 构建最小测试请求（OpenAI 兼容格式）
 	requestBody := map[string]interface{}{
-		"model": aiModel.Name,
+		"model": aiModel.RequestModelName(),
 		"messages": []map[string]string{
 			{"role": "user", "content": "hi"},
 		},
@@ -301,10 +400,19 @@ func (h *AIModelHandler) TestAIModel(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "创建请求失败"})
 		return
 	}
+	apiKey, err := service.DecryptSecret(aiModel.APIKey)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+aiModel.APIKey)
+	req.Header.Set("Authorization", "Bearer "+apiKey)
 
-	client := &http.Client{Timeout: 15 * time.Second}
+	client, err := newAIHTTPClient(aiModel, 15*time.Second)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 	resp, err := client.Do(req)
 	if err != nil {
 		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": SafeErrorMessage(err, "接口不可用")})
@@ -363,7 +471,7 @@ func (h *AIModelHandler) ReorderAIModels(c *gin.Context) {
 
 	var req ReorderAIModelsRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+		adminBindError(c, err)
 		return
 	}
 
@@ -380,9 +488,79 @@ func (h *AIModelHandler) ReorderAIModels(c *gin.Context) {
 	})
 }
 
+// AIModelHistoryCount 模型关联的历史记录条数
+type AIModelHistoryCount struct {
+	ChatMessages      int64 `json:"chat_messages"`      // AI 聊天记录条数
+	AnalysisHistories int64 `json:"analysis_histories"` // AI 分析历史条数
+}
+
+// countAIModelHistory 统计引用该模型的聊天记录与分析历史（不含已删除的历史）
+func countAIModelHistory(db *gorm.DB, modelID uint) (AIModelHistoryCount, error) {
+	var cnt AIModelHistoryCount
+	if err := db.Model(&models.AIChatMessage{}).Where("ai_model_id = ?", modelID).Count(&cnt.ChatMessages).Error; err != nil {
+		return cnt, err
+	}
+	if err := db.Model(&models.AIAnalysisHistory{}).Where("ai_model_id = ?", modelID).Count(&cnt.AnalysisHistories).Error; err != nil {
+		return cnt, err
+	}
+	return cnt, nil
+}
+
+// backfillAIModelName 为未记录模型名称的历史（冗余字段上线前产生的记录）补写模型名称
+func backfillAIModelName(tx *gorm.DB, m models.AIModel) error {
+	if err := tx.Model(&models.AIChatMessage{}).Where("ai_model_id = ? AND ai_model_name = ?", m.ID, "").
+		Update("ai_model_name", m.Name).Error; err != nil {
+		return err
+	}
+	return tx.Model(&models.AIAnalysisHistory{}).Where("ai_model_id = ? AND ai_model_name = ?", m.ID, "").
+		Update("ai_model_name", m.Name).Error
+}
+
+// GetAIModelHistoryCount 获取模型关联的历史条数（删除前确认用）
+// @Summary 获取AI模型关联的历史条数
+// @Description 返回引用该模型的聊天记录与分析历史条数，供删除前提示；删除模型后这些历史仍保留，可继续按 model_id 查看
+// @Tags 后台管理-AI模型
+// @Produce json
+// @Param id path int true "AI模型ID"
+// @Success 200 {object} map[string]interface{} "data 为 AIModelHistoryCount"
+// @Failure 400 {object} map[string]interface{} "无效的ID"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "模型不存在"
+// @Router /admin/ai-models/{id}/history-count [get]
+func (h *AIModelHandler) GetAIModelHistoryCount(c *gin.Context) {
+	user, err := getCurrentUser(c)
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，仅管理员可管理AI模型"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return
+	}
+	var aiModel models.AIModel
+	if err := database.DB.First(&aiModel, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "模型不存在"})
+		return
+	}
+
+	cnt, err := countAIModelHistory(database.DB, aiModel.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": cnt})
+}
+
 // DeleteAIModel 删除AI模型配置
 // @Summary 删除AI模型
-// @Description 删除指定的AI模型配置（软删除），仅管理员
+// @Description 删除指定的AI模型配置（软删除），仅管理员。关联的聊天记录与分析历史不会删除，
+// @Description 删除前会为其补写模型名称（ai_model_name），之后仍可按 model_id 查询这些历史；返回 data 为保留的历史条数
 // @Tags 后台管理-AI模型
 // @Produce json
 // @Param id path int true "AI模型ID"
@@ -415,13 +593,35 @@ func (h *AIModelHandler) DeleteAIModel(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&aiModel).Error; err != nil {
+	var cnt AIModelHistoryCount
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		if cnt, err = countAIModelHistory(tx, aiModel.ID); err != nil {
+			return err
+		}
+		if cnt.ChatMessages+cnt.AnalysisHistories > 0 {
+			if err := backfillAIModelName(tx, aiModel); err != nil {
+				return err
+			}
+		}
+		return tx.Delete(&aiModel).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
 		return
 	}
+	recordAuditLog(c, user, models.AuditActionAIModelDelete, models.AuditTargetAIModel, aiModel.ID, models.JSONMap{"name": aiModel.Name})
 
+	// 清除以该模型为备用的引用
+	database.DB.Model(&models.AIModel{}).Where("fallback_model_id = ?", aiModel.ID).Update("fallback_model_id", nil)
+
+	message := "删除成功"
+	if total := cnt.ChatMessages + cnt.AnalysisHistories; total > 0 {
+		message = fmt.Sprintf("删除成功，该模型的 %d 条聊天记录和 %d 条分析历史已保留", cnt.ChatMessages, cnt.AnalysisHistories)
+	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"message": "删除成功",
+		"message": message,
+		"data":    cnt,
 	})
 }
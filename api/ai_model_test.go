@@ -0,0 +1,154 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountAIModelHistory(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `ai_chat_messages`").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(4))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `ai_analysis_histories`").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	cnt, err := countAIModelHistory(database.DB, 3)
+	require.NoError(t, err)
+	assert.Equal(t, AIModelHistoryCount{ChatMessages: 4, AnalysisHistories: 2}, cnt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBackfillAIModelName(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 只补写尚未记录模型名称的历史
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_chat_messages` SET `ai_model_name`=\\? WHERE \\(ai_model_id = \\? AND ai_model_name = \\?\\)").
+		WithArgs("GPT-4o", 3, "").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_analysis_histories` SET `ai_model_name`=\\? WHERE \\(ai_model_id = \\? AND ai_model_name = \\?\\)").
+		WithArgs("GPT-4o", 3, "").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, backfillAIModelName(database.DB, models.AIModel{ID: 3, Name: "GPT-4o"}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestListAnalysisHistoryApp_ModelDeleted(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 模型删除后历史仍按 model_id 返回，不查询 ai_models，模型名称取自冗余字段
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `ai_analysis_histories`").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `ai_analysis_histories`").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ai_model_id", "ai_model_name", "user_id", "start_date", "end_date", "result", "created_at"}).
+			AddRow(5, 3, "GPT-4o", 1, "2024-01-01", "2024-01-31", "# 分析", time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/ai-analysis/history", NewAIAnalysisHandler().ListAnalysisHistoryApp)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ai-analysis/history?model_id=3", nil))
+
+	assert.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			Total int64                      `json:"total"`
+			List  []models.AIAnalysisHistory `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Data.Total)
+	require.Len(t, resp.Data.List, 1)
+	assert.Equal(t, "GPT-4o", resp.Data.List[0].AIModelName)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAIModelHandler_TestAIModel_DecryptsKey(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	var gotAuth string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	encrypted, err := service.EncryptSecret("sk-test-0123456789")
+	require.NoError(t, err)
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `ai_models` WHERE `ai_models`.`id` = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "base_url", "api_key"}).AddRow(2, "gpt", upstream.URL, encrypted))
+
+	w := serveAsAdmin("POST", "/admin/ai-models/:id/test", "/admin/ai-models/2/test", NewAIModelHandler().TestAIModel, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "Bearer sk-test-0123456789", gotAuth)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAIModelHandler_CreateAIModel_EncryptsKey(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `ai_models` WHERE name = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery("SELECT COALESCE\\(MAX\\(sort_order\\), -1\\) FROM `ai_models`").
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(0))
+	var storedKey string
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `ai_models` \\(`name`,`base_url`,`api_key`,`api_key_hint`").
+		WithArgs("gpt", "https://api.example.com/v1", captureString(&storedKey), "sk-****cdef",
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `audit_logs`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("POST", "/admin/ai-models", "/admin/ai-models", NewAIModelHandler().CreateAIModel,
+		`{"name":"gpt","base_url":"https://api.example.com/v1","api_key":"sk-test-0123456789abcdef"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, service.IsEncryptedSecret(storedKey))
+	plain, err := service.DecryptSecret(storedKey)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-test-0123456789abcdef", plain)
+	// 响应只含脱敏后的密钥
+	assert.NotContains(t, w.Body.String(), "0123456789")
+	assert.Contains(t, w.Body.String(), `"api_key":"sk-****cdef"`)
+}
@@ -0,0 +1,88 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"finance/config"
+	"finance/middleware"
+	"finance/models"
+)
+
+// errAIQueueTimeout 排队超过 ai.queue_timeout_seconds 仍未拿到名额
+var errAIQueueTimeout = errors.New("AI服务繁忙，排队超时，请稍后再试")
+
+// aiModelQueue 单个模型的并发名额与当前排队人数
+type aiModelQueue struct {
+	sem     *middleware.Semaphore
+	limit   int
+	waiting atomic.Int32
+}
+
+var (
+	aiQueuesMu sync.Mutex
+	aiQueues   = make(map[uint]*aiModelQueue)
+)
+
+// aiModelConcurrency 模型的并发上限：模型单独配置优先，否则使用全局 ai.max_concurrent_per_model
+func aiModelConcurrency(m models.AIModel) int {
+	if m.MaxConcurrent > 0 {
+		return m.MaxConcurrent
+	}
+	if config.GlobalConfig != nil && config.GlobalConfig.AI.MaxConcurrentPerModel > 0 {
+		return config.GlobalConfig.AI.MaxConcurrentPerModel
+	}
+	return config.DefaultAIMaxConcurrentPerModel
+}
+
+// aiQueueTimeout 最长排队等待时间
+func aiQueueTimeout() time.Duration {
+	seconds := config.DefaultAIQueueTimeoutSeconds
+	if config.GlobalConfig != nil && config.GlobalConfig.AI.QueueTimeoutSeconds > 0 {
+		seconds = config.GlobalConfig.AI.QueueTimeoutSeconds
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+// getAIModelQueue 获取模型的队列；管理员修改并发上限后新建队列，
+// 旧队列上进行中的请求结束时释放回旧队列，不影响新队列计数
+func getAIModelQueue(m models.AIModel) *aiModelQueue {
+	limit := aiModelConcurrency(m)
+	aiQueuesMu.Lock()
+	defer aiQueuesMu.Unlock()
+	q, ok := aiQueues[m.ID]
+	if !ok || q.limit != limit {
+		q = &aiModelQueue{sem: middleware.NewSemaphore(limit), limit: limit}
+		aiQueues[m.ID] = q
+	}
+	return q
+}
+
+// acquireAIModelSlot 占用模型的一个并发名额，成功时返回的 release 必须调用。
+// 名额已满时先回调 onQueued（参数为当前排队人数，含自己），再等待最多 timeout；
+// 超时或 ctx 取消（客户端断开）时退出排队，不占用名额
+func acquireAIModelSlot(ctx context.Context, m models.AIModel, timeout time.Duration, onQueued func(position int)) (func(), error) {
+	q := getAIModelQueue(m)
+	if q.sem.TryAcquire() {
+		return q.sem.Release, nil
+	}
+
+	position := int(q.waiting.Add(1))
+	defer q.waiting.Add(-1)
+	if onQueued != nil {
+		onQueued(position)
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	if err := q.sem.Acquire(waitCtx); err != nil {
+		if ctx.Err() != nil {
+			return nil, errors.New("客户端断开连接")
+		}
+		return nil, errAIQueueTimeout
+	}
+	return q.sem.Release, nil
+}
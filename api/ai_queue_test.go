@@ -0,0 +1,91 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAIModelConcurrency(t *testing.T) {
+	config.GlobalConfig = nil
+	assert.Equal(t, config.DefaultAIMaxConcurrentPerModel, aiModelConcurrency(models.AIModel{}))
+
+	config.GlobalConfig = &config.Config{AI: config.AIConfig{MaxConcurrentPerModel: 5}}
+	defer func() { config.GlobalConfig = nil }()
+	assert.Equal(t, 5, aiModelConcurrency(models.AIModel{}))
+	// 模型单独配置优先
+	assert.Equal(t, 2, aiModelConcurrency(models.AIModel{MaxConcurrent: 2}))
+}
+
+func TestAcquireAIModelSlot_Immediate(t *testing.T) {
+	m := models.AIModel{ID: 9001, MaxConcurrent: 1}
+	queued := false
+	release, err := acquireAIModelSlot(context.Background(), m, time.Second, func(int) { queued = true })
+	require.NoError(t, err)
+	assert.False(t, queued)
+	release()
+}
+
+func TestAcquireAIModelSlot_QueueTimeout(t *testing.T) {
+	m := models.AIModel{ID: 9002, MaxConcurrent: 1}
+	release, err := acquireAIModelSlot(context.Background(), m, time.Second, nil)
+	require.NoError(t, err)
+	defer release()
+
+	position := 0
+	_, err = acquireAIModelSlot(context.Background(), m, 20*time.Millisecond, func(p int) { position = p })
+	assert.ErrorIs(t, err, errAIQueueTimeout)
+	assert.Equal(t, 1, position)
+	// 超时后退出排队
+	assert.Equal(t, int32(0), getAIModelQueue(m).waiting.Load())
+}
+
+func TestAcquireAIModelSlot_QueuedThenServed(t *testing.T) {
+	m := models.AIModel{ID: 9003, MaxConcurrent: 1}
+	release, err := acquireAIModelSlot(context.Background(), m, time.Second, nil)
+	require.NoError(t, err)
+
+	queued := make(chan int, 1)
+	done := make(chan error, 1)
+	go func() {
+		r, err := acquireAIModelSlot(context.Background(), m, time.Second, func(p int) { queued <- p })
+		if err == nil {
+			r()
+		}
+		done <- err
+	}()
+
+	assert.Equal(t, 1, <-queued)
+	release()
+	assert.NoError(t, <-done)
+}
+
+func TestAcquireAIModelSlot_ClientGone(t *testing.T) {
+	m := models.AIModel{ID: 9004, MaxConcurrent: 1}
+	release, err := acquireAIModelSlot(context.Background(), m, time.Second, nil)
+	require.NoError(t, err)
+	defer release()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = acquireAIModelSlot(ctx, m, time.Second, nil)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, errAIQueueTimeout)
+}
+
+func TestGetAIModelQueue_LimitChanged(t *testing.T) {
+	m := models.AIModel{ID: 9005, MaxConcurrent: 1}
+	q := getAIModelQueue(m)
+	assert.Same(t, q, getAIModelQueue(m))
+
+	m.MaxConcurrent = 4
+	q2 := getAIModelQueue(m)
+	assert.NotSame(t, q, q2)
+	assert.Equal(t, 4, q2.limit)
+}
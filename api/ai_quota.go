@@ -0,0 +1,291 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// AIQuotaStatus 用户当日 AI 配额
+type AIQuotaStatus struct {
+	DailyLimit int    `json:"daily_limit" example:"50"` // 当日上限
+	Used       int    `json:"used" example:"12"`        // 今日已用次数
+	Remaining  int    `json:"remaining" example:"38"`   // 今日剩余次数
+	Custom     bool   `json:"custom" example:"false"`   // 是否为管理员单独设置的上限
+	ResetAt    string `json:"reset_at"`                 // 下次重置时间（次日零点）
+}
+
+// UpdateAIQuotaRequest 调整用户 AI 配额
+type UpdateAIQuotaRequest struct {
+	// DailyLimit 每日上限，0 表示禁止使用；传 null 恢复为全局配置 ai.daily_quota
+	DailyLimit *int `json:"daily_limit" binding:"omitempty,min=0,max=100000" example:"100"`
+	// ResetUsed 是否同时清零今日已用次数
+	ResetUsed bool `json:"reset_used" example:"false"`
+}
+
+// defaultAIDailyQuota 全局每日配额（ai.daily_quota）
+func defaultAIDailyQuota() int {
+	if config.GlobalConfig == nil || config.GlobalConfig.AI.DailyQuota <= 0 {
+		return config.DefaultAIDailyQuota
+	}
+	return config.GlobalConfig.AI.DailyQuota
+}
+
+// aiQuotaDate 配额计数所属日期（服务器本地时区）
+func aiQuotaDate(now time.Time) string {
+	return now.In(time.Local).Format("2006-01-02")
+}
+
+// loadAIQuota 读取用户配额记录，不存在时创建；并发创建冲突时重新读取
+func loadAIQuota(userID uint) (models.AIQuota, error) {
+	var q models.AIQuota
+	err := database.DB.Where("user_id = ?", userID).First(&q).Error
+	if err == nil {
+		return q, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return q, err
+	}
+	q = models.AIQuota{UserID: userID, ResetDate: aiQuotaDate(time.Now())}
+	if err := database.DB.Create(&q).Error; err != nil {
+		var existing models.AIQuota
+		if e := database.DB.Where("user_id = ?", userID).First(&existing).Error; e != nil {
+			return q, err
+		}
+		return existing, nil
+	}
+	return q, nil
+}
+
+// buildAIQuotaStatus 计算当日配额；reset_date 不是今天时视为已跨天重置
+func buildAIQuotaStatus(q models.AIQuota, now time.Time) AIQuotaStatus {
+	st := AIQuotaStatus{DailyLimit: defaultAIDailyQuota()}
+	if q.DailyLimit != nil {
+		st.DailyLimit = *q.DailyLimit
+		st.Custom = true
+	}
+	if q.ResetDate == aiQuotaDate(now) {
+		st.Used = q.UsedToday
+	}
+	st.Remaining = st.DailyLimit - st.Used
+	if st.Remaining < 0 {
+		st.Remaining = 0
+	}
+	local := now.In(time.Local)
+	st.ResetAt = models.FormatTime(time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, time.Local))
+	return st
+}
+
+// consumeAIQuota 占用 1 次当日配额，返回占用后的配额；ok 为 false 表示已达上限（未占用）。
+// 跨天时先把计数归零，再用带上限条件的 UPDATE 自增，并发请求不会超额
+func consumeAIQuota(userID uint) (AIQuotaStatus, bool, error) {
+	q, err := loadAIQuota(userID)
+	if err != nil {
+		return AIQuotaStatus{}, false, err
+	}
+	now := time.Now()
+	st := buildAIQuotaStatus(q, now)
+	if st.Remaining <= 0 {
+		return st, false, nil
+	}
+
+	today := aiQuotaDate(now)
+	if q.ResetDate != today {
+		if err := database.DB.Model(&models.AIQuota{}).
+			Where("id = ? AND (reset_date <> ? OR reset_date IS NULL)", q.ID, today).
+			Updates(map[string]interface{}{"used_today": 0, "reset_date": today}).Error; err != nil {
+			return st, false, err
+		}
+	}
+	res := database.DB.Model(&models.AIQuota{}).
+		Where("id = ? AND reset_date = ? AND used_today < ?", q.ID, today, st.DailyLimit).
+		UpdateColumn("used_today", gorm.Expr("used_today + 1"))
+	if res.Error != nil {
+		return st, false, res.Error
+	}
+	if res.RowsAffected == 0 {
+		// 与其他请求并发时已被用完
+		st.Used, st.Remaining = st.DailyLimit, 0
+		return st, false, nil
+	}
+	st.Used++
+	st.Remaining--
+	return st, true, nil
+}
+
+// aiQuotaExceededMessage 超限提示
+func aiQuotaExceededMessage(st AIQuotaStatus) string {
+	if st.DailyLimit == 0 {
+		return "你的账号暂无 AI 使用额度，请联系管理员"
+	}
+	return fmt.Sprintf("今日 AI 调用次数已达上限（%d 次），剩余 0 次，将于次日零点重置", st.DailyLimit)
+}
+
+// setAIQuotaHeaders 在响应头中附带配额，流式接口也能读取剩余次数
+func setAIQuotaHeaders(c *gin.Context, st AIQuotaStatus) {
+	c.Header("X-AI-Quota-Limit", strconv.Itoa(st.DailyLimit))
+	c.Header("X-AI-Quota-Remaining", strconv.Itoa(st.Remaining))
+}
+
+// checkAIQuotaApp App 端入口校验并占用配额，失败时已写入响应
+func checkAIQuotaApp(c *gin.Context, userID uint) bool {
+	st, ok, err := consumeAIQuota(userID)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询 AI 配额失败"))
+		return false
+	}
+	setAIQuotaHeaders(c, st)
+	if !ok {
+		c.JSON(http.StatusTooManyRequests, Response{Code: http.StatusTooManyRequests, Message: aiQuotaExceededMessage(st), Data: st})
+		return false
+	}
+	return true
+}
+
+// checkAIQuotaAdmin 后台入口校验并占用配额（管理员不受限），失败时已写入响应
+func checkAIQuotaAdmin(c *gin.Context, user *models.User) bool {
+	if user.IsAdmin {
+		return true
+	}
+	st, ok, err := consumeAIQuota(user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询 AI 配额失败")})
+		return false
+	}
+	setAIQuotaHeaders(c, st)
+	if !ok {
+		c.JSON(http.StatusTooManyRequests, gin.H{"success": false, "message": aiQuotaExceededMessage(st), "data": st})
+		return false
+	}
+	return true
+}
+
+// AIQuotaHandler AI 配额查询（App端）
+type AIQuotaHandler struct{}
+
+// NewAIQuotaHandler 创建 AI 配额处理器
+func NewAIQuotaHandler() *AIQuotaHandler {
+	return &AIQuotaHandler{}
+}
+
+// GetQuota 查询当日 AI 配额
+// @Summary 查询 AI 配额
+// @Description 返回当前用户今日的 AI 调用上限、已用与剩余次数。聊天与分析合并计数，每次发起请求计 1 次，按服务器本地日期零点重置
+// @Tags AI
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=AIQuotaStatus} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/ai-quota [get]
+func (h *AIQuotaHandler) GetQuota(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	q, err := loadAIQuota(userID)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询 AI 配额失败"))
+		return
+	}
+	Success(c, buildAIQuotaStatus(q, time.Now()))
+}
+
+// GetUserAIQuota 查询用户 AI 配额（仅管理员）
+// @Summary 查询用户AI配额
+// @Description 返回指定用户今日的 AI 调用上限、已用与剩余次数，custom=true 表示该用户有单独设置的上限
+// @Tags 用户管理
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /admin/users/{id}/ai-quota [get]
+func (h *AdminHandler) GetUserAIQuota(c *gin.Context) {
+	userID, ok := adminQuotaTarget(c)
+	if !ok {
+		return
+	}
+	q, err := loadAIQuota(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": buildAIQuotaStatus(q, time.Now())})
+}
+
+// UpdateUserAIQuota 调整用户 AI 配额（仅管理员）
+// @Summary 调整用户AI配额
+// @Description 为指定用户单独设置每日 AI 调用上限（0 表示禁止使用，null 恢复为全局配置），可同时清零今日已用次数
+// @Tags 用户管理
+// @Accept json
+// @Produce json
+// @Param id path int true "用户ID"
+// @Param request body UpdateAIQuotaRequest true "配额"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /admin/users/{id}/ai-quota [put]
+func (h *AdminHandler) UpdateUserAIQuota(c *gin.Context) {
+	userID, ok := adminQuotaTarget(c)
+	if !ok {
+		return
+	}
+	var req UpdateAIQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+
+	q, err := loadAIQuota(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
+		return
+	}
+	updates := map[string]interface{}{"daily_limit": req.DailyLimit}
+	if req.ResetUsed {
+		updates["used_today"] = 0
+	}
+	if err := database.DB.Model(&q).Updates(updates).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
+		return
+	}
+	q.DailyLimit = req.DailyLimit
+	if req.ResetUsed {
+		q.UsedToday = 0
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": buildAIQuotaStatus(q, time.Now())})
+}
+
+// adminQuotaTarget 校验管理员身份并解析目标用户，失败时已写入响应
+func adminQuotaTarget(c *gin.Context) (uint, bool) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return 0, false
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return 0, false
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的用户ID"})
+		return 0, false
+	}
+	var user models.User
+	if err := database.DB.Select("id").First(&user, uint(id)).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "用户不存在"})
+		return 0, false
+	}
+	return user.ID, true
+}
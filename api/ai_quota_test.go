@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var aiQuotaColumns = []string{"id", "user_id", "daily_limit", "used_today", "reset_date", "created_at", "updated_at"}
+
+func TestBuildAIQuotaStatus(t *testing.T) {
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+
+	// 今日计数
+	st := buildAIQuotaStatus(models.AIQuota{UsedToday: 12, ResetDate: "2024-03-15"}, now)
+	assert.Equal(t, config.DefaultAIDailyQuota, st.DailyLimit)
+	assert.Equal(t, 12, st.Used)
+	assert.Equal(t, config.DefaultAIDailyQuota-12, st.Remaining)
+	assert.False(t, st.Custom)
+	assert.Equal(t, models.FormatTime(time.Date(2024, 3, 16, 0, 0, 0, 0, time.Local)), st.ResetAt)
+
+	// 跨天后计数视为已归零
+	st = buildAIQuotaStatus(models.AIQuota{UsedToday: 12, ResetDate: "2024-03-14"}, now)
+	assert.Equal(t, 0, st.Used)
+
+	// 单独设置的上限，已用超过上限时剩余为 0
+	limit := 5
+	st = buildAIQuotaStatus(models.AIQuota{DailyLimit: &limit, UsedToday: 8, ResetDate: "2024-03-15"}, now)
+	assert.True(t, st.Custom)
+	assert.Equal(t, 5, st.DailyLimit)
+	assert.Equal(t, 0, st.Remaining)
+
+	// 0 表示禁止使用
+	zero := 0
+	st = buildAIQuotaStatus(models.AIQuota{DailyLimit: &zero}, now)
+	assert.Equal(t, 0, st.Remaining)
+	assert.Contains(t, aiQuotaExceededMessage(st), "暂无")
+}
+
+func TestConsumeAIQuota_NewDay(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	today := aiQuotaDate(time.Now())
+	mock.ExpectQuery("SELECT \\* FROM `ai_quotas` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(aiQuotaColumns).AddRow(3, 1, nil, 50, "2000-01-01", time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_quotas` SET .*`used_today`=\\?.* WHERE id = \\? AND \\(reset_date <> \\? OR reset_date IS NULL\\)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_quotas` SET `used_today`=used_today \\+ 1 WHERE id = \\? AND reset_date = \\? AND used_today < \\?").
+		WithArgs(3, today, config.DefaultAIDailyQuota).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	st, ok, err := consumeAIQuota(1)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 1, st.Used)
+	assert.Equal(t, config.DefaultAIDailyQuota-1, st.Remaining)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeAIQuota_Exceeded(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	today := aiQuotaDate(time.Now())
+	mock.ExpectQuery("SELECT \\* FROM `ai_quotas` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(aiQuotaColumns).AddRow(3, 1, 2, 2, today, time.Now(), time.Now()))
+
+	st, ok, err := consumeAIQuota(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 2, st.DailyLimit)
+	assert.Equal(t, 0, st.Remaining)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConsumeAIQuota_ConcurrentExhausted(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	today := aiQuotaDate(time.Now())
+	mock.ExpectQuery("SELECT \\* FROM `ai_quotas` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows(aiQuotaColumns).AddRow(3, 1, 2, 1, today, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_quotas` SET `used_today`=used_today \\+ 1").
+		WithArgs(3, today, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	st, ok, err := consumeAIQuota(1)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	assert.Equal(t, 0, st.Remaining)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAIQuotaHandler_GetQuota(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	today := aiQuotaDate(time.Now())
+	mock.ExpectQuery("SELECT \\* FROM `ai_quotas` WHERE user_id = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows(aiQuotaColumns).AddRow(3, 7, 10, 4, today, time.Now(), time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(7))
+	router.GET("/ai-quota", NewAIQuotaHandler().GetQuota)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/ai-quota", nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data AIQuotaStatus `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 10, resp.Data.DailyLimit)
+	assert.Equal(t, 4, resp.Data.Used)
+	assert.Equal(t, 6, resp.Data.Remaining)
+	assert.True(t, resp.Data.Custom)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// validateAmountRange 校验金额区间：均不能为负数，同时传入时最小值不能大于最大值
+func validateAmountRange(min, max *float64) error {
+	if min != nil && *min < 0 {
+		return errors.New("min_amount 不能为负数")
+	}
+	if max != nil && *max < 0 {
+		return errors.New("max_amount 不能为负数")
+	}
+	if min != nil && max != nil && *min > *max {
+		return errors.New("min_amount 不能大于 max_amount")
+	}
+	return nil
+}
+
+// applyAmountRange 按金额区间筛选（含边界，按记录原币种金额比较），未传的一端不限
+func applyAmountRange(query *gorm.DB, min, max *float64) (*gorm.DB, error) {
+	if err := validateAmountRange(min, max); err != nil {
+		return nil, err
+	}
+	if min != nil {
+		query = query.Where("amount >= ?", *min)
+	}
+	if max != nil {
+		query = query.Where("amount <= ?", *max)
+	}
+	return query, nil
+}
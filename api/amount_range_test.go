@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateAmountRange(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+
+	assert.NoError(t, validateAmountRange(nil, nil))
+	assert.NoError(t, validateAmountRange(f(0), nil))
+	assert.NoError(t, validateAmountRange(f(100), f(100)))
+	assert.Error(t, validateAmountRange(f(-1), nil))
+	assert.Error(t, validateAmountRange(nil, f(-0.01)))
+	assert.Error(t, validateAmountRange(f(200), f(100)))
+}
+
+func TestIncomeHandler_List_AmountRange(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `incomes` WHERE amount >= \\? AND amount <= \\? AND user_id = \\?").
+		WithArgs(1000.0, 5000.0, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `incomes` WHERE amount >= \\? AND amount <= \\? AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "income_time"}).
+			AddRow(3, 1, 3000, "奖金", time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes", NewIncomeHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/incomes?min_amount=1000&max_amount=5000", nil))
+
+	assert.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_List_InvalidAmountRange(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	for _, query := range []string{"min_amount=500&max_amount=100", "min_amount=-1", "max_amount=abc"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?"+query, nil))
+		assert.Equal(t, 400, w.Code, query)
+	}
+}
@@ -0,0 +1,156 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 月度异常检测默认参数
+const (
+	DefaultAnomalyMonths      = 12  // 回看的完整月份数
+	DefaultAnomalyZScore      = 1.5 // 偏离均值的标准差倍数
+	DefaultAnomalyDropPercent = 30  // 环比跌幅阈值（%）
+	minAnomalySamples         = 3   // 有数据的月份少于该值时不做判断
+)
+
+// 异常原因
+const (
+	AnomalyReasonZScore  = "zscore"   // 偏离均值超过 z 个标准差
+	AnomalyReasonMoMDrop = "mom_drop" // 环比变化超过阈值
+)
+
+// anomalyConfig 月度异常检测阈值
+type anomalyConfig struct {
+	Months      int
+	ZScore      float64
+	DropPercent float64
+}
+
+// MonthlyAnomaly 单月统计与异常标记
+type MonthlyAnomaly struct {
+	Month         string   `json:"month" example:"2024-01"`
+	Amount        float64  `json:"amount" example:"3000.00"`
+	ZScore        float64  `json:"z_score" example:"-1.80"`  // (amount-均值)/标准差，标准差为 0 时为 0
+	ChangePercent *float64 `json:"change_percent,omitempty"` // 环比变化（%），上月为 0 时不返回
+	Anomaly       bool     `json:"anomaly" example:"true"`
+	Reasons       []string `json:"reasons,omitempty"` // zscore / mom_drop
+}
+
+// MonthlyAnomalyResult 月度异常检测结果
+type MonthlyAnomalyResult struct {
+	Months       []MonthlyAnomaly `json:"months"`
+	Mean         float64          `json:"mean"`
+	StdDev       float64          `json:"std_dev"`
+	ZScore       float64          `json:"z_threshold"`
+	DropPercent  float64          `json:"drop_percent_threshold"`
+	Insufficient bool             `json:"insufficient"`      // 样本不足，未做判断
+	Message      string           `json:"message,omitempty"` // 样本不足时的提示
+}
+
+// parseAnomalyConfig 解析 months/z/drop_percent 查询参数
+func parseAnomalyConfig(c *gin.Context) (anomalyConfig, error) {
+	cfg := anomalyConfig{Months: DefaultAnomalyMonths, ZScore: DefaultAnomalyZScore, DropPercent: DefaultAnomalyDropPercent}
+	if v := c.Query("months"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < minAnomalySamples || n > 36 {
+			return cfg, errors.New("months 需在 3-36 之间")
+		}
+		cfg.Months = n
+	}
+	if v := c.Query("z"); v != "" {
+		z, err := strconv.ParseFloat(v, 64)
+		if err != nil || z <= 0 || z > 5 {
+			return cfg, errors.New("z 需在 (0, 5] 之间")
+		}
+		cfg.ZScore = z
+	}
+	if v := c.Query("drop_percent"); v != "" {
+		p, err := strconv.ParseFloat(v, 64)
+		if err != nil || p <= 0 || p > 100 {
+			return cfg, errors.New("drop_percent 需在 (0, 100] 之间")
+		}
+		cfg.DropPercent = p
+	}
+	return cfg, nil
+}
+
+// anomalyWindow 检测窗口：截至上月末的 months 个完整月份
+func anomalyWindow(now time.Time, months int) (time.Time, time.Time) {
+	thisMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	return thisMonth.AddDate(0, -months, 0), thisMonth.Add(-time.Second)
+}
+
+// meanStdDev 计算均值与总体标准差
+func meanStdDev(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(sq / float64(len(values)))
+}
+
+// detectMonthlyAnomalies 按月聚合后标记异常月份（收入、消费等月度序列共用）。
+// amounts 为 month(YYYY-MM) -> 金额；窗口内无记录的月份按 0 计入。
+// below 为 true 时检测"明显偏低"（收入骤降），为 false 时检测"明显偏高"（消费激增），
+// 此时 DropPercent 作为环比涨幅阈值使用。
+func detectMonthlyAnomalies(amounts map[string]float64, start time.Time, months int, cfg anomalyConfig, below bool) MonthlyAnomalyResult {
+	result := MonthlyAnomalyResult{ZScore: cfg.ZScore, DropPercent: cfg.DropPercent, Months: []MonthlyAnomaly{}}
+
+	values := make([]float64, months)
+	keys := make([]string, months)
+	nonEmpty := 0
+	for i := 0; i < months; i++ {
+		keys[i] = start.AddDate(0, i, 0).Format("2006-01")
+		values[i] = math.Round(amounts[keys[i]]*100) / 100
+		if values[i] != 0 {
+			nonEmpty++
+		}
+	}
+
+	mean, std := meanStdDev(values)
+	result.Mean = math.Round(mean*100) / 100
+	result.StdDev = math.Round(std*100) / 100
+
+	if nonEmpty < minAnomalySamples {
+		result.Insufficient = true
+		result.Message = "样本不足：至少需要 3 个月有记录才能判断异常"
+	}
+
+	sign := 1.0
+	if below {
+		sign = -1.0
+	}
+	for i, v := range values {
+		m := MonthlyAnomaly{Month: keys[i], Amount: v}
+		if std > 0 {
+			m.ZScore = math.Round((v-mean)/std*100) / 100
+		}
+		if i > 0 && values[i-1] != 0 {
+			change := math.Round((v-values[i-1])/values[i-1]*10000) / 100
+			m.ChangePercent = &change
+		}
+		if !result.Insufficient {
+			if std > 0 && sign*(v-mean)/std >= cfg.ZScore {
+				m.Reasons = append(m.Reasons, AnomalyReasonZScore)
+			}
+			if m.ChangePercent != nil && sign**m.ChangePercent >= cfg.DropPercent {
+				m.Reasons = append(m.Reasons, AnomalyReasonMoMDrop)
+			}
+			m.Anomaly = len(m.Reasons) > 0
+		}
+		result.Months = append(result.Months, m)
+	}
+	return result
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func defaultAnomalyConfig() anomalyConfig {
+	return anomalyConfig{Months: 6, ZScore: DefaultAnomalyZScore, DropPercent: DefaultAnomalyDropPercent}
+}
+
+func TestMeanStdDev(t *testing.T) {
+	mean, std := meanStdDev([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+	assert.Equal(t, 5.0, mean)
+	assert.Equal(t, 2.0, std)
+
+	mean, std = meanStdDev(nil)
+	assert.Zero(t, mean)
+	assert.Zero(t, std)
+}
+
+func TestAnomalyWindow(t *testing.T) {
+	now := time.Date(2024, 7, 15, 10, 0, 0, 0, time.Local)
+	start, end := anomalyWindow(now, 6)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), start)
+	assert.Equal(t, time.Date(2024, 6, 30, 23, 59, 59, 0, time.Local), end)
+}
+
+func TestDetectMonthlyAnomalies_IncomeDrop(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	amounts := map[string]float64{
+		"2024-01": 10000, "2024-02": 10500, "2024-03": 9800,
+		"2024-04": 10200, "2024-05": 3000, "2024-06": 9900,
+	}
+	res := detectMonthlyAnomalies(amounts, start, 6, defaultAnomalyConfig(), true)
+
+	require.False(t, res.Insufficient)
+	require.Len(t, res.Months, 6)
+	may := res.Months[4]
+	assert.Equal(t, "2024-05", may.Month)
+	assert.True(t, may.Anomaly)
+	assert.Contains(t, may.Reasons, AnomalyReasonZScore)
+	assert.Contains(t, may.Reasons, AnomalyReasonMoMDrop)
+
+	// 6 月回升不算异常（环比为涨）
+	assert.False(t, res.Months[5].Anomaly)
+	assert.False(t, res.Months[0].Anomaly)
+}
+
+func TestDetectMonthlyAnomalies_Insufficient(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	res := detectMonthlyAnomalies(map[string]float64{"2024-06": 5000, "2024-05": 100}, start, 6, defaultAnomalyConfig(), true)
+
+	assert.True(t, res.Insufficient)
+	assert.NotEmpty(t, res.Message)
+	for _, m := range res.Months {
+		assert.False(t, m.Anomaly)
+	}
+}
+
+func TestIncomeHandler_Anomalies_InvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/anomalies", NewIncomeHandler().Anomalies)
+
+	for _, q := range []string{"months=2", "z=0", "drop_percent=150", "z=abc"} {
+		req := httptest.NewRequest("GET", "/incomes/anomalies?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, q)
+	}
+}
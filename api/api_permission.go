@@ -2,10 +2,13 @@ package api
 
 import (
 	"net/http"
+	"sort"
 	"strconv"
+	"strings"
 
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,13 +20,65 @@ func NewAPIPermissionHandler() *APIPermissionHandler {
 	return &APIPermissionHandler{}
 }
 
+// APIPermissionGroup 按模块（路径前缀）分组的接口
+type APIPermissionGroup struct {
+	Module string                 `json:"module"` // 如 /admin/expenses
+	APIs   []models.APIPermission `json:"apis"`
+}
+
+// apiModule 取接口所属模块：/admin/xxx 取前两段，/api/v1/xxx 取前三段，如 /admin/users/:id -> /admin/users
+func apiModule(path string) string {
+	segs := strings.Split(strings.Trim(path, "/"), "/")
+	n := 2
+	if len(segs) >= 2 && segs[0] == "api" && strings.HasPrefix(segs[1], "v") {
+		n = 3
+	}
+	if len(segs) < n {
+		n = len(segs)
+	}
+	return "/" + strings.Join(segs[:n], "/")
+}
+
+// groupAPIPermissions 按模块分组，组按模块名排序，组内保持原顺序
+func groupAPIPermissions(list []models.APIPermission) []APIPermissionGroup {
+	index := map[string]int{}
+	groups := []APIPermissionGroup{}
+	for _, a := range list {
+		m := apiModule(a.Path)
+		i, ok := index[m]
+		if !ok {
+			i = len(groups)
+			index[m] = i
+			groups = append(groups, APIPermissionGroup{Module: m})
+		}
+		groups[i].APIs = append(groups[i].APIs, a)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].Module < groups[j].Module })
+	return groups
+}
+
 // List 接口列表
+// 支持 method（精确匹配）、keyword（路径或描述模糊匹配）过滤；group=true 时按模块分组返回
 func (h *APIPermissionHandler) List(c *gin.Context) {
+	query := database.DB.Order("method ASC, path ASC")
+	if method := strings.ToUpper(strings.TrimSpace(c.Query("method"))); method != "" {
+		query = query.Where("method = ?", method)
+	}
+	if keyword := strings.TrimSpace(c.Query("keyword")); keyword != "" {
+		like := "%" + escapeLikeValue(keyword) + "%"
+		esc := likeEscape(query)
+		query = query.Where("path LIKE ?"+esc+" OR `desc` LIKE ?"+esc, like, like)
+	}
+
 	var list []models.APIPermission
-	if err := database.DB.Order("method ASC, path ASC").Find(&list).Error; err != nil {
+	if err := query.Find(&list).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
 		return
 	}
+	if c.Query("group") == "true" {
+		c.JSON(http.StatusOK, gin.H{"success": true, "data": groupAPIPermissions(list)})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
 }
 
@@ -42,8 +97,8 @@ type APIPermissionUpdateRequest struct {
 // Create 创建接口
 func (h *APIPermissionHandler) Create(c *gin.Context) {
 	var req APIPermissionCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var exist models.APIPermission
@@ -71,8 +126,8 @@ func (h *APIPermissionHandler) Update(c *gin.Context) {
 		return
 	}
 	var req APIPermissionUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var api models.APIPermission
@@ -110,6 +165,7 @@ func (h *APIPermissionHandler) Update(c *gin.Context) {
 			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 			return
 		}
+		service.InvalidateRolePermissions()
 	}
 	database.DB.First(&api, api.ID)
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": api})
@@ -132,5 +188,6 @@ func (h *APIPermissionHandler) Delete(c *gin.Context) {
 		return
 	}
 	_ = database.DB.Where("api_id = ?", id).Delete(&models.MenuAPI{})
+	service.InvalidateRolePermissions()
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
 }
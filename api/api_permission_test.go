@@ -117,3 +117,59 @@ func TestAPIPermissionHandler_Update_DuplicateMethodPath(t *testing.T) {
 	assert.Equal(t, "方法+路径已存在", resp["message"])
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestAPIModule(t *testing.T) {
+	assert.Equal(t, "/admin/expenses", apiModule("/admin/expenses"))
+	assert.Equal(t, "/admin/users", apiModule("/admin/users/:id"))
+	assert.Equal(t, "/admin/users", apiModule("/admin/users/:id/role"))
+	assert.Equal(t, "/api/v1/expenses", apiModule("/api/v1/expenses/:id"))
+	assert.Equal(t, "/admin", apiModule("/admin"))
+}
+
+func TestAPIPermissionHandler_List_Search(t *testing.T) {
+	mock, cleanup := setupAPIMockDB(t)
+	defer cleanup()
+
+	// method 转大写精确匹配，keyword 中的 % 被转义
+	mock.ExpectQuery("SELECT .* FROM `api_permissions` WHERE method = \\? AND \\(path LIKE \\? ESCAPE '\\\\\\\\' OR `desc` LIKE \\? ESCAPE '\\\\\\\\'\\)").
+		WithArgs("GET", `%50\%%`, `%50\%%`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "method", "path", "desc", "created_at", "updated_at", "deleted_at"}))
+
+	router := gin.New()
+	router.GET("/admin/apis", NewAPIPermissionHandler().List)
+	req := httptest.NewRequest("GET", "/admin/apis?method=get&keyword=50%25", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIPermissionHandler_List_Group(t *testing.T) {
+	mock, cleanup := setupAPIMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `api_permissions`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "method", "path", "desc", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "DELETE", "/admin/users/:id", "删除用户", time.Now(), time.Now(), nil).
+			AddRow(2, "GET", "/admin/expenses", "消费列表", time.Now(), time.Now(), nil).
+			AddRow(3, "GET", "/admin/users", "用户列表", time.Now(), time.Now(), nil))
+
+	router := gin.New()
+	router.GET("/admin/apis", NewAPIPermissionHandler().List)
+	req := httptest.NewRequest("GET", "/admin/apis?group=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var resp struct {
+		Success bool                 `json:"success"`
+		Data    []APIPermissionGroup `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, "/admin/expenses", resp.Data[0].Module)
+	assert.Equal(t, "/admin/users", resp.Data[1].Module)
+	assert.Len(t, resp.Data[1].APIs, 2)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
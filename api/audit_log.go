@@ -0,0 +1,104 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// recordAuditLog 记录后台敏感操作，在操作成功后调用；写入失败只打印，不影响操作结果。
+// detail 中不得放入密码、API Key 等敏感明文
+func recordAuditLog(c *gin.Context, actor *models.User, action, targetType string, targetID uint, detail models.JSONMap) {
+	entry := models.AuditLog{
+		ActorUserID: actor.ID,
+		Action:      action,
+		TargetType:  targetType,
+		TargetID:    targetID,
+		Detail:      detail,
+		IP:          c.ClientIP(),
+	}
+	if err := database.DB.Create(&entry).Error; err != nil {
+		log.Printf("记录审计日志失败（%s，操作人 %d）: %v", action, actor.ID, err)
+	}
+}
+
+// GetAuditLogs 操作审计日志（仅超级管理员）
+// @Summary 查询操作审计日志
+// @Description 按时间倒序分页返回后台敏感操作记录：设置管理员、删除用户、修改用户状态/密码、模拟登录、开关两步验证、AI 模型密钥变更
+// @Tags 后台管理-用户管理
+// @Produce json
+// @Param actor_user_id query int false "操作人用户ID"
+// @Param action query string false "操作类型：user.set_admin/user.delete/user.update_status/user.update_password/user.impersonate/user.2fa_enable/user.2fa_disable/ai_model.key_change/ai_model.delete"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/audit-logs [get]
+func (h *AdminHandler) GetAuditLogs(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		if v, e := strconv.Atoi(p); e == nil && v > 0 {
+			page = v
+		}
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		if v, e := strconv.Atoi(ps); e == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	query := created.Filter(database.DB.Model(&models.AuditLog{}), "created_at")
+	if s := c.Query("actor_user_id"); s != "" {
+		actorID, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的操作人ID"})
+			return
+		}
+		query = query.Where("actor_user_id = ?", uint(actorID))
+	}
+	if a := c.Query("action"); a != "" {
+		query = query.Where("action = ?", a)
+	}
+	var total int64
+	query.Count(&total)
+
+	var list []models.AuditLog
+	if err := query.Order(created.Order("created_at", "id DESC")).Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewPageResponse(total, page, pageSize, list),
+	})
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_SetAdmin_WritesAuditLog(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(5, "alice", false))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `audit_logs`").
+		WithArgs(1, models.AuditActionSetAdmin, models.AuditTargetUser, 5, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("PUT", "/admin/users/:id/admin", "/admin/users/5/admin", NewAdminHandler().SetAdmin, `{"is_admin":true}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_ImpersonateUser_AuditsAdminAndTarget(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(7, "bob", false))
+	mock.ExpectBegin()
+	// 操作人为原始管理员（1），对象为被模拟用户（7）
+	mock.ExpectExec("INSERT INTO `audit_logs`").
+		WithArgs(1, models.AuditActionImpersonate, models.AuditTargetUser, 7,
+			`{"admin_username":"admin","target_username":"bob"}`, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("POST", "/admin/users/impersonate", "/admin/users/impersonate", NewAdminHandler().ImpersonateUser, `{"user_id":7}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_GetAuditLogs_Filters(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `audit_logs` WHERE actor_user_id = \\? AND action = \\?").
+		WithArgs(1, models.AuditActionDeleteUser).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	mock.ExpectQuery("SELECT \\* FROM `audit_logs` WHERE actor_user_id = \\? AND action = \\? ORDER BY id DESC LIMIT 20 OFFSET 20$").
+		WithArgs(1, models.AuditActionDeleteUser).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "actor_user_id", "action", "target_type", "target_id", "detail", "ip", "created_at"}).
+			AddRow(3, 1, models.AuditActionDeleteUser, models.AuditTargetUser, 9, `{"username":"carol"}`, "192.0.2.1", time.Now()))
+
+	w := serveAsAdmin("GET", "/admin/audit-logs", "/admin/audit-logs?actor_user_id=1&action=user.delete&page=2", NewAdminHandler().GetAuditLogs, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp struct {
+		Data struct {
+			Total int64             `json:"total"`
+			Page  int               `json:"page"`
+			List  []models.AuditLog `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.EqualValues(t, 21, resp.Data.Total)
+	assert.Equal(t, 2, resp.Data.Page)
+	require.Len(t, resp.Data.List, 1)
+	assert.Equal(t, "carol", resp.Data.List[0].Detail["username"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_GetAuditLogs_InvalidActor(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+
+	w := serveAsAdmin("GET", "/admin/audit-logs", "/admin/audit-logs?actor_user_id=abc", NewAdminHandler().GetAuditLogs, "")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,7 +1,9 @@
 package api
 
 import (
+	"math"
 	"net/http"
+	"strings"
 	"time"
 
 	"finance/config"
@@ -33,18 +35,40 @@ type RegisterRequest struct {
 	Username string `json:"username" binding:"required,min=3,max=50" example:"testuser"`
 	Password string `json:"password" binding:"required,min=6,max=50" example:"password123"`
 	Email    string `json:"email" binding:"omitempty,email" example:"test@example.com"`
+	Phone    string `json:"phone" example:"13800138000"` // 可选，中国大陆号码或 +国家码 开头的国际号码
 }
 
-// LoginRequest 登录请求（支持用户名或邮箱）
+// LoginRequest 登录请求（支持用户名、邮箱或手机号）
 type LoginRequest struct {
-	Username string `json:"username" binding:"required" example:"testuser"` // 可为用户名或邮箱
+	Username string `json:"username" binding:"required" example:"testuser"` // 可为用户名、邮箱或手机号
 	Password string `json:"password" binding:"required" example:"password123"`
+	Device   string `json:"device" binding:"omitempty,max=255" example:"iPhone 15"` // 设备描述（可选，用于会话列表展示）
 }
 
 // LoginResponse 登录响应
 type LoginResponse struct {
-	Token    string      `json:"token"`
-	UserInfo models.User `json:"user_info"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"` // 用于 /auth/refresh 换取新 token
+	UserInfo     models.User `json:"user_info"`
+}
+
+// normalizeOptionalPhone 可选手机号：未填写返回 nil（存为 NULL，多个未填写的用户不会触发唯一索引冲突），否则规范化并校验格式
+func normalizeOptionalPhone(phone string) (*string, error) {
+	if phone == "" {
+		return nil, nil
+	}
+	p, err := models.NormalizePhone(phone)
+	if err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// phoneTaken 手机号（已规范化）是否已被其他用户使用
+func phoneTaken(phone string) bool {
+	var count int64
+	database.DB.Model(&models.User{}).Where("phone = ?", phone).Count(&count)
+	return count > 0
 }
 
 // Register 用户注册
@@ -61,7 +85,12 @@ type LoginResponse struct {
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
+		return
+	}
+	phone, err := normalizeOptionalPhone(req.Phone)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
@@ -71,6 +100,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		BadRequest(c, "用户名已存在")
 		return
 	}
+	if phone != nil && phoneTaken(*phone) {
+		BadRequest(c, "该手机号已被注册")
+		return
+	}
 
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -84,6 +117,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Username: req.Username,
 		Password: string(hashedPassword),
 		Email:    req.Email,
+		Phone:    phone,
 		Status:   models.UserStatusLocked,
 	}
 
@@ -105,22 +139,35 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Success 200 {object} Response{data=LoginResponse} "登录成功"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "用户名或密码错误"
+// @Failure 429 {object} Response "失败次数过多，账号或 IP 已被临时锁定（响应头 Retry-After 为剩余秒数）"
 // @Router /api/v1/auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
 		return
 	}
 
-	// 查找用户（支持用户名或邮箱）
+	// 账号或 IP 连续失败被锁定时直接拒绝，不查库、不校验密码
+	ip := c.ClientIP()
+	if checkLoginLocked(c, req.Username, ip) {
+		c.JSON(http.StatusTooManyRequests, Response{Code: http.StatusTooManyRequests, Message: loginLockedMessage})
+		return
+	}
+
+	// 查找用户（支持用户名、邮箱或手机号；输入符合手机号格式时才按手机号匹配）
+	query := database.DB.Where("username = ? OR email = ?", req.Username, req.Username)
+	if phone, err := models.NormalizePhone(req.Username); err == nil {
+		query = database.DB.Where("username = ? OR email = ? OR phone = ?", req.Username, req.Username, phone)
+	}
 	var user models.User
-	if err := database.DB.Where("username = ? OR email = ?", req.Username, req.Username).First(&user).Error; err != nil {
+	if err := query.First(&user).Error; err != nil {
+		recordLoginFailure("App ", req.Username, ip)
 		Unauthorized(c, "用户名或密码错误")
 		return
 	}
 
-	// 仅正常用户可登录
+	// 仅正常用户可登录；先于密码校验，被锁定账号无论密码对错都返回同样的提示，不暴露密码是否正确
 	if user.Status != models.UserStatusActive {
 		Error(c, http.StatusForbidden, "账号已锁定，请联系管理员解锁")
 		return
@@ -128,34 +175,53 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// 验证密码
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+		recordLoginFailure("App ", req.Username, ip)
 		Unauthorized(c, "用户名或密码错误")
 		return
 	}
+	loginLimiter().ResetUser(req.Username)
+
+	// 创建登录会话（持久化 refresh token）
+	session, refreshToken, err := createSession(c, user.ID, req.Device, refreshTTL(h.cfg))
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建会话失败"))
+		return
+	}
 
 	// 生成 token
-	token, err := middleware.GenerateToken(user.ID, user.Username, h.cfg.JWT.ExpireTime)
+	token, err := middleware.GenerateSessionToken(user.ID, user.Username, session.ID, user.TokenVersion, h.cfg.JWT.ExpireTime)
 	if err != nil {
 		InternalError(c, SafeErrorMessage(err, "生成 token 失败"))
 		return
 	}
 
 	Success(c, LoginResponse{
-		Token:    token,
-		UserInfo: user,
+		Token:        token,
+		RefreshToken: refreshToken,
+		UserInfo:     user,
 	})
 }
 
 // ProfileResponse profile 接口返回结构（仅包含必要字段）
 type ProfileResponse struct {
-	Username  string    `json:"username"`
-	Email     string    `json:"email"`
-	Status    string    `json:"status"`
-	CreatedAt time.Time `json:"created_at"`
+	Username      string    `json:"username"`
+	Email         string    `json:"email"`
+	Phone         *string   `json:"phone"`
+	Status        string    `json:"status"`
+	CreatedAt     time.Time `json:"created_at"`
+	NotifyChannel string    `json:"notify_channel"` // 通知渠道偏好：空为自动、email、feishu、none
+	WeekStart     string    `json:"week_start"`     // 周起始日偏好：空为跟随系统、mon、sun
+	// LargeExpenseThreshold 大额消费阈值（本位币），0 表示不推送 Webhook
+	LargeExpenseThreshold float64 `json:"large_expense_threshold"`
+	EmailVerified         bool    `json:"email_verified"`  // 邮箱是否已通过验证码验证
+	DigestEnabled         bool    `json:"digest_enabled"`  // 是否订阅每日消费汇总邮件
+	DigestHour            int     `json:"digest_hour"`     // 每日汇总邮件发送时刻（12–23 点）
+	DigestTimezone        string  `json:"digest_timezone"` // 每日汇总所用时区，空为服务器时区
 }
 
 // GetProfile 获取用户信息
 // @Summary 获取当前用户信息
-// @Description 获取当前登录用户的 username、email、status、created_at
+// @Description 获取当前登录用户的 username、email、phone、status、created_at、notify_channel、week_start、large_expense_threshold、email_verified、digest_enabled、digest_hour、digest_timezone
 // @Tags 认证
 // @Accept json
 // @Produce json
@@ -173,13 +239,190 @@ func (h *AuthHandler) GetProfile(c *gin.Context) {
 	}
 
 	Success(c, ProfileResponse{
-		Username:  user.Username,
-		Email:     user.Email,
-		Status:    user.Status,
-		CreatedAt: user.CreatedAt,
+		Username:              user.Username,
+		Email:                 user.Email,
+		Phone:                 user.Phone,
+		Status:                user.Status,
+		CreatedAt:             user.CreatedAt,
+		NotifyChannel:         user.NotifyChannel,
+		WeekStart:             user.WeekStart,
+		LargeExpenseThreshold: user.LargeExpenseThreshold,
+		EmailVerified:         user.EmailVerified,
+		DigestEnabled:         user.DigestEnabled,
+		DigestHour:            user.DigestHour,
+		DigestTimezone:        user.DigestTimezone,
 	})
 }
 
+// UpdateNotifyChannelRequest 修改通知渠道偏好请求
+type UpdateNotifyChannelRequest struct {
+	Channel string `json:"channel" example:"email"` // 空字符串为自动，或 email/feishu/none
+}
+
+// UpdateNotifyChannel 修改通知渠道偏好
+// @Summary 修改通知渠道偏好
+// @Description 设置超支提醒等业务通知的接收渠道：空字符串为自动（已绑定飞书时发飞书，失败或未绑定时发邮件），email 只发邮件，feishu 只发飞书，none 不接收通知
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateNotifyChannelRequest true "通知渠道"
+// @Success 200 {object} Response "修改成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/notify-channel [put]
+func (h *AuthHandler) UpdateNotifyChannel(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req UpdateNotifyChannelRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if !models.ValidNotifyChannel(req.Channel) {
+		BadRequest(c, "通知渠道只能是 email、feishu、none 或空（自动）")
+		return
+	}
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("notify_channel", req.Channel).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "修改失败"))
+		return
+	}
+	SuccessWithMessage(c, "修改成功", gin.H{"notify_channel": req.Channel})
+}
+
+// UpdateWeekStartRequest 修改周起始日偏好请求
+type UpdateWeekStartRequest struct {
+	WeekStart string `json:"week_start" example:"sun"` // 空字符串为跟随系统配置，或 mon/sun
+}
+
+// UpdateWeekStart 修改周起始日偏好
+// @Summary 修改周起始日偏好
+// @Description 设置统计中一周的第一天：mon 周一，sun 周日，空字符串跟随系统配置 stats.week_start。影响 period=this_week 的范围
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateWeekStartRequest true "周起始日"
+// @Success 200 {object} Response "修改成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/week-start [put]
+func (h *AuthHandler) UpdateWeekStart(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req UpdateWeekStartRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if !models.ValidWeekStart(req.WeekStart) {
+		BadRequest(c, "周起始日只能是 mon、sun 或空（跟随系统）")
+		return
+	}
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("week_start", req.WeekStart).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "修改失败"))
+		return
+	}
+	SuccessWithMessage(c, "修改成功", gin.H{"week_start": req.WeekStart})
+}
+
+// UpdateLargeExpenseThresholdRequest 修改大额消费阈值请求
+type UpdateLargeExpenseThresholdRequest struct {
+	Threshold *float64 `json:"threshold" binding:"required,min=0,max=99999999.99" example:"1000"` // 本位币金额，0 表示不推送
+}
+
+// UpdateLargeExpenseThreshold 修改大额消费阈值
+// @Summary 修改大额消费阈值
+// @Description 单笔消费（分期按总额）折合本位币超过该金额时，向管理员配置的 Webhook 推送 expense.large 事件；0 表示不推送
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateLargeExpenseThresholdRequest true "大额阈值"
+// @Success 200 {object} Response "修改成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/large-expense-threshold [put]
+func (h *AuthHandler) UpdateLargeExpenseThreshold(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req UpdateLargeExpenseThresholdRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	threshold := math.Round(*req.Threshold*100) / 100
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("large_expense_threshold", threshold).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "修改失败"))
+		return
+	}
+	SuccessWithMessage(c, "修改成功", gin.H{"large_expense_threshold": threshold})
+}
+
+// UpdateDailyDigestRequest 修改每日消费汇总邮件设置请求
+type UpdateDailyDigestRequest struct {
+	Enabled bool `json:"enabled" example:"true"`
+	// Hour 发送时刻（汇总时区的整点，12–23），不传时保持原值（默认 21 点）
+	Hour *int `json:"hour" binding:"omitempty,min=12,max=23" example:"21"`
+	// Timezone 发送时刻与“当天”所用的 IANA 时区，空字符串为服务器时区，不传时保持原值
+	Timezone *string `json:"timezone" example:"Asia/Shanghai"`
+}
+
+// UpdateDailyDigest 修改每日消费汇总邮件设置
+// @Summary 设置每日消费汇总邮件
+// @Description 开启后每天在设定时刻向已验证的邮箱发送当天的消费合计、消费最多的类别及与近 7 天日均的对比（金额折算为本位币）。
+// @Description 开启需要已通过验证码绑定邮箱
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body UpdateDailyDigestRequest true "汇总邮件设置"
+// @Success 200 {object} Response "修改成功"
+// @Failure 400 {object} Response "请求参数错误或邮箱未验证"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/daily-digest [put]
+func (h *AuthHandler) UpdateDailyDigest(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req UpdateDailyDigestRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		NotFound(c, "用户不存在")
+		return
+	}
+	if req.Enabled && (user.Email == "" || !user.EmailVerified) {
+		BadRequest(c, "请先绑定并验证邮箱")
+		return
+	}
+
+	updates := map[string]interface{}{"digest_enabled": req.Enabled}
+	hour := user.DigestHour
+	if req.Hour != nil {
+		hour = *req.Hour
+		updates["digest_hour"] = hour
+	}
+	timezone := user.DigestTimezone
+	if req.Timezone != nil {
+		timezone = strings.TrimSpace(*req.Timezone)
+		if timezone != "" {
+			if _, err := time.LoadLocation(timezone); err != nil {
+				BadRequest(c, "无效的时区: "+timezone)
+				return
+			}
+		}
+		updates["digest_timezone"] = timezone
+	}
+	if err := database.DB.Model(&user).Updates(updates).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "修改失败"))
+		return
+	}
+	SuccessWithMessage(c, "修改成功", gin.H{"digest_enabled": req.Enabled, "digest_hour": hour, "digest_timezone": timezone})
+}
+
 // ChangePasswordRequest 修改密码请求
 type ChangePasswordRequest struct {
 	OldPassword string `json:"old_password" binding:"required" example:"oldpassword123"`
@@ -203,7 +446,7 @@ func (h *AuthHandler) ChangePassword(c *gin.Context) {
 
 	var req ChangePasswordRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
 		return
 	}
 
@@ -373,6 +616,7 @@ type RegisterWithVerificationRequest struct {
 	Password string `json:"password" binding:"required,min=6,max=50" example:"password123"`
 	Email    string `json:"email" binding:"required,email" example:"test@example.com"`
 	Code     string `json:"code" binding:"required,len=6" example:"123456"`
+	Phone    string `json:"phone" example:"13800138000"` // 可选
 }
 
 // RegisterWithVerification 带邮箱验证的用户注册
@@ -389,7 +633,12 @@ type RegisterWithVerificationRequest struct {
 func (h *AuthHandler) RegisterWithVerification(c *gin.Context) {
 	var req RegisterWithVerificationRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
+		return
+	}
+	phone, err := normalizeOptionalPhone(req.Phone)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
@@ -422,6 +671,10 @@ func (h *AuthHandler) RegisterWithVerification(c *gin.Context) {
 		BadRequest(c, "该邮箱已被注册")
 		return
 	}
+	if phone != nil && phoneTaken(*phone) {
+		BadRequest(c, "该手机号已被注册")
+		return
+	}
 
 	// 加密密码
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
@@ -432,10 +685,12 @@ func (h *AuthHandler) RegisterWithVerification(c *gin.Context) {
 
 	// 创建用户
 	user := models.User{
-		Username: req.Username,
-		Password: string(hashedPassword),
-		Email:    req.Email,
-		Status:   models.UserStatusLocked,
+		Username:      req.Username,
+		Password:      string(hashedPassword),
+		Email:         req.Email,
+		Phone:         phone,
+		Status:        models.UserStatusLocked,
+		EmailVerified: true, // 注册时已校验邮箱验证码
 	}
 
 	if err := database.DB.Create(&user).Error; err != nil {
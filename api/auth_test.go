@@ -11,6 +11,7 @@ import (
 	"finance/database"
 	"finance/middleware"
 	"finance/models"
+	"finance/service"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
@@ -33,6 +34,8 @@ func setupMockDB(t *testing.T) (sqlmock.Sqlmock, func()) {
 
 	oldDB := database.DB
 	database.DB = gormDB
+	// 换库后进程内缓存的统计结果不再有效
+	service.ExpenseStatisticsCache.InvalidateAll()
 	return mock, func() {
 		database.DB = oldDB
 		sqlDB.Close()
@@ -134,6 +137,11 @@ func TestAuthHandler_Login(t *testing.T) {
 		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "is_admin", "status", "feishu_open_id", "feishu_union_id", "created_at", "updated_at", "deleted_at"}).
 			AddRow(1, "loginuser", string(hashed), "login@x.com", false, models.UserStatusActive, nil, "", time.Now(), time.Now(), nil))
 
+	// 创建登录会话
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `sessions`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
 	router := gin.New()
 	h := NewAuthHandler(cfg)
 	router.POST("/login", h.Login)
@@ -151,6 +159,7 @@ func TestAuthHandler_Login(t *testing.T) {
 	assert.NotEmpty(t, resp["data"])
 	data := resp["data"].(map[string]interface{})
 	assert.NotEmpty(t, data["token"])
+	assert.NotEmpty(t, data["refresh_token"])
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -179,3 +188,154 @@ func TestAuthHandler_Login_UserNotFound(t *testing.T) {
 	assert.Equal(t, 401, w.Code)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestAuthHandler_Login_ByPhone(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "debug"}, JWT: config.JWTConfig{Secret: "x"}}
+	config.GlobalConfig = cfg
+	defer func() { config.GlobalConfig = nil }()
+
+	// 符合手机号格式时按规范化后的号码匹配 phone 列
+	mock.ExpectQuery("SELECT .* FROM `users` WHERE \\(username = \\? OR email = \\? OR phone = \\?\\)").
+		WithArgs("+86 138-0013-8000", "+86 138-0013-8000", "13800138000").
+		WillReturnRows(sqlmock.NewRows([]string{}))
+
+	router := gin.New()
+	h := NewAuthHandler(cfg)
+	router.POST("/login", h.Login)
+
+	body := `{"username":"+86 138-0013-8000","password":"any"}`
+	req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_Register_Phone(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "debug"}, JWT: config.JWTConfig{Secret: "x"}}
+	router := gin.New()
+	router.POST("/register", NewAuthHandler(cfg).Register)
+
+	// 格式错误直接返回 400
+	body := `{"username":"newuser","password":"password123","phone":"12345"}`
+	req := httptest.NewRequest("POST", "/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+
+	// 规范化后的号码已被使用
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WithArgs("newuser").
+		WillReturnRows(sqlmock.NewRows([]string{}))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE phone = \\?").
+		WithArgs("13800138000").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	body = `{"username":"newuser","password":"password123","phone":"0086 138 0013 8000"}`
+	req = httptest.NewRequest("POST", "/register", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "该手机号已被注册", resp["message"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_UpdateDailyDigest(t *testing.T) {
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "debug"}, JWT: config.JWTConfig{Secret: "x"}}
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.PUT("/auth/daily-digest", NewAuthHandler(cfg).UpdateDailyDigest)
+	put := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/auth/daily-digest", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("hour out of range", func(t *testing.T) {
+		w := put(`{"enabled":true,"hour":8}`)
+		assert.Equal(t, 400, w.Code)
+	})
+
+	t.Run("unverified email", func(t *testing.T) {
+		mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "email_verified", "digest_hour"}).AddRow(1, "a@example.com", false, 21))
+
+		w := put(`{"enabled":true}`)
+		assert.Equal(t, 400, w.Code)
+		var resp map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "请先绑定并验证邮箱", resp["message"])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("enable with hour", func(t *testing.T) {
+		mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "email_verified", "digest_hour"}).AddRow(1, "a@example.com", true, 21))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `users` SET `digest_enabled`=\\?,`digest_hour`=\\?,`updated_at`=\\?").
+			WithArgs(true, 22, sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		w := put(`{"enabled":true,"hour":22}`)
+		require.Equal(t, 200, w.Code, w.Body.String())
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, true, resp.Data["digest_enabled"])
+		assert.Equal(t, 22.0, resp.Data["digest_hour"])
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("invalid timezone", func(t *testing.T) {
+		mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "email_verified", "digest_hour"}).AddRow(1, "a@example.com", true, 21))
+
+		w := put(`{"enabled":true,"timezone":"Mars/Olympus"}`)
+		assert.Equal(t, 400, w.Code)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("set timezone", func(t *testing.T) {
+		mock, cleanup := setupMockDB(t)
+		defer cleanup()
+		mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+			WithArgs(1).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "email", "email_verified", "digest_hour"}).AddRow(1, "a@example.com", true, 21))
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `users` SET `digest_enabled`=\\?,`digest_timezone`=\\?,`updated_at`=\\?").
+			WithArgs(true, "Asia/Tokyo", sqlmock.AnyArg(), 1).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectCommit()
+
+		w := put(`{"enabled":true,"timezone":"Asia/Tokyo"}`)
+		require.Equal(t, 200, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"digest_timezone":"Asia/Tokyo"`)
+		require.NoError(t, mock.ExpectationsWereMet())
+	})
+}
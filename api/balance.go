@@ -0,0 +1,145 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxBalanceRebuildMonths 单次补算快照的最大月数
+const maxBalanceRebuildMonths = 120
+
+// BalanceHandler 结余快照处理器
+type BalanceHandler struct{}
+
+// NewBalanceHandler 创建结余快照处理器
+func NewBalanceHandler() *BalanceHandler {
+	return &BalanceHandler{}
+}
+
+// RebuildBalanceSnapshotsRequest 补算结余快照请求
+type RebuildBalanceSnapshotsRequest struct {
+	From string `json:"from" binding:"required" example:"2024-01"` // 起始月份（YYYY-MM）
+	To   string `json:"to" example:"2024-12"`                      // 结束月份（YYYY-MM），默认上月
+}
+
+// parseSnapshotMonthRange 解析月份范围（YYYY-MM），空值使用默认值；返回两端月份的第一天
+func parseSnapshotMonthRange(from, to string, defFrom, defTo time.Time) (time.Time, time.Time, error) {
+	start, end := defFrom, defTo
+	var err error
+	if from != "" {
+		if start, err = time.ParseInLocation("2006-01", from, time.Local); err != nil {
+			return time.Time{}, time.Time{}, errors.New("月份格式错误，应为: 2006-01")
+		}
+	}
+	if to != "" {
+		if end, err = time.ParseInLocation("2006-01", to, time.Local); err != nil {
+			return time.Time{}, time.Time{}, errors.New("月份格式错误，应为: 2006-01")
+		}
+	}
+	if start.After(end) {
+		return time.Time{}, time.Time{}, errors.New("起始月份不能晚于结束月份")
+	}
+	return start, end, nil
+}
+
+// History 结余曲线
+// @Summary 获取月末结余曲线
+// @Description 读取月末结余快照（每月 1 日自动生成上月快照），按月份升序返回当月收入、当月支出（不含内部转账）与截至月末的累计结余。
+// @Description 默认返回最近 12 个月；尚未生成快照的月份不返回
+// @Tags 结余
+// @Produce json
+// @Security BearerAuth
+// @Param from query string false "起始月份 (2024-01)"
+// @Param to query string false "结束月份 (2024-12)"
+// @Success 200 {object} Response{data=[]models.BalanceSnapshot} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/balance/history [get]
+func (h *BalanceHandler) History(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	lastMonth := service.MonthStart(time.Now()).AddDate(0, -1, 0)
+	start, end, err := parseSnapshotMonthRange(c.Query("from"), c.Query("to"), lastMonth.AddDate(0, -11, 0), lastMonth)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	var list []models.BalanceSnapshot
+	err = database.DB.Where("user_id = ? AND period >= ? AND period <= ?", userID, start.Format("2006-01"), end.Format("2006-01")).
+		Order("period ASC").Find(&list).Error
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// AdminRebuild 补算结余快照（仅超级管理员）
+// @Summary 补算结余快照
+// @Description 为所有用户重新生成指定月份范围内的月末结余快照，已有快照按 用户+月份 覆盖，可重复执行。
+// @Description 结束月份默认上月，不能包含当月；单次最多 120 个月
+// @Tags 后台管理-结余快照
+// @Accept json
+// @Produce json
+// @Param request body RebuildBalanceSnapshotsRequest true "月份范围"
+// @Success 200 {object} map[string]interface{} "补算完成，返回月份数与快照条数"
+// @Failure 400 {object} map[string]interface{} "请求参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/balance-snapshots/rebuild [post]
+func (h *BalanceHandler) AdminRebuild(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	var req RebuildBalanceSnapshotsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	lastMonth := service.MonthStart(time.Now()).AddDate(0, -1, 0)
+	start, end, err := parseSnapshotMonthRange(req.From, req.To, lastMonth, lastMonth)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if end.After(lastMonth) {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "只能补算已结束的月份"})
+		return
+	}
+	months := (end.Year()-start.Year())*12 + int(end.Month()-start.Month()) + 1
+	if months > maxBalanceRebuildMonths {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "单次最多补算 120 个月"})
+		return
+	}
+
+	total := 0
+	for m := start; !m.After(end); m = m.AddDate(0, 1, 0) {
+		n, err := service.GenerateBalanceSnapshots(m)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "补算 "+m.Format("2006-01")+" 失败")})
+			return
+		}
+		total += n
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "补算完成",
+		"data":    gin.H{"months": months, "snapshots": total},
+	})
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSnapshotMonthRange(t *testing.T) {
+	def := time.Date(2024, 6, 1, 0, 0, 0, 0, time.Local)
+	start, end, err := parseSnapshotMonthRange("", "", def.AddDate(0, -11, 0), def)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-07", start.Format("2006-01"))
+	assert.Equal(t, "2024-06", end.Format("2006-01"))
+
+	start, end, err = parseSnapshotMonthRange("2024-01", "2024-03", def, def)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01", start.Format("2006-01"))
+	assert.Equal(t, "2024-03", end.Format("2006-01"))
+
+	_, _, err = parseSnapshotMonthRange("2024-13", "", def, def)
+	assert.Error(t, err)
+	_, _, err = parseSnapshotMonthRange("2024-05", "2024-04", def, def)
+	assert.Error(t, err)
+}
+
+func TestBalanceHandler_History(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `balance_snapshots` WHERE user_id = \\? AND period >= \\? AND period <= \\? ORDER BY period ASC").
+		WithArgs(1, "2024-01", "2024-02").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "period", "total_income", "total_expense", "balance"}).
+			AddRow(1, 1, "2024-01", 5000, 300, 4700).
+			AddRow(2, 1, "2024-02", 5000, 800, 8900))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/balance/history", NewBalanceHandler().History)
+
+	req := httptest.NewRequest("GET", "/balance/history?from=2024-01&to=2024-02", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"balance":8900`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,71 @@
+package api
+
+import (
+	"finance/database"
+
+	"gorm.io/gorm"
+)
+
+// BatchFailure 批量操作中单项的失败原因；按请求顺序处理的接口返回 index，按记录 ID 处理的接口返回 id
+type BatchFailure struct {
+	Index  *int   `json:"index,omitempty"` // 该项在请求中的位置（从 0 开始）
+	ID     *uint  `json:"id,omitempty"`    // 记录ID
+	Key    string `json:"key,omitempty"`   // 便于识别的标识，如用户名
+	Reason string `json:"reason"`
+}
+
+// BatchResult 批量接口统一返回结构：成功数 = 总数 - 失败数
+type BatchResult struct {
+	Total        int            `json:"total"`
+	SuccessCount int            `json:"success_count"`
+	FailCount    int            `json:"fail_count"`
+	Failures     []BatchFailure `json:"failures"`
+	Warnings     []BatchFailure `json:"warnings,omitempty"` // 已成功但附带问题的项（如通知发送失败）
+}
+
+// NewBatchResult 创建批量结果，初始时全部视为成功
+func NewBatchResult(total int) *BatchResult {
+	return &BatchResult{Total: total, SuccessCount: total, Failures: []BatchFailure{}}
+}
+
+// FailIndex 记录第 index 项失败
+func (r *BatchResult) FailIndex(index int, key, reason string) {
+	r.fail(BatchFailure{Index: &index, Key: key, Reason: reason})
+}
+
+// FailID 记录 ID 为 id 的项失败
+func (r *BatchResult) FailID(id uint, reason string) {
+	r.fail(BatchFailure{ID: &id, Reason: reason})
+}
+
+// WarnIndex 记录第 index 项成功但附带问题
+func (r *BatchResult) WarnIndex(index int, key, reason string) {
+	r.Warnings = append(r.Warnings, BatchFailure{Index: &index, Key: key, Reason: reason})
+}
+
+func (r *BatchResult) fail(f BatchFailure) {
+	r.Failures = append(r.Failures, f)
+	r.FailCount++
+	r.SuccessCount = r.Total - r.FailCount
+}
+
+// BatchPending 已通过校验、等待写入的项
+type BatchPending struct {
+	Index int
+	Key   string
+}
+
+// CommitTx 在同一事务中执行 write，任一失败则全部回滚，并把 pending 中的每一项记为失败；返回是否写入成功
+func (r *BatchResult) CommitTx(pending []BatchPending, failMessage string, write func(tx *gorm.DB) error) bool {
+	if len(pending) == 0 {
+		return true
+	}
+	if err := database.DB.Transaction(write); err != nil {
+		reason := SafeErrorMessage(err, failMessage)
+		for _, p := range pending {
+			r.FailIndex(p.Index, p.Key, reason)
+		}
+		return false
+	}
+	return true
+}
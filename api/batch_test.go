@@ -0,0 +1,33 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchResult(t *testing.T) {
+	r := NewBatchResult(4)
+	assert.Equal(t, 4, r.SuccessCount)
+
+	r.FailIndex(1, "bob", "用户名已存在")
+	r.FailID(9, "记录不存在")
+	r.WarnIndex(2, "carol", "邮件发送失败")
+	assert.Equal(t, 2, r.SuccessCount)
+	assert.Equal(t, 2, r.FailCount)
+
+	data, err := json.Marshal(r)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{
+		"total": 4, "success_count": 2, "fail_count": 2,
+		"failures": [{"index": 1, "key": "bob", "reason": "用户名已存在"}, {"id": 9, "reason": "记录不存在"}],
+		"warnings": [{"index": 2, "key": "carol", "reason": "邮件发送失败"}]
+	}`, string(data))
+
+	// 全部成功时 failures 为空数组而非 null，省略 warnings
+	data, err = json.Marshal(NewBatchResult(2))
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"total": 2, "success_count": 2, "fail_count": 0, "failures": []}`, string(data))
+}
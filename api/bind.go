@@ -0,0 +1,85 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// trimmedJSONBinding 解析 JSON 后先去掉字符串字段首尾空白（含全角空格），再执行 binding 校验，
+// 避免" 餐饮"与"餐饮"被当成不同值，也让 required 等校验作用于去空白后的值。
+// 字段标记 trim:"-" 时保持原样（如密码）。skipValidate 为 true 时只解析不校验，由调用方逐项校验
+type trimmedJSONBinding struct {
+	skipValidate bool
+}
+
+func (trimmedJSONBinding) Name() string {
+	return "json"
+}
+
+func (b trimmedJSONBinding) Bind(req *http.Request, obj interface{}) error {
+	if req == nil || req.Body == nil {
+		return errors.New("invalid request")
+	}
+	decoder := json.NewDecoder(req.Body)
+	if binding.EnableDecoderUseNumber {
+		decoder.UseNumber()
+	}
+	if binding.EnableDecoderDisallowUnknownFields {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(obj); err != nil {
+		return err
+	}
+	trimStrings(reflect.ValueOf(obj))
+	if b.skipValidate || binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(obj)
+}
+
+// bindJSON 绑定 JSON 请求体并统一去除字符串字段首尾空白，创建/更新类接口使用它代替 ShouldBindJSON
+func bindJSON(c *gin.Context, obj interface{}) error {
+	return c.ShouldBindWith(obj, trimmedJSONBinding{})
+}
+
+// bindJSONItems 绑定 JSON 数组请求体并去除字符串首尾空白，但不执行 binding 校验；
+// 批量接口逐项校验，单项不合法时只记该项失败而不是拒绝整个请求
+func bindJSONItems(c *gin.Context, obj interface{}) error {
+	return c.ShouldBindWith(obj, trimmedJSONBinding{skipValidate: true})
+}
+
+// trimStrings 递归去除结构体（及结构体切片）中 string、*string、[]string 字段的首尾空白
+func trimStrings(v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			trimStrings(v.Elem())
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(strings.TrimSpace(v.String()))
+		}
+	case reflect.Slice:
+		switch v.Type().Elem().Kind() {
+		case reflect.String, reflect.Struct, reflect.Ptr:
+			for i := 0; i < v.Len(); i++ {
+				trimStrings(v.Index(i))
+			}
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < v.NumField(); i++ {
+			f := t.Field(i)
+			if !f.IsExported() || f.Tag.Get("trim") == "-" {
+				continue
+			}
+			trimStrings(v.Field(i))
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindJSON_TrimStrings(t *testing.T) {
+	type nested struct {
+		Label string `json:"label"`
+	}
+	type request struct {
+		Name     string   `json:"name" binding:"required"`
+		Category *string  `json:"category"`
+		Tags     []string `json:"tags"`
+		Password string   `json:"password" trim:"-"`
+		Nested   nested   `json:"nested"`
+	}
+
+	var got request
+	router := gin.New()
+	router.POST("/", func(c *gin.Context) {
+		if err := bindJSON(c, &got); err != nil {
+			c.String(400, err.Error())
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	body := `{"name":" 餐饮　","category":"  交通 ","tags":[" a ","b "],"password":" secret ","nested":{"label":" x "}}`
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(body)))
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "餐饮", got.Name)
+	require.NotNil(t, got.Category)
+	assert.Equal(t, "交通", *got.Category)
+	assert.Equal(t, []string{"a", "b"}, got.Tags)
+	assert.Equal(t, " secret ", got.Password)
+	assert.Equal(t, "x", got.Nested.Label)
+
+	// 校验作用于去空白后的值：纯空白不满足 required
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/", bytes.NewBufferString(`{"name":"   "}`)))
+	assert.Equal(t, 400, w.Code)
+}
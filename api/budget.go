@@ -0,0 +1,494 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// BudgetHandler 预算处理器（App端）
+type BudgetHandler struct{}
+
+// NewBudgetHandler 创建预算处理器
+func NewBudgetHandler() *BudgetHandler {
+	return &BudgetHandler{}
+}
+
+// CreateBudgetRequest 创建预算请求
+type CreateBudgetRequest struct {
+	Category    string  `json:"category" binding:"required" example:"餐饮"`
+	Month       string  `json:"month" binding:"required" example:"2024-01"`
+	LimitAmount float64 `json:"limit_amount" binding:"required,gt=0" example:"2000.00"`
+	WarnPercent *int    `json:"warn_percent" example:"80"` // 不传默认 80
+}
+
+// UpdateBudgetRequest 更新预算请求
+type UpdateBudgetRequest struct {
+	LimitAmount float64 `json:"limit_amount" binding:"omitempty,gt=0" example:"2000.00"`
+	WarnPercent *int    `json:"warn_percent" example:"80"`
+}
+
+// BudgetInfo 预算使用情况
+type BudgetInfo struct {
+	BudgetID     uint    `json:"budget_id" example:"1"`
+	Category     string  `json:"category" example:"餐饮"`
+	Month        string  `json:"month" example:"2024-01"`
+	LimitAmount  float64 `json:"limit_amount" example:"2000.00"`
+	Spent        float64 `json:"spent" example:"1700.00"`
+	Remaining    float64 `json:"remaining" example:"300.00"`
+	UsagePercent float64 `json:"usage_percent" example:"85.00"`
+	WarnPercent  int     `json:"warn_percent" example:"80"`
+	Level        string  `json:"level" example:"warning"` // normal / warning / exceeded
+	// 缺少汇率、未计入 spent 的币种
+	UnconvertedCurrencies []string `json:"unconverted_currencies,omitempty"`
+}
+
+// normalizeWarnPercent 校验提醒阈值，未传时使用默认值
+func normalizeWarnPercent(p *int) (int, error) {
+	if p == nil {
+		return models.DefaultBudgetWarnPercent, nil
+	}
+	if *p < 1 || *p > 99 {
+		return 0, errors.New("warn_percent 需在 1-99 之间")
+	}
+	return *p, nil
+}
+
+// parseBudgetMonth 解析预算月份（YYYY-MM），返回当月起止时间
+func parseBudgetMonth(month string) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation("2006-01", month, time.Local)
+	if err != nil {
+		return time.Time{}, time.Time{}, errors.New("月份格式错误，应为: 2006-01")
+	}
+	end := start.AddDate(0, 1, 0).Add(-time.Second)
+	return start, end, nil
+}
+
+// budgetLevel 根据使用率计算预算等级
+func budgetLevel(spent, limit float64, warnPercent int) string {
+	if limit <= 0 {
+		return models.BudgetLevelNormal
+	}
+	usage := spent / limit * 100
+	if usage >= 100 {
+		return models.BudgetLevelExceeded
+	}
+	if usage >= float64(warnPercent) {
+		return models.BudgetLevelWarning
+	}
+	return models.BudgetLevelNormal
+}
+
+// calcBudgetInfo 统计预算所在月份的实际消费（折算为本位币）并计算使用情况
+func calcBudgetInfo(b models.Budget, rates exchangeRates) (*BudgetInfo, error) {
+	start, end, err := parseBudgetMonth(b.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	spent, unconverted, err := sumInBaseCurrency(database.DB.Model(&models.Expense{}).
+		Where("user_id = ? AND category = ? AND expense_time >= ? AND expense_time <= ?", b.UserID, b.Category, start, end), rates)
+	if err != nil {
+		return nil, err
+	}
+
+	warn := b.WarnPercent
+	if warn <= 0 {
+		warn = models.DefaultBudgetWarnPercent
+	}
+
+	return &BudgetInfo{
+		BudgetID:     b.ID,
+		Category:     b.Category,
+		Month:        b.Month,
+		LimitAmount:  b.LimitAmount,
+		Spent:        spent,
+		Remaining:    b.LimitAmount - spent,
+		UsagePercent: math.Round(spent/b.LimitAmount*10000) / 100,
+		WarnPercent:  warn,
+		Level:        budgetLevel(spent, b.LimitAmount, warn),
+
+		UnconvertedCurrencies: unconverted,
+	}, nil
+}
+
+// budgetWarning 超支提示文案，未超支时返回空字符串
+func budgetWarning(info *BudgetInfo) string {
+	if info == nil || info.Level != models.BudgetLevelExceeded {
+		return ""
+	}
+	if info.Remaining >= 0 {
+		return fmt.Sprintf("%s %s 预算已用完（预算 %.2f，已花 %.2f）", info.Category, info.Month, info.LimitAmount, info.Spent)
+	}
+	return fmt.Sprintf("%s %s 预算已超支 %.2f（预算 %.2f，已花 %.2f）",
+		info.Category, info.Month, -info.Remaining, info.LimitAmount, info.Spent)
+}
+
+// findBudgetInfo 查找某用户某类别在指定时间所在月份的预算使用情况，无预算时返回 nil
+func findBudgetInfo(userID uint, category string, t time.Time) (*BudgetInfo, error) {
+	var b models.Budget
+	err := database.DB.Where("user_id = ? AND category = ? AND month = ?", userID, category, t.Format("2006-01")).
+		First(&b).Error
+	if err != nil {
+		return nil, err
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+	return calcBudgetInfo(b, rates)
+}
+
+// List 获取预算列表
+// @Summary 获取预算列表
+// @Description 获取当前用户的类别月度预算，可按月份筛选
+// @Tags 预算
+// @Produce json
+// @Security BearerAuth
+// @Param month query string false "月份 (YYYY-MM)"
+// @Success 200 {object} Response{data=[]models.Budget} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets [get]
+func (h *BudgetHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	query := database.DB.Where("user_id = ?", userID)
+	if month := c.Query("month"); month != "" {
+		query = query.Where("month = ?", month)
+	}
+
+	var list []models.Budget
+	if err := query.Order("month DESC, category ASC").Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// Create 创建预算
+// @Summary 创建预算
+// @Description 为某个消费类别设置月度预算，warn_percent 为提醒阈值（1-99，默认 80）
+// @Tags 预算
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateBudgetRequest true "预算信息"
+// @Success 200 {object} Response{data=models.Budget} "创建成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets [post]
+func (h *BudgetHandler) Create(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var req CreateBudgetRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	var cat models.ExpenseCategory
+	if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
+		BadRequest(c, "无效的消费类别")
+		return
+	}
+	if _, _, err := parseBudgetMonth(req.Month); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	warn, err := normalizeWarnPercent(req.WarnPercent)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	var count int64
+	database.DB.Model(&models.Budget{}).
+		Where("user_id = ? AND category = ? AND month = ?", userID, req.Category, req.Month).
+		Count(&count)
+	if count > 0 {
+		BadRequest(c, "该类别本月预算已存在")
+		return
+	}
+
+	budget := models.Budget{
+		UserID:      userID,
+		Category:    req.Category,
+		Month:       req.Month,
+		LimitAmount: req.LimitAmount,
+		WarnPercent: warn,
+	}
+	if err := database.DB.Create(&budget).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建预算失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", budget)
+}
+
+// Update 更新预算
+// @Summary 更新预算
+// @Description 更新预算额度或提醒阈值
+// @Tags 预算
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "预算ID"
+// @Param request body UpdateBudgetRequest true "预算信息"
+// @Success 200 {object} Response{data=models.Budget} "更新成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 404 {object} Response "预算不存在"
+// @Router /api/v1/budgets/{id} [put]
+func (h *BudgetHandler) Update(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&budget).Error; err != nil {
+		NotFound(c, "预算不存在")
+		return
+	}
+	var req UpdateBudgetRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	updates := map[string]interface{}{}
+	if req.LimitAmount > 0 {
+		updates["limit_amount"] = req.LimitAmount
+	}
+	if req.WarnPercent != nil {
+		warn, err := normalizeWarnPercent(req.WarnPercent)
+		if err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["warn_percent"] = warn
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&budget).Updates(updates).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "更新失败"))
+			return
+		}
+	}
+	database.DB.First(&budget, budget.ID)
+	SuccessWithMessage(c, "更新成功", budget)
+}
+
+// Delete 删除预算
+// @Summary 删除预算
+// @Tags 预算
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "预算ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 404 {object} Response "预算不存在"
+// @Router /api/v1/budgets/{id} [delete]
+func (h *BudgetHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var budget models.Budget
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&budget).Error; err != nil {
+		NotFound(c, "预算不存在")
+		return
+	}
+	if err := database.DB.Delete(&budget).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	SuccessWithMessage(c, "删除成功", nil)
+}
+
+// BudgetStatusResponse 本月预算执行情况
+type BudgetStatusResponse struct {
+	Month          string       `json:"month" example:"2024-01"` // 按 tz 计算的当前月份
+	TotalLimit     float64      `json:"total_limit" example:"5000.00"`
+	TotalSpent     float64      `json:"total_spent" example:"3200.00"`
+	TotalRemaining float64      `json:"total_remaining" example:"1800.00"`
+	Items          []BudgetInfo `json:"items"`
+}
+
+// Status 本月预算执行情况
+// @Summary 获取本月预算执行情况
+// @Description 返回当前月份每个预算的额度、已花（当月该类别消费合计）、剩余额度与等级，以及全部预算的合计。当前月份按 tz 计算，不传时使用服务器时区
+// @Tags 预算
+// @Produce json
+// @Security BearerAuth
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai"
+// @Success 200 {object} Response{data=BudgetStatusResponse} "获取成功"
+// @Failure 400 {object} Response "时区无效"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets/status [get]
+func (h *BudgetHandler) Status(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	month := now.Format("2006-01")
+
+	var budgets []models.Budget
+	if err := database.DB.Where("user_id = ? AND month = ?", userID, month).
+		Order("category ASC").
+		Find(&budgets).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "读取汇率失败"))
+		return
+	}
+
+	resp := BudgetStatusResponse{Month: month, Items: make([]BudgetInfo, 0, len(budgets))}
+	for _, b := range budgets {
+		info, err := calcBudgetInfo(b, rates)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "计算预算使用情况失败"))
+			return
+		}
+		resp.Items = append(resp.Items, *info)
+		resp.TotalLimit += info.LimitAmount
+		resp.TotalSpent += info.Spent
+	}
+	resp.TotalRemaining = resp.TotalLimit - resp.TotalSpent
+	Success(c, resp)
+}
+
+// budgetUnbudgetedLabel 无预算但有消费的类别所在分区标题
+const budgetUnbudgetedLabel = "无预算"
+
+// Export 导出预算对账表
+// @Summary 导出预算对账表
+// @Description 按月份导出"各类别预算 vs 实际"的 Excel：预算额、实际花费、差额、完成率，超支行高亮；有消费但未设预算的类别列在"无预算"区（默认不含内部转账类别）
+// @Tags 预算
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Security BearerAuth
+// @Param month query string false "月份 (YYYY-MM)，默认当月"
+// @Success 200 {file} file "Excel文件"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets/export [get]
+func (h *BudgetHandler) Export(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	month := c.DefaultQuery("month", time.Now().Format("2006-01"))
+	start, end, err := parseBudgetMonth(month)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	var budgets []models.Budget
+	if err := database.DB.Where("user_id = ? AND month = ?", userID, month).
+		Order("category ASC").
+		Find(&budgets).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "读取汇率失败"))
+		return
+	}
+
+	infos := make([]*BudgetInfo, 0, len(budgets))
+	budgeted := make(map[string]bool, len(budgets))
+	for _, b := range budgets {
+		info, err := calcBudgetInfo(b, rates)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "计算预算使用情况失败"))
+			return
+		}
+		infos = append(infos, info)
+		budgeted[b.Category] = true
+	}
+
+	// 当月有消费的类别（折算为本位币），用于找出无预算的部分
+	rows, err := sumByCategoryAndCurrency(excludeTransferCategories(database.DB.Model(&models.Expense{}), "category").
+		Where("user_id = ? AND expense_time >= ? AND expense_time <= ?", userID, start, end), "category")
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	spentList := convertCurrencyStats(rows, rates).Categories
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	sheetName := "预算对账"
+	f.SetSheetName("Sheet1", sheetName)
+
+	headerStyle, _ := excelHeaderStyle(f)
+	dataStyle, _ := excelDataStyle(f, "0.00")
+	percentStyle, _ := excelDataStyle(f, "0.00%")
+	overStyle, _ := excelHighlightStyle(f, "FFC7CE", "0.00")
+	overPercentStyle, _ := excelHighlightStyle(f, "FFC7CE", "0.00%")
+	summaryStyle, _ := excelSummaryStyle(f, "0.00")
+
+	f.SetColWidth(sheetName, "A", "A", 16)
+	f.SetColWidth(sheetName, "B", "F", 14)
+
+	// 有预算的类别
+	excelWriteRow(f, sheetName, 1, []interface{}{"类别", "预算额", "实际花费", "差额", "完成率", "状态"}, headerStyle)
+	row := 2
+	var totalLimit, totalSpent float64
+	for _, info := range infos {
+		status := "正常"
+		rowStyle, rateStyle := dataStyle, percentStyle
+		switch info.Level {
+		case models.BudgetLevelExceeded:
+			status = "超支"
+			rowStyle, rateStyle = overStyle, overPercentStyle
+		case models.BudgetLevelWarning:
+			status = "预警"
+		}
+		excelWriteRow(f, sheetName, row, []interface{}{
+			info.Category, info.LimitAmount, info.Spent, info.Remaining, info.UsagePercent / 100, status,
+		}, rowStyle)
+		f.SetCellStyle(sheetName, fmt.Sprintf("E%d", row), fmt.Sprintf("E%d", row), rateStyle)
+		totalLimit += info.LimitAmount
+		totalSpent += info.Spent
+		row++
+	}
+	excelWriteRow(f, sheetName, row, []interface{}{"合计", totalLimit, totalSpent, totalLimit - totalSpent, "", ""}, summaryStyle)
+	row += 2
+
+	// 无预算但有消费的类别
+	excelWriteRow(f, sheetName, row, []interface{}{budgetUnbudgetedLabel, "", "实际花费"}, headerStyle)
+	row++
+	var unbudgetedTotal float64
+	for _, s := range spentList {
+		if budgeted[s.Category] {
+			continue
+		}
+		excelWriteRow(f, sheetName, row, []interface{}{s.Category, "", s.Total}, dataStyle)
+		unbudgetedTotal += s.Total
+		row++
+	}
+	excelWriteRow(f, sheetName, row, []interface{}{"合计", "", unbudgetedTotal}, summaryStyle)
+
+	filename := fmt.Sprintf("预算对账_%s.xlsx", month)
+	if err := writeExcelResponse(c, f, filename); err != nil {
+		InternalError(c, "生成 Excel 失败")
+		return
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"math"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BudgetDailyAllowance 某类别本月预算的建议每日可用额度
+type BudgetDailyAllowance struct {
+	BudgetInfo
+	DailyAllowance float64 `json:"daily_allowance" example:"50.00"` // 剩余额度 / 本月剩余天数；已超支时为负值
+}
+
+// DailyAllowanceResponse 每日可用额度返回
+type DailyAllowanceResponse struct {
+	Month         string                 `json:"month" example:"2024-01"`    // 按 tz 计算的当前月份
+	Today         string                 `json:"today" example:"2024-01-25"` // 按 tz 计算的今天
+	Timezone      string                 `json:"timezone" example:"Asia/Shanghai"`
+	RemainingDays int                    `json:"remaining_days" example:"7"` // 本月剩余天数（含今天），月末最后一天为 1
+	Items         []BudgetDailyAllowance `json:"items"`
+}
+
+// monthRemainingDays 返回 now 所在月份（YYYY-MM）及本月剩余天数（含当天）
+func monthRemainingDays(now time.Time) (string, int) {
+	// 下月 1 日的前一天即本月最后一天，自动处理大小月与闰年
+	lastDay := time.Date(now.Year(), now.Month()+1, 0, 0, 0, 0, 0, now.Location()).Day()
+	return now.Format("2006-01"), lastDay - now.Day() + 1
+}
+
+// calcDailyAllowance 剩余额度平摊到剩余天数；结余按分向下取整，避免建议额度合计超出预算
+func calcDailyAllowance(remaining float64, days int) float64 {
+	if days < 1 {
+		days = 1
+	}
+	v := remaining / float64(days)
+	if v >= 0 {
+		return math.Floor(v*100+1e-9) / 100
+	}
+	return math.Round(v*100) / 100
+}
+
+// loadBudgetAllowances 统计用户某月各预算类别的使用情况及每日可用额度，按类别排序
+func loadBudgetAllowances(userID uint, month string, days int) ([]BudgetDailyAllowance, error) {
+	var budgets []models.Budget
+	if err := database.DB.Where("user_id = ? AND month = ?", userID, month).
+		Order("category ASC").
+		Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return []BudgetDailyAllowance{}, nil
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]BudgetDailyAllowance, 0, len(budgets))
+	for _, b := range budgets {
+		info, err := calcBudgetInfo(b, rates)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, BudgetDailyAllowance{
+			BudgetInfo:     *info,
+			DailyAllowance: calcDailyAllowance(info.Remaining, days),
+		})
+	}
+	return items, nil
+}
+
+// DailyAllowance 本月预算的每日可用额度
+// @Summary 获取预算每日可用额度
+// @Description 对当前月份每个设置了预算的类别，按（剩余额度 / 本月剩余天数）给出建议每日可用额度，已超支的类别为负值且 level=exceeded。
+// @Description 当前月份与剩余天数按 tz 计算（含今天，月末最后一天剩余 1 天），不传 tz 时使用服务器时区
+// @Tags 预算
+// @Produce json
+// @Security BearerAuth
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai"
+// @Success 200 {object} Response{data=DailyAllowanceResponse} "获取成功"
+// @Failure 400 {object} Response "时区无效"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets/daily-allowance [get]
+func (h *BudgetHandler) DailyAllowance(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	loc, err := requestLocation(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	now := time.Now().In(loc)
+	month, days := monthRemainingDays(now)
+
+	items, err := loadBudgetAllowances(userID, month, days)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "计算预算使用情况失败"))
+		return
+	}
+
+	Success(c, DailyAllowanceResponse{
+		Month:         month,
+		Today:         now.Format("2006-01-02"),
+		Timezone:      loc.String(),
+		RemainingDays: days,
+		Items:         items,
+	})
+}
@@ -0,0 +1,110 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthRemainingDays(t *testing.T) {
+	cases := []struct {
+		now   time.Time
+		month string
+		days  int
+	}{
+		{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), "2024-01", 31},
+		{time.Date(2024, 1, 25, 10, 0, 0, 0, time.UTC), "2024-01", 7},
+		{time.Date(2024, 1, 31, 23, 59, 59, 0, time.UTC), "2024-01", 1}, // 月末最后一天
+		{time.Date(2024, 2, 29, 8, 0, 0, 0, time.UTC), "2024-02", 1},    // 闰年 2 月
+		{time.Date(2023, 2, 28, 8, 0, 0, 0, time.UTC), "2023-02", 1},
+		{time.Date(2024, 4, 30, 8, 0, 0, 0, time.UTC), "2024-04", 1},
+	}
+	for _, tc := range cases {
+		month, days := monthRemainingDays(tc.now)
+		assert.Equal(t, tc.month, month, tc.now)
+		assert.Equal(t, tc.days, days, tc.now)
+	}
+}
+
+func TestMonthRemainingDays_Timezone(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	// UTC 还在 1 月 31 日，上海已是 2 月 1 日
+	now := time.Date(2024, 1, 31, 17, 0, 0, 0, time.UTC)
+	month, days := monthRemainingDays(now.In(shanghai))
+	assert.Equal(t, "2024-02", month)
+	assert.Equal(t, 29, days)
+
+	month, days = monthRemainingDays(now)
+	assert.Equal(t, "2024-01", month)
+	assert.Equal(t, 1, days)
+}
+
+func TestCalcDailyAllowance(t *testing.T) {
+	assert.Equal(t, 33.33, calcDailyAllowance(100, 3))
+	assert.Equal(t, 0.7, calcDailyAllowance(2.1, 3))
+	assert.Equal(t, 300.0, calcDailyAllowance(300, 1))
+	assert.Equal(t, -33.33, calcDailyAllowance(-100, 3))
+	assert.Equal(t, 50.0, calcDailyAllowance(50, 0))
+}
+
+func TestBudgetHandler_DailyAllowance(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	month := time.Now().In(time.UTC).Format("2006-01")
+	mock.ExpectQuery("SELECT \\* FROM `budgets` WHERE \\(user_id = \\? AND month = \\?\\)").
+		WithArgs(1, month).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "category", "month", "limit_amount", "warn_percent"}).
+			AddRow(1, 1, "交通", month, 500, 80).
+			AddRow(2, 1, "餐饮", month, 1000, 80))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 100))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 1200))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/daily-allowance", NewBudgetHandler().DailyAllowance)
+
+	req := httptest.NewRequest("GET", "/budgets/daily-allowance?tz=UTC", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data DailyAllowanceResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, month, resp.Data.Month)
+	assert.Equal(t, "UTC", resp.Data.Timezone)
+	require.Len(t, resp.Data.Items, 2)
+	assert.Equal(t, calcDailyAllowance(400, resp.Data.RemainingDays), resp.Data.Items[0].DailyAllowance)
+	assert.Less(t, resp.Data.Items[1].DailyAllowance, 0.0)
+	assert.Equal(t, models.BudgetLevelExceeded, resp.Data.Items[1].Level)
+}
+
+func TestBudgetHandler_DailyAllowance_InvalidTimezone(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/daily-allowance", NewBudgetHandler().DailyAllowance)
+
+	req := httptest.NewRequest("GET", "/budgets/daily-allowance?tz=Mars/Base", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,218 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestNormalizeWarnPercent(t *testing.T) {
+	v, err := normalizeWarnPercent(nil)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultBudgetWarnPercent, v)
+
+	p := 50
+	v, err = normalizeWarnPercent(&p)
+	require.NoError(t, err)
+	assert.Equal(t, 50, v)
+
+	for _, bad := range []int{0, -10, 100, 150} {
+		b := bad
+		_, err := normalizeWarnPercent(&b)
+		assert.Error(t, err, "warn_percent=%d 应非法", bad)
+	}
+}
+
+func TestBudgetLevel(t *testing.T) {
+	assert.Equal(t, models.BudgetLevelNormal, budgetLevel(500, 1000, 80))
+	assert.Equal(t, models.BudgetLevelWarning, budgetLevel(800, 1000, 80))
+	assert.Equal(t, models.BudgetLevelWarning, budgetLevel(999.99, 1000, 80))
+	assert.Equal(t, models.BudgetLevelExceeded, budgetLevel(1000, 1000, 80))
+	assert.Equal(t, models.BudgetLevelExceeded, budgetLevel(1200, 1000, 80))
+	assert.Equal(t, models.BudgetLevelNormal, budgetLevel(100, 0, 80))
+}
+
+func TestBudgetHandler_Create_InvalidWarnPercent(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sort", "color", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "餐饮", 10, "#ef4444", time.Now(), time.Now(), nil))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/budgets", NewBudgetHandler().Create)
+
+	body := `{"category":"餐饮","month":"2024-01","limit_amount":1000,"warn_percent":120}`
+	req := httptest.NewRequest("POST", "/budgets", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "warn_percent")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetWarning(t *testing.T) {
+	assert.Equal(t, "", budgetWarning(nil))
+	assert.Equal(t, "", budgetWarning(&BudgetInfo{Level: models.BudgetLevelWarning}))
+	assert.Equal(t, "餐饮 2024-01 预算已超支 150.00（预算 1000.00，已花 1150.00）",
+		budgetWarning(&BudgetInfo{Category: "餐饮", Month: "2024-01", LimitAmount: 1000, Spent: 1150, Remaining: -150, Level: models.BudgetLevelExceeded}))
+	assert.Equal(t, "餐饮 2024-01 预算已用完（预算 1000.00，已花 1000.00）",
+		budgetWarning(&BudgetInfo{Category: "餐饮", Month: "2024-01", LimitAmount: 1000, Spent: 1000, Remaining: 0, Level: models.BudgetLevelExceeded}))
+}
+
+func TestBudgetHandler_Status(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	month := time.Now().Format("2006-01")
+	mock.ExpectQuery("SELECT .* FROM `budgets` WHERE \\(user_id = \\? AND month = \\?\\)").
+		WithArgs(1, month).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "category", "month", "limit_amount", "warn_percent"}).
+			AddRow(1, 1, "交通", month, 500, 80).
+			AddRow(2, 1, "餐饮", month, 1000, 80))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 100))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 1200))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/status", NewBudgetHandler().Status)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/budgets/status", nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data BudgetStatusResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, month, resp.Data.Month)
+	assert.Equal(t, 1500.0, resp.Data.TotalLimit)
+	assert.Equal(t, 1300.0, resp.Data.TotalSpent)
+	assert.Equal(t, 200.0, resp.Data.TotalRemaining)
+	require.Len(t, resp.Data.Items, 2)
+	assert.Equal(t, 400.0, resp.Data.Items[0].Remaining)
+	assert.Equal(t, models.BudgetLevelExceeded, resp.Data.Items[1].Level)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_BudgetWarning(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sort", "color", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "餐饮", 10, "#ef4444", time.Now(), time.Now(), nil))
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	// 查询当月预算
+	mock.ExpectQuery("SELECT .* FROM `budgets`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "category", "month", "limit_amount", "warn_percent"}).
+			AddRow(1, 1, "餐饮", "2024-01", 1000, 80))
+	// 汇总当月消费：700 CNY + 20 USD（汇率 7）
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 710).AddRow("USD", 20))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":50,"category":"餐饮","expense_time":"2024-01-15 12:30:00"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			ID         uint        `json:"id"`
+			BudgetInfo *BudgetInfo `json:"budget_info"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotNil(t, resp.Data.BudgetInfo)
+	assert.Equal(t, models.BudgetLevelWarning, resp.Data.BudgetInfo.Level)
+	assert.Equal(t, 150.0, resp.Data.BudgetInfo.Remaining)
+	assert.NotContains(t, w.Body.String(), "budget_warning")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBudgetHandler_Export(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `budgets`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "category", "month", "limit_amount", "warn_percent"}).
+			AddRow(1, 1, "餐饮", "2024-01", 1000, 80))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).AddRow("CNY", 1200))
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`.* GROUP BY category, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("餐饮", "CNY", 1200, 3).
+			AddRow("交通", "CNY", 160, 2).
+			AddRow("交通", "USD", 20, 1))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/export", NewBudgetHandler().Export)
+
+	req := httptest.NewRequest("GET", "/budgets/export?month=2024-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("预算对账")
+	require.NoError(t, err)
+	assert.Equal(t, "餐饮", rows[1][0])
+	assert.Equal(t, "超支", rows[1][5])
+	// 合计行后空一行，再是"无预算"区
+	assert.Equal(t, budgetUnbudgetedLabel, rows[4][0])
+	assert.Equal(t, "交通", rows[5][0])
+	assert.Equal(t, "300.00", rows[5][2])
+}
+
+func TestBudgetHandler_Export_InvalidMonth(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/export", NewBudgetHandler().Export)
+
+	req := httptest.NewRequest("GET", "/budgets/export?month=2024/01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 预算趋势查询的月份数
+const (
+	defaultBudgetTrendMonths = 6
+	maxBudgetTrendMonths     = 24
+)
+
+// BudgetTrendPoint 某月的预算完成情况
+type BudgetTrendPoint struct {
+	Month        string   `json:"month" example:"2024-01"`
+	HasBudget    bool     `json:"has_budget" example:"true"`
+	LimitAmount  *float64 `json:"limit_amount" example:"2000.00"` // 当月预算，无预算时为 null
+	Spent        float64  `json:"spent" example:"1700.00"`        // 当月实际花费，无预算时照常统计
+	UsagePercent *float64 `json:"usage_percent" example:"85.00"`  // 完成率 = 花费 / 预算 × 100，无预算时为 null
+	Level        string   `json:"level,omitempty" example:"warning"`
+}
+
+// BudgetTrendResponse 预算滚动对比返回
+type BudgetTrendResponse struct {
+	Category string             `json:"category" example:"餐饮"`
+	Months   int                `json:"months" example:"6"`
+	Items    []BudgetTrendPoint `json:"items"` // 按月份从早到晚，最后一项为当前月份
+}
+
+// recentMonths 返回截至 now 所在月份的最近 n 个月（YYYY-MM），从早到晚
+func recentMonths(now time.Time, n int) []string {
+	months := make([]string, n)
+	for i := 0; i < n; i++ {
+		// 固定取 1 日再回退月份，避免 31 日回退到小月时溢出
+		t := time.Date(now.Year(), now.Month()-time.Month(n-1-i), 1, 0, 0, 0, 0, now.Location())
+		months[i] = t.Format("2006-01")
+	}
+	return months
+}
+
+// buildBudgetTrend 按月份合并预算与花费；每月使用该月自己设置的预算，没有则为占位
+func buildBudgetTrend(months []string, budgets []models.Budget, spent map[string]float64) []BudgetTrendPoint {
+	byMonth := make(map[string]models.Budget, len(budgets))
+	for _, b := range budgets {
+		byMonth[b.Month] = b
+	}
+
+	items := make([]BudgetTrendPoint, 0, len(months))
+	for _, month := range months {
+		point := BudgetTrendPoint{Month: month, Spent: spent[month]}
+		if b, ok := byMonth[month]; ok && b.LimitAmount > 0 {
+			limit := b.LimitAmount
+			usage := math.Round(point.Spent/limit*10000) / 100
+			warn := b.WarnPercent
+			if warn <= 0 {
+				warn = models.DefaultBudgetWarnPercent
+			}
+			point.HasBudget = true
+			point.LimitAmount = &limit
+			point.UsagePercent = &usage
+			point.Level = budgetLevel(point.Spent, limit, warn)
+		}
+		items = append(items, point)
+	}
+	return items
+}
+
+// Trend 某类别最近 N 个月的预算完成情况
+// @Summary 获取预算滚动对比
+// @Description 返回某消费类别最近 N 个月（含当前月份）每月的预算额、实际花费与完成率，按月份从早到晚排列。
+// @Description 预算按月设置，每月取该月自己的预算（即当时生效的值），不会用其他月份的预算补齐；某月没有预算时 has_budget=false，limit_amount 与 usage_percent 为 null，spent 照常统计
+// @Tags 预算
+// @Produce json
+// @Security BearerAuth
+// @Param category query string true "消费类别"
+// @Param months query int false "月份数，默认 6，最大 24"
+// @Success 200 {object} Response{data=BudgetTrendResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/budgets/trend [get]
+func (h *BudgetHandler) Trend(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	category := c.Query("category")
+	if category == "" {
+		BadRequest(c, "请指定类别")
+		return
+	}
+	n := defaultBudgetTrendMonths
+	if s := c.Query("months"); s != "" {
+		v, err := strconv.Atoi(s)
+		if err != nil || v < 1 || v > maxBudgetTrendMonths {
+			BadRequest(c, "months 需在 1-24 之间")
+			return
+		}
+		n = v
+	}
+
+	months := recentMonths(time.Now(), n)
+	start, _, _ := parseBudgetMonth(months[0])
+	_, end, _ := parseBudgetMonth(months[len(months)-1])
+
+	var budgets []models.Budget
+	if err := database.DB.Where("user_id = ? AND category = ? AND month IN ?", userID, category, months).
+		Find(&budgets).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询预算失败"))
+		return
+	}
+
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "读取汇率失败"))
+		return
+	}
+
+	var expenses []models.Expense
+	if err := database.DB.Select("expense_time", "amount", "currency").
+		Where("user_id = ? AND category = ? AND expense_time >= ? AND expense_time <= ?", userID, category, start, end).
+		Find(&expenses).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询消费记录失败"))
+		return
+	}
+	// 按本位币累计，缺少汇率的币种不计入
+	spent := make(map[string]float64, n)
+	for _, e := range expenses {
+		amount, ok := rates.ToBase(e.Amount, e.Currency)
+		if !ok {
+			continue
+		}
+		spent[e.ExpenseTime.In(time.Local).Format("2006-01")] += amount
+	}
+	for month, v := range spent {
+		spent[month] = math.Round(v*100) / 100
+	}
+
+	Success(c, BudgetTrendResponse{
+		Category: category,
+		Months:   n,
+		Items:    buildBudgetTrend(months, budgets, spent),
+	})
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecentMonths(t *testing.T) {
+	now := time.Date(2024, 3, 31, 12, 0, 0, 0, time.UTC)
+	assert.Equal(t, []string{"2023-11", "2023-12", "2024-01", "2024-02", "2024-03"}, recentMonths(now, 5))
+	assert.Equal(t, []string{"2024-03"}, recentMonths(now, 1))
+}
+
+func TestBuildBudgetTrend(t *testing.T) {
+	months := []string{"2024-01", "2024-02", "2024-03"}
+	budgets := []models.Budget{
+		{Month: "2024-01", LimitAmount: 1000, WarnPercent: 80},
+		{Month: "2024-03", LimitAmount: 500},
+	}
+	spent := map[string]float64{"2024-01": 850, "2024-02": 300, "2024-03": 600}
+
+	items := buildBudgetTrend(months, budgets, spent)
+	require.Len(t, items, 3)
+
+	assert.True(t, items[0].HasBudget)
+	assert.Equal(t, 1000.0, *items[0].LimitAmount)
+	assert.Equal(t, 85.0, *items[0].UsagePercent)
+	assert.Equal(t, models.BudgetLevelWarning, items[0].Level)
+
+	// 无预算的月份：占位，花费照常统计
+	assert.False(t, items[1].HasBudget)
+	assert.Nil(t, items[1].LimitAmount)
+	assert.Nil(t, items[1].UsagePercent)
+	assert.Equal(t, 300.0, items[1].Spent)
+	assert.Empty(t, items[1].Level)
+
+	// 每月使用自己的预算，不沿用上月
+	assert.Equal(t, 500.0, *items[2].LimitAmount)
+	assert.Equal(t, 120.0, *items[2].UsagePercent)
+	assert.Equal(t, models.BudgetLevelExceeded, items[2].Level)
+}
+
+func TestBudgetHandler_Trend(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	months := recentMonths(time.Now(), 3)
+	mock.ExpectQuery("SELECT \\* FROM `budgets` WHERE \\(user_id = \\? AND category = \\? AND month IN \\(\\?,\\?,\\?\\)\\)").
+		WithArgs(1, "餐饮", months[0], months[1], months[2]).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "category", "month", "limit_amount", "warn_percent"}).
+			AddRow(1, 1, "餐饮", months[2], 1000, 80))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT `expense_time`,`amount`,`currency` FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"expense_time", "amount", "currency"}).
+			AddRow(time.Now(), 160, "CNY").
+			AddRow(time.Now(), 20, "USD").
+			AddRow(time.Now(), 300, "JPY"))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/trend", NewBudgetHandler().Trend)
+
+	req := httptest.NewRequest("GET", "/budgets/trend?category=%E9%A4%90%E9%A5%AE&months=3", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data BudgetTrendResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "餐饮", resp.Data.Category)
+	require.Len(t, resp.Data.Items, 3)
+	assert.False(t, resp.Data.Items[0].HasBudget)
+	last := resp.Data.Items[2]
+	assert.Equal(t, months[2], last.Month)
+	assert.Equal(t, 300.0, last.Spent)
+	assert.Equal(t, 30.0, *last.UsagePercent)
+}
+
+func TestBudgetHandler_Trend_InvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/budgets/trend", NewBudgetHandler().Trend)
+
+	for _, q := range []string{"", "?category=a&months=0", "?category=a&months=25", "?category=a&months=x"} {
+		req := httptest.NewRequest("GET", "/budgets/trend"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, q)
+	}
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"net/http"
+
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetCacheMetrics 缓存指标（仅超级管理员）
+// @Summary 查看缓存命中率
+// @Description 返回各进程内缓存（如消费统计缓存）自启动以来的命中/未命中次数、命中率、因数据变更失效的条目数、当前条目数及内存占用估算。
+// @Description 条目数与内存估算不含已过期的条目；多实例部署时仅反映当前实例
+// @Tags 后台管理-系统
+// @Produce json
+// @Success 200 {object} map[string]interface{} "获取成功，data 为 []service.CacheStats"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/metrics/cache [get]
+func (h *AdminHandler) GetCacheMetrics(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": service.AllCacheStats()})
+}
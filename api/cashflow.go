@@ -0,0 +1,251 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// CashFlowMonth 单月现金流，金额均已折算为本位币
+type CashFlowMonth struct {
+	Month   string  `json:"month" example:"2024-01"`
+	Income  float64 `json:"income" example:"8000.00"`  // 当月收入
+	Expense float64 `json:"expense" example:"3500.00"` // 当月支出
+	Net     float64 `json:"net" example:"4500.00"`     // 收入 - 支出
+	Balance float64 `json:"balance" example:"4500.00"` // 年内累计净额（1 月起累加至当月）
+}
+
+// CashFlowResponse 年度现金流
+type CashFlowResponse struct {
+	Year                  int             `json:"year" example:"2024"`
+	BaseCurrency          string          `json:"base_currency" example:"CNY"`
+	TotalIncome           float64         `json:"total_income"`
+	TotalExpense          float64         `json:"total_expense"`
+	Net                   float64         `json:"net"`
+	Months                []CashFlowMonth `json:"months"`                 // 固定 12 个月，无记录的月份为 0
+	UnconvertedCurrencies []string        `json:"unconverted_currencies"` // 缺少汇率、未计入的币种
+}
+
+// monthCurrencyRow 按月份与币种分组的金额汇总
+type monthCurrencyRow struct {
+	Month    int
+	Currency string
+	Total    float64
+}
+
+// parseCashFlowYear 解析 year 参数，默认今年
+func parseCashFlowYear(c *gin.Context, now time.Time) (int, error) {
+	year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+	if err != nil || year < 2000 || year > 2100 {
+		return 0, errors.New("year格式错误，应为4位数字（如：2024）")
+	}
+	return year, nil
+}
+
+// monthExpr 返回取时间列月份（1-12）的 SQL 表达式，按数据库方言处理
+func monthExpr(dialect, timeColumn string) string {
+	if dialect == "sqlite" {
+		// SQLite 按文本存储本地时间（YYYY-MM-DD HH:MM:SS...），直接截取月份，避免 strftime 换算到 UTC
+		return "CAST(substr(" + timeColumn + ", 6, 2) AS INTEGER)"
+	}
+	return "MONTH(" + timeColumn + ")"
+}
+
+// sumByMonthAndCurrency 在 query 已有的筛选条件上按月份与币种分组汇总金额；timeColumn 为记录时间列
+func sumByMonthAndCurrency(query *gorm.DB, timeColumn string) ([]monthCurrencyRow, error) {
+	var rows []monthCurrencyRow
+	month := monthExpr(query.Dialector.Name(), timeColumn)
+	err := query.
+		Select(month + " AS month, currency, SUM(amount) AS total").
+		Group(month + ", currency").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// queryCashFlow 统计 year 年每月的收入与支出。scope 限定记录归属，支出默认排除内部转账类别；
+// 收入、支出各做一次按月分组汇总，再在内存中按月合并，保证 12 个月都出现
+func queryCashFlow(scope func(*gorm.DB) *gorm.DB, year int, withTransfer bool) (CashFlowResponse, error) {
+	start := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(year, 12, 31, 23, 59, 59, 0, time.Local)
+
+	incomeQ := database.DB.Model(&models.Income{}).Scopes(scope).
+		Where("income_time >= ? AND income_time <= ?", start, end)
+	incomeRows, err := sumByMonthAndCurrency(incomeQ, "income_time")
+	if err != nil {
+		return CashFlowResponse{}, err
+	}
+	expenseQ := database.DB.Model(&models.Expense{}).Scopes(scope).
+		Where("expense_time >= ? AND expense_time <= ?", start, end)
+	if !withTransfer {
+		expenseQ = excludeTransferCategories(expenseQ, "category")
+	}
+	expenseRows, err := sumByMonthAndCurrency(expenseQ, "expense_time")
+	if err != nil {
+		return CashFlowResponse{}, err
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return CashFlowResponse{}, err
+	}
+	return buildCashFlow(year, incomeRows, expenseRows, rates), nil
+}
+
+// buildCashFlow 把按月汇总的收入、支出折算为本位币并合并为 12 个月的现金流，缺少汇率的币种不计入
+func buildCashFlow(year int, incomeRows, expenseRows []monthCurrencyRow, rates exchangeRates) CashFlowResponse {
+	var income, expense [12]float64
+	var unconverted []string
+	add := func(sums *[12]float64, rows []monthCurrencyRow) {
+		for _, row := range rows {
+			if row.Month < 1 || row.Month > 12 {
+				continue
+			}
+			amount, ok := rates.ToBase(row.Total, row.Currency)
+			if !ok {
+				unconverted = append(unconverted, row.Currency)
+				continue
+			}
+			sums[row.Month-1] += amount
+		}
+	}
+	add(&income, incomeRows)
+	add(&expense, expenseRows)
+
+	resp := CashFlowResponse{
+		Year:                  year,
+		BaseCurrency:          baseCurrency(),
+		Months:                make([]CashFlowMonth, 12),
+		UnconvertedCurrencies: mergeCurrencyCodes(unconverted),
+	}
+	var balance float64
+	for i := 0; i < 12; i++ {
+		m := CashFlowMonth{
+			Month:   fmt.Sprintf("%04d-%02d", year, i+1),
+			Income:  roundMoney(income[i]),
+			Expense: roundMoney(expense[i]),
+		}
+		m.Net = roundMoney(m.Income - m.Expense)
+		balance += m.Net
+		m.Balance = roundMoney(balance)
+		resp.Months[i] = m
+		resp.TotalIncome += m.Income
+		resp.TotalExpense += m.Expense
+	}
+	resp.TotalIncome = roundMoney(resp.TotalIncome)
+	resp.TotalExpense = roundMoney(resp.TotalExpense)
+	resp.Net = roundMoney(resp.TotalIncome - resp.TotalExpense)
+	return resp
+}
+
+// GetCashFlow 获取年度现金流
+// @Summary 获取年度现金流
+// @Description 按月返回指定年份的收入、支出、净额（收入 - 支出）与年内累计净额，固定 12 个月，无记录的月份为 0。
+// @Description 统计当前用户（或当前共享账本）的记录；管理员可传 user_id 统计指定用户。金额按汇率折算为本位币，缺少汇率的币种不计入并列在 unconverted_currencies 中
+// @Tags 统计
+// @Produce json
+// @Security BearerAuth
+// @Param year query int false "年份（如 2024），默认今年（按 tz 计算）"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，默认服务器时区"
+// @Param user_id query int false "用户ID（仅管理员可用）"
+// @Param include_transfer query bool false "支出是否包含内部转账类别，默认不包含"
+// @Success 200 {object} Response{data=CashFlowResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Failure 403 {object} Response "非管理员不能查看其他用户"
+// @Router /api/v1/statistics/cashflow [get]
+func (h *ExpenseHandler) GetCashFlow(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	year, err := parseCashFlowYear(c, now)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	scope := recordScope(c, userID)
+	if v := c.Query("user_id"); v != "" {
+		uid, err := strconv.ParseUint(v, 10, 32)
+		if err != nil {
+			BadRequest(c, "无效的用户ID")
+			return
+		}
+		if uint(uid) != userID {
+			var user models.User
+			if err := database.DB.Select("id", "is_admin").First(&user, userID).Error; err != nil || !user.IsAdmin {
+				Error(c, http.StatusForbidden, "权限不足，只能查看自己的现金流")
+				return
+			}
+		}
+		scope = func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", uint(uid)) }
+	}
+
+	resp, err := queryCashFlow(scope, year, includeTransfer(c))
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, resp)
+}
+
+// AdminCashFlow 获取年度现金流（后台）
+// @Summary 获取年度现金流（后台）
+// @Description 按月返回指定年份的收入、支出、净额与年内累计净额，固定 12 个月。管理员可传user_id统计指定用户，非管理员只能统计自己的数据（忽略user_id）
+// @Tags 后台管理-统计
+// @Produce json
+// @Param year query int false "年份（如 2024），默认今年"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param user_id query int false "用户ID（仅管理员可用）"
+// @Param include_transfer query bool false "支出是否包含内部转账类别，默认不包含"
+// @Success 200 {object} map[string]interface{} "获取成功，data 为 CashFlowResponse"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/statistics/cashflow [get]
+func (h *AdminHandler) AdminCashFlow(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	year, err := parseCashFlowYear(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	targetUserID := currentUser.ID
+	if userIDFilter := c.Query("user_id"); currentUser.IsAdmin && userIDFilter != "" {
+		uid, err := strconv.ParseUint(userIDFilter, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的用户ID"})
+			return
+		}
+		targetUserID = uint(uid)
+	}
+
+	scope := func(db *gorm.DB) *gorm.DB { return db.Where("user_id = ?", targetUserID) }
+	resp, err := queryCashFlow(scope, year, includeTransfer(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": resp})
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonthExpr(t *testing.T) {
+	assert.Equal(t, "MONTH(expense_time)", monthExpr("mysql", "expense_time"))
+	assert.Equal(t, "CAST(substr(income_time, 6, 2) AS INTEGER)", monthExpr("sqlite", "income_time"))
+}
+
+func TestBuildCashFlow(t *testing.T) {
+	rates := exchangeRates{"CNY": 1, "USD": 7}
+	incomes := []monthCurrencyRow{{Month: 1, Currency: "CNY", Total: 8000}, {Month: 3, Currency: "USD", Total: 100}, {Month: 3, Currency: "JPY", Total: 1000}}
+	expenses := []monthCurrencyRow{{Month: 1, Currency: "CNY", Total: 3000}, {Month: 2, Currency: "CNY", Total: 1200.5}}
+
+	resp := buildCashFlow(2024, incomes, expenses, rates)
+	require.Len(t, resp.Months, 12)
+	assert.Equal(t, CashFlowMonth{Month: "2024-01", Income: 8000, Expense: 3000, Net: 5000, Balance: 5000}, resp.Months[0])
+	assert.Equal(t, CashFlowMonth{Month: "2024-02", Expense: 1200.5, Net: -1200.5, Balance: 3799.5}, resp.Months[1])
+	assert.Equal(t, CashFlowMonth{Month: "2024-03", Income: 700, Net: 700, Balance: 4499.5}, resp.Months[2])
+	// 无记录的月份为 0，累计净额保持不变
+	assert.Equal(t, CashFlowMonth{Month: "2024-12", Balance: 4499.5}, resp.Months[11])
+	assert.Equal(t, 8700.0, resp.TotalIncome)
+	assert.Equal(t, 4200.5, resp.TotalExpense)
+	assert.Equal(t, 4499.5, resp.Net)
+	assert.Equal(t, []string{"JPY"}, resp.UnconvertedCurrencies)
+}
+
+func TestExpenseHandler_GetCashFlow(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT MONTH\\(income_time\\) AS month, currency, SUM\\(amount\\) AS total FROM `incomes` WHERE \\(income_time >= \\? AND income_time <= \\?\\) AND user_id = \\?.* GROUP BY MONTH\\(income_time\\), currency").
+		WillReturnRows(sqlmock.NewRows([]string{"month", "currency", "total"}).AddRow(5, "CNY", 9000))
+	mock.ExpectQuery("SELECT MONTH\\(expense_time\\) AS month, currency, SUM\\(amount\\) AS total FROM `expenses` WHERE .*category NOT IN \\(SELECT `name` FROM `expense_categories` WHERE is_transfer = \\?.* GROUP BY MONTH\\(expense_time\\), currency").
+		WillReturnRows(sqlmock.NewRows([]string{"month", "currency", "total"}).AddRow(5, "CNY", 4000).AddRow(6, "CNY", 500))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/statistics/cashflow", NewExpenseHandler().GetCashFlow)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/statistics/cashflow?year=2024", nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data CashFlowResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2024, resp.Data.Year)
+	require.Len(t, resp.Data.Months, 12)
+	assert.Equal(t, 5000.0, resp.Data.Months[4].Net)
+	assert.Equal(t, 4500.0, resp.Data.Months[5].Balance)
+	assert.Equal(t, 4500.0, resp.Data.Net)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_GetCashFlow_OtherUserRequiresAdmin(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id`,`is_admin` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "is_admin"}).AddRow(1, false))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/statistics/cashflow", NewExpenseHandler().GetCashFlow)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/statistics/cashflow?user_id=2", nil))
+	assert.Equal(t, 403, w.Code)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/statistics/cashflow?year=1999", nil))
+	assert.Equal(t, 400, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
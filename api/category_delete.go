@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+
+	"finance/config"
+	"finance/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// expenseFallbackCategory 强制删除消费类别时记录改挂到的类别（category.expense_fallback）
+func expenseFallbackCategory() string {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Category.ExpenseFallback != "" {
+		return cfg.Category.ExpenseFallback
+	}
+	return config.DefaultFallbackCategory
+}
+
+// incomeFallbackCategory 强制删除收入类别时记录改挂到的类别（category.income_fallback）
+func incomeFallbackCategory() string {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Category.IncomeFallback != "" {
+		return cfg.Category.IncomeFallback
+	}
+	return config.DefaultFallbackCategory
+}
+
+// categoryInUse 删除类别前的引用检查
+type categoryInUse struct {
+	RecordModel   interface{} // 引用类别名称的记录表，如 &models.Expense{}
+	Column        string      // 记录表中的类别列
+	CategoryModel interface{} // 类别表，用于确认兜底类别存在
+	Fallback      string      // 强制删除时记录改挂到的类别
+	RecordLabel   string      // 提示文案中的记录名称，如"消费记录"
+}
+
+// countRecords 统计引用该类别名称的记录数，回收站中的记录也算在内，恢复后同样需要有效类别
+func (u categoryInUse) countRecords(name string) (int64, error) {
+	var n int64
+	err := database.DB.Unscoped().Model(u.RecordModel).Where(u.Column+" = ?", name).Count(&n).Error
+	return n, err
+}
+
+// checkDelete 校验类别能否删除：仍被引用且未带 force 时返回 400 与记录数；
+// 带 force 时兜底类别必须存在且不能是被删除的类别本身。返回引用的记录数，ok 为 false 时已写入响应
+func (u categoryInUse) checkDelete(c *gin.Context, name string) (int64, bool) {
+	count, err := u.countRecords(name)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return 0, false
+	}
+	if count == 0 {
+		return 0, true
+	}
+	if c.Query("force") != "true" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"success": false,
+			"message": fmt.Sprintf("仍有 %d 条%s使用该类别，带 force=true 删除时这些记录将改为“%s”", count, u.RecordLabel, u.Fallback),
+			"data":    gin.H{"record_count": count, "fallback": u.Fallback},
+		})
+		return 0, false
+	}
+	if name == u.Fallback {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("“%s”是兜底类别，仍被%s使用时不能删除", name, u.RecordLabel)})
+		return 0, false
+	}
+	var n int64
+	if err := database.DB.Model(u.CategoryModel).Where("name = ?", u.Fallback).Count(&n).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return 0, false
+	}
+	if n == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("兜底类别“%s”不存在，请先创建", u.Fallback)})
+		return 0, false
+	}
+	return count, true
+}
+
+// deleteCategory 软删除类别；count > 0 时在同一事务中先把引用它的记录（含回收站）改挂到兜底类别，
+// 返回改挂的记录数
+func (u categoryInUse) deleteCategory(cat interface{}, name string, count int64) (int64, error) {
+	var reassigned int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if count > 0 {
+			res := tx.Unscoped().Model(u.RecordModel).Where(u.Column+" = ?", name).Update(u.Column, u.Fallback)
+			if res.Error != nil {
+				return res.Error
+			}
+			reassigned = res.RowsAffected
+		}
+		return tx.Delete(cat).Error
+	})
+	return reassigned, err
+}
+
+// renameCategory 在同一事务中更新类别并把引用旧名称的记录（含回收站）改为新名称，返回改名的记录数；
+// 名称未变时只更新类别
+func (u categoryInUse) renameCategory(cat interface{}, updates map[string]interface{}, oldName string) (int64, error) {
+	var renamed int64
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(cat).Updates(updates).Error; err != nil {
+			return err
+		}
+		newName, ok := updates["name"].(string)
+		if !ok || newName == oldName {
+			return nil
+		}
+		res := tx.Unscoped().Model(u.RecordModel).Where(u.Column+" = ?", oldName).Update(u.Column, newName)
+		renamed = res.RowsAffected
+		return res.Error
+	})
+	return renamed, err
+}
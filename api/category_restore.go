@@ -0,0 +1,47 @@
+package api
+
+import (
+	"net/http"
+
+	"finance/database"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// deletedCategoryConflictMessage 同名类别已被软删除时的提示，前端据此询问是否恢复
+const deletedCategoryConflictMessage = "类别已存在（已删除），是否恢复？"
+
+// findCategoryByName 按名称查找类别，包含已软删除的行：name 上的唯一索引不区分是否删除，
+// 只查未删除的行会放过冲突，直到写库时才报错。excludeID 非 0 时排除该类别自身
+func findCategoryByName(dest interface{}, name string, excludeID uint) error {
+	query := database.DB.Unscoped().Where("name = ?", name)
+	if excludeID != 0 {
+		query = query.Where("id != ?", excludeID)
+	}
+	return query.First(dest).Error
+}
+
+// duplicateCategoryNameMessage 改名时与其他类别重名的提示；对方已删除时提示先恢复或换名，
+// 改名不提供恢复，否则会出现两个同名类别
+func duplicateCategoryNameMessage(deletedAt gorm.DeletedAt) string {
+	if deletedAt.Valid {
+		return "类别名称已被已删除的类别占用，请先恢复该类别或换一个名称"
+	}
+	return "类别名称已存在"
+}
+
+// respondDeletedCategoryConflict 同名类别已删除且请求未要求恢复：返回 409 和已删除类别的 ID，
+// 确认后带 restore=true 重新提交即可恢复
+func respondDeletedCategoryConflict(c *gin.Context, deletedID uint) {
+	c.JSON(http.StatusConflict, gin.H{
+		"success": false,
+		"message": deletedCategoryConflictMessage,
+		"data":    gin.H{"deleted_id": deletedID, "restorable": true},
+	})
+}
+
+// restoreCategory 清除类别的删除标记，并把 columns 按 cat 中的新值一并写入
+func restoreCategory(cat interface{}, columns ...string) error {
+	return database.DB.Unscoped().Model(cat).Select(append(columns, "deleted_at")).Updates(cat).Error
+}
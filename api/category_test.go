@@ -0,0 +1,360 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectAdminUser 期望 getCurrentUser 查询 Cookie 中的管理员
+func expectAdminUser(mock sqlmock.Sqlmock) {
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+}
+
+// serveAsAdmin 以管理员 Cookie 请求 route 上注册的 handler
+func serveAsAdmin(method, route, path string, handler gin.HandlerFunc, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Handle(method, route, handler)
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCategoryHandler_Create_DeletedNameConflict(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	// 唯一性检查包含已删除的行
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\? ORDER BY").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(3, "餐饮", time.Now()))
+
+	w := serveAsAdmin("POST", "/admin/categories", "/admin/categories", NewCategoryHandler().Create, `{"name":"餐饮"}`)
+
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	var resp struct {
+		Message string `json:"message"`
+		Data    struct {
+			DeletedID  uint `json:"deleted_id"`
+			Restorable bool `json:"restorable"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, deletedCategoryConflictMessage, resp.Message)
+	assert.EqualValues(t, 3, resp.Data.DeletedID)
+	assert.True(t, resp.Data.Restorable)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Create_Restore(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\? ORDER BY").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sort", "color", "deleted_at"}).AddRow(3, "餐饮", 1, "#ef4444", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expense_categories` SET `sort`=\\?,`color`=\\?,`is_transfer`=\\?,`parent_id`=\\?,`updated_at`=\\?,`deleted_at`=\\? WHERE `id` = \\?").
+		WithArgs(5, "#10b981", false, nil, sqlmock.AnyArg(), nil, 3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("POST", "/admin/categories", "/admin/categories", NewCategoryHandler().Create, `{"name":"餐饮","sort":5,"color":"#10b981","restore":true}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "恢复成功")
+	assert.Contains(t, w.Body.String(), `"id":3`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Create_ActiveNameExists(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\? ORDER BY").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(3, "餐饮", nil))
+
+	// 未删除的同名类别不能通过 restore 覆盖
+	w := serveAsAdmin("POST", "/admin/categories", "/admin/categories", NewCategoryHandler().Create, `{"name":"餐饮","restore":true}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "类别名称已存在")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Update_NameTakenByDeleted(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\? AND `expense_categories`.`deleted_at` IS NULL").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\? AND id != \\? ORDER BY").
+		WithArgs("餐饮", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(3, "餐饮", time.Now()))
+
+	w := serveAsAdmin("PUT", "/admin/categories/:id", "/admin/categories/4", NewCategoryHandler().Update, `{"name":"餐饮"}`)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "已删除的类别占用")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeCategoryHandler_Create_Restore(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `income_categories` WHERE name = \\? ORDER BY").
+		WithArgs("奖金").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(2, "奖金", time.Now()))
+
+	w := serveAsAdmin("POST", "/admin/income-categories", "/admin/income-categories", NewIncomeCategoryHandler().Create, `{"name":"奖金"}`)
+	require.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), deletedCategoryConflictMessage)
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `income_categories` WHERE name = \\? ORDER BY").
+		WithArgs("奖金").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "deleted_at"}).AddRow(2, "奖金", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `income_categories` SET `sort`=\\?,`color`=\\?,`updated_at`=\\?,`deleted_at`=\\? WHERE `id` = \\?").
+		WithArgs(0, "#64748b", sqlmock.AnyArg(), nil, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w = serveAsAdmin("POST", "/admin/income-categories", "/admin/income-categories", NewIncomeCategoryHandler().Create, `{"name":"奖金","restore":true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), "恢复成功")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Delete_InUse(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	// 回收站中的记录也计入
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE category = \\?$").
+		WithArgs("外卖").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+
+	w := serveAsAdmin("DELETE", "/admin/categories/:id", "/admin/categories/4", NewCategoryHandler().Delete, "")
+
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var resp struct {
+		Message string `json:"message"`
+		Data    struct {
+			RecordCount int64  `json:"record_count"`
+			Fallback    string `json:"fallback"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Contains(t, resp.Message, "仍有 12 条消费记录使用该类别")
+	assert.EqualValues(t, 12, resp.Data.RecordCount)
+	assert.Equal(t, "其他", resp.Data.Fallback)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Delete_ForceReassigns(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+	config.GlobalConfig.Category.ExpenseFallback = "杂项"
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE category = \\?$").
+		WithArgs("外卖").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(12))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expense_categories` WHERE name = \\? AND `expense_categories`.`deleted_at` IS NULL").
+		WithArgs("杂项").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expenses` SET `category`=\\?,`updated_at`=\\? WHERE category = \\?$").
+		WithArgs("杂项", sqlmock.AnyArg(), "外卖").
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectExec("UPDATE `expense_categories` SET `deleted_at`=\\? WHERE `expense_categories`.`id` = \\?").
+		WithArgs(sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("DELETE", "/admin/categories/:id", "/admin/categories/4?force=true", NewCategoryHandler().Delete, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"reassigned":12`)
+	assert.Contains(t, w.Body.String(), `"fallback":"杂项"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Delete_ForceFallbackMissing(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expense_categories`").
+		WithArgs("其他").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	w := serveAsAdmin("DELETE", "/admin/categories/:id", "/admin/categories/4?force=true", NewCategoryHandler().Delete, "")
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "兜底类别“其他”不存在")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeCategoryHandler_Delete_Unused(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `income_categories` WHERE `income_categories`.`id` = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "奖金"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `incomes` WHERE type = \\?$").
+		WithArgs("奖金").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	// 未被使用时不改挂，直接删除
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `income_categories` SET `deleted_at`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	w := serveAsAdmin("DELETE", "/admin/income-categories/:id", "/admin/income-categories/2", NewIncomeCategoryHandler().Delete, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"reassigned":0`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Update_RenameCascades(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\? AND id != \\?").
+		WithArgs("餐饮外卖", 4).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expense_categories` SET `name`=\\?,`updated_at`=\\? WHERE").
+		WithArgs("餐饮外卖", sqlmock.AnyArg(), 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `expenses` SET `category`=\\?,`updated_at`=\\? WHERE category = \\?$").
+		WithArgs("餐饮外卖", sqlmock.AnyArg(), "外卖").
+		WillReturnResult(sqlmock.NewResult(0, 7))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "餐饮外卖"))
+
+	w := serveAsAdmin("PUT", "/admin/categories/:id", "/admin/categories/4", NewCategoryHandler().Update, `{"name":"餐饮外卖"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"renamed_records":7`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCategoryHandler_Update_RenameRollsBack(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(4, "外卖"))
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expense_categories`").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `expenses`").
+		WillReturnError(assert.AnError)
+	mock.ExpectRollback()
+
+	w := serveAsAdmin("PUT", "/admin/categories/:id", "/admin/categories/4", NewCategoryHandler().Update, `{"name":"餐饮外卖"}`)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeCategoryHandler_Update_RenameCascades(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `income_categories` WHERE `income_categories`.`id` = \\?").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "奖金"))
+	mock.ExpectQuery("SELECT \\* FROM `income_categories` WHERE name = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `income_categories` SET `name`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE `incomes` SET `type`=\\?,`updated_at`=\\? WHERE type = \\?$").
+		WithArgs("年终奖", sqlmock.AnyArg(), "奖金").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `income_categories`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "年终奖"))
+
+	w := serveAsAdmin("PUT", "/admin/income-categories/:id", "/admin/income-categories/2", NewIncomeCategoryHandler().Update, `{"name":"年终奖"}`)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"renamed_records":3`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,106 @@
+package api
+
+import (
+	"errors"
+	"sort"
+
+	"finance/database"
+	"finance/models"
+)
+
+// loadCategoryParents 读取未删除的消费类别层级，返回 类别名 → 父类别名；
+// 没有父类别或父类别已删除的类别不出现在结果中
+func loadCategoryParents() (map[string]string, error) {
+	var cats []models.ExpenseCategory
+	if err := database.DB.Select("id", "name", "parent_id").Find(&cats).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[uint]string, len(cats))
+	for _, c := range cats {
+		names[c.ID] = c.Name
+	}
+	parents := map[string]string{}
+	for _, c := range cats {
+		if c.ParentID == nil {
+			continue
+		}
+		if p, ok := names[*c.ParentID]; ok && p != c.Name {
+			parents[c.Name] = p
+		}
+	}
+	return parents, nil
+}
+
+// rootCategory 沿父类别向上找到顶层类别；遇到循环（历史脏数据）时不上卷，返回自身
+func rootCategory(name string, parents map[string]string) string {
+	visited := map[string]bool{name: true}
+	cur := name
+	for {
+		p, ok := parents[cur]
+		if !ok {
+			return cur
+		}
+		if visited[p] {
+			return name
+		}
+		visited[p] = true
+		cur = p
+	}
+}
+
+// rollupCategoryStats 把各类别的金额与笔数累加到顶层父类别，按金额降序；
+// 没有层级数据时与输入相同。返回新切片，不修改入参（入参可能来自缓存）
+func rollupCategoryStats(stats []ExpenseCategoryStat, parents map[string]string) []ExpenseCategoryStat {
+	if len(parents) == 0 {
+		return stats
+	}
+	index := map[string]int{}
+	var result []ExpenseCategoryStat
+	for _, s := range stats {
+		root := rootCategory(s.Category, parents)
+		i, ok := index[root]
+		if !ok {
+			i = len(result)
+			index[root] = i
+			result = append(result, ExpenseCategoryStat{Category: root})
+		}
+		result[i].Total += s.Total
+		result[i].Count += s.Count
+	}
+	sort.SliceStable(result, func(a, b int) bool { return result[a].Total > result[b].Total })
+	return result
+}
+
+// validateCategoryParent 校验把类别 id（新建时为 0）挂到 parentID 下：父类别须存在，且不能是自身或自身的子孙类别
+func validateCategoryParent(id, parentID uint) error {
+	if id != 0 && parentID == id {
+		return errors.New("父类别不能是自身")
+	}
+	var cats []models.ExpenseCategory
+	if err := database.DB.Select("id", "parent_id").Find(&cats).Error; err != nil {
+		return err
+	}
+	parentOf := make(map[uint]*uint, len(cats))
+	for _, c := range cats {
+		parentOf[c.ID] = c.ParentID
+	}
+	if _, ok := parentOf[parentID]; !ok {
+		return errors.New("父类别不存在")
+	}
+	// 从新父类别向上遍历，若经过自身则会形成循环
+	visited := map[uint]bool{}
+	for cur := parentID; ; {
+		if cur == id && id != 0 {
+			return errors.New("不能把类别挂到其子类别下")
+		}
+		if visited[cur] {
+			return nil
+		}
+		visited[cur] = true
+		p, ok := parentOf[cur]
+		if !ok || p == nil {
+			return nil
+		}
+		cur = *p
+	}
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRootCategory(t *testing.T) {
+	parents := map[string]string{
+		"早餐": "餐饮",
+		"餐饮": "生活",
+		"甲":  "乙",
+		"乙":  "甲",
+	}
+	assert.Equal(t, "生活", rootCategory("早餐", parents), "多层嵌套上卷到顶层")
+	assert.Equal(t, "生活", rootCategory("生活", parents))
+	assert.Equal(t, "交通", rootCategory("交通", parents))
+	assert.Equal(t, "甲", rootCategory("甲", parents), "循环时不上卷")
+}
+
+func TestRollupCategoryStats(t *testing.T) {
+	stats := []ExpenseCategoryStat{
+		{Category: "交通", Total: 100, Count: 2},
+		{Category: "早餐", Total: 60, Count: 3},
+		{Category: "午餐", Total: 50, Count: 2},
+		{Category: "餐饮", Total: 10, Count: 1},
+	}
+
+	// 无层级数据时等价于原结果
+	assert.Equal(t, stats, rollupCategoryStats(stats, map[string]string{}))
+
+	parents := map[string]string{"早餐": "餐饮", "午餐": "餐饮"}
+	assert.Equal(t, []ExpenseCategoryStat{
+		{Category: "餐饮", Total: 120, Count: 6},
+		{Category: "交通", Total: 100, Count: 2},
+	}, rollupCategoryStats(stats, parents))
+	assert.Equal(t, "早餐", stats[1].Category, "不修改入参")
+}
+
+func TestLoadCategoryParents(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id`,`name`,`parent_id` FROM `expense_categories`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "parent_id"}).
+			AddRow(1, "餐饮", nil).
+			AddRow(2, "早餐", 1).
+			AddRow(3, "夜宵", 99)) // 父类别已删除
+
+	parents, err := loadCategoryParents()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"早餐": "餐饮"}, parents)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestValidateCategoryParent(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	rows := func() *sqlmock.Rows {
+		// 1 ← 2 ← 3
+		return sqlmock.NewRows([]string{"id", "parent_id"}).AddRow(1, nil).AddRow(2, 1).AddRow(3, 2)
+	}
+	for i := 0; i < 3; i++ {
+		mock.ExpectQuery("SELECT `id`,`parent_id` FROM `expense_categories`").WillReturnRows(rows())
+	}
+
+	assert.NoError(t, validateCategoryParent(0, 3))
+	assert.EqualError(t, validateCategoryParent(1, 3), "不能把类别挂到其子类别下")
+	assert.EqualError(t, validateCategoryParent(0, 9), "父类别不存在")
+	assert.EqualError(t, validateCategoryParent(2, 2), "父类别不能是自身")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
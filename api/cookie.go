@@ -8,6 +8,7 @@ import (
 	"finance/config"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // GetVerifiedAdminUserID 验证 admin_user_id cookie 签名并返回用户 ID
@@ -28,6 +29,15 @@ func escapeLikeValue(s string) string {
 	return s
 }
 
+// likeEscape 返回与 escapeLikeValue 配套的 ESCAPE 子句，拼在 LIKE ? 之后。
+// SQLite 的 LIKE 没有默认转义字符，必须显式声明；MySQL 字符串字面量中的反斜杠本身需要再转义一次
+func likeEscape(db *gorm.DB) string {
+	if db.Dialector.Name() == "mysql" {
+		return ` ESCAPE '\\'`
+	}
+	return ` ESCAPE '\'`
+}
+
 // getCookieOptions 根据运行模式返回 Cookie 的安全选项
 // release 模式下启用 Secure（仅 HTTPS 传输），并设置 SameSite 以防止 CSRF
 func getCookieOptions() (secure bool, sameSite http.SameSite) {
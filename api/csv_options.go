@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+// CSV 导出分隔符
+const (
+	CSVDelimiterComma     = "comma"
+	CSVDelimiterSemicolon = "semicolon"
+	CSVDelimiterTab       = "tab"
+)
+
+// CSV 导出编码
+const (
+	CSVEncodingUTF8BOM = "utf8-bom"
+	CSVEncodingUTF8    = "utf8"
+	CSVEncodingGBK     = "gbk"
+)
+
+// utf8BOM UTF-8 字节序标记，Excel 依靠它识别中文
+const utf8BOM = "\xEF\xBB\xBF"
+
+var csvDelimiters = map[string]rune{
+	CSVDelimiterComma:     ',',
+	CSVDelimiterSemicolon: ';',
+	CSVDelimiterTab:       '\t',
+}
+
+// csvOptions CSV 导出的分隔符与编码
+type csvOptions struct {
+	Delimiter rune
+	Encoding  string
+}
+
+// resolveCSVOptions 解析 delimiter / encoding 查询参数，默认逗号 + UTF-8 BOM（与旧行为一致）
+func resolveCSVOptions(c *gin.Context) (*csvOptions, error) {
+	delimiter := strings.ToLower(c.DefaultQuery("delimiter", CSVDelimiterComma))
+	comma, ok := csvDelimiters[delimiter]
+	if !ok {
+		return nil, errors.New("不支持的分隔符: " + delimiter + "，可选值: comma/semicolon/tab")
+	}
+
+	encoding := strings.ToLower(c.DefaultQuery("encoding", CSVEncodingUTF8BOM))
+	switch encoding {
+	case CSVEncodingUTF8BOM, CSVEncodingUTF8, CSVEncodingGBK:
+	default:
+		return nil, errors.New("不支持的编码: " + encoding + "，可选值: utf8-bom/utf8/gbk")
+	}
+
+	return &csvOptions{Delimiter: comma, Encoding: encoding}, nil
+}
+
+// ContentType 返回与编码匹配的 Content-Type
+func (o *csvOptions) ContentType() string {
+	if o.Encoding == CSVEncodingGBK {
+		return "text/csv; charset=gbk"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// Encode 将 csv.Writer 生成的 UTF-8 内容转换为目标编码
+func (o *csvOptions) Encode(data []byte) ([]byte, error) {
+	switch o.Encoding {
+	case CSVEncodingUTF8BOM:
+		out := make([]byte, 0, len(utf8BOM)+len(data))
+		out = append(out, utf8BOM...)
+		return append(out, data...), nil
+	case CSVEncodingGBK:
+		out, err := simplifiedchinese.GBK.NewEncoder().Bytes(data)
+		if err != nil {
+			return nil, errors.New("内容包含 GBK 无法表示的字符，请改用 UTF-8 编码导出")
+		}
+		return out, nil
+	default:
+		return bytes.Clone(data), nil
+	}
+}
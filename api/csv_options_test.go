@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+func exportCSVWithOptions(t *testing.T, query string) *httptest.ResponseRecorder {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "description", "expense_time", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, 1, 99.99, "餐饮", "午餐;加班,打车", time.Now(), time.Now(), time.Now(), nil))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/csv", NewExportHandler().ExportCSV)
+
+	req := httptest.NewRequest("GET", "/export/csv?start_time=2024-01-01&end_time=2024-01-31"+query, nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.NoError(t, mock.ExpectationsWereMet())
+	return w
+}
+
+func parseExportedCSV(t *testing.T, data []byte, comma rune) [][]string {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	records, err := r.ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	return records
+}
+
+func TestExportCSV_Options(t *testing.T) {
+	cases := []struct {
+		name        string
+		query       string
+		comma       rune
+		contentType string
+		bom         bool
+		gbk         bool
+	}{
+		{"默认逗号+BOM", "", ',', "text/csv; charset=utf-8", true, false},
+		{"分号+UTF-8", "&delimiter=semicolon&encoding=utf8", ';', "text/csv; charset=utf-8", false, false},
+		{"制表符+GBK", "&delimiter=tab&encoding=gbk", '\t', "text/csv; charset=gbk", false, true},
+		{"逗号+GBK", "&encoding=GBK", ',', "text/csv; charset=gbk", false, true},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := exportCSVWithOptions(t, tc.query)
+			require.Equal(t, 200, w.Code)
+			assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+
+			data := w.Body.Bytes()
+			assert.Equal(t, tc.bom, bytes.HasPrefix(data, []byte(utf8BOM)))
+			data = bytes.TrimPrefix(data, []byte(utf8BOM))
+			if tc.gbk {
+				decoded, err := simplifiedchinese.GBK.NewDecoder().Bytes(data)
+				require.NoError(t, err)
+				data = decoded
+			}
+
+			records := parseExportedCSV(t, data, tc.comma)
+			assert.Equal(t, []string{"ID", "金额", "类别", "描述", "消费时间", "创建时间"}, records[0])
+			assert.Equal(t, "99.99", records[1][1])
+			assert.Equal(t, "餐饮", records[1][2])
+			assert.Equal(t, "午餐;加班,打车", records[1][3])
+		})
+	}
+}
+
+func TestExportCSV_InvalidOptions(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/csv", NewExportHandler().ExportCSV)
+
+	for _, q := range []string{"&delimiter=pipe", "&encoding=utf16"} {
+		req := httptest.NewRequest("GET", "/export/csv?start_time=2024-01-01&end_time=2024-01-31"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, q)
+	}
+}
+
+func TestCSVOptions_EncodeGBKUnsupportedChar(t *testing.T) {
+	opts := &csvOptions{Delimiter: ',', Encoding: CSVEncodingGBK}
+	_, err := opts.Encode([]byte("早餐🍜\n"))
+	assert.Error(t, err)
+
+	out, err := opts.Encode([]byte("早餐\n"))
+	require.NoError(t, err)
+	assert.NotEqual(t, []byte("早餐\n"), out)
+}
@@ -0,0 +1,140 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 导出金额格式化默认值
+const (
+	DefaultCurrency = "CNY"
+	DefaultLocale   = "zh-CN"
+)
+
+// currencySpec 货币符号与小数位
+type currencySpec struct {
+	Symbol   string
+	Decimals int
+}
+
+var currencySpecs = map[string]currencySpec{
+	"CNY": {Symbol: "¥", Decimals: 2},
+	"USD": {Symbol: "$", Decimals: 2},
+	"EUR": {Symbol: "€", Decimals: 2},
+	"GBP": {Symbol: "£", Decimals: 2},
+	"HKD": {Symbol: "HK$", Decimals: 2},
+	"JPY": {Symbol: "¥", Decimals: 0},
+}
+
+// localeSpec 千分位/小数点分隔符及货币符号位置
+type localeSpec struct {
+	Group        string
+	Decimal      string
+	SymbolSuffix bool // 符号放在数字后（如 1.234,56 €）
+}
+
+var localeSpecs = map[string]localeSpec{
+	"zh-CN": {Group: ",", Decimal: "."},
+	"en-US": {Group: ",", Decimal: "."},
+	"en-GB": {Group: ",", Decimal: "."},
+	"ja-JP": {Group: ",", Decimal: "."},
+	"de-DE": {Group: ".", Decimal: ",", SymbolSuffix: true},
+	"fr-FR": {Group: " ", Decimal: ",", SymbolSuffix: true},
+}
+
+// moneyFormat 导出时的金额本地化格式
+type moneyFormat struct {
+	Currency string
+	Locale   string
+	currency currencySpec
+	locale   localeSpec
+}
+
+// resolveMoneyFormat 解析导出的 formatted/currency/locale 参数。
+// formatted 不为 true 时返回 nil，导出保持裸数字。currency 只是默认币种，
+// 每条记录按自身币种显示符号（见 withCurrency）。
+func resolveMoneyFormat(c *gin.Context) (*moneyFormat, error) {
+	if c.Query("formatted") != "true" {
+		return nil, nil
+	}
+	return newMoneyFormat(c.DefaultQuery("currency", DefaultCurrency), c.DefaultQuery("locale", DefaultLocale))
+}
+
+// newMoneyFormat 按货币代码和 locale 构建格式
+func newMoneyFormat(currency, locale string) (*moneyFormat, error) {
+	currency = strings.ToUpper(currency)
+	cs, ok := currencySpecs[currency]
+	if !ok {
+		return nil, errors.New("不支持的货币: " + currency + "，可选值: CNY/USD/EUR/GBP/HKD/JPY")
+	}
+	ls, ok := localeSpecs[locale]
+	if !ok {
+		return nil, errors.New("不支持的 locale: " + locale + "，可选值: zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR")
+	}
+	return &moneyFormat{Currency: currency, Locale: locale, currency: cs, locale: ls}, nil
+}
+
+// withCurrency 返回按记录自身币种显示的格式，locale 不变；
+// 记录币种为空或不在支持列表中时沿用请求的币种
+func (m *moneyFormat) withCurrency(code string) *moneyFormat {
+	code = strings.ToUpper(code)
+	cs, ok := currencySpecs[code]
+	if !ok || code == m.Currency {
+		return m
+	}
+	return &moneyFormat{Currency: code, Locale: m.Locale, currency: cs, locale: m.locale}
+}
+
+// Format 将金额格式化为本地化货币字符串，如 ¥1,234.56、1.234,56 €
+func (m *moneyFormat) Format(amount float64) string {
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+		amount = -amount
+	}
+	// 四舍五入（FormatFloat 对 .5 按银行家舍入，1234.5 日元会显示成 ¥1,234）
+	scale := math.Pow10(m.currency.Decimals)
+	amount = math.Round(amount*scale) / scale
+
+	s := strconv.FormatFloat(amount, 'f', m.currency.Decimals, 64)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	// 插入千分位
+	var b strings.Builder
+	for i, ch := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(m.locale.Group)
+		}
+		b.WriteRune(ch)
+	}
+	number := b.String()
+	if fracPart != "" {
+		number += m.locale.Decimal + fracPart
+	}
+
+	if m.locale.SymbolSuffix {
+		return sign + number + " " + m.currency.Symbol
+	}
+	return sign + m.currency.Symbol + number
+}
+
+// ExcelNumFmt 返回 Excel 自定义数字格式，单元格仍为数值可参与计算。
+// 千分位/小数点的显示字符由 Excel 按查看者的区域设置决定，这里只控制货币符号和小数位。
+func (m *moneyFormat) ExcelNumFmt() string {
+	number := "#,##0"
+	if m.currency.Decimals > 0 {
+		number += "." + strings.Repeat("0", m.currency.Decimals)
+	}
+	symbol := `"` + m.currency.Symbol + `"`
+	if m.locale.SymbolSuffix {
+		return number + ` ` + symbol + `;-` + number + ` ` + symbol
+	}
+	return symbol + number + `;-` + symbol + number
+}
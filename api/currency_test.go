@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoneyFormat_Format(t *testing.T) {
+	cases := []struct {
+		currency, locale string
+		amount           float64
+		want             string
+	}{
+		{"CNY", "zh-CN", 1234.56, "¥1,234.56"},
+		{"CNY", "zh-CN", 99.9, "¥99.90"},
+		{"USD", "en-US", 1234567.891, "$1,234,567.89"},
+		{"EUR", "de-DE", 1234.56, "1.234,56 €"},
+		{"JPY", "ja-JP", 1234.5, "¥1,235"},
+		{"CNY", "zh-CN", -1234.56, "-¥1,234.56"},
+		{"CNY", "zh-CN", 0, "¥0.00"},
+	}
+	for _, tc := range cases {
+		m, err := newMoneyFormat(tc.currency, tc.locale)
+		require.NoError(t, err)
+		assert.Equal(t, tc.want, m.Format(tc.amount), "%s/%s %v", tc.currency, tc.locale, tc.amount)
+	}
+}
+
+func TestMoneyFormat_ExcelNumFmt(t *testing.T) {
+	m, _ := newMoneyFormat("CNY", "zh-CN")
+	assert.Equal(t, `"¥"#,##0.00;-"¥"#,##0.00`, m.ExcelNumFmt())
+
+	m, _ = newMoneyFormat("EUR", "de-DE")
+	assert.Equal(t, `#,##0.00 "€";-#,##0.00 "€"`, m.ExcelNumFmt())
+}
+
+func TestMoneyFormat_WithCurrency(t *testing.T) {
+	m, _ := newMoneyFormat("CNY", "de-DE")
+	assert.Equal(t, "1.234,56 $", m.withCurrency("usd").Format(1234.56))
+	assert.Equal(t, "1.235 ¥", m.withCurrency("JPY").Format(1234.5))
+	assert.Same(t, m, m.withCurrency(""), "记录无币种时沿用请求的币种")
+	assert.Same(t, m, m.withCurrency("XYZ"))
+}
+
+func TestNewMoneyFormat_Invalid(t *testing.T) {
+	_, err := newMoneyFormat("XYZ", "zh-CN")
+	assert.Error(t, err)
+
+	_, err = newMoneyFormat("CNY", "xx-XX")
+	assert.Error(t, err)
+}
+
+func TestExportHandler_ExportCSV_Formatted(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "category", "description", "expense_time", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, 1, 1234.56, "CNY", "餐饮", "聚餐", time.Now(), time.Now(), time.Now(), nil).
+			AddRow(2, 1, 20, "USD", "餐饮", "机场", time.Now(), time.Now(), time.Now(), nil))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/csv", NewExportHandler().ExportCSV)
+
+	req := httptest.NewRequest("GET", "/export/csv?start_time=2024-01-01&end_time=2024-01-31&formatted=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	// 含千分位的金额需要被 CSV 加引号
+	assert.Contains(t, w.Body.String(), `"¥1,234.56"`)
+	// 外币记录按自身币种显示符号
+	assert.Contains(t, w.Body.String(), "$20.00")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
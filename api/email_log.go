@@ -0,0 +1,89 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GetEmailLogs 邮件发送日志（仅超级管理员）
+// @Summary 查询邮件发送日志
+// @Description 按发送时间倒序分页返回邮件发送结果（成功/失败及失败原因），收件人邮箱脱敏
+// @Tags 后台管理-密码重置
+// @Produce json
+// @Param email query string false "收件人邮箱（精确匹配）"
+// @Param type query string false "邮件类型：password_reset/app_password_reset/verification/initial_password/test/notification/daily_digest"
+// @Param status query string false "发送状态：sent/failed"
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页条数，默认20，最大100"
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/email-logs [get]
+func (h *AdminHandler) GetEmailLogs(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		if v, e := strconv.Atoi(p); e == nil && v > 0 {
+			page = v
+		}
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		if v, e := strconv.Atoi(ps); e == nil && v > 0 {
+			pageSize = v
+		}
+	}
+	if pageSize > 100 {
+		pageSize = 100
+	}
+
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	query := created.Filter(database.DB.Model(&models.EmailLog{}), "created_at")
+	if email := strings.TrimSpace(c.Query("email")); email != "" {
+		query = query.Where("to_email = ?", email)
+	}
+	if t := c.Query("type"); t != "" {
+		query = query.Where("type = ?", t)
+	}
+	if s := c.Query("status"); s != "" {
+		query = query.Where("status = ?", s)
+	}
+	var total int64
+	query.Count(&total)
+
+	var list []models.EmailLog
+	if err := query.Order(created.Order("created_at", "id DESC")).Offset((page - 1) * pageSize).Limit(pageSize).Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	for i := range list {
+		list[i].To = maskEmail(list[i].To)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewPageResponse(total, page, pageSize, list),
+	})
+}
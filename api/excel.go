@@ -0,0 +1,153 @@
+package api
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// excelBorders 导出表格统一使用的细边框
+var excelBorders = []excelize.Border{
+	{Type: "left", Color: "000000", Style: 1},
+	{Type: "top", Color: "000000", Style: 1},
+	{Type: "bottom", Color: "000000", Style: 1},
+	{Type: "right", Color: "000000", Style: 1},
+}
+
+// excelCenter 居中对齐
+var excelCenter = &excelize.Alignment{Horizontal: "center", Vertical: "center"}
+
+// excelHeaderStyle 表头样式：蓝底白字加粗
+func excelHeaderStyle(f *excelize.File) (int, error) {
+	return f.NewStyle(&excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 12, Color: "FFFFFF"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"4F81BD"}, Pattern: 1},
+		Alignment: excelCenter,
+		Border:    excelBorders,
+	})
+}
+
+// excelDataStyle 数据行样式；numFmt 非空时使用自定义数字格式
+func excelDataStyle(f *excelize.File, numFmt string) (int, error) {
+	style := &excelize.Style{Alignment: excelCenter, Border: excelBorders}
+	if numFmt != "" {
+		style.CustomNumFmt = &numFmt
+	}
+	return f.NewStyle(style)
+}
+
+// excelAmountStyles 金额单元格样式，按币种缓存：money 为 nil 时统一用裸数字样式，
+// 否则每条记录按自身币种的货币格式显示
+type excelAmountStyles struct {
+	f      *excelize.File
+	money  *moneyFormat
+	styles map[string]int
+}
+
+func newExcelAmountStyles(f *excelize.File, money *moneyFormat) *excelAmountStyles {
+	return &excelAmountStyles{f: f, money: money, styles: map[string]int{}}
+}
+
+// get 返回 currency 币种金额的样式
+func (s *excelAmountStyles) get(currency string) int {
+	numFmt := ""
+	if s.money != nil {
+		numFmt = s.money.withCurrency(currency).ExcelNumFmt()
+	}
+	if style, ok := s.styles[numFmt]; ok {
+		return style
+	}
+	style, _ := excelDataStyle(s.f, numFmt)
+	s.styles[numFmt] = style
+	return style
+}
+
+// excelHighlightStyle 高亮数据行样式（如超支），fill 为背景色
+func excelHighlightStyle(f *excelize.File, fill, numFmt string) (int, error) {
+	style := &excelize.Style{
+		Font:      &excelize.Font{Color: "9C0006"},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{fill}, Pattern: 1},
+		Alignment: excelCenter,
+		Border:    excelBorders,
+	}
+	if numFmt != "" {
+		style.CustomNumFmt = &numFmt
+	}
+	return f.NewStyle(style)
+}
+
+// excelSummaryStyle 汇总行样式：橙底加粗；numFmt 非空时使用自定义数字格式
+func excelSummaryStyle(f *excelize.File, numFmt string) (int, error) {
+	style := &excelize.Style{
+		Font:      &excelize.Font{Bold: true, Size: 11},
+		Fill:      excelize.Fill{Type: "pattern", Color: []string{"FFC000"}, Pattern: 1},
+		Alignment: excelCenter,
+		Border:    excelBorders,
+	}
+	if numFmt != "" {
+		style.CustomNumFmt = &numFmt
+	}
+	return f.NewStyle(style)
+}
+
+// excelWriteRow 从 A 列开始用 SetSheetRow 整行写入，并对整行应用样式
+func excelWriteRow(f *excelize.File, sheet string, row int, values []interface{}, style int) {
+	if len(values) == 0 {
+		return
+	}
+	first, _ := excelize.CoordinatesToCellName(1, row)
+	last, _ := excelize.CoordinatesToCellName(len(values), row)
+	f.SetSheetRow(sheet, first, &values)
+	f.SetCellStyle(sheet, first, last, style)
+}
+
+// excelColumn 导出表格的一列：表头与列宽
+type excelColumn struct {
+	Title string
+	Width float64
+}
+
+// excelWriteHeader 按列设置列宽，并在第 1 行写入带表头样式的列名
+func excelWriteHeader(f *excelize.File, sheet string, columns []excelColumn) {
+	style, _ := excelHeaderStyle(f)
+	values := make([]interface{}, len(columns))
+	for i, col := range columns {
+		name, _ := excelize.ColumnNumberToName(i + 1)
+		f.SetColWidth(sheet, name, name, col.Width)
+		values[i] = col.Title
+	}
+	excelWriteRow(f, sheet, 1, values, style)
+}
+
+// excelWriteSummary 在 row 行写入汇总行：金额列之前的单元格合并写"合计"，金额列写总额，
+// 之后到 lastCol 的单元格合并写说明文字（如记录条数）；列号从 1 开始，numFmt 为金额列数字格式
+func excelWriteSummary(f *excelize.File, sheet string, row, amountCol, lastCol int, total float64, text, numFmt string) {
+	style, _ := excelSummaryStyle(f, "")
+	amountStyle, _ := excelSummaryStyle(f, numFmt)
+	cell := func(col int) string {
+		name, _ := excelize.CoordinatesToCellName(col, row)
+		return name
+	}
+
+	f.SetCellValue(sheet, cell(1), "合计")
+	if amountCol > 2 {
+		f.MergeCell(sheet, cell(1), cell(amountCol-1))
+	}
+	f.SetCellValue(sheet, cell(amountCol), total)
+	if amountCol < lastCol {
+		f.SetCellValue(sheet, cell(amountCol+1), text)
+		if amountCol+1 < lastCol {
+			f.MergeCell(sheet, cell(amountCol+1), cell(lastCol))
+		}
+	}
+	f.SetCellStyle(sheet, cell(1), cell(lastCol), style)
+	f.SetCellStyle(sheet, cell(amountCol), cell(amountCol), amountStyle)
+}
+
+// writeExcelResponse 以附件形式输出 Excel 文件
+func writeExcelResponse(c *gin.Context, f *excelize.File, filename string) error {
+	c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", filename))
+	return f.Write(c.Writer)
+}
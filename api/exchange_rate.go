@@ -0,0 +1,378 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// baseCurrency 本位币（currency.base）
+func baseCurrency() string {
+	return service.BaseCurrency()
+}
+
+// allowedCurrencies 允许记账的币种（currency.allowed），本位币排在首位
+func allowedCurrencies() []string {
+	if config.GlobalConfig == nil || len(config.GlobalConfig.Currency.Allowed) == 0 {
+		return []string{baseCurrency()}
+	}
+	return config.GlobalConfig.Currency.Allowed
+}
+
+// resolveCurrency 校验并规范化记录的币种：不传时使用本位币，不在 currency.allowed 中时返回错误
+func resolveCurrency(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return baseCurrency(), nil
+	}
+	allowed := allowedCurrencies()
+	for _, a := range allowed {
+		if a == code {
+			return code, nil
+		}
+	}
+	return "", errors.New("不支持的币种: " + code + "，可选值: " + strings.Join(allowed, "/"))
+}
+
+// exchangeRates 币种 → 1 单位折合多少本位币，本位币恒为 1
+type exchangeRates = service.ExchangeRates
+
+// loadExchangeRates 读取汇率表
+func loadExchangeRates() (exchangeRates, error) {
+	return service.LoadExchangeRates()
+}
+
+// currencyAmountRow 按币种分组的原币金额汇总
+type currencyAmountRow struct {
+	Currency string
+	Total    float64
+}
+
+// sumInBaseCurrency 在 query 已有的筛选条件上按币种汇总金额并折算为本位币；
+// 缺少汇率的币种不计入合计，其代码列入 unconverted
+func sumInBaseCurrency(query *gorm.DB, rates exchangeRates) (float64, []string, error) {
+	var rows []currencyAmountRow
+	if err := query.Select("currency, SUM(amount) AS total").Group("currency").Scan(&rows).Error; err != nil {
+		return 0, nil, err
+	}
+	var total float64
+	var unconverted []string
+	for _, row := range rows {
+		converted, ok := rates.ToBase(row.Total, row.Currency)
+		if !ok {
+			unconverted = append(unconverted, row.Currency)
+			continue
+		}
+		total += converted
+	}
+	return roundMoney(total), mergeCurrencyCodes(unconverted), nil
+}
+
+// CurrencyTotal 某币种的原币合计
+type CurrencyTotal struct {
+	Currency  string   `json:"currency" example:"USD"`
+	Total     float64  `json:"total" example:"120.50"` // 原币金额
+	Count     int64    `json:"count" example:"3"`
+	Converted *float64 `json:"converted" example:"867.60"` // 折合本位币金额，缺少汇率时为 null
+}
+
+// currencyStatRow 按类别与币种分组的原币汇总
+type currencyStatRow struct {
+	Category string
+	Currency string
+	Total    float64
+	Count    int64
+}
+
+// currencySummary 折算为本位币后的统计结果
+type currencySummary struct {
+	Total       float64               // 本位币合计，不含缺少汇率的币种
+	Count       int64                 // 记录总笔数（含缺少汇率的币种）
+	Categories  []ExpenseCategoryStat // 各类别本位币合计，按金额倒序
+	Currencies  []CurrencyTotal       // 各币种原币合计，本位币在前
+	Unconverted []string              // 缺少汇率、未计入合计的币种
+}
+
+// sumByCategoryAndCurrency 在 query 已有的筛选条件上按类别与币种分组汇总金额与笔数
+func sumByCategoryAndCurrency(query *gorm.DB, categoryColumn string) ([]currencyStatRow, error) {
+	var rows []currencyStatRow
+	err := query.
+		Select(categoryColumn + " AS category, currency, SUM(amount) AS total, COUNT(*) AS count").
+		Group(categoryColumn + ", currency").
+		Scan(&rows).Error
+	return rows, err
+}
+
+// convertCurrencyStats 按汇率把各类别、各币种的原币汇总折算为本位币并合并；
+// 缺少汇率的币种不计入类别与总额，只在 Currencies 中给出原币金额并列入 Unconverted
+func convertCurrencyStats(rows []currencyStatRow, rates exchangeRates) currencySummary {
+	base := baseCurrency()
+	var s currencySummary
+	categories := map[string]*ExpenseCategoryStat{}
+	var categoryOrder []string
+	currencies := map[string]*CurrencyTotal{}
+	for _, row := range rows {
+		code := row.Currency
+		if code == "" {
+			code = base
+		}
+		ct := currencies[code]
+		if ct == nil {
+			ct = &CurrencyTotal{Currency: code}
+			currencies[code] = ct
+		}
+		ct.Total += row.Total
+		ct.Count += row.Count
+		s.Count += row.Count
+
+		converted, ok := rates.ToBase(row.Total, code)
+		if !ok {
+			continue
+		}
+		if ct.Converted == nil {
+			ct.Converted = new(float64)
+		}
+		*ct.Converted += converted
+		s.Total += converted
+
+		cs := categories[row.Category]
+		if cs == nil {
+			cs = &ExpenseCategoryStat{Category: row.Category}
+			categories[row.Category] = cs
+			categoryOrder = append(categoryOrder, row.Category)
+		}
+		cs.Total += converted
+		cs.Count += row.Count
+	}
+
+	s.Total = roundMoney(s.Total)
+	s.Categories = make([]ExpenseCategoryStat, 0, len(categoryOrder))
+	for _, name := range categoryOrder {
+		cs := categories[name]
+		cs.Total = roundMoney(cs.Total)
+		s.Categories = append(s.Categories, *cs)
+	}
+	sort.SliceStable(s.Categories, func(i, j int) bool { return s.Categories[i].Total > s.Categories[j].Total })
+
+	s.Currencies = make([]CurrencyTotal, 0, len(currencies))
+	for _, ct := range currencies {
+		ct.Total = roundMoney(ct.Total)
+		if ct.Converted != nil {
+			*ct.Converted = roundMoney(*ct.Converted)
+		} else {
+			s.Unconverted = append(s.Unconverted, ct.Currency)
+		}
+		s.Currencies = append(s.Currencies, *ct)
+	}
+	sort.Slice(s.Currencies, func(i, j int) bool {
+		if (s.Currencies[i].Currency == base) != (s.Currencies[j].Currency == base) {
+			return s.Currencies[i].Currency == base
+		}
+		return s.Currencies[i].Currency < s.Currencies[j].Currency
+	})
+	sort.Strings(s.Unconverted)
+	return s
+}
+
+// mergeCurrencyCodes 合并多组币种代码，去重并排序
+func mergeCurrencyCodes(groups ...[]string) []string {
+	seen := map[string]bool{}
+	merged := []string{}
+	for _, g := range groups {
+		for _, code := range g {
+			if !seen[code] {
+				seen[code] = true
+				merged = append(merged, code)
+			}
+		}
+	}
+	sort.Strings(merged)
+	return merged
+}
+
+// queryCurrencySummary 汇总 query 匹配的记录并折算为本位币
+func queryCurrencySummary(query *gorm.DB, categoryColumn string) (currencySummary, error) {
+	rows, err := sumByCategoryAndCurrency(query, categoryColumn)
+	if err != nil {
+		return currencySummary{}, err
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return currencySummary{}, err
+	}
+	return convertCurrencyStats(rows, rates), nil
+}
+
+// roundMoney 金额保留两位小数
+func roundMoney(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// CurrencyInfo 币种配置与汇率
+type CurrencyInfo struct {
+	BaseCurrency string                `json:"base_currency" example:"CNY"`
+	Allowed      []string              `json:"allowed"`
+	Rates        []models.ExchangeRate `json:"rates"`
+}
+
+// loadCurrencyInfo 读取本位币、允许的币种与汇率表
+func loadCurrencyInfo() (CurrencyInfo, error) {
+	info := CurrencyInfo{BaseCurrency: baseCurrency(), Allowed: allowedCurrencies(), Rates: []models.ExchangeRate{}}
+	err := database.DB.Order("currency ASC").Find(&info.Rates).Error
+	return info, err
+}
+
+// ExchangeRateHandler 币种与汇率（App端）
+type ExchangeRateHandler struct{}
+
+// NewExchangeRateHandler 创建币种处理器
+func NewExchangeRateHandler() *ExchangeRateHandler {
+	return &ExchangeRateHandler{}
+}
+
+// GetCurrencies 获取可用币种与汇率
+// @Summary 获取可用币种与汇率
+// @Description 返回本位币、允许记账的币种（currency.allowed）及后台维护的汇率（1 单位该币种折合多少本位币）
+// @Tags 消费记录
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=CurrencyInfo} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/currencies [get]
+func (h *ExchangeRateHandler) GetCurrencies(c *gin.Context) {
+	info, err := loadCurrencyInfo()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, info)
+}
+
+// UpsertExchangeRateRequest 设置汇率请求
+type UpsertExchangeRateRequest struct {
+	Rate float64 `json:"rate" binding:"required,gt=0" example:"7.2"` // 1 单位该币种折合多少本位币
+}
+
+// GetExchangeRates 获取汇率列表
+// @Summary 获取汇率列表
+// @Description 返回本位币、允许记账的币种及汇率表
+// @Tags 后台管理-统计
+// @Produce json
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/exchange-rates [get]
+func (h *AdminHandler) GetExchangeRates(c *gin.Context) {
+	if _, err := getCurrentUser(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	info, err := loadCurrencyInfo()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": info})
+}
+
+// UpsertExchangeRate 设置汇率（仅管理员）
+// @Summary 设置汇率
+// @Description 新增或更新某币种的汇率（1 单位该币种折合多少本位币），币种需在 currency.allowed 中且不能是本位币。修改后统计缓存立即失效
+// @Tags 后台管理-统计
+// @Accept json
+// @Produce json
+// @Param currency path string true "币种代码，如 USD"
+// @Param request body UpsertExchangeRateRequest true "汇率"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/exchange-rates/{currency} [put]
+func (h *AdminHandler) UpsertExchangeRate(c *gin.Context) {
+	code, ok := adminExchangeRateCurrency(c)
+	if !ok {
+		return
+	}
+	var req UpsertExchangeRateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+
+	var rate models.ExchangeRate
+	err := database.DB.Where("currency = ?", code).First(&rate).Error
+	switch {
+	case err == nil:
+		rate.Rate = req.Rate
+		err = database.DB.Save(&rate).Error
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		rate = models.ExchangeRate{Currency: code, Rate: req.Rate}
+		err = database.DB.Create(&rate).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "保存失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "保存成功", "data": rate})
+}
+
+// DeleteExchangeRate 删除汇率（仅管理员）
+// @Summary 删除汇率
+// @Description 删除某币种的汇率，之后统计中该币种的金额不再折算，列入 unconverted_currencies
+// @Tags 后台管理-统计
+// @Produce json
+// @Param currency path string true "币种代码，如 USD"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "汇率不存在"
+// @Router /admin/exchange-rates/{currency} [delete]
+func (h *AdminHandler) DeleteExchangeRate(c *gin.Context) {
+	code, ok := adminExchangeRateCurrency(c)
+	if !ok {
+		return
+	}
+	res := database.DB.Where("currency = ?", code).Delete(&models.ExchangeRate{})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(res.Error, "删除失败")})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "汇率不存在"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
+}
+
+// adminExchangeRateCurrency 校验管理员身份并解析路径中的币种，失败时已写入响应
+func adminExchangeRateCurrency(c *gin.Context) (string, bool) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return "", false
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return "", false
+	}
+	code, err := resolveCurrency(c.Param("currency"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return "", false
+	}
+	if code == baseCurrency() {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "本位币的汇率固定为 1，无需设置"})
+		return "", false
+	}
+	return code, true
+}
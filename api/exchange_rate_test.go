@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveCurrency(t *testing.T) {
+	config.GlobalConfig = &config.Config{Currency: config.CurrencyConfig{Base: "CNY", Allowed: []string{"CNY", "USD"}}}
+	defer func() { config.GlobalConfig = nil }()
+
+	code, err := resolveCurrency("")
+	require.NoError(t, err)
+	assert.Equal(t, "CNY", code)
+
+	code, err = resolveCurrency(" usd ")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", code)
+
+	_, err = resolveCurrency("EUR")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "CNY/USD")
+}
+
+func TestResolveCurrency_NoConfig(t *testing.T) {
+	config.GlobalConfig = nil
+
+	code, err := resolveCurrency("")
+	require.NoError(t, err)
+	assert.Equal(t, config.DefaultBaseCurrency, code)
+
+	_, err = resolveCurrency("USD")
+	assert.Error(t, err)
+}
+
+func TestConvertCurrencyStats(t *testing.T) {
+	config.GlobalConfig = nil
+
+	rows := []currencyStatRow{
+		{Category: "餐饮", Currency: "CNY", Total: 100, Count: 2},
+		{Category: "餐饮", Currency: "USD", Total: 10, Count: 1},
+		{Category: "交通", Currency: "", Total: 30, Count: 1},
+		{Category: "购物", Currency: "JPY", Total: 5000, Count: 1},
+	}
+	s := convertCurrencyStats(rows, exchangeRates{"CNY": 1, "USD": 7.2})
+
+	assert.Equal(t, 202.0, s.Total)
+	assert.Equal(t, int64(5), s.Count)
+	require.Len(t, s.Categories, 2)
+	assert.Equal(t, ExpenseCategoryStat{Category: "餐饮", Total: 172, Count: 3}, s.Categories[0])
+	assert.Equal(t, ExpenseCategoryStat{Category: "交通", Total: 30, Count: 1}, s.Categories[1])
+
+	require.Len(t, s.Currencies, 3)
+	assert.Equal(t, "CNY", s.Currencies[0].Currency)
+	assert.Equal(t, 130.0, s.Currencies[0].Total)
+	assert.Equal(t, "JPY", s.Currencies[1].Currency)
+	assert.Nil(t, s.Currencies[1].Converted)
+	assert.Equal(t, "USD", s.Currencies[2].Currency)
+	require.NotNil(t, s.Currencies[2].Converted)
+	assert.Equal(t, 72.0, *s.Currencies[2].Converted)
+	assert.Equal(t, []string{"JPY"}, s.Unconverted)
+}
+
+func TestMergeCurrencyCodes(t *testing.T) {
+	assert.Equal(t, []string{"JPY", "USD"}, mergeCurrencyCodes([]string{"USD"}, nil, []string{"JPY", "USD"}))
+	assert.Equal(t, []string{}, mergeCurrencyCodes())
+}
+
+func TestExchangeRateHandler_GetCurrencies(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	config.GlobalConfig = &config.Config{Currency: config.CurrencyConfig{Base: "CNY", Allowed: []string{"CNY", "USD"}}}
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates` ORDER BY currency ASC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate", "created_at", "updated_at"}).
+			AddRow(1, "USD", 7.2, time.Now(), time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/currencies", NewExchangeRateHandler().GetCurrencies)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/currencies", nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data CurrencyInfo `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "CNY", resp.Data.BaseCurrency)
+	assert.Equal(t, []string{"CNY", "USD"}, resp.Data.Allowed)
+	require.Len(t, resp.Data.Rates, 1)
+	assert.Equal(t, 7.2, resp.Data.Rates[0].Rate)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -1,16 +1,19 @@
 package api
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"finance/database"
 	"finance/middleware"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // ExpenseHandler 消费记录处理器
@@ -24,17 +27,69 @@ func NewExpenseHandler() *ExpenseHandler {
 // CreateExpenseRequest 创建消费记录请求
 type CreateExpenseRequest struct {
 	Amount      float64 `json:"amount" binding:"required,gt=0" example:"99.99"`
-	Category    string  `json:"category" binding:"required" example:"餐饮"`
+	Currency    string  `json:"currency" example:"CNY"` // 币种（ISO 4217），不传时为本位币，需在 currency.allowed 中
+	Category    string  `json:"category" example:"餐饮"`  // 可不传：关联商户时取商户默认类别，带坐标时按地理围栏规则自动归类
 	Description string  `json:"description" example:"午餐"`
 	ExpenseTime string  `json:"expense_time" binding:"required" example:"2024-01-15 12:30:00"`
+	// 类别ID（可选）：传入时按 ID 取类别当前名称写入，优先于 category 名称
+	CategoryID *uint `json:"category_id" example:"1"`
+	// 消费地点（可选，需同时传）
+	Latitude  *float64 `json:"latitude" example:"31.2304"`
+	Longitude *float64 `json:"longitude" example:"121.4737"`
+	// 关联商户（可选）
+	MerchantID *uint `json:"merchant_id" example:"1"`
+	// 分期付款（可选）：installments ≥ 2 时按月拆成多条记录，amount 为总额
+	Installments         int    `json:"installments" binding:"omitempty,min=2,max=120" example:"12"`
+	FirstInstallmentDate string `json:"first_installment_date" example:"2024-02-01"` // 首期日期，默认为 expense_time 当天
+	// 自定义扩展字段（可选）：任意键值对，最多 20 个键、3 层嵌套、2KB
+	Extra models.JSONMap `json:"extra" swaggertype:"object"`
+	// 标签（可选）：最多 10 个，每个不超过 20 个字符，不存在的标签自动创建
+	Tags []string `json:"tags" example:"出差,报销"`
+}
+
+// ExpenseCreateResponse 创建消费记录返回（在消费记录字段之外附带预算提醒）
+type ExpenseCreateResponse struct {
+	models.Expense
+	BudgetInfo     *BudgetInfo      `json:"budget_info,omitempty"`      // 仅在 level 为 warning/exceeded 时返回
+	BudgetWarning  string           `json:"budget_warning,omitempty"`   // 该类别当月已超支时的提示文案
+	Installments   []models.Expense `json:"installments,omitempty"`     // 分期创建时返回全部各期（首期即外层记录）
+	MatchedGeoRule *models.GeoRule  `json:"matched_geo_rule,omitempty"` // 按地理围栏自动归类时命中的规则
+}
+
+// MarshalJSON 保留 models.Expense 的统一时间格式并附带 budget_info/budget_warning/installments/matched_geo_rule
+func (r ExpenseCreateResponse) MarshalJSON() ([]byte, error) {
+	extra := map[string]interface{}{}
+	if r.BudgetInfo != nil {
+		extra["budget_info"] = r.BudgetInfo
+	}
+	if r.BudgetWarning != "" {
+		extra["budget_warning"] = r.BudgetWarning
+	}
+	if len(r.Installments) > 0 {
+		extra["installments"] = r.Installments
+	}
+	if r.MatchedGeoRule != nil {
+		extra["matched_geo_rule"] = r.MatchedGeoRule
+	}
+	return marshalWithExtra(r.Expense, extra)
 }
 
 // UpdateExpenseRequest 更新消费记录请求
 type UpdateExpenseRequest struct {
 	Amount      float64 `json:"amount" binding:"omitempty,gt=0" example:"99.99"`
+	Currency    string  `json:"currency" example:"USD"` // 不传则不修改
 	Category    string  `json:"category" example:"餐饮"`
 	Description string  `json:"description" example:"午餐"`
 	ExpenseTime string  `json:"expense_time" example:"2024-01-15 12:30:00"`
+	// 传入时整体替换扩展字段，传 {} 清空；不传则不修改
+	Extra *models.JSONMap `json:"extra" swaggertype:"object"`
+	// 消费地点（需同时传）
+	Latitude  *float64 `json:"latitude" example:"31.2304"`
+	Longitude *float64 `json:"longitude" example:"121.4737"`
+	// 关联商户，传 0 解除关联；不传则不修改
+	MerchantID *uint `json:"merchant_id" example:"1"`
+	// 传入时整体替换标签，传 [] 清空；不传则不修改
+	Tags *[]string `json:"tags" example:"出差,报销"`
 }
 
 // ExpenseListRequest 消费记录列表请求
@@ -44,39 +99,142 @@ type ExpenseListRequest struct {
 	Category  string `form:"category" example:"餐饮"`
 	StartTime string `form:"start_time" example:"2024-01-01"`
 	EndTime   string `form:"end_time" example:"2024-12-31"`
+	Period    string `form:"period" example:"this_month"`
+	// 商户筛选
+	MerchantID uint `form:"merchant_id" example:"1"`
+	// 标签筛选：逗号分隔，需同时带有全部标签
+	Tags string `form:"tags" example:"出差,报销"`
+	// 扩展字段筛选与带出
+	ExtraKey     string `form:"extra_key" example:"project"`
+	ExtraValue   string `form:"extra_value" example:"装修"`
+	IncludeExtra bool   `form:"include_extra" example:"true"`
+	// 字段裁剪：逗号分隔，只返回这些字段（id 始终返回）
+	Fields string `form:"fields" example:"amount,expense_time"`
+	// 游标分页：传上一页返回的 next_cursor，此时忽略 page 且不统计总数
+	Cursor string `form:"cursor"`
+	// 描述搜索：忽略大小写的模糊匹配，可与其他筛选条件组合
+	Q string `form:"q" example:"酒店"`
+	// 金额区间（含边界，按原币种金额）
+	MinAmount *float64 `form:"min_amount" example:"100"`
+	MaxAmount *float64 `form:"max_amount" example:"1000"`
 }
 
 // Create 创建消费记录
 // @Summary 创建消费记录
-// @Description 创建一条新的消费记录
+// @Description 创建一条新的消费记录。若该类别当月设置了预算且使用率达到提醒阈值，返回 budget_info（level=warning），达到 100% 时 level=exceeded 并附带 budget_warning 提示文案（不影响创建）
+// @Description 传 installments（≥2）时按月拆分为多期，每期金额=总额/期数（按分计算，尾差计入最后一期），各期共享 installment_group_id
+// @Description 类别优先级：category_id（按 ID 取类别当前名称，不存在返回 400）> category 名称 > 关联商户（merchant_id）的默认类别 > 带 latitude/longitude 时按地理围栏规则自动归类（多条命中时优先级高 > 半径小 > 距离近，并返回 matched_geo_rule）
+// @Description 带 Idempotency-Key 请求头时，24 小时内同一用户重复提交相同的键不再新建，直接返回首次创建的记录（分期返回整组），并带响应头 Idempotent-Replayed: true；首次创建的记录已删除时返回 409
 // @Tags 消费记录
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "幂等键，最长 100 个字符"
 // @Param request body CreateExpenseRequest true "消费记录信息"
-// @Success 200 {object} Response{data=models.Expense} "创建成功"
+// @Success 200 {object} Response{data=ExpenseCreateResponse} "创建成功"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "未授权"
+// @Failure 409 {object} Response "幂等键对应的记录已删除"
 // @Router /api/v1/expenses [post]
 func (h *ExpenseHandler) Create(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
+	// 幂等键已处理过（客户端网络重试）时直接返回首次创建的记录
+	idemKey, err := idempotencyKeyFrom(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if idemKey != "" && replayExpenseCreate(c, userID, idemKey) {
+		return
+	}
+
 	var req CreateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if err := validateAmount(req.Amount); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if err := validateDescription(req.Description); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if req.Installments >= 2 {
+		if err := validateInstallments(req.Amount, req.Description, req.Installments); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+	}
+	currency, err := resolveCurrency(req.Currency)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
+	// 消费地点：经纬度需同时传
+	if (req.Latitude == nil) != (req.Longitude == nil) {
+		BadRequest(c, "latitude 和 longitude 需同时传")
+		return
+	}
+	hasLocation := req.Latitude != nil
+	if hasLocation {
+		if err := validateCoordinates(*req.Latitude, *req.Longitude); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	// 指定类别ID时按 ID 取类别当前名称，优先于 category 名称
+	var cat models.ExpenseCategory
+	if req.CategoryID != nil && *req.CategoryID > 0 {
+		if err := database.DB.First(&cat, *req.CategoryID).Error; err != nil {
+			BadRequest(c, "消费类别不存在")
+			return
+		}
+		req.Category = cat.Name
+	}
+
+	// 关联商户：未指定类别时带出商户默认类别
+	if req.MerchantID != nil {
+		merchant, err := findMerchant(userID, *req.MerchantID)
+		if err != nil {
+			BadRequest(c, "商户不存在")
+			return
+		}
+		if req.Category == "" {
+			req.Category = merchant.DefaultCategory
+		}
+	}
+
+	// 仍未确定类别时按地理围栏规则自动归类
+	var matchedRule *models.GeoRule
+	if req.Category == "" && hasLocation {
+		rule, err := findGeoRule(userID, *req.Latitude, *req.Longitude)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "匹配地理围栏规则失败"))
+			return
+		}
+		if rule == nil {
+			BadRequest(c, "未指定类别，且消费地点不在任何地理围栏规则内")
+			return
+		}
+		matchedRule = rule
+		req.Category = rule.Category
+	}
+
 	// 校验类别是否存在（来源于数据库）
-	req.Category = strings.TrimSpace(req.Category)
 	if req.Category == "" {
 		BadRequest(c, "类别不能为空")
 		return
 	}
-	var cat models.ExpenseCategory
-	if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的消费类别，请先在后台维护类别"})
-		return
+	if cat.ID == 0 {
+		if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的消费类别，请先在后台维护类别"})
+			return
+		}
 	}
 
 	// 解析时间
@@ -85,21 +243,161 @@ func (h *ExpenseHandler) Create(c *gin.Context) {
 		BadRequest(c, "时间格式错误，应为: 2006-01-02 15:04:05")
 		return
 	}
+	if err := validateRecordTime("消费时间", expenseTime); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	if err := models.ValidateExtra(req.Extra); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	tags, err := parseTagNames(req.Tags)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	expense := models.Expense{
-		UserID:      userID,
-		Amount:      req.Amount,
-		Category:    req.Category,
-		Description: req.Description,
-		ExpenseTime: expenseTime,
+		UserID:       userID,
+		Amount:       req.Amount,
+		Currency:     currency,
+		Category:     req.Category,
+		Description:  req.Description,
+		ExpenseTime:  expenseTime,
+		Extra:        req.Extra,
+		Latitude:     req.Latitude,
+		Longitude:    req.Longitude,
+		MerchantID:   req.MerchantID,
+		LedgerID:     currentLedgerID(c),
+		CreatedBy:    userID,
+		RecordSource: recordSourceFrom(c),
 	}
 
-	if err := database.DB.Create(&expense).Error; err != nil {
-		InternalError(c, SafeErrorMessage(err, "创建消费记录失败"))
-		return
+	var installments []models.Expense
+	if req.Installments >= 2 {
+		first := expenseTime
+		if req.FirstInstallmentDate != "" {
+			d, err := time.ParseInLocation("2006-01-02", req.FirstInstallmentDate, time.Local)
+			if err != nil {
+				BadRequest(c, "首期日期格式错误，应为: 2006-01-02")
+				return
+			}
+			first = time.Date(d.Year(), d.Month(), d.Day(), expenseTime.Hour(), expenseTime.Minute(), expenseTime.Second(), 0, time.Local)
+			if err := validateRecordTime("首期日期", first); err != nil {
+				BadRequest(c, err.Error())
+				return
+			}
+		}
+		installments, err = buildInstallments(expense, req.Installments, first)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "生成分期失败"))
+			return
+		}
+		// 各期都打上同样的标签
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&installments).Error; err != nil {
+				return err
+			}
+			ids := make([]uint, len(installments))
+			for i := range installments {
+				ids[i] = installments[i].ID
+				installments[i].Tags = tags
+			}
+			if err := attachExpenseTags(tx, userID, ids, tags); err != nil {
+				return err
+			}
+			return saveIdempotencyKey(tx, userID, models.IdempotencyScopeExpense, idemKey, installments[0].ID)
+		})
+		if err != nil {
+			// 相同幂等键的并发请求已先提交时返回其创建的记录
+			if idemKey != "" && replayExpenseCreate(c, userID, idemKey) {
+				return
+			}
+			InternalError(c, SafeErrorMessage(err, "创建消费记录失败"))
+			return
+		}
+		expense = installments[0]
+	} else {
+		if req.FirstInstallmentDate != "" {
+			BadRequest(c, "first_installment_date 仅在分期（installments ≥ 2）时可用")
+			return
+		}
+		err = database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(&expense).Error; err != nil {
+				return err
+			}
+			if err := attachExpenseTags(tx, userID, []uint{expense.ID}, tags); err != nil {
+				return err
+			}
+			return saveIdempotencyKey(tx, userID, models.IdempotencyScopeExpense, idemKey, expense.ID)
+		})
+		if err != nil {
+			if idemKey != "" && replayExpenseCreate(c, userID, idemKey) {
+				return
+			}
+			InternalError(c, SafeErrorMessage(err, "创建消费记录失败"))
+			return
+		}
+		expense.Tags = tags
+	}
+
+	// 预算提醒：达到提醒阈值或超支时附带 budget_info，超支时另附 budget_warning，计算失败不影响创建结果
+	resp := ExpenseCreateResponse{Expense: expense, Installments: installments, MatchedGeoRule: matchedRule}
+	if info, err := findBudgetInfo(userID, expense.Category, expense.ExpenseTime); err == nil && info.Level != models.BudgetLevelNormal {
+		resp.BudgetInfo = info
+		resp.BudgetWarning = budgetWarning(info)
+	}
+	notifyLargeExpense(userID, expense, req.Amount, len(installments))
+
+	SuccessWithMessage(c, "创建成功", resp)
+}
+
+// replayExpenseCreate 幂等键已处理过时返回首次创建的消费记录（分期时附带整组），返回 true 表示已写入响应
+func replayExpenseCreate(c *gin.Context, userID uint, key string) bool {
+	recordID, ok, err := findIdempotentRecord(userID, models.IdempotencyScopeExpense, key)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询幂等键失败"))
+		return true
+	}
+	if !ok {
+		return false
+	}
+
+	var expense models.Expense
+	if err := database.DB.Where("id = ? AND user_id = ?", recordID, userID).First(&expense).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, http.StatusConflict, "该 Idempotency-Key 创建的记录已删除")
+		} else {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+		}
+		return true
+	}
+	list := []models.Expense{expense}
+	if expense.InstallmentGroupID != nil {
+		if err := database.DB.Where("installment_group_id = ? AND user_id = ?", *expense.InstallmentGroupID, userID).
+			Order("expense_time ASC, id ASC").Find(&list).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return true
+		}
+	}
+	if err := loadExpenseTags(list); err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return true
 	}
 
-	SuccessWithMessage(c, "创建成功", expense)
+	resp := ExpenseCreateResponse{Expense: expense}
+	for _, e := range list {
+		if e.ID == expense.ID {
+			resp.Expense = e
+		}
+	}
+	if expense.InstallmentGroupID != nil {
+		resp.Installments = list
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	SuccessWithMessage(c, "创建成功", resp)
+	return true
 }
 
 // List 获取消费记录列表
@@ -114,7 +412,19 @@ func (h *ExpenseHandler) Create(c *gin.Context) {
 // @Param category query string false "类别筛选"
 // @Param start_time query string false "开始时间 (2024-01-01)"
 // @Param end_time query string false "结束时间 (2024-12-31)"
-// @Success 200 {object} Response{data=PageResponse{list=[]models.Expense}} "获取成功"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param merchant_id query int false "商户ID筛选"
+// @Param tags query string false "标签筛选，多个标签用逗号分隔，需同时带有全部标签（忽略大小写）"
+// @Param extra_key query string false "按扩展字段筛选的键名（字母/数字/下划线）"
+// @Param extra_value query string false "扩展字段值（与 extra_key 配合，按文本精确匹配）"
+// @Param include_extra query bool false "是否返回扩展字段，默认不返回"
+// @Param fields query string false "只返回的字段，逗号分隔（如 amount,expense_time），id 始终返回，不存在的字段名忽略"
+// @Param min_amount query number false "最小金额（含，按原币种金额，不能为负数）"
+// @Param max_amount query number false "最大金额（含，按原币种金额，不能为负数，且不能小于 min_amount）"
+// @Param q query string false "按描述搜索（忽略大小写，% 和 _ 按字面匹配，最多100字），未传 fields 时每条记录附带 match_snippet（关键词前后的片段）"
+// @Param cursor query string false "游标分页：传上一页返回的 next_cursor，按 (expense_time, id) 降序继续取，此时返回 CursorPageResponse 且忽略 page"
+// @Success 200 {object} Response{data=PageResponse{list=[]models.Expense}} "获取成功（传 cursor 时 data 为 CursorPageResponse）"
+// @Failure 400 {object} Response "cursor 无效或金额区间不合法"
 // @Failure 401 {object} Response "未授权"
 // @Router /api/v1/expenses [get]
 func (h *ExpenseHandler) List(c *gin.Context) {
@@ -122,7 +432,7 @@ func (h *ExpenseHandler) List(c *gin.Context) {
 
 	var req ExpenseListRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
 		return
 	}
 
@@ -137,12 +447,36 @@ func (h *ExpenseHandler) List(c *gin.Context) {
 		req.PageSize = 100
 	}
 
-	query := database.DB.Model(&models.Expense{}).Where("user_id = ?", userID)
+	startStr, endStr, err := resolveUserPeriodQuery(userID, req.Period, req.StartTime, req.EndTime, time.Now())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	req.StartTime, req.EndTime = startStr, endStr
+	req.Q, err = normalizeDescriptionQuery(req.Q)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	query := database.DB.Model(&models.Expense{}).Scopes(recordScope(c, userID))
 
 	// 类别筛选
 	if req.Category != "" {
 		query = query.Where("category = ?", req.Category)
 	}
+	// 商户筛选
+	if req.MerchantID > 0 {
+		query = query.Where("merchant_id = ?", req.MerchantID)
+	}
+	// 描述搜索
+	query = applyDescriptionSearch(query, "description", req.Q)
+	// 金额区间
+	query, err = applyAmountRange(query, req.MinAmount, req.MaxAmount)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	// 时间范围筛选
 	if req.StartTime != "" {
@@ -160,24 +494,91 @@ func (h *ExpenseHandler) List(c *gin.Context) {
 		}
 	}
 
+	// 扩展字段筛选
+	query, err = applyExtraFilter(query, "extra", req.ExtraKey, req.ExtraValue)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	// 标签筛选
+	query, err = applyTagFilter(query, req.Tags)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	// 游标模式：keyset 查询，多取一条判断是否还有下一页，不统计总数
+	if req.Cursor != "" {
+		query, err = applyExpenseCursor(query, req.Cursor)
+		if err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		var expenses []models.Expense
+		if err := query.Order("expense_time DESC, id DESC").Limit(req.PageSize + 1).Find(&expenses).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return
+		}
+		resp := CursorPageResponse{PageSize: req.PageSize}
+		if len(expenses) > req.PageSize {
+			expenses = expenses[:req.PageSize]
+			resp.HasNext = true
+			resp.NextCursor = encodeExpenseCursor(expenses[len(expenses)-1])
+		}
+		resp.List, err = h.expenseListItems(expenses, req)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return
+		}
+		Success(c, resp)
+		return
+	}
+
 	// 获取总数
 	var total int64
 	query.Count(&total)
 
-	// 获取列表
+	// 获取列表（id 作为同一时间的次序，保证与游标模式的顺序一致）
 	var expenses []models.Expense
 	offset := (req.Page - 1) * req.PageSize
-	if err := query.Order("expense_time DESC").Offset(offset).Limit(req.PageSize).Find(&expenses).Error; err != nil {
+	if err := query.Order("expense_time DESC, id DESC").Offset(offset).Limit(req.PageSize).Find(&expenses).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	list, err := h.expenseListItems(expenses, req)
+	if err != nil {
 		InternalError(c, SafeErrorMessage(err, "查询失败"))
 		return
 	}
 
-	Success(c, PageResponse{
-		Total:    total,
-		Page:     req.Page,
-		PageSize: req.PageSize,
-		List:     expenses,
-	})
+	resp := NewPageResponse(total, req.Page, req.PageSize, list)
+	// 带出游标，客户端可从任意一页切换到游标模式继续翻页
+	if resp.HasNext && len(expenses) > 0 {
+		resp.NextCursor = encodeExpenseCursor(expenses[len(expenses)-1])
+	}
+	Success(c, resp)
+}
+
+// expenseListItems 为列表结果加载标签、按需去掉扩展字段并裁剪返回字段；
+// 按描述搜索且未裁剪字段时附带命中片段
+func (h *ExpenseHandler) expenseListItems(expenses []models.Expense, req ExpenseListRequest) (interface{}, error) {
+	if err := loadExpenseTags(expenses); err != nil {
+		return nil, err
+	}
+	if !req.IncludeExtra {
+		stripExtra(expenses)
+	}
+	fields := parseFieldsParam(req.Fields)
+	if req.Q != "" && len(fields) == 0 {
+		records := make([]interface{}, len(expenses))
+		descriptions := make([]string, len(expenses))
+		for i, e := range expenses {
+			records[i] = e
+			descriptions[i] = e.Description
+		}
+		return withMatchSnippets(records, descriptions, req.Q), nil
+	}
+	return sparseList(expenses, fields)
 }
 
 // Get 获取单条消费记录
@@ -205,8 +606,13 @@ func (h *ExpenseHandler) Get(c *gin.Context) {
 		NotFound(c, "记录不存在")
 		return
 	}
+	list := []models.Expense{expense}
+	if err := loadExpenseTags(list); err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
 
-	Success(c, expense)
+	Success(c, list[0])
 }
 
 // Update 更新消费记录
@@ -238,22 +644,29 @@ func (h *ExpenseHandler) Update(c *gin.Context) {
 	}
 
 	var req UpdateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
 		return
 	}
 
 	// 更新字段
 	updates := make(map[string]interface{})
 	if req.Amount > 0 {
+		if err := validateAmount(req.Amount); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
 		updates["amount"] = req.Amount
 	}
-	if req.Category != "" {
-		req.Category = strings.TrimSpace(req.Category)
-		if req.Category == "" {
-			BadRequest(c, "类别不能为空")
+	if req.Currency != "" {
+		currency, err := resolveCurrency(req.Currency)
+		if err != nil {
+			BadRequest(c, err.Error())
 			return
 		}
+		updates["currency"] = currency
+	}
+	if req.Category != "" {
 		var cat models.ExpenseCategory
 		if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
 			BadRequest(c, "无效的消费类别，请先在后台维护类别")
@@ -262,6 +675,10 @@ func (h *ExpenseHandler) Update(c *gin.Context) {
 		updates["category"] = req.Category
 	}
 	if req.Description != "" {
+		if err := validateDescription(req.Description); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
 		updates["description"] = req.Description
 	}
 	if req.ExpenseTime != "" {
@@ -270,17 +687,72 @@ func (h *ExpenseHandler) Update(c *gin.Context) {
 			BadRequest(c, "时间格式错误，应为: 2006-01-02 15:04:05")
 			return
 		}
+		if err := validateRecordTime("消费时间", expenseTime); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
 		updates["expense_time"] = expenseTime
 	}
+	if req.Extra != nil {
+		if err := models.ValidateExtra(*req.Extra); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["extra"] = *req.Extra
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		if req.Latitude == nil || req.Longitude == nil {
+			BadRequest(c, "latitude 和 longitude 需同时传")
+			return
+		}
+		if err := validateCoordinates(*req.Latitude, *req.Longitude); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["latitude"] = *req.Latitude
+		updates["longitude"] = *req.Longitude
+	}
+	if req.MerchantID != nil {
+		if *req.MerchantID == 0 {
+			updates["merchant_id"] = nil
+		} else {
+			if _, err := findMerchant(userID, *req.MerchantID); err != nil {
+				BadRequest(c, "商户不存在")
+				return
+			}
+			updates["merchant_id"] = *req.MerchantID
+		}
+	}
+	var tags []string
+	if req.Tags != nil {
+		if tags, err = parseTagNames(*req.Tags); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+	}
 
-	if err := database.DB.Model(&expense).Updates(updates).Error; err != nil {
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&expense).Updates(updates).Error; err != nil {
+			return err
+		}
+		if req.Tags != nil {
+			return replaceExpenseTags(tx, userID, expense.ID, tags)
+		}
+		return nil
+	})
+	if err != nil {
 		InternalError(c, SafeErrorMessage(err, "更新失败"))
 		return
 	}
 
 	// 重新获取更新后的记录
 	database.DB.First(&expense, expense.ID)
-	SuccessWithMessage(c, "更新成功", expense)
+	list := []models.Expense{expense}
+	if err := loadExpenseTags(list); err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	SuccessWithMessage(c, "更新成功", list[0])
 }
 
 // Delete 删除消费记录
@@ -309,7 +781,7 @@ func (h *ExpenseHandler) Delete(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Delete(&expense).Error; err != nil {
+	if err := models.SoftDeleteExpenses(database.DB.Model(&expense), userID).Error; err != nil {
 		InternalError(c, SafeErrorMessage(err, "删除失败"))
 		return
 	}
@@ -367,90 +839,190 @@ func (h *ExpenseHandler) GetCategories(c *gin.Context) {
 
 // GetStatistics 获取消费统计
 // @Summary 获取消费统计
-// @Description 获取指定时间范围内的消费统计
+// @Description 获取指定时间范围内的消费统计。各币种金额按后台维护的汇率折算为本位币（base_currency）后合计，
+// @Description currency_totals 给出各币种原币合计，缺少汇率的币种不计入合计并列在 unconverted_currencies 中。
+// @Description 不传 period/start_time/end_time 时默认统计本月（1 日至今天，按 tz 计算），响应中的 start_time/end_time 回显实际使用的范围
 // @Tags 消费记录
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param start_time query string false "开始时间 (2024-01-01)"
 // @Param end_time query string false "结束时间 (2024-12-31)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，用于计算默认的本月范围与 period，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param rollup query bool false "是否把子类别金额与笔数累加到顶层父类别，默认不上卷；没有类别层级时结果不变"
 // @Success 200 {object} Response "获取成功"
 // @Failure 401 {object} Response "未授权"
 // @Router /api/v1/expenses/statistics [get]
 func (h *ExpenseHandler) GetStatistics(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
+	stats, err := queryExpenseStatistics(c, userID)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
-	query := database.DB.Model(&models.Expense{}).Where("user_id = ?", userID)
+	Success(c, gin.H{
+		"start_time":             stats.StartTime,
+		"end_time":               stats.EndTime,
+		"base_currency":          baseCurrency(),
+		"total_amount":           stats.TotalAmount,
+		"category_stats":         stats.CategoryStats,
+		"currency_totals":        stats.CurrencyTotals,
+		"unconverted_currencies": stats.Unconverted,
+	})
+}
 
-	// 时间范围筛选
-	if startTimeStr != "" {
-		startTime, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
-		if err == nil {
-			query = query.Where("expense_time >= ?", startTime)
+// ExpenseCategoryStat 按类别统计的消费
+type ExpenseCategoryStat struct {
+	Category string  `json:"category"`
+	Total    float64 `json:"total"`
+	Count    int64   `json:"count"`
+}
+
+// ExpenseCategoryShare 带占比的类别统计
+type ExpenseCategoryShare struct {
+	ExpenseCategoryStat
+	Percentage float64 `json:"percentage"` // 占总金额的百分比
+}
+
+// withCategoryPercentage 计算各类别占总金额的百分比，总金额为 0 时占比均为 0
+func withCategoryPercentage(stats []ExpenseCategoryStat, total float64) []ExpenseCategoryShare {
+	out := make([]ExpenseCategoryShare, len(stats))
+	for i, s := range stats {
+		out[i] = ExpenseCategoryShare{ExpenseCategoryStat: s}
+		if total > 0 {
+			out[i].Percentage = (s.Total / total) * 100
 		}
 	}
-	if endTimeStr != "" {
-		endTime, err := time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
-		if err == nil {
-			endTime = endTime.Add(24*time.Hour - time.Second)
-			query = query.Where("expense_time <= ?", endTime)
-		}
+	return out
+}
+
+// expenseStatisticsResult 缓存的消费统计结果，金额均已折算为本位币
+type expenseStatisticsResult struct {
+	StartTime      string // 实际使用的开始日期（YYYY-MM-DD），为空表示不限
+	EndTime        string // 实际使用的结束日期（YYYY-MM-DD），为空表示不限
+	TotalAmount    float64
+	CategoryStats  []ExpenseCategoryStat
+	CurrencyTotals []CurrencyTotal // 各币种原币合计
+	Unconverted    []string        // 缺少汇率、未计入合计的币种
+}
+
+// queryExpenseStatistics 按 period/start_time/end_time/include_transfer 查询总金额和类别统计，
+// rollup=true 时把子类别金额上卷到顶层父类别。消费统计接口与统计图共用，仅在时间参数冲突时返回错误
+func queryExpenseStatistics(c *gin.Context, userID uint) (expenseStatisticsResult, error) {
+	stats, err := queryCategoryStatistics(c, userID)
+	if err != nil || c.Query("rollup") != "true" {
+		return stats, err
 	}
+	// 读取类别层级失败时退回不上卷的结果
+	parents, perr := loadCategoryParents()
+	if perr != nil {
+		return stats, nil
+	}
+	stats.CategoryStats = rollupCategoryStats(stats.CategoryStats, parents)
+	return stats, nil
+}
 
-	// 总金额
-	var totalAmount float64
-	query.Select("COALESCE(SUM(amount), 0)").Scan(&totalAmount)
+// queryCategoryStatistics 查询总金额和按类别（不上卷）的统计，各币种按汇率折算为本位币，结果按解析后的时间范围缓存。
+// 未传 period/start_time/end_time 时默认统计本月（按 tz 参数所指时区）
+func queryCategoryStatistics(c *gin.Context, userID uint) (expenseStatisticsResult, error) {
+	now, err := requestNow(c)
+	if err != nil {
+		return expenseStatisticsResult{}, err
+	}
+	startTimeStr, endTimeStr, err := resolveUserPeriodQuery(userID, c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		return expenseStatisticsResult{}, err
+	}
+	startTimeStr, endTimeStr = defaultToThisMonth(startTimeStr, endTimeStr, now)
 
-	// 按类别统计
-	type CategoryStat struct {
-		Category string  `json:"category"`
-		Total    float64 `json:"total"`
-		Count    int64   `json:"count"`
+	prefix := service.UserCachePrefix(userID)
+	if ledgerID := middleware.GetCurrentLedgerID(c); ledgerID > 0 {
+		prefix = service.LedgerCachePrefix(ledgerID)
+	}
+	cacheKey := fmt.Sprintf("%s%s~%s:%t", prefix, startTimeStr, endTimeStr, includeTransfer(c))
+	if v, ok := service.ExpenseStatisticsCache.Get(cacheKey); ok {
+		return v.(expenseStatisticsResult), nil
 	}
-	var categoryStats []CategoryStat
 
-	database.DB.Model(&models.Expense{}).
-		Select("category, SUM(amount) as total, COUNT(*) as count").
-		Where("user_id = ?", userID).
-		Group("category").
-		Order("total DESC").
-		Scan(&categoryStats)
+	// 总金额与类别统计使用相同的筛选条件
+	ownerScope := recordScope(c, userID)
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = ownerScope(db)
+		// 默认排除内部转账类别
+		if !includeTransfer(c) {
+			db = excludeTransferCategories(db, "category")
+		}
+		// 时间范围筛选
+		if startTimeStr != "" {
+			if startTime, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local); err == nil {
+				db = db.Where("expense_time >= ?", startTime)
+			}
+		}
+		if endTimeStr != "" {
+			if endTime, err := time.ParseInLocation("2006-01-02", endTimeStr, time.Local); err == nil {
+				db = db.Where("expense_time <= ?", endTime.Add(24*time.Hour-time.Second))
+			}
+		}
+		return db
+	}
 
-	Success(c, gin.H{
-		"total_amount":   totalAmount,
-		"category_stats": categoryStats,
-	})
+	// 按类别与币种汇总后折算为本位币，总金额为各类别之和
+	summary, err := queryCurrencySummary(database.DB.Model(&models.Expense{}).Scopes(scope), "category")
+	result := expenseStatisticsResult{
+		StartTime:      startTimeStr,
+		EndTime:        endTimeStr,
+		TotalAmount:    summary.Total,
+		CategoryStats:  summary.Categories,
+		CurrencyTotals: summary.Currencies,
+		Unconverted:    summary.Unconverted,
+	}
+
+	// 查询失败的结果不缓存
+	if err != nil {
+		return result, nil
+	}
+	service.ExpenseStatisticsCache.Set(cacheKey, result)
+	return result, nil
 }
 
 // GetDetailedStatistics 获取详细消费统计（支持月/年/自定义时间范围和多个类别筛选）
 // @Summary 获取详细消费统计
 // @Description 获取消费统计信息，支持多种时间范围筛选（月、年、自定义）和多个类别筛选。返回按类别统计的数据，适合绘制饼图和柱状图。
 // @Description
-// @Description 时间范围类型说明：
-// @Description - month: 按月统计，需要传入 year_month 参数（格式：2024-01）
-// @Description - year: 按年统计，需要传入 year 参数（格式：2024）
-// @Description - custom: 自定义时间范围，需要传入 start_time 和 end_time 参数（格式：2024-01-01）
+// @Description 时间范围类型说明（不传 range_type 时按月）：
+// @Description - month: 按月统计，year_month 参数（格式：2024-01）不传时为本月
+// @Description - year: 按年统计，year 参数（格式：2024）不传时为今年
+// @Description - custom: 自定义时间范围，start_time 和 end_time 参数（格式：2024-01-01）需同时传入，都不传时为本月 1 日至今天
+// @Description 本月/今年按 tz 参数所指时区计算；响应中的 start_time、end_time 为实际使用的范围
 // @Description
 // @Description 类别筛选说明：
 // @Description - categories: 可选的类别筛选，多个类别用逗号分隔（如：餐饮,交通），不传则统计所有类别
 // @Description
 // @Description 返回数据说明：
-// @Description - total_amount: 总金额
+// @Description - base_currency: 本位币
+// @Description - total_amount: 总金额（各币种按汇率折算为本位币，缺少汇率的币种不计入）
 // @Description - total_count: 总记录数
-// @Description - category_stats: 按类别统计的数组，每个元素包含 category（类别名称）、total（总金额）、count（记录数）、percentage（占比百分比）
+// @Description - category_stats: 按类别统计的数组，每个元素包含 category（类别名称）、total（本位币总金额）、count（记录数）、percentage（占比百分比）
+// @Description - currency_totals: 各币种的原币合计 total、笔数 count 与折合本位币金额 converted（缺少汇率时为 null）
+// @Description - unconverted_currencies: 缺少汇率、未计入合计的币种
+// @Description - tag_stats: 仅 by_tag=true 时返回，按标签统计的数组，每个元素包含 tag、total、count、percentage；一条记录有多个标签时计入每个标签，未打标签的记录不出现
 // @Tags 消费记录
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param range_type query string true "时间范围类型：month（月）/year（年）/custom（自定义）" Enums(month,year,custom)
-// @Param year_month query string false "年月（当range_type=month时必填，格式：2024-01）"
-// @Param year query string false "年份（当range_type=year时必填，格式：2024）"
-// @Param start_time query string false "开始时间（当range_type=custom时必填，格式：2024-01-01）"
-// @Param end_time query string false "结束时间（当range_type=custom时必填，格式：2024-12-31）"
+// @Param range_type query string false "时间范围类型：month（月，默认）/year（年）/custom（自定义）" Enums(month,year,custom)
+// @Param year_month query string false "年月（range_type=month时使用，格式：2024-01），默认本月"
+// @Param year query string false "年份（range_type=year时使用，格式：2024），默认今年"
+// @Param start_time query string false "开始时间（range_type=custom时使用，格式：2024-01-01），与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间（range_type=custom时使用，格式：2024-12-31），与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，用于计算默认的本月/今年，默认服务器时区"
 // @Param categories query string false "类别筛选，多个类别用逗号分隔（如：餐饮,交通）"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param by_tag query bool false "是否同时返回按标签的统计 tag_stats，默认不返回"
 // @Success 200 {object} Response "获取成功，返回统计数据和分类统计"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "未授权"
@@ -458,140 +1030,64 @@ func (h *ExpenseHandler) GetStatistics(c *gin.Context) {
 func (h *ExpenseHandler) GetDetailedStatistics(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	rangeType := c.Query("range_type")
-	if rangeType == "" {
-		BadRequest(c, "range_type参数必填，可选值：month、year、custom")
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
-
-	query := database.DB.Model(&models.Expense{}).Where("user_id = ?", userID)
-
-	var startTime, endTime time.Time
-	var err error
-
-	// 根据时间范围类型设置时间范围
-	switch rangeType {
-	case "month":
-		yearMonth := c.Query("year_month")
-		if yearMonth == "" {
-			BadRequest(c, "range_type=month时，year_month参数必填（格式：2024-01）")
-			return
-		}
-		startTime, err = time.ParseInLocation("2006-01", yearMonth, time.Local)
-		if err != nil {
-			BadRequest(c, "year_month格式错误，应为：2024-01")
-			return
-		}
-		// 该月的第一天 00:00:00
-		startTime = time.Date(startTime.Year(), startTime.Month(), 1, 0, 0, 0, 0, time.Local)
-		// 该月的最后一天 23:59:59
-		endTime = startTime.AddDate(0, 1, 0).Add(-time.Second)
-
-	case "year":
-		yearStr := c.Query("year")
-		if yearStr == "" {
-			BadRequest(c, "range_type=year时，year参数必填（格式：2024）")
-			return
-		}
-		year, err := strconv.Atoi(yearStr)
-		if err != nil || year < 2000 || year > 2100 {
-			BadRequest(c, "year格式错误，应为4位数字（如：2024）")
-			return
-		}
-		// 该年的第一天
-		startTime = time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
-		// 该年的最后一天
-		endTime = time.Date(year, 12, 31, 23, 59, 59, 0, time.Local)
-
-	case "custom":
-		startTimeStr := c.Query("start_time")
-		endTimeStr := c.Query("end_time")
-		if startTimeStr == "" || endTimeStr == "" {
-			BadRequest(c, "range_type=custom时，start_time和end_time参数必填（格式：2024-01-01）")
-			return
-		}
-		startTime, err = time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
-		if err != nil {
-			BadRequest(c, "start_time格式错误，应为：2024-01-01")
-			return
-		}
-		endTime, err = time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
-		if err != nil {
-			BadRequest(c, "end_time格式错误，应为：2024-12-31")
-			return
-		}
-		// 包含结束日期当天
-		endTime = endTime.Add(24*time.Hour - time.Second)
-
-	default:
-		BadRequest(c, "range_type参数值错误，可选值：month、year、custom")
+	// 不传 range_type 时按月统计
+	r, err := parseStatisticsRange(c, now)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 
-	// 应用时间范围筛选
-	query = query.Where("expense_time >= ? AND expense_time <= ?", startTime, endTime)
-
-	// 类别筛选（支持多个类别）
-	categoriesStr := c.Query("categories")
-	if categoriesStr != "" {
-		categories := strings.Split(categoriesStr, ",")
-		// 去除空格
-		for i := range categories {
-			categories[i] = strings.TrimSpace(categories[i])
+	// 类别统计与标签统计使用相同的筛选条件
+	ownerScope := recordScope(c, userID)
+	withTransfer := includeTransfer(c)
+	categories := splitQueryList(c.Query("categories"))
+	scope := func(db *gorm.DB) *gorm.DB {
+		db = ownerScope(db)
+		// 应用时间范围筛选
+		db = db.Where("expense_time >= ? AND expense_time <= ?", r.Start, r.End)
+		// 默认排除内部转账类别
+		if !withTransfer {
+			db = excludeTransferCategories(db, "category")
 		}
+		// 类别筛选（支持多个类别）
 		if len(categories) > 0 {
-			query = query.Where("category IN ?", categories)
+			db = db.Where("category IN ?", categories)
 		}
+		return db
 	}
 
-	// 总金额和总记录数
-	var totalAmount float64
-	var totalCount int64
-	query.Select("COALESCE(SUM(amount), 0)").Scan(&totalAmount)
-	query.Count(&totalCount)
-
-	// 按类别统计
-	type CategoryStat struct {
-		Category   string  `json:"category"`
-		Total      float64 `json:"total"`
-		Count      int64   `json:"count"`
-		Percentage float64 `json:"percentage"`
+	// 按类别与币种汇总，折算为本位币后计算总金额、总记录数与各类别占比
+	summary, err := queryCurrencySummary(database.DB.Model(&models.Expense{}).Scopes(scope), "category")
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
 	}
-	var categoryStats []CategoryStat
-
-	// 构建类别统计查询
-	categoryQuery := database.DB.Model(&models.Expense{}).
-		Select("category, SUM(amount) as total, COUNT(*) as count").
-		Where("user_id = ? AND expense_time >= ? AND expense_time <= ?", userID, startTime, endTime)
+	totalAmount, totalCount := summary.Total, summary.Count
+	categoryStats := withCategoryPercentage(summary.Categories, totalAmount)
 
-	// 应用类别筛选
-	if categoriesStr != "" {
-		categories := strings.Split(categoriesStr, ",")
-		for i := range categories {
-			categories[i] = strings.TrimSpace(categories[i])
-		}
-		if len(categories) > 0 {
-			categoryQuery = categoryQuery.Where("category IN ?", categories)
-		}
+	resp := gin.H{
+		"range_type":             r.Type,
+		"start_time":             models.FormatTime(r.Start),
+		"end_time":               models.FormatTime(r.End),
+		"base_currency":          baseCurrency(),
+		"total_amount":           totalAmount,
+		"total_count":            totalCount,
+		"category_stats":         categoryStats,
+		"currency_totals":        summary.Currencies,
+		"unconverted_currencies": summary.Unconverted,
 	}
-
-	categoryQuery.Group("category").Order("total DESC").Scan(&categoryStats)
-
-	// 计算每个类别的占比
-	for i := range categoryStats {
-		if totalAmount > 0 {
-			categoryStats[i].Percentage = (categoryStats[i].Total / totalAmount) * 100
-		} else {
-			categoryStats[i].Percentage = 0
+	if c.Query("by_tag") == "true" {
+		tagStats, err := queryTagStatistics(database.DB.Model(&models.Expense{}).Scopes(scope), totalAmount)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return
 		}
+		resp["tag_stats"] = tagStats
 	}
-
-	Success(c, gin.H{
-		"range_type":     rangeType,
-		"start_time":     startTime.Format("2006-01-02 15:04:05"),
-		"end_time":       endTime.Format("2006-01-02 15:04:05"),
-		"total_amount":   totalAmount,
-		"total_count":    totalCount,
-		"category_stats": categoryStats,
-	})
+	Success(c, resp)
 }
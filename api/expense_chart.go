@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// parseChartSize 解析图片宽/高，未传时使用默认值
+func parseChartSize(v string, def int) (int, bool) {
+	if v == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < service.MinChartSize || n > service.MaxChartSize {
+		return 0, false
+	}
+	return n, true
+}
+
+// chartFontPath 统计图字体路径（未加载配置时为空）
+func chartFontPath() string {
+	if config.GlobalConfig == nil {
+		return ""
+	}
+	return config.GlobalConfig.Export.ChartFontPath
+}
+
+// GetStatisticsChart 消费统计图
+// @Summary 导出消费统计图（PNG）
+// @Description 与消费统计接口使用相同的筛选条件，按类别统计在服务端渲染饼图或柱状图并返回 PNG，颜色取消费类别的 color。
+// @Description 超过 10 个类别时其余合并为"其他"；无数据时返回"暂无数据"占位图。不传时间参数时默认本月，实际范围由响应头 X-Range-Start/X-Range-End 回显
+// @Tags 消费记录
+// @Produce png
+// @Security BearerAuth
+// @Param type query string false "图表类型：pie（饼图，默认）/bar（柱状图）" Enums(pie,bar)
+// @Param width query int false "图片宽度（像素），默认 800，范围 200-2000"
+// @Param height query int false "图片高度（像素），默认 600，范围 200-2000"
+// @Param start_time query string false "开始时间 (2024-01-01)"
+// @Param end_time query string false "结束时间 (2024-12-31)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param rollup query bool false "是否把子类别上卷到顶层父类别，默认不上卷"
+// @Success 200 {file} binary "PNG 图片"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/expenses/statistics/chart.png [get]
+func (h *ExpenseHandler) GetStatisticsChart(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	chartType := c.DefaultQuery("type", service.ChartTypePie)
+	if chartType != service.ChartTypePie && chartType != service.ChartTypeBar {
+		BadRequest(c, "type 仅支持 pie/bar")
+		return
+	}
+	width, ok := parseChartSize(c.Query("width"), service.DefaultChartWidth)
+	if !ok {
+		BadRequest(c, "width 需在 200-2000 之间")
+		return
+	}
+	height, ok := parseChartSize(c.Query("height"), service.DefaultChartHeight)
+	if !ok {
+		BadRequest(c, "height 需在 200-2000 之间")
+		return
+	}
+
+	stats, err := queryExpenseStatistics(c, userID)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	categoryStats := stats.CategoryStats
+	setRangeHeaders(c, stats.StartTime, stats.EndTime)
+
+	// 类别颜色
+	colors := make(map[string]string)
+	if len(categoryStats) > 0 {
+		names := make([]string, len(categoryStats))
+		for i, s := range categoryStats {
+			names[i] = s.Category
+		}
+		var cats []models.ExpenseCategory
+		database.DB.Select("name", "color").Where("name IN ?", names).Find(&cats)
+		for _, cat := range cats {
+			colors[cat.Name] = cat.Color
+		}
+	}
+	items := make([]service.ChartItem, len(categoryStats))
+	for i, s := range categoryStats {
+		items[i] = service.ChartItem{Label: s.Category, Value: s.Total, Color: colors[s.Category]}
+	}
+
+	var buf bytes.Buffer
+	err = service.RenderChartPNG(&buf, items, service.ChartOptions{
+		Type:     chartType,
+		Width:    width,
+		Height:   height,
+		Title:    "消费统计",
+		FontPath: chartFontPath(),
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "生成统计图失败"))
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Data(http.StatusOK, "image/png", buf.Bytes())
+}
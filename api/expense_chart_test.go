@@ -0,0 +1,82 @@
+package api
+
+import (
+	"bytes"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpenseHandler_GetStatisticsChart(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 按类别与币种汇总后折算为本位币
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses` WHERE user_id = \\? AND expense_time >= \\? AND expense_time <= \\?.* GROUP BY category, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("餐饮", "CNY", 100, 2).AddRow("交通", "CNY", 20, 1).AddRow("交通", "USD", 5, 1))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}).AddRow(1, "USD", 6))
+	mock.ExpectQuery("SELECT `name`,`color` FROM `expense_categories` WHERE name IN \\(\\?,\\?\\)").
+		WithArgs("餐饮", "交通").
+		WillReturnRows(sqlmock.NewRows([]string{"name", "color"}).AddRow("餐饮", "#ef4444").AddRow("交通", "#3b82f6"))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics/chart.png", NewExpenseHandler().GetStatisticsChart)
+
+	req := httptest.NewRequest("GET", "/expenses/statistics/chart.png?type=bar&width=400&height=300&start_time=2024-01-01&end_time=2024-01-31&include_transfer=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "image/png", w.Header().Get("Content-Type"))
+	cfg, err := png.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 400, cfg.Width)
+	assert.Equal(t, 300, cfg.Height)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_GetStatisticsChart_Empty(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics/chart.png", NewExpenseHandler().GetStatisticsChart)
+
+	req := httptest.NewRequest("GET", "/expenses/statistics/chart.png", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// 无数据时返回默认尺寸的占位图
+	require.Equal(t, 200, w.Code)
+	cfg, err := png.DecodeConfig(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 800, cfg.Width)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_GetStatisticsChart_InvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics/chart.png", NewExpenseHandler().GetStatisticsChart)
+
+	for _, q := range []string{"type=line", "width=100", "height=3000", "width=abc", "period=this_month&start_time=2024-01-01"} {
+		req := httptest.NewRequest("GET", "/expenses/statistics/chart.png?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equalf(t, 400, w.Code, "query %s", q)
+	}
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// errInvalidCursor 游标无法解析
+var errInvalidCursor = errors.New("cursor 无效")
+
+// CursorPageResponse 游标分页响应：不统计总数，按 next_cursor 继续向后翻页
+type CursorPageResponse struct {
+	PageSize   int         `json:"page_size"`
+	HasNext    bool        `json:"has_next"`
+	NextCursor string      `json:"next_cursor,omitempty"` // 下一页游标，没有更多记录时为空
+	List       interface{} `json:"list"`
+}
+
+// encodeExpenseCursor 将最后一条记录的 expense_time + id 编码为不透明游标
+func encodeExpenseCursor(e models.Expense) string {
+	raw := strconv.FormatInt(e.ExpenseTime.UnixNano(), 10) + ":" + strconv.FormatUint(uint64(e.ID), 10)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeExpenseCursor 解析游标，返回上一页最后一条记录的 expense_time 与 id
+func decodeExpenseCursor(cursor string) (time.Time, uint, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, 0, errInvalidCursor
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return time.Time{}, 0, errInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, 0, errInvalidCursor
+	}
+	n, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || n == 0 {
+		return time.Time{}, 0, errInvalidCursor
+	}
+	return time.Unix(0, nanos).In(time.Local), uint(n), nil
+}
+
+// applyExpenseCursor 按 (expense_time, id) 降序取游标之后的记录（keyset 分页，不受中途新增记录影响）
+func applyExpenseCursor(query *gorm.DB, cursor string) (*gorm.DB, error) {
+	expenseTime, id, err := decodeExpenseCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	return query.Where("(expense_time, id) < (?, ?)", expenseTime, id), nil
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpenseCursor_RoundTrip(t *testing.T) {
+	ts := time.Date(2024, 3, 1, 12, 30, 0, 0, time.Local)
+	cursor := encodeExpenseCursor(models.Expense{ID: 42, ExpenseTime: ts})
+
+	gotTime, gotID, err := decodeExpenseCursor(cursor)
+	require.NoError(t, err)
+	assert.True(t, ts.Equal(gotTime))
+	assert.Equal(t, uint(42), gotID)
+
+	for _, bad := range []string{"!!", "bm9jb2xvbg", "YWJjOjE", "MTIzOjA"} {
+		_, _, err := decodeExpenseCursor(bad)
+		assert.ErrorIs(t, err, errInvalidCursor, bad)
+	}
+}
+
+func TestExpenseHandler_List_Cursor(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	last := time.Date(2024, 3, 1, 12, 0, 0, 0, time.Local)
+	cursor := encodeExpenseCursor(models.Expense{ID: 10, ExpenseTime: last})
+
+	// 多取一条判断是否还有下一页，不统计总数
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(expense_time, id\\) < \\(\\?, \\?\\) AND user_id = \\?.*ORDER BY expense_time DESC, id DESC LIMIT 3").
+		WithArgs(sqlmock.AnyArg(), 10, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "expense_time"}).
+			AddRow(9, 1, 10, "餐饮", last).
+			AddRow(8, 1, 20, "交通", last.Add(-time.Hour)).
+			AddRow(7, 1, 30, "购物", last.Add(-2*time.Hour)))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags`").
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?page_size=2&cursor="+cursor, nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data struct {
+			Total      *int64                   `json:"total"`
+			HasNext    bool                     `json:"has_next"`
+			NextCursor string                   `json:"next_cursor"`
+			List       []map[string]interface{} `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Nil(t, resp.Data.Total)
+	assert.True(t, resp.Data.HasNext)
+	require.Len(t, resp.Data.List, 2)
+
+	// 下一页游标指向本页最后一条
+	nextTime, nextID, err := decodeExpenseCursor(resp.Data.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, uint(8), nextID)
+	assert.True(t, last.Add(-time.Hour).Equal(nextTime))
+}
+
+func TestExpenseHandler_List_InvalidCursor(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?cursor=not-a-cursor", nil))
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "cursor 无效")
+}
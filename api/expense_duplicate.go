@@ -0,0 +1,95 @@
+package api
+
+import (
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// DuplicateExpenseRequest 复制消费记录请求（可省略请求体）
+type DuplicateExpenseRequest struct {
+	ExpenseTime string `json:"expense_time" example:"2024-01-16 08:00:00"` // 新记录的消费时间，不传为当前时间
+}
+
+// Duplicate 复制消费记录
+// @Summary 复制消费记录
+// @Description 将自己的一条消费记录复制为新记录：金额、类别、描述、扩展字段、消费地点、商户沿用原记录，消费时间默认为当前时间。
+// @Description 分期信息不复制（新记录为普通消费）；原记录的类别已被删除时不能复制
+// @Tags 消费记录
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "消费记录ID"
+// @Param request body DuplicateExpenseRequest false "可选，指定新记录的消费时间"
+// @Success 200 {object} Response{data=models.Expense} "复制成功，返回新记录"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "记录不存在"
+// @Router /api/v1/expenses/{id}/duplicate [post]
+func (h *ExpenseHandler) Duplicate(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+
+	var req DuplicateExpenseRequest
+	if c.Request.ContentLength > 0 {
+		if err := bindJSON(c, &req); err != nil {
+			BindError(c, err)
+			return
+		}
+	}
+
+	var src models.Expense
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&src).Error; err != nil {
+		NotFound(c, "记录不存在")
+		return
+	}
+
+	expenseTime := time.Now()
+	if req.ExpenseTime != "" {
+		expenseTime, err = time.ParseInLocation("2006-01-02 15:04:05", req.ExpenseTime, time.Local)
+		if err != nil {
+			BadRequest(c, "时间格式错误，应为: 2006-01-02 15:04:05")
+			return
+		}
+		if err := validateRecordTime("消费时间", expenseTime); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+	}
+
+	var cat models.ExpenseCategory
+	if err := database.DB.Where("name = ?", src.Category).First(&cat).Error; err != nil {
+		BadRequest(c, "原记录的类别已被删除，无法复制")
+		return
+	}
+
+	expense := models.Expense{
+		UserID:       userID,
+		Amount:       src.Amount,
+		Currency:     src.Currency,
+		Category:     src.Category,
+		Description:  src.Description,
+		ExpenseTime:  expenseTime,
+		Extra:        src.Extra,
+		Latitude:     src.Latitude,
+		Longitude:    src.Longitude,
+		MerchantID:   src.MerchantID,
+		CreatedBy:    userID,
+		RecordSource: recordSourceFrom(c),
+	}
+	if err := database.DB.Create(&expense).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "复制消费记录失败"))
+		return
+	}
+
+	SuccessWithMessage(c, "复制成功", expense)
+}
@@ -0,0 +1,91 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func duplicateRouter(userID uint) *gin.Engine {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(userID))
+	router.POST("/expenses/:id/duplicate", NewExpenseHandler().Duplicate)
+	return router
+}
+
+func TestExpenseHandler_Duplicate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "description", "expense_time", "installment_group_id", "installment_index", "installment_total"}).
+			AddRow(5, 1, 12.5, "餐饮", "早餐", time.Date(2024, 1, 15, 8, 0, 0, 0, time.Local), "g1", 1, 3))
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\?").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "餐饮"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectCommit()
+
+	before := time.Now().Add(-time.Second)
+	req := httptest.NewRequest("POST", "/expenses/5/duplicate", nil)
+	w := httptest.NewRecorder()
+	duplicateRouter(1).ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, float64(6), resp.Data["id"])
+	assert.Equal(t, 12.5, resp.Data["amount"])
+	assert.Equal(t, "早餐", resp.Data["description"])
+	assert.Nil(t, resp.Data["installment_group_id"], "分期信息不复制")
+	expenseTime, err := time.Parse(time.RFC3339, resp.Data["expense_time"].(string))
+	require.NoError(t, err)
+	assert.True(t, expenseTime.After(before), "消费时间默认为当前时间")
+}
+
+func TestExpenseHandler_Duplicate_NotOwned(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 记录属于其他用户时按用户过滤查不到
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	req := httptest.NewRequest("POST", "/expenses/5/duplicate", nil)
+	w := httptest.NewRecorder()
+	duplicateRouter(2).ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Duplicate_InvalidTime(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category"}).AddRow(5, 1, 12.5, "餐饮"))
+
+	req := httptest.NewRequest("POST", "/expenses/5/duplicate", bytes.NewBufferString(`{"expense_time":"2024-01-16"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	duplicateRouter(1).ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"errors"
+
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// includeExtra 列表/导出是否带出扩展字段（include_extra=true）
+func includeExtra(c *gin.Context) bool {
+	return c.Query("include_extra") == "true"
+}
+
+// stripExtra 清空扩展字段，使其在 JSON 中省略
+func stripExtra(expenses []models.Expense) {
+	for i := range expenses {
+		expenses[i].Extra = nil
+	}
+}
+
+// extraFieldExpr 返回取扩展字段某键文本值的 SQL 表达式，按数据库方言处理 JSON 路径语法。
+// JSON 路径中的键名加双引号（$."key"），纯数字或含连字符的键名也能正确取值。
+// key 必须已通过 models.IsValidExtraKey 校验（仅字母/数字/下划线/连字符），才能安全拼入 SQL
+func extraFieldExpr(dialect, column, key string) string {
+	switch dialect {
+	case "postgres":
+		return column + "->>'" + key + "'"
+	case "sqlite":
+		return "json_extract(" + column + ", '$.\"" + key + "\"')"
+	default:
+		return "JSON_UNQUOTE(JSON_EXTRACT(" + column + ", '$.\"" + key + "\"'))"
+	}
+}
+
+// applyExtraFilter 按扩展字段 extra_key=extra_value 筛选；未传 extra_key 时原样返回
+func applyExtraFilter(query *gorm.DB, column, key, value string) (*gorm.DB, error) {
+	if key == "" {
+		return query, nil
+	}
+	if !models.IsValidExtraKey(key) {
+		return nil, errors.New("extra_key 只能包含字母、数字、下划线、连字符，长度 1-32")
+	}
+	return query.Where(extraFieldExpr(query.Dialector.Name(), column, key)+" = ?", value), nil
+}
@@ -0,0 +1,60 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtraFieldExpr(t *testing.T) {
+	assert.Equal(t, "JSON_UNQUOTE(JSON_EXTRACT(extra, '$.\"project\"'))", extraFieldExpr("mysql", "extra", "project"))
+	assert.Equal(t, "extra->>'project'", extraFieldExpr("postgres", "extra", "project"))
+	assert.Equal(t, "json_extract(extra, '$.\"project\"')", extraFieldExpr("sqlite", "extra", "project"))
+	// 纯数字、含连字符的键名需加引号才是合法的 JSON 路径
+	assert.Equal(t, "JSON_UNQUOTE(JSON_EXTRACT(extra, '$.\"123\"'))", extraFieldExpr("mysql", "extra", "123"))
+	assert.Equal(t, "json_extract(extra, '$.\"order-no\"')", extraFieldExpr("sqlite", "extra", "order-no"))
+}
+
+func TestExpenseHandler_List_ExtraFilter(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE JSON_UNQUOTE\\(JSON_EXTRACT\\(extra, '\\$\\.\"project\"'\\)\\) = \\? AND user_id = \\?").
+		WithArgs("装修", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE JSON_UNQUOTE\\(JSON_EXTRACT\\(extra, '\\$\\.\"project\"'\\)\\) = \\? AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "extra"}).
+			AddRow(1, 1, 100, "住房", `{"project":"装修"}`))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags` JOIN tags").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?extra_key=project&extra_value=%E8%A3%85%E4%BF%AE&include_extra=true", nil))
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"extra":{"project":"装修"}`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_List_InvalidExtraKey(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?extra_key=a')%20OR%201=1--", nil))
+
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,376 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 消费/收入记录导入限制
+const (
+	maxImportRows          = 1000    // 单次最多导入的数据行数（不含表头）
+	maxImportFileSize      = 2 << 20 // CSV 文件大小上限 2MB
+	maxCategoryMappingSize = 200     // 类别映射表最多条目数
+	maxCategoryNameLength  = 50      // 与类别名列 size:50 一致
+)
+
+// 未映射类别的处理结果
+const (
+	unmatchedActionExisting = "existing" // 系统中已有同名类别，直接使用
+	unmatchedActionSkipped  = "skipped"  // 不存在，按配置跳过该行
+	unmatchedActionCreate   = "create"   // 不存在，按配置自动创建
+)
+
+// importTimeLayouts CSV 中消费/收入时间支持的格式（与导出格式一致，也可只写日期）
+var importTimeLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"}
+
+// CategoryMappingUsage 映射表中某条映射的命中情况
+type CategoryMappingUsage struct {
+	Source string `json:"source"` // 源类别名（映射表中的写法）
+	Target string `json:"target"` // 本系统类别名
+	Count  int    `json:"count"`  // 命中的行数
+}
+
+// UnmatchedCategory 未在映射表中命中的源类别
+type UnmatchedCategory struct {
+	Source string `json:"source"`
+	Count  int    `json:"count"`
+	Action string `json:"action"` // existing：系统中已有同名类别；skipped：不存在，已跳过；create：不存在，按配置自动创建
+}
+
+// ExpenseImportResult 消费记录导入结果：批量结果之外附带类别映射汇总
+type ExpenseImportResult struct {
+	*BatchResult
+	MappingsUsed      []CategoryMappingUsage `json:"mappings_used"`      // 命中的映射
+	UnusedMappings    []string               `json:"unused_mappings"`    // 映射表中未被任何行用到的源类别名
+	Unmatched         []UnmatchedCategory    `json:"unmatched"`          // 未命中映射的源类别
+	CreatedCategories []string               `json:"created_categories"` // 本次自动创建的类别
+}
+
+// categoryMappingEntry 映射表条目，按源类别名忽略大小写匹配
+type categoryMappingEntry struct {
+	source string
+	target string
+}
+
+// parseImportCSV 读取导入用 CSV（去掉 UTF-8 BOM），首列为金额；首行以 amount/金额 开头时视为表头跳过
+func parseImportCSV(r io.Reader) ([][]string, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.New("CSV 解析失败: " + err.Error())
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\xEF\xBB\xBF")
+	}
+
+	// 表头行
+	offset := 0
+	if len(records) > 0 && len(records[0]) > 0 {
+		first := strings.ToLower(strings.TrimSpace(records[0][0]))
+		if first == "amount" || first == "金额" {
+			offset = 1
+		}
+	}
+	rows := records[offset:]
+	if len(rows) == 0 {
+		return nil, errors.New("CSV 中没有数据行")
+	}
+	if len(rows) > maxImportRows {
+		return nil, fmt.Errorf("单次最多导入 %d 条记录", maxImportRows)
+	}
+	return rows, nil
+}
+
+// parseCategoryMapping 解析类别映射表（JSON 对象：源类别名 → 本系统类别名），键忽略大小写；
+// field 为表单字段名，用于错误提示
+func parseCategoryMapping(field, raw string) (map[string]categoryMappingEntry, error) {
+	mapping := map[string]categoryMappingEntry{}
+	if strings.TrimSpace(raw) == "" {
+		return mapping, nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(raw), &m); err != nil {
+		return nil, errors.New(field + " 应为 JSON 对象，如 {\"Food\":\"餐饮\"}")
+	}
+	if len(m) > maxCategoryMappingSize {
+		return nil, fmt.Errorf("%s 最多 %d 条", field, maxCategoryMappingSize)
+	}
+	for source, target := range m {
+		source, target = strings.TrimSpace(source), strings.TrimSpace(target)
+		if source == "" || target == "" {
+			return nil, errors.New(field + " 的源类别和目标类别不能为空")
+		}
+		key := strings.ToLower(source)
+		if prev, ok := mapping[key]; ok && prev.target != target {
+			return nil, fmt.Errorf("%s 中「%s」与「%s」仅大小写不同但目标不一致", field, prev.source, source)
+		}
+		mapping[key] = categoryMappingEntry{source: source, target: target}
+	}
+	return mapping, nil
+}
+
+// loadExpenseCategoryNames 系统中已有的消费类别名
+func loadExpenseCategoryNames() (map[string]bool, error) {
+	var names []string
+	if err := database.DB.Model(&models.ExpenseCategory{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, n := range names {
+		existing[n] = true
+	}
+	return existing, nil
+}
+
+// validateMappingTargets 映射的目标必须是系统中已有的类别；field 为表单字段名
+func validateMappingTargets(field string, mapping map[string]categoryMappingEntry, existing map[string]bool) error {
+	var invalid []string
+	for _, e := range mapping {
+		if !existing[e.target] {
+			invalid = append(invalid, e.target)
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return errors.New(field + " 的目标类别不存在: " + strings.Join(invalid, "、"))
+	}
+	return nil
+}
+
+// parseImportTime 解析 CSV 中的时间（服务器本地时区），label 为字段名，如"消费时间"
+func parseImportTime(label, s string) (time.Time, error) {
+	for _, layout := range importTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, errors.New(label + "格式错误，应为: 2006-01-02 15:04:05 或 2006-01-02")
+}
+
+// unknownCategoryPolicy 未映射且不存在的类别的处理方式；未加载配置时跳过
+func unknownCategoryPolicy() string {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Import.UnknownCategory == config.ImportUnknownCategoryCreate {
+		return config.ImportUnknownCategoryCreate
+	}
+	return config.ImportUnknownCategorySkip
+}
+
+// importExpenseRows 逐行映射类别并校验，通过的行与需自动创建的类别在同一事务中写入；
+// 失败项的 index 为数据行位置（从 0 开始，不含表头），key 为源类别名；origin 为导入请求的提交来源
+func importExpenseRows(userID uint, origin models.RecordSource, rows [][]string, mapping map[string]categoryMappingEntry, existing map[string]bool, policy string) *ExpenseImportResult {
+	result := &ExpenseImportResult{
+		BatchResult:       NewBatchResult(len(rows)),
+		MappingsUsed:      []CategoryMappingUsage{},
+		UnusedMappings:    []string{},
+		Unmatched:         []UnmatchedCategory{},
+		CreatedCategories: []string{},
+	}
+
+	mappingHits := map[string]int{}
+	unmatched := map[string]*UnmatchedCategory{}
+	var unmatchedOrder []string
+	var pending []models.Expense
+	var pendingItems []BatchPending
+	var toCreate []string
+	willCreate := map[string]bool{}
+
+	for i, rec := range rows {
+		field := func(idx int) string {
+			if idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		amountStr, source, desc, timeStr := field(0), field(1), field(2), field(3)
+
+		// 先按映射表转换类别，再按转换后的名称校验
+		if source == "" {
+			result.FailIndex(i, source, "类别不能为空")
+			continue
+		}
+		category := source
+		createCategory := false
+		if e, ok := mapping[strings.ToLower(source)]; ok {
+			category = e.target
+			mappingHits[strings.ToLower(source)]++
+		} else {
+			u, seen := unmatched[source]
+			if !seen {
+				action := unmatchedActionExisting
+				if !existing[source] {
+					action = unmatchedActionSkipped
+					if policy == config.ImportUnknownCategoryCreate {
+						action = unmatchedActionCreate
+					}
+				}
+				u = &UnmatchedCategory{Source: source, Action: action}
+				unmatched[source] = u
+				unmatchedOrder = append(unmatchedOrder, source)
+			}
+			u.Count++
+			switch u.Action {
+			case unmatchedActionSkipped:
+				result.FailIndex(i, source, "类别「"+source+"」未映射且不存在，已跳过")
+				continue
+			case unmatchedActionCreate:
+				if utf8.RuneCountInString(source) > maxCategoryNameLength {
+					result.FailIndex(i, source, fmt.Sprintf("类别名不能超过 %d 个字符", maxCategoryNameLength))
+					continue
+				}
+				createCategory = true
+			}
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(amountStr, ",", ""), 64)
+		if err != nil {
+			result.FailIndex(i, source, "金额格式错误")
+			continue
+		}
+		amount = math.Round(amount*100) / 100
+		if err := validateAmount(amount); err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+		if err := validateDescription(desc); err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+		expenseTime, err := parseImportTime("消费时间", timeStr)
+		if err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+		if err := validateRecordTime("消费时间", expenseTime); err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+
+		if createCategory && !willCreate[category] {
+			willCreate[category] = true
+			toCreate = append(toCreate, category)
+		}
+		pending = append(pending, models.Expense{
+			UserID:       userID,
+			Amount:       amount,
+			Currency:     baseCurrency(),
+			Category:     category,
+			Description:  desc,
+			ExpenseTime:  expenseTime,
+			CreatedBy:    userID,
+			RecordSource: origin,
+		})
+		pendingItems = append(pendingItems, BatchPending{Index: i, Key: source})
+	}
+
+	// 映射汇总
+	for key, e := range mapping {
+		if n := mappingHits[key]; n > 0 {
+			result.MappingsUsed = append(result.MappingsUsed, CategoryMappingUsage{Source: e.source, Target: e.target, Count: n})
+		} else {
+			result.UnusedMappings = append(result.UnusedMappings, e.source)
+		}
+	}
+	sort.Slice(result.MappingsUsed, func(a, b int) bool { return result.MappingsUsed[a].Source < result.MappingsUsed[b].Source })
+	sort.Strings(result.UnusedMappings)
+	for _, source := range unmatchedOrder {
+		result.Unmatched = append(result.Unmatched, *unmatched[source])
+	}
+
+	if len(pending) == 0 {
+		return result
+	}
+
+	// 自动创建的类别与消费记录在同一事务中写入，任一失败则全部回滚
+	ok := result.CommitTx(pendingItems, "导入消费记录失败", func(tx *gorm.DB) error {
+		for _, name := range toCreate {
+			cat := models.ExpenseCategory{Name: name}
+			if err := tx.Where("name = ?", name).FirstOrCreate(&cat).Error; err != nil {
+				return err
+			}
+		}
+		return tx.CreateInBatches(&pending, 100).Error
+	})
+	if !ok {
+		return result
+	}
+	result.CreatedCategories = append(result.CreatedCategories, toCreate...)
+	return result
+}
+
+// Import 导入消费记录
+// @Summary 从 CSV 导入消费记录
+// @Description 上传 CSV（列：金额,类别,描述,消费时间；首行为 amount/金额 开头的表头时自动跳过），单次最多 1000 行。
+// @Description category_mapping 为 JSON 对象（源类别名 → 本系统类别名，源类别名忽略大小写），导入时先按映射转换再校验类别；映射的目标类别必须已存在。
+// @Description 未映射且系统中不存在的类别按服务端配置 import.unknown_category 处理：skip 跳过该行（默认），create 自动创建该类别。
+// @Description 校验通过的行在同一事务中写入，返回批量结果（failures 的 index 为数据行位置，从 0 开始，不含表头），并汇总命中的映射、未用到的映射和未命中映射的源类别
+// @Tags 消费记录
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV 文件"
+// @Param category_mapping formData string false "类别映射表（JSON），如 {\"Food\":\"餐饮\"}"
+// @Success 200 {object} Response{data=ExpenseImportResult} "导入完成"
+// @Failure 400 {object} Response "参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/expenses/import [post]
+func (h *ExpenseHandler) Import(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequest(c, "请上传 CSV 文件")
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		BadRequest(c, "文件不能超过 2MB")
+		return
+	}
+	mapping, err := parseCategoryMapping("category_mapping", c.PostForm("category_mapping"))
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	existing, err := loadExpenseCategoryNames()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询类别失败"))
+		return
+	}
+	if err := validateMappingTargets("category_mapping", mapping, existing); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		BadRequest(c, "读取文件失败")
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseImportCSV(file)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	SuccessWithMessage(c, "导入完成", importExpenseRows(userID, recordSourceFrom(c), rows, mapping, existing, unknownCategoryPolicy()))
+}
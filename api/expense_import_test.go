@@ -0,0 +1,116 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImportCSV_SkipHeaderAndBOM(t *testing.T) {
+	data := "\xEF\xBB\xBFamount,category,description,time\n12.50,Food,lunch,2024-03-01 12:00:00\n"
+	rows, err := parseImportCSV(strings.NewReader(data))
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "Food", rows[0][1])
+
+	_, err = parseImportCSV(strings.NewReader("金额,类别,描述,消费时间\n"))
+	assert.Error(t, err)
+}
+
+func TestParseCategoryMapping(t *testing.T) {
+	mapping, err := parseCategoryMapping("category_mapping", `{" Food ":"餐饮","Transport":"交通"}`)
+	require.NoError(t, err)
+	require.Len(t, mapping, 2)
+	assert.Equal(t, categoryMappingEntry{source: "Food", target: "餐饮"}, mapping["food"])
+
+	mapping, err = parseCategoryMapping("category_mapping", "")
+	require.NoError(t, err)
+	assert.Empty(t, mapping)
+
+	_, err = parseCategoryMapping("category_mapping", `["Food"]`)
+	assert.Error(t, err)
+	_, err = parseCategoryMapping("category_mapping", `{"Food":""}`)
+	assert.Error(t, err)
+	_, err = parseCategoryMapping("category_mapping", `{"Food":"餐饮","food":"购物"}`)
+	assert.Error(t, err)
+}
+
+func TestValidateMappingTargets(t *testing.T) {
+	mapping, err := parseCategoryMapping("category_mapping", `{"Food":"餐饮","Rent":"房租"}`)
+	require.NoError(t, err)
+
+	err = validateMappingTargets("category_mapping", mapping, map[string]bool{"餐饮": true})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "房租")
+	assert.NoError(t, validateMappingTargets("category_mapping", mapping, map[string]bool{"餐饮": true, "房租": true}))
+}
+
+func TestImportExpenseRows_MappingAndSkip(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	mapping, err := parseCategoryMapping("category_mapping", `{"Food":"餐饮","Rent":"房租"}`)
+	require.NoError(t, err)
+	existing := map[string]bool{"餐饮": true, "房租": true}
+	rows := [][]string{
+		{"12.50", "food", "lunch", "2024-03-01 12:00:00"},
+		{"30", "餐饮", "", "2024-03-02"},
+		{"8", "Snacks", "", "2024-03-02"},
+		{"abc", "Food", "", "2024-03-03"},
+	}
+	result := importExpenseRows(1, models.RecordSource{}, rows, mapping, existing, config.ImportUnknownCategorySkip)
+
+	assert.Equal(t, 4, result.Total)
+	assert.Equal(t, 2, result.SuccessCount)
+	require.Len(t, result.Failures, 2)
+	assert.Equal(t, 2, *result.Failures[0].Index)
+	assert.Contains(t, result.Failures[0].Reason, "未映射且不存在")
+	assert.Equal(t, 3, *result.Failures[1].Index)
+	assert.Equal(t, "金额格式错误", result.Failures[1].Reason)
+
+	assert.Equal(t, []CategoryMappingUsage{{Source: "Food", Target: "餐饮", Count: 2}}, result.MappingsUsed)
+	assert.Equal(t, []string{"Rent"}, result.UnusedMappings)
+	assert.Equal(t, []UnmatchedCategory{
+		{Source: "餐饮", Count: 1, Action: unmatchedActionExisting},
+		{Source: "Snacks", Count: 1, Action: unmatchedActionSkipped},
+	}, result.Unmatched)
+	assert.Empty(t, result.CreatedCategories)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportExpenseRows_CreateUnknownCategory(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE name = \\?").
+		WithArgs("Snacks").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+	mock.ExpectExec("INSERT INTO `expense_categories`").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	rows := [][]string{
+		{"8", "Snacks", "", "2024-03-02"},
+		{"1,200.00", "Snacks", "", "2024-03-03 09:30"},
+	}
+	result := importExpenseRows(1, models.RecordSource{}, rows, map[string]categoryMappingEntry{}, map[string]bool{}, config.ImportUnknownCategoryCreate)
+
+	assert.Equal(t, 2, result.SuccessCount)
+	assert.Equal(t, []UnmatchedCategory{{Source: "Snacks", Count: 2, Action: unmatchedActionCreate}}, result.Unmatched)
+	assert.Equal(t, []string{"Snacks"}, result.CreatedCategories)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
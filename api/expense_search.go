@@ -0,0 +1,52 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// maxDescriptionQueryLength 描述搜索关键词的最大字数
+const maxDescriptionQueryLength = 100
+
+// matchSnippetRadius 命中片段中关键词前后各保留的字数
+const matchSnippetRadius = 15
+
+// normalizeDescriptionQuery 去除首尾空白并校验长度，返回空串表示不搜索
+func normalizeDescriptionQuery(q string) (string, error) {
+	q = strings.TrimSpace(q)
+	if utf8.RuneCountInString(q) > maxDescriptionQueryLength {
+		return "", fmt.Errorf("搜索关键词不能超过 %d 字", maxDescriptionQueryLength)
+	}
+	return q, nil
+}
+
+// applyDescriptionSearch 按描述模糊搜索（忽略大小写），关键词中的 % 和 _ 按字面匹配
+func applyDescriptionSearch(query *gorm.DB, column, q string) *gorm.DB {
+	if q == "" {
+		return query
+	}
+	return query.Where("LOWER("+column+") LIKE ?"+likeEscape(query), "%"+escapeLikeValue(strings.ToLower(q))+"%")
+}
+
+// withMatchSnippet 描述搜索结果附带关键词前后的片段（match_snippet），便于客户端高亮
+type withMatchSnippet struct {
+	record  interface{}
+	snippet string
+}
+
+// MarshalJSON 按记录自身格式序列化，再合并 match_snippet
+func (w withMatchSnippet) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(w.record, map[string]interface{}{"match_snippet": w.snippet})
+}
+
+// withMatchSnippets 为每条记录附带描述中关键词的上下文；records 与 descriptions 一一对应
+func withMatchSnippets(records []interface{}, descriptions []string, q string) []withMatchSnippet {
+	out := make([]withMatchSnippet, len(records))
+	for i, r := range records {
+		out[i] = withMatchSnippet{record: r, snippet: searchSnippet(descriptions[i], q, matchSnippetRadius)}
+	}
+	return out
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/glebarez/sqlite"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestApplyDescriptionSearch_SQLite SQLite 的 LIKE 没有默认转义字符，确认 ESCAPE 子句让 % 按字面匹配
+func TestApplyDescriptionSearch_SQLite(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open("file:desc_search?mode=memory&cache=shared"), &gorm.Config{})
+	require.NoError(t, err)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	require.NoError(t, db.AutoMigrate(&models.Expense{}))
+	now := time.Now()
+	require.NoError(t, db.Create(&[]models.Expense{
+		{UserID: 1, Amount: 10, Category: "购物", Description: "满减 50% 优惠", ExpenseTime: now},
+		{UserID: 1, Amount: 10, Category: "购物", Description: "满减 500 优惠", ExpenseTime: now},
+	}).Error)
+
+	var found []models.Expense
+	require.NoError(t, applyDescriptionSearch(db.Model(&models.Expense{}), "description", "50%").Find(&found).Error)
+	require.Len(t, found, 1)
+	assert.Equal(t, "满减 50% 优惠", found[0].Description)
+}
+
+func TestNormalizeDescriptionQuery(t *testing.T) {
+	q, err := normalizeDescriptionQuery("  酒店 ")
+	require.NoError(t, err)
+	assert.Equal(t, "酒店", q)
+
+	_, err = normalizeDescriptionQuery(strings.Repeat("字", maxDescriptionQueryLength+1))
+	assert.Error(t, err)
+}
+
+func TestWithMatchSnippet_MarshalJSON(t *testing.T) {
+	e := models.Expense{ID: 1, Amount: 680, Description: "三月出差住的Hotel，含早餐"}
+	raw, err := json.Marshal(withMatchSnippets([]interface{}{e}, []string{e.Description}, "hotel"))
+	require.NoError(t, err)
+
+	var items []map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &items))
+	require.Len(t, items, 1)
+	assert.Equal(t, 680.0, items[0]["amount"])
+	assert.Contains(t, items[0]["match_snippet"], "Hotel")
+}
+
+func TestExpenseHandler_List_DescriptionSearch(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 关键词转小写，通配符按字面匹配
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE LOWER\\(description\\) LIKE \\? ESCAPE '\\\\\\\\' AND user_id = \\?").
+		WithArgs("%hotel\\_a%", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE LOWER\\(description\\) LIKE \\? ESCAPE '\\\\\\\\' AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "description", "expense_time"}).
+			AddRow(7, 1, 680, "住宿", "春季出差 Hotel_A 两晚", time.Now()))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags`").
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?q="+url.QueryEscape("Hotel_A"), nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data struct {
+			List []map[string]interface{} `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.List, 1)
+	assert.Equal(t, "春季出差 Hotel_A 两晚", resp.Data.List[0]["match_snippet"])
+}
+
+func TestAdminHandler_GetAllExpenses_DescriptionSearch(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` LEFT JOIN users .* LOWER\\(expenses.description\\) LIKE \\? ESCAPE '\\\\\\\\'").
+		WithArgs("%50\\%%").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT expenses\\.\\*, users\\.username, creators\\.username AS created_by_name FROM `expenses` .* LOWER\\(expenses.description\\) LIKE \\? ESCAPE '\\\\\\\\'").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "description", "expense_time", "username", "admin_note"}).
+			AddRow(3, 2, 50, "满减 50% 优惠", time.Now(), "alice", "已核对"))
+
+	router := gin.New()
+	router.GET("/admin/expenses", NewAdminHandler().GetAllExpenses)
+
+	req := httptest.NewRequest("GET", "/admin/expenses?q="+url.QueryEscape("50%"), nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data struct {
+			List []map[string]interface{} `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.List, 1)
+	// 管理员字段与命中片段同时保留
+	assert.Equal(t, "已核对", resp.Data.List[0]["admin_note"])
+	assert.Equal(t, "满减 50% 优惠", resp.Data.List[0]["match_snippet"])
+}
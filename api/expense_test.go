@@ -4,9 +4,12 @@ import (
 	"bytes"
 	"encoding/json"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
+	"finance/models"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -74,3 +77,141 @@ func TestExpenseHandler_Create_InvalidCategory(t *testing.T) {
 	assert.Equal(t, 400, w.Code)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestExpenseHandler_Create_CategoryIDTakesPrecedence(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 同时传 category_id 与 category 时按 ID 取类别当前名称，不再按名称查询
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(3, "外卖"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":30,"category_id":3,"category":"餐饮","expense_time":"2024-01-15 12:30:00"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"category":"外卖"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_CategoryIDNotFound(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE `expense_categories`.`id` = \\?").
+		WithArgs(99).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":30,"category_id":99,"expense_time":"2024-01-15 12:30:00"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "消费类别不存在")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_GetStatistics_ExcludeTransfer(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 默认排除内部转账类别（子查询 expense_categories.is_transfer）
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses` WHERE .*category NOT IN \\(SELECT `name` FROM `expense_categories` WHERE is_transfer = \\?.* GROUP BY category, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).AddRow("餐饮", "CNY", 100, 2))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics", NewExpenseHandler().GetStatistics)
+
+	req := httptest.NewRequest("GET", "/expenses/statistics", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_GetStatistics_IncludeTransfer(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 未传时间参数时默认统计本月
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses` WHERE user_id = \\? AND expense_time >= \\? AND expense_time <= \\? AND `expenses`.`deleted_at` IS NULL GROUP BY category, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).AddRow("还信用卡", "CNY", 300, 1))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics", NewExpenseHandler().GetStatistics)
+
+	req := httptest.NewRequest("GET", "/expenses/statistics?include_transfer=true", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			StartTime string `json:"start_time"`
+			EndTime   string `json:"end_time"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	now := time.Now()
+	assert.Equal(t, now.Format("2006-01")+"-01", resp.Data.StartTime)
+	assert.Equal(t, now.Format("2006-01-02"), resp.Data.EndTime)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseWithUser_MarshalJSON(t *testing.T) {
+	ts := time.Date(2024, 1, 15, 12, 30, 0, 0, time.Local)
+	data, err := json.Marshal(ExpenseWithUser{
+		Expense:  models.Expense{ID: 1, Amount: 10, ExpenseTime: ts},
+		Username: "alice",
+	})
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "alice", m["username"])
+	assert.Equal(t, models.FormatTime(ts), m["expense_time"])
+}
+
+func TestExpenseHandler_Create_DescriptionTooLong(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":10,"category":"餐饮","description":"` + strings.Repeat("午", 256) + `","expense_time":"2024-01-15 12:30:00"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "描述不能超过")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
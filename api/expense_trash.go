@@ -0,0 +1,241 @@
+package api
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TrashedExpense 回收站中的消费记录，附带删除时间、删除者与预计清理时间
+type TrashedExpense struct {
+	ExpenseWithUser
+	DeletedByName string    // 删除者用户名，删除者未记录或已不存在时为空
+	PurgeAt       time.Time // 超过保留期、将被定时任务物理删除的时间
+}
+
+// MarshalJSON 在 ExpenseWithUser 的基础上附带 deleted_at、deleted_by、deleted_by_name、purge_at
+func (e TrashedExpense) MarshalJSON() ([]byte, error) {
+	var deletedAt, purgeAt interface{}
+	if e.DeletedAt.Valid {
+		deletedAt = models.FormatTime(e.DeletedAt.Time)
+	}
+	if !e.PurgeAt.IsZero() {
+		purgeAt = models.FormatTime(e.PurgeAt)
+	}
+	return marshalWithExtra(e.ExpenseWithUser, map[string]interface{}{
+		"deleted_at":      deletedAt,
+		"deleted_by":      e.DeletedBy,
+		"deleted_by_name": e.DeletedByName,
+		"purge_at":        purgeAt,
+	})
+}
+
+// deletedRetentionDays 软删除记录的保留天数，超过后由定时任务物理删除
+func deletedRetentionDays() int {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Retention.DeletedDays > 0 {
+		return cfg.Retention.DeletedDays
+	}
+	return config.DefaultDeletedRetentionDays
+}
+
+// loadTrashedExpense 按 ID 查询回收站（已软删除）中的消费记录，失败时已写入响应
+func loadTrashedExpense(c *gin.Context) (*models.Expense, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	var expense models.Expense
+	if err := database.DB.Unscoped().Where("deleted_at IS NOT NULL").First(&expense, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "回收站中不存在该记录"})
+		return nil, false
+	}
+	return &expense, true
+}
+
+// GetTrashedExpenses 获取回收站中的消费记录
+// @Summary 获取已删除的消费记录
+// @Description 列出已软删除、尚未被清理的消费记录，按删除时间倒序。分页与筛选参数同消费记录列表：管理员可查看所有记录并按用户ID筛选，非管理员只能查看自己的记录。
+// @Description 每条记录附带 deleted_at、deleted_by、deleted_by_name 与预计清理时间 purge_at；超过 retention.deleted_days 天的记录会被定时任务物理删除，即使尚未清理也不再列出
+// @Tags 后台管理-消费记录
+// @Produce json
+// @Param page query int false "页码，默认1"
+// @Param page_size query int false "每页数量，默认20"
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，按消费时间筛选"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param category query string false "类别筛选"
+// @Param username query string false "用户名筛选（模糊匹配）"
+// @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Success 200 {object} map[string]interface{} "获取成功，返回分页数据"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/expenses/trash [get]
+func (h *AdminHandler) GetTrashedExpenses(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	page := 1
+	pageSize := 20
+	if p := c.Query("page"); p != "" {
+		fmt.Sscanf(p, "%d", &page)
+	}
+	if ps := c.Query("page_size"); ps != "" {
+		fmt.Sscanf(ps, "%d", &pageSize)
+	}
+
+	query, err := adminExpenseListQuery(c, database.DB.Unscoped(), currentUser)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	// 已过保留期、只是定时任务尚未执行到的记录随时会被清理，不再列出
+	retention := time.Duration(deletedRetentionDays()) * 24 * time.Hour
+	query = query.Where("expenses.deleted_at IS NOT NULL AND expenses.deleted_at >= ?", time.Now().Add(-retention))
+
+	var total int64
+	query.Count(&total)
+
+	var expenses []ExpenseWithUser
+	offset := (page - 1) * pageSize
+	query.Order("expenses.deleted_at DESC").Offset(offset).Limit(pageSize).Scan(&expenses)
+
+	deleterNames := loadDeleterNames(expenses)
+	trashed := make([]TrashedExpense, len(expenses))
+	for i, e := range expenses {
+		trashed[i] = TrashedExpense{ExpenseWithUser: e}
+		if e.DeletedAt.Valid {
+			trashed[i].PurgeAt = e.DeletedAt.Time.Add(retention)
+		}
+		if e.DeletedBy != nil {
+			trashed[i].DeletedByName = deleterNames[*e.DeletedBy]
+		}
+	}
+
+	// 管理员备注只对管理员返回
+	var list interface{} = trashed
+	if currentUser.IsAdmin {
+		withFields := make([]withAdminFields, len(trashed))
+		for i, e := range trashed {
+			withFields[i] = withAdminFields{record: e, adminNote: e.AdminNote, source: e.RecordSource}
+		}
+		list = withFields
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    NewPageResponse(total, page, pageSize, list),
+	})
+}
+
+// RestoreExpense 恢复已删除的消费记录
+// @Summary 恢复已删除的消费记录
+// @Description 将回收站中的消费记录恢复为正常记录（清除删除时间与删除者）。管理员可以恢复任何记录，非管理员只能恢复自己的记录；记录所属用户已不存在时不允许恢复。
+// @Tags 后台管理-消费记录
+// @Produce json
+// @Param id path int true "消费记录ID"
+// @Success 200 {object} map[string]interface{} "恢复成功"
+// @Failure 400 {object} map[string]interface{} "无效的ID或所属用户已不存在"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "回收站中不存在该记录"
+// @Router /admin/expenses/{id}/restore [post]
+func (h *AdminHandler) RestoreExpense(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	expense, ok := loadTrashedExpense(c)
+	if !ok {
+		return
+	}
+
+	// 权限检查：非管理员只能恢复自己的记录
+	if !currentUser.IsAdmin && expense.UserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，只能恢复自己的记录"})
+		return
+	}
+
+	// 所属用户已删除时恢复出来的记录无人可见，直接拒绝
+	var owner models.User
+	if err := database.DB.Select("id").First(&owner, expense.UserID).Error; err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "记录所属用户已不存在，无法恢复"})
+		return
+	}
+
+	if err := database.DB.Unscoped().Model(expense).UpdateColumns(map[string]interface{}{
+		"deleted_at": nil,
+		"deleted_by": nil,
+	}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "恢复失败")})
+		return
+	}
+	expense.DeletedAt = gorm.DeletedAt{}
+	expense.DeletedBy = nil
+
+	var data interface{} = expense
+	if currentUser.IsAdmin {
+		data = withAdminFields{record: expense, adminNote: expense.AdminNote, source: expense.RecordSource}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "恢复成功",
+		"data":    data,
+	})
+}
+
+// PurgeExpense 彻底删除消费记录（仅管理员）
+// @Summary 彻底删除消费记录
+// @Description 从数据库中物理删除回收站中的消费记录，连同其小票图片与标签关联，不可恢复；未删除的记录需先删除再彻底删除。
+// @Tags 后台管理-消费记录
+// @Produce json
+// @Param id path int true "消费记录ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "无效的ID"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "回收站中不存在该记录"
+// @Router /admin/expenses/{id}/purge [delete]
+func (h *AdminHandler) PurgeExpense(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	expense, ok := loadTrashedExpense(c)
+	if !ok {
+		return
+	}
+
+	// 小票与标签关联随记录一并删除，避免留下孤儿行和文件
+	if _, err := service.PurgeExpenses([]uint{expense.ID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
+		return
+	}
+	log.Printf("[审计] 管理员 %s(ID %d) 彻底删除消费记录 ID %d（用户ID %d）", currentUser.Username, currentUser.ID, expense.ID, expense.UserID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "已彻底删除",
+	})
+}
@@ -0,0 +1,160 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestTrashedExpense_MarshalJSON(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 1, 8, 0, 0, 0, time.Local)
+	by := uint(9)
+	e := TrashedExpense{
+		ExpenseWithUser: ExpenseWithUser{
+			Expense:  models.Expense{ID: 1, UserID: 2, CreatedBy: 2, DeletedAt: gorm.DeletedAt{Time: deletedAt, Valid: true}, DeletedBy: &by},
+			Username: "alice",
+		},
+		DeletedByName: "admin",
+	}
+
+	raw, err := json.Marshal(e)
+	require.NoError(t, err)
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &m))
+	assert.Equal(t, "alice", m["username"])
+	assert.Equal(t, models.FormatTime(deletedAt), m["deleted_at"])
+	assert.Equal(t, 9.0, m["deleted_by"])
+	assert.Equal(t, "admin", m["deleted_by_name"])
+	assert.Equal(t, false, m["proxy_entry"])
+}
+
+func TestLoadTrashedExpense(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE deleted_at IS NOT NULL AND `expenses`.`id` = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "deleted_at"}).AddRow(5, 2, 12.5, time.Now()))
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	expense, ok := loadTrashedExpense(c)
+	require.True(t, ok)
+	assert.Equal(t, uint(2), expense.UserID)
+	assert.True(t, expense.DeletedAt.Valid)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadTrashedExpense_NotInTrash(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE deleted_at IS NOT NULL").
+		WithArgs(5).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "5"}}
+	_, ok := loadTrashedExpense(c)
+	assert.False(t, ok)
+	assert.Equal(t, 404, w.Code)
+
+	w = httptest.NewRecorder()
+	c, _ = gin.CreateTestContext(w)
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+	_, ok = loadTrashedExpense(c)
+	assert.False(t, ok)
+	assert.Equal(t, 400, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_PurgeExpense_RemovesAssociations(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE deleted_at IS NOT NULL AND `expenses`.`id` = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "deleted_at"}).AddRow(5, 2, 12.5, time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `path` FROM `receipts` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"path"}))
+	mock.ExpectExec("DELETE FROM `receipts` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM `expenses` WHERE id IN \\(\\?\\)").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.DELETE("/admin/expenses/:id/purge", NewAdminHandler().PurgeExpense)
+
+	req := httptest.NewRequest("DELETE", "/admin/expenses/5/purge", nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_GetTrashedExpenses_Retention(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+	config.GlobalConfig.Retention.DeletedDays = 7
+
+	deletedAt := time.Now().Add(-48 * time.Hour).Truncate(time.Second)
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` .*expenses.deleted_at IS NOT NULL AND expenses.deleted_at >= \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT expenses\\.\\*.* FROM `expenses` .*expenses.deleted_at IS NOT NULL AND expenses.deleted_at >= \\?.*ORDER BY expenses.deleted_at DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "expense_time", "deleted_at", "username"}).
+			AddRow(3, 2, 50, time.Now(), deletedAt, "alice"))
+
+	router := gin.New()
+	router.GET("/admin/expenses/trash", NewAdminHandler().GetTrashedExpenses)
+
+	req := httptest.NewRequest("GET", "/admin/expenses/trash", nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data struct {
+			List []map[string]interface{} `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.List, 1)
+	assert.Equal(t, models.FormatTime(deletedAt.Add(7*24*time.Hour)), resp.Data.List[0]["purge_at"])
+}
@@ -3,6 +3,8 @@ package api
 import (
 	"bytes"
 	"encoding/csv"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
@@ -12,8 +14,122 @@ import (
 	"finance/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// exportScope 导出范围：时间区间 + 用户过滤
+type exportScope struct {
+	StartStr string
+	EndStr   string
+	Start    time.Time
+	End      time.Time
+	// UserID 为 0 表示不按用户过滤（管理员导出全部数据）
+	UserID uint
+	// IncludeTransfer 是否包含内部转账类别（仅对消费导出生效）
+	IncludeTransfer bool
+}
+
+// resolveExportScope 解析并校验导出的开始/结束日期，并按是否管理员决定用户过滤。
+// 消费、收入的所有导出入口统一调用，返回的 error 信息可直接展示给用户。
+func resolveExportScope(startStr, endStr string, currentUserID uint, isAdmin bool) (*exportScope, error) {
+	if startStr == "" || endStr == "" {
+		return nil, errors.New("请提供开始时间和结束时间")
+	}
+
+	start, err := time.ParseInLocation("2006-01-02", startStr, time.Local)
+	if err != nil {
+		return nil, errors.New("开始时间格式错误，应为: 2006-01-02")
+	}
+
+	end, err := time.ParseInLocation("2006-01-02", endStr, time.Local)
+	if err != nil {
+		return nil, errors.New("结束时间格式错误，应为: 2006-01-02")
+	}
+
+	if start.After(end) {
+		return nil, errors.New("开始时间不能晚于结束时间")
+	}
+
+	scope := &exportScope{
+		StartStr: startStr,
+		EndStr:   endStr,
+		Start:    start,
+		End:      end.Add(24*time.Hour - time.Second),
+	}
+	if !isAdmin {
+		scope.UserID = currentUserID
+	}
+	return scope, nil
+}
+
+// exportScopeFromQuery 从 start_time/end_time 查询参数解析导出范围；两者都不传时默认导出本月
+// （1 日至今天，按 tz 参数所指时区），只传其一时仍按 resolveExportScope 报错
+func exportScopeFromQuery(c *gin.Context, currentUserID uint, isAdmin bool) (*exportScope, error) {
+	now, err := requestNow(c)
+	if err != nil {
+		return nil, err
+	}
+	startStr, endStr := defaultToThisMonth(c.Query("start_time"), c.Query("end_time"), now)
+	return resolveExportScope(startStr, endStr, currentUserID, isAdmin)
+}
+
+// apply 将时间范围与用户过滤应用到查询上
+func (s *exportScope) apply(query *gorm.DB, userColumn, timeColumn string) *gorm.DB {
+	query = query.Where(timeColumn+" >= ? AND "+timeColumn+" <= ?", s.Start, s.End)
+	if s.UserID != 0 {
+		query = query.Where(userColumn+" = ?", s.UserID)
+	}
+	return query
+}
+
+// applyExpense 在 apply 基础上默认排除内部转账类别（除非显式 include_transfer=true）
+func (s *exportScope) applyExpense(query *gorm.DB, userColumn, timeColumn, categoryColumn string) *gorm.DB {
+	query = s.apply(query, userColumn, timeColumn)
+	if !s.IncludeTransfer {
+		query = excludeTransferCategories(query, categoryColumn)
+	}
+	return query
+}
+
+// loadDeleterNames 查询导出记录中删除者的用户名（含已删除的用户），返回 用户ID → 用户名
+func loadDeleterNames(expenses []ExpenseWithUser) map[uint]string {
+	names := map[uint]string{}
+	var ids []uint
+	for _, e := range expenses {
+		if e.DeletedBy != nil {
+			if _, ok := names[*e.DeletedBy]; !ok {
+				names[*e.DeletedBy] = ""
+				ids = append(ids, *e.DeletedBy)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return names
+	}
+	var users []models.User
+	database.DB.Unscoped().Select("id", "username").Where("id IN ?", ids).Find(&users)
+	for _, u := range users {
+		names[u.ID] = u.Username
+	}
+	return names
+}
+
+// deletedExpenseColumns 审计导出行尾的删除时间、删除者；未删除的记录两列为空，
+// 删除者未记录（早于记录删除者的数据）或用户已不存在时分别显示“未知”和用户ID
+func deletedExpenseColumns(e models.Expense, deleterNames map[uint]string) (string, string) {
+	if !e.DeletedAt.Valid {
+		return "", ""
+	}
+	deletedAt := e.DeletedAt.Time.Format("2006-01-02 15:04:05")
+	if e.DeletedBy == nil {
+		return deletedAt, "未知"
+	}
+	if name := deleterNames[*e.DeletedBy]; name != "" {
+		return deletedAt, name
+	}
+	return deletedAt, fmt.Sprintf("用户#%d", *e.DeletedBy)
+}
+
 // ExportHandler 导出处理器
 type ExportHandler struct{}
 
@@ -24,13 +140,21 @@ func NewExportHandler() *ExportHandler {
 
 // ExportCSV 导出消费记录为 CSV
 // @Summary 导出消费记录
-// @Description 根据时间范围导出消费记录为 CSV 文件
+// @Description 根据时间范围导出消费记录为 CSV 文件。不传时间范围时导出本月，实际范围由响应头 X-Range-Start/X-Range-End 回显
 // @Tags 导出
 // @Accept json
 // @Produce text/csv
 // @Security BearerAuth
-// @Param start_time query string true "开始时间 (2024-01-01)"
-// @Param end_time query string true "结束时间 (2024-12-31)"
+// @Param start_time query string false "开始时间 (2024-01-01)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (2024-12-31)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，用于计算默认的本月范围，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param formatted query bool false "金额输出为本地化货币字符串（如 ¥1,234.56），默认裸数字"
+// @Param currency query string false "默认货币代码（formatted=true 时生效，每条记录按自身币种显示，币种不在列表中时用该值）：CNY/USD/EUR/GBP/HKD/JPY，默认 CNY"
+// @Param locale query string false "区域（formatted=true 时生效）：zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR，默认 zh-CN"
+// @Param include_extra query bool false "是否追加“扩展字段”列（JSON 文本），默认不追加"
+// @Param delimiter query string false "分隔符：comma/semicolon/tab，默认 comma"
+// @Param encoding query string false "编码：utf8-bom/utf8/gbk，默认 utf8-bom"
 // @Success 200 {file} file "CSV 文件"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "未授权"
@@ -38,30 +162,28 @@ func NewExportHandler() *ExportHandler {
 func (h *ExportHandler) ExportCSV(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
-
-	if startTimeStr == "" || endTimeStr == "" {
-		BadRequest(c, "请提供开始时间和结束时间")
+	scope, err := exportScopeFromQuery(c, userID, false)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
+	scope.IncludeTransfer = includeTransfer(c)
 
-	startTime, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
+	money, err := resolveMoneyFormat(c)
 	if err != nil {
-		BadRequest(c, "开始时间格式错误，应为: 2006-01-02")
+		BadRequest(c, err.Error())
 		return
 	}
 
-	endTime, err := time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
+	opts, err := resolveCSVOptions(c)
 	if err != nil {
-		BadRequest(c, "结束时间格式错误，应为: 2006-01-02")
+		BadRequest(c, err.Error())
 		return
 	}
-	endTime = endTime.Add(24*time.Hour - time.Second)
 
 	// 查询数据
 	var expenses []models.Expense
-	if err := database.DB.Where("user_id = ? AND expense_time >= ? AND expense_time <= ?", userID, startTime, endTime).
+	if err := scope.applyExpense(database.DB, "user_id", "expense_time", "category").
 		Order("expense_time DESC").
 		Find(&expenses).Error; err != nil {
 		InternalError(c, SafeErrorMessage(err, "查询数据失败"))
@@ -70,13 +192,15 @@ func (h *ExportHandler) ExportCSV(c *gin.Context) {
 
 	// 生成 CSV
 	buf := new(bytes.Buffer)
-	// 添加 BOM 以支持 Excel 中文显示
-	buf.WriteString("\xEF\xBB\xBF")
-	
 	writer := csv.NewWriter(buf)
+	writer.Comma = opts.Delimiter
 
 	// 写入表头
 	headers := []string{"ID", "金额", "类别", "描述", "消费时间", "创建时间"}
+	withExtra := includeExtra(c)
+	if withExtra {
+		headers = append(headers, "扩展字段")
+	}
 	if err := writer.Write(headers); err != nil {
 		InternalError(c, "生成 CSV 失败")
 		return
@@ -84,14 +208,26 @@ func (h *ExportHandler) ExportCSV(c *gin.Context) {
 
 	// 写入数据
 	for _, expense := range expenses {
+		amount := fmt.Sprintf("%.2f", expense.Amount)
+		if money != nil {
+			amount = money.withCurrency(expense.Currency).Format(expense.Amount)
+		}
 		row := []string{
 			fmt.Sprintf("%d", expense.ID),
-			fmt.Sprintf("%.2f", expense.Amount),
+			amount,
 			expense.Category,
 			expense.Description,
 			expense.ExpenseTime.Format("2006-01-02 15:04:05"),
 			expense.CreatedAt.Format("2006-01-02 15:04:05"),
 		}
+		if withExtra {
+			extra := ""
+			if len(expense.Extra) > 0 {
+				b, _ := json.Marshal(expense.Extra)
+				extra = string(b)
+			}
+			row = append(row, extra)
+		}
 		if err := writer.Write(row); err != nil {
 			InternalError(c, "生成 CSV 失败")
 			return
@@ -104,24 +240,36 @@ func (h *ExportHandler) ExportCSV(c *gin.Context) {
 		return
 	}
 
+	// 按所选编码输出（utf8-bom 添加 BOM 以支持 Excel 中文显示）
+	data, err := opts.Encode(buf.Bytes())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
 	// 设置响应头
-	filename := fmt.Sprintf("expenses_%s_%s.csv", startTimeStr, endTimeStr)
-	c.Header("Content-Type", "text/csv; charset=utf-8")
+	filename := fmt.Sprintf("expenses_%s_%s.csv", scope.StartStr, scope.EndStr)
+	c.Header("Content-Type", opts.ContentType())
 	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
-	c.Header("Content-Length", fmt.Sprintf("%d", buf.Len()))
+	setRangeHeaders(c, scope.StartStr, scope.EndStr)
+	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
 
-	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+	c.Data(http.StatusOK, opts.ContentType(), data)
 }
 
 // ExportJSON 导出消费记录为 JSON
 // @Summary 导出消费记录为 JSON
-// @Description 根据时间范围导出消费记录为 JSON 格式
+// @Description 根据时间范围导出消费记录为 JSON 格式。不传时间范围时导出本月，响应中的 start_time/end_time 为实际使用的范围。
+// @Description total_amount 为折算为本位币（base_currency）的合计，缺少汇率的币种不计入并列在 unconverted_currencies
 // @Tags 导出
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param start_time query string true "开始时间 (2024-01-01)"
-// @Param end_time query string true "结束时间 (2024-12-31)"
+// @Param start_time query string false "开始时间 (2024-01-01)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (2024-12-31)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，用于计算默认的本月范围，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Param include_extra query bool false "是否带出扩展字段，默认不带出"
 // @Success 200 {object} Response{data=[]models.Expense} "导出成功"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "未授权"
@@ -129,48 +277,46 @@ func (h *ExportHandler) ExportCSV(c *gin.Context) {
 func (h *ExportHandler) ExportJSON(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
-
-	if startTimeStr == "" || endTimeStr == "" {
-		BadRequest(c, "请提供开始时间和结束时间")
-		return
-	}
-
-	startTime, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
+	scope, err := exportScopeFromQuery(c, userID, false)
 	if err != nil {
-		BadRequest(c, "开始时间格式错误，应为: 2006-01-02")
+		BadRequest(c, err.Error())
 		return
 	}
-
-	endTime, err := time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
-	if err != nil {
-		BadRequest(c, "结束时间格式错误，应为: 2006-01-02")
-		return
-	}
-	endTime = endTime.Add(24*time.Hour - time.Second)
+	scope.IncludeTransfer = includeTransfer(c)
 
 	// 查询数据
 	var expenses []models.Expense
-	if err := database.DB.Where("user_id = ? AND expense_time >= ? AND expense_time <= ?", userID, startTime, endTime).
+	if err := scope.applyExpense(database.DB, "user_id", "expense_time", "category").
 		Order("expense_time DESC").
 		Find(&expenses).Error; err != nil {
 		InternalError(c, SafeErrorMessage(err, "查询数据失败"))
 		return
 	}
 
-	// 计算汇总信息
-	var totalAmount float64
+	if !includeExtra(c) {
+		stripExtra(expenses)
+	}
+
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询汇率失败"))
+		return
+	}
+
+	// 计算汇总信息：折算为本位币，缺少汇率的币种不计入
+	var total sheetTotal
 	for _, expense := range expenses {
-		totalAmount += expense.Amount
+		total.add(rates, expense.Amount, expense.Currency)
 	}
 
 	Success(c, gin.H{
-		"start_time":   startTimeStr,
-		"end_time":     endTimeStr,
-		"total_count":  len(expenses),
-		"total_amount": totalAmount,
-		"expenses":     expenses,
+		"start_time":             scope.StartStr,
+		"end_time":               scope.EndStr,
+		"total_count":            len(expenses),
+		"base_currency":          baseCurrency(),
+		"total_amount":           roundMoney(total.amount),
+		"unconverted_currencies": mergeCurrencyCodes(total.unconverted),
+		"expenses":               expenses,
 	})
 }
 
@@ -0,0 +1,196 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pdfFontPath PDF 报表嵌入的字体路径（未加载配置时为空）
+func pdfFontPath() string {
+	if config.GlobalConfig == nil {
+		return ""
+	}
+	return config.GlobalConfig.Export.PDFFontPath
+}
+
+// buildExpenseReport 按类别与币种汇总明细并折算为本位币，得到类别小计与总计；
+// 缺少汇率的币种不计入小计与总计，在表尾备注中列出
+func buildExpenseReport(rows []service.ExpenseReportRow, rangeLabel string, showUsername bool) (service.ExpenseReport, error) {
+	report := service.ExpenseReport{
+		Title:        "消费报表",
+		RangeLabel:   rangeLabel,
+		BaseCurrency: baseCurrency(),
+		ShowUsername: showUsername,
+		Rows:         rows,
+	}
+
+	index := map[[2]string]int{}
+	var stats []currencyStatRow
+	for _, r := range rows {
+		key := [2]string{r.Category, r.Currency}
+		i, ok := index[key]
+		if !ok {
+			i = len(stats)
+			index[key] = i
+			stats = append(stats, currencyStatRow{Category: r.Category, Currency: r.Currency})
+		}
+		stats[i].Total += r.Amount
+		stats[i].Count++
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return report, err
+	}
+	summary := convertCurrencyStats(stats, rates)
+
+	report.Total = summary.Total
+	report.Subtotals = make([]service.ExpenseReportSubtotal, 0, len(summary.Categories))
+	for _, cs := range summary.Categories {
+		report.Subtotals = append(report.Subtotals, service.ExpenseReportSubtotal{Category: cs.Category, Count: cs.Count, Total: cs.Total})
+	}
+	if len(summary.Unconverted) > 0 {
+		report.Note = "以下币种缺少汇率，未计入小计与总计：" + strings.Join(summary.Unconverted, "、")
+	}
+	return report, nil
+}
+
+// writePDFResponse 以附件形式输出 PDF，文件名与 Excel 导出一样使用 UTF-8 编码
+func writePDFResponse(c *gin.Context, data []byte, filename string) {
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename*=UTF-8''%s", filename))
+	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
+	c.Data(http.StatusOK, "application/pdf", data)
+}
+
+// ExportPDF 导出消费报表为 PDF
+// @Summary 导出消费报表（PDF）
+// @Description 根据时间范围导出消费报表 PDF：标题、统计区间、消费明细、类别小计（折算为本位币）与总计。不传时间范围时导出本月，实际范围由响应头 X-Range-Start/X-Range-End 回显。
+// @Description 中文依赖 export.pdf_font_path 指定的 TrueType 字体（嵌入 PDF），未配置时使用阅读器内置宋体
+// @Tags 导出
+// @Produce application/pdf
+// @Security BearerAuth
+// @Param start_time query string false "开始时间 (2024-01-01)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (2024-12-31)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，用于计算默认的本月范围，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Success 200 {file} file "PDF 文件"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/export/pdf [get]
+func (h *ExportHandler) ExportPDF(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	scope, err := exportScopeFromQuery(c, userID, false)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	scope.IncludeTransfer = includeTransfer(c)
+
+	var expenses []models.Expense
+	if err := scope.applyExpense(database.DB, "user_id", "expense_time", "category").
+		Order("expense_time DESC").
+		Find(&expenses).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询数据失败"))
+		return
+	}
+
+	rows := make([]service.ExpenseReportRow, 0, len(expenses))
+	for _, e := range expenses {
+		rows = append(rows, service.ExpenseReportRow{
+			Time: e.ExpenseTime, Category: e.Category, Description: e.Description, Amount: e.Amount, Currency: e.Currency,
+		})
+	}
+	report, err := buildExpenseReport(rows, scope.StartStr+" 至 "+scope.EndStr, false)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询汇率失败"))
+		return
+	}
+	data, err := service.RenderExpenseReportPDF(report, pdfFontPath(), time.Now())
+	if err != nil {
+		InternalError(c, "生成 PDF 失败")
+		return
+	}
+
+	setRangeHeaders(c, scope.StartStr, scope.EndStr)
+	writePDFResponse(c, data, fmt.Sprintf("消费报表_%s_%s.pdf", scope.StartStr, scope.EndStr))
+}
+
+// ExportPDF 后台导出消费报表为 PDF
+// @Summary 导出消费报表为PDF
+// @Description 按与消费记录列表（GET /admin/expenses）相同的筛选参数导出消费报表 PDF：标题、统计区间、消费明细、类别小计（折算为本位币）与总计。管理员可导出所有用户数据（明细带用户名列），普通用户只能导出自己的数据。
+// @Description 不传任何时间条件（start_time/end_time/period）时导出本月（按 tz 计算），实际范围由响应头 X-Range-Start/X-Range-End 回显，未限制的一端为空。
+// @Tags 后台管理-导出
+// @Produce application/pdf
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，可只传一端；时间条件都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)，可只传一端；时间条件都不传时默认今天"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param category query string false "类别筛选"
+// @Param username query string false "用户名筛选（模糊匹配）"
+// @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
+// @Success 200 {file} file "PDF文件"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/export/pdf [get]
+func (h *AdminHandler) ExportPDF(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	filter, err := parseAdminExpenseFilter(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	filter.StartStr, filter.EndStr = defaultToThisMonth(filter.StartStr, filter.EndStr, now)
+
+	var expenses []ExpenseWithUser
+	query := filter.query(database.DB, currentUser)
+	if !includeTransfer(c) {
+		query = excludeTransferCategories(query, "expenses.category")
+	}
+	if err := query.Order("expenses.expense_time DESC").Scan(&expenses).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询数据失败")})
+		return
+	}
+
+	rows := make([]service.ExpenseReportRow, 0, len(expenses))
+	for _, e := range expenses {
+		rows = append(rows, service.ExpenseReportRow{
+			Time: e.ExpenseTime, Username: e.Username, Category: e.Category, Description: e.Description, Amount: e.Amount, Currency: e.Currency,
+		})
+	}
+	rangeText := rangeLabel(filter.StartStr, "最早") + " 至 " + rangeLabel(filter.EndStr, "至今")
+	report, err := buildExpenseReport(rows, rangeText, currentUser.IsAdmin)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询汇率失败")})
+		return
+	}
+	data, err := service.RenderExpenseReportPDF(report, pdfFontPath(), time.Now())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成 PDF 失败"})
+		return
+	}
+
+	setRangeHeaders(c, filter.StartStr, filter.EndStr)
+	filename := fmt.Sprintf("消费报表_%s_%s.pdf", rangeLabel(filter.StartStr, "最早"), rangeLabel(filter.EndStr, "至今"))
+	writePDFResponse(c, data, filename)
+}
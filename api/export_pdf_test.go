@@ -0,0 +1,81 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportHandler_ExportPDF(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "category", "description", "expense_time", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, 1, 99.99, "CNY", "餐饮", "午餐", time.Now(), time.Now(), time.Now(), nil))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/pdf", NewExportHandler().ExportPDF)
+
+	req := httptest.NewRequest("GET", "/export/pdf?start_time=2024-01-01&end_time=2024-01-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "application/pdf", w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename*=UTF-8''消费报表_2024-01-01_2024-01-31.pdf", w.Header().Get("Content-Disposition"))
+	assert.Equal(t, "2024-01-01", w.Header().Get("X-Range-Start"))
+	assert.True(t, bytes.HasPrefix(w.Body.Bytes(), []byte("%PDF-")))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportHandler_ExportPDF_InvalidRange(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/pdf", NewExportHandler().ExportPDF)
+
+	req := httptest.NewRequest("GET", "/export/pdf?start_time=2024-02-01&end_time=2024-01-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestBuildExpenseReport(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}).AddRow(1, "USD", 7))
+
+	rows := []service.ExpenseReportRow{
+		{Category: "餐饮", Amount: 30, Currency: "CNY"},
+		{Category: "交通", Amount: 10, Currency: "USD"},
+		{Category: "餐饮", Amount: 20, Currency: ""},
+		{Category: "购物", Amount: 1000, Currency: "JPY"},
+	}
+	report, err := buildExpenseReport(rows, "2024-01-01 至 2024-01-31", true)
+	require.NoError(t, err)
+
+	// 小计按本位币金额倒序；缺少汇率的 JPY 不计入小计与总计
+	assert.Equal(t, []service.ExpenseReportSubtotal{
+		{Category: "交通", Count: 1, Total: 70},
+		{Category: "餐饮", Count: 2, Total: 50},
+	}, report.Subtotals)
+	assert.Equal(t, 120.0, report.Total)
+	assert.Equal(t, "CNY", report.BaseCurrency)
+	assert.True(t, report.ShowUsername)
+	assert.Contains(t, report.Note, "JPY")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"finance/models"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -35,14 +37,139 @@ func TestExportHandler_ExportCSV(t *testing.T) {
 	require.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestExportHandler_ExportCSV_MissingParams(t *testing.T) {
+func TestExportHandler_ExportCSV_DefaultThisMonth(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expenses` WHERE \\(expense_time >= \\? AND expense_time <= \\?\\) AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "expense_time"}))
+
 	router := gin.New()
 	router.Use(setUserIDMiddleware(1))
 	router.GET("/export/csv", NewExportHandler().ExportCSV)
 
-	req := httptest.NewRequest("GET", "/export/csv", nil)
+	req := httptest.NewRequest("GET", "/export/csv?tz=Asia/Shanghai", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	// 不传时间范围时导出本月，并在响应头回显
+	require.Equal(t, 200, w.Code)
+	now := time.Now().In(time.FixedZone("CST", 8*3600))
+	assert.Equal(t, now.Format("2006-01")+"-01", w.Header().Get("X-Range-Start"))
+	assert.Equal(t, now.Format("2006-01-02"), w.Header().Get("X-Range-End"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportHandler_ExportCSV_PartialRange(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/csv", NewExportHandler().ExportCSV)
+
+	// 只传其一仍为参数错误，时区无效同样返回 400
+	for _, q := range []string{"start_time=2024-01-01", "end_time=2024-01-31", "tz=Mars/Base"} {
+		req := httptest.NewRequest("GET", "/export/csv?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equalf(t, 400, w.Code, "query %s", q)
+	}
+}
+
+func TestResolveExportScope(t *testing.T) {
+	t.Run("缺少参数", func(t *testing.T) {
+		_, err := resolveExportScope("", "2024-01-31", 1, false)
+		require.Error(t, err)
+		assert.Equal(t, "请提供开始时间和结束时间", err.Error())
+
+		_, err = resolveExportScope("2024-01-01", "", 1, false)
+		require.Error(t, err)
+	})
+
+	t.Run("格式错误", func(t *testing.T) {
+		_, err := resolveExportScope("2024/01/01", "2024-01-31", 1, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "开始时间格式错误")
+
+		_, err = resolveExportScope("2024-01-01", "20240131", 1, false)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "结束时间格式错误")
+	})
+
+	t.Run("开始晚于结束", func(t *testing.T) {
+		_, err := resolveExportScope("2024-02-01", "2024-01-31", 1, false)
+		require.Error(t, err)
+		assert.Equal(t, "开始时间不能晚于结束时间", err.Error())
+	})
+
+	t.Run("同一天合法且包含整天", func(t *testing.T) {
+		scope, err := resolveExportScope("2024-01-01", "2024-01-01", 1, false)
+		require.NoError(t, err)
+		assert.Equal(t, 23, scope.End.Hour())
+		assert.Equal(t, 59, scope.End.Second())
+	})
+
+	t.Run("普通用户只导出自己的数据", func(t *testing.T) {
+		scope, err := resolveExportScope("2024-01-01", "2024-01-31", 7, false)
+		require.NoError(t, err)
+		assert.Equal(t, uint(7), scope.UserID)
+	})
+
+	t.Run("管理员不按用户过滤", func(t *testing.T) {
+		scope, err := resolveExportScope("2024-01-01", "2024-01-31", 7, true)
+		require.NoError(t, err)
+		assert.Equal(t, uint(0), scope.UserID)
+	})
+}
+
+func TestExportHandler_ExportCSV_StartAfterEnd(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/csv", NewExportHandler().ExportCSV)
+
+	req := httptest.NewRequest("GET", "/export/csv?start_time=2024-02-01&end_time=2024-01-01", nil)
 	w := httptest.NewRecorder()
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, 400, w.Code)
 }
+
+func TestDeletedExpenseColumns(t *testing.T) {
+	deletedAt := time.Date(2024, 3, 1, 10, 0, 0, 0, time.Local)
+	by, gone := uint(2), uint(9)
+	names := map[uint]string{2: "admin"}
+
+	at, who := deletedExpenseColumns(models.Expense{}, names)
+	assert.Equal(t, "", at)
+	assert.Equal(t, "", who)
+
+	e := models.Expense{DeletedBy: &by}
+	e.DeletedAt.Time, e.DeletedAt.Valid = deletedAt, true
+	at, who = deletedExpenseColumns(e, names)
+	assert.Equal(t, "2024-03-01 10:00:00", at)
+	assert.Equal(t, "admin", who)
+
+	e.DeletedBy = &gone
+	_, who = deletedExpenseColumns(e, names)
+	assert.Equal(t, "用户#9", who)
+
+	e.DeletedBy = nil
+	_, who = deletedExpenseColumns(e, names)
+	assert.Equal(t, "未知", who)
+}
+
+func TestLoadDeleterNames(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id`,`username` FROM `users` WHERE id IN \\(\\?\\)$").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "admin"))
+
+	by := uint(2)
+	expenses := []ExpenseWithUser{
+		{Expense: models.Expense{ID: 1}},
+		{Expense: models.Expense{ID: 2, DeletedBy: &by}},
+		{Expense: models.Expense{ID: 3, DeletedBy: &by}},
+	}
+	assert.Equal(t, map[uint]string{2: "admin"}, loadDeleterNames(expenses))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
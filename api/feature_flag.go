@@ -0,0 +1,78 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"finance/database"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// UpdateFeatureFlagRequest 修改功能开关请求
+type UpdateFeatureFlagRequest struct {
+	Key     string `json:"key" binding:"required" example:"ai_chat"`
+	Enabled *bool  `json:"enabled" binding:"required" example:"false"`
+}
+
+// GetFeatureFlags 功能开关列表（仅超级管理员）
+// @Summary 获取功能开关
+// @Description 返回所有已登记功能开关的当前状态、默认值及是否被改动过
+// @Tags 后台管理-系统
+// @Produce json
+// @Success 200 {object} map[string]interface{} "获取成功，data 为 []service.FeatureFlagState"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/feature-flags [get]
+func (h *AdminHandler) GetFeatureFlags(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": service.ListFeatureFlags()})
+}
+
+// UpdateFeatureFlag 修改功能开关（仅超级管理员）
+// @Summary 修改功能开关
+// @Description 开启或关闭某个功能，修改后本实例立即生效；多实例部署时其他实例在 30 秒内同步。关闭的功能入口返回 403“功能未开放”
+// @Tags 后台管理-系统
+// @Accept json
+// @Produce json
+// @Param request body UpdateFeatureFlagRequest true "开关键与是否开启"
+// @Success 200 {object} map[string]interface{} "修改成功，data 为 []service.FeatureFlagState"
+// @Failure 400 {object} map[string]interface{} "参数错误或未知的开关"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/feature-flags [put]
+func (h *AdminHandler) UpdateFeatureFlag(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	var req UpdateFeatureFlagRequest
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if err := service.SetFeatureFlag(database.DB, req.Key, *req.Enabled, currentUser.ID); err != nil {
+		if errors.Is(err, service.ErrUnknownFeature) {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "未知的功能开关: " + req.Key})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "修改功能开关失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "修改成功", "data": service.ListFeatureFlags()})
+}
@@ -195,8 +195,7 @@ func (h *FeishuAuthHandler) handleFeishuLogin(c *gin.Context, code, redirectURI
 			redirectToLogin(c, "账号已锁定，请联系管理员")
 			return
 		}
-		setAdminCookies(c, &user)
-		c.Redirect(http.StatusFound, "/")
+		completeFeishuLogin(c, &user)
 		return
 	}
 
@@ -294,6 +293,18 @@ func redirectToLogin(c *gin.Context, errMsg string) {
 	c.Redirect(http.StatusFound, u)
 }
 
+// completeFeishuLogin 飞书扫码登录成功后的收尾：开启两步验证的账号与密码登录一样，
+// 先进入待验证状态，需 POST /admin/2fa/verify 通过后才设置登录 Cookie
+func completeFeishuLogin(c *gin.Context, user *models.User) {
+	if user.TOTPEnabled {
+		setTwoFactorPending(c, user.ID)
+		c.Redirect(http.StatusFound, "/?2fa_required=1")
+		return
+	}
+	setAdminCookies(c, user)
+	c.Redirect(http.StatusFound, "/")
+}
+
 func setAdminCookies(c *gin.Context, user *models.User) {
 	setSignedAdminCookie(c, "admin_user_id", fmt.Sprintf("%d", user.ID), 86400, true)
 	setAdminCookie(c, "admin_username", user.Username, 86400, false)
@@ -2,13 +2,16 @@ package api
 
 import (
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"finance/config"
+	"finance/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFeishuAuthHandler_GetFeishuConfig_Disabled(t *testing.T) {
@@ -55,3 +58,49 @@ func TestFeishuAuthHandler_GetFeishuConfig_Enabled(t *testing.T) {
 	assert.Contains(t, data["auth_url"], "www.feishu.cn")
 	assert.Contains(t, data["auth_url"], "bind")
 }
+
+func TestCompleteFeishuLogin_TwoFactorRequired(t *testing.T) {
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	user := &models.User{ID: 5, Username: "totpadmin", IsAdmin: true, Status: models.UserStatusActive, TOTPEnabled: true}
+	router := gin.New()
+	router.GET("/feishu/callback", func(c *gin.Context) { completeFeishuLogin(c, user) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/feishu/callback", nil))
+
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/?2fa_required=1", w.Header().Get("Location"))
+	assert.Nil(t, responseCookie(w, "admin_user_id"), "两步验证通过前不能设置登录 Cookie")
+	pending := responseCookie(w, twoFactorPendingCookie)
+	require.NotNil(t, pending)
+
+	// 待验证 Cookie 指向该用户，可直接用于 POST /admin/2fa/verify
+	verify := gin.New()
+	verify.GET("/check", func(c *gin.Context) {
+		id, ok := twoFactorPendingUserID(c)
+		assert.True(t, ok)
+		assert.Equal(t, uint(5), id)
+	})
+	req := httptest.NewRequest("GET", "/check", nil)
+	req.AddCookie(pending)
+	verify.ServeHTTP(httptest.NewRecorder(), req)
+}
+
+func TestCompleteFeishuLogin_WithoutTwoFactor(t *testing.T) {
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	user := &models.User{ID: 6, Username: "feishu_user", Status: models.UserStatusActive}
+	router := gin.New()
+	router.GET("/feishu/callback", func(c *gin.Context) { completeFeishuLogin(c, user) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/feishu/callback", nil))
+
+	require.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/", w.Header().Get("Location"))
+	assert.NotNil(t, responseCookie(w, "admin_user_id"))
+	assert.Nil(t, responseCookie(w, twoFactorPendingCookie))
+}
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// maxFieldsParam fields 参数最多解析的字段数，防止超长参数
+const maxFieldsParam = 50
+
+// parseFieldsParam 解析 fields 查询参数（逗号分隔的字段白名单），去掉空白与重复项；为空时返回 nil 表示不裁剪
+func parseFieldsParam(raw string) []string {
+	if strings.TrimSpace(raw) == "" {
+		return nil
+	}
+	seen := map[string]bool{}
+	var fields []string
+	for _, f := range strings.Split(raw, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+		fields = append(fields, f)
+		if len(fields) >= maxFieldsParam {
+			break
+		}
+	}
+	return fields
+}
+
+// sparseList 按 fields 裁剪列表每一项序列化后的字段（sparse fieldset），字段名即 JSON 键名。
+// 先按元素自身的 MarshalJSON 序列化，时间格式等与完整返回一致；不返回的字段（如 json:"-"）无法被请求。
+// 不存在的字段名忽略，id 始终保留；fields 为空或没有一个有效字段时原样返回
+func sparseList(list interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return list, nil
+	}
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+	var items []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, err
+	}
+
+	keep := map[string]bool{"id": true}
+	matched := false
+	for _, f := range fields {
+		keep[f] = true
+		for _, item := range items {
+			if _, ok := item[f]; ok {
+				matched = true
+				break
+			}
+		}
+	}
+	if !matched {
+		return list, nil
+	}
+
+	out := make([]map[string]json.RawMessage, len(items))
+	for i, item := range items {
+		picked := make(map[string]json.RawMessage, len(keep))
+		for k, v := range item {
+			if keep[k] {
+				picked[k] = v
+			}
+		}
+		out[i] = picked
+	}
+	return out, nil
+}
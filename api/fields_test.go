@@ -0,0 +1,89 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldsParam(t *testing.T) {
+	assert.Nil(t, parseFieldsParam(""))
+	assert.Nil(t, parseFieldsParam(" , "))
+	assert.Equal(t, []string{"amount", "expense_time"}, parseFieldsParam(" amount,expense_time,amount,"))
+}
+
+func TestSparseList(t *testing.T) {
+	expenses := []models.Expense{
+		{ID: 1, Amount: 12.5, Category: "餐饮", Description: "午饭", ExpenseTime: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)},
+	}
+
+	out, err := sparseList(expenses, []string{"amount", "expense_time", "no_such_field", "admin_note"})
+	require.NoError(t, err)
+	raw, err := json.Marshal(out)
+	require.NoError(t, err)
+
+	var items []map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &items))
+	require.Len(t, items, 1)
+	// 非法字段名忽略，id 始终保留，时间格式与完整返回一致
+	assert.Len(t, items[0], 3)
+	assert.Equal(t, 1.0, items[0]["id"])
+	assert.Equal(t, 12.5, items[0]["amount"])
+	assert.Equal(t, models.FormatTime(expenses[0].ExpenseTime), items[0]["expense_time"])
+}
+
+func TestSparseList_NoValidFields(t *testing.T) {
+	expenses := []models.Expense{{ID: 1, Amount: 12.5}}
+
+	out, err := sparseList(expenses, []string{"bogus"})
+	require.NoError(t, err)
+	assert.Equal(t, expenses, out)
+
+	out, err = sparseList(expenses, nil)
+	require.NoError(t, err)
+	assert.Equal(t, expenses, out)
+
+	empty := []models.Expense{}
+	out, err = sparseList(empty, []string{"amount"})
+	require.NoError(t, err)
+	assert.Equal(t, empty, out)
+}
+
+func TestIncomeHandler_List_Fields(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `incomes`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `incomes`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "income_time"}).
+			AddRow(3, 1, 5000, "工资", time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes", NewIncomeHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/incomes?fields=amount,bogus", nil))
+
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+	var resp struct {
+		Data struct {
+			Total int64                    `json:"total"`
+			List  []map[string]interface{} `json:"list"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, int64(1), resp.Data.Total)
+	require.Len(t, resp.Data.List, 1)
+	assert.Equal(t, map[string]interface{}{"id": 3.0, "amount": 5000.0}, resp.Data.List[0])
+}
@@ -0,0 +1,290 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// earthRadiusMeters 地球平均半径（米）
+const earthRadiusMeters = 6371000.0
+
+// GeoRuleHandler 地理围栏规则处理器（App端）
+type GeoRuleHandler struct{}
+
+// NewGeoRuleHandler 创建地理围栏规则处理器
+func NewGeoRuleHandler() *GeoRuleHandler {
+	return &GeoRuleHandler{}
+}
+
+// CreateGeoRuleRequest 创建地理围栏规则请求
+type CreateGeoRuleRequest struct {
+	Name      string   `json:"name" binding:"max=50" example:"家门口超市"`
+	CenterLat *float64 `json:"center_lat" binding:"required" example:"31.2304"`
+	CenterLng *float64 `json:"center_lng" binding:"required" example:"121.4737"`
+	Radius    float64  `json:"radius" binding:"required" example:"200"` // 米，10-50000
+	Category  string   `json:"category" binding:"required" example:"日用品"`
+	Priority  int      `json:"priority" example:"0"` // 多条命中时优先级高的生效
+}
+
+// UpdateGeoRuleRequest 更新地理围栏规则请求（只更新传入的字段）
+type UpdateGeoRuleRequest struct {
+	Name      *string  `json:"name" binding:"omitempty,max=50" example:"家门口超市"`
+	CenterLat *float64 `json:"center_lat" example:"31.2304"`
+	CenterLng *float64 `json:"center_lng" example:"121.4737"`
+	Radius    *float64 `json:"radius" example:"200"`
+	Category  *string  `json:"category" example:"日用品"`
+	Priority  *int     `json:"priority" example:"0"`
+}
+
+// haversineDistance 计算两点间的球面距离（米）
+func haversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := func(d float64) float64 { return d * math.Pi / 180 }
+	dLat := toRad(lat2 - lat1)
+	dLng := toRad(lng2 - lng1)
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(toRad(lat1))*math.Cos(toRad(lat2))*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMeters * math.Asin(math.Min(1, math.Sqrt(a)))
+}
+
+// validateCoordinates 校验经纬度范围
+func validateCoordinates(lat, lng float64) error {
+	if lat < -90 || lat > 90 {
+		return errors.New("纬度需在 -90 到 90 之间")
+	}
+	if lng < -180 || lng > 180 {
+		return errors.New("经度需在 -180 到 180 之间")
+	}
+	return nil
+}
+
+// validateGeoRadius 校验围栏半径
+func validateGeoRadius(r float64) error {
+	if r < models.MinGeoRuleRadius || r > models.MaxGeoRuleRadius {
+		return errors.New("半径需在 10-50000 米之间")
+	}
+	return nil
+}
+
+// matchGeoRule 返回坐标命中的最优规则，未命中返回 nil。
+// 多条命中时依次按：优先级高 > 半径小（更精确） > 距圆心近 > 创建早
+func matchGeoRule(rules []models.GeoRule, lat, lng float64) *models.GeoRule {
+	type hit struct {
+		rule     models.GeoRule
+		distance float64
+	}
+	var hits []hit
+	for _, r := range rules {
+		if d := haversineDistance(lat, lng, r.CenterLat, r.CenterLng); d <= r.Radius {
+			hits = append(hits, hit{rule: r, distance: d})
+		}
+	}
+	if len(hits) == 0 {
+		return nil
+	}
+	sort.SliceStable(hits, func(i, j int) bool {
+		a, b := hits[i], hits[j]
+		if a.rule.Priority != b.rule.Priority {
+			return a.rule.Priority > b.rule.Priority
+		}
+		if a.rule.Radius != b.rule.Radius {
+			return a.rule.Radius < b.rule.Radius
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		return a.rule.ID < b.rule.ID
+	})
+	return &hits[0].rule
+}
+
+// findGeoRule 查找用户规则中坐标命中的最优规则
+func findGeoRule(userID uint, lat, lng float64) (*models.GeoRule, error) {
+	var rules []models.GeoRule
+	if err := database.DB.Where("user_id = ?", userID).Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return matchGeoRule(rules, lat, lng), nil
+}
+
+// List 获取地理围栏规则列表
+// @Summary 获取地理围栏规则列表
+// @Description 获取当前用户的地理围栏自动归类规则，按优先级倒序
+// @Tags 地理围栏
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]models.GeoRule} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/geo-rules [get]
+func (h *GeoRuleHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var list []models.GeoRule
+	if err := database.DB.Where("user_id = ?", userID).Order("priority DESC, id ASC").Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// Create 创建地理围栏规则
+// @Summary 创建地理围栏规则
+// @Description 创建圆形围栏：创建带坐标且未指定类别的消费时，落在围栏内即自动归到该类别
+// @Tags 地理围栏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateGeoRuleRequest true "规则信息"
+// @Success 200 {object} Response{data=models.GeoRule} "创建成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/geo-rules [post]
+func (h *GeoRuleHandler) Create(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var req CreateGeoRuleRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if err := validateCoordinates(*req.CenterLat, *req.CenterLng); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if err := validateGeoRadius(req.Radius); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	var cat models.ExpenseCategory
+	if err := database.DB.Where("name = ?", req.Category).First(&cat).Error; err != nil {
+		BadRequest(c, "无效的消费类别")
+		return
+	}
+
+	rule := models.GeoRule{
+		UserID:    userID,
+		Name:      req.Name,
+		CenterLat: *req.CenterLat,
+		CenterLng: *req.CenterLng,
+		Radius:    req.Radius,
+		Category:  req.Category,
+		Priority:  req.Priority,
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建规则失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", rule)
+}
+
+// Update 更新地理围栏规则
+// @Summary 更新地理围栏规则
+// @Tags 地理围栏
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Param request body UpdateGeoRuleRequest true "规则信息"
+// @Success 200 {object} Response{data=models.GeoRule} "更新成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 404 {object} Response "规则不存在"
+// @Router /api/v1/geo-rules/{id} [put]
+func (h *GeoRuleHandler) Update(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var rule models.GeoRule
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+		NotFound(c, "规则不存在")
+		return
+	}
+	var req UpdateGeoRuleRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.CenterLat != nil || req.CenterLng != nil {
+		lat, lng := rule.CenterLat, rule.CenterLng
+		if req.CenterLat != nil {
+			lat = *req.CenterLat
+		}
+		if req.CenterLng != nil {
+			lng = *req.CenterLng
+		}
+		if err := validateCoordinates(lat, lng); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["center_lat"] = lat
+		updates["center_lng"] = lng
+	}
+	if req.Radius != nil {
+		if err := validateGeoRadius(*req.Radius); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["radius"] = *req.Radius
+	}
+	if req.Category != nil {
+		var cat models.ExpenseCategory
+		if err := database.DB.Where("name = ?", *req.Category).First(&cat).Error; err != nil {
+			BadRequest(c, "无效的消费类别")
+			return
+		}
+		updates["category"] = *req.Category
+	}
+	if req.Priority != nil {
+		updates["priority"] = *req.Priority
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&rule).Updates(updates).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "更新失败"))
+			return
+		}
+	}
+	database.DB.First(&rule, rule.ID)
+	SuccessWithMessage(c, "更新成功", rule)
+}
+
+// Delete 删除地理围栏规则
+// @Summary 删除地理围栏规则
+// @Tags 地理围栏
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "规则ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 404 {object} Response "规则不存在"
+// @Router /api/v1/geo-rules/{id} [delete]
+func (h *GeoRuleHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var rule models.GeoRule
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&rule).Error; err != nil {
+		NotFound(c, "规则不存在")
+		return
+	}
+	if err := database.DB.Delete(&rule).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	SuccessWithMessage(c, "删除成功", nil)
+}
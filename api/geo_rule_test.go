@@ -0,0 +1,96 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHaversineDistance(t *testing.T) {
+	assert.InDelta(t, 0, haversineDistance(31.23, 121.47, 31.23, 121.47), 1e-6)
+	// 赤道上经度相差 1 度约 111.19 km
+	assert.InDelta(t, 111195, haversineDistance(0, 0, 0, 1), 10)
+	// 高纬度处经度 1 度的距离按 cos(lat) 缩短
+	assert.InDelta(t, 55597, haversineDistance(60, 0, 60, 1), 50)
+}
+
+func TestMatchGeoRule(t *testing.T) {
+	rules := []models.GeoRule{
+		{ID: 1, CenterLat: 31.2304, CenterLng: 121.4737, Radius: 1000, Category: "购物"},
+		{ID: 2, CenterLat: 31.2305, CenterLng: 121.4738, Radius: 100, Category: "日用品"},
+		{ID: 3, CenterLat: 40.0, CenterLng: 116.0, Radius: 500, Category: "交通"},
+	}
+
+	// 同优先级时半径小的更精确
+	r := matchGeoRule(rules, 31.2304, 121.4737)
+	require.NotNil(t, r)
+	assert.Equal(t, uint(2), r.ID)
+
+	// 优先级高的优先
+	rules[0].Priority = 10
+	r = matchGeoRule(rules, 31.2304, 121.4737)
+	require.NotNil(t, r)
+	assert.Equal(t, uint(1), r.ID)
+
+	// 不在任何围栏内
+	assert.Nil(t, matchGeoRule(rules, 0, 0))
+}
+
+func TestExpenseHandler_Create_GeoRuleCategory(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `geo_rules` WHERE user_id = \\?").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "center_lat", "center_lng", "radius", "category", "priority"}).
+			AddRow(7, 1, 31.2304, 121.4737, 200, "日用品", 0))
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("日用品").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(9, "日用品", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":35,"expense_time":"2024-01-15 12:30:00","latitude":31.2305,"longitude":121.4738}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "日用品", resp.Data["category"])
+	assert.NotNil(t, resp.Data["matched_geo_rule"])
+}
+
+func TestExpenseHandler_Create_NoCategoryNoLocation(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":35,"expense_time":"2024-01-15 12:30:00"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "类别不能为空")
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"finance/config"
+	"finance/database"
+
+	"github.com/gin-gonic/gin"
+)
+
+// healthPingTimeout 健康检查中数据库 ping 的超时时间
+const healthPingTimeout = 2 * time.Second
+
+// errDatabaseNotInitialized 数据库连接尚未建立
+var errDatabaseNotInitialized = errors.New("数据库未初始化")
+
+// processStartTime 进程启动时间，用于计算运行时长
+var processStartTime = time.Now()
+
+// HealthCheck 健康检查
+// @Summary 健康检查
+// @Description 检查服务与数据库连通性：数据库可用时返回 200 和 status=ok，不可用时返回 503 和 status=degraded，供负载均衡摘除异常实例
+// @Tags 系统
+// @Produce json
+// @Success 200 {object} map[string]interface{} "服务正常"
+// @Failure 503 {object} map[string]interface{} "数据库不可用"
+// @Router /health [get]
+func HealthCheck(c *gin.Context) {
+	resp := gin.H{
+		"status":         "ok",
+		"db":             "ok",
+		"uptime_seconds": int64(time.Since(processStartTime).Seconds()),
+	}
+	if config.GlobalConfig != nil {
+		resp["db_name"] = config.GlobalConfig.Database.DBName
+	}
+
+	if err := pingDatabase(c.Request.Context()); err != nil {
+		resp["status"] = "degraded"
+		resp["db"] = "error"
+		c.JSON(http.StatusServiceUnavailable, resp)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// pingDatabase 在 healthPingTimeout 内 ping 数据库
+func pingDatabase(ctx context.Context) error {
+	if database.DB == nil {
+		return errDatabaseNotInitialized
+	}
+	sqlDB, err := database.DB.DB()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, healthPingTimeout)
+	defer cancel()
+	return sqlDB.PingContext(ctx)
+}
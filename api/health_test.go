@@ -0,0 +1,90 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"finance/config"
+	"finance/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+// setupPingMockDB 与 setupMockDB 相同，但开启 ping 监控以便断言 Ping（关闭 gorm 打开连接时的自动 ping）
+func setupPingMockDB(t *testing.T) (sqlmock.Sqlmock, func()) {
+	sqlDB, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+
+	oldDB := database.DB
+	database.DB = gormDB
+	return mock, func() {
+		database.DB = oldDB
+		sqlDB.Close()
+	}
+}
+
+func performHealthCheck(t *testing.T) (int, map[string]interface{}) {
+	router := gin.New()
+	router.GET("/health", HealthCheck)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/health", nil))
+
+	var body map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHealthCheck_OK(t *testing.T) {
+	mock, cleanup := setupPingMockDB(t)
+	defer cleanup()
+
+	config.GlobalConfig = &config.Config{Database: config.DatabaseConfig{DBName: "finance"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectPing()
+
+	code, body := performHealthCheck(t)
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok", body["status"])
+	assert.Equal(t, "ok", body["db"])
+	assert.Equal(t, "finance", body["db_name"])
+	assert.Contains(t, body, "uptime_seconds")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHealthCheck_DatabaseDown(t *testing.T) {
+	mock, cleanup := setupPingMockDB(t)
+	defer cleanup()
+
+	mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+	code, body := performHealthCheck(t)
+	assert.Equal(t, 503, code)
+	assert.Equal(t, "degraded", body["status"])
+	assert.Equal(t, "error", body["db"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestHealthCheck_DatabaseNotInitialized(t *testing.T) {
+	oldDB := database.DB
+	database.DB = nil
+	defer func() { database.DB = oldDB }()
+
+	code, body := performHealthCheck(t)
+	assert.Equal(t, 503, code)
+	assert.Equal(t, "degraded", body["status"])
+}
@@ -0,0 +1,58 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IdempotencyKeyHeader 创建接口的幂等键请求头：客户端为每次创建生成唯一值，网络重试时沿用同一个值
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotentReplayedHeader 响应头：值为 true 表示返回的是该幂等键首次请求创建的记录，本次未新建
+const IdempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKeyLength 幂等键最大长度，与 idempotency_key 列宽一致
+const maxIdempotencyKeyLength = 100
+
+// idempotencyKeyFrom 读取 Idempotency-Key 请求头，未传时返回空串（不做幂等处理）
+func idempotencyKeyFrom(c *gin.Context) (string, error) {
+	key := strings.TrimSpace(c.GetHeader(IdempotencyKeyHeader))
+	if len(key) > maxIdempotencyKeyLength {
+		return "", fmt.Errorf("%s 不能超过 %d 个字符", IdempotencyKeyHeader, maxIdempotencyKeyLength)
+	}
+	return key, nil
+}
+
+// findIdempotentRecord 查询用户在 scope 下是否处理过该幂等键，返回首次创建的记录ID。
+// 已过期但尚未被清理的键在这里删除，按新请求处理
+func findIdempotentRecord(userID uint, scope, key string) (uint, bool, error) {
+	var k models.IdempotencyKey
+	err := database.DB.Where("user_id = ? AND scope = ? AND idempotency_key = ?", userID, scope, key).First(&k).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	if k.Expired(time.Now()) {
+		return 0, false, database.DB.Delete(&k).Error
+	}
+	return k.RecordID, true, nil
+}
+
+// saveIdempotencyKey 在创建记录的同一事务中登记幂等键，key 为空时不登记。
+// 相同键的并发请求会因唯一索引冲突整体回滚，调用方再按键取回先提交的记录
+func saveIdempotencyKey(tx *gorm.DB, userID uint, scope, key string, recordID uint) error {
+	if key == "" {
+		return nil
+	}
+	return tx.Create(&models.IdempotencyKey{UserID: userID, Scope: scope, Key: key, RecordID: recordID}).Error
+}
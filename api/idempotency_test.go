@@ -0,0 +1,176 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func postWithIdempotencyKey(handler gin.HandlerFunc, path, key, body string) *httptest.ResponseRecorder {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST(path, handler)
+	req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(IdempotencyKeyHeader, key)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func expectIdempotencyKey(mock sqlmock.Sqlmock, scope, key string, rows *sqlmock.Rows) {
+	mock.ExpectQuery("SELECT \\* FROM `idempotency_keys` WHERE user_id = \\? AND scope = \\? AND idempotency_key = \\?").
+		WithArgs(1, scope, key).
+		WillReturnRows(rows)
+}
+
+func idempotencyKeyRows() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "user_id", "scope", "idempotency_key", "record_id", "created_at"})
+}
+
+const idempotentExpenseBody = `{"amount":99.99,"category":"餐饮","description":"午餐","expense_time":"2024-01-15 12:30:00"}`
+
+func TestExpenseHandler_Create_IdempotentReplay(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	expectIdempotencyKey(mock, models.IdempotencyScopeExpense, "k1",
+		idempotencyKeyRows().AddRow(1, 1, models.IdempotencyScopeExpense, "k1", 7, time.Now().Add(-time.Hour)))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "category"}).
+			AddRow(7, 1, 99.99, "CNY", "餐饮"))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags`").
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}).AddRow(7, "午饭"))
+
+	w := postWithIdempotencyKey(NewExpenseHandler().Create, "/expenses", "k1", idempotentExpenseBody)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	var resp struct {
+		Data struct {
+			ID   uint     `json:"id"`
+			Tags []string `json:"tags"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, uint(7), resp.Data.ID)
+	assert.Equal(t, []string{"午饭"}, resp.Data.Tags)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_SavesIdempotencyKey(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	expectIdempotencyKey(mock, models.IdempotencyScopeExpense, "k1", idempotencyKeyRows())
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "餐饮"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(8, 1))
+	mock.ExpectExec("INSERT INTO `idempotency_keys` \\(`user_id`,`scope`,`idempotency_key`,`record_id`,`created_at`\\)").
+		WithArgs(1, models.IdempotencyScopeExpense, "k1", 8, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	w := postWithIdempotencyKey(NewExpenseHandler().Create, "/expenses", "k1", idempotentExpenseBody)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_IdempotencyKeyTooLong(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	w := postWithIdempotencyKey(NewExpenseHandler().Create, "/expenses", strings.Repeat("k", maxIdempotencyKeyLength+1), idempotentExpenseBody)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+const idempotentIncomeBody = `{"amount":5000,"type":"工资","income_time":"2024-01-15 09:00:00"}`
+
+func TestIncomeHandler_Create_ExpiredIdempotencyKey(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 过期的键先删除，按新请求创建
+	expectIdempotencyKey(mock, models.IdempotencyScopeIncome, "k2",
+		idempotencyKeyRows().AddRow(3, 1, models.IdempotencyScopeIncome, "k2", 5, time.Now().Add(-25*time.Hour)))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `idempotency_keys` WHERE `idempotency_keys`.`id` = \\?").
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectExec("INSERT INTO `idempotency_keys`").
+		WithArgs(1, models.IdempotencyScopeIncome, "k2", 6, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	w := postWithIdempotencyKey(NewIncomeHandler().Create, "/incomes", "k2", idempotentIncomeBody)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Empty(t, w.Header().Get(IdempotentReplayedHeader))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Create_ConcurrentDuplicate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 并发的相同请求先提交：登记幂等键冲突回滚后返回对方创建的记录
+	expectIdempotencyKey(mock, models.IdempotencyScopeIncome, "k2", idempotencyKeyRows())
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnResult(sqlmock.NewResult(6, 1))
+	mock.ExpectExec("INSERT INTO `idempotency_keys`").
+		WillReturnError(errors.New("Error 1062: Duplicate entry"))
+	mock.ExpectRollback()
+	expectIdempotencyKey(mock, models.IdempotencyScopeIncome, "k2",
+		idempotencyKeyRows().AddRow(3, 1, models.IdempotencyScopeIncome, "k2", 5, time.Now()))
+	mock.ExpectQuery("SELECT \\* FROM `incomes` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "type"}).AddRow(5, 1, 5000, "CNY", "工资"))
+
+	w := postWithIdempotencyKey(NewIncomeHandler().Create, "/incomes", "k2", idempotentIncomeBody)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(IdempotentReplayedHeader))
+	assert.Contains(t, w.Body.String(), `"id":5`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Create_IdempotentRecordDeleted(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	expectIdempotencyKey(mock, models.IdempotencyScopeIncome, "k2",
+		idempotencyKeyRows().AddRow(3, 1, models.IdempotencyScopeIncome, "k2", 5, time.Now()))
+	mock.ExpectQuery("SELECT \\* FROM `incomes`").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	w := postWithIdempotencyKey(NewIncomeHandler().Create, "/incomes", "k2", idempotentIncomeBody)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
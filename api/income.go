@@ -1,10 +1,10 @@
 package api
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
-	"strings"
 	"time"
 
 	"finance/database"
@@ -12,6 +12,7 @@ import (
 	"finance/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // IncomeHandler 收入处理器（App端）
@@ -23,22 +24,28 @@ func NewIncomeHandler() *IncomeHandler {
 
 type CreateIncomeRequest struct {
 	Amount     float64 `json:"amount" binding:"required,gt=0" example:"5000.00"`
+	Currency   string  `json:"currency" example:"CNY"` // 币种（ISO 4217），不传时为本位币，需在 currency.allowed 中
 	Type       string  `json:"type" binding:"required" example:"工资"`
 	IncomeTime string  `json:"income_time" binding:"required" example:"2024-01-15 09:00:00"`
 }
 
 type UpdateIncomeRequest struct {
 	Amount     float64 `json:"amount" binding:"omitempty,gt=0"`
+	Currency   string  `json:"currency"` // 不传则不修改
 	Type       string  `json:"type"`
 	IncomeTime string  `json:"income_time"`
 }
 
 type IncomeListRequest struct {
-	Page      int    `form:"page" example:"1"`
-	PageSize  int    `form:"page_size" example:"10"`
-	Type      string `form:"type" example:"工资"`
-	StartTime string `form:"start_time" example:"2024-01-01"`
-	EndTime   string `form:"end_time" example:"2024-12-31"`
+	Page      int      `form:"page" example:"1"`
+	PageSize  int      `form:"page_size" example:"10"`
+	Type      string   `form:"type" example:"工资"`
+	StartTime string   `form:"start_time" example:"2024-01-01"`
+	EndTime   string   `form:"end_time" example:"2024-12-31"`
+	Period    string   `form:"period" example:"this_month"`
+	Fields    string   `form:"fields" example:"amount,income_time"` // 字段裁剪：逗号分隔，只返回这些字段（id 始终返回）
+	MinAmount *float64 `form:"min_amount" example:"100"`            // 最小金额（含，按原币种金额）
+	MaxAmount *float64 `form:"max_amount" example:"10000"`          // 最大金额（含，按原币种金额）
 }
 
 // GetIncomeCategories 获取收入类别列表
@@ -61,21 +68,40 @@ func (h *IncomeHandler) GetIncomeCategories(c *gin.Context) {
 
 // Create 创建收入
 // @Summary 创建收入
-// @Description 创建一条新的收入记录
+// @Description 创建一条新的收入记录。带 Idempotency-Key 请求头时，24 小时内重复提交相同的键直接返回首次创建的记录（响应头 Idempotent-Replayed: true），记录已删除时返回 409
 // @Tags 收入
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "幂等键，最长 100 个字符"
 // @Param request body CreateIncomeRequest true "收入信息"
 // @Success 200 {object} Response{data=models.Income} "创建成功"
 // @Failure 400 {object} Response "请求参数错误"
 // @Failure 401 {object} Response "未授权"
+// @Failure 409 {object} Response "幂等键对应的记录已删除"
 // @Router /api/v1/incomes [post]
 func (h *IncomeHandler) Create(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
+	idemKey, err := idempotencyKeyFrom(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if idemKey != "" && replayIncomeCreate(c, userID, idemKey) {
+		return
+	}
 	var req CreateIncomeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if err := validateAmount(req.Amount); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	currency, err := resolveCurrency(req.Currency)
+	if err != nil {
+		BadRequest(c, err.Error())
 		return
 	}
 	t, err := time.ParseInLocation("2006-01-02 15:04:05", req.IncomeTime, time.Local)
@@ -83,14 +109,52 @@ func (h *IncomeHandler) Create(c *gin.Context) {
 		BadRequest(c, "时间格式错误，应为: 2006-01-02 15:04:05")
 		return
 	}
-	in := models.Income{UserID: userID, Amount: req.Amount, Type: req.Type, IncomeTime: t}
-	if err := database.DB.Create(&in).Error; err != nil {
+	if err := validateRecordTime("收入时间", t); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	in := models.Income{UserID: userID, Amount: req.Amount, Currency: currency, Type: req.Type, IncomeTime: t, LedgerID: currentLedgerID(c), CreatedBy: userID, RecordSource: recordSourceFrom(c)}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&in).Error; err != nil {
+			return err
+		}
+		return saveIdempotencyKey(tx, userID, models.IdempotencyScopeIncome, idemKey, in.ID)
+	})
+	if err != nil {
+		// 相同幂等键的并发请求已先提交时返回其创建的记录
+		if idemKey != "" && replayIncomeCreate(c, userID, idemKey) {
+			return
+		}
 		InternalError(c, SafeErrorMessage(err, "创建收入失败"))
 		return
 	}
 	SuccessWithMessage(c, "创建成功", in)
 }
 
+// replayIncomeCreate 幂等键已处理过时返回首次创建的收入，返回 true 表示已写入响应
+func replayIncomeCreate(c *gin.Context, userID uint, key string) bool {
+	recordID, ok, err := findIdempotentRecord(userID, models.IdempotencyScopeIncome, key)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询幂等键失败"))
+		return true
+	}
+	if !ok {
+		return false
+	}
+	var in models.Income
+	if err := database.DB.Where("id = ? AND user_id = ?", recordID, userID).First(&in).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			Error(c, http.StatusConflict, "该 Idempotency-Key 创建的记录已删除")
+		} else {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+		}
+		return true
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	SuccessWithMessage(c, "创建成功", in)
+	return true
+}
+
 // List 获取收入列表
 // @Summary 获取收入列表
 // @Description 获取当前用户的收入列表，支持分页与筛选
@@ -102,14 +166,19 @@ func (h *IncomeHandler) Create(c *gin.Context) {
 // @Param type query string false "收入类型筛选"
 // @Param start_time query string false "开始时间 (2024-01-01)"
 // @Param end_time query string false "结束时间 (2024-12-31)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param fields query string false "只返回的字段，逗号分隔（如 amount,income_time），id 始终返回，不存在的字段名忽略"
+// @Param min_amount query number false "最小金额（含，按原币种金额，不能为负数）"
+// @Param max_amount query number false "最大金额（含，按原币种金额，不能为负数，且不能小于 min_amount）"
 // @Success 200 {object} Response{data=PageResponse{list=[]models.Income}} "获取成功"
+// @Failure 400 {object} Response "金额区间不合法"
 // @Failure 401 {object} Response "未授权"
 // @Router /api/v1/incomes [get]
 func (h *IncomeHandler) List(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 	var req IncomeListRequest
 	if err := c.ShouldBindQuery(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		BindError(c, err)
 		return
 	}
 	if req.Page <= 0 {
@@ -122,7 +191,14 @@ func (h *IncomeHandler) List(c *gin.Context) {
 		req.PageSize = 100
 	}
 
-	query := database.DB.Model(&models.Income{}).Where("user_id = ?", userID)
+	startStr, endStr, err := resolveUserPeriodQuery(userID, req.Period, req.StartTime, req.EndTime, time.Now())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	req.StartTime, req.EndTime = startStr, endStr
+
+	query := database.DB.Model(&models.Income{}).Scopes(recordScope(c, userID))
 	if req.Type != "" {
 		query = query.Where("type = ?", req.Type)
 	}
@@ -137,6 +213,11 @@ func (h *IncomeHandler) List(c *gin.Context) {
 			query = query.Where("income_time <= ?", t)
 		}
 	}
+	query, err = applyAmountRange(query, req.MinAmount, req.MaxAmount)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
 
 	var total int64
 	query.Count(&total)
@@ -146,7 +227,12 @@ func (h *IncomeHandler) List(c *gin.Context) {
 		InternalError(c, SafeErrorMessage(err, "查询失败"))
 		return
 	}
-	Success(c, PageResponse{Total: total, Page: req.Page, PageSize: req.PageSize, List: list})
+	data, err := sparseList(list, parseFieldsParam(req.Fields))
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, NewPageResponse(total, req.Page, req.PageSize, data))
 }
 
 // Get 获取单条收入
@@ -202,14 +288,26 @@ func (h *IncomeHandler) Update(c *gin.Context) {
 		return
 	}
 	var req UpdateIncomeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
 		return
 	}
 	updates := map[string]interface{}{}
 	if req.Amount > 0 {
+		if err := validateAmount(req.Amount); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
 		updates["amount"] = req.Amount
 	}
+	if req.Currency != "" {
+		currency, err := resolveCurrency(req.Currency)
+		if err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["currency"] = currency
+	}
 	if req.Type != "" {
 		updates["type"] = req.Type
 	}
@@ -219,6 +317,10 @@ func (h *IncomeHandler) Update(c *gin.Context) {
 			BadRequest(c, "时间格式错误，应为: 2006-01-02 15:04:05")
 			return
 		}
+		if err := validateRecordTime("收入时间", t); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
 		updates["income_time"] = t
 	}
 	if err := database.DB.Model(&in).Updates(updates).Error; err != nil {
@@ -259,11 +361,61 @@ func (h *IncomeHandler) Delete(c *gin.Context) {
 	SuccessWithMessage(c, "删除成功", nil)
 }
 
+// Anomalies 收入异常检测
+// @Summary 收入异常检测
+// @Description 按月聚合截至上月的收入，标记明显低于平时的月份：低于均值超过 z 个标准差（zscore），或环比跌幅超过 drop_percent（mom_drop）。有收入的月份少于 3 个时返回 insufficient=true
+// @Tags 收入
+// @Produce json
+// @Security BearerAuth
+// @Param months query int false "回看的完整月份数（3-36）" default(12)
+// @Param z query number false "标准差倍数阈值" default(1.5)
+// @Param drop_percent query number false "环比跌幅阈值（%）" default(30)
+// @Success 200 {object} Response{data=MonthlyAnomalyResult} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/anomalies [get]
+func (h *IncomeHandler) Anomalies(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	cfg, err := parseAnomalyConfig(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	start, end := anomalyWindow(time.Now(), cfg.Months)
+	var list []models.Income
+	if err := database.DB.Select("amount, currency, income_time").
+		Where("user_id = ? AND income_time >= ? AND income_time <= ?", userID, start, end).
+		Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "读取汇率失败"))
+		return
+	}
+
+	// 按本位币聚合，缺少汇率的币种不计入
+	amounts := make(map[string]float64)
+	for _, in := range list {
+		amount, ok := rates.ToBase(in.Amount, in.Currency)
+		if !ok {
+			continue
+		}
+		amounts[in.IncomeTime.In(time.Local).Format("2006-01")] += amount
+	}
+
+	Success(c, detectMonthlyAnomalies(amounts, start, cfg.Months, cfg, true))
+}
+
 // ===== 后台管理（Admin） =====
 
 type AdminCreateIncomeRequest struct {
 	UserID     uint    `json:"user_id" binding:"required"`
 	Amount     float64 `json:"amount" binding:"required,gt=0"`
+	Currency   string  `json:"currency"` // 币种（ISO 4217），不传时为本位币
 	Type       string  `json:"type" binding:"required"`
 	IncomeTime string  `json:"income_time" binding:"required"` // 2006-01-02 15:04:05
 }
@@ -272,17 +424,36 @@ type AdminUpdateIncomeRequest struct {
 	Amount     float64 `json:"amount" binding:"omitempty,gt=0"`
 	Type       string  `json:"type"`
 	IncomeTime string  `json:"income_time"`
+	// AdminNote 管理员内部备注（仅管理员可写，最多 500 字符），传空字符串表示清除；不传则不修改
+	AdminNote *string `json:"admin_note"`
+}
+
+// IncomeWithUser 带用户名的收入记录
+type IncomeWithUser struct {
+	models.Income
+	Username      string `json:"username"`
+	CreatedByName string `json:"created_by_name"` // 录入人用户名
+}
+
+// MarshalJSON 保留 models.Income 的统一时间格式并附带 username 与录入人信息
+func (i IncomeWithUser) MarshalJSON() ([]byte, error) {
+	return marshalWithExtra(i.Income, map[string]interface{}{
+		"username":        i.Username,
+		"created_by_name": i.CreatedByName,
+		"proxy_entry":     isProxyEntry(i.UserID, i.CreatedBy),
+	})
 }
 
 // GetAllIncomes 获取收入记录列表（后台管理）
 // @Summary 获取收入记录列表
-// @Description 获取收入记录列表，支持分页、时间范围、类型、用户名筛选。管理员可查看所有记录并可按用户ID筛选，非管理员只能查看自己的记录。
+// @Description 获取收入记录列表，支持分页、时间范围、类型、用户名筛选。管理员可查看所有记录并可按用户ID筛选，返回中附带 admin_note；非管理员只能查看自己的记录，不返回 admin_note。
 // @Tags 后台管理-收入管理
 // @Produce json
 // @Param page query int false "页码，默认1"
 // @Param page_size query int false "每页数量，默认20"
 // @Param start_time query string false "开始时间 (YYYY-MM-DD)"
 // @Param end_time query string false "结束时间 (YYYY-MM-DD)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
 // @Param type query string false "收入类型筛选"
 // @Param username query string false "用户名筛选（模糊匹配）"
 // @Param user_id query int false "用户ID筛选（仅管理员可用）"
@@ -305,15 +476,19 @@ func (h *AdminHandler) GetAllIncomes(c *gin.Context) {
 	if ps := c.Query("page_size"); ps != "" {
 		fmt.Sscanf(ps, "%d", &pageSize)
 	}
-	startTime := c.Query("start_time")
-	endTime := c.Query("end_time")
+	startTime, endTime, err := resolvePeriodQuery(c.Query("period"), c.Query("start_time"), c.Query("end_time"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 	typ := c.Query("type")
 	username := c.Query("username")
 	userIDFilter := c.Query("user_id") // 管理员可以按用户ID筛选
 
 	query := database.DB.Model(&models.Income{}).
-		Select("incomes.*, users.username").
-		Joins("LEFT JOIN users ON incomes.user_id = users.id")
+		Select("incomes.*, users.username, creators.username AS created_by_name").
+		Joins("LEFT JOIN users ON incomes.user_id = users.id").
+		Joins("LEFT JOIN users creators ON incomes.created_by = creators.id")
 
 	// 权限过滤：非管理员只能看自己的数据
 	if !currentUser.IsAdmin {
@@ -349,22 +524,19 @@ func (h *AdminHandler) GetAllIncomes(c *gin.Context) {
 	var total int64
 	query.Count(&total)
 
-	type IncomeWithUser struct {
-		models.Income
-		Username string `json:"username"`
-	}
 	var list []IncomeWithUser
 	offset := (page - 1) * pageSize
 	query.Order("incomes.income_time DESC").Offset(offset).Limit(pageSize).Scan(&list)
 
+	// 管理员备注只对管理员返回
+	var data interface{} = list
+	if currentUser.IsAdmin {
+		data = incomesWithAdminFields(list)
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"total":     total,
-			"page":      page,
-			"page_size": pageSize,
-			"list":      list,
-		},
+		"data":    NewPageResponse(total, page, pageSize, data),
 	})
 }
 
@@ -390,8 +562,17 @@ func (h *AdminHandler) CreateIncome(c *gin.Context) {
 	}
 
 	var req AdminCreateIncomeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if err := validateAmount(req.Amount); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	currency, err := resolveCurrency(req.Currency)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
 		return
 	}
 
@@ -408,7 +589,6 @@ func (h *AdminHandler) CreateIncome(c *gin.Context) {
 	}
 
 	// 校验收入类型是否存在（来源于数据库）
-	req.Type = strings.TrimSpace(req.Type)
 	if req.Type == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "收入类型不能为空"})
 		return
@@ -424,7 +604,11 @@ func (h *AdminHandler) CreateIncome(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "时间格式错误，应为: 2006-01-02 15:04:05"})
 		return
 	}
-	in := models.Income{UserID: req.UserID, Amount: req.Amount, Type: req.Type, IncomeTime: t}
+	if err := validateRecordTime("收入时间", t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	in := models.Income{UserID: req.UserID, Amount: req.Amount, Currency: currency, Type: req.Type, IncomeTime: t, CreatedBy: currentUser.ID, RecordSource: recordSourceFrom(c)}
 	if err := database.DB.Create(&in).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "创建失败")})
 		return
@@ -434,7 +618,7 @@ func (h *AdminHandler) CreateIncome(c *gin.Context) {
 
 // UpdateIncome 更新收入记录（后台管理）
 // @Summary 更新收入记录
-// @Description 更新指定的收入记录。管理员可以更新任何记录，非管理员只能更新自己的记录。
+// @Description 更新指定的收入记录。管理员可以更新任何记录并填写内部备注 admin_note，非管理员只能更新自己的记录且不能填写 admin_note。
 // @Tags 后台管理-收入管理
 // @Accept json
 // @Produce json
@@ -472,20 +656,19 @@ func (h *AdminHandler) UpdateIncome(c *gin.Context) {
 		return
 	}
 	var req AdminUpdateIncomeRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	updates := map[string]interface{}{}
 	if req.Amount > 0 {
+		if err := validateAmount(req.Amount); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		updates["amount"] = req.Amount
 	}
 	if req.Type != "" {
-		req.Type = strings.TrimSpace(req.Type)
-		if req.Type == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "收入类型不能为空"})
-			return
-		}
 		var incCat models.IncomeCategory
 		if err := database.DB.Where("name = ?", req.Type).First(&incCat).Error; err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的收入类型，请先在「收入类别」中维护"})
@@ -499,14 +682,33 @@ func (h *AdminHandler) UpdateIncome(c *gin.Context) {
 			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "时间格式错误，应为: 2006-01-02 15:04:05"})
 			return
 		}
+		if err := validateRecordTime("收入时间", t); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		updates["income_time"] = t
 	}
+	if req.AdminNote != nil {
+		if !currentUser.IsAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，仅管理员可填写备注"})
+			return
+		}
+		if err := validateAdminNote(*req.AdminNote); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["admin_note"] = *req.AdminNote
+	}
 	if err := database.DB.Model(&in).Updates(updates).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
 	database.DB.First(&in, in.ID)
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": in})
+	var data interface{} = in
+	if currentUser.IsAdmin {
+		data = withAdminFields{record: in, adminNote: in.AdminNote, source: in.RecordSource}
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": data})
 }
 
 // DeleteIncome 删除收入记录（后台管理）
@@ -0,0 +1,95 @@
+package api
+
+import (
+	"fmt"
+	"time"
+
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxIncomeBatchSize 单次批量创建收入的最大条数
+const maxIncomeBatchSize = 500
+
+// createIncomeItems 逐项校验收入请求，通过校验的在同一事务中写入；types 为已存在的收入类别名称，origin 为请求的提交来源
+func createIncomeItems(userID uint, origin models.RecordSource, reqs []CreateIncomeRequest, types map[string]bool) *BatchResult {
+	result := NewBatchResult(len(reqs))
+	var pending []models.Income
+	var pendingItems []BatchPending
+	for i, req := range reqs {
+		if req.Type == "" {
+			result.FailIndex(i, "", "收入类型不能为空")
+			continue
+		}
+		if !types[req.Type] {
+			result.FailIndex(i, req.Type, "收入类型不存在: "+req.Type)
+			continue
+		}
+		if err := validateAmount(req.Amount); err != nil {
+			result.FailIndex(i, req.Type, err.Error())
+			continue
+		}
+		currency, err := resolveCurrency(req.Currency)
+		if err != nil {
+			result.FailIndex(i, req.Type, err.Error())
+			continue
+		}
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", req.IncomeTime, time.Local)
+		if err != nil {
+			result.FailIndex(i, req.Type, "时间格式错误，应为: 2006-01-02 15:04:05")
+			continue
+		}
+		if err := validateRecordTime("收入时间", t); err != nil {
+			result.FailIndex(i, req.Type, err.Error())
+			continue
+		}
+		pending = append(pending, models.Income{UserID: userID, Amount: req.Amount, Currency: currency, Type: req.Type, IncomeTime: t, CreatedBy: userID, RecordSource: origin})
+		pendingItems = append(pendingItems, BatchPending{Index: i, Key: req.Type})
+	}
+
+	result.CommitTx(pendingItems, "创建收入失败", func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&pending, 100).Error
+	})
+	return result
+}
+
+// BatchCreate 批量创建收入
+// @Summary 批量创建收入
+// @Description 一次提交多条收入记录（字段同创建收入），单次最多 500 条。逐项校验收入类型存在、金额、币种和收入时间格式，
+// @Description 校验通过的记录在同一事务中写入（写入失败则全部回滚）；返回批量结果，failures 的 index 为该项在数组中的位置（从 0 开始）
+// @Tags 收入
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body []CreateIncomeRequest true "收入信息数组"
+// @Success 200 {object} Response{data=BatchResult} "处理完成"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/batch [post]
+func (h *IncomeHandler) BatchCreate(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var reqs []CreateIncomeRequest
+	if err := bindJSONItems(c, &reqs); err != nil {
+		BindError(c, err)
+		return
+	}
+	if len(reqs) == 0 {
+		BadRequest(c, "收入列表不能为空")
+		return
+	}
+	if len(reqs) > maxIncomeBatchSize {
+		BadRequest(c, fmt.Sprintf("单次最多创建 %d 条收入", maxIncomeBatchSize))
+		return
+	}
+
+	types, err := loadIncomeCategoryNames()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询收入类别失败"))
+		return
+	}
+
+	SuccessWithMessage(c, "批量创建完成", createIncomeItems(userID, recordSourceFrom(c), reqs, types))
+}
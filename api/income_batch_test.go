@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateIncomeItems_PartialFailure(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	types := map[string]bool{"工资": true, "奖金": true}
+	reqs := []CreateIncomeRequest{
+		{Amount: 8000, Type: "工资", IncomeTime: "2024-01-15 09:00:00"},
+		{Amount: 8000, Type: "股票", IncomeTime: "2024-02-15 09:00:00"},
+		{Amount: 8000, Type: "工资", IncomeTime: "2024-03-15"},
+		{Amount: 0, Type: "奖金", IncomeTime: "2024-03-15 09:00:00"},
+		{Amount: 2000, Type: "奖金", IncomeTime: "2024-04-15 09:00:00"},
+	}
+	result := createIncomeItems(1, models.RecordSource{}, reqs, types)
+
+	assert.Equal(t, 5, result.Total)
+	assert.Equal(t, 2, result.SuccessCount)
+	require.Len(t, result.Failures, 3)
+	assert.Equal(t, 1, *result.Failures[0].Index)
+	assert.Contains(t, result.Failures[0].Reason, "收入类型不存在")
+	assert.Equal(t, 2, *result.Failures[1].Index)
+	assert.Contains(t, result.Failures[1].Reason, "时间格式错误")
+	assert.Equal(t, 3, *result.Failures[2].Index)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateIncomeItems_RollbackMarksAllFailed(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnError(errors.New("db down"))
+	mock.ExpectRollback()
+
+	reqs := []CreateIncomeRequest{
+		{Amount: 8000, Type: "工资", IncomeTime: "2024-01-15 09:00:00"},
+		{Amount: 8000, Type: "工资", IncomeTime: "2024-02-15 09:00:00"},
+	}
+	result := createIncomeItems(1, models.RecordSource{}, reqs, map[string]bool{"工资": true})
+
+	assert.Equal(t, 0, result.SuccessCount)
+	assert.Equal(t, 2, result.FailCount)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_BatchCreate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `name` FROM `income_categories`").
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("工资"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/incomes/batch", NewIncomeHandler().BatchCreate)
+
+	// 类型首尾空白会被去除；缺少必填字段只让该项失败
+	body := `[{"amount":8000,"type":" 工资 ","income_time":"2024-01-15 09:00:00"},{"amount":100}]`
+	req := httptest.NewRequest("POST", "/incomes/batch", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	var resp struct {
+		Data BatchResult `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 1, resp.Data.SuccessCount)
+	require.Len(t, resp.Data.Failures, 1)
+	assert.Equal(t, 1, *resp.Data.Failures[0].Index)
+
+	req = httptest.NewRequest("POST", "/incomes/batch", strings.NewReader(`[]`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
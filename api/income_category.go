@@ -1,14 +1,15 @@
 package api
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
-	"strings"
 
 	"finance/database"
 	"finance/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // IncomeCategoryHandler 收入类别管理
@@ -19,9 +20,10 @@ func NewIncomeCategoryHandler() *IncomeCategoryHandler {
 }
 
 type IncomeCategoryCreateRequest struct {
-	Name  string `json:"name" binding:"required,min=1,max=50"`
-	Sort  int    `json:"sort"`
-	Color string `json:"color" binding:"omitempty,max=20"` // 颜色代码，如 #10b981
+	Name    string `json:"name" binding:"required,min=1,max=50"`
+	Sort    int    `json:"sort"`
+	Color   string `json:"color" binding:"omitempty,max=20"` // 颜色代码 #RRGGBB 或 #RRGGBBAA，如 #10b981
+	Restore bool   `json:"restore"`                          // 同名类别已删除时恢复它（并写入本次提交的其他字段）
 }
 
 type IncomeCategoryUpdateRequest struct {
@@ -36,11 +38,21 @@ type IncomeCategoryUpdateRequest struct {
 // @Tags 后台管理-收入类别
 // @Produce json
 // @Param name query string false "类别名称（模糊匹配）"
+// @Param created_start query string false "创建时间起（2006-01-02 或 2006-01-02 15:04:05）"
+// @Param created_end query string false "创建时间止（只写日期时包含当天）"
+// @Param sort query string false "按创建时间排序，不传时使用默认排序" Enums(created_at_desc,created_at_asc)
 // @Success 200 {object} map[string]interface{} "获取成功，返回类别列表"
 // @Router /admin/income-categories [get]
 func (h *IncomeCategoryHandler) List(c *gin.Context) {
+	created, err := parseCreatedAtQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
 	var list []models.IncomeCategory
-	if err := database.DB.Order("sort ASC, id ASC").Find(&list).Error; err != nil {
+	query := created.Filter(database.DB, "created_at")
+	if err := query.Order(created.Order("created_at", "sort ASC, id ASC")).Find(&list).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
 		return
 	}
@@ -49,14 +61,16 @@ func (h *IncomeCategoryHandler) List(c *gin.Context) {
 
 // Create 创建收入类别
 // @Summary 创建收入类别
-// @Description 创建新的收入类别，支持设置名称、排序和颜色（仅管理员）
+// @Description 创建新的收入类别，支持设置名称、排序和颜色（仅管理员）。
+// @Description 同名类别已被删除时返回 409 及其 deleted_id，带 restore=true 重新提交则恢复该类别并写入本次提交的字段
 // @Tags 后台管理-收入类别
 // @Accept json
 // @Produce json
 // @Param request body IncomeCategoryCreateRequest true "类别信息"
-// @Success 200 {object} map[string]interface{} "创建成功"
+// @Success 200 {object} map[string]interface{} "创建成功或恢复成功"
 // @Failure 400 {object} map[string]interface{} "参数错误或类别名称已存在"
 // @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 409 {object} map[string]interface{} "同名类别已删除，可恢复"
 // @Router /admin/income-categories [post]
 func (h *IncomeCategoryHandler) Create(c *gin.Context) {
 	user, err := getCurrentUser(c)
@@ -70,27 +84,52 @@ func (h *IncomeCategoryHandler) Create(c *gin.Context) {
 	}
 
 	var req IncomeCategoryCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
-	req.Name = strings.TrimSpace(req.Name)
 	if req.Name == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "名称不能为空"})
 		return
 	}
 
-	// 唯一性
+	// 唯一性：包含已删除的同名类别
 	var existing models.IncomeCategory
-	if err := database.DB.Where("name = ?", req.Name).First(&existing).Error; err == nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "类别名称已存在"})
+	err = findCategoryByName(&existing, req.Name, 0)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
 		return
 	}
+	restoring := err == nil
+	if restoring {
+		if !existing.DeletedAt.Valid {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "类别名称已存在"})
+			return
+		}
+		if !req.Restore {
+			respondDeletedCategoryConflict(c, existing.ID)
+			return
+		}
+	}
 
+	if err := validateCategoryColor(req.Color); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
 	color := req.Color
 	if color == "" {
 		color = "#64748b" // 默认灰色
 	}
+	if restoring {
+		existing.Sort, existing.Color = req.Sort, color
+		existing.DeletedAt = gorm.DeletedAt{}
+		if err := restoreCategory(&existing, "sort", "color"); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "恢复失败")})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"success": true, "message": "恢复成功", "data": existing})
+		return
+	}
 	cat := models.IncomeCategory{Name: req.Name, Sort: req.Sort, Color: color}
 	if err := database.DB.Create(&cat).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "创建失败")})
@@ -101,7 +140,7 @@ func (h *IncomeCategoryHandler) Create(c *gin.Context) {
 
 // Update 更新收入类别
 // @Summary 更新收入类别
-// @Description 更新指定的收入类别信息（仅管理员）
+// @Description 更新指定的收入类别信息（仅管理员）。修改名称时在同一事务中把收入记录（含回收站）的 type 一并改为新名称，返回 renamed_records 条数
 // @Tags 后台管理-收入类别
 // @Accept json
 // @Produce json
@@ -136,21 +175,16 @@ func (h *IncomeCategoryHandler) Update(c *gin.Context) {
 	}
 
 	var req IncomeCategoryUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 
 	updates := map[string]interface{}{}
 	if req.Name != "" {
-		req.Name = strings.TrimSpace(req.Name)
-		if req.Name == "" {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "名称不能为空"})
-			return
-		}
 		var existing models.IncomeCategory
-		if err := database.DB.Where("name = ? AND id != ?", req.Name, cat.ID).First(&existing).Error; err == nil {
-			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "类别名称已存在"})
+		if err := findCategoryByName(&existing, req.Name, cat.ID); err == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": duplicateCategoryNameMessage(existing.DeletedAt)})
 			return
 		}
 		updates["name"] = req.Name
@@ -159,6 +193,10 @@ func (h *IncomeCategoryHandler) Update(c *gin.Context) {
 		updates["sort"] = *req.Sort
 	}
 	if req.Color != nil {
+		if err := validateCategoryColor(*req.Color); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
 		color := *req.Color
 		if color == "" {
 			color = "#64748b" // 默认灰色
@@ -170,22 +208,26 @@ func (h *IncomeCategoryHandler) Update(c *gin.Context) {
 		return
 	}
 
-	if err := database.DB.Model(&cat).Updates(updates).Error; err != nil {
+	usage := categoryInUse{RecordModel: &models.Income{}, Column: "type"}
+	renamed, err := usage.renameCategory(&cat, updates, cat.Name)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
 		return
 	}
 	database.DB.First(&cat, cat.ID)
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": cat})
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": cat, "renamed_records": renamed})
 }
 
 // Delete 软删除收入类别
 // @Summary 删除收入类别
-// @Description 软删除指定的收入类别（仅管理员）
+// @Description 软删除指定的收入类别（仅管理员）。仍被收入记录（含回收站）使用时返回 400 及 record_count；
+// @Description 带 force=true 时在同一事务中把这些记录改挂到兜底类别（category.income_fallback，默认“其他”，需已存在）后删除，返回 reassigned 条数
 // @Tags 后台管理-收入类别
 // @Produce json
 // @Param id path int true "类别ID"
+// @Param force query bool false "仍被记录使用时是否强制删除（记录改挂到兜底类别）"
 // @Success 200 {object} map[string]interface{} "删除成功"
-// @Failure 400 {object} map[string]interface{} "无效的ID"
+// @Failure 400 {object} map[string]interface{} "无效的ID、类别仍被使用或兜底类别不存在"
 // @Failure 403 {object} map[string]interface{} "权限不足"
 // @Failure 404 {object} map[string]interface{} "类别不存在"
 // @Router /admin/income-categories/{id} [delete]
@@ -210,9 +252,21 @@ func (h *IncomeCategoryHandler) Delete(c *gin.Context) {
 		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "类别不存在"})
 		return
 	}
-	if err := database.DB.Delete(&cat).Error; err != nil {
+	usage := categoryInUse{
+		RecordModel:   &models.Income{},
+		Column:        "type",
+		CategoryModel: &models.IncomeCategory{},
+		Fallback:      incomeFallbackCategory(),
+		RecordLabel:   "收入记录",
+	}
+	count, ok := usage.checkDelete(c, cat.Name)
+	if !ok {
+		return
+	}
+	reassigned, err := usage.deleteCategory(&cat, cat.Name, count)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功", "data": gin.H{"reassigned": reassigned, "fallback": usage.Fallback}})
 }
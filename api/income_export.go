@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"net/http"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// ExportIncomesCSV 导出收入记录为 CSV
+// @Summary 导出收入记录
+// @Description 根据时间范围导出当前用户的收入记录为 CSV 文件，参数与消费 CSV 导出一致。不传时间范围时导出本月，实际范围由响应头 X-Range-Start/X-Range-End 回显
+// @Tags 导出
+// @Produce text/csv
+// @Security BearerAuth
+// @Param start_time query string false "开始时间 (2024-01-01)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (2024-12-31)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，用于计算默认的本月范围，默认服务器时区"
+// @Param formatted query bool false "金额输出为本地化货币字符串（如 ¥1,234.56），默认裸数字"
+// @Param currency query string false "默认货币代码（formatted=true 时生效，每条记录按自身币种显示，币种不在列表中时用该值）：CNY/USD/EUR/GBP/HKD/JPY，默认 CNY"
+// @Param locale query string false "区域（formatted=true 时生效）：zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR，默认 zh-CN"
+// @Param delimiter query string false "分隔符：comma/semicolon/tab，默认 comma"
+// @Param encoding query string false "编码：utf8-bom/utf8/gbk，默认 utf8-bom"
+// @Success 200 {file} file "CSV 文件"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/export/incomes/csv [get]
+func (h *ExportHandler) ExportIncomesCSV(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	scope, err := exportScopeFromQuery(c, userID, false)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	money, err := resolveMoneyFormat(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	opts, err := resolveCSVOptions(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	var incomes []models.Income
+	if err := scope.apply(database.DB, "user_id", "income_time").
+		Order("income_time DESC").
+		Find(&incomes).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询数据失败"))
+		return
+	}
+
+	buf := new(bytes.Buffer)
+	writer := csv.NewWriter(buf)
+	writer.Comma = opts.Delimiter
+
+	if err := writer.Write([]string{"ID", "金额", "收入类型", "收入时间", "创建时间"}); err != nil {
+		InternalError(c, "生成 CSV 失败")
+		return
+	}
+	for _, income := range incomes {
+		amount := fmt.Sprintf("%.2f", income.Amount)
+		if money != nil {
+			amount = money.withCurrency(income.Currency).Format(income.Amount)
+		}
+		row := []string{
+			fmt.Sprintf("%d", income.ID),
+			amount,
+			income.Type,
+			income.IncomeTime.Format("2006-01-02 15:04:05"),
+			income.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if err := writer.Write(row); err != nil {
+			InternalError(c, "生成 CSV 失败")
+			return
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		InternalError(c, "生成 CSV 失败")
+		return
+	}
+
+	data, err := opts.Encode(buf.Bytes())
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	filename := fmt.Sprintf("incomes_%s_%s.csv", scope.StartStr, scope.EndStr)
+	c.Header("Content-Type", opts.ContentType())
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%s", filename))
+	setRangeHeaders(c, scope.StartStr, scope.EndStr)
+	c.Header("Content-Length", fmt.Sprintf("%d", len(data)))
+
+	c.Data(http.StatusOK, opts.ContentType(), data)
+}
+
+// ExportIncomesExcel 导出收入记录为 Excel
+// @Summary 导出收入记录为Excel
+// @Description 根据时间范围导出收入记录为 Excel 文件，末行为合计。管理员导出所有用户数据，普通用户只能导出自己的数据。
+// @Description 不传时间范围时导出本月（按 tz 计算），实际范围由响应头 X-Range-Start/X-Range-End 回显
+// @Tags 后台管理-导出
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param formatted query bool false "金额列使用货币数字格式（仍为数值，可计算），默认裸数字"
+// @Param currency query string false "默认货币代码（formatted=true 时生效，每条记录按自身币种显示，币种不在列表中时用该值）：CNY/USD/EUR/GBP/HKD/JPY，默认 CNY"
+// @Param locale query string false "区域（formatted=true 时生效）：zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR，默认 zh-CN"
+// @Success 200 {file} file "Excel文件"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/export/incomes/excel [get]
+func (h *AdminHandler) ExportIncomesExcel(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	scope, err := exportScopeFromQuery(c, currentUser.ID, currentUser.IsAdmin)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	money, err := resolveMoneyFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	incomes, err := queryIncomesWithUser(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询数据失败")})
+		return
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询汇率失败")})
+		return
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.SetSheetName("Sheet1", incomeSheetName)
+
+	writeIncomeSheet(f, incomeSheetName, incomes, money, rates)
+
+	filename := fmt.Sprintf("收入记录_%s_%s.xlsx", scope.StartStr, scope.EndStr)
+	setRangeHeaders(c, scope.StartStr, scope.EndStr)
+	if err := writeExcelResponse(c, f, filename); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成 Excel 失败"})
+		return
+	}
+}
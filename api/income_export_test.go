@@ -0,0 +1,105 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestExportHandler_ExportIncomesCSV(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `incomes` WHERE \\(income_time >= \\? AND income_time <= \\?\\) AND user_id = \\?").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "income_time", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, 1, 8000, "工资", time.Now(), time.Now(), time.Now(), nil))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/incomes/csv", NewExportHandler().ExportIncomesCSV)
+
+	req := httptest.NewRequest("GET", "/export/incomes/csv?start_time=2024-01-01&end_time=2024-01-31&encoding=utf8", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "attachment; filename=incomes_2024-01-01_2024-01-31.csv", w.Header().Get("Content-Disposition"))
+	assert.Contains(t, w.Body.String(), "ID,金额,收入类型,收入时间,创建时间\n1,8000.00,工资,")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExportHandler_ExportIncomesCSV_PartialRange(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/export/incomes/csv", NewExportHandler().ExportIncomesCSV)
+
+	req := httptest.NewRequest("GET", "/export/incomes/csv?start_time=2024-01-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestAdminHandler_ExportIncomesExcel(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	// 管理员导出全部用户，不按 user_id 过滤
+	mock.ExpectQuery("SELECT incomes\\.\\*, users\\.username FROM `incomes` LEFT JOIN users ON incomes.user_id = users.id " +
+		"WHERE \\(incomes.income_time >= \\? AND incomes.income_time <= \\?\\) AND `incomes`.`deleted_at` IS NULL ORDER BY incomes.income_time DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "type", "income_time", "created_at", "username"}).
+			AddRow(2, 1, 8000, "CNY", "工资", time.Now(), time.Now(), "alice").
+			AddRow(1, 2, 71.5, "USD", "奖金", time.Now(), time.Now(), "bob"))
+	// 合计按汇率折算为本位币：8000 + 71.5 × 7
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+
+	router := gin.New()
+	router.GET("/admin/export/incomes/excel", NewAdminHandler().ExportIncomesExcel)
+
+	req := httptest.NewRequest("GET", "/admin/export/incomes/excel?start_time=2024-01-01&end_time=2024-01-31", nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "2024-01-01", w.Header().Get("X-Range-Start"))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows("收入记录")
+	require.NoError(t, err)
+	require.Len(t, rows, 4)
+	assert.Equal(t, []string{"ID", "用户名", "金额", "收入类型", "收入时间", "创建时间"}, rows[0])
+	assert.Equal(t, "alice", rows[1][1])
+	assert.Equal(t, "合计", rows[3][0])
+	assert.Equal(t, "8500.5", rows[3][2])
+	assert.Equal(t, "共 2 条记录", rows[3][3])
+
+	merged, err := f.GetMergeCells("收入记录")
+	require.NoError(t, err)
+	require.Len(t, merged, 2)
+	assert.Equal(t, "A4", merged[0].GetStartAxis())
+	assert.Equal(t, "B4", merged[0].GetEndAxis())
+	assert.Equal(t, "D4", merged[1].GetStartAxis())
+	assert.Equal(t, "F4", merged[1].GetEndAxis())
+}
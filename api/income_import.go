@@ -0,0 +1,148 @@
+package api
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// loadIncomeCategoryNames 系统中已有的收入类别名
+func loadIncomeCategoryNames() (map[string]bool, error) {
+	var names []string
+	if err := database.DB.Model(&models.IncomeCategory{}).Pluck("name", &names).Error; err != nil {
+		return nil, err
+	}
+	existing := make(map[string]bool, len(names))
+	for _, n := range names {
+		existing[n] = true
+	}
+	return existing, nil
+}
+
+// importIncomeRows 逐行映射类型并校验，通过的行在同一事务中写入；
+// 失败项的 index 为数据行位置（从 0 开始，不含表头），key 为源类型名；origin 为导入请求的提交来源
+func importIncomeRows(userID uint, origin models.RecordSource, rows [][]string, mapping map[string]categoryMappingEntry, types map[string]bool) *BatchResult {
+	result := NewBatchResult(len(rows))
+	var pending []models.Income
+	var pendingItems []BatchPending
+
+	for i, rec := range rows {
+		field := func(idx int) string {
+			if idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		// 第 4 列备注：收入记录没有描述字段，读取时忽略
+		amountStr, source, timeStr := field(0), field(1), field(2)
+
+		if source == "" {
+			result.FailIndex(i, source, "收入类型不能为空")
+			continue
+		}
+		typ := source
+		if e, ok := mapping[strings.ToLower(source)]; ok {
+			typ = e.target
+		}
+		if !types[typ] {
+			result.FailIndex(i, source, "收入类型不存在: "+typ)
+			continue
+		}
+
+		amount, err := strconv.ParseFloat(strings.ReplaceAll(amountStr, ",", ""), 64)
+		if err != nil {
+			result.FailIndex(i, source, "金额格式错误")
+			continue
+		}
+		amount = math.Round(amount*100) / 100
+		if err := validateAmount(amount); err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+		incomeTime, err := parseImportTime("收入时间", timeStr)
+		if err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+		if err := validateRecordTime("收入时间", incomeTime); err != nil {
+			result.FailIndex(i, source, err.Error())
+			continue
+		}
+
+		pending = append(pending, models.Income{UserID: userID, Amount: amount, Currency: baseCurrency(), Type: typ, IncomeTime: incomeTime, CreatedBy: userID, RecordSource: origin})
+		pendingItems = append(pendingItems, BatchPending{Index: i, Key: source})
+	}
+
+	if len(pending) == 0 {
+		return result
+	}
+	result.CommitTx(pendingItems, "导入收入记录失败", func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&pending, 100).Error
+	})
+	return result
+}
+
+// Import 导入收入记录
+// @Summary 从 CSV 导入收入记录
+// @Description 上传 CSV（列：金额,类型,收入时间,备注；首行为 amount/金额 开头的表头时自动跳过），单次最多 1000 行。收入记录没有备注字段，备注列会被忽略。
+// @Description type_mapping 为 JSON 对象（源类型名 → 本系统收入类别名，源类型名忽略大小写），导入时先按映射转换再校验类型；映射的目标必须已存在，转换后仍不存在的类型该行失败。
+// @Description 收入时间支持 2006-01-02 15:04:05、2006-01-02 15:04 或 2006-01-02。校验通过的行在同一事务中写入，返回批量结果（failures 的 index 为数据行位置，从 0 开始，不含表头）
+// @Tags 收入
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "CSV 文件"
+// @Param type_mapping formData string false "类型映射表（JSON），如 {\"Salary\":\"工资\"}"
+// @Success 200 {object} Response{data=BatchResult} "导入完成"
+// @Failure 400 {object} Response "参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/import [post]
+func (h *IncomeHandler) Import(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequest(c, "请上传 CSV 文件")
+		return
+	}
+	if fileHeader.Size > maxImportFileSize {
+		BadRequest(c, "文件不能超过 2MB")
+		return
+	}
+	mapping, err := parseCategoryMapping("type_mapping", c.PostForm("type_mapping"))
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	types, err := loadIncomeCategoryNames()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询收入类别失败"))
+		return
+	}
+	if err := validateMappingTargets("type_mapping", mapping, types); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		BadRequest(c, "读取文件失败")
+		return
+	}
+	defer file.Close()
+
+	rows, err := parseImportCSV(file)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	SuccessWithMessage(c, "导入完成", importIncomeRows(userID, recordSourceFrom(c), rows, mapping, types))
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"testing"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportIncomeRows(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	mapping, err := parseCategoryMapping("type_mapping", `{"Salary":"工资"}`)
+	require.NoError(t, err)
+	types := map[string]bool{"工资": true, "奖金": true}
+	rows := [][]string{
+		{"8,000.00", "salary", "2024-03-01", "三月工资"},
+		{"500", "奖金", "2024-03-02 10:00:00"},
+		{"100", "理财", "2024-03-03"},
+		{"abc", "奖金", "2024-03-03"},
+		{"100", "奖金", "2024/03/03"},
+		{"100", "", "2024-03-03"},
+	}
+	result := importIncomeRows(1, models.RecordSource{}, rows, mapping, types)
+
+	assert.Equal(t, 6, result.Total)
+	assert.Equal(t, 2, result.SuccessCount)
+	require.Len(t, result.Failures, 4)
+	assert.Equal(t, 2, *result.Failures[0].Index)
+	assert.Equal(t, "理财", result.Failures[0].Key)
+	assert.Contains(t, result.Failures[0].Reason, "收入类型不存在")
+	assert.Equal(t, "金额格式错误", result.Failures[1].Reason)
+	assert.Contains(t, result.Failures[2].Reason, "收入时间格式错误")
+	assert.Equal(t, "收入类型不能为空", result.Failures[3].Reason)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestImportIncomeRows_AllInvalidSkipsWrite(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	result := importIncomeRows(1, models.RecordSource{}, [][]string{{"100", "理财", "2024-03-03"}}, map[string]categoryMappingEntry{}, map[string]bool{"工资": true})
+	assert.Equal(t, 0, result.SuccessCount)
+	assert.Len(t, result.Failures, 1)
+}
@@ -0,0 +1,233 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// IncomeTypeShare 按收入类型统计的收入及占比
+type IncomeTypeShare struct {
+	Type       string  `json:"type"`
+	Total      float64 `json:"total"`      // 折合本位币金额
+	Count      int64   `json:"count"`      // 记录数
+	Percentage float64 `json:"percentage"` // 占总金额的百分比
+}
+
+// IncomeStatisticsResponse 收入统计结果，金额均已按汇率折算为本位币
+type IncomeStatisticsResponse struct {
+	RangeType             string            `json:"range_type,omitempty"` // 仅详细统计返回
+	StartTime             string            `json:"start_time"`
+	EndTime               string            `json:"end_time"`
+	BaseCurrency          string            `json:"base_currency"`
+	TotalAmount           float64           `json:"total_amount"`
+	TotalCount            int64             `json:"total_count"`
+	TypeStats             []IncomeTypeShare `json:"type_stats"`
+	CurrencyTotals        []CurrencyTotal   `json:"currency_totals"`
+	UnconvertedCurrencies []string          `json:"unconverted_currencies"`
+}
+
+// queryIncomeStatistics 在 query 已有的筛选条件上按收入类型与币种汇总，折算为本位币后计算总金额、记录数与各类型占比
+func queryIncomeStatistics(query *gorm.DB) (IncomeStatisticsResponse, error) {
+	summary, err := queryCurrencySummary(query, "type")
+	if err != nil {
+		return IncomeStatisticsResponse{}, err
+	}
+	shares := withCategoryPercentage(summary.Categories, summary.Total)
+	types := make([]IncomeTypeShare, len(shares))
+	for i, s := range shares {
+		types[i] = IncomeTypeShare{Type: s.Category, Total: s.Total, Count: s.Count, Percentage: s.Percentage}
+	}
+	unconverted := summary.Unconverted
+	if unconverted == nil {
+		unconverted = []string{}
+	}
+	return IncomeStatisticsResponse{
+		BaseCurrency:          baseCurrency(),
+		TotalAmount:           summary.Total,
+		TotalCount:            summary.Count,
+		TypeStats:             types,
+		CurrencyTotals:        summary.Currencies,
+		UnconvertedCurrencies: unconverted,
+	}, nil
+}
+
+// incomeDetailedQuery 详细统计的公共筛选：时间范围与收入类型（types，逗号分隔）
+func incomeDetailedQuery(c *gin.Context, query *gorm.DB, r statisticsRange) *gorm.DB {
+	query = query.Where("income_time >= ? AND income_time <= ?", r.Start, r.End)
+	if types := splitQueryList(c.Query("types")); len(types) > 0 {
+		query = query.Where("type IN ?", types)
+	}
+	return query
+}
+
+// GetStatistics 获取收入统计
+// @Summary 获取收入统计
+// @Description 获取当前用户（或当前共享账本）的收入总金额、记录数及按收入类型的统计与占比。金额按汇率折算为本位币后合计，
+// @Description currency_totals 给出各币种原币合计，缺少汇率的币种不计入合计并列在 unconverted_currencies 中。
+// @Description 不传 period/start_time/end_time 时默认统计本月（1 日至今天，按 tz 计算），响应中的 start_time/end_time 回显实际使用的范围
+// @Tags 收入
+// @Produce json
+// @Security BearerAuth
+// @Param start_time query string false "开始时间 (2024-01-01)"
+// @Param end_time query string false "结束时间 (2024-12-31)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，用于计算默认的本月范围与 period，默认服务器时区"
+// @Success 200 {object} Response{data=IncomeStatisticsResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/statistics [get]
+func (h *IncomeHandler) GetStatistics(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	startTimeStr, endTimeStr, err := resolveUserPeriodQuery(userID, c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	startTimeStr, endTimeStr = defaultToThisMonth(startTimeStr, endTimeStr, now)
+
+	query := database.DB.Model(&models.Income{}).Scopes(recordScope(c, userID))
+	if startTimeStr != "" {
+		startTime, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local)
+		if err != nil {
+			BadRequest(c, "start_time格式错误，应为：2024-01-01")
+			return
+		}
+		query = query.Where("income_time >= ?", startTime)
+	}
+	if endTimeStr != "" {
+		endTime, err := time.ParseInLocation("2006-01-02", endTimeStr, time.Local)
+		if err != nil {
+			BadRequest(c, "end_time格式错误，应为：2024-12-31")
+			return
+		}
+		query = query.Where("income_time <= ?", endTime.Add(24*time.Hour-time.Second))
+	}
+
+	stats, err := queryIncomeStatistics(query)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	stats.StartTime = startTimeStr
+	stats.EndTime = endTimeStr
+	Success(c, stats)
+}
+
+// GetDetailedStatistics 获取详细收入统计（支持月/年/自定义时间范围和多个收入类型筛选）
+// @Summary 获取详细收入统计
+// @Description 按月、按年或自定义时间范围统计当前用户（或当前共享账本）的收入，返回总金额、总记录数及按收入类型的统计与占比，适合绘制饼图和柱状图。
+// @Description 时间范围参数与消费详细统计一致（不传 range_type 时按月）：month 使用 year_month（默认本月），year 使用 year（默认今年），custom 使用 start_time 和 end_time（需同时传入，都不传时为本月 1 日至今天）。
+// @Description 本月/今年按 tz 参数所指时区计算；响应中的 start_time、end_time 为实际使用的范围
+// @Tags 收入
+// @Produce json
+// @Security BearerAuth
+// @Param range_type query string false "时间范围类型：month（月，默认）/year（年）/custom（自定义）" Enums(month,year,custom)
+// @Param year_month query string false "年月（range_type=month时使用，格式：2024-01），默认本月"
+// @Param year query string false "年份（range_type=year时使用，格式：2024），默认今年"
+// @Param start_time query string false "开始时间（range_type=custom时使用，格式：2024-01-01）"
+// @Param end_time query string false "结束时间（range_type=custom时使用，格式：2024-12-31）"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，用于计算默认的本月/今年，默认服务器时区"
+// @Param types query string false "收入类型筛选，多个类型用逗号分隔（如：工资,奖金）"
+// @Success 200 {object} Response{data=IncomeStatisticsResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/detailed-statistics [get]
+func (h *IncomeHandler) GetDetailedStatistics(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	r, err := parseStatisticsRange(c, now)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	query := database.DB.Model(&models.Income{}).Scopes(recordScope(c, userID))
+	stats, err := queryIncomeStatistics(incomeDetailedQuery(c, query, r))
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	stats.RangeType = r.Type
+	stats.StartTime = models.FormatTime(r.Start)
+	stats.EndTime = models.FormatTime(r.End)
+	Success(c, stats)
+}
+
+// GetIncomeDetailedStatistics 获取详细收入统计（后台）
+// @Summary 获取详细收入统计
+// @Description 按月、按年或自定义时间范围统计收入，支持多个收入类型筛选。管理员可按用户ID筛选，非管理员只能查看自己的数据。
+// @Description 金额按汇率折算为本位币后合计，currency_totals 给出各币种原币合计，缺少汇率的币种不计入合计并列在 unconverted_currencies 中。
+// @Tags 后台管理-统计
+// @Produce json
+// @Param range_type query string false "时间范围类型：month(按月，默认)、year(按年)、custom(自定义)"
+// @Param year_month query string false "range_type=month时使用，格式：2024-01，默认本月"
+// @Param year query string false "range_type=year时使用，格式：2024，默认今年"
+// @Param start_time query string false "range_type=custom时使用，格式：2024-01-01；与end_time都不传时默认本月1日至今天"
+// @Param end_time query string false "range_type=custom时使用，格式：2024-12-31"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param types query string false "收入类型筛选，多个类型用逗号分隔，如：工资,奖金"
+// @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Success 200 {object} map[string]interface{} "获取成功，data 为 IncomeStatisticsResponse"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/incomes/detailed-statistics [get]
+func (h *AdminHandler) GetIncomeDetailedStatistics(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	r, err := parseStatisticsRange(c, now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	query := database.DB.Model(&models.Income{})
+	// 权限过滤：非管理员只能看自己的数据，管理员可按用户ID筛选
+	if !currentUser.IsAdmin {
+		query = query.Where("user_id = ?", currentUser.ID)
+	} else if userIDFilter := c.Query("user_id"); userIDFilter != "" {
+		uid, err := strconv.ParseUint(userIDFilter, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的用户ID"})
+			return
+		}
+		query = query.Where("user_id = ?", uint(uid))
+	}
+
+	stats, err := queryIncomeStatistics(incomeDetailedQuery(c, query, r))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	stats.RangeType = r.Type
+	stats.StartTime = models.FormatTime(r.Start)
+	stats.EndTime = models.FormatTime(r.End)
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": stats})
+}
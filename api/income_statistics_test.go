@@ -0,0 +1,80 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomeHandler_GetStatistics(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT type AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `incomes` WHERE income_time >= \\? AND income_time <= \\? AND user_id = \\?.* GROUP BY type, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("工资", "CNY", 9000, 1).AddRow("奖金", "USD", 100, 1))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}).AddRow(1, "USD", 10))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/statistics", NewIncomeHandler().GetStatistics)
+
+	req := httptest.NewRequest("GET", "/incomes/statistics?start_time=2024-01-01&end_time=2024-01-31", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data IncomeStatisticsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "2024-01-01", resp.Data.StartTime)
+	assert.Equal(t, "2024-01-31", resp.Data.EndTime)
+	assert.Equal(t, 10000.0, resp.Data.TotalAmount)
+	assert.Equal(t, int64(2), resp.Data.TotalCount)
+	require.Len(t, resp.Data.TypeStats, 2)
+	assert.Equal(t, IncomeTypeShare{Type: "工资", Total: 9000, Count: 1, Percentage: 90}, resp.Data.TypeStats[0])
+	assert.Equal(t, "奖金", resp.Data.TypeStats[1].Type)
+	assert.Empty(t, resp.Data.UnconvertedCurrencies)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_GetDetailedStatistics(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT type AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `incomes` WHERE .*income_time >= \\? AND income_time <= \\?.* AND type IN \\(\\?,\\?\\).* GROUP BY type, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).AddRow("工资", "CNY", 8000, 1))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/detailed-statistics", NewIncomeHandler().GetDetailedStatistics)
+
+	req := httptest.NewRequest("GET", "/incomes/detailed-statistics?range_type=year&year=2024&types=工资,奖金", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data IncomeStatisticsResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, RangeTypeYear, resp.Data.RangeType)
+	assert.Equal(t, 8000.0, resp.Data.TotalAmount)
+	require.Len(t, resp.Data.TypeStats, 1)
+	assert.Equal(t, 100.0, resp.Data.TypeStats[0].Percentage)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	req = httptest.NewRequest("GET", "/incomes/detailed-statistics?range_type=week", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
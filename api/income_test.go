@@ -7,6 +7,8 @@ import (
 	"testing"
 	"time"
 
+	"finance/models"
+
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -58,3 +60,109 @@ func TestIncomeHandler_GetIncomeCategories(t *testing.T) {
 	assert.Equal(t, 200, w.Code)
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestIncomeHandler_Create_AmountTooLarge(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/incomes", NewIncomeHandler().Create)
+
+	body := `{"amount":100000000000,"type":"工资","income_time":"2024-01-15 09:00:00"}`
+	req := httptest.NewRequest("POST", "/incomes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "金额不能超过")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Update_AmountTooLarge(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `incomes`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "income_time"}).
+			AddRow(1, 1, 5000, "工资", time.Now()))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.PUT("/incomes/:id", NewIncomeHandler().Update)
+
+	req := httptest.NewRequest("PUT", "/incomes/1", bytes.NewBufferString(`{"amount":100000000000}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Create_TimeOutOfRange(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/incomes", NewIncomeHandler().Create)
+
+	for _, incomeTime := range []string{"1999-12-31 23:59:59", time.Now().AddDate(0, 0, 2).Format("2006-01-02 15:04:05")} {
+		body := `{"amount":5000,"type":"工资","income_time":"` + incomeTime + `"}`
+		req := httptest.NewRequest("POST", "/incomes", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, 400, w.Code, incomeTime)
+		assert.Contains(t, w.Body.String(), "收入时间不能")
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Create_TrimsType(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `incomes`").
+		// user_id, amount, currency, type, income_time, ledger_id, created_by, created_at, updated_at, deleted_at, admin_note, source_ip, user_agent
+		WithArgs(1, 5000.0, "CNY", "工资", sqlmock.AnyArg(), nil, 1, sqlmock.AnyArg(), sqlmock.AnyArg(), nil, "", "192.0.2.1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/incomes", NewIncomeHandler().Create)
+
+	body := `{"amount":5000,"type":" 工资 ","income_time":"2024-01-15 09:00:00"}`
+	req := httptest.NewRequest("POST", "/incomes", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecordWithUser_ProxyEntry(t *testing.T) {
+	assert.False(t, isProxyEntry(3, 3))
+	assert.False(t, isProxyEntry(3, 0), "历史数据录入人未知时按本人录入处理")
+	assert.True(t, isProxyEntry(3, 1))
+
+	raw, err := json.Marshal(IncomeWithUser{Income: models.Income{ID: 1, UserID: 3, CreatedBy: 1}, Username: "alice", CreatedByName: "admin"})
+	require.NoError(t, err)
+	var got map[string]interface{}
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, true, got["proxy_entry"])
+	assert.Equal(t, "admin", got["created_by_name"])
+	assert.Equal(t, float64(1), got["created_by"])
+
+	raw, err = json.Marshal(ExpenseWithUser{Expense: models.Expense{ID: 2, UserID: 3, CreatedBy: 3}, Username: "alice", CreatedByName: "alice"})
+	require.NoError(t, err)
+	got = nil
+	require.NoError(t, json.Unmarshal(raw, &got))
+	assert.Equal(t, false, got["proxy_entry"])
+}
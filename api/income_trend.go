@@ -0,0 +1,87 @@
+package api
+
+import (
+	"finance/database"
+	"finance/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Trend 收入趋势
+// @Summary 收入趋势
+// @Description 按日/周/月/年汇总当前用户（或当前共享账本）的收入金额与笔数，返回连续的时间段序列，无收入的时间段金额为 0。
+// @Description 不传时间范围时默认截至今天的最近 12 个时间段；week 粒度的一周起始日跟随用户设置。首尾时间段只统计落在范围内的记录
+// @Tags 收入
+// @Produce json
+// @Security BearerAuth
+// @Param granularity query string false "时间粒度：day/week/month/year" default(month)
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，需与 end_time 同时提供"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param type query string false "收入类型"
+// @Success 200 {object} Response{data=RecordTrendResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/trend [get]
+func (h *IncomeHandler) Trend(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	q, err := parseTrendQuery(c, userID, "type")
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	db := database.DB.Scopes(recordScope(c, userID))
+	if q.Category != "" {
+		db = db.Where(incomeRecordSource.CategoryColumn+" = ?", q.Category)
+	}
+	buckets, unconverted, err := aggregateRecords(db, incomeRecordSource, q.Start, q.End, q.Granularity, q.WeekStart)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	resp := buildTrend(q, buckets)
+	resp.UnconvertedCurrencies = unconverted
+	Success(c, resp)
+}
+
+// Compare 收入同比/环比
+// @Summary 收入同比/环比
+// @Description 返回指定月份（或年份）的收入与上一周期（环比）、去年同期（同比）的对比，按完整自然月/年统计。
+// @Description 基期金额为 0 时变化率为 null；year 粒度下上一周期即去年，只返回环比
+// @Tags 收入
+// @Produce json
+// @Security BearerAuth
+// @Param granularity query string false "对比粒度：month/year" default(month)
+// @Param date query string false "对比的周期，month 粒度为 YYYY-MM，year 粒度为 YYYY，默认当前周期"
+// @Param type query string false "收入类型"
+// @Success 200 {object} Response{data=RecordCompareResponse} "获取成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/incomes/compare [get]
+func (h *IncomeHandler) Compare(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	q, err := parseCompareQuery(c, "type")
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	db := database.DB.Scopes(recordScope(c, userID))
+	if q.Category != "" {
+		db = db.Where(incomeRecordSource.CategoryColumn+" = ?", q.Category)
+	}
+	start, end := compareRange(q)
+	buckets, unconverted, err := aggregateRecords(db, incomeRecordSource, start, end, q.Granularity, 0)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	resp := buildCompare(q, buckets)
+	resp.UnconvertedCurrencies = unconverted
+	Success(c, resp)
+}
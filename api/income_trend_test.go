@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIncomeHandler_Trend(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT income_time AS record_time, amount, currency FROM `incomes`").
+		WithArgs("工资", sqlmock.AnyArg(), sqlmock.AnyArg(), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"record_time", "amount", "currency"}).
+			AddRow(time.Date(2024, 1, 10, 9, 0, 0, 0, time.Local), 5000, "CNY").
+			AddRow(time.Date(2024, 3, 10, 9, 0, 0, 0, time.Local), 5500, "CNY").
+			AddRow(time.Date(2024, 3, 20, 9, 0, 0, 0, time.Local), 100, "USD").
+			AddRow(time.Date(2024, 3, 25, 9, 0, 0, 0, time.Local), 9000, "JPY"))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/trend", NewIncomeHandler().Trend)
+
+	req := httptest.NewRequest("GET", "/incomes/trend?granularity=month&start_time=2024-01-01&end_time=2024-03-31&type=%E5%B7%A5%E8%B5%84", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data RecordTrendResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Items, 3)
+	assert.Equal(t, 5000.0, resp.Data.Items[0].Amount)
+	assert.Equal(t, 0.0, resp.Data.Items[1].Amount)
+	assert.Equal(t, 6200.0, resp.Data.Items[2].Amount)
+	assert.EqualValues(t, 3, resp.Data.Items[2].Count)
+	assert.Equal(t, 11200.0, resp.Data.Total)
+	assert.Equal(t, []string{"JPY"}, resp.Data.UnconvertedCurrencies)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Trend_InvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/trend", NewIncomeHandler().Trend)
+
+	for _, q := range []string{
+		"granularity=hour",
+		"start_time=2024-01-01",
+		"start_time=2024-03-01&end_time=2024-01-01",
+		"granularity=day&start_time=2020-01-01&end_time=2024-01-01",
+	} {
+		req := httptest.NewRequest("GET", "/incomes/trend?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, q)
+	}
+}
+
+func TestIncomeHandler_Compare(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT income_time AS record_time, amount, currency FROM `incomes`").
+		WithArgs(time.Date(2023, 3, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 31, 23, 59, 59, 0, time.Local), 2).
+		WillReturnRows(sqlmock.NewRows([]string{"record_time", "amount", "currency"}).
+			AddRow(time.Date(2023, 3, 5, 9, 0, 0, 0, time.Local), 4000, "CNY").
+			AddRow(time.Date(2024, 2, 5, 9, 0, 0, 0, time.Local), 5000, "CNY").
+			AddRow(time.Date(2024, 3, 5, 9, 0, 0, 0, time.Local), 4500, ""))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(2))
+	router.GET("/incomes/compare", NewIncomeHandler().Compare)
+
+	req := httptest.NewRequest("GET", "/incomes/compare?date=2024-03", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data RecordCompareResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 4500.0, resp.Data.Current.Amount)
+	assert.Equal(t, 5000.0, resp.Data.Previous.Amount)
+	require.NotNil(t, resp.Data.MoMChangePercent)
+	assert.Equal(t, -10.0, *resp.Data.MoMChangePercent)
+	require.NotNil(t, resp.Data.YoYChangePercent)
+	assert.Equal(t, 12.5, *resp.Data.YoYChangePercent)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIncomeHandler_Compare_InvalidDate(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/incomes/compare", NewIncomeHandler().Compare)
+
+	for _, q := range []string{"granularity=week", "date=2024-13", "granularity=year&date=2024-01"} {
+		req := httptest.NewRequest("GET", "/incomes/compare?"+q, nil)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, q)
+	}
+}
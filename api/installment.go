@@ -0,0 +1,106 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// splitInstallments 将总额按期数均分（按分计算），除不尽的尾差计入最后一期
+func splitInstallments(total float64, n int) []float64 {
+	cents := int64(math.Round(total * 100))
+	per := cents / int64(n)
+	amounts := make([]float64, n)
+	for i := 0; i < n-1; i++ {
+		amounts[i] = float64(per) / 100
+	}
+	amounts[n-1] = float64(cents-per*int64(n-1)) / 100
+	return amounts
+}
+
+// installmentDescription 第 i/n 期的描述：原描述后追加分期标记
+func installmentDescription(desc string, i, n int) string {
+	return fmt.Sprintf("%s（分期 %d/%d）", desc, i, n)
+}
+
+// validateInstallments 校验分期参数：每期至少 0.01，且追加分期标记后的描述不超长
+func validateInstallments(total float64, desc string, n int) error {
+	if int64(math.Round(total*100)) < int64(n) {
+		return fmt.Errorf("分期总额过小，%d 期每期至少 0.01", n)
+	}
+	// 期号位数最多的是最后一期
+	return validateDescription(installmentDescription(desc, n, n))
+}
+
+// addMonthsClamped 加上若干个月；目标月份没有该日时取当月最后一天（如 1-31 的下一期为 2-28/29）
+func addMonthsClamped(t time.Time, months int) time.Time {
+	first := time.Date(t.Year(), t.Month()+time.Month(months), 1, t.Hour(), t.Minute(), t.Second(), 0, t.Location())
+	lastDay := first.AddDate(0, 1, -1).Day()
+	day := t.Day()
+	if day > lastDay {
+		day = lastDay
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// buildInstallments 按月生成 n 期消费记录，共享同一个分期组 ID
+func buildInstallments(base models.Expense, n int, first time.Time) ([]models.Expense, error) {
+	groupID, err := models.NewInstallmentGroupID()
+	if err != nil {
+		return nil, err
+	}
+
+	amounts := splitInstallments(base.Amount, n)
+	list := make([]models.Expense, n)
+	for i := 0; i < n; i++ {
+		e := base
+		e.Amount = amounts[i]
+		e.ExpenseTime = addMonthsClamped(first, i)
+		e.InstallmentGroupID = &groupID
+		e.InstallmentIndex = i + 1
+		e.InstallmentTotal = n
+		e.Description = installmentDescription(base.Description, i+1, n)
+		list[i] = e
+	}
+	return list, nil
+}
+
+// CancelInstallments 取消分期（删除尚未到期的各期）
+// @Summary 取消分期
+// @Description 删除该分期组中消费时间晚于当前时间的各期，已落账的期数保留
+// @Tags 消费记录
+// @Produce json
+// @Security BearerAuth
+// @Param group_id path string true "分期组ID"
+// @Success 200 {object} Response "取消成功，返回删除的期数"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "分期不存在"
+// @Router /api/v1/expenses/installments/{group_id} [delete]
+func (h *ExpenseHandler) CancelInstallments(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	groupID := c.Param("group_id")
+
+	var count int64
+	database.DB.Model(&models.Expense{}).
+		Where("installment_group_id = ? AND user_id = ?", groupID, userID).
+		Count(&count)
+	if count == 0 {
+		NotFound(c, "分期不存在")
+		return
+	}
+
+	result := models.SoftDeleteExpenses(database.DB.Model(&models.Expense{}).
+		Where("installment_group_id = ? AND user_id = ? AND expense_time > ?", groupID, userID, time.Now()), userID)
+	if result.Error != nil {
+		InternalError(c, SafeErrorMessage(result.Error, "取消分期失败"))
+		return
+	}
+
+	SuccessWithMessage(c, "取消成功", gin.H{"deleted": result.RowsAffected})
+}
@@ -0,0 +1,174 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitInstallments(t *testing.T) {
+	assert.Equal(t, []float64{33.33, 33.33, 33.34}, splitInstallments(100, 3))
+	assert.Equal(t, []float64{500, 500}, splitInstallments(1000, 2))
+
+	// 各期之和必须等于总额
+	amounts := splitInstallments(9999.99, 12)
+	var cents int64
+	for _, a := range amounts {
+		cents += int64(a*100 + 0.5)
+	}
+	assert.Equal(t, int64(999999), cents)
+	assert.Equal(t, 833.33, amounts[0])
+	assert.Equal(t, 833.36, amounts[11])
+}
+
+func TestValidateInstallments(t *testing.T) {
+	assert.NoError(t, validateInstallments(0.12, "手机", 12))
+	assert.Error(t, validateInstallments(0.11, "手机", 12), "不足每期 0.01 时拒绝")
+
+	// 描述本身不超长，但追加"（分期 12/12）"后超过 255 字
+	desc := strings.Repeat("字", 250)
+	assert.NoError(t, validateDescription(desc))
+	assert.Error(t, validateInstallments(100, desc, 12))
+	assert.NoError(t, validateInstallments(100, strings.Repeat("字", 245), 12))
+}
+
+func TestExpenseHandler_Create_InstallmentsTooSmall(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":0.05,"category":"购物","expense_time":"2024-01-15 12:30:00","installments":12}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "分期总额过小")
+}
+
+func TestAddMonthsClamped(t *testing.T) {
+	jan31 := time.Date(2024, 1, 31, 10, 0, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2024, 2, 29, 10, 0, 0, 0, time.Local), addMonthsClamped(jan31, 1))
+	assert.Equal(t, time.Date(2024, 3, 31, 10, 0, 0, 0, time.Local), addMonthsClamped(jan31, 2))
+	assert.Equal(t, time.Date(2025, 1, 31, 10, 0, 0, 0, time.Local), addMonthsClamped(jan31, 12))
+}
+
+func TestBuildInstallments(t *testing.T) {
+	first := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+	list, err := buildInstallments(models.Expense{UserID: 1, Amount: 100, Category: "购物", Description: "手机"}, 3, first)
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+
+	for i, e := range list {
+		assert.Equal(t, i+1, e.InstallmentIndex)
+		assert.Equal(t, 3, e.InstallmentTotal)
+		require.NotNil(t, e.InstallmentGroupID)
+		assert.Equal(t, *list[0].InstallmentGroupID, *e.InstallmentGroupID)
+		assert.Equal(t, time.Month(i+1), e.ExpenseTime.Month())
+	}
+	assert.Equal(t, 33.34, list[2].Amount)
+	assert.Contains(t, list[0].Description, "1/3")
+}
+
+func TestExpenseHandler_Create_Installments(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("购物").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sort", "color", "created_at", "updated_at", "deleted_at"}).
+			AddRow(1, "购物", 10, "#ef4444", time.Now(), time.Now(), nil))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 3))
+	mock.ExpectCommit()
+	// 首期所在月份无预算
+	mock.ExpectQuery("SELECT .* FROM `budgets`").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":100,"category":"购物","expense_time":"2024-01-15 12:30:00","installments":3,"first_installment_date":"2024-02-01"}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			Amount       float64 `json:"amount"`
+			Installments []struct {
+				Amount      float64 `json:"amount"`
+				ExpenseTime string  `json:"expense_time"`
+			} `json:"installments"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data.Installments, 3)
+	assert.Equal(t, 33.33, resp.Data.Amount)
+	assert.Equal(t, 33.34, resp.Data.Installments[2].Amount)
+	assert.Contains(t, resp.Data.Installments[0].ExpenseTime, "2024-02-01T12:30:00")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_InstallmentsFirstDateOutOfRange(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	for _, date := range []string{"1999-12-31", time.Now().AddDate(0, 0, 3).Format("2006-01-02")} {
+		mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+			WithArgs("购物").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "name", "sort", "color", "created_at", "updated_at", "deleted_at"}).
+				AddRow(1, "购物", 10, "#ef4444", time.Now(), time.Now(), nil))
+
+		body := `{"amount":100,"category":"购物","expense_time":"2024-01-15 12:30:00","installments":3,"first_installment_date":"` + date + `"}`
+		req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, 400, w.Code, date)
+		assert.Contains(t, w.Body.String(), "首期日期", date)
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_CancelInstallments_NotFound(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.DELETE("/expenses/installments/:group_id", NewExpenseHandler().CancelInstallments)
+
+	req := httptest.NewRequest("DELETE", "/expenses/installments/abc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
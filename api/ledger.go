@@ -0,0 +1,341 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// LedgerHandler 共享账本处理器（App端）
+type LedgerHandler struct{}
+
+// NewLedgerHandler 创建共享账本处理器
+func NewLedgerHandler() *LedgerHandler {
+	return &LedgerHandler{}
+}
+
+// CreateLedgerRequest 创建账本请求
+type CreateLedgerRequest struct {
+	Name string `json:"name" binding:"required,max=50" example:"我家账本"`
+}
+
+// AddLedgerMemberRequest 邀请成员请求
+type AddLedgerMemberRequest struct {
+	Username string `json:"username" binding:"required" example:"alice"` // 被邀请用户的用户名
+}
+
+// SwitchLedgerRequest 切换当前账本请求
+type SwitchLedgerRequest struct {
+	LedgerID *uint `json:"ledger_id" example:"1"` // 为空时切回个人账本
+}
+
+// LedgerItem 账本列表项
+type LedgerItem struct {
+	models.Ledger
+	Role string `json:"role"` // 当前用户在该账本中的角色
+}
+
+// LedgerMemberItem 账本成员列表项
+type LedgerMemberItem struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// recordScope 消费/收入列表与统计的可见范围：切换到共享账本时按 ledger_id 查看全体成员记的账，
+// 否则按 user_id 只看自己的（包括自己记在共享账本里的）
+func recordScope(c *gin.Context, userID uint) func(*gorm.DB) *gorm.DB {
+	ledgerID := middleware.GetCurrentLedgerID(c)
+	return func(db *gorm.DB) *gorm.DB {
+		if ledgerID > 0 {
+			return db.Where("ledger_id = ?", ledgerID)
+		}
+		return db.Where("user_id = ?", userID)
+	}
+}
+
+// currentLedgerID 新记录所属账本：切换到共享账本时记到该账本，否则为个人账本（NULL）
+func currentLedgerID(c *gin.Context) *uint {
+	if id := middleware.GetCurrentLedgerID(c); id > 0 {
+		return &id
+	}
+	return nil
+}
+
+// findLedgerMember 查询用户在账本中的成员记录
+func findLedgerMember(ledgerID, userID uint) (*models.LedgerMember, error) {
+	var m models.LedgerMember
+	if err := database.DB.Where("ledger_id = ? AND user_id = ?", ledgerID, userID).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// parseLedgerID 解析路径中的账本ID并确认当前用户是成员；失败时已写入响应
+func parseLedgerID(c *gin.Context, userID uint) (uint, *models.LedgerMember, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的账本ID")
+		return 0, nil, false
+	}
+	m, err := findLedgerMember(uint(id), userID)
+	if err != nil {
+		NotFound(c, "账本不存在")
+		return 0, nil, false
+	}
+	return uint(id), m, true
+}
+
+// Create 创建共享账本
+// @Summary 创建共享账本
+// @Description 创建一个共享账本，创建者自动成为 owner
+// @Tags 共享账本
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateLedgerRequest true "账本信息"
+// @Success 200 {object} Response{data=models.Ledger} "创建成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/ledgers [post]
+func (h *LedgerHandler) Create(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req CreateLedgerRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	ledger := models.Ledger{Name: req.Name, OwnerID: userID}
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&ledger).Error; err != nil {
+			return err
+		}
+		return tx.Create(&models.LedgerMember{LedgerID: ledger.ID, UserID: userID, Role: models.LedgerRoleOwner}).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", ledger)
+}
+
+// List 获取我加入的账本
+// @Summary 获取我加入的共享账本
+// @Description 返回当前用户加入的全部账本及其角色，current_ledger_id 为当前使用的账本（0 表示个人账本）
+// @Tags 共享账本
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/ledgers [get]
+func (h *LedgerHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var members []models.LedgerMember
+	if err := database.DB.Where("user_id = ?", userID).Find(&members).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	list := make([]LedgerItem, 0, len(members))
+	if len(members) > 0 {
+		roles := make(map[uint]string, len(members))
+		ids := make([]uint, 0, len(members))
+		for _, m := range members {
+			roles[m.LedgerID] = m.Role
+			ids = append(ids, m.LedgerID)
+		}
+		var ledgers []models.Ledger
+		if err := database.DB.Where("id IN ?", ids).Order("id").Find(&ledgers).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return
+		}
+		for _, l := range ledgers {
+			list = append(list, LedgerItem{Ledger: l, Role: roles[l.ID]})
+		}
+	}
+	Success(c, gin.H{
+		"current_ledger_id": middleware.GetCurrentLedgerID(c),
+		"list":              list,
+	})
+}
+
+// Switch 切换当前账本
+// @Summary 切换当前账本
+// @Description 切换后消费/收入的列表与统计按该账本查看全体成员的记录，新记的账归入该账本；ledger_id 为空时切回个人账本
+// @Tags 共享账本
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body SwitchLedgerRequest true "目标账本"
+// @Success 200 {object} Response "切换成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "账本不存在"
+// @Router /api/v1/ledgers/current [put]
+func (h *LedgerHandler) Switch(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	var req SwitchLedgerRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if req.LedgerID != nil && *req.LedgerID == 0 {
+		req.LedgerID = nil
+	}
+	if req.LedgerID != nil {
+		if _, err := findLedgerMember(*req.LedgerID, userID); err != nil {
+			NotFound(c, "账本不存在")
+			return
+		}
+	}
+	if err := database.DB.Model(&models.User{}).Where("id = ?", userID).
+		Update("current_ledger_id", req.LedgerID).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "切换失败"))
+		return
+	}
+	SuccessWithMessage(c, "切换成功", gin.H{"current_ledger_id": req.LedgerID})
+}
+
+// Members 获取账本成员
+// @Summary 获取账本成员
+// @Description 仅账本成员可查看
+// @Tags 共享账本
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "账本ID"
+// @Success 200 {object} Response{data=[]LedgerMemberItem} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "账本不存在"
+// @Router /api/v1/ledgers/{id}/members [get]
+func (h *LedgerHandler) Members(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	ledgerID, _, ok := parseLedgerID(c, userID)
+	if !ok {
+		return
+	}
+
+	var list []LedgerMemberItem
+	if err := database.DB.Table("ledger_members").
+		Select("ledger_members.user_id, users.username, ledger_members.role").
+		Joins("JOIN users ON users.id = ledger_members.user_id").
+		Where("ledger_members.ledger_id = ?", ledgerID).
+		Order("ledger_members.id").
+		Scan(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// AddMember 邀请成员
+// @Summary 邀请账本成员
+// @Description 仅 owner 可邀请，按用户名添加为 member
+// @Tags 共享账本
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "账本ID"
+// @Param request body AddLedgerMemberRequest true "被邀请用户"
+// @Success 200 {object} Response{data=LedgerMemberItem} "邀请成功"
+// @Failure 400 {object} Response "用户不存在或已是成员"
+// @Failure 401 {object} Response "未授权"
+// @Failure 403 {object} Response "仅 owner 可管理成员"
+// @Failure 404 {object} Response "账本不存在"
+// @Router /api/v1/ledgers/{id}/members [post]
+func (h *LedgerHandler) AddMember(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	ledgerID, me, ok := parseLedgerID(c, userID)
+	if !ok {
+		return
+	}
+	if me.Role != models.LedgerRoleOwner {
+		Error(c, 403, "仅账本创建者可管理成员")
+		return
+	}
+	var req AddLedgerMemberRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	var user models.User
+	if err := database.DB.Select("id", "username").Where("username = ?", req.Username).First(&user).Error; err != nil {
+		BadRequest(c, "用户不存在")
+		return
+	}
+	if _, err := findLedgerMember(ledgerID, user.ID); err == nil {
+		BadRequest(c, "该用户已是账本成员")
+		return
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	member := models.LedgerMember{LedgerID: ledgerID, UserID: user.ID, Role: models.LedgerRoleMember}
+	if err := database.DB.Create(&member).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "邀请失败"))
+		return
+	}
+	SuccessWithMessage(c, "邀请成功", LedgerMemberItem{UserID: user.ID, Username: user.Username, Role: member.Role})
+}
+
+// RemoveMember 移除成员
+// @Summary 移除账本成员
+// @Description owner 可移除其他成员，member 只能移除自己（退出账本）；owner 不能被移除。被移除成员已记的账仍保留在账本中
+// @Tags 共享账本
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "账本ID"
+// @Param user_id path int true "成员用户ID"
+// @Success 200 {object} Response "移除成功"
+// @Failure 400 {object} Response "不能移除 owner"
+// @Failure 401 {object} Response "未授权"
+// @Failure 403 {object} Response "仅 owner 可管理成员"
+// @Failure 404 {object} Response "账本或成员不存在"
+// @Router /api/v1/ledgers/{id}/members/{user_id} [delete]
+func (h *LedgerHandler) RemoveMember(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	ledgerID, me, ok := parseLedgerID(c, userID)
+	if !ok {
+		return
+	}
+	targetID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的用户ID")
+		return
+	}
+	if uint(targetID) != userID && me.Role != models.LedgerRoleOwner {
+		Error(c, 403, "仅账本创建者可管理成员")
+		return
+	}
+	target, err := findLedgerMember(ledgerID, uint(targetID))
+	if err != nil {
+		NotFound(c, "成员不存在")
+		return
+	}
+	if target.Role == models.LedgerRoleOwner {
+		BadRequest(c, "不能移除账本创建者")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(target).Error; err != nil {
+			return err
+		}
+		// 正在使用该账本的成员切回个人账本
+		return tx.Model(&models.User{}).Where("id = ? AND current_ledger_id = ?", target.UserID, ledgerID).
+			Update("current_ledger_id", nil).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "移除失败"))
+		return
+	}
+	SuccessWithMessage(c, "移除成功", nil)
+}
@@ -0,0 +1,245 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// setLedgerMiddleware 模拟 JWTAuth 写入当前用户与当前账本
+func setLedgerMiddleware(userID, ledgerID uint) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set("userID", userID)
+		if ledgerID > 0 {
+			c.Set("ledgerID", ledgerID)
+		}
+		c.Next()
+	}
+}
+
+func ledgerMemberRows(ledgerID, userID uint, role string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "ledger_id", "user_id", "role", "created_at"}).
+		AddRow(1, ledgerID, userID, role, time.Now())
+}
+
+func ledgerRequest(router *gin.Engine, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestLedgerHandler_Create(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 账本与 owner 成员在同一事务中创建
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `ledgers`").WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectExec("INSERT INTO `ledger_members`").
+		WithArgs(5, 1, models.LedgerRoleOwner, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ledgers", NewLedgerHandler().Create)
+
+	w := ledgerRequest(router, "POST", "/ledgers", `{"name":"我家账本"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "我家账本")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_AddMember(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members` WHERE ledger_id = \\? AND user_id = \\?").
+		WithArgs(5, 1).
+		WillReturnRows(ledgerMemberRows(5, 1, models.LedgerRoleOwner))
+	mock.ExpectQuery("SELECT `id`,`username` FROM `users`").
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username"}).AddRow(2, "bob"))
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 2).
+		WillReturnError(gorm.ErrRecordNotFound)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `ledger_members`").
+		WithArgs(5, 2, models.LedgerRoleMember, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/ledgers/:id/members", NewLedgerHandler().AddMember)
+
+	w := ledgerRequest(router, "POST", "/ledgers/5/members", `{"username":"bob"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"role":"member"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_AddMember_NotOwner(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 2).
+		WillReturnRows(ledgerMemberRows(5, 2, models.LedgerRoleMember))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(2))
+	router.POST("/ledgers/:id/members", NewLedgerHandler().AddMember)
+
+	w := ledgerRequest(router, "POST", "/ledgers/5/members", `{"username":"carol"}`)
+	assert.Equal(t, 403, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_AddMember_NotMember(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 非成员看不到账本
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 3).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(3))
+	router.POST("/ledgers/:id/members", NewLedgerHandler().AddMember)
+
+	w := ledgerRequest(router, "POST", "/ledgers/5/members", `{"username":"carol"}`)
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_RemoveMember_Owner(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 1).
+		WillReturnRows(ledgerMemberRows(5, 1, models.LedgerRoleOwner))
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 1).
+		WillReturnRows(ledgerMemberRows(5, 1, models.LedgerRoleOwner))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.DELETE("/ledgers/:id/members/:user_id", NewLedgerHandler().RemoveMember)
+
+	// owner 不能被移除（包括自己退出）
+	w := ledgerRequest(router, "DELETE", "/ledgers/5/members/1", "")
+	assert.Equal(t, 400, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_RemoveMember_Leave(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 2).
+		WillReturnRows(ledgerMemberRows(5, 2, models.LedgerRoleMember))
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(5, 2).
+		WillReturnRows(ledgerMemberRows(5, 2, models.LedgerRoleMember))
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `ledger_members`").WillReturnResult(sqlmock.NewResult(0, 1))
+	// 正在使用该账本时切回个人账本
+	mock.ExpectExec("UPDATE `users` SET `current_ledger_id`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(2))
+	router.DELETE("/ledgers/:id/members/:user_id", NewLedgerHandler().RemoveMember)
+
+	w := ledgerRequest(router, "DELETE", "/ledgers/5/members/2", "")
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLedgerHandler_Switch(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 不是成员不能切换
+	mock.ExpectQuery("SELECT \\* FROM `ledger_members`").
+		WithArgs(9, 1).
+		WillReturnError(gorm.ErrRecordNotFound)
+	// 切回个人账本不需要校验
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `current_ledger_id`=\\?").
+		WithArgs(nil, sqlmock.AnyArg(), 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.PUT("/ledgers/current", NewLedgerHandler().Switch)
+
+	w := ledgerRequest(router, "PUT", "/ledgers/current", `{"ledger_id":9}`)
+	assert.Equal(t, 404, w.Code)
+
+	w = ledgerRequest(router, "PUT", "/ledgers/current", `{"ledger_id":null}`)
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_List_Ledger(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 切换到共享账本后按 ledger_id 查看全体成员的记录
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE ledger_id = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE ledger_id = \\?").
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "ledger_id", "amount", "category", "expense_time"}).
+			AddRow(1, 1, 5, 30.5, "餐饮", time.Now()).
+			AddRow(2, 2, 5, 12, "交通", time.Now()))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags` JOIN tags").
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}))
+
+	router := gin.New()
+	router.Use(setLedgerMiddleware(1, 5))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := ledgerRequest(router, "GET", "/expenses", "")
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"total":2`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Update_LedgerOthersRecord(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 共享账本中成员只能修改自己记的账：他人的记录按 user_id 查不到
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(7, 2).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	router := gin.New()
+	router.Use(setLedgerMiddleware(2, 5))
+	router.PUT("/expenses/:id", NewExpenseHandler().Update)
+
+	w := ledgerRequest(router, "PUT", "/expenses/7", `{"amount":10}`)
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 后台列表按创建时间排序的取值
+const (
+	sortCreatedAtDesc = "created_at_desc"
+	sortCreatedAtAsc  = "created_at_asc"
+)
+
+// createdAtQuery 后台列表通用的创建时间筛选与排序
+type createdAtQuery struct {
+	start *time.Time // 含
+	end   *time.Time // 不含
+	sort  string     // 为空时使用列表原有的默认排序
+}
+
+// parseCreatedAtQuery 从查询参数 created_start/created_end/sort 解析创建时间筛选与排序
+func parseCreatedAtQuery(c *gin.Context) (*createdAtQuery, error) {
+	return parseCreatedAtParams(c.Query("created_start"), c.Query("created_end"), c.Query("sort"))
+}
+
+// parseCreatedAtParams 时间为 2006-01-02 或 2006-01-02 15:04:05（服务器本地时区），
+// 只写日期的 created_end 包含当天；sort 为 created_at_desc/created_at_asc
+func parseCreatedAtParams(startStr, endStr, sort string) (*createdAtQuery, error) {
+	q := &createdAtQuery{}
+	if startStr != "" {
+		t, _, err := parseCreatedAtBound(startStr)
+		if err != nil {
+			return nil, errors.New("created_start 格式错误，应为: 2006-01-02 或 2006-01-02 15:04:05")
+		}
+		q.start = &t
+	}
+	if endStr != "" {
+		t, dateOnly, err := parseCreatedAtBound(endStr)
+		if err != nil {
+			return nil, errors.New("created_end 格式错误，应为: 2006-01-02 或 2006-01-02 15:04:05")
+		}
+		if dateOnly {
+			t = t.AddDate(0, 0, 1)
+		} else {
+			t = t.Add(time.Second)
+		}
+		q.end = &t
+	}
+	if q.start != nil && q.end != nil && !q.start.Before(*q.end) {
+		return nil, errors.New("created_start 不能晚于 created_end")
+	}
+	switch sort {
+	case "", sortCreatedAtDesc, sortCreatedAtAsc:
+		q.sort = sort
+	default:
+		return nil, errors.New("sort 仅支持 created_at_desc/created_at_asc")
+	}
+	return q, nil
+}
+
+// parseCreatedAtBound 解析时间边界，dateOnly 表示只写了日期
+func parseCreatedAtBound(s string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", s, time.Local); err == nil {
+		return t, false, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", s, time.Local)
+	return t, true, err
+}
+
+// Filter 按创建时间筛选；column 为创建时间列名
+func (q *createdAtQuery) Filter(db *gorm.DB, column string) *gorm.DB {
+	if q.start != nil {
+		db = db.Where(column+" >= ?", *q.start)
+	}
+	if q.end != nil {
+		db = db.Where(column+" < ?", *q.end)
+	}
+	return db
+}
+
+// Order 指定了 sort 时按创建时间排序（同一时间按 id 同向排序），否则使用 defaultOrder
+func (q *createdAtQuery) Order(column, defaultOrder string) string {
+	switch q.sort {
+	case sortCreatedAtDesc:
+		return column + " DESC, id DESC"
+	case sortCreatedAtAsc:
+		return column + " ASC, id ASC"
+	}
+	return defaultOrder
+}
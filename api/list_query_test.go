@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCreatedAtParams(t *testing.T) {
+	q, err := parseCreatedAtParams("2024-03-01", "2024-03-31", "")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), *q.start)
+	assert.Equal(t, time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local), *q.end, "只写日期的 created_end 包含当天")
+	assert.Equal(t, "sort ASC, id ASC", q.Order("created_at", "sort ASC, id ASC"))
+
+	q, err = parseCreatedAtParams("", "2024-03-31 12:00:00", sortCreatedAtAsc)
+	require.NoError(t, err)
+	assert.Nil(t, q.start)
+	assert.Equal(t, time.Date(2024, 3, 31, 12, 0, 1, 0, time.Local), *q.end)
+	assert.Equal(t, "created_at ASC, id ASC", q.Order("created_at", "id DESC"))
+
+	for _, tc := range []struct{ start, end, sort string }{
+		{"2024/03/01", "", ""},
+		{"", "yesterday", ""},
+		{"2024-03-02", "2024-03-01", ""},
+		{"2024-03-01 10:00:00", "2024-03-01 09:59:59", ""},
+		{"", "", "name"},
+	} {
+		_, err := parseCreatedAtParams(tc.start, tc.end, tc.sort)
+		assert.Error(t, err, tc)
+	}
+
+	// 起止为同一天
+	_, err = parseCreatedAtParams("2024-03-01", "2024-03-01", "")
+	assert.NoError(t, err)
+}
+
+func TestCategoryHandler_List_CreatedAtFilter(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expense_categories` WHERE created_at >= \\? AND created_at < \\? .*ORDER BY created_at DESC, id DESC").
+		WithArgs(time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), time.Date(2024, 3, 2, 0, 0, 0, 0, time.Local)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "餐饮"))
+
+	router := gin.New()
+	router.GET("/categories", NewCategoryHandler().List)
+
+	req := httptest.NewRequest("GET", "/categories?created_start=2024-03-01&created_end=2024-03-01&sort=created_at_desc", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	req = httptest.NewRequest("GET", "/categories?created_start=bad", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, 400, w.Code)
+}
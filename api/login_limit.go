@@ -0,0 +1,61 @@
+package api
+
+import (
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"finance/config"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// loginLockedMessage 锁定期间的提示，不区分账号是否存在
+const loginLockedMessage = "登录失败次数过多，请稍后再试"
+
+var (
+	loginLimiterOnce sync.Once
+	loginLimiterInst *service.LoginLimiter
+)
+
+// loginLimiter App 与后台登录共用的失败锁定器，次数、窗口与锁定时长取自 login 配置
+func loginLimiter() *service.LoginLimiter {
+	loginLimiterOnce.Do(func() {
+		maxFailures := config.DefaultLoginMaxFailures
+		window := config.DefaultLoginFailureWindowMinutes
+		lockout := config.DefaultLoginLockoutMinutes
+		if cfg := config.GlobalConfig; cfg != nil {
+			if cfg.Login.MaxFailures > 0 {
+				maxFailures = cfg.Login.MaxFailures
+			}
+			if cfg.Login.FailureWindowMinutes > 0 {
+				window = cfg.Login.FailureWindowMinutes
+			}
+			if cfg.Login.LockoutMinutes > 0 {
+				lockout = cfg.Login.LockoutMinutes
+			}
+		}
+		loginLimiterInst = service.NewLoginLimiter(maxFailures, time.Duration(window)*time.Minute, time.Duration(lockout)*time.Minute)
+		loginLimiterInst.StartCleanup(time.Minute)
+	})
+	return loginLimiterInst
+}
+
+// checkLoginLocked 账号或 IP 处于锁定中时设置 Retry-After（秒，向上取整）并返回 true
+func checkLoginLocked(c *gin.Context, username, ip string) bool {
+	remaining, locked := loginLimiter().Locked(username, ip)
+	if !locked {
+		return false
+	}
+	c.Header("Retry-After", strconv.Itoa(int((remaining+time.Second-1)/time.Second)))
+	return true
+}
+
+// recordLoginFailure 记录一次登录失败，触发锁定时写日志（只记在服务端，响应仍为通用的密码错误提示）
+func recordLoginFailure(source, username, ip string) {
+	if lockout, locked := loginLimiter().RecordFailure(username, ip); locked {
+		log.Printf("[安全] %s登录连续失败，账号 %q / IP %s 锁定 %s", source, username, ip, lockout)
+	}
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func postAppLogin(router *gin.Engine, ip, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/login", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = ip + ":12345"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestAuthHandler_Login_LockedAfterFailures(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "debug"}, JWT: config.JWTConfig{Secret: "x"}}
+	config.GlobalConfig = cfg
+	defer func() { config.GlobalConfig = nil }()
+
+	const ip = "10.8.8.1"
+	limiter := loginLimiter()
+	defer limiter.Reset("bruteapp", ip)
+
+	router := gin.New()
+	router.POST("/login", NewAuthHandler(cfg).Login)
+	body := `{"username":"bruteapp","password":"wrong"}`
+
+	// 达到上限前：正常查库，返回通用的 401
+	for i := 0; i < config.DefaultLoginMaxFailures; i++ {
+		mock.ExpectQuery("SELECT .* FROM `users`").
+			WithArgs("bruteapp", "bruteapp").
+			WillReturnError(gorm.ErrRecordNotFound)
+		w := postAppLogin(router, ip, body)
+		assert.Equal(t, 401, w.Code)
+	}
+
+	// 锁定后：直接返回 429 与 Retry-After，不再查库
+	w := postAppLogin(router, ip, body)
+	assert.Equal(t, 429, w.Code)
+	assert.Equal(t, "900", w.Header().Get("Retry-After"))
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, loginLockedMessage, resp["message"])
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_Login_LockedAccountSameResponse(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{Server: config.ServerConfig{Mode: "debug"}, JWT: config.JWTConfig{Secret: "x"}}
+	config.GlobalConfig = cfg
+	defer func() { config.GlobalConfig = nil }()
+
+	const ip = "10.8.8.2"
+	defer loginLimiter().Reset("lockedapp", ip)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("pass"), bcrypt.DefaultCost)
+	router := gin.New()
+	router.POST("/login", NewAuthHandler(cfg).Login)
+
+	// 被管理员锁定的账号，密码对错返回同样的 403，不暴露密码是否正确
+	var bodies []string
+	for _, password := range []string{"wrong", "pass"} {
+		mock.ExpectQuery("SELECT .* FROM `users`").
+			WithArgs("lockedapp", "lockedapp").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "is_admin", "status", "created_at", "updated_at", "deleted_at"}).
+				AddRow(1, "lockedapp", string(hashed), "l@x.com", false, models.UserStatusLocked, time.Now(), time.Now(), nil))
+		w := postAppLogin(router, ip, `{"username":"lockedapp","password":"`+password+`"}`)
+		assert.Equal(t, 403, w.Code)
+		bodies = append(bodies, w.Body.String())
+	}
+	assert.Equal(t, bodies[0], bodies[1])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -6,6 +6,7 @@ import (
 
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -125,8 +126,8 @@ type MenuUpdateRequest struct {
 // Create 创建菜单
 func (h *MenuHandler) Create(c *gin.Context) {
 	var req MenuCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	if req.ParentID > 0 {
@@ -158,8 +159,8 @@ func (h *MenuHandler) Update(c *gin.Context) {
 		return
 	}
 	var req MenuUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var menu models.Menu
@@ -234,6 +235,7 @@ func (h *MenuHandler) Delete(c *gin.Context) {
 	}
 	_ = database.DB.Where("menu_id = ?", id).Delete(&models.MenuAPI{})
 	_ = database.DB.Where("menu_id = ?", id).Delete(&models.RoleMenu{})
+	service.InvalidateRolePermissions()
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
 }
 
@@ -249,8 +251,8 @@ func (h *MenuHandler) AssignAPIs(c *gin.Context) {
 		return
 	}
 	var req MenuAPIsRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var menu models.Menu
@@ -265,5 +267,6 @@ func (h *MenuHandler) AssignAPIs(c *gin.Context) {
 	for _, apiID := range req.APIIDs {
 		_ = database.DB.Create(&models.MenuAPI{MenuID: uint(id), APIID: apiID}).Error
 	}
+	service.InvalidateRolePermissions()
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "绑定成功"})
 }
@@ -0,0 +1,310 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// MerchantHandler 商户字典处理器（App端）
+type MerchantHandler struct{}
+
+// NewMerchantHandler 创建商户字典处理器
+func NewMerchantHandler() *MerchantHandler {
+	return &MerchantHandler{}
+}
+
+// CreateMerchantRequest 创建商户请求
+type CreateMerchantRequest struct {
+	Name            string `json:"name" binding:"required,max=50" example:"星巴克"`
+	DefaultCategory string `json:"default_category" example:"餐饮"` // 可选，创建消费未指定类别时带出
+}
+
+// UpdateMerchantRequest 更新商户请求（只更新传入的字段）
+type UpdateMerchantRequest struct {
+	Name            *string `json:"name" binding:"omitempty,max=50" example:"星巴克"`
+	DefaultCategory *string `json:"default_category" example:"餐饮"` // 传空字符串清除默认类别
+}
+
+// MergeMerchantRequest 合并商户请求
+type MergeMerchantRequest struct {
+	TargetID uint `json:"target_id" binding:"required" example:"1"`
+}
+
+// findMerchant 查找当前用户的商户
+func findMerchant(userID, id uint) (*models.Merchant, error) {
+	var m models.Merchant
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&m).Error; err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// merchantNameTaken 同一用户下是否已有同名商户（按去重键比较，excludeID 为更新时的自身）
+func merchantNameTaken(userID uint, normalized string, excludeID uint) (bool, error) {
+	var count int64
+	err := database.DB.Model(&models.Merchant{}).
+		Where("user_id = ? AND normalized_name = ? AND id <> ?", userID, normalized, excludeID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// validateMerchantCategory 默认类别为空或为已维护的消费类别
+func validateMerchantCategory(category string) error {
+	if category == "" {
+		return nil
+	}
+	var cat models.ExpenseCategory
+	if err := database.DB.Where("name = ?", category).First(&cat).Error; err != nil {
+		return errors.New("无效的消费类别")
+	}
+	return nil
+}
+
+// List 获取商户列表
+// @Summary 获取商户列表
+// @Description 获取当前用户的商户字典，按使用频率（关联的消费笔数）倒序，可按名称模糊搜索
+// @Tags 商户
+// @Produce json
+// @Security BearerAuth
+// @Param keyword query string false "名称关键词"
+// @Success 200 {object} Response{data=[]models.Merchant} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/merchants [get]
+func (h *MerchantHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	query := database.DB.Model(&models.Merchant{}).
+		Select("merchants.*, (SELECT COUNT(*) FROM expenses WHERE expenses.merchant_id = merchants.id AND expenses.deleted_at IS NULL) AS usage_count").
+		Where("merchants.user_id = ?", userID)
+	if kw := strings.TrimSpace(c.Query("keyword")); kw != "" {
+		query = query.Where("merchants.name LIKE ?"+likeEscape(query), "%"+escapeLikeValue(kw)+"%")
+	}
+
+	var list []models.Merchant
+	if err := query.Order("usage_count DESC, merchants.id ASC").Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// Create 创建商户
+// @Summary 创建商户
+// @Description 商户名去掉首尾空白、合并连续空白后保存；忽略大小写和空白后与已有商户同名时返回 400
+// @Tags 商户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateMerchantRequest true "商户信息"
+// @Success 200 {object} Response{data=models.Merchant} "创建成功"
+// @Failure 400 {object} Response "请求参数错误或商户已存在"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/merchants [post]
+func (h *MerchantHandler) Create(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var req CreateMerchantRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	name := models.CleanMerchantName(req.Name)
+	if name == "" {
+		BadRequest(c, "商户名不能为空")
+		return
+	}
+	normalized := models.NormalizeMerchantName(name)
+	taken, err := merchantNameTaken(userID, normalized, 0)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	if taken {
+		BadRequest(c, "商户「"+name+"」已存在")
+		return
+	}
+	if err := validateMerchantCategory(req.DefaultCategory); err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+
+	merchant := models.Merchant{
+		UserID:          userID,
+		Name:            name,
+		NormalizedName:  normalized,
+		DefaultCategory: req.DefaultCategory,
+	}
+	if err := database.DB.Create(&merchant).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建商户失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", merchant)
+}
+
+// Update 更新商户
+// @Summary 更新商户
+// @Tags 商户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "商户ID"
+// @Param request body UpdateMerchantRequest true "商户信息"
+// @Success 200 {object} Response{data=models.Merchant} "更新成功"
+// @Failure 400 {object} Response "请求参数错误或商户名重复"
+// @Failure 404 {object} Response "商户不存在"
+// @Router /api/v1/merchants/{id} [put]
+func (h *MerchantHandler) Update(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	merchant, err := findMerchant(userID, uint(id))
+	if err != nil {
+		NotFound(c, "商户不存在")
+		return
+	}
+	var req UpdateMerchantRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != nil {
+		name := models.CleanMerchantName(*req.Name)
+		if name == "" {
+			BadRequest(c, "商户名不能为空")
+			return
+		}
+		normalized := models.NormalizeMerchantName(name)
+		taken, err := merchantNameTaken(userID, normalized, merchant.ID)
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "查询失败"))
+			return
+		}
+		if taken {
+			BadRequest(c, "商户「"+name+"」已存在")
+			return
+		}
+		updates["name"] = name
+		updates["normalized_name"] = normalized
+	}
+	if req.DefaultCategory != nil {
+		if err := validateMerchantCategory(*req.DefaultCategory); err != nil {
+			BadRequest(c, err.Error())
+			return
+		}
+		updates["default_category"] = *req.DefaultCategory
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(merchant).Updates(updates).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "更新失败"))
+			return
+		}
+	}
+	database.DB.First(merchant, merchant.ID)
+	SuccessWithMessage(c, "更新成功", merchant)
+}
+
+// Delete 删除商户
+// @Summary 删除商户
+// @Description 删除商户并解除其与消费记录（含已删除的记录）的关联，消费记录本身保留
+// @Tags 商户
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "商户ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 404 {object} Response "商户不存在"
+// @Router /api/v1/merchants/{id} [delete]
+func (h *MerchantHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	merchant, err := findMerchant(userID, uint(id))
+	if err != nil {
+		NotFound(c, "商户不存在")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Expense{}).
+			Where("user_id = ? AND merchant_id = ?", userID, merchant.ID).
+			Update("merchant_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(merchant).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Merge 合并商户
+// @Summary 合并商户
+// @Description 将该商户（如"星巴克咖啡"）的消费记录全部改挂到目标商户（如"星巴克"）后删除该商户，用于清理重复商户
+// @Tags 商户
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "被合并的商户ID"
+// @Param request body MergeMerchantRequest true "目标商户"
+// @Success 200 {object} Response{data=models.Merchant} "合并成功，返回目标商户"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 404 {object} Response "商户不存在"
+// @Router /api/v1/merchants/{id}/merge [post]
+func (h *MerchantHandler) Merge(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var req MergeMerchantRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if req.TargetID == uint(id) {
+		BadRequest(c, "不能合并到自身")
+		return
+	}
+	source, err := findMerchant(userID, uint(id))
+	if err != nil {
+		NotFound(c, "商户不存在")
+		return
+	}
+	target, err := findMerchant(userID, req.TargetID)
+	if err != nil {
+		NotFound(c, "目标商户不存在")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.Expense{}).
+			Where("user_id = ? AND merchant_id = ?", userID, source.ID).
+			Update("merchant_id", target.ID).Error; err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "合并失败"))
+		return
+	}
+	SuccessWithMessage(c, "合并成功", target)
+}
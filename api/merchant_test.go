@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerchantHandler_Create_Duplicate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 忽略大小写与空白后同名
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `merchants` WHERE \\(user_id = \\? AND normalized_name = \\? AND id <> \\?\\)").
+		WithArgs(1, "starbucks", 0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/merchants", NewMerchantHandler().Create)
+
+	req := httptest.NewRequest("POST", "/merchants", bytes.NewBufferString(`{"name":" Star  Bucks "}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "Star Bucks")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerchantHandler_Create(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `merchants`").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "餐饮"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `merchants`").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/merchants", NewMerchantHandler().Create)
+
+	req := httptest.NewRequest("POST", "/merchants", bytes.NewBufferString(`{"name":"星巴克","default_category":"餐饮"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "星巴克", resp.Data["name"])
+	assert.NotContains(t, resp.Data, "normalized_name")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerchantHandler_List_OrderByUsage(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT merchants\\.\\*, \\(SELECT COUNT\\(\\*\\) FROM expenses WHERE expenses.merchant_id = merchants.id AND expenses.deleted_at IS NULL\\) AS usage_count FROM `merchants` WHERE merchants.user_id = \\? AND merchants.name LIKE \\? .*ORDER BY usage_count DESC").
+		WithArgs(1, "%星%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "default_category", "usage_count"}).
+			AddRow(2, 1, "星巴克", "餐饮", 12).
+			AddRow(5, 1, "星巴克咖啡", "餐饮", 1))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/merchants", NewMerchantHandler().List)
+
+	req := httptest.NewRequest("GET", "/merchants?keyword=星", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data []map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Data, 2)
+	assert.Equal(t, float64(12), resp.Data[0]["usage_count"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerchantHandler_Delete_ClearsExpenses(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `merchants` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(5, 1, "星巴克咖啡"))
+	mock.ExpectBegin()
+	// 含已软删除的消费记录一并解除关联
+	mock.ExpectExec("UPDATE `expenses` SET `merchant_id`=\\?,`updated_at`=\\? WHERE user_id = \\? AND merchant_id = \\?$").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("UPDATE `merchants` SET `deleted_at`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.DELETE("/merchants/:id", NewMerchantHandler().Delete)
+
+	req := httptest.NewRequest("DELETE", "/merchants/5", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMerchantHandler_Merge(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `merchants`").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(5, 1, "星巴克咖啡"))
+	mock.ExpectQuery("SELECT \\* FROM `merchants`").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(2, 1, "星巴克"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expenses` SET `merchant_id`=\\?").
+		WithArgs(2, sqlmock.AnyArg(), 1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("UPDATE `merchants` SET `deleted_at`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/merchants/:id/merge", NewMerchantHandler().Merge)
+
+	req := httptest.NewRequest("POST", "/merchants/5/merge", bytes.NewBufferString(`{"target_id":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"星巴克"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// 不能合并到自身
+	req2 := httptest.NewRequest("POST", "/merchants/5/merge", bytes.NewBufferString(`{"target_id":5}`))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, 400, w2.Code)
+}
+
+func TestExpenseHandler_Create_MerchantDefaultCategory(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `merchants`").
+		WithArgs(2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "default_category"}).AddRow(2, 1, "星巴克", "餐饮"))
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("餐饮").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "created_at"}).AddRow(1, "餐饮", time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":32,"expense_time":"2024-01-15 08:30:00","merchant_id":2}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "餐饮", resp.Data["category"])
+	assert.Equal(t, float64(2), resp.Data["merchant_id"])
+}
@@ -0,0 +1,228 @@
+package api
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 快捷时间范围（period 参数）
+const (
+	PeriodToday     = "today"
+	PeriodThisWeek  = "this_week"
+	PeriodThisMonth = "this_month"
+	PeriodLastMonth = "last_month"
+	PeriodThisYear  = "this_year"
+	PeriodLast7d    = "last_7d"
+	PeriodLast30d   = "last_30d"
+)
+
+// weekStartDay 将周起始日配置（mon/sun）转换为 time.Weekday，无法识别时按周一处理
+func weekStartDay(ws string) time.Weekday {
+	if ws == models.WeekStartSunday {
+		return time.Sunday
+	}
+	return time.Monday
+}
+
+// defaultWeekStart 系统配置的周起始日（stats.week_start），默认周一
+func defaultWeekStart() time.Weekday {
+	if config.GlobalConfig == nil {
+		return time.Monday
+	}
+	return weekStartDay(config.GlobalConfig.Stats.WeekStart)
+}
+
+// userWeekStart 用户偏好的周起始日，未设置时跟随系统配置
+func userWeekStart(userID uint) time.Weekday {
+	var ws string
+	if err := database.DB.Model(&models.User{}).Select("week_start").
+		Where("id = ?", userID).Scan(&ws).Error; err != nil || ws == models.WeekStartDefault {
+		return defaultWeekStart()
+	}
+	return weekStartDay(ws)
+}
+
+// periodRange 将快捷时间参数解析为起止日期（均为当天零点，结束日期包含在范围内）。
+// now 的时区即为解析所用时区，weekStart 为 this_week 所用的一周第一天。
+func periodRange(period string, now time.Time, weekStart time.Weekday) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case PeriodToday:
+		return today, today, nil
+	case PeriodThisWeek:
+		offset := (int(today.Weekday()) - int(weekStart) + 7) % 7
+		return today.AddDate(0, 0, -offset), today, nil
+	case PeriodThisMonth:
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		return first, today, nil
+	case PeriodLastMonth:
+		first := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, today.Location())
+		return first.AddDate(0, -1, 0), first.AddDate(0, 0, -1), nil
+	case PeriodThisYear:
+		return time.Date(today.Year(), 1, 1, 0, 0, 0, 0, today.Location()), today, nil
+	case PeriodLast7d:
+		return today.AddDate(0, 0, -6), today, nil
+	case PeriodLast30d:
+		return today.AddDate(0, 0, -29), today, nil
+	}
+	return time.Time{}, time.Time{}, errors.New("无效的 period 参数，可选值: today/this_week/this_month/last_month/this_year/last_7d/last_30d")
+}
+
+// resolvePeriodQuery 处理 period 快捷参数，this_week 按系统配置的周起始日计算（后台等非个人视角使用）。
+// period 与显式 start_time/end_time 互斥；传入 period 时返回对应的开始/结束日期（YYYY-MM-DD），
+// 未传 period 时原样返回 start/end，由调用方按原有逻辑解析。
+func resolvePeriodQuery(period, startStr, endStr string, now time.Time) (string, string, error) {
+	return resolvePeriodQueryWeek(period, startStr, endStr, now, defaultWeekStart())
+}
+
+// resolveUserPeriodQuery 同 resolvePeriodQuery，但 this_week 按用户偏好的周起始日计算；
+// 仅在 period=this_week 时查询用户偏好
+func resolveUserPeriodQuery(userID uint, period, startStr, endStr string, now time.Time) (string, string, error) {
+	weekStart := time.Monday
+	if period == PeriodThisWeek && startStr == "" && endStr == "" {
+		weekStart = userWeekStart(userID)
+	}
+	return resolvePeriodQueryWeek(period, startStr, endStr, now, weekStart)
+}
+
+func resolvePeriodQueryWeek(period, startStr, endStr string, now time.Time, weekStart time.Weekday) (string, string, error) {
+	if period == "" {
+		return startStr, endStr, nil
+	}
+	if startStr != "" || endStr != "" {
+		return "", "", errors.New("period 与 start_time/end_time 不能同时使用")
+	}
+	start, end, err := periodRange(period, now, weekStart)
+	if err != nil {
+		return "", "", err
+	}
+	return start.Format("2006-01-02"), end.Format("2006-01-02"), nil
+}
+
+// requestLocation 解析 tz 查询参数（IANA 时区，如 Asia/Shanghai），不传时使用服务器时区
+func requestLocation(c *gin.Context) (*time.Location, error) {
+	tz := c.Query("tz")
+	if tz == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, errors.New("无效的时区: " + tz)
+	}
+	return loc, nil
+}
+
+// requestNow 按 tz 查询参数换算的当前时间，用于计算“今天”“本月”等默认范围
+func requestNow(c *gin.Context) (time.Time, error) {
+	loc, err := requestLocation(c)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().In(loc), nil
+}
+
+// defaultToThisMonth 统计、导出接口的默认时间范围：start/end 均未传时返回本月 1 日至今天
+// （按 now 的时区），只要传了其中一个就原样返回，保持显式传参时的行为不变
+func defaultToThisMonth(startStr, endStr string, now time.Time) (string, string) {
+	if startStr != "" || endStr != "" {
+		return startStr, endStr
+	}
+	start, end, _ := periodRange(PeriodThisMonth, now, time.Monday)
+	return start.Format("2006-01-02"), end.Format("2006-01-02")
+}
+
+// setRangeHeaders 文件类响应（导出、统计图）通过响应头回显实际使用的时间范围
+func setRangeHeaders(c *gin.Context, startStr, endStr string) {
+	c.Header("X-Range-Start", startStr)
+	c.Header("X-Range-End", endStr)
+}
+
+// rangeLabel 导出文件名中的日期，未限制的一端用 placeholder 代替
+func rangeLabel(date, placeholder string) string {
+	if date == "" {
+		return placeholder
+	}
+	return date
+}
+
+// 详细统计的时间范围类型（range_type 参数）
+const (
+	RangeTypeMonth  = "month"
+	RangeTypeYear   = "year"
+	RangeTypeCustom = "custom"
+)
+
+// statisticsRange 详细统计解析后的时间范围，End 为结束日期当天 23:59:59
+type statisticsRange struct {
+	Type  string
+	Start time.Time
+	End   time.Time
+}
+
+// parseStatisticsRange 解析详细统计的 range_type（默认 month）及 year_month/year/start_time/end_time 参数，
+// 消费与收入的 App、后台详细统计共用。month/year 不传对应参数时取本月/今年，custom 两端都不传时取本月 1 日至今天
+func parseStatisticsRange(c *gin.Context, now time.Time) (statisticsRange, error) {
+	r := statisticsRange{Type: c.DefaultQuery("range_type", RangeTypeMonth)}
+	switch r.Type {
+	case RangeTypeMonth:
+		yearMonth := c.DefaultQuery("year_month", now.Format("2006-01"))
+		start, err := time.ParseInLocation("2006-01", yearMonth, time.Local)
+		if err != nil {
+			return r, errors.New("year_month格式错误，应为：2024-01")
+		}
+		// 该月第一天 00:00:00 至最后一天 23:59:59
+		r.Start = time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.Local)
+		r.End = r.Start.AddDate(0, 1, 0).Add(-time.Second)
+
+	case RangeTypeYear:
+		year, err := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(now.Year())))
+		if err != nil || year < 2000 || year > 2100 {
+			return r, errors.New("year格式错误，应为4位数字（如：2024）")
+		}
+		r.Start = time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+		r.End = time.Date(year, 12, 31, 23, 59, 59, 0, time.Local)
+
+	case RangeTypeCustom:
+		// 两者都不传时取本月 1 日至今天，只传其一仍视为参数错误
+		startStr, endStr := defaultToThisMonth(c.Query("start_time"), c.Query("end_time"), now)
+		if startStr == "" || endStr == "" {
+			return r, errors.New("range_type=custom时，start_time和end_time需同时提供（格式：2024-01-01）")
+		}
+		start, err := time.ParseInLocation("2006-01-02", startStr, time.Local)
+		if err != nil {
+			return r, errors.New("start_time格式错误，应为：2024-01-01")
+		}
+		end, err := time.ParseInLocation("2006-01-02", endStr, time.Local)
+		if err != nil {
+			return r, errors.New("end_time格式错误，应为：2024-12-31")
+		}
+		r.Start = start
+		// 包含结束日期当天
+		r.End = end.Add(24*time.Hour - time.Second)
+
+	default:
+		return r, errors.New("range_type参数值错误，可选值：month、year、custom")
+	}
+	return r, nil
+}
+
+// splitQueryList 拆分逗号分隔的查询参数（如 categories=餐饮,交通）并去掉各项首尾空格，参数为空时返回 nil
+func splitQueryList(v string) []string {
+	if v == "" {
+		return nil
+	}
+	items := strings.Split(v, ",")
+	for i := range items {
+		items[i] = strings.TrimSpace(items[i])
+	}
+	return items
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeriodRange(t *testing.T) {
+	// 2024-03-13 是周三
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.Local)
+
+	cases := []struct {
+		period string
+		start  string
+		end    string
+	}{
+		{PeriodToday, "2024-03-13", "2024-03-13"},
+		{PeriodThisWeek, "2024-03-11", "2024-03-13"},
+		{PeriodThisMonth, "2024-03-01", "2024-03-13"},
+		{PeriodLastMonth, "2024-02-01", "2024-02-29"},
+		{PeriodThisYear, "2024-01-01", "2024-03-13"},
+		{PeriodLast7d, "2024-03-07", "2024-03-13"},
+		{PeriodLast30d, "2024-02-13", "2024-03-13"},
+	}
+	for _, tc := range cases {
+		start, end, err := periodRange(tc.period, now, time.Monday)
+		require.NoError(t, err, tc.period)
+		assert.Equal(t, tc.start, start.Format("2006-01-02"), tc.period)
+		assert.Equal(t, tc.end, end.Format("2006-01-02"), tc.period)
+	}
+}
+
+func TestPeriodRange_EdgeCases(t *testing.T) {
+	// 周日属于以周一开始的那一周
+	sunday := time.Date(2024, 3, 17, 8, 0, 0, 0, time.Local)
+	start, _, err := periodRange(PeriodThisWeek, sunday, time.Monday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-11", start.Format("2006-01-02"))
+
+	// 1 月的上月跨年
+	jan := time.Date(2024, 1, 5, 0, 0, 0, 0, time.Local)
+	start, end, err := periodRange(PeriodLastMonth, jan, time.Monday)
+	require.NoError(t, err)
+	assert.Equal(t, "2023-12-01", start.Format("2006-01-02"))
+	assert.Equal(t, "2023-12-31", end.Format("2006-01-02"))
+
+	_, _, err = periodRange("next_month", jan, time.Monday)
+	assert.Error(t, err)
+}
+
+func TestPeriodRange_WeekStart(t *testing.T) {
+	// 同一个周日：周一起始时属于 3/11 开始的那一周，周日起始时是新一周的第一天
+	sunday := time.Date(2024, 3, 17, 8, 0, 0, 0, time.Local)
+	start, _, err := periodRange(PeriodThisWeek, sunday, time.Monday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-11", start.Format("2006-01-02"))
+	start, _, err = periodRange(PeriodThisWeek, sunday, time.Sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-17", start.Format("2006-01-02"))
+
+	// 同一个周一：周一起始时是本周第一天，周日起始时属于前一天开始的那一周
+	monday := time.Date(2024, 3, 18, 8, 0, 0, 0, time.Local)
+	start, _, err = periodRange(PeriodThisWeek, monday, time.Monday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-18", start.Format("2006-01-02"))
+	start, _, err = periodRange(PeriodThisWeek, monday, time.Sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-17", start.Format("2006-01-02"))
+}
+
+func TestDefaultWeekStart(t *testing.T) {
+	defer func() { config.GlobalConfig = nil }()
+
+	config.GlobalConfig = nil
+	assert.Equal(t, time.Monday, defaultWeekStart())
+	config.GlobalConfig = &config.Config{Stats: config.StatsConfig{WeekStart: "sun"}}
+	assert.Equal(t, time.Sunday, defaultWeekStart())
+	config.GlobalConfig = &config.Config{Stats: config.StatsConfig{WeekStart: "mon"}}
+	assert.Equal(t, time.Monday, defaultWeekStart())
+}
+
+func TestResolveUserPeriodQuery_WeekStart(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	config.GlobalConfig = &config.Config{Stats: config.StatsConfig{WeekStart: "mon"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	sunday := time.Date(2024, 3, 17, 8, 0, 0, 0, time.Local)
+
+	// 用户偏好周日
+	mock.ExpectQuery("SELECT `week_start` FROM `users`").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"week_start"}).AddRow("sun"))
+	start, end, err := resolveUserPeriodQuery(1, PeriodThisWeek, "", "", sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-17", start)
+	assert.Equal(t, "2024-03-17", end)
+
+	// 未设置偏好时跟随系统配置（周一）
+	mock.ExpectQuery("SELECT `week_start` FROM `users`").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"week_start"}).AddRow(""))
+	start, _, err = resolveUserPeriodQuery(2, PeriodThisWeek, "", "", sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-11", start)
+
+	// 其他 period 不查询用户偏好
+	start, _, err = resolveUserPeriodQuery(1, PeriodThisMonth, "", "", sunday)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01", start)
+
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestResolvePeriodQuery(t *testing.T) {
+	now := time.Date(2024, 3, 13, 0, 0, 0, 0, time.Local)
+
+	start, end, err := resolvePeriodQuery("", "2024-01-01", "2024-01-31", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-01-01", start)
+	assert.Equal(t, "2024-01-31", end)
+
+	start, end, err = resolvePeriodQuery(PeriodThisMonth, "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-03-01", start)
+	assert.Equal(t, "2024-03-13", end)
+
+	_, _, err = resolvePeriodQuery(PeriodThisMonth, "2024-01-01", "", now)
+	assert.Error(t, err)
+}
+
+func TestExpenseHandler_GetStatistics_PeriodConflict(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/statistics", NewExpenseHandler().GetStatistics)
+
+	req := httptest.NewRequest("GET", "/expenses/statistics?period=this_month&start_time=2024-01-01", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestDefaultToThisMonth(t *testing.T) {
+	now := time.Date(2024, 3, 13, 22, 0, 0, 0, time.Local)
+
+	start, end := defaultToThisMonth("", "", now)
+	assert.Equal(t, "2024-03-01", start)
+	assert.Equal(t, "2024-03-13", end)
+
+	// 显式传参时原样返回
+	start, end = defaultToThisMonth("2024-01-01", "2024-01-31", now)
+	assert.Equal(t, "2024-01-01", start)
+	assert.Equal(t, "2024-01-31", end)
+
+	start, end = defaultToThisMonth("2024-01-01", "", now)
+	assert.Equal(t, "2024-01-01", start)
+	assert.Equal(t, "", end)
+
+	// 按 now 的时区取“今天”：UTC 3 月 31 日 20:00 在东八区已是 4 月 1 日
+	shanghai := time.FixedZone("CST", 8*3600)
+	start, end = defaultToThisMonth("", "", time.Date(2024, 3, 31, 20, 0, 0, 0, time.UTC).In(shanghai))
+	assert.Equal(t, "2024-04-01", start)
+	assert.Equal(t, "2024-04-01", end)
+}
+
+func TestRequestLocation(t *testing.T) {
+	// gin 会缓存解析过的查询参数，每次用新的 Context
+	location := func(query string) (*time.Location, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+		return requestLocation(c)
+	}
+
+	loc, err := location("")
+	require.NoError(t, err)
+	assert.Equal(t, time.Local, loc)
+
+	loc, err = location("tz=Asia/Tokyo")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Tokyo", loc.String())
+
+	_, err = location("tz=Nowhere/City")
+	assert.EqualError(t, err, "无效的时区: Nowhere/City")
+}
+
+func TestParseStatisticsRange(t *testing.T) {
+	now := time.Date(2024, 3, 13, 15, 30, 0, 0, time.Local)
+	parse := func(query string) (statisticsRange, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/?"+query, nil)
+		return parseStatisticsRange(c, now)
+	}
+
+	r, err := parse("")
+	require.NoError(t, err)
+	assert.Equal(t, RangeTypeMonth, r.Type)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), r.Start)
+	assert.Equal(t, time.Date(2024, 3, 31, 23, 59, 59, 0, time.Local), r.End)
+
+	r, err = parse("range_type=month&year_month=2024-02")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 2, 29, 23, 59, 59, 0, time.Local), r.End)
+
+	r, err = parse("range_type=year")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), r.Start)
+	assert.Equal(t, time.Date(2024, 12, 31, 23, 59, 59, 0, time.Local), r.End)
+
+	r, err = parse("range_type=custom")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), r.Start)
+	assert.Equal(t, time.Date(2024, 3, 13, 23, 59, 59, 0, time.Local), r.End)
+
+	for query, msg := range map[string]string{
+		"range_type=month&year_month=2024/02":     "year_month格式错误，应为：2024-01",
+		"range_type=year&year=1999":               "year格式错误，应为4位数字（如：2024）",
+		"range_type=custom&start_time=2024-01-01": "range_type=custom时，start_time和end_time需同时提供（格式：2024-01-01）",
+		"range_type=week":                         "range_type参数值错误，可选值：month、year、custom",
+	} {
+		_, err := parse(query)
+		assert.EqualError(t, err, msg, query)
+	}
+}
+
+func TestSplitQueryList(t *testing.T) {
+	assert.Nil(t, splitQueryList(""))
+	assert.Equal(t, []string{"餐饮", "交通"}, splitQueryList("餐饮, 交通"))
+}
@@ -0,0 +1,289 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 可配置的提示词模板标识
+const (
+	PromptKeyAISystem   = "ai_system"   // 聊天/分析共用的系统提示词
+	PromptKeyAIAnalysis = "ai_analysis" // 账单分析的用户提示词
+)
+
+// maxPromptTemplateLength 模板内容最大字数
+const maxPromptTemplateLength = 10000
+
+// defaultAnalysisPrompt 内置的账单分析提示词
+const defaultAnalysisPrompt = `请分析以下消费记录数据，并提供详细的总结和建议：
+
+时间范围：{{date_range}}
+总记录数：{{record_count}} 条
+总消费金额：{{total_amount}} 元
+
+消费类别统计：
+{{category_stats}}
+
+详细消费记录（最近20条）：
+{{recent_records}}
+
+请提供：
+1. 消费趋势分析
+2. 主要消费类别分析
+3. 消费习惯总结
+4. 优化建议和理财建议
+
+内容要详细、专业、实用。`
+
+// promptTemplateDef 提示词模板定义：内置默认内容与可用占位符
+type promptTemplateDef struct {
+	Key          string            `json:"key"`
+	Name         string            `json:"name"`
+	Default      string            `json:"default_content"`
+	Placeholders map[string]string `json:"placeholders"` // 占位符 → 说明
+}
+
+// promptTemplateDefs 所有可配置的模板，按展示顺序排列
+var promptTemplateDefs = []promptTemplateDef{
+	{
+		Key:          PromptKeyAISystem,
+		Name:         "AI 系统提示词",
+		Default:      aiSystemPromptBase,
+		Placeholders: map[string]string{},
+	},
+	{
+		Key:     PromptKeyAIAnalysis,
+		Name:    "账单分析提示词",
+		Default: defaultAnalysisPrompt,
+		Placeholders: map[string]string{
+			"date_range":     "时间范围，如 2024-01-01 至 2024-01-31",
+			"start_time":     "开始日期",
+			"end_time":       "结束日期",
+			"record_count":   "消费记录条数",
+			"total_amount":   "消费总金额（两位小数）",
+			"category_stats": "按类别的金额与条数，每类一行",
+			"recent_records": "最近 20 条消费明细，每条一行",
+		},
+	},
+}
+
+// findPromptTemplateDef 按标识查找模板定义
+func findPromptTemplateDef(key string) (promptTemplateDef, bool) {
+	for _, d := range promptTemplateDefs {
+		if d.Key == key {
+			return d, true
+		}
+	}
+	return promptTemplateDef{}, false
+}
+
+// loadPromptTemplate 请求时读取模板内容；未配置、内容为空或查询失败时使用内置默认
+func loadPromptTemplate(key string) string {
+	def, _ := findPromptTemplateDef(key)
+	if database.DB == nil {
+		return def.Default
+	}
+	var tpl models.PromptTemplate
+	if err := database.DB.Where(&models.PromptTemplate{Key: key}).First(&tpl).Error; err != nil || strings.TrimSpace(tpl.Content) == "" {
+		return def.Default
+	}
+	return tpl.Content
+}
+
+// promptPlaceholderPattern 匹配 {{name}}，允许括号内两侧有空格
+var promptPlaceholderPattern = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}`)
+
+// renderPromptTemplate 替换模板中的占位符；vars 中没有的占位符原样保留
+func renderPromptTemplate(tpl string, vars map[string]string) string {
+	return promptPlaceholderPattern.ReplaceAllStringFunc(tpl, func(m string) string {
+		name := promptPlaceholderPattern.FindStringSubmatch(m)[1]
+		if v, ok := vars[name]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// unknownPlaceholders 返回模板中未在定义里声明的占位符
+func unknownPlaceholders(def promptTemplateDef, content string) []string {
+	var unknown []string
+	seen := map[string]bool{}
+	for _, m := range promptPlaceholderPattern.FindAllStringSubmatch(content, -1) {
+		if _, ok := def.Placeholders[m[1]]; !ok && !seen[m[1]] {
+			seen[m[1]] = true
+			unknown = append(unknown, m[1])
+		}
+	}
+	return unknown
+}
+
+// PromptTemplateHandler 提示词模板管理处理器
+type PromptTemplateHandler struct{}
+
+// NewPromptTemplateHandler 创建提示词模板管理处理器
+func NewPromptTemplateHandler() *PromptTemplateHandler {
+	return &PromptTemplateHandler{}
+}
+
+// PromptTemplateItem 模板列表项：定义与当前生效内容
+type PromptTemplateItem struct {
+	promptTemplateDef
+	Content   string     `json:"content"`    // 当前生效内容（未自定义时同默认内容）
+	IsCustom  bool       `json:"is_custom"`  // 是否已自定义
+	UpdatedAt *time.Time `json:"updated_at"` // 自定义内容的最后修改时间
+}
+
+// UpdatePromptTemplateRequest 更新提示词模板请求
+type UpdatePromptTemplateRequest struct {
+	Content string `json:"content" binding:"required"`
+}
+
+// requirePromptAdmin 提示词影响所有用户的 AI 请求，仅管理员可管理
+func requirePromptAdmin(c *gin.Context) (*models.User, bool) {
+	user, err := getCurrentUser(c)
+	if err != nil || user == nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return nil, false
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，仅管理员可管理提示词模板"})
+		return nil, false
+	}
+	return user, true
+}
+
+// ListPromptTemplates 获取提示词模板列表
+// @Summary 获取提示词模板列表
+// @Description 返回所有可配置的提示词模板：默认内容、可用占位符、当前生效内容及是否已自定义（仅管理员）
+// @Tags 后台管理-AI模型
+// @Produce json
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/prompt-templates [get]
+func (h *PromptTemplateHandler) ListPromptTemplates(c *gin.Context) {
+	if _, ok := requirePromptAdmin(c); !ok {
+		return
+	}
+
+	var saved []models.PromptTemplate
+	if err := database.DB.Find(&saved).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	byKey := map[string]models.PromptTemplate{}
+	for _, t := range saved {
+		byKey[t.Key] = t
+	}
+
+	items := make([]PromptTemplateItem, 0, len(promptTemplateDefs))
+	for _, def := range promptTemplateDefs {
+		item := PromptTemplateItem{promptTemplateDef: def, Content: def.Default}
+		if t, ok := byKey[def.Key]; ok && strings.TrimSpace(t.Content) != "" {
+			updatedAt := t.UpdatedAt
+			item.Content = t.Content
+			item.IsCustom = true
+			item.UpdatedAt = &updatedAt
+		}
+		items = append(items, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": items})
+}
+
+// UpdatePromptTemplate 保存提示词模板
+// @Summary 保存提示词模板
+// @Description 自定义指定模板的内容，保存后下一次 AI 请求即生效；只能使用该模板声明的占位符（仅管理员）
+// @Tags 后台管理-AI模型
+// @Accept json
+// @Produce json
+// @Param key path string true "模板标识" Enums(ai_system,ai_analysis)
+// @Param request body UpdatePromptTemplateRequest true "模板内容"
+// @Success 200 {object} map[string]interface{} "保存成功"
+// @Failure 400 {object} map[string]interface{} "内容为空、过长或含未知占位符"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /admin/prompt-templates/{key} [put]
+func (h *PromptTemplateHandler) UpdatePromptTemplate(c *gin.Context) {
+	user, ok := requirePromptAdmin(c)
+	if !ok {
+		return
+	}
+
+	def, found := findPromptTemplateDef(c.Param("key"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "模板不存在"})
+		return
+	}
+
+	var req UpdatePromptTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	content := strings.TrimSpace(req.Content)
+	if content == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "模板内容不能为空"})
+		return
+	}
+	if utf8.RuneCountInString(content) > maxPromptTemplateLength {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": fmt.Sprintf("模板内容不能超过 %d 字", maxPromptTemplateLength)})
+		return
+	}
+	if unknown := unknownPlaceholders(def, content); len(unknown) > 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "未知的占位符: " + strings.Join(unknown, ", ")})
+		return
+	}
+
+	var tpl models.PromptTemplate
+	err := database.DB.Where(&models.PromptTemplate{Key: def.Key}).First(&tpl).Error
+	tpl.Key = def.Key
+	tpl.Content = content
+	tpl.UpdatedBy = user.ID
+	if err == nil {
+		err = database.DB.Save(&tpl).Error
+	} else {
+		err = database.DB.Create(&tpl).Error
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "保存失败")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "保存成功", "data": tpl})
+}
+
+// ResetPromptTemplate 恢复默认提示词
+// @Summary 恢复默认提示词
+// @Description 删除指定模板的自定义内容，恢复使用内置默认提示词（仅管理员）
+// @Tags 后台管理-AI模型
+// @Produce json
+// @Param key path string true "模板标识" Enums(ai_system,ai_analysis)
+// @Success 200 {object} map[string]interface{} "已恢复默认"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /admin/prompt-templates/{key} [delete]
+func (h *PromptTemplateHandler) ResetPromptTemplate(c *gin.Context) {
+	if _, ok := requirePromptAdmin(c); !ok {
+		return
+	}
+
+	def, found := findPromptTemplateDef(c.Param("key"))
+	if !found {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "模板不存在"})
+		return
+	}
+
+	if err := database.DB.Where(&models.PromptTemplate{Key: def.Key}).Delete(&models.PromptTemplate{}).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "恢复失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "已恢复默认"})
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderPromptTemplate(t *testing.T) {
+	out := renderPromptTemplate("区间 {{date_range}}，合计 {{ total_amount }} 元，{{unknown}}", map[string]string{
+		"date_range":   "2024-01-01 至 2024-01-31",
+		"total_amount": "12.50",
+	})
+	assert.Equal(t, "区间 2024-01-01 至 2024-01-31，合计 12.50 元，{{unknown}}", out)
+}
+
+func TestUnknownPlaceholders(t *testing.T) {
+	def, ok := findPromptTemplateDef(PromptKeyAIAnalysis)
+	require.True(t, ok)
+	assert.Empty(t, unknownPlaceholders(def, defaultAnalysisPrompt))
+	assert.Equal(t, []string{"foo"}, unknownPlaceholders(def, "{{total_amount}} {{foo}} {{ foo }}"))
+}
+
+func TestLoadPromptTemplate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates` WHERE `prompt_templates`.`key` = \\?").
+		WithArgs(PromptKeyAISystem).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}).AddRow(1, PromptKeyAISystem, "你是记账助手。"))
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}))
+
+	assert.Equal(t, "你是记账助手。请用中文回答。", aiSystemPromptFor("zh"))
+	// 未自定义时使用内置默认
+	assert.Equal(t, aiSystemPromptBase, loadPromptTemplate(PromptKeyAISystem))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildAnalysisPrompt_CustomTemplate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}).
+			AddRow(2, PromptKeyAIAnalysis, "{{date_range}} 共 {{record_count}} 笔，{{total_amount}} 元\n{{category_stats}}"))
+
+	expenses := []ExpenseWithUser{{Expense: models.Expense{Amount: 30, Category: "餐饮", ExpenseTime: time.Now()}, Username: "alice"}}
+	prompt := NewAIAnalysisHandler().buildAnalysisPrompt(expenses, exchangeRates{"CNY": 1}, "2024-01-01", "2024-01-31", "zh")
+	assert.Equal(t, "2024-01-01 至 2024-01-31 共 1 笔，30.00 元\n- 餐饮: 30.00 元 (1 条记录)请用中文回答。", prompt)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildAnalysisPrompt_ConvertsCurrency(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}).
+			AddRow(2, PromptKeyAIAnalysis, "{{total_amount}}\n{{category_stats}}\n{{recent_records}}"))
+
+	now := time.Date(2024, 1, 5, 12, 0, 0, 0, time.Local)
+	expenses := []ExpenseWithUser{
+		{Expense: models.Expense{Amount: 30, Currency: "CNY", Category: "餐饮", ExpenseTime: now}, Username: "alice"},
+		{Expense: models.Expense{Amount: 10, Currency: "USD", Category: "餐饮", ExpenseTime: now}, Username: "alice"},
+		{Expense: models.Expense{Amount: 1000, Currency: "JPY", Category: "交通", ExpenseTime: now}, Username: "alice"},
+	}
+	prompt := NewAIAnalysisHandler().buildAnalysisPrompt(expenses, exchangeRates{"CNY": 1, "USD": 7}, "2024-01-01", "2024-01-31", "zh")
+	assert.True(t, strings.HasPrefix(prompt, "100.00\n- 餐饮: 100.00 元 (2 条记录)\n- 另有 JPY 币种记录缺少汇率，未计入以上统计\n"), prompt)
+	assert.Contains(t, prompt, "消费 10.00 USD，类别：餐饮")
+	assert.Contains(t, prompt, "消费 30.00 元，类别：餐饮")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildAnalysisPrompt_Default(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}))
+
+	expenses := []ExpenseWithUser{{Expense: models.Expense{Amount: 30, Category: "餐饮", Description: "午餐", ExpenseTime: time.Now()}, Username: "alice"}}
+	prompt := NewAIAnalysisHandler().buildAnalysisPrompt(expenses, exchangeRates{"CNY": 1}, "2024-01-01", "2024-01-31", "zh")
+	assert.Contains(t, prompt, "时间范围：2024-01-01 至 2024-01-31\n总记录数：1 条\n总消费金额：30.00 元")
+	assert.Contains(t, prompt, "，说明：午餐\n\n请提供：")
+	assert.NotContains(t, prompt, "{{")
+}
+
+func TestPromptTemplateHandler_Update(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	router := gin.New()
+	router.PUT("/admin/prompt-templates/:key", NewPromptTemplateHandler().UpdatePromptTemplate)
+	put := func(key, body string) *httptest.ResponseRecorder {
+		mock.ExpectQuery("SELECT .* FROM `users`").
+			WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+		req := httptest.NewRequest("PUT", "/admin/prompt-templates/"+key, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未知模板、未知占位符、超长内容都不写库
+	assert.Equal(t, 404, put("nope", `{"content":"x"}`).Code)
+	w := put(PromptKeyAIAnalysis, `{"content":"{{total_amount}} {{balance}}"}`)
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "balance")
+	assert.Equal(t, 400, put(PromptKeyAISystem, `{"content":"`+strings.Repeat("长", maxPromptTemplateLength+1)+`"}`).Code)
+
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	mock.ExpectQuery("SELECT \\* FROM `prompt_templates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "content"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `prompt_templates`").
+		WithArgs(PromptKeyAISystem, "你是记账助手。", 1, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	req := httptest.NewRequest("PUT", "/admin/prompt-templates/"+PromptKeyAISystem, bytes.NewBufferString(`{"content":" 你是记账助手。 "}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,323 @@
+package api
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// receiptSniffLen 识别附件类型读取的文件头长度
+const receiptSniffLen = 512
+
+// receiptType 允许的附件类型
+type receiptType struct {
+	ext  string // 保存时使用的扩展名
+	kind string // 附件分类，决定大小上限
+}
+
+// receiptTypes 允许的附件类型（按文件头识别的 Content-Type）
+var receiptTypes = map[string]receiptType{
+	"image/jpeg":                {ext: ".jpg", kind: models.ReceiptKindImage},
+	"image/png":                 {ext: ".png", kind: models.ReceiptKindImage},
+	"audio/mpeg":                {ext: ".mp3", kind: models.ReceiptKindAudio},
+	"audio/mp4":                 {ext: ".m4a", kind: models.ReceiptKindAudio},
+	"text/plain; charset=utf-8": {ext: ".txt", kind: models.ReceiptKindText},
+}
+
+// receiptKindNames 附件分类在提示信息中的名称
+var receiptKindNames = map[string]string{
+	models.ReceiptKindImage: "图片",
+	models.ReceiptKindAudio: "音频",
+	models.ReceiptKindText:  "文本",
+}
+
+// ReceiptHandler 消费附件（小票图片、语音、文本）处理器
+type ReceiptHandler struct{}
+
+// NewReceiptHandler 创建消费附件处理器
+func NewReceiptHandler() *ReceiptHandler {
+	return &ReceiptHandler{}
+}
+
+// receiptMaxSizeMB 各类附件的单个文件大小上限（MB）
+func receiptMaxSizeMB(kind string) int {
+	var storage config.StorageConfig
+	if cfg := config.GlobalConfig; cfg != nil {
+		storage = cfg.Storage
+	}
+	switch kind {
+	case models.ReceiptKindAudio:
+		if storage.AudioMaxSizeMB > 0 {
+			return storage.AudioMaxSizeMB
+		}
+		return config.DefaultAudioMaxSizeMB
+	case models.ReceiptKindText:
+		if storage.TextMaxSizeMB > 0 {
+			return storage.TextMaxSizeMB
+		}
+		return config.DefaultTextMaxSizeMB
+	default:
+		if storage.ReceiptMaxSizeMB > 0 {
+			return storage.ReceiptMaxSizeMB
+		}
+		return config.DefaultReceiptMaxSizeMB
+	}
+}
+
+// sniffReceiptType 按文件头的魔数识别附件类型，只接受 receiptTypes 中的类型；不信任扩展名与客户端声明的 Content-Type。
+// http.DetectContentType 不识别 M4A 与不带 ID3 标签的 MP3，先单独判断
+func sniffReceiptType(head []byte) (string, bool) {
+	if isM4A(head) {
+		return "audio/mp4", true
+	}
+	if _, ok := parseMP3Frame(head); ok {
+		return "audio/mpeg", true
+	}
+	contentType := http.DetectContentType(head)
+	_, ok := receiptTypes[contentType]
+	return contentType, ok
+}
+
+// saveReceiptFile 把上传内容写入 dir/<userID>/<随机名><扩展名>，返回相对 dir 的路径与字节数；
+// 超过 maxSize 或写入失败时删除已写入的文件
+func saveReceiptFile(dir string, userID uint, contentType string, r io.Reader, maxSize int64) (string, int64, error) {
+	name, err := models.GenerateToken()
+	if err != nil {
+		return "", 0, err
+	}
+	rel := filepath.Join(strconv.FormatUint(uint64(userID), 10), name[:32]+receiptTypes[contentType].ext)
+	abs := filepath.Join(dir, rel)
+	if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+		return "", 0, err
+	}
+	f, err := os.OpenFile(abs, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return "", 0, err
+	}
+	// 多读 1 字节用于判断是否超限（multipart 声明的大小不可信）
+	n, err := io.Copy(f, io.LimitReader(r, maxSize+1))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && n > maxSize {
+		err = errReceiptTooLarge
+	}
+	if err != nil {
+		_ = os.Remove(abs)
+		return "", 0, err
+	}
+	return rel, n, nil
+}
+
+// errReceiptTooLarge 附件超过大小上限
+var errReceiptTooLarge = errors.New("附件超过大小上限")
+
+// findOwnExpense 查询当前用户自己的消费记录（不含共享账本中他人的记录）
+func findOwnExpense(c *gin.Context, userID uint) (*models.Expense, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return nil, false
+	}
+	var expense models.Expense
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&expense).Error; err != nil {
+		NotFound(c, "记录不存在")
+		return nil, false
+	}
+	return &expense, true
+}
+
+// Upload 上传附件
+// @Summary 上传消费附件
+// @Description 为自己的消费记录上传一个附件（multipart 字段 file）：小票图片（JPEG/PNG）、语音备忘（MP3/M4A）或文本说明（UTF-8 TXT），按文件内容的魔数识别而不是扩展名；
+// @Description 大小上限按类型分别由 storage.receipt_max_size_mb（默认 5MB）、audio_max_size_mb（默认 10MB）、text_max_size_mb（默认 1MB）配置。
+// @Description 返回的 kind 为 image/audio/text，音频附带时长 duration（秒）。一条消费记录可上传多个
+// @Tags 消费记录
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "消费记录ID"
+// @Param file formData file true "附件（JPEG/PNG/MP3/M4A/TXT）"
+// @Success 200 {object} Response{data=models.Receipt} "上传成功"
+// @Failure 400 {object} Response "文件缺失、类型不支持或超过大小上限"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "记录不存在"
+// @Router /api/v1/expenses/{id}/receipt [post]
+func (h *ReceiptHandler) Upload(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	expense, ok := findOwnExpense(c, userID)
+	if !ok {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		BadRequest(c, "请上传附件")
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		BadRequest(c, "读取文件失败")
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, receiptSniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		BadRequest(c, "读取文件失败")
+		return
+	}
+	head = head[:n]
+	contentType, ok := sniffReceiptType(head)
+	if !ok {
+		BadRequest(c, "仅支持 JPEG/PNG 图片、MP3/M4A 音频或 UTF-8 文本")
+		return
+	}
+
+	// 大小上限按识别出的类型确定
+	typ := receiptTypes[contentType]
+	maxMB := receiptMaxSizeMB(typ.kind)
+	maxSize := int64(maxMB) << 20
+	tooLarge := fmt.Sprintf("%s不能超过 %dMB", receiptKindNames[typ.kind], maxMB)
+	if fileHeader.Size > maxSize {
+		BadRequest(c, tooLarge)
+		return
+	}
+
+	rel, size, err := saveReceiptFile(service.ReceiptDir(), userID, contentType, io.MultiReader(bytes.NewReader(head), file), maxSize)
+	if err != nil {
+		if errors.Is(err, errReceiptTooLarge) {
+			BadRequest(c, tooLarge)
+			return
+		}
+		InternalError(c, SafeErrorMessage(err, "保存附件失败"))
+		return
+	}
+
+	receipt := models.Receipt{
+		ExpenseID:   expense.ID,
+		UserID:      userID,
+		Path:        rel,
+		Kind:        typ.kind,
+		ContentType: contentType,
+		Size:        size,
+	}
+	if typ.kind == models.ReceiptKindAudio {
+		receipt.Duration = audioDuration(file, size, contentType)
+	}
+	if err := database.DB.Create(&receipt).Error; err != nil {
+		service.RemoveReceiptFile(rel)
+		InternalError(c, SafeErrorMessage(err, "保存附件失败"))
+		return
+	}
+	SuccessWithMessage(c, "上传成功", receipt)
+}
+
+// List 消费记录的附件列表
+// @Summary 获取消费记录的附件列表
+// @Description 按上传时间升序返回自己的消费记录的附件信息（kind 区分 image/audio/text，音频附带 duration），文件内容通过 GET /api/v1/receipts/{id} 获取
+// @Tags 消费记录
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "消费记录ID"
+// @Success 200 {object} Response{data=[]models.Receipt} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "记录不存在"
+// @Router /api/v1/expenses/{id}/receipts [get]
+func (h *ReceiptHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	expense, ok := findOwnExpense(c, userID)
+	if !ok {
+		return
+	}
+
+	list := []models.Receipt{}
+	if err := database.DB.Where("expense_id = ? AND user_id = ?", expense.ID, userID).
+		Order("id ASC").Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// findOwnReceipt 查询当前用户上传的附件
+func findOwnReceipt(c *gin.Context, userID uint) (*models.Receipt, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return nil, false
+	}
+	var receipt models.Receipt
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&receipt).Error; err != nil {
+		NotFound(c, "附件不存在")
+		return nil, false
+	}
+	return &receipt, true
+}
+
+// Get 获取附件内容
+// @Summary 获取附件内容
+// @Description 返回自己上传的附件内容，Content-Type 为上传时识别的类型
+// @Tags 消费记录
+// @Produce image/jpeg,image/png,audio/mpeg,audio/mp4,text/plain
+// @Security BearerAuth
+// @Param id path int true "附件ID"
+// @Success 200 {file} binary "附件内容"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "附件不存在"
+// @Router /api/v1/receipts/{id} [get]
+func (h *ReceiptHandler) Get(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	receipt, ok := findOwnReceipt(c, userID)
+	if !ok {
+		return
+	}
+
+	f, err := os.Open(filepath.Join(service.ReceiptDir(), receipt.Path))
+	if err != nil {
+		NotFound(c, "附件文件不存在")
+		return
+	}
+	defer f.Close()
+	c.DataFromReader(http.StatusOK, receipt.Size, receipt.ContentType, f, nil)
+}
+
+// Delete 删除附件
+// @Summary 删除附件
+// @Description 删除自己上传的附件记录及文件
+// @Tags 消费记录
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "附件ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 401 {object} Response "未授权"
+// @Failure 404 {object} Response "附件不存在"
+// @Router /api/v1/receipts/{id} [delete]
+func (h *ReceiptHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	receipt, ok := findOwnReceipt(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(receipt).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	service.RemoveReceiptFile(receipt.Path)
+	SuccessWithMessage(c, "删除成功", nil)
+}
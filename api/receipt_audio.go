@@ -0,0 +1,231 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// MPEG Layer III 帧头各字段取值表，按 MPEG 版本区分（MPEG-2 与 MPEG-2.5 相同）
+var (
+	mp3BitratesV1  = [16]int{0, 32, 40, 48, 56, 64, 80, 96, 112, 128, 160, 192, 224, 256, 320, 0}
+	mp3BitratesV2  = [16]int{0, 8, 16, 24, 32, 40, 48, 56, 64, 80, 96, 112, 128, 144, 160, 0}
+	mp3SampleRates = map[byte][3]int{
+		3: {44100, 48000, 32000}, // MPEG-1
+		2: {22050, 24000, 16000}, // MPEG-2
+		0: {11025, 12000, 8000},  // MPEG-2.5
+	}
+)
+
+// mp3Frame MPEG Layer III 帧头中计算时长需要的字段
+type mp3Frame struct {
+	mpeg1      bool
+	mono       bool
+	bitrate    int // kbps
+	sampleRate int
+}
+
+// parseMP3Frame 解析 4 字节帧头，只接受 Layer III
+func parseMP3Frame(b []byte) (mp3Frame, bool) {
+	if len(b) < 4 || b[0] != 0xFF || b[1]&0xE0 != 0xE0 {
+		return mp3Frame{}, false
+	}
+	version := (b[1] >> 3) & 0x03
+	layer := (b[1] >> 1) & 0x03
+	bitrateIdx := b[2] >> 4
+	rateIdx := (b[2] >> 2) & 0x03
+	rates, ok := mp3SampleRates[version]
+	if !ok || layer != 1 || bitrateIdx == 0 || bitrateIdx == 15 || rateIdx == 3 {
+		return mp3Frame{}, false
+	}
+	f := mp3Frame{mpeg1: version == 3, mono: b[3]>>6 == 3, sampleRate: rates[rateIdx]}
+	if f.mpeg1 {
+		f.bitrate = mp3BitratesV1[bitrateIdx]
+	} else {
+		f.bitrate = mp3BitratesV2[bitrateIdx]
+	}
+	return f, true
+}
+
+// samplesPerFrame 每帧采样数
+func (f mp3Frame) samplesPerFrame() int {
+	if f.mpeg1 {
+		return 1152
+	}
+	return 576
+}
+
+// sideInfoLen 帧头之后边信息的长度，Xing/Info 头紧随其后
+func (f mp3Frame) sideInfoLen() int {
+	switch {
+	case f.mpeg1 && f.mono:
+		return 17
+	case f.mpeg1:
+		return 32
+	case f.mono:
+		return 9
+	default:
+		return 17
+	}
+}
+
+// id3v2Len 文件开头 ID3v2 标签的总长度，没有标签时为 0
+func id3v2Len(head []byte) int64 {
+	if len(head) < 10 || !bytes.HasPrefix(head, []byte("ID3")) {
+		return 0
+	}
+	// 标签长度为 4 字节 syncsafe 整数（每字节只用低 7 位），不含 10 字节标签头
+	n := int64(head[6]&0x7F)<<21 | int64(head[7]&0x7F)<<14 | int64(head[8]&0x7F)<<7 | int64(head[9]&0x7F)
+	n += 10
+	if head[5]&0x10 != 0 { // 带页脚
+		n += 10
+	}
+	return n
+}
+
+// mp3Duration 计算 MP3 时长（秒）：VBR 文件读取首帧的 Xing/Info 或 VBRI 头中的总帧数，
+// 否则按首帧比特率视为 CBR 用文件大小估算
+func mp3Duration(r io.ReaderAt, size int64) (float64, bool) {
+	head := make([]byte, 10)
+	if _, err := r.ReadAt(head, 0); err != nil {
+		return 0, false
+	}
+	offset := id3v2Len(head)
+
+	// 首帧帧头 + 最长的边信息 + Xing 头（标识 4 字节、标志 4 字节、帧数 4 字节），VBRI 头在帧头后 32 字节处
+	buf := make([]byte, 4+32+12)
+	n, err := r.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return 0, false
+	}
+	buf = buf[:n]
+	frame, ok := parseMP3Frame(buf)
+	if !ok {
+		return 0, false
+	}
+
+	frames := 0
+	if x := 4 + frame.sideInfoLen(); len(buf) >= x+12 {
+		tag := string(buf[x : x+4])
+		if (tag == "Xing" || tag == "Info") && binary.BigEndian.Uint32(buf[x+4:])&0x01 != 0 {
+			frames = int(binary.BigEndian.Uint32(buf[x+8:]))
+		}
+	}
+	if frames == 0 {
+		vbri := make([]byte, 18)
+		if _, err := r.ReadAt(vbri, offset+4+32); err == nil && string(vbri[:4]) == "VBRI" {
+			frames = int(binary.BigEndian.Uint32(vbri[14:]))
+		}
+	}
+	if frames > 0 {
+		return float64(frames) * float64(frame.samplesPerFrame()) / float64(frame.sampleRate), true
+	}
+	return float64(size-offset) * 8 / float64(frame.bitrate*1000), true
+}
+
+// m4aDuration 从 moov/mvhd 中读取 M4A 时长（秒）；moov 可能位于文件末尾，按顶层 box 依次跳过查找
+func m4aDuration(r io.ReaderAt, size int64) (float64, bool) {
+	moov, moovSize, ok := findMP4Box(r, 0, size, "moov")
+	if !ok {
+		return 0, false
+	}
+	mvhd, mvhdSize, ok := findMP4Box(r, moov, moov+moovSize, "mvhd")
+	if !ok || mvhdSize < 20 {
+		return 0, false
+	}
+	// mvhd：版本(1) 标志(3)，之后版本 0 为 创建/修改时间各 4 字节 + timescale(4) + duration(4)，
+	// 版本 1 为 创建/修改时间各 8 字节 + timescale(4) + duration(8)
+	b := make([]byte, 32)
+	if mvhdSize < 32 {
+		b = b[:mvhdSize]
+	}
+	if _, err := r.ReadAt(b, mvhd); err != nil {
+		return 0, false
+	}
+	var timescale uint32
+	var duration uint64
+	if b[0] == 1 {
+		if len(b) < 32 {
+			return 0, false
+		}
+		timescale = binary.BigEndian.Uint32(b[20:])
+		duration = binary.BigEndian.Uint64(b[24:])
+	} else {
+		timescale = binary.BigEndian.Uint32(b[12:])
+		duration = uint64(binary.BigEndian.Uint32(b[16:]))
+	}
+	if timescale == 0 {
+		return 0, false
+	}
+	return float64(duration) / float64(timescale), true
+}
+
+// findMP4Box 在 [start, end) 范围内按顺序查找指定类型的 box，返回其内容的起始位置与长度
+func findMP4Box(r io.ReaderAt, start, end int64, boxType string) (int64, int64, bool) {
+	header := make([]byte, 16)
+	for pos := start; pos+8 <= end; {
+		if _, err := r.ReadAt(header[:8], pos); err != nil {
+			return 0, 0, false
+		}
+		boxSize := int64(binary.BigEndian.Uint32(header))
+		headerLen := int64(8)
+		switch boxSize {
+		case 0: // 延伸到范围末尾
+			boxSize = end - pos
+		case 1: // 64 位长度紧随类型之后
+			if _, err := r.ReadAt(header[8:16], pos+8); err != nil {
+				return 0, 0, false
+			}
+			boxSize = int64(binary.BigEndian.Uint64(header[8:]))
+			headerLen = 16
+		}
+		if boxSize < headerLen || pos+boxSize > end {
+			return 0, 0, false
+		}
+		if string(header[4:8]) == boxType {
+			return pos + headerLen, boxSize - headerLen, true
+		}
+		pos += boxSize
+	}
+	return 0, 0, false
+}
+
+// isM4A 判断文件头是否为 M4A 音频：ftyp box 的主品牌或兼容品牌中包含 M4A/M4B
+func isM4A(head []byte) bool {
+	if len(head) < 16 || string(head[4:8]) != "ftyp" {
+		return false
+	}
+	boxSize := int(binary.BigEndian.Uint32(head))
+	if boxSize > len(head) {
+		boxSize = len(head)
+	}
+	// 主品牌在偏移 8，偏移 12 为次版本号，兼容品牌从偏移 16 开始
+	for i := 8; i+4 <= boxSize; i += 4 {
+		if i == 12 {
+			continue
+		}
+		if brand := string(head[i : i+4]); brand == "M4A " || brand == "M4B " {
+			return true
+		}
+	}
+	return false
+}
+
+// audioDuration 计算音频附件的时长（整秒，四舍五入），无法解析时返回 0
+func audioDuration(r io.ReaderAt, size int64, contentType string) int {
+	var (
+		seconds float64
+		ok      bool
+	)
+	switch contentType {
+	case "audio/mpeg":
+		seconds, ok = mp3Duration(r, size)
+	case "audio/mp4":
+		seconds, ok = m4aDuration(r, size)
+	}
+	if !ok || seconds < 0 || math.IsInf(seconds, 0) || math.IsNaN(seconds) {
+		return 0
+	}
+	return int(math.Round(seconds))
+}
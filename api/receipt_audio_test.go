@@ -0,0 +1,63 @@
+package api
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// testMP3Header MPEG-1 Layer III、128kbps、44.1kHz、立体声的帧头
+var testMP3Header = []byte{0xFF, 0xFB, 0x90, 0x00}
+
+// buildMP4Box 拼接一个 MP4 box：4 字节长度 + 4 字节类型 + 内容
+func buildMP4Box(boxType string, payload ...[]byte) []byte {
+	body := bytes.Join(payload, nil)
+	b := make([]byte, 8, 8+len(body))
+	binary.BigEndian.PutUint32(b, uint32(8+len(body)))
+	copy(b[4:], boxType)
+	return append(b, body...)
+}
+
+// buildTestM4A 构造 moov 位于 mdat 之后的最小 M4A 文件
+func buildTestM4A(timescale, duration uint32) []byte {
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:], timescale)
+	binary.BigEndian.PutUint32(mvhd[16:], duration)
+	return bytes.Join([][]byte{
+		buildMP4Box("ftyp", []byte("M4A "), []byte{0, 0, 0, 0}, []byte("M4A mp42isom")),
+		buildMP4Box("mdat", bytes.Repeat([]byte{0}, 64)),
+		buildMP4Box("moov", buildMP4Box("mvhd", mvhd)),
+	}, nil)
+}
+
+func TestMP3Duration(t *testing.T) {
+	// CBR：按比特率用文件大小估算，16000 字节 × 8 / 128kbps = 1 秒
+	cbr := append(append([]byte{}, testMP3Header...), make([]byte, 16000-4)...)
+	assert.Equal(t, 1, audioDuration(bytes.NewReader(cbr), int64(len(cbr)), "audio/mpeg"))
+
+	// VBR：读取 Xing 头中的总帧数，100 × 1152 / 44100 ≈ 2.6 秒；前面带 ID3v2 标签
+	id3 := []byte{'I', 'D', '3', 4, 0, 0, 0, 0, 0, 20}
+	frame := append(append([]byte{}, testMP3Header...), make([]byte, 32)...)
+	frame = append(frame, []byte("Xing")...)
+	frame = append(frame, 0, 0, 0, 1, 0, 0, 0, 100)
+	vbr := bytes.Join([][]byte{id3, make([]byte, 20), frame, make([]byte, 400)}, nil)
+	assert.Equal(t, 3, audioDuration(bytes.NewReader(vbr), int64(len(vbr)), "audio/mpeg"))
+
+	// 不是 Layer III 帧头时无法解析
+	assert.Equal(t, 0, audioDuration(bytes.NewReader([]byte("not mp3 at all")), 14, "audio/mpeg"))
+}
+
+func TestM4ADuration(t *testing.T) {
+	m4a := buildTestM4A(1000, 5000)
+	assert.True(t, isM4A(m4a))
+	assert.Equal(t, 5, audioDuration(bytes.NewReader(m4a), int64(len(m4a)), "audio/mp4"))
+
+	// 没有 moov 时无法解析
+	broken := buildMP4Box("ftyp", []byte("M4A "), []byte{0, 0, 0, 0})
+	assert.Equal(t, 0, audioDuration(bytes.NewReader(broken), int64(len(broken)), "audio/mp4"))
+
+	// 普通 MP4 视频不是 M4A
+	assert.False(t, isM4A(buildMP4Box("ftyp", []byte("isom"), []byte{0, 0, 0, 0}, []byte("isommp41"))))
+}
@@ -0,0 +1,198 @@
+package api
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testPNG  = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{0}, 32)...)
+	testJPEG = append([]byte("\xff\xd8\xff\xe0\x00\x10JFIF\x00"), bytes.Repeat([]byte{0}, 32)...)
+)
+
+func TestSniffReceiptType(t *testing.T) {
+	ct, ok := sniffReceiptType(testPNG)
+	assert.True(t, ok)
+	assert.Equal(t, "image/png", ct)
+
+	ct, ok = sniffReceiptType(testJPEG)
+	assert.True(t, ok)
+	assert.Equal(t, "image/jpeg", ct)
+
+	ct, ok = sniffReceiptType(append(append([]byte{}, testMP3Header...), make([]byte, 32)...))
+	assert.True(t, ok)
+	assert.Equal(t, "audio/mpeg", ct)
+
+	ct, ok = sniffReceiptType(buildTestM4A(1000, 1000))
+	assert.True(t, ok)
+	assert.Equal(t, "audio/mp4", ct)
+
+	// 只看内容：改成 .png 的文本按文本识别
+	ct, ok = sniffReceiptType([]byte("not really a png"))
+	assert.True(t, ok)
+	assert.Equal(t, "text/plain; charset=utf-8", ct)
+
+	// GIF、HTML、二进制数据都不接受
+	_, ok = sniffReceiptType([]byte("GIF89a......"))
+	assert.False(t, ok)
+	_, ok = sniffReceiptType([]byte("<html>not an image</html>"))
+	assert.False(t, ok)
+	_, ok = sniffReceiptType([]byte{0x00, 0x01, 0x02, 0x03})
+	assert.False(t, ok)
+}
+
+func TestSaveReceiptFile(t *testing.T) {
+	dir := t.TempDir()
+
+	rel, size, err := saveReceiptFile(dir, 7, "image/png", bytes.NewReader(testPNG), 1024)
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(testPNG)), size)
+	assert.True(t, strings.HasPrefix(rel, "7"+string(filepath.Separator)))
+	assert.Equal(t, ".png", filepath.Ext(rel))
+	data, err := os.ReadFile(filepath.Join(dir, rel))
+	require.NoError(t, err)
+	assert.Equal(t, testPNG, data)
+
+	// 超过上限时不留下文件
+	_, _, err = saveReceiptFile(dir, 8, "image/jpeg", bytes.NewReader(testJPEG), 10)
+	assert.ErrorIs(t, err, errReceiptTooLarge)
+	entries, err := os.ReadDir(filepath.Join(dir, "8"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func receiptUploadRequest(t *testing.T, filename string, content []byte) *httptest.ResponseRecorder {
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	_, err = part.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, mw.Close())
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses/:id/receipt", NewReceiptHandler().Upload)
+
+	req := httptest.NewRequest("POST", "/expenses/5/receipt", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestReceiptHandler_Upload(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	dir := t.TempDir()
+	config.GlobalConfig = &config.Config{Storage: config.StorageConfig{ReceiptDir: dir, ReceiptMaxSizeMB: 1}}
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `receipts`").
+		WithArgs(5, 1, sqlmock.AnyArg(), "image", "image/png", len(testPNG), 0, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectCommit()
+
+	// 扩展名不影响识别
+	w := receiptUploadRequest(t, "receipt.jpg", testPNG)
+	require.Equal(t, 200, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"content_type":"image/png"`)
+	assert.Contains(t, w.Body.String(), `"kind":"image"`)
+	assert.NotContains(t, w.Body.String(), `"path"`)
+	entries, err := os.ReadDir(filepath.Join(dir, "1"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceiptHandler_Upload_RejectsNonImage(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	dir := t.TempDir()
+	config.GlobalConfig = &config.Config{Storage: config.StorageConfig{ReceiptDir: dir, ReceiptMaxSizeMB: 1}}
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1))
+
+	w := receiptUploadRequest(t, "receipt.png", []byte("<html>not an image</html>"))
+	assert.Equal(t, 400, w.Code)
+	_, err := os.Stat(filepath.Join(dir, "1"))
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceiptHandler_Upload_OtherUsersExpense(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}))
+
+	w := receiptUploadRequest(t, "receipt.png", testPNG)
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceiptHandler_Upload_Audio(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	dir := t.TempDir()
+	config.GlobalConfig = &config.Config{Storage: config.StorageConfig{ReceiptDir: dir, ReceiptMaxSizeMB: 1, AudioMaxSizeMB: 2}}
+	defer func() { config.GlobalConfig = nil }()
+
+	m4a := buildTestM4A(600, 6000)
+	mock.ExpectQuery("SELECT \\* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `receipts`").
+		WithArgs(5, 1, sqlmock.AnyArg(), "audio", "audio/mp4", len(m4a), 10, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	mock.ExpectCommit()
+
+	w := receiptUploadRequest(t, "memo.m4a", m4a)
+	require.Equal(t, 200, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"kind":"audio"`)
+	assert.Contains(t, w.Body.String(), `"duration":10`)
+	entries, err := os.ReadDir(filepath.Join(dir, "1"))
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, ".m4a", filepath.Ext(entries[0].Name()))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestReceiptHandler_Upload_PerKindSizeLimit(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	dir := t.TempDir()
+	// 文本上限 1MB，小于图片上限；超过文本上限的文本被拒绝
+	config.GlobalConfig = &config.Config{Storage: config.StorageConfig{ReceiptDir: dir, ReceiptMaxSizeMB: 5, TextMaxSizeMB: 1}}
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id"}).AddRow(5, 1))
+
+	w := receiptUploadRequest(t, "note.txt", bytes.Repeat([]byte("备注"), 200000))
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "文本不能超过 1MB")
+	_, err := os.Stat(filepath.Join(dir, "1"))
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"unicode/utf8"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxUserAgentLength User-Agent 最大保存字符数，与 user_agent 列宽一致
+const maxUserAgentLength = 255
+
+// recordSourceEnabled 是否记录提交来源（audit.record_source），未加载配置时按默认开启
+func recordSourceEnabled() bool {
+	return config.GlobalConfig == nil || config.GlobalConfig.Audit.RecordSource
+}
+
+// recordSourceFrom 采集创建请求的来源 IP 与 User-Agent；关闭 audit.record_source 时返回空值。
+// 来源只由服务端采集，不接受客户端在请求体中传入
+func recordSourceFrom(c *gin.Context) models.RecordSource {
+	if !recordSourceEnabled() {
+		return models.RecordSource{}
+	}
+	return models.RecordSource{
+		SourceIP:  c.ClientIP(),
+		UserAgent: truncateRunes(c.Request.UserAgent(), maxUserAgentLength),
+	}
+}
+
+// truncateRunes 按字符截断字符串，超过 n 个字符时只保留前 n 个
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
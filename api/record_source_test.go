@@ -0,0 +1,43 @@
+package api
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"finance/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordSourceFrom(t *testing.T) {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/v1/expenses", nil)
+	c.Request.RemoteAddr = "203.0.113.5:52000"
+	c.Request.Header.Set("User-Agent", "FinanceApp/2.3 (Android 14)")
+
+	config.GlobalConfig = &config.Config{Audit: config.AuditConfig{RecordSource: true}}
+	defer func() { config.GlobalConfig = nil }()
+
+	src := recordSourceFrom(c)
+	assert.Equal(t, "203.0.113.5", src.SourceIP)
+	assert.Equal(t, "FinanceApp/2.3 (Android 14)", src.UserAgent)
+
+	// 关闭后不采集
+	config.GlobalConfig.Audit.RecordSource = false
+	src = recordSourceFrom(c)
+	assert.Empty(t, src.SourceIP)
+	assert.Empty(t, src.UserAgent)
+}
+
+func TestRecordSourceFrom_TruncatesUserAgent(t *testing.T) {
+	config.GlobalConfig = nil
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("POST", "/api/v1/incomes", nil)
+	c.Request.Header.Set("User-Agent", strings.Repeat("浏", 300))
+
+	src := recordSourceFrom(c)
+	assert.Equal(t, strings.Repeat("浏", maxUserAgentLength), src.UserAgent)
+}
@@ -0,0 +1,315 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// 趋势/对比的时间粒度
+const (
+	TrendGranularityDay   = "day"
+	TrendGranularityWeek  = "week"
+	TrendGranularityMonth = "month"
+	TrendGranularityYear  = "year"
+)
+
+// 趋势查询的时间桶数量
+const (
+	defaultTrendBuckets = 12  // 未指定时间范围时回看的桶数（含当前）
+	maxTrendBuckets     = 366 // 单次最多返回的桶数
+)
+
+// recordSource 可做趋势/对比聚合的流水表（消费、收入）
+type recordSource struct {
+	Model          interface{}
+	TimeColumn     string // 记账时间列
+	CategoryColumn string // 类别列
+}
+
+var (
+	expenseRecordSource = recordSource{Model: &models.Expense{}, TimeColumn: "expense_time", CategoryColumn: "category"}
+	incomeRecordSource  = recordSource{Model: &models.Income{}, TimeColumn: "income_time", CategoryColumn: "type"}
+)
+
+// TrendBucket 某个时间桶的金额与笔数
+type TrendBucket struct {
+	Period string  `json:"period" example:"2024-01"` // day: 2024-01-15；week: 该周第一天；month: 2024-01；year: 2024
+	Amount float64 `json:"amount" example:"5000.00"`
+	Count  int64   `json:"count" example:"3"`
+}
+
+// RecordTrendResponse 趋势返回
+type RecordTrendResponse struct {
+	Granularity string        `json:"granularity" example:"month"`
+	StartTime   string        `json:"start_time" example:"2024-01-01"`
+	EndTime     string        `json:"end_time" example:"2024-12-31"`
+	Total       float64       `json:"total" example:"60000.00"`
+	Items       []TrendBucket `json:"items"` // 按时间从早到晚，无记录的桶金额为 0
+	// 金额均已折算为本位币；缺少汇率、未计入金额的币种
+	UnconvertedCurrencies []string `json:"unconverted_currencies"`
+}
+
+// RecordCompareResponse 同比/环比返回
+type RecordCompareResponse struct {
+	Granularity      string       `json:"granularity" example:"month"`
+	Current          TrendBucket  `json:"current"`
+	Previous         TrendBucket  `json:"previous"`                          // 环比：上一个周期
+	LastYear         *TrendBucket `json:"last_year,omitempty"`               // 同比：去年同期，仅 month 粒度返回
+	MoMChangePercent *float64     `json:"mom_change_percent" example:"12.5"` // 环比变化（%），上一周期为 0 时为 null
+	YoYChangePercent *float64     `json:"yoy_change_percent" example:"-3.2"` // 同比变化（%），去年同期为 0 或 year 粒度时为 null
+	// 金额均已折算为本位币；缺少汇率、未计入金额的币种
+	UnconvertedCurrencies []string `json:"unconverted_currencies"`
+}
+
+// trendQuery 趋势查询参数
+type trendQuery struct {
+	Granularity string
+	Start       time.Time // 开始日零点（首个桶可能只统计其中一部分）
+	End         time.Time // 最后一天 23:59:59
+	WeekStart   time.Weekday
+	Category    string
+}
+
+// validTrendGranularity 校验趋势粒度
+func validTrendGranularity(g string) bool {
+	switch g {
+	case TrendGranularityDay, TrendGranularityWeek, TrendGranularityMonth, TrendGranularityYear:
+		return true
+	}
+	return false
+}
+
+// bucketStart 返回 t 所在时间桶的起点（按 t 的时区）
+func bucketStart(t time.Time, granularity string, weekStart time.Weekday) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	switch granularity {
+	case TrendGranularityWeek:
+		offset := (int(day.Weekday()) - int(weekStart) + 7) % 7
+		return day.AddDate(0, 0, -offset)
+	case TrendGranularityMonth:
+		return time.Date(day.Year(), day.Month(), 1, 0, 0, 0, 0, day.Location())
+	case TrendGranularityYear:
+		return time.Date(day.Year(), 1, 1, 0, 0, 0, 0, day.Location())
+	}
+	return day
+}
+
+// bucketNext 返回下一个时间桶的起点
+func bucketNext(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case TrendGranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case TrendGranularityMonth:
+		return start.AddDate(0, 1, 0)
+	case TrendGranularityYear:
+		return start.AddDate(1, 0, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// bucketKey 时间桶的展示键
+func bucketKey(start time.Time, granularity string) string {
+	switch granularity {
+	case TrendGranularityMonth:
+		return start.Format("2006-01")
+	case TrendGranularityYear:
+		return start.Format("2006")
+	}
+	return start.Format("2006-01-02")
+}
+
+// countBuckets 统计 [start, end] 覆盖的桶数，超过 limit 时提前返回 limit+1
+func countBuckets(start, end time.Time, granularity string, limit int) int {
+	n := 0
+	for t := start; !t.After(end); t = bucketNext(t, granularity) {
+		n++
+		if n > limit {
+			break
+		}
+	}
+	return n
+}
+
+// parseTrendQuery 解析 granularity/start_time/end_time/period 与类别参数；
+// 未指定时间范围时默认取截至今天的最近 12 个桶
+func parseTrendQuery(c *gin.Context, userID uint, categoryParam string) (trendQuery, error) {
+	q := trendQuery{Granularity: c.DefaultQuery("granularity", TrendGranularityMonth), WeekStart: time.Monday, Category: c.Query(categoryParam)}
+	if !validTrendGranularity(q.Granularity) {
+		return q, errors.New("无效的 granularity 参数，可选值: day/week/month/year")
+	}
+
+	now := time.Now()
+	startStr, endStr, err := resolveUserPeriodQuery(userID, c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		return q, err
+	}
+	if q.Granularity == TrendGranularityWeek {
+		q.WeekStart = userWeekStart(userID)
+	}
+
+	var start, end time.Time
+	switch {
+	case startStr == "" && endStr == "":
+		end = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		start = bucketStart(end, q.Granularity, q.WeekStart)
+		for i := 1; i < defaultTrendBuckets; i++ {
+			start = bucketStart(start.AddDate(0, 0, -1), q.Granularity, q.WeekStart)
+		}
+	case startStr == "" || endStr == "":
+		return q, errors.New("start_time 与 end_time 需同时提供")
+	default:
+		if start, err = time.ParseInLocation("2006-01-02", startStr, time.Local); err != nil {
+			return q, errors.New("开始时间格式错误，请使用 YYYY-MM-DD 格式")
+		}
+		if end, err = time.ParseInLocation("2006-01-02", endStr, time.Local); err != nil {
+			return q, errors.New("结束时间格式错误，请使用 YYYY-MM-DD 格式")
+		}
+		if end.Before(start) {
+			return q, errors.New("开始时间不能晚于结束时间")
+		}
+	}
+
+	q.Start = start
+	q.End = end.Add(24*time.Hour - time.Second)
+	if countBuckets(bucketStart(q.Start, q.Granularity, q.WeekStart), q.End, q.Granularity, maxTrendBuckets) > maxTrendBuckets {
+		return q, errors.New("时间范围过大，最多 " + strconv.Itoa(maxTrendBuckets) + " 个时间段，请缩小范围或改用更粗的粒度")
+	}
+	return q, nil
+}
+
+// aggregateRecords 按时间桶汇总 src 表在 [start, end] 内的金额（折算为本位币）与笔数；
+// db 由调用方带好用户/账本/类别等过滤条件，时间按 start 的时区分桶。
+// 缺少汇率的币种只计笔数不计金额，其代码随第二个返回值给出
+func aggregateRecords(db *gorm.DB, src recordSource, start, end time.Time, granularity string, weekStart time.Weekday) (map[string]TrendBucket, []string, error) {
+	var rows []struct {
+		Amount     float64
+		Currency   string
+		RecordTime time.Time
+	}
+	if err := db.Model(src.Model).
+		Select(src.TimeColumn+" AS record_time, amount, currency").
+		Where(src.TimeColumn+" >= ? AND "+src.TimeColumn+" <= ?", start, end).
+		Scan(&rows).Error; err != nil {
+		return nil, nil, err
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	buckets := make(map[string]TrendBucket)
+	var unconverted []string
+	for _, r := range rows {
+		key := bucketKey(bucketStart(r.RecordTime.In(start.Location()), granularity, weekStart), granularity)
+		b := buckets[key]
+		b.Period = key
+		b.Count++
+		if amount, ok := rates.ToBase(r.Amount, r.Currency); ok {
+			b.Amount += amount
+		} else {
+			unconverted = append(unconverted, r.Currency)
+		}
+		buckets[key] = b
+	}
+	for key, b := range buckets {
+		b.Amount = math.Round(b.Amount*100) / 100
+		buckets[key] = b
+	}
+	return buckets, mergeCurrencyCodes(unconverted), nil
+}
+
+// buildTrend 将汇总结果展开为连续的时间桶序列，无记录的桶补 0
+func buildTrend(q trendQuery, buckets map[string]TrendBucket) RecordTrendResponse {
+	resp := RecordTrendResponse{
+		Granularity: q.Granularity,
+		StartTime:   q.Start.Format("2006-01-02"),
+		EndTime:     q.End.Format("2006-01-02"),
+		Items:       []TrendBucket{},
+	}
+	var total float64
+	for t := bucketStart(q.Start, q.Granularity, q.WeekStart); !t.After(q.End); t = bucketNext(t, q.Granularity) {
+		key := bucketKey(t, q.Granularity)
+		b := buckets[key]
+		b.Period = key
+		total += b.Amount
+		resp.Items = append(resp.Items, b)
+	}
+	resp.Total = math.Round(total*100) / 100
+	return resp
+}
+
+// compareQuery 同比/环比查询参数
+type compareQuery struct {
+	Granularity string
+	Current     time.Time // 当前周期起点
+	Category    string
+}
+
+// parseCompareQuery 解析 granularity（month/year）与 date（YYYY-MM / YYYY，默认当前周期）
+func parseCompareQuery(c *gin.Context, categoryParam string) (compareQuery, error) {
+	q := compareQuery{Granularity: c.DefaultQuery("granularity", TrendGranularityMonth), Category: c.Query(categoryParam)}
+	layout := "2006-01"
+	switch q.Granularity {
+	case TrendGranularityMonth:
+	case TrendGranularityYear:
+		layout = "2006"
+	default:
+		return q, errors.New("无效的 granularity 参数，可选值: month/year")
+	}
+
+	if s := c.Query("date"); s != "" {
+		t, err := time.ParseInLocation(layout, s, time.Local)
+		if err != nil {
+			return q, errors.New("date 格式错误，month 粒度使用 YYYY-MM，year 粒度使用 YYYY")
+		}
+		q.Current = t
+	} else {
+		q.Current = bucketStart(time.Now(), q.Granularity, time.Monday)
+	}
+	return q, nil
+}
+
+// changePercent 计算变化百分比，基期为 0 时返回 nil
+func changePercent(cur, base float64) *float64 {
+	if base == 0 {
+		return nil
+	}
+	v := math.Round((cur-base)/base*10000) / 100
+	return &v
+}
+
+// compareRange 对比所需的查询区间：month 粒度从去年同月起，year 粒度从上一年起，至当前周期末
+func compareRange(q compareQuery) (time.Time, time.Time) {
+	start := q.Current.AddDate(-1, 0, 0)
+	return start, bucketNext(q.Current, q.Granularity).Add(-time.Second)
+}
+
+// buildCompare 从按周期汇总的结果中取出当前、上一周期与去年同期并计算变化率
+func buildCompare(q compareQuery, buckets map[string]TrendBucket) RecordCompareResponse {
+	pick := func(t time.Time) TrendBucket {
+		key := bucketKey(t, q.Granularity)
+		b := buckets[key]
+		b.Period = key
+		return b
+	}
+
+	resp := RecordCompareResponse{Granularity: q.Granularity, Current: pick(q.Current)}
+	if q.Granularity == TrendGranularityYear {
+		// 年粒度下上一周期即去年，只给环比
+		resp.Previous = pick(q.Current.AddDate(-1, 0, 0))
+	} else {
+		resp.Previous = pick(q.Current.AddDate(0, -1, 0))
+		lastYear := pick(q.Current.AddDate(-1, 0, 0))
+		resp.LastYear = &lastYear
+		resp.YoYChangePercent = changePercent(resp.Current.Amount, lastYear.Amount)
+	}
+	resp.MoMChangePercent = changePercent(resp.Current.Amount, resp.Previous.Amount)
+	return resp
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"finance/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBucketStart(t *testing.T) {
+	// 2024-03-13 为周三
+	d := time.Date(2024, 3, 13, 15, 30, 0, 0, time.Local)
+
+	assert.Equal(t, time.Date(2024, 3, 13, 0, 0, 0, 0, time.Local), bucketStart(d, TrendGranularityDay, time.Monday))
+	assert.Equal(t, time.Date(2024, 3, 11, 0, 0, 0, 0, time.Local), bucketStart(d, TrendGranularityWeek, time.Monday))
+	assert.Equal(t, time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local), bucketStart(d, TrendGranularityWeek, time.Sunday))
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local), bucketStart(d, TrendGranularityMonth, time.Monday))
+	assert.Equal(t, time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local), bucketStart(d, TrendGranularityYear, time.Monday))
+}
+
+func TestBuildTrend_FillsEmptyBuckets(t *testing.T) {
+	q := trendQuery{
+		Granularity: TrendGranularityMonth,
+		Start:       time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local),
+		End:         time.Date(2024, 4, 10, 23, 59, 59, 0, time.Local),
+	}
+	resp := buildTrend(q, map[string]TrendBucket{
+		"2024-01": {Period: "2024-01", Amount: 100, Count: 1},
+		"2024-03": {Period: "2024-03", Amount: 250.5, Count: 2},
+	})
+
+	require.Len(t, resp.Items, 4)
+	assert.Equal(t, "2024-01-15", resp.StartTime)
+	assert.Equal(t, "2024-04-10", resp.EndTime)
+	assert.Equal(t, "2024-02", resp.Items[1].Period)
+	assert.Equal(t, 0.0, resp.Items[1].Amount)
+	assert.Equal(t, "2024-04", resp.Items[3].Period)
+	assert.Equal(t, 350.5, resp.Total)
+}
+
+func TestBuildCompare(t *testing.T) {
+	buckets := map[string]TrendBucket{
+		"2024-03": {Amount: 1200, Count: 2},
+		"2024-02": {Amount: 1000, Count: 1},
+		"2023-03": {Amount: 0},
+	}
+	resp := buildCompare(compareQuery{Granularity: TrendGranularityMonth, Current: time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)}, buckets)
+
+	assert.Equal(t, "2024-03", resp.Current.Period)
+	assert.Equal(t, "2024-02", resp.Previous.Period)
+	require.NotNil(t, resp.LastYear)
+	assert.Equal(t, "2023-03", resp.LastYear.Period)
+	require.NotNil(t, resp.MoMChangePercent)
+	assert.Equal(t, 20.0, *resp.MoMChangePercent)
+	assert.Nil(t, resp.YoYChangePercent, "去年同期为 0 时同比为 null")
+
+	yearly := buildCompare(compareQuery{Granularity: TrendGranularityYear, Current: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)},
+		map[string]TrendBucket{"2024": {Amount: 90}, "2023": {Amount: 120}})
+	assert.Equal(t, "2023", yearly.Previous.Period)
+	assert.Nil(t, yearly.LastYear)
+	require.NotNil(t, yearly.MoMChangePercent)
+	assert.Equal(t, -25.0, *yearly.MoMChangePercent)
+}
+
+func TestAggregateRecords_Expense(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 3, 31, 23, 59, 59, 0, time.Local)
+	mock.ExpectQuery("SELECT expense_time AS record_time, amount, currency FROM `expenses`").
+		WithArgs(1, start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"record_time", "amount", "currency"}).
+			AddRow(time.Date(2024, 3, 4, 12, 0, 0, 0, time.Local), 10.1, "CNY").
+			AddRow(time.Date(2024, 3, 8, 12, 0, 0, 0, time.Local), 20.2, "CNY").
+			AddRow(time.Date(2024, 3, 11, 12, 0, 0, 0, time.Local), 5, "CNY").
+			AddRow(time.Date(2024, 3, 12, 12, 0, 0, 0, time.Local), 2, "USD").
+			AddRow(time.Date(2024, 3, 13, 12, 0, 0, 0, time.Local), 300, "JPY"))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+
+	buckets, unconverted, err := aggregateRecords(database.DB.Where("user_id = ?", 1), expenseRecordSource, start, end, TrendGranularityWeek, time.Monday)
+	require.NoError(t, err)
+	assert.Equal(t, TrendBucket{Period: "2024-03-04", Amount: 30.3, Count: 2}, buckets["2024-03-04"])
+	assert.Equal(t, TrendBucket{Period: "2024-03-11", Amount: 19, Count: 3}, buckets["2024-03-11"])
+	assert.Equal(t, []string{"JPY"}, unconverted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,494 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecurringExpenseHandler 周期消费模板处理器（App端）
+type RecurringExpenseHandler struct{}
+
+// NewRecurringExpenseHandler 创建周期消费模板处理器
+func NewRecurringExpenseHandler() *RecurringExpenseHandler {
+	return &RecurringExpenseHandler{}
+}
+
+// CreateRecurringExpenseRequest 创建周期消费模板请求
+type CreateRecurringExpenseRequest struct {
+	Amount      float64 `json:"amount" binding:"required,gt=0" example:"99.00"`
+	Currency    string  `json:"currency" example:"CNY"` // 币种（ISO 4217），不传时为本位币，需在 currency.allowed 中
+	Category    string  `json:"category" binding:"required" example:"订阅"`
+	Description string  `json:"description" example:"视频会员"`
+	Frequency   string  `json:"frequency" binding:"required,oneof=daily weekly monthly" example:"monthly"`
+	NextRun     string  `json:"next_run" binding:"required" example:"2024-02-01"` // 首次生成日期（YYYY-MM-DD），不能早于今天
+	Active      *bool   `json:"active" example:"true"`                            // 不传默认启用
+}
+
+// UpdateRecurringExpenseRequest 更新周期消费模板请求，未传的字段不修改
+type UpdateRecurringExpenseRequest struct {
+	Amount      float64 `json:"amount" binding:"omitempty,gt=0" example:"99.00"`
+	Currency    string  `json:"currency" example:"CNY"`
+	Category    string  `json:"category" example:"订阅"`
+	Description string  `json:"description" example:"视频会员"`
+	Frequency   string  `json:"frequency" binding:"omitempty,oneof=daily weekly monthly" example:"monthly"`
+	NextRun     string  `json:"next_run" example:"2024-03-01"` // 修改后按月重复以新日期的日为准
+	Active      *bool   `json:"active" example:"false"`
+}
+
+// AdminCreateRecurringExpenseRequest 管理员创建周期消费模板请求
+type AdminCreateRecurringExpenseRequest struct {
+	UserID uint `json:"user_id" binding:"required" example:"1"`
+	CreateRecurringExpenseRequest
+}
+
+// RecurringExpenseWithUser 附带用户名的周期消费模板（后台列表）
+type RecurringExpenseWithUser struct {
+	models.RecurringExpense
+	Username string `json:"username"`
+}
+
+// parseRecurringNextRun 校验下次生成日期（YYYY-MM-DD），不能早于 today
+func parseRecurringNextRun(nextRun, today string) error {
+	if _, err := time.ParseInLocation("2006-01-02", nextRun, time.Local); err != nil {
+		return errors.New("next_run 格式错误，应为: 2006-01-02")
+	}
+	if nextRun < today {
+		return errors.New("next_run 不能早于今天")
+	}
+	return nil
+}
+
+// validateExpenseCategory 校验消费类别是否存在
+func validateExpenseCategory(name string) error {
+	var cat models.ExpenseCategory
+	if err := database.DB.Where("name = ?", name).First(&cat).Error; err != nil {
+		return errors.New("无效的消费类别，请先在“消费类别”中维护")
+	}
+	return nil
+}
+
+// buildRecurringExpense 校验创建请求并构造模板，userID 为所属用户，createdBy 为创建人
+func buildRecurringExpense(req CreateRecurringExpenseRequest, userID, createdBy uint, today string) (*models.RecurringExpense, error) {
+	if err := validateAmount(req.Amount); err != nil {
+		return nil, err
+	}
+	if err := validateDescription(req.Description); err != nil {
+		return nil, err
+	}
+	currency, err := resolveCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if !models.ValidRecurringFrequency(req.Frequency) {
+		return nil, errors.New("frequency 只能为 daily/weekly/monthly")
+	}
+	if err := parseRecurringNextRun(req.NextRun, today); err != nil {
+		return nil, err
+	}
+	if err := validateExpenseCategory(req.Category); err != nil {
+		return nil, err
+	}
+
+	active := true
+	if req.Active != nil {
+		active = *req.Active
+	}
+	return &models.RecurringExpense{
+		UserID:      userID,
+		Amount:      req.Amount,
+		Currency:    currency,
+		Category:    req.Category,
+		Description: req.Description,
+		Frequency:   req.Frequency,
+		StartDate:   req.NextRun,
+		NextRun:     req.NextRun,
+		Active:      active,
+		CreatedBy:   createdBy,
+	}, nil
+}
+
+// recurringExpenseUpdates 校验更新请求并返回需要更新的字段。
+// 修改 next_run 时同步把 start_date 改为新日期（按月重复以其日为准），且新日期须晚于最近一次已生成的日期，避免同一天重复生成
+func recurringExpenseUpdates(tpl *models.RecurringExpense, req UpdateRecurringExpenseRequest, today string) (map[string]interface{}, error) {
+	updates := make(map[string]interface{})
+	if req.Amount > 0 {
+		if err := validateAmount(req.Amount); err != nil {
+			return nil, err
+		}
+		updates["amount"] = req.Amount
+	}
+	if req.Currency != "" {
+		currency, err := resolveCurrency(req.Currency)
+		if err != nil {
+			return nil, err
+		}
+		updates["currency"] = currency
+	}
+	if req.Category != "" {
+		if err := validateExpenseCategory(req.Category); err != nil {
+			return nil, err
+		}
+		updates["category"] = req.Category
+	}
+	if req.Description != "" {
+		if err := validateDescription(req.Description); err != nil {
+			return nil, err
+		}
+		updates["description"] = req.Description
+	}
+	if req.Frequency != "" {
+		if !models.ValidRecurringFrequency(req.Frequency) {
+			return nil, errors.New("frequency 只能为 daily/weekly/monthly")
+		}
+		updates["frequency"] = req.Frequency
+	}
+	if req.NextRun != "" && req.NextRun != tpl.NextRun {
+		if err := parseRecurringNextRun(req.NextRun, today); err != nil {
+			return nil, err
+		}
+		if tpl.LastCreatedDate != nil && req.NextRun <= *tpl.LastCreatedDate {
+			return nil, fmt.Errorf("next_run 须晚于最近一次已生成的日期 %s", *tpl.LastCreatedDate)
+		}
+		updates["next_run"] = req.NextRun
+		updates["start_date"] = req.NextRun
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	return updates, nil
+}
+
+// List 获取周期消费模板列表
+// @Summary 获取周期消费模板列表
+// @Description 获取当前用户的周期消费模板，按下次生成日期排序
+// @Tags 周期消费
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]models.RecurringExpense} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/recurring-expenses [get]
+func (h *RecurringExpenseHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var list []models.RecurringExpense
+	if err := database.DB.Where("user_id = ?", userID).
+		Order("next_run ASC, id ASC").
+		Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// Create 创建周期消费模板
+// @Summary 创建周期消费模板
+// @Description 按 daily/weekly/monthly 周期自动记账：到达 next_run 当天由定时任务（每小时扫描）生成一条消费记录并推进 next_run。
+// @Description 按月重复时以首次日期的日为准，该月没有这一天时取月末
+// @Tags 周期消费
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body CreateRecurringExpenseRequest true "模板信息"
+// @Success 200 {object} Response{data=models.RecurringExpense} "创建成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/recurring-expenses [post]
+func (h *RecurringExpenseHandler) Create(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	var req CreateRecurringExpenseRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	tpl, err := buildRecurringExpense(req, userID, userID, time.Now().Format("2006-01-02"))
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if err := database.DB.Create(tpl).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "创建失败"))
+		return
+	}
+	SuccessWithMessage(c, "创建成功", tpl)
+}
+
+// Update 更新周期消费模板
+// @Summary 更新周期消费模板
+// @Description 更新金额、类别、周期、下次生成日期或启停状态；active=false 后不再生成
+// @Tags 周期消费
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "模板ID"
+// @Param request body UpdateRecurringExpenseRequest true "模板信息"
+// @Success 200 {object} Response{data=models.RecurringExpense} "更新成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 404 {object} Response "模板不存在"
+// @Router /api/v1/recurring-expenses/{id} [put]
+func (h *RecurringExpenseHandler) Update(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var tpl models.RecurringExpense
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&tpl).Error; err != nil {
+		NotFound(c, "模板不存在")
+		return
+	}
+	var req UpdateRecurringExpenseRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	updates, err := recurringExpenseUpdates(&tpl, req, time.Now().Format("2006-01-02"))
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(&tpl).Updates(updates).Error; err != nil {
+			InternalError(c, SafeErrorMessage(err, "更新失败"))
+			return
+		}
+	}
+	database.DB.First(&tpl, tpl.ID)
+	SuccessWithMessage(c, "更新成功", tpl)
+}
+
+// Delete 删除周期消费模板
+// @Summary 删除周期消费模板
+// @Description 删除模板后不再生成，已生成的消费记录保留
+// @Tags 周期消费
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "模板ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 404 {object} Response "模板不存在"
+// @Router /api/v1/recurring-expenses/{id} [delete]
+func (h *RecurringExpenseHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var tpl models.RecurringExpense
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&tpl).Error; err != nil {
+		NotFound(c, "模板不存在")
+		return
+	}
+	if err := database.DB.Delete(&tpl).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	SuccessWithMessage(c, "删除成功", nil)
+}
+
+// GetRecurringExpenses 获取周期消费模板列表
+// @Summary 获取周期消费模板列表
+// @Description 管理员可查看所有用户的模板并按用户ID筛选，非管理员只能查看自己的模板
+// @Tags 后台管理-周期消费
+// @Produce json
+// @Param user_id query int false "用户ID筛选（仅管理员可用）"
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/recurring-expenses [get]
+func (h *AdminHandler) GetRecurringExpenses(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	query := database.DB.Model(&models.RecurringExpense{}).
+		Select("recurring_expenses.*, users.username").
+		Joins("LEFT JOIN users ON recurring_expenses.user_id = users.id")
+	if !currentUser.IsAdmin {
+		query = query.Where("recurring_expenses.user_id = ?", currentUser.ID)
+	} else if userIDFilter := c.Query("user_id"); userIDFilter != "" {
+		if uid, err := strconv.ParseUint(userIDFilter, 10, 32); err == nil {
+			query = query.Where("recurring_expenses.user_id = ?", uint(uid))
+		}
+	}
+
+	var list []RecurringExpenseWithUser
+	if err := query.Order("recurring_expenses.next_run ASC, recurring_expenses.id ASC").Scan(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    list,
+	})
+}
+
+// CreateRecurringExpense 创建周期消费模板
+// @Summary 创建周期消费模板
+// @Description 管理员可以为任何用户创建，非管理员只能为自己创建。到期生成的消费记录以创建人为录入人
+// @Tags 后台管理-周期消费
+// @Accept json
+// @Produce json
+// @Param request body AdminCreateRecurringExpenseRequest true "模板信息"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误或类别不存在"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /admin/recurring-expenses [post]
+func (h *AdminHandler) CreateRecurringExpense(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	var req AdminCreateRecurringExpenseRequest
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+
+	// 权限检查：非管理员只能为自己创建
+	if !currentUser.IsAdmin && req.UserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，只能为自己创建记录"})
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, req.UserID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "用户不存在"})
+		return
+	}
+
+	tpl, err := buildRecurringExpense(req.CreateRecurringExpenseRequest, req.UserID, currentUser.ID, time.Now().Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if err := database.DB.Create(tpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "创建失败")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "创建成功",
+		"data":    tpl,
+	})
+}
+
+// loadRecurringExpenseForAdmin 按 ID 查询模板并做权限检查（非管理员只能操作自己的），失败时已写入响应
+func loadRecurringExpenseForAdmin(c *gin.Context, currentUser *models.User) (*models.RecurringExpense, bool) {
+	var id uint
+	if _, err := fmt.Sscanf(c.Param("id"), "%d", &id); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	var tpl models.RecurringExpense
+	if err := database.DB.First(&tpl, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "模板不存在"})
+		return nil, false
+	}
+	if !currentUser.IsAdmin && tpl.UserID != currentUser.ID {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足，只能操作自己的模板"})
+		return nil, false
+	}
+	return &tpl, true
+}
+
+// UpdateRecurringExpense 更新周期消费模板
+// @Summary 更新周期消费模板
+// @Description 管理员可以更新任何模板，非管理员只能更新自己的模板
+// @Tags 后台管理-周期消费
+// @Accept json
+// @Produce json
+// @Param id path int true "模板ID"
+// @Param request body UpdateRecurringExpenseRequest true "模板信息"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /admin/recurring-expenses/{id} [put]
+func (h *AdminHandler) UpdateRecurringExpense(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	tpl, ok := loadRecurringExpenseForAdmin(c, currentUser)
+	if !ok {
+		return
+	}
+
+	var req UpdateRecurringExpenseRequest
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	updates, err := recurringExpenseUpdates(tpl, req, time.Now().Format("2006-01-02"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(tpl).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
+			return
+		}
+	}
+	database.DB.First(tpl, tpl.ID)
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "更新成功",
+		"data":    tpl,
+	})
+}
+
+// DeleteRecurringExpense 删除周期消费模板
+// @Summary 删除周期消费模板
+// @Description 管理员可以删除任何模板，非管理员只能删除自己的模板；已生成的消费记录保留
+// @Tags 后台管理-周期消费
+// @Produce json
+// @Param id path int true "模板ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "模板不存在"
+// @Router /admin/recurring-expenses/{id} [delete]
+func (h *AdminHandler) DeleteRecurringExpense(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	tpl, ok := loadRecurringExpenseForAdmin(c, currentUser)
+	if !ok {
+		return
+	}
+
+	if err := database.DB.Delete(tpl).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "删除成功",
+	})
+}
@@ -0,0 +1,101 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRecurringNextRun(t *testing.T) {
+	assert.NoError(t, parseRecurringNextRun("2024-03-01", "2024-03-01"))
+	assert.NoError(t, parseRecurringNextRun("2024-04-01", "2024-03-01"))
+	assert.Error(t, parseRecurringNextRun("2024-02-29", "2024-03-01"))
+	assert.Error(t, parseRecurringNextRun("2024/04/01", "2024-03-01"))
+}
+
+func TestRecurringExpenseUpdates(t *testing.T) {
+	last := "2024-03-01"
+	tpl := &models.RecurringExpense{Frequency: models.RecurringMonthly, StartDate: "2024-01-01", NextRun: "2024-04-01", LastCreatedDate: &last}
+	inactive := false
+
+	updates, err := recurringExpenseUpdates(tpl, UpdateRecurringExpenseRequest{NextRun: "2024-04-15", Active: &inactive}, "2024-03-10")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"next_run": "2024-04-15", "start_date": "2024-04-15", "active": false}, updates)
+
+	// next_run 未变化时不重置 start_date
+	updates, err = recurringExpenseUpdates(tpl, UpdateRecurringExpenseRequest{NextRun: "2024-04-01", Frequency: models.RecurringWeekly}, "2024-03-10")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"frequency": models.RecurringWeekly}, updates)
+
+	// 不能回退到已生成过的日期
+	_, err = recurringExpenseUpdates(tpl, UpdateRecurringExpenseRequest{NextRun: "2024-03-01"}, "2024-03-01")
+	assert.Error(t, err)
+}
+
+func TestRecurringExpenseHandler_Create(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	nextRun := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WithArgs("订阅").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(1, "订阅"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `recurring_expenses`").
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/recurring-expenses", NewRecurringExpenseHandler().Create)
+
+	body := `{"amount":30,"category":"订阅","description":"视频会员","frequency":"monthly","next_run":"` + nextRun + `"}`
+	req := httptest.NewRequest("POST", "/recurring-expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var resp struct {
+		Data models.RecurringExpense `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, uint(3), resp.Data.ID)
+	assert.Equal(t, uint(1), resp.Data.CreatedBy)
+	assert.Equal(t, nextRun, resp.Data.StartDate)
+	assert.True(t, resp.Data.Active)
+	assert.Equal(t, "CNY", resp.Data.Currency)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRecurringExpenseHandler_Create_Invalid(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/recurring-expenses", NewRecurringExpenseHandler().Create)
+
+	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
+	for _, body := range []string{
+		`{"amount":30,"category":"订阅","frequency":"monthly","next_run":"` + yesterday + `"}`,
+		`{"amount":30,"category":"订阅","frequency":"yearly","next_run":"2099-01-01"}`,
+	} {
+		req := httptest.NewRequest("POST", "/recurring-expenses", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, 400, w.Code, body)
+	}
+	require.NoError(t, mock.ExpectationsWereMet())
+}
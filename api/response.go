@@ -1,24 +1,44 @@
 package api
 
 import (
+	"encoding/json"
 	"net/http"
 
+	"finance/middleware"
+
 	"github.com/gin-gonic/gin"
 )
 
 // Response 通用响应结构
 type Response struct {
-	Code    int         `json:"code"`
-	Message string      `json:"message"`
-	Data    interface{} `json:"data,omitempty"`
+	Code      int          `json:"code"`
+	Message   string       `json:"message"`
+	Data      interface{}  `json:"data,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`     // 参数校验失败时的字段级错误
+	RequestID string       `json:"request_id,omitempty"` // 错误响应附带请求 ID，便于反馈问题时定位日志
 }
 
 // PageResponse 分页响应结构
 type PageResponse struct {
-	Total    int64       `json:"total"`
-	Page     int         `json:"page"`
-	PageSize int         `json:"page_size"`
-	List     interface{} `json:"list"`
+	Total      int64       `json:"total"`
+	Page       int         `json:"page"`
+	PageSize   int         `json:"page_size"`
+	TotalPages int         `json:"total_pages"`           // 总页数，没有记录时为 0
+	HasNext    bool        `json:"has_next"`              // 是否还有下一页
+	HasPrev    bool        `json:"has_prev"`              // 是否有上一页
+	NextCursor string      `json:"next_cursor,omitempty"` // 支持游标分页的列表返回下一页游标
+	List       interface{} `json:"list"`
+}
+
+// NewPageResponse 构建分页响应，总页数与前后页标记由 total、page_size 计算
+func NewPageResponse(total int64, page, pageSize int, list interface{}) PageResponse {
+	resp := PageResponse{Total: total, Page: page, PageSize: pageSize, List: list}
+	if pageSize > 0 {
+		resp.TotalPages = int((total + int64(pageSize) - 1) / int64(pageSize))
+	}
+	resp.HasNext = page < resp.TotalPages
+	resp.HasPrev = page > 1
+	return resp
 }
 
 // Success 成功响应
@@ -42,8 +62,9 @@ func SuccessWithMessage(c *gin.Context, message string, data interface{}) {
 // Error 错误响应
 func Error(c *gin.Context, code int, message string) {
 	c.JSON(code, Response{
-		Code:    code,
-		Message: message,
+		Code:      code,
+		Message:   message,
+		RequestID: middleware.GetRequestID(c),
 	})
 }
 
@@ -67,3 +88,19 @@ func NotFound(c *gin.Context, message string) {
 	Error(c, http.StatusNotFound, message)
 }
 
+// marshalWithExtra 用于嵌入 models.Expense/Income 的响应结构：
+// 被嵌入模型的 MarshalJSON 会被提升，外层字段会丢失，因此先按模型序列化，再合并附加字段。
+func marshalWithExtra(base interface{}, extra map[string]interface{}) ([]byte, error) {
+	raw, err := json.Marshal(base)
+	if err != nil {
+		return nil, err
+	}
+	merged := map[string]interface{}{}
+	if err := json.Unmarshal(raw, &merged); err != nil {
+		return nil, err
+	}
+	for k, v := range extra {
+		merged[k] = v
+	}
+	return json.Marshal(merged)
+}
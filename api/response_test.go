@@ -0,0 +1,31 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPageResponse(t *testing.T) {
+	cases := []struct {
+		name             string
+		total            int64
+		page, pageSize   int
+		totalPages       int
+		hasNext, hasPrev bool
+	}{
+		{"无记录", 0, 1, 20, 0, false, false},
+		{"不足一页", 5, 1, 20, 1, false, false},
+		{"恰好整页", 40, 1, 20, 2, true, false},
+		{"最后一页", 40, 2, 20, 2, false, true},
+		{"末页不满", 41, 2, 20, 3, true, true},
+		{"超出总页数", 41, 5, 20, 3, false, true},
+	}
+	for _, tc := range cases {
+		resp := NewPageResponse(tc.total, tc.page, tc.pageSize, nil)
+		assert.Equal(t, tc.totalPages, resp.TotalPages, tc.name)
+		assert.Equal(t, tc.hasNext, resp.HasNext, tc.name)
+		assert.Equal(t, tc.hasPrev, resp.HasPrev, tc.name)
+		assert.Equal(t, tc.total, resp.Total, tc.name)
+	}
+}
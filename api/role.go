@@ -6,6 +6,7 @@ import (
 
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
 )
@@ -69,8 +70,8 @@ type RoleUpdateRequest struct {
 // Create 创建角色
 func (h *RoleHandler) Create(c *gin.Context) {
 	var req RoleCreateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var exist models.Role
@@ -98,8 +99,8 @@ func (h *RoleHandler) Update(c *gin.Context) {
 		return
 	}
 	var req RoleUpdateRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var role models.Role
@@ -128,6 +129,8 @@ func (h *RoleHandler) Update(c *gin.Context) {
 			return
 		}
 	}
+	// 角色编码变更可能影响未分配角色用户的只读角色回退
+	service.InvalidateRolePermissions()
 	database.DB.First(&role, role.ID)
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": role})
 }
@@ -149,6 +152,7 @@ func (h *RoleHandler) Delete(c *gin.Context) {
 		return
 	}
 	_ = database.DB.Where("role_id = ?", id).Delete(&models.RoleMenu{})
+	service.InvalidateRolePermissions()
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
 }
 
@@ -164,8 +168,8 @@ func (h *RoleHandler) AssignMenus(c *gin.Context) {
 		return
 	}
 	var req RoleMenusRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误")})
+	if err := bindJSON(c, &req); err != nil {
+		adminBindError(c, err)
 		return
 	}
 	var role models.Role
@@ -180,5 +184,6 @@ func (h *RoleHandler) AssignMenus(c *gin.Context) {
 	for _, menuID := range req.MenuIDs {
 		_ = database.DB.Create(&models.RoleMenu{RoleID: uint(id), MenuID: menuID}).Error
 	}
+	service.InvalidateRolePermissions()
 	c.JSON(http.StatusOK, gin.H{"success": true, "message": "分配成功"})
 }
@@ -0,0 +1,204 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+)
+
+// 全局搜索限制
+const (
+	DefaultSearchLimit  = 5  // 每类默认返回条数
+	maxSearchLimit      = 20 // 每类最多返回条数
+	maxSearchKeywordLen = 50 // 关键词最大字符数
+	searchSnippetRadius = 40 // AI 历史摘要在命中位置前后各取的字符数
+)
+
+// SearchHandler 全局搜索处理器
+type SearchHandler struct{}
+
+// NewSearchHandler 创建全局搜索处理器
+func NewSearchHandler() *SearchHandler {
+	return &SearchHandler{}
+}
+
+// SearchGroup 单类搜索结果
+type SearchGroup struct {
+	Items   interface{} `json:"items"`
+	HasMore bool        `json:"has_more"` // 是否还有更多匹配（超过 limit）
+}
+
+// SearchAnalysisItem AI 分析历史命中项（只返回命中位置附近的摘要）
+type SearchAnalysisItem struct {
+	ID        uint   `json:"id"`
+	AIModelID uint   `json:"ai_model_id"`
+	StartDate string `json:"start_date"`
+	EndDate   string `json:"end_date"`
+	Snippet   string `json:"snippet"`
+	CreatedAt string `json:"created_at"`
+}
+
+// SearchResult 全局搜索结果
+type SearchResult struct {
+	Keyword    string      `json:"q"`
+	Expenses   SearchGroup `json:"expenses"`
+	Incomes    SearchGroup `json:"incomes"`
+	AIAnalyses SearchGroup `json:"ai_analyses"`
+}
+
+// searchSnippet 截取关键词首次出现位置前后的片段，未命中时取开头
+func searchSnippet(text, keyword string, radius int) string {
+	runes := []rune(text)
+	pos := 0
+	if idx := strings.Index(strings.ToLower(text), strings.ToLower(keyword)); idx >= 0 {
+		pos = utf8.RuneCountInString(text[:idx])
+	}
+	start := pos - radius
+	if start < 0 {
+		start = 0
+	}
+	end := pos + utf8.RuneCountInString(keyword) + radius
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	snippet := strings.Join(strings.Fields(string(runes[start:end])), " ")
+	if start > 0 {
+		snippet = "…" + snippet
+	}
+	if end < len(runes) {
+		snippet += "…"
+	}
+	return snippet
+}
+
+// exactMatchFirst 完全匹配的排在前面，其余按时间倒序
+func exactMatchFirst(keyword, timeColumn string, columns ...string) clause.OrderBy {
+	conds := make([]string, len(columns))
+	vars := make([]interface{}, len(columns))
+	for i, col := range columns {
+		conds[i] = col + " = ?"
+		vars[i] = keyword
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:                "CASE WHEN " + strings.Join(conds, " OR ") + " THEN 0 ELSE 1 END, " + timeColumn + " DESC",
+		Vars:               vars,
+		WithoutParentheses: true,
+	}}
+}
+
+// Search 全局搜索
+// @Summary 全局搜索
+// @Description 在当前用户的消费记录（描述/类别）、收入记录（类型）、AI 分析历史（分析结果）中按关键词模糊搜索，各类分别返回前 limit 条。完全匹配的排在前面，其余按时间倒序；AI 历史只返回命中位置附近的摘要
+// @Tags 搜索
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "关键词（最多 50 个字符）"
+// @Param limit query int false "每类返回条数，默认 5，最大 20"
+// @Success 200 {object} Response{data=SearchResult} "搜索成功"
+// @Failure 400 {object} Response "参数错误"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		BadRequest(c, "请输入搜索关键词")
+		return
+	}
+	if utf8.RuneCountInString(q) > maxSearchKeywordLen {
+		BadRequest(c, "关键词不能超过 50 个字符")
+		return
+	}
+	limit := DefaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 || n > maxSearchLimit {
+			BadRequest(c, "limit 需在 1-20 之间")
+			return
+		}
+		limit = n
+	}
+	like := "%" + escapeLikeValue(q) + "%"
+	esc := likeEscape(database.DB)
+
+	// 三类查询互不依赖，并行执行；多取一条用于判断 has_more
+	var (
+		wg          sync.WaitGroup
+		expenses    []models.Expense
+		incomes     []models.Income
+		histories   []models.AIAnalysisHistory
+		errExpenses error
+		errIncomes  error
+		errAnalyses error
+	)
+	wg.Add(3)
+	go func() {
+		defer wg.Done()
+		errExpenses = database.DB.Where("user_id = ? AND (description LIKE ?"+esc+" OR category LIKE ?"+esc+")", userID, like, like).
+			Clauses(exactMatchFirst(q, "expense_time", "category", "description")).
+			Limit(limit + 1).Find(&expenses).Error
+	}()
+	go func() {
+		defer wg.Done()
+		errIncomes = database.DB.Where("user_id = ? AND type LIKE ?"+esc, userID, like).
+			Clauses(exactMatchFirst(q, "income_time", "type")).
+			Limit(limit + 1).Find(&incomes).Error
+	}()
+	go func() {
+		defer wg.Done()
+		errAnalyses = database.DB.Where("user_id = ? AND result LIKE ?"+esc, userID, like).
+			Order("created_at DESC").
+			Limit(limit + 1).Find(&histories).Error
+	}()
+	wg.Wait()
+
+	for _, err := range []error{errExpenses, errIncomes, errAnalyses} {
+		if err != nil {
+			InternalError(c, SafeErrorMessage(err, "搜索失败"))
+			return
+		}
+	}
+
+	result := SearchResult{Keyword: q}
+	result.Expenses.HasMore = len(expenses) > limit
+	if result.Expenses.HasMore {
+		expenses = expenses[:limit]
+	}
+	stripExtra(expenses)
+	result.Expenses.Items = expenses
+
+	result.Incomes.HasMore = len(incomes) > limit
+	if result.Incomes.HasMore {
+		incomes = incomes[:limit]
+	}
+	result.Incomes.Items = incomes
+
+	result.AIAnalyses.HasMore = len(histories) > limit
+	if result.AIAnalyses.HasMore {
+		histories = histories[:limit]
+	}
+	analyses := make([]SearchAnalysisItem, len(histories))
+	for i, his := range histories {
+		analyses[i] = SearchAnalysisItem{
+			ID:        his.ID,
+			AIModelID: his.AIModelID,
+			StartDate: his.StartDate,
+			EndDate:   his.EndDate,
+			Snippet:   searchSnippet(his.Result, q, searchSnippetRadius),
+			CreatedAt: models.FormatTime(his.CreatedAt),
+		}
+	}
+	result.AIAnalyses.Items = analyses
+
+	Success(c, result)
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchSnippet(t *testing.T) {
+	text := strings.Repeat("甲", 50) + "餐饮支出偏高" + strings.Repeat("乙", 50)
+	s := searchSnippet(text, "餐饮", 5)
+	assert.Equal(t, "…甲甲甲甲甲餐饮支出偏高乙…", s)
+
+	assert.Equal(t, "短文本", searchSnippet("短文本", "不存在", 5))
+}
+
+func TestSearchHandler_Search(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	mock.MatchExpectationsInOrder(false)
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(user_id = \\? AND \\(description LIKE \\? ESCAPE '\\\\\\\\' OR category LIKE \\? ESCAPE '\\\\\\\\'\\)\\).*ORDER BY CASE WHEN category = \\? OR description = \\? THEN 0 ELSE 1 END, expense_time DESC").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "description", "expense_time"}).
+			AddRow(1, 1, 30, "餐饮", "午餐", now).
+			AddRow(2, 1, 20, "餐饮", "早餐", now))
+	mock.ExpectQuery("SELECT \\* FROM `incomes` WHERE \\(user_id = \\? AND type LIKE \\? ESCAPE '\\\\\\\\'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "type", "income_time"}))
+	mock.ExpectQuery("SELECT \\* FROM `ai_analysis_histories` WHERE \\(user_id = \\? AND result LIKE \\? ESCAPE '\\\\\\\\'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "ai_model_id", "user_id", "start_date", "end_date", "result", "created_at"}).
+			AddRow(3, 1, 1, "2024-01-01", "2024-01-31", "本月餐饮支出最高", now))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/search", NewSearchHandler().Search)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/search?q=%E9%A4%90%E9%A5%AE&limit=1", nil))
+	require.Equal(t, 200, w.Code)
+
+	var resp struct {
+		Data struct {
+			Expenses struct {
+				Items   []map[string]interface{} `json:"items"`
+				HasMore bool                     `json:"has_more"`
+			} `json:"expenses"`
+			Incomes struct {
+				Items []map[string]interface{} `json:"items"`
+			} `json:"incomes"`
+			AIAnalyses struct {
+				Items []SearchAnalysisItem `json:"items"`
+			} `json:"ai_analyses"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(t, resp.Data.Expenses.Items, 1)
+	assert.True(t, resp.Data.Expenses.HasMore)
+	assert.Empty(t, resp.Data.Incomes.Items)
+	require.Len(t, resp.Data.AIAnalyses.Items, 1)
+	assert.Equal(t, "本月餐饮支出最高", resp.Data.AIAnalyses.Items[0].Snippet)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchHandler_Search_InvalidParams(t *testing.T) {
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/search", NewSearchHandler().Search)
+
+	for _, url := range []string{"/search", "/search?q=%20", "/search?q=a&limit=50"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", url, nil))
+		assert.Equal(t, 400, w.Code, url)
+	}
+}
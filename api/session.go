@@ -0,0 +1,262 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RefreshTokenRequest 刷新 token 请求
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// RefreshTokenResponse 刷新 token 响应
+type RefreshTokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// SessionItem 会话列表项
+type SessionItem struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	LastActive time.Time `json:"last_active"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"` // 是否为当前请求所用的会话
+}
+
+// sessionDevice 会话设备描述：优先使用客户端上报，否则取 User-Agent
+func sessionDevice(c *gin.Context, device string) string {
+	if device == "" {
+		device = c.GetHeader("User-Agent")
+	}
+	if len(device) > 255 {
+		device = device[:255]
+	}
+	return device
+}
+
+// refreshTTL refresh token 有效期：取 jwt.refresh_expire_days，未加载配置时使用默认值
+func refreshTTL(cfg *config.Config) time.Duration {
+	if cfg != nil && cfg.JWT.RefreshExpireTime > 0 {
+		return cfg.JWT.RefreshExpireTime
+	}
+	return models.SessionRefreshTTL
+}
+
+// createSession 登录成功后创建会话，返回明文 refresh token（只在此时返回给客户端）
+func createSession(c *gin.Context, userID uint, device string, ttl time.Duration) (*models.Session, string, error) {
+	refreshToken, err := models.GenerateToken()
+	if err != nil {
+		return nil, "", err
+	}
+	now := time.Now()
+	session := models.Session{
+		UserID:           userID,
+		Device:           sessionDevice(c, device),
+		IP:               c.ClientIP(),
+		LastActive:       now,
+		RefreshTokenHash: models.HashRefreshToken(refreshToken),
+		ExpiresAt:        now.Add(ttl),
+	}
+	if err := database.DB.Create(&session).Error; err != nil {
+		return nil, "", err
+	}
+	return &session, refreshToken, nil
+}
+
+// revokeSession 吊销会话（幂等）
+func revokeSession(session *models.Session) error {
+	if session.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now()
+	return database.DB.Model(session).Update("revoked_at", &now).Error
+}
+
+// Refresh 使用 refresh token 换取新的 access token
+// @Summary 刷新 token
+// @Description 使用登录时返回的 refresh_token 换取新的 access token，同时轮换 refresh_token（旧的立即失效）。
+// @Description 已被轮换掉的旧 refresh_token 再次出现时视为被盗用，整个会话立即吊销并返回 401，需重新登录
+// @Tags 认证
+// @Accept json
+// @Produce json
+// @Param request body RefreshTokenRequest true "refresh token"
+// @Success 200 {object} Response{data=RefreshTokenResponse} "刷新成功"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 401 {object} Response "refresh token 无效或已过期"
+// @Router /api/v1/auth/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		BindError(c, err)
+		return
+	}
+
+	hash := models.HashRefreshToken(req.RefreshToken)
+	var session models.Session
+	if err := database.DB.Where("refresh_token_hash = ?", hash).First(&session).Error; err != nil {
+		// 重放检测：轮换前的旧 token 只可能来自泄露，吊销该会话，持有新 token 的一方也需重新登录
+		var reused models.Session
+		if err := database.DB.Where("previous_hash = ?", hash).First(&reused).Error; err == nil {
+			if err := revokeSession(&reused); err != nil {
+				InternalError(c, SafeErrorMessage(err, "吊销会话失败"))
+				return
+			}
+			log.Printf("[安全] 会话 %d（用户ID %d）的旧 refresh token 被重复使用，已吊销该会话，来源 IP %s", reused.ID, reused.UserID, c.ClientIP())
+			Unauthorized(c, "refresh token 已失效，请重新登录")
+			return
+		}
+		Unauthorized(c, "refresh token 无效或已过期")
+		return
+	}
+	if !session.IsActive() {
+		Unauthorized(c, "refresh token 无效或已过期")
+		return
+	}
+
+	var user models.User
+	if err := database.DB.First(&user, session.UserID).Error; err != nil {
+		Unauthorized(c, "用户不存在")
+		return
+	}
+	if user.Status != models.UserStatusActive {
+		Error(c, http.StatusForbidden, "账号已锁定，请联系管理员解锁")
+		return
+	}
+
+	// 轮换 refresh token
+	newRefreshToken, err := models.GenerateToken()
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "生成 token 失败"))
+		return
+	}
+	// 按旧哈希条件更新：并发刷新时只有一个请求能轮换成功，另一个按失效处理
+	now := time.Now()
+	res := database.DB.Model(&session).Where("refresh_token_hash = ?", hash).Updates(map[string]interface{}{
+		"refresh_token_hash": models.HashRefreshToken(newRefreshToken),
+		"previous_hash":      hash,
+		"last_active":        now,
+		"ip":                 c.ClientIP(),
+		"expires_at":         now.Add(refreshTTL(h.cfg)),
+	})
+	if res.Error != nil {
+		InternalError(c, SafeErrorMessage(res.Error, "刷新会话失败"))
+		return
+	}
+	if res.RowsAffected == 0 {
+		Unauthorized(c, "refresh token 无效或已过期")
+		return
+	}
+
+	token, err := middleware.GenerateSessionToken(user.ID, user.Username, session.ID, user.TokenVersion, h.cfg.JWT.ExpireTime)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "生成 token 失败"))
+		return
+	}
+
+	Success(c, RefreshTokenResponse{
+		Token:        token,
+		RefreshToken: newRefreshToken,
+	})
+}
+
+// Logout 退出登录（吊销当前会话的 refresh token）
+// @Summary 退出登录
+// @Description 吊销当前 token 所属会话的 refresh token
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response "退出成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	sessionID := middleware.GetCurrentSessionID(c)
+	if sessionID != 0 {
+		var session models.Session
+		if err := database.DB.Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err == nil {
+			if err := revokeSession(&session); err != nil {
+				InternalError(c, SafeErrorMessage(err, "退出失败"))
+				return
+			}
+		}
+	}
+	SuccessWithMessage(c, "退出成功", nil)
+}
+
+// ListSessions 列出当前用户的活跃会话
+// @Summary 获取登录会话列表
+// @Description 列出当前用户所有未吊销、未过期的登录会话，current=true 为当前请求所用会话
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} Response{data=[]SessionItem} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	currentSessionID := middleware.GetCurrentSessionID(c)
+
+	var sessions []models.Session
+	if err := database.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_active DESC").
+		Find(&sessions).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+
+	items := make([]SessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, SessionItem{
+			ID:         s.ID,
+			Device:     s.Device,
+			IP:         s.IP,
+			LastActive: s.LastActive,
+			CreatedAt:  s.CreatedAt,
+			Current:    s.ID == currentSessionID,
+		})
+	}
+	Success(c, items)
+}
+
+// DeleteSession 下线指定会话
+// @Summary 下线指定会话
+// @Description 吊销指定会话的 refresh token，该设备需重新登录
+// @Tags 认证
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "会话ID"
+// @Success 200 {object} Response "下线成功"
+// @Failure 400 {object} Response "无效的ID"
+// @Failure 404 {object} Response "会话不存在"
+// @Router /api/v1/auth/sessions/{id} [delete]
+func (h *AuthHandler) DeleteSession(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+
+	var session models.Session
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&session).Error; err != nil {
+		NotFound(c, "会话不存在")
+		return
+	}
+	if err := revokeSession(&session); err != nil {
+		InternalError(c, SafeErrorMessage(err, "下线失败"))
+		return
+	}
+	SuccessWithMessage(c, "下线成功", nil)
+}
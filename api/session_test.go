@@ -0,0 +1,156 @@
+package api
+
+import (
+	"bytes"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var sessionColumns = []string{"id", "user_id", "device", "ip", "last_active", "refresh_token_hash", "expires_at", "revoked_at", "created_at", "updated_at"}
+
+func TestAuthHandler_Refresh_RevokedSession(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	revokedAt := time.Now().Add(-time.Minute)
+	mock.ExpectQuery("SELECT .* FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow(1, 1, "iPhone", "127.0.0.1", time.Now(), "hash", time.Now().Add(time.Hour), revokedAt, time.Now(), time.Now()))
+
+	router := gin.New()
+	h := NewAuthHandler(&config.Config{})
+	router.POST("/refresh", h.Refresh)
+
+	req := httptest.NewRequest("POST", "/refresh", bytes.NewBufferString(`{"refresh_token":"abc"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_DeleteSession(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow(3, 1, "iPhone", "127.0.0.1", time.Now(), "hash", time.Now().Add(time.Hour), nil, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET `revoked_at`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	h := NewAuthHandler(&config.Config{})
+	router.DELETE("/sessions/:id", h.DeleteSession)
+
+	req := httptest.NewRequest("DELETE", "/sessions/3", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 200, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_DeleteSession_NotOwned(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionColumns))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	h := NewAuthHandler(&config.Config{})
+	router.DELETE("/sessions/:id", h.DeleteSession)
+
+	req := httptest.NewRequest("DELETE", "/sessions/9", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 404, w.Code)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_Refresh_Rotates(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	cfg := &config.Config{JWT: config.JWTConfig{Secret: "test-secret", ExpireTime: time.Hour, RefreshExpireTime: 7 * 24 * time.Hour}}
+	middleware.InitJWT(cfg)
+
+	hash := models.HashRefreshToken("old-token")
+	mock.ExpectQuery("SELECT .* FROM `sessions` WHERE refresh_token_hash = \\?").
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow(3, 1, "iPhone", "127.0.0.1", time.Now(), hash, time.Now().Add(time.Hour), nil, time.Now(), time.Now()))
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "status"}).AddRow(1, "alice", models.UserStatusActive))
+	// 轮换时记下旧哈希，并以旧哈希为条件防止并发刷新
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET .*`previous_hash`=\\?.* WHERE refresh_token_hash = \\? AND `id` = \\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.POST("/refresh", NewAuthHandler(cfg).Refresh)
+
+	req := httptest.NewRequest("POST", "/refresh", bytes.NewBufferString(`{"refresh_token":"old-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"refresh_token"`)
+	assert.NotContains(t, w.Body.String(), "old-token")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_Refresh_ReuseRevokesSession(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	hash := models.HashRefreshToken("rotated-token")
+	mock.ExpectQuery("SELECT .* FROM `sessions` WHERE refresh_token_hash = \\?").
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(sessionColumns))
+	// 已被轮换掉的旧 token 再次出现：吊销整个会话
+	mock.ExpectQuery("SELECT .* FROM `sessions` WHERE previous_hash = \\?").
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows(sessionColumns).
+			AddRow(3, 1, "iPhone", "127.0.0.1", time.Now(), "new-hash", time.Now().Add(time.Hour), nil, time.Now(), time.Now()))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `sessions` SET `revoked_at`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.POST("/refresh", NewAuthHandler(&config.Config{}).Refresh)
+
+	req := httptest.NewRequest("POST", "/refresh", bytes.NewBufferString(`{"refresh_token":"rotated-token"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 401, w.Code)
+	assert.Contains(t, w.Body.String(), "请重新登录")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTTL(t *testing.T) {
+	assert.Equal(t, models.SessionRefreshTTL, refreshTTL(nil))
+	assert.Equal(t, models.SessionRefreshTTL, refreshTTL(&config.Config{}))
+	cfg := &config.Config{JWT: config.JWTConfig{RefreshExpireTime: 7 * 24 * time.Hour}}
+	assert.Equal(t, 7*24*time.Hour, refreshTTL(cfg))
+}
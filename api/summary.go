@@ -10,33 +10,71 @@ import (
 	"finance/models"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // IncomeExpenseSummaryResponse 支出/收入汇总返回
 type IncomeExpenseSummaryResponse struct {
-	TotalExpense float64 `json:"total_expense" example:"123.45"` // 支出总和
-	TotalIncome  float64 `json:"total_income" example:"5000.00"` // 收入总和
+	StartTime             string   `json:"start_time" example:"2024-03-01"` // 实际使用的开始日期，为空表示不限
+	EndTime               string   `json:"end_time" example:"2024-03-15"`   // 实际使用的结束日期，为空表示不限
+	BaseCurrency          string   `json:"base_currency" example:"CNY"`
+	TotalExpense          float64  `json:"total_expense" example:"123.45"` // 支出总和（本位币）
+	TotalIncome           float64  `json:"total_income" example:"5000.00"` // 收入总和（本位币）
+	UnconvertedCurrencies []string `json:"unconverted_currencies"`         // 缺少汇率、未计入总和的币种
+}
+
+// sumIncomeExpense 在两个查询已有的筛选条件上分别汇总支出与收入，按汇率折算为本位币
+func sumIncomeExpense(expenseQ, incomeQ *gorm.DB) (IncomeExpenseSummaryResponse, error) {
+	resp := IncomeExpenseSummaryResponse{BaseCurrency: baseCurrency()}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		return resp, err
+	}
+	var expenseUnconverted, incomeUnconverted []string
+	if resp.TotalExpense, expenseUnconverted, err = sumInBaseCurrency(expenseQ, rates); err != nil {
+		return resp, err
+	}
+	if resp.TotalIncome, incomeUnconverted, err = sumInBaseCurrency(incomeQ, rates); err != nil {
+		return resp, err
+	}
+	resp.UnconvertedCurrencies = mergeCurrencyCodes(expenseUnconverted, incomeUnconverted)
+	return resp, nil
 }
 
 // GetIncomeExpenseSummary 获取支出和收入汇总（App端，JWT）
 // @Summary 获取支出/收入汇总
-// @Description 按时间范围统计当前用户的支出总和与收入总和。不传 start_time/end_time 则统计全部时间。
+// @Description 按时间范围统计当前用户的支出总和与收入总和，各币种按汇率折算为本位币（base_currency），缺少汇率的币种不计入并列在 unconverted_currencies 中。不传 period/start_time/end_time 时默认统计本月（1 日至今天，按 tz 计算），响应中回显实际使用的范围。
 // @Tags 统计
 // @Produce json
 // @Security BearerAuth
 // @Param start_time query string false "开始时间 (YYYY-MM-DD)，例如 2024-01-01"
 // @Param end_time query string false "结束时间 (YYYY-MM-DD)，例如 2024-12-31"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，如 Asia/Shanghai，默认服务器时区"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
 // @Success 200 {object} Response{data=IncomeExpenseSummaryResponse} "获取成功"
 // @Failure 401 {object} Response "未授权"
 // @Router /api/v1/statistics/summary [get]
 func (h *ExpenseHandler) GetIncomeExpenseSummary(c *gin.Context) {
 	userID := middleware.GetCurrentUserID(c)
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
+	now, err := requestNow(c)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	startTimeStr, endTimeStr, err := resolveUserPeriodQuery(userID, c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	startTimeStr, endTimeStr = defaultToThisMonth(startTimeStr, endTimeStr, now)
 
-	expenseQ := database.DB.Model(&models.Expense{}).Where("user_id = ?", userID)
-	incomeQ := database.DB.Model(&models.Income{}).Where("user_id = ?", userID)
+	expenseQ := database.DB.Model(&models.Expense{}).Scopes(recordScope(c, userID))
+	incomeQ := database.DB.Model(&models.Income{}).Scopes(recordScope(c, userID))
+	if !includeTransfer(c) {
+		expenseQ = excludeTransferCategories(expenseQ, "category")
+	}
 
 	if startTimeStr != "" {
 		if t, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local); err == nil {
@@ -52,25 +90,26 @@ func (h *ExpenseHandler) GetIncomeExpenseSummary(c *gin.Context) {
 		}
 	}
 
-	var totalExpense float64
-	var totalIncome float64
-	expenseQ.Select("COALESCE(SUM(amount), 0)").Scan(&totalExpense)
-	incomeQ.Select("COALESCE(SUM(amount), 0)").Scan(&totalIncome)
-
-	Success(c, IncomeExpenseSummaryResponse{
-		TotalExpense: totalExpense,
-		TotalIncome:  totalIncome,
-	})
+	resp, err := sumIncomeExpense(expenseQ, incomeQ)
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	resp.StartTime, resp.EndTime = startTimeStr, endTimeStr
+	Success(c, resp)
 }
 
 // AdminIncomeExpenseSummary 获取支出和收入汇总（后台，Cookie）
 // @Summary 获取支出/收入汇总（后台）
-// @Description 按时间范围统计支出总和与收入总和。管理员可传user_id统计指定用户，非管理员只能统计自己的数据（忽略user_id）。不传start_time/end_time则统计全部时间。
+// @Description 按时间范围统计支出总和与收入总和（按汇率折算为本位币，缺少汇率的币种列在unconverted_currencies中）。管理员可传user_id统计指定用户，非管理员只能统计自己的数据（忽略user_id）。不传period/start_time/end_time时默认统计本月（按tz计算），响应中回显实际使用的范围。
 // @Tags 后台管理-统计
 // @Produce json
 // @Param start_time query string false "开始时间 (YYYY-MM-DD)，例如 2024-01-01"
 // @Param end_time query string false "结束时间 (YYYY-MM-DD)，例如 2024-12-31"
+// @Param period query string false "快捷时间范围（today/this_week/this_month/last_month/this_year/last_7d/last_30d），与 start_time/end_time 互斥"
+// @Param tz query string false "IANA 时区，默认服务器时区"
 // @Param user_id query int false "用户ID（仅管理员可用）"
+// @Param include_transfer query bool false "是否包含内部转账类别，默认不包含"
 // @Success 200 {object} map[string]interface{} "获取成功，返回支出总和和收入总和"
 // @Failure 401 {object} map[string]interface{} "未登录"
 // @Router /admin/statistics/summary [get]
@@ -81,8 +120,17 @@ func (h *AdminHandler) AdminIncomeExpenseSummary(c *gin.Context) {
 		return
 	}
 
-	startTimeStr := c.Query("start_time")
-	endTimeStr := c.Query("end_time")
+	now, err := requestNow(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	startTimeStr, endTimeStr, err := resolvePeriodQuery(c.Query("period"), c.Query("start_time"), c.Query("end_time"), now)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	startTimeStr, endTimeStr = defaultToThisMonth(startTimeStr, endTimeStr, now)
 	userIDFilter := c.Query("user_id")
 
 	targetUserID := currentUser.ID
@@ -94,6 +142,9 @@ func (h *AdminHandler) AdminIncomeExpenseSummary(c *gin.Context) {
 
 	expenseQ := database.DB.Model(&models.Expense{}).Where("user_id = ?", targetUserID)
 	incomeQ := database.DB.Model(&models.Income{}).Where("user_id = ?", targetUserID)
+	if !includeTransfer(c) {
+		expenseQ = excludeTransferCategories(expenseQ, "category")
+	}
 
 	if startTimeStr != "" {
 		if t, err := time.ParseInLocation("2006-01-02", startTimeStr, time.Local); err == nil {
@@ -109,16 +160,21 @@ func (h *AdminHandler) AdminIncomeExpenseSummary(c *gin.Context) {
 		}
 	}
 
-	var totalExpense float64
-	var totalIncome float64
-	expenseQ.Select("COALESCE(SUM(amount), 0)").Scan(&totalExpense)
-	incomeQ.Select("COALESCE(SUM(amount), 0)").Scan(&totalIncome)
+	sums, err := sumIncomeExpense(expenseQ, incomeQ)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"data": gin.H{
-			"total_expense": totalExpense,
-			"total_income":  totalIncome,
+			"start_time":             startTimeStr,
+			"end_time":               endTimeStr,
+			"base_currency":          sums.BaseCurrency,
+			"total_expense":          sums.TotalExpense,
+			"total_income":           sums.TotalIncome,
+			"unconverted_currencies": sums.UnconvertedCurrencies,
 		},
 	})
 }
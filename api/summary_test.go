@@ -0,0 +1,48 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpenseHandler_GetIncomeExpenseSummary_ConvertsCurrency(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses` .*GROUP BY `currency`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).
+			AddRow("CNY", 100).
+			AddRow("USD", 10).
+			AddRow("JPY", 1000))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `incomes` .*GROUP BY `currency`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).
+			AddRow("", 5000).
+			AddRow("EUR", 20))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/statistics/summary", NewExpenseHandler().GetIncomeExpenseSummary)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/statistics/summary?start_time=2024-01-01&end_time=2024-01-31&include_transfer=true", nil))
+
+	require.Equal(t, 200, w.Code, w.Body.String())
+	require.NoError(t, mock.ExpectationsWereMet())
+	var resp struct {
+		Data IncomeExpenseSummaryResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "2024-01-01", resp.Data.StartTime)
+	assert.Equal(t, "CNY", resp.Data.BaseCurrency)
+	assert.Equal(t, 170.0, resp.Data.TotalExpense)
+	assert.Equal(t, 5000.0, resp.Data.TotalIncome)
+	assert.Equal(t, []string{"EUR", "JPY"}, resp.Data.UnconvertedCurrencies)
+}
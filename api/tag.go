@@ -0,0 +1,391 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"finance/database"
+	"finance/middleware"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// TagHandler 消费标签处理器（App端）
+type TagHandler struct{}
+
+// NewTagHandler 创建消费标签处理器
+func NewTagHandler() *TagHandler {
+	return &TagHandler{}
+}
+
+// RenameTagRequest 重命名标签请求
+type RenameTagRequest struct {
+	Name string `json:"name" binding:"required" example:"出差"`
+}
+
+// MergeTagRequest 合并标签请求
+type MergeTagRequest struct {
+	TargetID uint `json:"target_id" binding:"required" example:"1"`
+}
+
+// ExpenseTagShare 按标签统计的消费；一条记录有多个标签时计入每个标签，各标签之和可能超过总金额
+type ExpenseTagShare struct {
+	Tag        string  `json:"tag"`
+	Total      float64 `json:"total"`      // 折合本位币金额
+	Count      int64   `json:"count"`      // 记录数
+	Percentage float64 `json:"percentage"` // 占总金额的百分比
+}
+
+// parseTagNames 清理标签名（合并空白）并按去重键去重，保留首次出现的写法；返回 nil 表示没有标签
+func parseTagNames(raw []string) ([]string, error) {
+	var names []string
+	seen := map[string]bool{}
+	for _, r := range raw {
+		name := models.CleanTagName(r)
+		if name == "" {
+			return nil, errors.New("标签名不能为空")
+		}
+		if utf8.RuneCountInString(name) > models.MaxTagNameLength {
+			return nil, fmt.Errorf("标签名不能超过%d个字符", models.MaxTagNameLength)
+		}
+		key := models.NormalizeTagName(name)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		names = append(names, name)
+	}
+	if len(names) > models.MaxTagsPerExpense {
+		return nil, fmt.Errorf("每条记录最多%d个标签", models.MaxTagsPerExpense)
+	}
+	return names, nil
+}
+
+// ensureTags 按去重键查找用户已有的标签，不存在的自动创建，返回与 names 顺序一致的标签ID
+func ensureTags(tx *gorm.DB, userID uint, names []string) ([]uint, error) {
+	keys := make([]string, len(names))
+	for i, name := range names {
+		keys[i] = models.NormalizeTagName(name)
+	}
+	var existing []models.Tag
+	if err := tx.Where("user_id = ? AND normalized_name IN ?", userID, keys).Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	byKey := make(map[string]uint, len(existing))
+	for _, t := range existing {
+		byKey[t.NormalizedName] = t.ID
+	}
+
+	ids := make([]uint, len(names))
+	for i, name := range names {
+		id, ok := byKey[keys[i]]
+		if !ok {
+			tag := models.Tag{UserID: userID, Name: name, NormalizedName: keys[i]}
+			if err := tx.Create(&tag).Error; err != nil {
+				return nil, err
+			}
+			id = tag.ID
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// attachExpenseTags 给消费记录（分期时为全部各期）打上标签，未知标签自动创建
+func attachExpenseTags(tx *gorm.DB, userID uint, expenseIDs []uint, names []string) error {
+	if len(names) == 0 || len(expenseIDs) == 0 {
+		return nil
+	}
+	tagIDs, err := ensureTags(tx, userID, names)
+	if err != nil {
+		return err
+	}
+	links := make([]models.ExpenseTag, 0, len(expenseIDs)*len(tagIDs))
+	for _, expenseID := range expenseIDs {
+		for _, tagID := range tagIDs {
+			links = append(links, models.ExpenseTag{ExpenseID: expenseID, TagID: tagID})
+		}
+	}
+	return tx.Create(&links).Error
+}
+
+// replaceExpenseTags 用 names 整体替换消费记录的标签，names 为空时清空
+func replaceExpenseTags(tx *gorm.DB, userID, expenseID uint, names []string) error {
+	if err := tx.Where("expense_id = ?", expenseID).Delete(&models.ExpenseTag{}).Error; err != nil {
+		return err
+	}
+	return attachExpenseTags(tx, userID, []uint{expenseID}, names)
+}
+
+// loadExpenseTags 一次查询带出各消费记录的标签名（按名称排序）
+func loadExpenseTags(expenses []models.Expense) error {
+	if len(expenses) == 0 {
+		return nil
+	}
+	ids := make([]uint, len(expenses))
+	for i, e := range expenses {
+		ids[i] = e.ID
+	}
+	var rows []struct {
+		ExpenseID uint
+		Name      string
+	}
+	if err := database.DB.Table("expense_tags").
+		Select("expense_tags.expense_id, tags.name").
+		Joins("JOIN tags ON tags.id = expense_tags.tag_id").
+		Where("expense_tags.expense_id IN ?", ids).
+		Order("tags.name ASC").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+	byExpense := map[uint][]string{}
+	for _, r := range rows {
+		byExpense[r.ExpenseID] = append(byExpense[r.ExpenseID], r.Name)
+	}
+	for i := range expenses {
+		expenses[i].Tags = byExpense[expenses[i].ID]
+	}
+	return nil
+}
+
+// applyTagFilter 按标签筛选消费记录（tags 逗号分隔，需同时带有全部标签）；按去重键匹配，
+// 不限标签归属用户，共享账本中可按同名标签筛选全体成员的记录
+func applyTagFilter(query *gorm.DB, tags string) (*gorm.DB, error) {
+	if tags == "" {
+		return query, nil
+	}
+	var keys []string
+	seen := map[string]bool{}
+	for _, name := range splitQueryList(tags) {
+		key := models.NormalizeTagName(name)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("tags 不能为空")
+	}
+	matched := database.DB.Model(&models.ExpenseTag{}).
+		Select("expense_tags.expense_id").
+		Joins("JOIN tags ON tags.id = expense_tags.tag_id").
+		Where("tags.normalized_name IN ?", keys).
+		Group("expense_tags.expense_id").
+		Having("COUNT(DISTINCT tags.normalized_name) = ?", len(keys))
+	return query.Where("id IN (?)", matched), nil
+}
+
+// queryTagStatistics 在 query（消费记录的筛选条件）上按标签与币种汇总，折算为本位币，占比相对 total 计算
+func queryTagStatistics(query *gorm.DB, total float64) ([]ExpenseTagShare, error) {
+	tagged := query.Joins("JOIN (SELECT expense_tags.expense_id, tags.name AS tag_name FROM expense_tags JOIN tags ON tags.id = expense_tags.tag_id) AS et ON et.expense_id = expenses.id")
+	summary, err := queryCurrencySummary(tagged, "et.tag_name")
+	if err != nil {
+		return nil, err
+	}
+	shares := withCategoryPercentage(summary.Categories, total)
+	out := make([]ExpenseTagShare, len(shares))
+	for i, s := range shares {
+		out[i] = ExpenseTagShare{Tag: s.Category, Total: s.Total, Count: s.Count, Percentage: s.Percentage}
+	}
+	return out, nil
+}
+
+// findTag 查找当前用户的标签
+func findTag(userID, id uint) (*models.Tag, error) {
+	var t models.Tag
+	if err := database.DB.Where("id = ? AND user_id = ?", id, userID).First(&t).Error; err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// List 获取标签列表
+// @Summary 获取标签列表
+// @Description 获取当前用户的标签，按使用次数（打了该标签的消费笔数）倒序，可按名称模糊搜索
+// @Tags 标签
+// @Produce json
+// @Security BearerAuth
+// @Param keyword query string false "名称关键词"
+// @Success 200 {object} Response{data=[]models.Tag} "获取成功"
+// @Failure 401 {object} Response "未授权"
+// @Router /api/v1/tags [get]
+func (h *TagHandler) List(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+
+	query := database.DB.Model(&models.Tag{}).
+		Select("tags.*, (SELECT COUNT(*) FROM expense_tags JOIN expenses ON expenses.id = expense_tags.expense_id WHERE expense_tags.tag_id = tags.id AND expenses.deleted_at IS NULL) AS usage_count").
+		Where("tags.user_id = ?", userID)
+	if kw := strings.TrimSpace(c.Query("keyword")); kw != "" {
+		query = query.Where("tags.name LIKE ?"+likeEscape(query), "%"+escapeLikeValue(kw)+"%")
+	}
+
+	var list []models.Tag
+	if err := query.Order("usage_count DESC, tags.id ASC").Find(&list).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	Success(c, list)
+}
+
+// Rename 重命名标签
+// @Summary 重命名标签
+// @Description 修正标签名（如改正错别字），所有打了该标签的消费记录随之生效；忽略大小写和多余空白后与其他标签同名时返回 400，此时应使用合并
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "标签ID"
+// @Param request body RenameTagRequest true "新名称"
+// @Success 200 {object} Response{data=models.Tag} "更新成功"
+// @Failure 400 {object} Response "请求参数错误或标签名重复"
+// @Failure 404 {object} Response "标签不存在"
+// @Router /api/v1/tags/{id} [put]
+func (h *TagHandler) Rename(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	tag, err := findTag(userID, uint(id))
+	if err != nil {
+		NotFound(c, "标签不存在")
+		return
+	}
+	var req RenameTagRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	names, err := parseTagNames([]string{req.Name})
+	if err != nil {
+		BadRequest(c, err.Error())
+		return
+	}
+	name := names[0]
+	normalized := models.NormalizeTagName(name)
+
+	var count int64
+	if err := database.DB.Model(&models.Tag{}).
+		Where("user_id = ? AND normalized_name = ? AND id <> ?", userID, normalized, tag.ID).
+		Count(&count).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "查询失败"))
+		return
+	}
+	if count > 0 {
+		BadRequest(c, "标签「"+name+"」已存在，请使用合并")
+		return
+	}
+
+	if err := database.DB.Model(tag).Updates(map[string]interface{}{
+		"name":            name,
+		"normalized_name": normalized,
+	}).Error; err != nil {
+		InternalError(c, SafeErrorMessage(err, "更新失败"))
+		return
+	}
+	database.DB.First(tag, tag.ID)
+	SuccessWithMessage(c, "更新成功", tag)
+}
+
+// Delete 删除标签
+// @Summary 删除标签
+// @Description 删除标签并去掉所有消费记录上的该标签，消费记录本身保留
+// @Tags 标签
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "标签ID"
+// @Success 200 {object} Response "删除成功"
+// @Failure 404 {object} Response "标签不存在"
+// @Router /api/v1/tags/{id} [delete]
+func (h *TagHandler) Delete(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	tag, err := findTag(userID, uint(id))
+	if err != nil {
+		NotFound(c, "标签不存在")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.ExpenseTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(tag).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "删除失败"))
+		return
+	}
+	SuccessWithMessage(c, "删除成功", nil)
+}
+
+// Merge 合并标签
+// @Summary 合并标签
+// @Description 将该标签（如错拼的"出查"）下的消费记录全部改打目标标签（如"出差"）后删除该标签；已同时带有两个标签的记录只保留目标标签
+// @Tags 标签
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "被合并的标签ID"
+// @Param request body MergeTagRequest true "目标标签"
+// @Success 200 {object} Response{data=models.Tag} "合并成功，返回目标标签"
+// @Failure 400 {object} Response "请求参数错误"
+// @Failure 404 {object} Response "标签不存在"
+// @Router /api/v1/tags/{id}/merge [post]
+func (h *TagHandler) Merge(c *gin.Context) {
+	userID := middleware.GetCurrentUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		BadRequest(c, "无效的ID")
+		return
+	}
+	var req MergeTagRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	if req.TargetID == uint(id) {
+		BadRequest(c, "不能合并到自身")
+		return
+	}
+	source, err := findTag(userID, uint(id))
+	if err != nil {
+		NotFound(c, "标签不存在")
+		return
+	}
+	target, err := findTag(userID, req.TargetID)
+	if err != nil {
+		NotFound(c, "目标标签不存在")
+		return
+	}
+
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		// 已带目标标签的记录不改挂（主键冲突），随后连同源标签的关联一起删除；
+		// MySQL 不允许在 UPDATE 的子查询中直接引用被更新的表，需包一层派生表
+		if err := tx.Model(&models.ExpenseTag{}).
+			Where("tag_id = ? AND expense_id NOT IN (SELECT expense_id FROM (SELECT expense_id FROM expense_tags WHERE tag_id = ?) AS t)", source.ID, target.ID).
+			Update("tag_id", target.ID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("tag_id = ?", source.ID).Delete(&models.ExpenseTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(source).Error
+	})
+	if err != nil {
+		InternalError(c, SafeErrorMessage(err, "合并失败"))
+		return
+	}
+	SuccessWithMessage(c, "合并成功", target)
+}
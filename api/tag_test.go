@@ -0,0 +1,280 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTagNames(t *testing.T) {
+	names, err := parseTagNames([]string{" 出差 ", "Travel", "travel", "road  trip"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"出差", "Travel", "road trip"}, names)
+
+	names, err = parseTagNames(nil)
+	require.NoError(t, err)
+	assert.Nil(t, names)
+
+	_, err = parseTagNames([]string{"  "})
+	assert.Error(t, err)
+	_, err = parseTagNames([]string{strings.Repeat("长", 21)})
+	assert.Error(t, err)
+
+	many := make([]string, 11)
+	for i := range many {
+		many[i] = strings.Repeat("a", i+1)
+	}
+	_, err = parseTagNames(many)
+	assert.Error(t, err)
+}
+
+func TestExpenseHandler_Create_WithTags(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "交通"))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").
+		WillReturnResult(sqlmock.NewResult(9, 1))
+	// "出差" 已存在（按去重键匹配），"报销" 自动创建
+	mock.ExpectQuery("SELECT \\* FROM `tags` WHERE user_id = \\? AND normalized_name IN \\(\\?,\\?\\)").
+		WithArgs(1, "出差", "报销").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "normalized_name"}).AddRow(3, 1, "出差", "出差"))
+	mock.ExpectExec("INSERT INTO `tags`").
+		WithArgs(1, "报销", "报销", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectExec("INSERT INTO `expense_tags` \\(`expense_id`,`tag_id`\\) VALUES \\(\\?,\\?\\),\\(\\?,\\?\\)").
+		WithArgs(9, 3, 9, 4).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":35,"category":"交通","expense_time":"2024-01-15 08:30:00","tags":["出差","报销"," 出差 "]}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, []interface{}{"出差", "报销"}, resp.Data["tags"])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Create_TooManyTags(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT .* FROM `expense_categories`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow(2, "交通"))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/expenses", NewExpenseHandler().Create)
+
+	body := `{"amount":35,"category":"交通","expense_time":"2024-01-15 08:30:00","tags":["a","b","c","d","e","f","g","h","i","j","k"]}`
+	req := httptest.NewRequest("POST", "/expenses", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "最多10个标签")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_List_FilterByTags(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	// 需同时带有全部标签：按记录分组后命中的标签数等于筛选的标签数
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `expenses` WHERE id IN \\(SELECT expense_tags.expense_id FROM `expense_tags` JOIN tags ON tags.id = expense_tags.tag_id WHERE tags.normalized_name IN \\(\\?,\\?\\) GROUP BY `expense_tags`.`expense_id` HAVING COUNT\\(DISTINCT tags.normalized_name\\) = \\?\\) AND user_id = \\?").
+		WithArgs("出差", "travel", 2, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE id IN \\(SELECT expense_tags.expense_id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "expense_time"}).
+			AddRow(7, 1, 120, "交通", time.Now()))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags` JOIN tags ON tags.id = expense_tags.tag_id WHERE expense_tags.expense_id IN \\(\\?\\) ORDER BY tags.name ASC").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}).
+			AddRow(7, "Travel").
+			AddRow(7, "出差"))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses", NewExpenseHandler().List)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses?tags=出差,Travel,出差", nil))
+
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"tags":["Travel","出差"]`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestExpenseHandler_Update_ClearTags(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE \\(id = \\? AND user_id = \\?\\)").
+		WithArgs(7, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category"}).AddRow(7, 1, 120, "交通"))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expenses` SET `updated_at`=\\?").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE expense_id = \\?").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT \\* FROM `expenses`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category"}).AddRow(7, 1, 120, "交通"))
+	mock.ExpectQuery("SELECT expense_tags.expense_id, tags.name FROM `expense_tags`").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"expense_id", "name"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.PUT("/expenses/:id", NewExpenseHandler().Update)
+
+	req := httptest.NewRequest("PUT", "/expenses/7", bytes.NewBufferString(`{"tags":[]}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.NotContains(t, w.Body.String(), `"tags"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTagHandler_Rename_Duplicate(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `tags` WHERE id = \\? AND user_id = \\?").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "normalized_name"}).AddRow(5, 1, "出查", "出查"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `tags` WHERE user_id = \\? AND normalized_name = \\? AND id <> \\?").
+		WithArgs(1, "出差", 5).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.PUT("/tags/:id", NewTagHandler().Rename)
+
+	req := httptest.NewRequest("PUT", "/tags/5", bytes.NewBufferString(`{"name":"出差"}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+	assert.Contains(t, w.Body.String(), "请使用合并")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTagHandler_Merge(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `tags`").
+		WithArgs(5, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(5, 1, "出查"))
+	mock.ExpectQuery("SELECT \\* FROM `tags`").
+		WithArgs(3, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name"}).AddRow(3, 1, "出差"))
+	mock.ExpectBegin()
+	// 已带目标标签的记录不改挂，避免主键冲突
+	mock.ExpectExec("UPDATE `expense_tags` SET `tag_id`=\\? WHERE tag_id = \\? AND expense_id NOT IN \\(SELECT expense_id FROM \\(SELECT expense_id FROM expense_tags WHERE tag_id = \\?\\) AS t\\)").
+		WithArgs(3, 5, 3).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE tag_id = \\?").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `tags` WHERE `tags`.`id` = \\?").
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/tags/:id/merge", NewTagHandler().Merge)
+
+	req := httptest.NewRequest("POST", "/tags/5/merge", bytes.NewBufferString(`{"target_id":3}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"name":"出差"`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTagHandler_Merge_Self(t *testing.T) {
+	_, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.POST("/tags/:id/merge", NewTagHandler().Merge)
+
+	req := httptest.NewRequest("POST", "/tags/5/merge", bytes.NewBufferString(`{"target_id":5}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
+
+func TestExpenseHandler_GetDetailedStatistics_ByTag(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT category AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses` WHERE user_id = \\?.* GROUP BY category, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("交通", "CNY", 300, 3).
+			AddRow("餐饮", "CNY", 100, 2))
+	mock.ExpectQuery("SELECT .* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}))
+	// 一条记录有多个标签时计入每个标签
+	mock.ExpectQuery("SELECT et.tag_name AS category, currency, SUM\\(amount\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses` JOIN \\(SELECT expense_tags.expense_id, tags.name AS tag_name FROM expense_tags JOIN tags ON tags.id = expense_tags.tag_id\\) AS et ON et.expense_id = expenses.id WHERE user_id = \\?.* GROUP BY et.tag_name, currency").
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("出差", "CNY", 200, 2).
+			AddRow("报销", "CNY", 200, 2))
+	mock.ExpectQuery("SELECT .* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}))
+
+	router := gin.New()
+	router.Use(setUserIDMiddleware(1))
+	router.GET("/expenses/detailed-statistics", NewExpenseHandler().GetDetailedStatistics)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/expenses/detailed-statistics?range_type=year&year=2024&by_tag=true", nil))
+
+	require.Equal(t, 200, w.Code)
+	var resp struct {
+		Data struct {
+			TotalAmount float64           `json:"total_amount"`
+			TagStats    []ExpenseTagShare `json:"tag_stats"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 400.0, resp.Data.TotalAmount)
+	require.Len(t, resp.Data.TagStats, 2)
+	assert.Equal(t, "出差", resp.Data.TagStats[0].Tag)
+	assert.Equal(t, 50.0, resp.Data.TagStats[0].Percentage)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,248 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"net/http"
+	"net/mail"
+	"strings"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// 批量导入用户限制
+const (
+	maxUserImportRows     = 100     // 单次最多导入的数据行数（不含表头）
+	maxUserImportFileSize = 1 << 20 // CSV 文件大小上限 1MB
+)
+
+// userImportRow 校验通过、待创建的行
+type userImportRow struct {
+	index    int // 在数据行中的位置（从 0 开始，不含表头）
+	user     models.User
+	password string // 明文初始密码，仅用于发送邮件
+}
+
+// parseUserImportCSV 读取 CSV：用户名,邮箱,初始密码,角色code,状态；首行为表头时自动跳过
+func parseUserImportCSV(r io.Reader) ([][]string, int, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, 0, errors.New("CSV 解析失败: " + err.Error())
+	}
+	if len(records) > 0 && len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\xEF\xBB\xBF")
+	}
+
+	// 表头行
+	offset := 0
+	if len(records) > 0 && len(records[0]) > 0 {
+		first := strings.ToLower(strings.TrimSpace(records[0][0]))
+		if first == "username" || first == "用户名" {
+			offset = 1
+		}
+	}
+	rows := records[offset:]
+	if len(rows) == 0 {
+		return nil, 0, errors.New("CSV 中没有数据行")
+	}
+	if len(rows) > maxUserImportRows {
+		return nil, 0, errors.New("单次最多导入 100 个用户")
+	}
+	return rows, offset, nil
+}
+
+// ImportUsers 批量导入用户（仅超级管理员）
+// @Summary 批量导入用户
+// @Description 上传 CSV（列：用户名,邮箱,初始密码,角色code,状态），逐行校验用户名/邮箱唯一、角色存在后在事务中批量创建，返回统一的批量结果（failures 的 index 为数据行位置，从 0 开始，不含表头）。单次最多 100 行。send_email=true 时给有邮箱的用户发送初始密码邮件
+// @Tags 后台管理-用户管理
+// @Accept multipart/form-data
+// @Produce json
+// @Param file formData file true "CSV 文件"
+// @Param send_email formData bool false "是否发送初始密码邮件"
+// @Success 200 {object} map[string]interface{} "导入完成，data 为 BatchResult"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/users/import [post]
+func (h *AdminHandler) ImportUsers(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "请上传 CSV 文件"})
+		return
+	}
+	if fileHeader.Size > maxUserImportFileSize {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "文件不能超过 1MB"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "读取文件失败"})
+		return
+	}
+	defer file.Close()
+
+	rows, _, err := parseUserImportCSV(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "导入完成",
+		"data":    importUserRows(rows, c.PostForm("send_email") == "true"),
+	})
+}
+
+// importUserRows 逐行校验后在同一事务中创建用户，失败项的 index 为数据行位置（从 0 开始，不含表头）
+func importUserRows(rows [][]string, sendEmail bool) *BatchResult {
+	// 角色 code -> ID
+	var roles []models.Role
+	database.DB.Find(&roles)
+	roleIDs := make(map[string]uint, len(roles))
+	for _, r := range roles {
+		roleIDs[r.Code] = r.ID
+	}
+
+	result := NewBatchResult(len(rows))
+	var pending []userImportRow
+	seenUsernames := map[string]bool{}
+	seenEmails := map[string]bool{}
+	for i, rec := range rows {
+		field := func(idx int) string {
+			if idx < len(rec) {
+				return strings.TrimSpace(rec[idx])
+			}
+			return ""
+		}
+		username, email, password, roleCode, status := field(0), field(1), field(2), field(3), field(4)
+
+		switch {
+		case len(username) < 3 || len(username) > 50:
+			result.FailIndex(i, username, "用户名长度需在 3-50 之间")
+			continue
+		case len(password) < 6 || len(password) > 50:
+			result.FailIndex(i, username, "初始密码长度需在 6-50 之间")
+			continue
+		case seenUsernames[username]:
+			result.FailIndex(i, username, "用户名在文件中重复")
+			continue
+		}
+		if email != "" {
+			if _, err := mail.ParseAddress(email); err != nil {
+				result.FailIndex(i, username, "邮箱格式错误")
+				continue
+			}
+			if seenEmails[strings.ToLower(email)] {
+				result.FailIndex(i, username, "邮箱在文件中重复")
+				continue
+			}
+		}
+		if status == "" {
+			status = models.UserStatusLocked
+		}
+		if status != models.UserStatusActive && status != models.UserStatusLocked {
+			result.FailIndex(i, username, "状态只能是 active 或 locked")
+			continue
+		}
+		var roleID *uint
+		if roleCode != "" {
+			id, ok := roleIDs[roleCode]
+			if !ok {
+				result.FailIndex(i, username, "角色不存在: "+roleCode)
+				continue
+			}
+			roleID = &id
+		}
+
+		var count int64
+		database.DB.Model(&models.User{}).Where("username = ?", username).Count(&count)
+		if count > 0 {
+			result.FailIndex(i, username, "用户名已存在")
+			continue
+		}
+		if email != "" {
+			database.DB.Model(&models.User{}).Where("email = ?", email).Count(&count)
+			if count > 0 {
+				result.FailIndex(i, username, "邮箱已被使用")
+				continue
+			}
+		}
+
+		hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			result.FailIndex(i, username, "密码加密失败")
+			continue
+		}
+
+		seenUsernames[username] = true
+		if email != "" {
+			seenEmails[strings.ToLower(email)] = true
+		}
+		pending = append(pending, userImportRow{
+			index:    i,
+			password: password,
+			user: models.User{
+				Username: username,
+				Password: string(hashed),
+				Email:    email,
+				RoleID:   roleID,
+				Status:   status,
+			},
+		})
+	}
+	if len(pending) == 0 {
+		return result
+	}
+
+	// 校验通过的行在同一事务中创建，任一失败则全部回滚
+	items := make([]BatchPending, len(pending))
+	for i, p := range pending {
+		items[i] = BatchPending{Index: p.index, Key: p.user.Username}
+	}
+	ok := result.CommitTx(items, "创建用户失败", func(tx *gorm.DB) error {
+		for i := range pending {
+			if err := tx.Create(&pending[i].user).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if !ok {
+		return result
+	}
+
+	if sendEmail {
+		emailService := service.NewEmailService(&config.GetConfig().Email)
+		for _, p := range pending {
+			if p.user.Email == "" {
+				continue
+			}
+			if err := emailService.SendInitialPasswordEmail(p.user.Email, p.user.Username, p.password); err != nil {
+				result.WarnIndex(p.index, p.user.Username, "创建成功，初始密码邮件发送失败")
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUserImportCSV_SkipHeaderAndBOM(t *testing.T) {
+	data := "\xEF\xBB\xBFusername,email,password,role_code,status\nalice,alice@x.com,secret1,,active\nbob,,secret2,viewer,\n"
+	rows, offset, err := parseUserImportCSV(strings.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 1, offset)
+	require.Len(t, rows, 2)
+	assert.Equal(t, "alice", rows[0][0])
+	assert.Equal(t, "viewer", rows[1][3])
+}
+
+func TestParseUserImportCSV_NoHeader(t *testing.T) {
+	rows, offset, err := parseUserImportCSV(strings.NewReader("alice,alice@x.com,secret1\n"))
+	require.NoError(t, err)
+	assert.Equal(t, 0, offset)
+	assert.Len(t, rows, 1)
+}
+
+func TestParseUserImportCSV_Limits(t *testing.T) {
+	_, _, err := parseUserImportCSV(strings.NewReader("用户名,邮箱,初始密码\n"))
+	assert.Error(t, err)
+
+	var b strings.Builder
+	for i := 0; i <= maxUserImportRows; i++ {
+		fmt.Fprintf(&b, "user%d,,secret%d\n", i, i)
+	}
+	_, _, err = parseUserImportCSV(strings.NewReader(b.String()))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "100")
+}
+
+func TestImportUserRows(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `roles`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "code"}).AddRow(2, "viewer"))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE username = \\?").
+		WithArgs("bob").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery("SELECT count\\(\\*\\) FROM `users` WHERE email = \\?").
+		WithArgs("bob@x.com").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `users`").
+		WillReturnResult(sqlmock.NewResult(5, 1))
+	mock.ExpectCommit()
+
+	rows := [][]string{
+		{"ab", "", "secret1"},
+		{"bob", "bob@x.com", "secret1", "viewer", "active"},
+		{"carol", "", "secret1", "nope"},
+	}
+	result := importUserRows(rows, false)
+
+	assert.Equal(t, 3, result.Total)
+	assert.Equal(t, 1, result.SuccessCount)
+	assert.Equal(t, 2, result.FailCount)
+	require.Len(t, result.Failures, 2)
+	assert.Equal(t, 0, *result.Failures[0].Index)
+	assert.Equal(t, "ab", result.Failures[0].Key)
+	assert.Equal(t, 2, *result.Failures[1].Index)
+	assert.Contains(t, result.Failures[1].Reason, "角色不存在")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
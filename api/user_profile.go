@@ -0,0 +1,167 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// userProfileRecentLimit 用户画像中最近记录的条数
+const userProfileRecentLimit = 5
+
+// UserProfileSummary 用户收支统计摘要（不含已删除的记录）
+type UserProfileSummary struct {
+	BaseCurrency string  `json:"base_currency"`
+	ExpenseTotal float64 `json:"expense_total"` // 本位币合计，不含缺少汇率的币种
+	ExpenseCount int64   `json:"expense_count"`
+	IncomeTotal  float64 `json:"income_total"` // 本位币合计，不含缺少汇率的币种
+	IncomeCount  int64   `json:"income_count"`
+	LastLoginAt  *string `json:"last_login_at"` // App 端最近一次登录/刷新时间，从未登录为 null
+	// 缺少汇率、未计入合计的币种
+	UnconvertedCurrencies []string `json:"unconverted_currencies"`
+}
+
+// UserProfile 后台用户画像
+type UserProfile struct {
+	User           models.User        `json:"user"`
+	Role           *models.Role       `json:"role"`       // 未分配角色为 null
+	DeletedAt      *string            `json:"deleted_at"` // 已删除（软删除）的用户返回删除时间，否则为 null
+	Summary        UserProfileSummary `json:"summary"`
+	RecentExpenses []models.Expense   `json:"recent_expenses"`
+	RecentIncomes  []models.Income    `json:"recent_incomes"`
+}
+
+// loadUserProfile 聚合用户画像：用户（含已软删除）、角色、统计摘要、最近记录
+func loadUserProfile(userID uint) (*UserProfile, error) {
+	var user models.User
+	if err := database.DB.Unscoped().First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	profile := &UserProfile{
+		User:           user,
+		RecentExpenses: []models.Expense{},
+		RecentIncomes:  []models.Income{},
+	}
+	if user.DeletedAt.Valid {
+		s := models.FormatTime(user.DeletedAt.Time)
+		profile.DeletedAt = &s
+	}
+	if user.RoleID != nil {
+		var role models.Role
+		if err := database.DB.First(&role, *user.RoleID).Error; err == nil {
+			profile.Role = &role
+		}
+	}
+
+	// 笔数与最近登录一次查询完成，金额按币种汇总后折算为本位币
+	var row struct {
+		ExpenseCount int64
+		IncomeCount  int64
+		LastLoginAt  *time.Time
+	}
+	err := database.DB.Raw(`SELECT
+		(SELECT COUNT(*) FROM expenses WHERE user_id = ? AND deleted_at IS NULL) AS expense_count,
+		(SELECT COUNT(*) FROM incomes WHERE user_id = ? AND deleted_at IS NULL) AS income_count,
+		(SELECT MAX(last_active) FROM sessions WHERE user_id = ?) AS last_login_at`,
+		userID, userID, userID).Scan(&row).Error
+	if err != nil {
+		return nil, err
+	}
+	profile.Summary = UserProfileSummary{
+		BaseCurrency:          baseCurrency(),
+		ExpenseCount:          row.ExpenseCount,
+		IncomeCount:           row.IncomeCount,
+		UnconvertedCurrencies: []string{},
+	}
+	if row.ExpenseCount > 0 || row.IncomeCount > 0 {
+		rates, err := loadExchangeRates()
+		if err != nil {
+			return nil, err
+		}
+		var expenseUnconverted, incomeUnconverted []string
+		if row.ExpenseCount > 0 {
+			profile.Summary.ExpenseTotal, expenseUnconverted, err = sumInBaseCurrency(
+				database.DB.Model(&models.Expense{}).Where("user_id = ?", userID), rates)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if row.IncomeCount > 0 {
+			profile.Summary.IncomeTotal, incomeUnconverted, err = sumInBaseCurrency(
+				database.DB.Model(&models.Income{}).Where("user_id = ?", userID), rates)
+			if err != nil {
+				return nil, err
+			}
+		}
+		profile.Summary.UnconvertedCurrencies = mergeCurrencyCodes(expenseUnconverted, incomeUnconverted)
+	}
+	if row.LastLoginAt != nil {
+		s := models.FormatTime(*row.LastLoginAt)
+		profile.Summary.LastLoginAt = &s
+	}
+
+	if profile.Summary.ExpenseCount > 0 {
+		if err := database.DB.Where("user_id = ?", userID).
+			Order("expense_time DESC, id DESC").Limit(userProfileRecentLimit).
+			Find(&profile.RecentExpenses).Error; err != nil {
+			return nil, err
+		}
+	}
+	if profile.Summary.IncomeCount > 0 {
+		if err := database.DB.Where("user_id = ?", userID).
+			Order("income_time DESC, id DESC").Limit(userProfileRecentLimit).
+			Find(&profile.RecentIncomes).Error; err != nil {
+			return nil, err
+		}
+	}
+	return profile, nil
+}
+
+// GetUserProfile 用户画像（仅管理员）
+// @Summary 查看用户画像
+// @Description 聚合返回用户基础信息（不含密码）、角色、收支总额与笔数、App 端最近登录时间及最近 5 条消费/收入记录。
+// @Description 已删除（软删除）的用户同样可查看，deleted_at 为删除时间
+// @Tags 后台管理-用户管理
+// @Produce json
+// @Param id path int true "用户ID"
+// @Success 200 {object} map[string]interface{} "获取成功，data 为 UserProfile"
+// @Failure 400 {object} map[string]interface{} "无效的用户ID"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "用户不存在"
+// @Router /admin/users/{id}/profile [get]
+func (h *AdminHandler) GetUserProfile(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+	if !currentUser.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return
+	}
+
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的用户ID"})
+		return
+	}
+
+	profile, err := loadUserProfile(uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "用户不存在"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": profile})
+}
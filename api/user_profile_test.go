@@ -0,0 +1,70 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestLoadUserProfile_DeletedUser(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Now()
+	mock.ExpectQuery("SELECT \\* FROM `users` WHERE `users`.`id` = \\?").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "password", "email", "role_id", "status", "created_at", "updated_at", "deleted_at"}).
+			AddRow(7, "alice", "hashed", "a@x.com", 2, "active", now, now, now))
+	mock.ExpectQuery("SELECT \\* FROM `roles` WHERE `roles`.`id` = \\? AND `roles`.`deleted_at` IS NULL").
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "code"}).AddRow(2, "只读", "viewer"))
+	mock.ExpectQuery("SELECT\\s+\\(SELECT COUNT\\(\\*\\) FROM expenses").
+		WithArgs(7, 7, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"expense_count", "income_count", "last_login_at"}).
+			AddRow(2, 0, now))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT currency, SUM\\(amount\\) AS total FROM `expenses` WHERE user_id = \\? AND `expenses`.`deleted_at` IS NULL GROUP BY `currency`").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "total"}).
+			AddRow("CNY", 50.5).
+			AddRow("USD", 5).
+			AddRow("JPY", 1000))
+	mock.ExpectQuery("SELECT \\* FROM `expenses` WHERE user_id = \\? AND `expenses`.`deleted_at` IS NULL ORDER BY expense_time DESC, id DESC LIMIT 5").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "category", "expense_time"}).
+			AddRow(11, 7, 50.5, "餐饮", now).
+			AddRow(10, 7, 38, "交通", now.Add(-time.Hour)))
+
+	profile, err := loadUserProfile(7)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", profile.User.Username)
+	assert.NotNil(t, profile.DeletedAt)
+	require.NotNil(t, profile.Role)
+	assert.Equal(t, "viewer", profile.Role.Code)
+	assert.Equal(t, 85.5, profile.Summary.ExpenseTotal)
+	assert.Equal(t, []string{"JPY"}, profile.Summary.UnconvertedCurrencies)
+	assert.EqualValues(t, 2, profile.Summary.ExpenseCount)
+	assert.NotNil(t, profile.Summary.LastLoginAt)
+	assert.Len(t, profile.RecentExpenses, 2)
+	assert.NotNil(t, profile.RecentIncomes)
+	assert.Empty(t, profile.RecentIncomes)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoadUserProfile_NotFound(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WithArgs(99).
+		WillReturnError(gorm.ErrRecordNotFound)
+
+	_, err := loadUserProfile(99)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
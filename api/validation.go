@@ -0,0 +1,89 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"finance/config"
+)
+
+// categoryColorPattern 类别颜色：#RRGGBB 或带透明度的 #RRGGBBAA
+var categoryColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{6}|[0-9a-fA-F]{8})$`)
+
+// recordTimeFutureTolerance 记账时间可超出当前时间的余量，容忍客户端与服务端的时区误差
+const recordTimeFutureTolerance = 24 * time.Hour
+
+// maxAmount 单笔金额上限；未加载配置（如单元测试）时使用默认值
+func maxAmount() float64 {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Limits.MaxAmount > 0 {
+		return cfg.Limits.MaxAmount
+	}
+	return config.DefaultMaxAmount
+}
+
+// maxDescriptionLength 描述/备注最大字符数；未加载配置时使用默认值
+func maxDescriptionLength() int {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Limits.MaxDescriptionLength > 0 {
+		return cfg.Limits.MaxDescriptionLength
+	}
+	return config.DefaultMaxDescriptionLength
+}
+
+// validateAmount 校验金额大于 0 且不超过上限（消费、收入创建/更新共用）
+func validateAmount(amount float64) error {
+	if amount <= 0 {
+		return fmt.Errorf("金额必须大于 0")
+	}
+	if limit := maxAmount(); amount > limit {
+		return fmt.Errorf("金额不能超过 %.2f", limit)
+	}
+	return nil
+}
+
+// validateDescription 校验描述/备注长度（按字符计）
+func validateDescription(desc string) error {
+	if limit := maxDescriptionLength(); utf8.RuneCountInString(desc) > limit {
+		return fmt.Errorf("描述不能超过 %d 个字符", limit)
+	}
+	return nil
+}
+
+// minRecordTime 消费/收入时间下限（当天 0 点）；未加载配置或格式错误时使用默认值
+func minRecordTime() time.Time {
+	date := config.DefaultMinRecordDate
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Limits.MinRecordDate != "" {
+		date = cfg.Limits.MinRecordDate
+	}
+	t, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		t, _ = time.ParseInLocation("2006-01-02", config.DefaultMinRecordDate, time.Local)
+	}
+	return t
+}
+
+// validateRecordTime 校验消费/收入时间不早于下限、不晚于当前时间 + 1 天，label 用于错误提示
+func validateRecordTime(label string, t time.Time) error {
+	return validateRecordTimeAt(label, t, time.Now())
+}
+
+func validateRecordTimeAt(label string, t, now time.Time) error {
+	if lower := minRecordTime(); t.Before(lower) {
+		return fmt.Errorf("%s不能早于 %s", label, lower.Format("2006-01-02"))
+	}
+	if upper := now.Add(recordTimeFutureTolerance); t.After(upper) {
+		return fmt.Errorf("%s不能晚于当前时间 1 天以上", label)
+	}
+	return nil
+}
+
+// validateCategoryColor 校验类别颜色为十六进制色值，空值表示使用默认颜色（消费、收入类别共用）。
+// 颜色会直接用于前端渲染，"red"、"javascript:..." 等一律拒绝
+func validateCategoryColor(color string) error {
+	if color == "" || categoryColorPattern.MatchString(color) {
+		return nil
+	}
+	return errors.New("颜色格式错误，应为 #RRGGBB 或 #RRGGBBAA，如 #ef4444")
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"finance/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError 字段级校验错误，field 与请求中的字段名（json/form 标签）一致
+type FieldError struct {
+	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Message string `json:"message"`
+}
+
+func init() {
+	// 校验错误中的字段名使用 json/form 标签，而不是 Go 结构体字段名
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			for _, key := range []string{"json", "form"} {
+				name := strings.SplitN(f.Tag.Get(key), ",", 2)[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return f.Name
+		})
+	}
+}
+
+// fieldErrorMessage 常见校验 tag 的中文文案
+func fieldErrorMessage(fe validator.FieldError) string {
+	param := fe.Param()
+	isLength := false
+	switch fe.Kind() {
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		isLength = true
+	}
+	unit := "个字符"
+	if fe.Kind() != reflect.String {
+		unit = "项"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "不能为空"
+	case "min":
+		if isLength {
+			return fmt.Sprintf("长度不能少于 %s %s", param, unit)
+		}
+		return "不能小于 " + param
+	case "max":
+		if isLength {
+			return fmt.Sprintf("长度不能超过 %s %s", param, unit)
+		}
+		return "不能大于 " + param
+	case "len":
+		if isLength {
+			return fmt.Sprintf("长度必须为 %s %s", param, unit)
+		}
+		return "必须等于 " + param
+	case "gt":
+		return "必须大于 " + param
+	case "gte":
+		return "不能小于 " + param
+	case "lt":
+		return "必须小于 " + param
+	case "lte":
+		return "不能大于 " + param
+	case "email":
+		return "邮箱格式不正确"
+	case "url", "http_url":
+		return "URL 格式不正确"
+	case "oneof":
+		return "只能是以下值之一: " + strings.Join(strings.Fields(param), "/")
+	case "numeric", "number":
+		return "必须是数字"
+	case "dive":
+		return "列表项不合法"
+	}
+	return "格式不正确"
+}
+
+// fieldPath 去掉命名空间开头的结构体名，保留嵌套与下标（如 items[0].amount）
+func fieldPath(fe validator.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.Index(ns, "."); i >= 0 {
+		return ns[i+1:]
+	}
+	return fe.Field()
+}
+
+// bindFieldErrors 将绑定/校验错误转换为字段级错误；不是字段错误（如 JSON 语法错误）时返回 nil
+func bindFieldErrors(err error) []FieldError {
+	var ves validator.ValidationErrors
+	if errors.As(err, &ves) {
+		out := make([]FieldError, 0, len(ves))
+		for _, fe := range ves {
+			out = append(out, FieldError{Field: fieldPath(fe), Tag: fe.Tag(), Message: fieldErrorMessage(fe)})
+		}
+		return out
+	}
+	var te *json.UnmarshalTypeError
+	if errors.As(err, &te) && te.Field != "" {
+		return []FieldError{{Field: te.Field, Tag: "type", Message: "类型不正确，应为 " + te.Type.String()}}
+	}
+	return nil
+}
+
+// bindErrorSummary 汇总字段错误为一句话，兼容只读取 message 的旧客户端
+func bindErrorSummary(fields []FieldError) string {
+	parts := make([]string, len(fields))
+	for i, f := range fields {
+		parts[i] = f.Field + " " + f.Message
+	}
+	return strings.Join(parts, "；")
+}
+
+// BindError 参数绑定失败的 400 响应：字段校验错误时 message 为汇总文案并附带 errors 明细，
+// 其他错误沿用 SafeErrorMessage 的处理
+func BindError(c *gin.Context, err error) {
+	fields := bindFieldErrors(err)
+	if len(fields) == 0 {
+		BadRequest(c, SafeErrorMessage(err, "参数错误"))
+		return
+	}
+	c.JSON(http.StatusBadRequest, Response{
+		Code:      http.StatusBadRequest,
+		Message:   bindErrorSummary(fields),
+		Errors:    fields,
+		RequestID: middleware.GetRequestID(c),
+	})
+}
+
+// adminBindError 后台接口（success/message 结构）的参数绑定失败响应，errors 含义同 BindError
+func adminBindError(c *gin.Context, err error) {
+	fields := bindFieldErrors(err)
+	if len(fields) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": SafeErrorMessage(err, "参数错误"), "request_id": middleware.GetRequestID(c)})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": bindErrorSummary(fields), "errors": fields, "request_id": middleware.GetRequestID(c)})
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type validationTestItem struct {
+	Amount float64 `json:"amount" binding:"gt=0"`
+}
+
+type validationTestRequest struct {
+	Username string               `json:"username" binding:"required"`
+	Password string               `json:"password" binding:"min=6,max=10"`
+	Email    string               `json:"email" binding:"omitempty,email"`
+	Age      int                  `json:"age" binding:"max=150"`
+	Kind     string               `json:"kind" binding:"omitempty,oneof=a b"`
+	Items    []validationTestItem `json:"items" binding:"dive"`
+}
+
+func postValidation(t *testing.T, handler gin.HandlerFunc, body string) map[string]interface{} {
+	router := gin.New()
+	router.POST("/v", handler)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v", bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, 400, w.Code)
+
+	var resp map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	return resp
+}
+
+func bindValidationHandler(c *gin.Context) {
+	var req validationTestRequest
+	if err := bindJSON(c, &req); err != nil {
+		BindError(c, err)
+		return
+	}
+	Success(c, nil)
+}
+
+func TestBindError_FieldErrors(t *testing.T) {
+	resp := postValidation(t, bindValidationHandler,
+		`{"password":"123","email":"not-an-email","age":200,"kind":"c","items":[{"amount":1},{"amount":0}]}`)
+
+	errs, ok := resp["errors"].([]interface{})
+	require.True(t, ok, "缺少 errors 明细")
+
+	got := map[string]map[string]interface{}{}
+	for _, e := range errs {
+		m := e.(map[string]interface{})
+		got[m["field"].(string)] = m
+	}
+
+	cases := []struct {
+		field, tag, message string
+	}{
+		{"username", "required", "不能为空"},
+		{"password", "min", "长度不能少于 6 个字符"},
+		{"email", "email", "邮箱格式不正确"},
+		{"age", "max", "不能大于 150"},
+		{"kind", "oneof", "只能是以下值之一: a/b"},
+		{"items[1].amount", "gt", "必须大于 0"},
+	}
+	require.Len(t, got, len(cases))
+	for _, tc := range cases {
+		fe, ok := got[tc.field]
+		require.True(t, ok, tc.field)
+		assert.Equal(t, tc.tag, fe["tag"], tc.field)
+		assert.Equal(t, tc.message, fe["message"], tc.field)
+	}
+
+	// 汇总 message 兼容旧客户端
+	assert.Contains(t, resp["message"], "username 不能为空")
+	assert.Contains(t, resp["message"], "password 长度不能少于 6 个字符")
+}
+
+func TestBindError_MaxLength(t *testing.T) {
+	resp := postValidation(t, bindValidationHandler, `{"username":"u","password":"12345678901"}`)
+	errs := resp["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "长度不能超过 10 个字符", errs[0].(map[string]interface{})["message"])
+}
+
+func TestBindError_TypeError(t *testing.T) {
+	resp := postValidation(t, bindValidationHandler, `{"username":"u","password":"123456","age":"old"}`)
+	errs := resp["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	fe := errs[0].(map[string]interface{})
+	assert.Equal(t, "age", fe["field"])
+	assert.Equal(t, "type", fe["tag"])
+}
+
+func TestBindError_SyntaxError(t *testing.T) {
+	// 非字段错误时不返回 errors，沿用原有 message
+	resp := postValidation(t, bindValidationHandler, `{"username":`)
+	assert.NotContains(t, resp, "errors")
+	assert.NotEmpty(t, resp["message"])
+}
+
+func TestAdminBindError(t *testing.T) {
+	resp := postValidation(t, func(c *gin.Context) {
+		var req validationTestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			adminBindError(c, err)
+			return
+		}
+	}, `{"password":"123456"}`)
+
+	assert.Equal(t, false, resp["success"])
+	assert.Equal(t, "username 不能为空", resp["message"])
+	errs := resp["errors"].([]interface{})
+	require.Len(t, errs, 1)
+	assert.Equal(t, "username", errs[0].(map[string]interface{})["field"])
+}
+
+func TestAdminBindError_SyntaxError(t *testing.T) {
+	resp := postValidation(t, func(c *gin.Context) {
+		var req validationTestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			adminBindError(c, err)
+			return
+		}
+	}, `{"username":`)
+
+	assert.Equal(t, false, resp["success"])
+	assert.NotContains(t, resp, "errors")
+	assert.NotEmpty(t, resp["message"])
+}
@@ -0,0 +1,63 @@
+package api
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"finance/config"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateAmount(t *testing.T) {
+	assert.NoError(t, validateAmount(0.01))
+	assert.NoError(t, validateAmount(config.DefaultMaxAmount))
+	assert.Error(t, validateAmount(0))
+	assert.Error(t, validateAmount(-1))
+	assert.Error(t, validateAmount(1e12))
+
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{Limits: config.LimitsConfig{MaxAmount: 1000}}
+	defer func() { config.GlobalConfig = old }()
+	assert.NoError(t, validateAmount(1000))
+	assert.EqualError(t, validateAmount(1000.01), "金额不能超过 1000.00")
+}
+
+func TestValidateDescription(t *testing.T) {
+	assert.NoError(t, validateDescription(""))
+	assert.NoError(t, validateDescription(strings.Repeat("午", config.DefaultMaxDescriptionLength)))
+	assert.Error(t, validateDescription(strings.Repeat("午", config.DefaultMaxDescriptionLength+1)))
+
+	old := config.GlobalConfig
+	config.GlobalConfig = &config.Config{Limits: config.LimitsConfig{MaxDescriptionLength: 5}}
+	defer func() { config.GlobalConfig = old }()
+	assert.EqualError(t, validateDescription("一二三四五六"), "描述不能超过 5 个字符")
+}
+
+func TestValidateRecordTime(t *testing.T) {
+	old := config.GlobalConfig
+	config.GlobalConfig = nil
+	defer func() { config.GlobalConfig = old }()
+
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.Local)
+	lower := time.Date(2000, 1, 1, 0, 0, 0, 0, time.Local)
+	assert.NoError(t, validateRecordTimeAt("消费时间", lower, now))
+	assert.EqualError(t, validateRecordTimeAt("消费时间", lower.Add(-time.Second), now), "消费时间不能早于 2000-01-01")
+	assert.NoError(t, validateRecordTimeAt("消费时间", now, now))
+	assert.NoError(t, validateRecordTimeAt("消费时间", now.Add(24*time.Hour), now))
+	assert.EqualError(t, validateRecordTimeAt("收入时间", now.Add(24*time.Hour+time.Second), now), "收入时间不能晚于当前时间 1 天以上")
+
+	config.GlobalConfig = &config.Config{Limits: config.LimitsConfig{MinRecordDate: "2020-01-01"}}
+	assert.EqualError(t, validateRecordTimeAt("消费时间", time.Date(2019, 12, 31, 23, 59, 59, 0, time.Local), now), "消费时间不能早于 2020-01-01")
+	assert.NoError(t, validateRecordTimeAt("消费时间", time.Date(2020, 1, 1, 0, 0, 0, 0, time.Local), now))
+}
+
+func TestValidateCategoryColor(t *testing.T) {
+	for _, color := range []string{"", "#ef4444", "#EF4444", "#10b981cc", "#64748B80"} {
+		assert.NoError(t, validateCategoryColor(color), color)
+	}
+	for _, color := range []string{"red", "ef4444", "#fff", "#ef44", "#ef44444", "#gggggg", "#ef4444 ", "javascript:alert(1)", "#ef4444;x"} {
+		assert.Error(t, validateCategoryColor(color), color)
+	}
+}
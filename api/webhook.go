@@ -0,0 +1,395 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CreateWebhookRequest 创建 Webhook 请求
+type CreateWebhookRequest struct {
+	Name   string   `json:"name" binding:"required,min=1,max=100" example:"飞书大额提醒"`
+	URL    string   `json:"url" binding:"required,url,max=500" example:"https://open.feishu.cn/open-apis/bot/v2/hook/xxx"`
+	Events []string `json:"events" binding:"required,min=1" example:"expense.large"` // 订阅的事件类型
+	// Secret 签名密钥，配置后每次推送带 X-Finance-Signature: sha256=<HMAC-SHA256(请求体)>
+	Secret string `json:"secret" binding:"omitempty,max=200"`
+	// Active 是否启用，不传时默认启用
+	Active *bool `json:"active"`
+}
+
+// UpdateWebhookRequest 更新 Webhook 请求，未传的字段不修改
+type UpdateWebhookRequest struct {
+	Name   string   `json:"name" binding:"omitempty,min=1,max=100"`
+	URL    string   `json:"url" binding:"omitempty,url,max=500"`
+	Events []string `json:"events"`
+	// Secret 签名密钥，传空字符串表示清除；不传则不修改
+	Secret *string `json:"secret" binding:"omitempty,max=200"`
+	Active *bool   `json:"active"`
+}
+
+// LargeExpenseEventData expense.large 事件的 data
+type LargeExpenseEventData struct {
+	UserID       uint    `json:"user_id"`
+	Username     string  `json:"username"`
+	ExpenseID    uint    `json:"expense_id"` // 分期时为首期记录ID
+	Amount       float64 `json:"amount"`     // 原币金额，分期时为总额
+	Currency     string  `json:"currency"`
+	BaseAmount   float64 `json:"base_amount"` // 折合本位币
+	Threshold    float64 `json:"threshold"`   // 用户设置的大额阈值（本位币）
+	Category     string  `json:"category"`
+	Description  string  `json:"description"`
+	ExpenseTime  string  `json:"expense_time"`           // RFC3339，与接口返回的时间格式一致
+	Installments int     `json:"installments,omitempty"` // 分期期数
+}
+
+// largeExpenseText 事件的可读摘要
+func largeExpenseText(d LargeExpenseEventData) string {
+	return fmt.Sprintf("大额消费提醒：%s 在「%s」消费 %.2f %s（阈值 %.2f %s）%s",
+		d.Username, d.Category, d.Amount, d.Currency, d.Threshold, baseCurrency(), d.Description)
+}
+
+// notifyLargeExpense 消费记录提交后调用：金额（分期时为总额）折合本位币超过用户设置的大额阈值时，
+// 异步推送 expense.large 事件给订阅的 Webhook；查询失败只打印日志，不影响创建结果
+func notifyLargeExpense(userID uint, expense models.Expense, amount float64, installments int) {
+	var user models.User
+	if err := database.DB.Select("id", "username", "large_expense_threshold").First(&user, userID).Error; err != nil {
+		log.Printf("查询大额消费阈值失败（用户 %d）: %v", userID, err)
+		return
+	}
+	if user.LargeExpenseThreshold <= 0 {
+		return
+	}
+
+	baseAmount := amount
+	if expense.Currency != "" && expense.Currency != baseCurrency() {
+		rates, err := loadExchangeRates()
+		if err != nil {
+			log.Printf("大额消费判断读取汇率失败: %v", err)
+			return
+		}
+		v, ok := rates.ToBase(amount, expense.Currency)
+		if !ok {
+			return
+		}
+		baseAmount = v
+	}
+	if baseAmount <= user.LargeExpenseThreshold {
+		return
+	}
+
+	hooks, err := service.ActiveWebhooks(models.WebhookEventLargeExpense)
+	if err != nil {
+		log.Printf("查询 Webhook 失败: %v", err)
+		return
+	}
+	if len(hooks) == 0 {
+		return
+	}
+	data := LargeExpenseEventData{
+		UserID:       user.ID,
+		Username:     user.Username,
+		ExpenseID:    expense.ID,
+		Amount:       amount,
+		Currency:     expense.Currency,
+		BaseAmount:   baseAmount,
+		Threshold:    user.LargeExpenseThreshold,
+		Category:     expense.Category,
+		Description:  expense.Description,
+		ExpenseTime:  models.FormatTime(expense.ExpenseTime),
+		Installments: installments,
+	}
+	service.DispatchWebhooks(hooks, service.NewWebhookPayload(models.WebhookEventLargeExpense, largeExpenseText(data), data))
+}
+
+// validateWebhookURL 只允许 http/https 地址
+func validateWebhookURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("Webhook 地址必须是 http 或 https URL")
+	}
+	return nil
+}
+
+// normalizeWebhookEvents 校验并去重事件类型，返回逗号分隔的存储值
+func normalizeWebhookEvents(events []string) (string, error) {
+	var list []string
+	seen := map[string]bool{}
+	for _, e := range events {
+		e = strings.TrimSpace(e)
+		if !models.ValidWebhookEvent(e) {
+			return "", errors.New("不支持的事件类型: " + e + "，可选值: " + models.WebhookEventLargeExpense)
+		}
+		if !seen[e] {
+			seen[e] = true
+			list = append(list, e)
+		}
+	}
+	if len(list) == 0 {
+		return "", errors.New("至少订阅一个事件")
+	}
+	return strings.Join(list, ","), nil
+}
+
+// webhookAdmin 校验当前用户为超级管理员，失败时已写入响应
+func webhookAdmin(c *gin.Context) bool {
+	user, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return false
+	}
+	if !user.IsAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"success": false, "message": "权限不足"})
+		return false
+	}
+	return true
+}
+
+// findWebhook 按路径参数 id 查询 Webhook，失败时已写入响应
+func findWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": "无效的ID"})
+		return nil, false
+	}
+	var hook models.Webhook
+	if err := database.DB.First(&hook, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"success": false, "message": "Webhook 不存在"})
+		return nil, false
+	}
+	hook.HasSecret = hook.Secret != ""
+	return &hook, true
+}
+
+// GetWebhooks Webhook 列表
+// @Summary Webhook 列表
+// @Description 返回全部 Webhook 配置，签名密钥不返回，只返回 has_secret（仅超级管理员）
+// @Tags 后台管理-Webhook
+// @Produce json
+// @Success 200 {object} map[string]interface{} "获取成功"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/webhooks [get]
+func (h *AdminHandler) GetWebhooks(c *gin.Context) {
+	if !webhookAdmin(c) {
+		return
+	}
+	var list []models.Webhook
+	if err := database.DB.Order("id ASC").Find(&list).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询失败")})
+		return
+	}
+	for i := range list {
+		list[i].HasSecret = list[i].Secret != ""
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": list})
+}
+
+// CreateWebhook 创建 Webhook
+// @Summary 创建 Webhook
+// @Description 配置事件推送地址。目前支持 expense.large：用户单笔消费（分期按总额）折合本位币超过其设置的大额阈值时，
+// @Description 在记录提交后异步 POST JSON，失败（网络错误、429、5xx）按 2s/8s/32s 退避重试（仅超级管理员）
+// @Tags 后台管理-Webhook
+// @Accept json
+// @Produce json
+// @Param request body CreateWebhookRequest true "Webhook 配置"
+// @Success 200 {object} map[string]interface{} "创建成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Router /admin/webhooks [post]
+func (h *AdminHandler) CreateWebhook(c *gin.Context) {
+	if !webhookAdmin(c) {
+		return
+	}
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	events, err := normalizeWebhookEvents(req.Events)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	secret, err := service.EncryptSecret(req.Secret)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "加密签名密钥失败"})
+		return
+	}
+
+	hook := models.Webhook{
+		Name:   strings.TrimSpace(req.Name),
+		URL:    req.URL,
+		Events: events,
+		Secret: secret,
+		Active: req.Active == nil || *req.Active,
+	}
+	if err := database.DB.Create(&hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "创建失败")})
+		return
+	}
+	hook.HasSecret = hook.Secret != ""
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "创建成功", "data": hook})
+}
+
+// UpdateWebhook 更新 Webhook
+// @Summary 更新 Webhook
+// @Description 修改名称、地址、订阅事件、签名密钥或启用状态，未传的字段不修改（仅超级管理员）
+// @Tags 后台管理-Webhook
+// @Accept json
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Param request body UpdateWebhookRequest true "要修改的字段"
+// @Success 200 {object} map[string]interface{} "更新成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "Webhook 不存在"
+// @Router /admin/webhooks/{id} [put]
+func (h *AdminHandler) UpdateWebhook(c *gin.Context) {
+	if !webhookAdmin(c) {
+		return
+	}
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		adminBindError(c, err)
+		return
+	}
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = strings.TrimSpace(req.Name)
+	}
+	if req.URL != "" {
+		if err := validateWebhookURL(req.URL); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["url"] = req.URL
+	}
+	if req.Events != nil {
+		events, err := normalizeWebhookEvents(req.Events)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+			return
+		}
+		updates["events"] = events
+	}
+	if req.Secret != nil {
+		secret, err := service.EncryptSecret(*req.Secret)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "加密签名密钥失败"})
+			return
+		}
+		updates["secret"] = secret
+	}
+	if req.Active != nil {
+		updates["active"] = *req.Active
+	}
+	if len(updates) > 0 {
+		if err := database.DB.Model(hook).Updates(updates).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "更新失败")})
+			return
+		}
+	}
+	hook.HasSecret = hook.Secret != ""
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "更新成功", "data": hook})
+}
+
+// DeleteWebhook 删除 Webhook
+// @Summary 删除 Webhook
+// @Description 删除后不再推送；已在重试中的推送不受影响（仅超级管理员）
+// @Tags 后台管理-Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]interface{} "删除成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "Webhook 不存在"
+// @Router /admin/webhooks/{id} [delete]
+func (h *AdminHandler) DeleteWebhook(c *gin.Context) {
+	if !webhookAdmin(c) {
+		return
+	}
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+	if err := database.DB.Delete(hook).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "删除失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "删除成功"})
+}
+
+// TestWebhook 发送测试事件
+// @Summary 测试 Webhook
+// @Description 立即向该地址发送一条 test 为 true 的 expense.large 样例事件（与正式推送相同的请求头与签名，不重试），
+// @Description 停用状态也可测试，返回接收方的响应状态（仅超级管理员）
+// @Tags 后台管理-Webhook
+// @Produce json
+// @Param id path int true "Webhook ID"
+// @Success 200 {object} map[string]interface{} "发送成功"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Failure 403 {object} map[string]interface{} "权限不足"
+// @Failure 404 {object} map[string]interface{} "Webhook 不存在"
+// @Failure 502 {object} map[string]interface{} "接收方不可用或返回错误"
+// @Router /admin/webhooks/{id}/test [post]
+func (h *AdminHandler) TestWebhook(c *gin.Context) {
+	if !webhookAdmin(c) {
+		return
+	}
+	hook, ok := findWebhook(c)
+	if !ok {
+		return
+	}
+
+	data := LargeExpenseEventData{
+		Username:    "sample",
+		Amount:      1288,
+		Currency:    baseCurrency(),
+		BaseAmount:  1288,
+		Threshold:   1000,
+		Category:    "购物",
+		Description: "Webhook 测试事件",
+		ExpenseTime: models.FormatTime(time.Now()),
+	}
+	payload := service.NewWebhookPayload(models.WebhookEventLargeExpense, largeExpenseText(data), data)
+	payload.Test = true
+
+	if err := service.SendWebhook(hook, payload); err != nil {
+		var se *service.WebhookStatusError
+		switch {
+		case errors.Is(err, service.ErrSecretDecrypt):
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": err.Error()})
+			return
+		case errors.As(err, &se):
+			c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": "推送失败，" + se.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"success": false, "message": SafeErrorMessage(err, "推送失败")})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "发送成功", "data": gin.H{"id": payload.ID}})
+}
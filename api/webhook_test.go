@@ -0,0 +1,184 @@
+package api
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+	"finance/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminHandler_TestWebhook_SendsSignedSample(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	var gotBody []byte
+	var gotSignature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotSignature = r.Header.Get(service.WebhookSignatureHeader)
+	}))
+	defer srv.Close()
+
+	secret, err := service.EncryptSecret("hook-secret")
+	require.NoError(t, err)
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `webhooks` WHERE `webhooks`.`id` = \\?").
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "secret", "active"}).
+			AddRow(3, "飞书", srv.URL, models.WebhookEventLargeExpense, secret, false))
+
+	w := serveAsAdmin("POST", "/admin/webhooks/:id/test", "/admin/webhooks/3/test", NewAdminHandler().TestWebhook, "")
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, service.SignWebhookPayload("hook-secret", gotBody), gotSignature)
+	var payload struct {
+		Event string                `json:"event"`
+		Test  bool                  `json:"test"`
+		Data  LargeExpenseEventData `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(gotBody, &payload))
+	assert.Equal(t, models.WebhookEventLargeExpense, payload.Event)
+	assert.True(t, payload.Test)
+	assert.Greater(t, payload.Data.Amount, payload.Data.Threshold)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_TestWebhook_ReceiverError(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	expectAdminUser(mock)
+	mock.ExpectQuery("SELECT \\* FROM `webhooks`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events", "active"}).
+			AddRow(3, srv.URL, models.WebhookEventLargeExpense, true))
+
+	w := serveAsAdmin("POST", "/admin/webhooks/:id/test", "/admin/webhooks/3/test", NewAdminHandler().TestWebhook, "")
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "403")
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_CreateWebhook_EncryptsSecret(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	var stored string
+	expectAdminUser(mock)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `webhooks`").
+		WithArgs("大额提醒", "https://hooks.example.com/finance", models.WebhookEventLargeExpense, captureString(&stored), true, sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(4, 1))
+	mock.ExpectCommit()
+
+	body := `{"name":"大额提醒","url":"https://hooks.example.com/finance","events":["expense.large","expense.large"],"secret":"hook-secret"}`
+	w := serveAsAdmin("POST", "/admin/webhooks", "/admin/webhooks", NewAdminHandler().CreateWebhook, body)
+
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, service.IsEncryptedSecret(stored))
+	plain, err := service.DecryptSecret(stored)
+	require.NoError(t, err)
+	assert.Equal(t, "hook-secret", plain)
+	assert.NotContains(t, w.Body.String(), "hook-secret")
+	assert.Contains(t, w.Body.String(), `"has_secret":true`)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminHandler_CreateWebhook_InvalidInput(t *testing.T) {
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	cases := map[string]string{
+		"unknown event": `{"name":"a","url":"https://hooks.example.com","events":["expense.small"]}`,
+		"bad scheme":    `{"name":"a","url":"ftp://hooks.example.com","events":["expense.large"]}`,
+	}
+	for name, body := range cases {
+		t.Run(name, func(t *testing.T) {
+			mock, cleanup := setupMockDB(t)
+			defer cleanup()
+			expectAdminUser(mock)
+
+			w := serveAsAdmin("POST", "/admin/webhooks", "/admin/webhooks", NewAdminHandler().CreateWebhook, body)
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			require.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestNotifyLargeExpense_OverThreshold(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	received := make(chan []byte, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer srv.Close()
+
+	mock.ExpectQuery("SELECT `id`,`username`,`large_expense_threshold` FROM `users`").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "large_expense_threshold"}).AddRow(1, "alice", 1000))
+	mock.ExpectQuery("SELECT \\* FROM `webhooks` WHERE active = \\?").
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "url", "events", "active"}).
+			AddRow(1, srv.URL, models.WebhookEventLargeExpense, true))
+
+	expense := models.Expense{ID: 9, Amount: 400, Currency: baseCurrency(), Category: "数码", ExpenseTime: time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)}
+	notifyLargeExpense(1, expense, 1200, 3)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	select {
+	case body := <-received:
+		var payload struct {
+			Event string                `json:"event"`
+			Data  LargeExpenseEventData `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, models.WebhookEventLargeExpense, payload.Event)
+		assert.Equal(t, "alice", payload.Data.Username)
+		assert.Equal(t, uint(9), payload.Data.ExpenseID)
+		assert.Equal(t, 1200.0, payload.Data.Amount)
+		assert.Equal(t, 3, payload.Data.Installments)
+		assert.Equal(t, models.FormatTime(expense.ExpenseTime), payload.Data.ExpenseTime)
+		_, err := time.Parse(time.RFC3339, payload.Data.ExpenseTime)
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("webhook not delivered")
+	}
+}
+
+func TestNotifyLargeExpense_UnderThreshold(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id`,`username`,`large_expense_threshold` FROM `users`").
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "large_expense_threshold"}).AddRow(1, "alice", 1000))
+
+	// 等于阈值不推送，也不会查询 Webhook
+	notifyLargeExpense(1, models.Expense{ID: 9, Currency: baseCurrency()}, 1000, 0)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,309 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xuri/excelize/v2"
+)
+
+// 导出工作簿中的工作表名
+const (
+	expenseSheetName = "消费记录"
+	incomeSheetName  = "收入记录"
+	summarySheetName = "收支汇总"
+)
+
+// writeExpenseSheet 写入消费记录工作表：表头、明细与合计行；includeDeleted 时行尾追加删除时间、删除者两列。
+// money 非 nil 时金额列按每条记录自身的币种使用货币数字格式；合计按 rates 折算为本位币
+func writeExpenseSheet(f *excelize.File, sheet string, expenses []ExpenseWithUser, money *moneyFormat, rates exchangeRates, includeDeleted bool, deleterNames map[uint]string) {
+	dataStyle, _ := excelDataStyle(f, "")
+	amountStyles := newExcelAmountStyles(f, money)
+
+	columns := []excelColumn{
+		{"ID", 10}, {"用户名", 15}, {"金额", 12}, {"类别", 12}, {"描述", 30}, {"消费时间", 20}, {"创建时间", 20},
+	}
+	if includeDeleted {
+		columns = append(columns, excelColumn{"删除时间", 20}, excelColumn{"删除者", 20})
+	}
+	excelWriteHeader(f, sheet, columns)
+
+	var total sheetTotal
+	deleted := 0
+	for i, expense := range expenses {
+		row := i + 2
+		values := []interface{}{
+			expense.ID,
+			expense.Username,
+			expense.Amount,
+			expense.Category,
+			expense.Description,
+			expense.ExpenseTime.Format("2006-01-02 15:04:05"),
+			expense.CreatedAt.Format("2006-01-02 15:04:05"),
+		}
+		if includeDeleted {
+			deletedAt, deletedBy := deletedExpenseColumns(expense.Expense, deleterNames)
+			values = append(values, deletedAt, deletedBy)
+			if expense.DeletedAt.Valid {
+				deleted++
+			}
+		}
+		excelWriteRow(f, sheet, row, values, dataStyle)
+		amountCell, _ := excelize.CoordinatesToCellName(3, row)
+		f.SetCellStyle(sheet, amountCell, amountCell, amountStyles.get(expense.Currency))
+		total.add(rates, expense.Amount, expense.Currency)
+	}
+
+	summaryText := fmt.Sprintf("共 %d 条记录", len(expenses))
+	if includeDeleted {
+		summaryText = fmt.Sprintf("共 %d 条记录（含已删除 %d 条）", len(expenses), deleted)
+	}
+	excelWriteSummary(f, sheet, len(expenses)+2, 3, len(columns), roundMoney(total.amount), summaryText+total.note(), summaryNumFmt(money))
+}
+
+// sheetTotal 明细表合计：逐条折算为本位币累加，缺少汇率的币种不计入
+type sheetTotal struct {
+	amount      float64
+	unconverted []string
+}
+
+func (t *sheetTotal) add(rates exchangeRates, amount float64, currency string) {
+	converted, ok := rates.ToBase(amount, currency)
+	if !ok {
+		t.unconverted = append(t.unconverted, currency)
+		return
+	}
+	t.amount += converted
+}
+
+// note 合计说明后缀，有未计入的币种时列出
+func (t *sheetTotal) note() string {
+	if len(t.unconverted) == 0 {
+		return ""
+	}
+	return "（" + strings.Join(mergeCurrencyCodes(t.unconverted), "/") + " 缺少汇率，未计入合计）"
+}
+
+// summaryNumFmt 合计行金额的数字格式（本位币），money 为 nil 时为裸数字
+func summaryNumFmt(money *moneyFormat) string {
+	if money == nil {
+		return ""
+	}
+	return money.withCurrency(baseCurrency()).ExcelNumFmt()
+}
+
+// writeIncomeSheet 写入收入记录工作表：表头、明细与合计行；money、rates 的含义同 writeExpenseSheet
+func writeIncomeSheet(f *excelize.File, sheet string, incomes []IncomeWithUser, money *moneyFormat, rates exchangeRates) {
+	dataStyle, _ := excelDataStyle(f, "")
+	amountStyles := newExcelAmountStyles(f, money)
+
+	columns := []excelColumn{
+		{"ID", 10}, {"用户名", 15}, {"金额", 12}, {"收入类型", 12}, {"收入时间", 20}, {"创建时间", 20},
+	}
+	excelWriteHeader(f, sheet, columns)
+
+	var total sheetTotal
+	for i, income := range incomes {
+		row := i + 2
+		excelWriteRow(f, sheet, row, []interface{}{
+			income.ID,
+			income.Username,
+			income.Amount,
+			income.Type,
+			income.IncomeTime.Format("2006-01-02 15:04:05"),
+			income.CreatedAt.Format("2006-01-02 15:04:05"),
+		}, dataStyle)
+		amountCell, _ := excelize.CoordinatesToCellName(3, row)
+		f.SetCellStyle(sheet, amountCell, amountCell, amountStyles.get(income.Currency))
+		total.add(rates, income.Amount, income.Currency)
+	}
+
+	excelWriteSummary(f, sheet, len(incomes)+2, 3, len(columns), roundMoney(total.amount), fmt.Sprintf("共 %d 条记录", len(incomes))+total.note(), summaryNumFmt(money))
+}
+
+// queryIncomesWithUser 按导出范围查询带用户名的收入记录，按收入时间倒序
+func queryIncomesWithUser(scope *exportScope) ([]IncomeWithUser, error) {
+	var incomes []IncomeWithUser
+	query := database.DB.Model(&models.Income{}).
+		Select("incomes.*, users.username").
+		Joins("LEFT JOIN users ON incomes.user_id = users.id")
+	err := scope.apply(query, "incomes.user_id", "incomes.income_time").
+		Order("incomes.income_time DESC").
+		Scan(&incomes).Error
+	return incomes, err
+}
+
+// queryExpensesWithUser 按导出范围查询带用户名的消费记录，按消费时间倒序
+func queryExpensesWithUser(scope *exportScope) ([]ExpenseWithUser, error) {
+	var expenses []ExpenseWithUser
+	query := database.DB.Model(&models.Expense{}).
+		Select("expenses.*, users.username").
+		Joins("LEFT JOIN users ON expenses.user_id = users.id")
+	err := scope.applyExpense(query, "expenses.user_id", "expenses.expense_time", "expenses.category").
+		Order("expenses.expense_time DESC").
+		Scan(&expenses).Error
+	return expenses, err
+}
+
+// buildWorkbookSummary 按月汇总明细：start 到 end 之间的每个月都出现（无记录为 0），
+// 金额按汇率折算为本位币，缺少汇率的币种不计入并返回其代码
+func buildWorkbookSummary(start, end time.Time, expenses []ExpenseWithUser, incomes []IncomeWithUser, rates exchangeRates) ([]CashFlowMonth, []string) {
+	var months []CashFlowMonth
+	index := map[string]int{}
+	for m := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, start.Location()); !m.After(end); m = m.AddDate(0, 1, 0) {
+		key := m.Format("2006-01")
+		index[key] = len(months)
+		months = append(months, CashFlowMonth{Month: key})
+	}
+
+	var unconverted []string
+	add := func(t time.Time, amount float64, currency string, income bool) {
+		i, ok := index[t.Format("2006-01")]
+		if !ok {
+			return
+		}
+		converted, ok := rates.ToBase(amount, currency)
+		if !ok {
+			unconverted = append(unconverted, currency)
+			return
+		}
+		if income {
+			months[i].Income += converted
+		} else {
+			months[i].Expense += converted
+		}
+	}
+	for _, e := range expenses {
+		add(e.ExpenseTime, e.Amount, e.Currency, false)
+	}
+	for _, in := range incomes {
+		add(in.IncomeTime, in.Amount, in.Currency, true)
+	}
+
+	var balance float64
+	for i := range months {
+		months[i].Income = roundMoney(months[i].Income)
+		months[i].Expense = roundMoney(months[i].Expense)
+		months[i].Net = roundMoney(months[i].Income - months[i].Expense)
+		balance += months[i].Net
+		months[i].Balance = roundMoney(balance)
+	}
+	return months, mergeCurrencyCodes(unconverted)
+}
+
+// writeSummarySheet 写入收支汇总工作表：按月的收入、支出、结余与累计结余，末行为合计
+func writeSummarySheet(f *excelize.File, sheet string, months []CashFlowMonth, unconverted []string) {
+	dataStyle, _ := excelDataStyle(f, "")
+	amountStyle, _ := excelDataStyle(f, "#,##0.00")
+	summaryStyle, _ := excelSummaryStyle(f, "#,##0.00")
+
+	base := baseCurrency()
+	excelWriteHeader(f, sheet, []excelColumn{
+		{"月份", 12}, {"收入（" + base + "）", 16}, {"支出（" + base + "）", 16}, {"结余（" + base + "）", 16}, {"累计结余（" + base + "）", 18},
+	})
+
+	var totalIncome, totalExpense float64
+	for i, m := range months {
+		row := i + 2
+		excelWriteRow(f, sheet, row, []interface{}{m.Month, m.Income, m.Expense, m.Net, m.Balance}, dataStyle)
+		first, _ := excelize.CoordinatesToCellName(2, row)
+		last, _ := excelize.CoordinatesToCellName(5, row)
+		f.SetCellStyle(sheet, first, last, amountStyle)
+		totalIncome += m.Income
+		totalExpense += m.Expense
+	}
+
+	row := len(months) + 2
+	totalIncome, totalExpense = roundMoney(totalIncome), roundMoney(totalExpense)
+	excelWriteRow(f, sheet, row, []interface{}{"合计", totalIncome, totalExpense, roundMoney(totalIncome - totalExpense), ""}, summaryStyle)
+
+	if len(unconverted) > 0 {
+		note := "以下币种缺少汇率，未计入汇总："
+		for i, code := range unconverted {
+			if i > 0 {
+				note += "、"
+			}
+			note += code
+		}
+		cell, _ := excelize.CoordinatesToCellName(1, row+2)
+		f.SetCellValue(sheet, cell, note)
+	}
+}
+
+// ExportWorkbook 导出收支工作簿
+// @Summary 导出收支工作簿（Excel）
+// @Description 导出一个包含三个工作表的 Excel：消费记录、收入记录（版式同各自的 Excel 导出，末行为合计）与收支汇总（按月的收入、支出、结余与累计结余，折算为本位币）。
+// @Description 管理员导出所有用户数据，普通用户只能导出自己的数据。不传时间范围时导出本月（按 tz 计算），实际范围由响应头 X-Range-Start/X-Range-End 回显
+// @Tags 后台管理-导出
+// @Produce application/vnd.openxmlformats-officedocument.spreadsheetml.sheet
+// @Param start_time query string false "开始时间 (YYYY-MM-DD)，与 end_time 都不传时默认本月 1 日"
+// @Param end_time query string false "结束时间 (YYYY-MM-DD)，与 start_time 都不传时默认今天"
+// @Param tz query string false "IANA 时区，默认服务器时区"
+// @Param include_transfer query bool false "消费是否包含内部转账类别，默认不包含"
+// @Param formatted query bool false "明细金额列使用货币数字格式（仍为数值，可计算），默认裸数字"
+// @Param currency query string false "默认货币代码（formatted=true 时生效，每条记录按自身币种显示，币种不在列表中时用该值）：CNY/USD/EUR/GBP/HKD/JPY，默认 CNY"
+// @Param locale query string false "区域（formatted=true 时生效）：zh-CN/en-US/en-GB/ja-JP/de-DE/fr-FR，默认 zh-CN"
+// @Success 200 {file} file "Excel文件"
+// @Failure 400 {object} map[string]interface{} "参数错误"
+// @Failure 401 {object} map[string]interface{} "未登录"
+// @Router /admin/export/workbook [get]
+func (h *AdminHandler) ExportWorkbook(c *gin.Context) {
+	currentUser, err := getCurrentUser(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"success": false, "message": "未登录"})
+		return
+	}
+
+	scope, err := exportScopeFromQuery(c, currentUser.ID, currentUser.IsAdmin)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+	scope.IncludeTransfer = includeTransfer(c)
+
+	money, err := resolveMoneyFormat(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"success": false, "message": err.Error()})
+		return
+	}
+
+	expenses, err := queryExpensesWithUser(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询数据失败")})
+		return
+	}
+	incomes, err := queryIncomesWithUser(scope)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询数据失败")})
+		return
+	}
+	rates, err := loadExchangeRates()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": SafeErrorMessage(err, "查询汇率失败")})
+		return
+	}
+
+	f := excelize.NewFile()
+	defer f.Close()
+
+	f.SetSheetName("Sheet1", expenseSheetName)
+	writeExpenseSheet(f, expenseSheetName, expenses, money, rates, false, nil)
+	f.NewSheet(incomeSheetName)
+	writeIncomeSheet(f, incomeSheetName, incomes, money, rates)
+	f.NewSheet(summarySheetName)
+	months, unconverted := buildWorkbookSummary(scope.Start, scope.End, expenses, incomes, rates)
+	writeSummarySheet(f, summarySheetName, months, unconverted)
+
+	filename := fmt.Sprintf("收支工作簿_%s_%s.xlsx", scope.StartStr, scope.EndStr)
+	setRangeHeaders(c, scope.StartStr, scope.EndStr)
+	if err := writeExcelResponse(c, f, filename); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "生成 Excel 失败"})
+		return
+	}
+}
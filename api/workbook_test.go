@@ -0,0 +1,138 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"finance/adminauth"
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xuri/excelize/v2"
+)
+
+func TestBuildWorkbookSummary(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 3, 31, 23, 59, 59, 0, time.Local)
+	expenses := []ExpenseWithUser{
+		{Expense: models.Expense{Amount: 100, Currency: "CNY", ExpenseTime: time.Date(2024, 1, 5, 0, 0, 0, 0, time.Local)}},
+		{Expense: models.Expense{Amount: 10, Currency: "USD", ExpenseTime: time.Date(2024, 3, 5, 0, 0, 0, 0, time.Local)}},
+		{Expense: models.Expense{Amount: 1000, Currency: "JPY", ExpenseTime: time.Date(2024, 3, 6, 0, 0, 0, 0, time.Local)}},
+	}
+	incomes := []IncomeWithUser{
+		{Income: models.Income{Amount: 500, Currency: "", IncomeTime: time.Date(2024, 1, 10, 0, 0, 0, 0, time.Local)}},
+	}
+
+	months, unconverted := buildWorkbookSummary(start, end, expenses, incomes, exchangeRates{"CNY": 1, "USD": 7})
+
+	// 没有记录的 2 月也列出；缺少汇率的 JPY 不计入
+	require.Len(t, months, 3)
+	assert.Equal(t, CashFlowMonth{Month: "2024-01", Income: 500, Expense: 100, Net: 400, Balance: 400}, months[0])
+	assert.Equal(t, CashFlowMonth{Month: "2024-02", Balance: 400}, months[1])
+	assert.Equal(t, CashFlowMonth{Month: "2024-03", Expense: 70, Net: -70, Balance: 330}, months[2])
+	assert.Equal(t, []string{"JPY"}, unconverted)
+}
+
+func TestWriteExpenseSheet_TotalInBaseCurrency(t *testing.T) {
+	f := excelize.NewFile()
+	defer f.Close()
+
+	now := time.Now()
+	expenses := []ExpenseWithUser{
+		{Expense: models.Expense{ID: 1, Amount: 100, Currency: "CNY", ExpenseTime: now, CreatedAt: now}},
+		{Expense: models.Expense{ID: 2, Amount: 10, Currency: "USD", ExpenseTime: now, CreatedAt: now}},
+		{Expense: models.Expense{ID: 3, Amount: 1000, Currency: "JPY", ExpenseTime: now, CreatedAt: now}},
+	}
+	writeExpenseSheet(f, "Sheet1", expenses, nil, exchangeRates{"CNY": 1, "USD": 7}, false, nil)
+
+	rows, err := f.GetRows("Sheet1")
+	require.NoError(t, err)
+	require.Len(t, rows, 5)
+	assert.Equal(t, "170", rows[4][2])
+	assert.Equal(t, "共 3 条记录（JPY 缺少汇率，未计入合计）", rows[4][3])
+}
+
+func TestAdminHandler_ExportWorkbook(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	jan := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+	mock.ExpectQuery("SELECT expenses\\.\\*, users\\.username FROM `expenses` LEFT JOIN users ON expenses.user_id = users.id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "category", "description", "expense_time", "created_at", "username"}).
+			AddRow(1, 1, 99.5, "CNY", "餐饮", "午餐", jan, jan, "alice"))
+	mock.ExpectQuery("SELECT incomes\\.\\*, users\\.username FROM `incomes` LEFT JOIN users ON incomes.user_id = users.id").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "amount", "currency", "type", "income_time", "created_at", "username"}).
+			AddRow(1, 1, 8000, "CNY", "工资", jan, jan, "alice"))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "currency", "rate"}))
+
+	router := gin.New()
+	router.GET("/admin/export/workbook", NewAdminHandler().ExportWorkbook)
+
+	req := httptest.NewRequest("GET", "/admin/export/workbook?start_time=2024-01-01&end_time=2024-02-29", nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "attachment; filename*=UTF-8''收支工作簿_2024-01-01_2024-02-29.xlsx", w.Header().Get("Content-Disposition"))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	f, err := excelize.OpenReader(bytes.NewReader(w.Body.Bytes()))
+	require.NoError(t, err)
+	defer f.Close()
+
+	assert.Equal(t, []string{"消费记录", "收入记录", "收支汇总"}, f.GetSheetList())
+
+	expenseRows, err := f.GetRows("消费记录")
+	require.NoError(t, err)
+	require.Len(t, expenseRows, 3)
+	assert.Equal(t, "午餐", expenseRows[1][4])
+	assert.Equal(t, "合计", expenseRows[2][0])
+
+	incomeRows, err := f.GetRows("收入记录")
+	require.NoError(t, err)
+	require.Len(t, incomeRows, 3)
+	assert.Equal(t, "工资", incomeRows[1][3])
+
+	summaryRows, err := f.GetRows("收支汇总", excelize.Options{RawCellValue: true})
+	require.NoError(t, err)
+	require.Len(t, summaryRows, 4)
+	assert.Equal(t, "月份", summaryRows[0][0])
+	assert.Equal(t, []string{"2024-01", "8000", "99.5", "7900.5", "7900.5"}, summaryRows[1])
+	assert.Equal(t, []string{"2024-02", "0", "0", "0", "7900.5"}, summaryRows[2])
+	assert.Equal(t, "合计", summaryRows[3][0])
+}
+
+func TestAdminHandler_ExportWorkbook_InvalidRange(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	initCookieTestConfig("debug", "test-secret")
+	defer func() { config.GlobalConfig = nil }()
+
+	mock.ExpectQuery("SELECT .* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "is_admin"}).AddRow(1, "admin", true))
+
+	router := gin.New()
+	router.GET("/admin/export/workbook", NewAdminHandler().ExportWorkbook)
+
+	req := httptest.NewRequest("GET", "/admin/export/workbook?start_time=2024-02-01", nil)
+	req.AddCookie(&http.Cookie{Name: "admin_user_id", Value: adminauth.SignCookieValue("1")})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, 400, w.Code)
+}
@@ -12,19 +12,189 @@ import (
 
 // Config 应用配置
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Email    EmailConfig    `mapstructure:"email"`
-	Feishu   FeishuConfig  `mapstructure:"feishu"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Email     EmailConfig     `mapstructure:"email"`
+	Feishu    FeishuConfig    `mapstructure:"feishu"`
+	Limits    LimitsConfig    `mapstructure:"limits"`
+	Export    ExportConfig    `mapstructure:"export"`
+	Import    ImportConfig    `mapstructure:"import"`
+	AI        AIConfig        `mapstructure:"ai"`
+	Login     LoginConfig     `mapstructure:"login"`
+	Stats     StatsConfig     `mapstructure:"stats"`
+	Retention RetentionConfig `mapstructure:"retention"`
+	Currency  CurrencyConfig  `mapstructure:"currency"`
+	Audit     AuditConfig     `mapstructure:"audit"`
+	Storage   StorageConfig   `mapstructure:"storage"`
+	Category  CategoryConfig  `mapstructure:"category"`
+	Security  SecurityConfig  `mapstructure:"security"`
+}
+
+// SecurityConfig 敏感数据加密配置
+type SecurityConfig struct {
+	// EncryptionKey 加密敏感字段（两步验证密钥、AI 模型 API Key）所用的密钥，为空时沿用 jwt.secret；
+	// 设置后不可随意更换，否则已加密的数据无法解密
+	EncryptionKey string `mapstructure:"encryption_key"`
+}
+
+// DefaultFallbackCategory 强制删除仍在使用的类别时，记录默认改挂到的类别
+const DefaultFallbackCategory = "其他"
+
+// CategoryConfig 类别管理配置
+type CategoryConfig struct {
+	ExpenseFallback string `mapstructure:"expense_fallback"` // 强制删除仍被消费记录使用的类别时，这些记录改挂到的消费类别
+	IncomeFallback  string `mapstructure:"income_fallback"`  // 强制删除仍被收入记录使用的类别时，这些记录改挂到的收入类别
+}
+
+// 消费附件存储默认值
+const (
+	DefaultReceiptDir       = "data/receipts"
+	DefaultReceiptMaxSizeMB = 5
+	DefaultAudioMaxSizeMB   = 10
+	DefaultTextMaxSizeMB    = 1
+)
+
+// StorageConfig 上传文件存储配置
+type StorageConfig struct {
+	ReceiptDir       string `mapstructure:"receipt_dir"`         // 消费附件保存目录（相对路径基于工作目录），按用户ID分子目录
+	ReceiptMaxSizeMB int    `mapstructure:"receipt_max_size_mb"` // 单张小票图片大小上限（MB）
+	AudioMaxSizeMB   int    `mapstructure:"audio_max_size_mb"`   // 单个语音附件（MP3/M4A）大小上限（MB）
+	TextMaxSizeMB    int    `mapstructure:"text_max_size_mb"`    // 单个文本附件（TXT）大小上限（MB）
+}
+
+// AuditConfig 安全审计配置
+type AuditConfig struct {
+	RecordSource bool `mapstructure:"record_source"` // 创建消费/收入记录时是否记录提交来源 IP 与 User-Agent（默认开启，出于隐私考虑可关闭）
+}
+
+// DefaultBaseCurrency 默认本位币
+const DefaultBaseCurrency = "CNY"
+
+// CurrencyConfig 多币种配置
+type CurrencyConfig struct {
+	Base    string   `mapstructure:"base"`    // 本位币（ISO 4217），统计时其他币种按汇率表折算为本位币，未传币种的记录也使用本位币
+	Allowed []string `mapstructure:"allowed"` // 允许记账的币种，本位币总是允许；环境变量用逗号分隔
+}
+
+// DefaultDeletedRetentionDays 软删除记录默认保留天数
+const DefaultDeletedRetentionDays = 30
+
+// RetentionConfig 数据保留配置
+type RetentionConfig struct {
+	DeletedDays int `mapstructure:"deleted_days"` // 软删除的消费/收入记录保留天数，超过后由定时任务物理删除
+}
+
+// 周起始日
+const (
+	WeekStartMonday = "mon"
+	WeekStartSunday = "sun"
+)
+
+// StatsConfig 统计配置
+type StatsConfig struct {
+	WeekStart string `mapstructure:"week_start"` // 一周的第一天：mon（默认）/sun，用户可在偏好中单独设置
+}
+
+// 登录防爆破默认值
+const (
+	DefaultLoginCaptchaThreshold     = 3  // 连续失败多少次后要求验证码
+	DefaultLoginFailureWindowMinutes = 15 // 失败次数统计窗口（分钟）
+	DefaultLoginMaxFailures          = 5  // 连续失败多少次后锁定
+	DefaultLoginLockoutMinutes       = 15 // 锁定时长（分钟）
+)
+
+// LoginConfig 登录防爆破配置（验证码仅用于后台登录，失败锁定同时作用于 App 与后台登录）
+type LoginConfig struct {
+	CaptchaThreshold     int `mapstructure:"captcha_threshold"`      // 同一账号或 IP 连续失败达到该次数后必须携带验证码
+	FailureWindowMinutes int `mapstructure:"failure_window_minutes"` // 失败次数统计窗口，窗口内无新的失败后自动清零
+	MaxFailures          int `mapstructure:"max_failures"`           // 同一账号或 IP 在统计窗口内失败达到该次数后锁定
+	LockoutMinutes       int `mapstructure:"lockout_minutes"`        // 锁定时长，锁定期间登录直接返回 429
+}
+
+// AI 调用排队默认值
+const (
+	DefaultAIMaxConcurrentPerModel = 3  // 每个模型默认最多同时进行的上游请求数
+	DefaultAIQueueTimeoutSeconds   = 60 // 默认最长排队等待秒数
+)
+
+// DefaultAIMaxAnalysisDays AI 分析时间范围默认最大跨度（天，含首尾）
+const DefaultAIMaxAnalysisDays = 366
+
+// DefaultAIDailyQuota 每个用户每日默认可发起的 AI 聊天/分析次数
+const DefaultAIDailyQuota = 50
+
+// 多轮对话默认附带的历史上限
+const (
+	DefaultAIChatHistoryMessages  = 10   // 默认最多附带的历史轮数
+	DefaultAIChatHistoryMaxTokens = 3000 // 默认历史的估算 token 上限
+)
+
+// AI 上游请求默认的超时与重试设置
+const (
+	DefaultAIConnectTimeoutSeconds   = 10  // 默认建连超时秒数
+	DefaultAIFirstByteTimeoutSeconds = 60  // 默认首帧等待秒数
+	DefaultAIMaxRetries              = 2   // 上游返回 429/5xx 时默认最多重试次数
+	DefaultAIRetryBackoffMs          = 500 // 默认首次重试前等待的毫秒数，之后每次翻倍
+)
+
+// AIConfig AI 调用配置
+type AIConfig struct {
+	ProxyURL                string `mapstructure:"proxy_url"`                  // 全局代理（http/https/socks5），模型单独配置了代理时以模型为准
+	MaxConcurrentPerModel   int    `mapstructure:"max_concurrent_per_model"`   // 每个模型的并发上限，模型单独配置时以模型为准
+	QueueTimeoutSeconds     int    `mapstructure:"queue_timeout_seconds"`      // 超出并发上限时最长排队等待秒数
+	MaxAnalysisDays         int    `mapstructure:"max_analysis_days"`          // AI 分析时间范围的最大跨度（天，含首尾）
+	DailyQuota              int    `mapstructure:"daily_quota"`                // 每个用户每日可发起的聊天/分析次数，管理员可为单个用户调整
+	ChatHistoryMessages     int    `mapstructure:"chat_history_messages"`      // 多轮对话最多附带的历史轮数（一问一答为一轮）
+	ChatHistoryMaxTokens    int    `mapstructure:"chat_history_max_tokens"`    // 多轮对话附带历史的估算 token 上限，超出时丢弃最早的轮次
+	ConnectTimeoutSeconds   int    `mapstructure:"connect_timeout_seconds"`    // 与上游建立连接（含 TLS 握手）的超时秒数
+	FirstByteTimeoutSeconds int    `mapstructure:"first_byte_timeout_seconds"` // 发出请求后等待首帧的最长秒数，超时视为上游不可用
+	MaxRetries              int    `mapstructure:"max_retries"`                // 上游返回 429/5xx 时最多重试次数，0 表示不重试
+	RetryBackoffMs          int    `mapstructure:"retry_backoff_ms"`           // 首次重试前等待的毫秒数，之后每次翻倍；上游给出 Retry-After 时取较大值
+}
+
+// DefaultExportMaxConcurrent 默认最多同时进行的导出数
+const DefaultExportMaxConcurrent = 2
+
+// ExportConfig 导出配置
+type ExportConfig struct {
+	MaxConcurrent int    `mapstructure:"max_concurrent"`  // 全局最多同时进行的导出数（Excel/CSV/JSON/PDF 合并计数）
+	ChartFontPath string `mapstructure:"chart_font_path"` // 统计图字体（TTF/OTF，需包含中文字形）；为空时类别名以序号代替
+	PDFFontPath   string `mapstructure:"pdf_font_path"`   // PDF 报表嵌入的字体（TrueType，需包含中文字形）；为空时使用阅读器内置宋体
+}
+
+// 导入时遇到未映射且不存在的类别的处理方式
+const (
+	ImportUnknownCategorySkip   = "skip"   // 跳过该行（默认）
+	ImportUnknownCategoryCreate = "create" // 自动创建该类别
+)
+
+// ImportConfig 消费记录导入配置
+type ImportConfig struct {
+	UnknownCategory string `mapstructure:"unknown_category"` // 未映射且不存在的类别：skip（跳过该行）/create（自动创建）
+}
+
+// 记账字段校验默认值
+const (
+	DefaultMaxAmount            = 99999999.99  // 与 decimal(10,2) 列的最大值一致
+	DefaultMaxDescriptionLength = 255          // 与描述列 size:255 一致
+	DefaultMinRecordDate        = "2000-01-01" // 消费/收入时间下限
+)
+
+// LimitsConfig 记账字段校验配置（消费、收入共用）
+type LimitsConfig struct {
+	MaxAmount            float64 `mapstructure:"max_amount"`             // 单笔金额上限，不能超过 DefaultMaxAmount
+	MaxDescriptionLength int     `mapstructure:"max_description_length"` // 描述/备注最大字符数，不能超过 DefaultMaxDescriptionLength
+	MinRecordDate        string  `mapstructure:"min_record_date"`        // 消费/收入时间下限（YYYY-MM-DD），上限固定为当前时间 + 1 天
 }
 
 // FeishuConfig 飞书配置（扫码登录）
 type FeishuConfig struct {
-	Enabled       bool   `mapstructure:"enabled"`
-	AppID         string `mapstructure:"app_id"`          // 等同于 client_id，从飞书开放平台获取
-	AppSecret     string `mapstructure:"app_secret"`      // 等同于 client_secret
-	AutoCreateUser bool  `mapstructure:"auto_create_user"` // 首次扫码是否自动创建用户，默认 false
+	Enabled        bool   `mapstructure:"enabled"`
+	AppID          string `mapstructure:"app_id"`           // 等同于 client_id，从飞书开放平台获取
+	AppSecret      string `mapstructure:"app_secret"`       // 等同于 client_secret
+	AutoCreateUser bool   `mapstructure:"auto_create_user"` // 首次扫码是否自动创建用户，默认 false
+	DocFolderToken string `mapstructure:"doc_folder_token"` // 导出云文档的目标文件夹 token，为空时创建在应用根目录
 }
 
 // ServerConfig 服务器配置
@@ -36,19 +206,35 @@ type ServerConfig struct {
 
 // DatabaseConfig 数据库配置
 type DatabaseConfig struct {
+	Driver   string `mapstructure:"driver"` // mysql（默认）或 sqlite
 	Host     string `mapstructure:"host"`
 	Port     string `mapstructure:"port"`
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	DBName   string `mapstructure:"dbname"`
 	Charset  string `mapstructure:"charset"`
+	Path     string `mapstructure:"path"` // SQLite 数据库文件路径，driver=sqlite 时使用
 }
 
+// 支持的数据库驱动
+const (
+	DatabaseDriverMySQL  = "mysql"
+	DatabaseDriverSQLite = "sqlite"
+)
+
+// DefaultSQLitePath driver=sqlite 且未配置 path 时使用的数据库文件
+const DefaultSQLitePath = "finance.db"
+
+// DefaultRefreshExpireDays refresh token 默认有效期（天）
+const DefaultRefreshExpireDays = 30
+
 // JWTConfig JWT配置
 type JWTConfig struct {
-	Secret      string        `mapstructure:"secret"`
-	ExpireHours int           `mapstructure:"expire_hours"`
-	ExpireTime  time.Duration `mapstructure:"-"`
+	Secret            string        `mapstructure:"secret"`
+	ExpireHours       int           `mapstructure:"expire_hours"`
+	ExpireTime        time.Duration `mapstructure:"-"`
+	RefreshExpireDays int           `mapstructure:"refresh_expire_days"` // refresh token 有效期（天），每次刷新重新计算
+	RefreshExpireTime time.Duration `mapstructure:"-"`
 }
 
 // EmailConfig 邮件配置
@@ -59,8 +245,17 @@ type EmailConfig struct {
 	Username string `mapstructure:"username"`
 	Password string `mapstructure:"password"`
 	From     string `mapstructure:"from"`
+	// DigestBatchSize 每日消费汇总邮件每批发送的封数，DigestBatchIntervalSeconds 为批次间隔，避免触发 SMTP 限流
+	DigestBatchSize            int `mapstructure:"digest_batch_size"`
+	DigestBatchIntervalSeconds int `mapstructure:"digest_batch_interval_seconds"`
 }
 
+// 每日消费汇总邮件分批发送默认值
+const (
+	DefaultDigestBatchSize            = 20
+	DefaultDigestBatchIntervalSeconds = 60
+)
+
 var (
 	// GlobalConfig 全局配置实例
 	GlobalConfig *Config
@@ -119,11 +314,116 @@ func LoadConfig(configPath string) (*Config, error) {
 		return nil, fmt.Errorf("解析配置失败: %w", err)
 	}
 
+	// 数据库驱动：默认 MySQL
+	cfg.Database.Driver = strings.ToLower(strings.TrimSpace(cfg.Database.Driver))
+	if cfg.Database.Driver == "" {
+		cfg.Database.Driver = DatabaseDriverMySQL
+	}
+	if cfg.Database.Driver == DatabaseDriverSQLite && strings.TrimSpace(cfg.Database.Path) == "" {
+		cfg.Database.Path = DefaultSQLitePath
+	}
+
 	// 设置 JWT 过期时间
 	if cfg.JWT.ExpireHours <= 0 {
 		cfg.JWT.ExpireHours = 24
 	}
 	cfg.JWT.ExpireTime = time.Duration(cfg.JWT.ExpireHours) * time.Hour
+	if cfg.JWT.RefreshExpireDays <= 0 {
+		cfg.JWT.RefreshExpireDays = DefaultRefreshExpireDays
+	}
+	cfg.JWT.RefreshExpireTime = time.Duration(cfg.JWT.RefreshExpireDays) * 24 * time.Hour
+
+	// 字段校验上限：未配置或超过数据库列的容量时使用默认值
+	if cfg.Limits.MaxAmount <= 0 || cfg.Limits.MaxAmount > DefaultMaxAmount {
+		cfg.Limits.MaxAmount = DefaultMaxAmount
+	}
+	if cfg.Limits.MaxDescriptionLength <= 0 || cfg.Limits.MaxDescriptionLength > DefaultMaxDescriptionLength {
+		cfg.Limits.MaxDescriptionLength = DefaultMaxDescriptionLength
+	}
+	if _, err := time.Parse("2006-01-02", cfg.Limits.MinRecordDate); err != nil {
+		cfg.Limits.MinRecordDate = DefaultMinRecordDate
+	}
+	if cfg.Export.MaxConcurrent <= 0 {
+		cfg.Export.MaxConcurrent = DefaultExportMaxConcurrent
+	}
+	if cfg.AI.MaxConcurrentPerModel <= 0 {
+		cfg.AI.MaxConcurrentPerModel = DefaultAIMaxConcurrentPerModel
+	}
+	if cfg.AI.QueueTimeoutSeconds <= 0 {
+		cfg.AI.QueueTimeoutSeconds = DefaultAIQueueTimeoutSeconds
+	}
+	if cfg.AI.MaxAnalysisDays <= 0 {
+		cfg.AI.MaxAnalysisDays = DefaultAIMaxAnalysisDays
+	}
+	if cfg.AI.DailyQuota <= 0 {
+		cfg.AI.DailyQuota = DefaultAIDailyQuota
+	}
+	if cfg.AI.ChatHistoryMessages <= 0 {
+		cfg.AI.ChatHistoryMessages = DefaultAIChatHistoryMessages
+	}
+	if cfg.AI.ChatHistoryMaxTokens <= 0 {
+		cfg.AI.ChatHistoryMaxTokens = DefaultAIChatHistoryMaxTokens
+	}
+	if cfg.AI.ConnectTimeoutSeconds <= 0 {
+		cfg.AI.ConnectTimeoutSeconds = DefaultAIConnectTimeoutSeconds
+	}
+	if cfg.AI.FirstByteTimeoutSeconds <= 0 {
+		cfg.AI.FirstByteTimeoutSeconds = DefaultAIFirstByteTimeoutSeconds
+	}
+	if cfg.AI.MaxRetries < 0 {
+		cfg.AI.MaxRetries = 0
+	}
+	if cfg.AI.RetryBackoffMs <= 0 {
+		cfg.AI.RetryBackoffMs = DefaultAIRetryBackoffMs
+	}
+	if cfg.Login.CaptchaThreshold <= 0 {
+		cfg.Login.CaptchaThreshold = DefaultLoginCaptchaThreshold
+	}
+	if cfg.Login.FailureWindowMinutes <= 0 {
+		cfg.Login.FailureWindowMinutes = DefaultLoginFailureWindowMinutes
+	}
+	if cfg.Login.MaxFailures <= 0 {
+		cfg.Login.MaxFailures = DefaultLoginMaxFailures
+	}
+	if cfg.Login.LockoutMinutes <= 0 {
+		cfg.Login.LockoutMinutes = DefaultLoginLockoutMinutes
+	}
+	if cfg.Retention.DeletedDays <= 0 {
+		cfg.Retention.DeletedDays = DefaultDeletedRetentionDays
+	}
+	if cfg.Email.DigestBatchSize <= 0 {
+		cfg.Email.DigestBatchSize = DefaultDigestBatchSize
+	}
+	if cfg.Email.DigestBatchIntervalSeconds <= 0 {
+		cfg.Email.DigestBatchIntervalSeconds = DefaultDigestBatchIntervalSeconds
+	}
+	if strings.TrimSpace(cfg.Storage.ReceiptDir) == "" {
+		cfg.Storage.ReceiptDir = DefaultReceiptDir
+	}
+	if cfg.Storage.ReceiptMaxSizeMB <= 0 {
+		cfg.Storage.ReceiptMaxSizeMB = DefaultReceiptMaxSizeMB
+	}
+	if cfg.Storage.AudioMaxSizeMB <= 0 {
+		cfg.Storage.AudioMaxSizeMB = DefaultAudioMaxSizeMB
+	}
+	if cfg.Storage.TextMaxSizeMB <= 0 {
+		cfg.Storage.TextMaxSizeMB = DefaultTextMaxSizeMB
+	}
+	if cfg.Stats.WeekStart != WeekStartSunday {
+		cfg.Stats.WeekStart = WeekStartMonday
+	}
+	cfg.Category.ExpenseFallback = strings.TrimSpace(cfg.Category.ExpenseFallback)
+	if cfg.Category.ExpenseFallback == "" {
+		cfg.Category.ExpenseFallback = DefaultFallbackCategory
+	}
+	cfg.Category.IncomeFallback = strings.TrimSpace(cfg.Category.IncomeFallback)
+	if cfg.Category.IncomeFallback == "" {
+		cfg.Category.IncomeFallback = DefaultFallbackCategory
+	}
+	if cfg.Import.UnknownCategory != ImportUnknownCategoryCreate {
+		cfg.Import.UnknownCategory = ImportUnknownCategorySkip
+	}
+	cfg.Currency.normalize()
 
 	// 保存到全局变量
 	GlobalConfig = &cfg
@@ -166,11 +466,34 @@ func PrintConfig() {
 	}
 	log.Printf("当前配置:")
 	log.Printf("  服务器: %s (模式: %s)", GlobalConfig.Server.Port, GlobalConfig.Server.Mode)
-	log.Printf("  数据库: %s@%s:%s/%s",
-		GlobalConfig.Database.Username,
-		GlobalConfig.Database.Host,
-		GlobalConfig.Database.Port,
-		GlobalConfig.Database.DBName)
+	if GlobalConfig.Database.Driver == DatabaseDriverSQLite {
+		log.Printf("  数据库: sqlite %s", GlobalConfig.Database.Path)
+	} else {
+		log.Printf("  数据库: %s@%s:%s/%s",
+			GlobalConfig.Database.Username,
+			GlobalConfig.Database.Host,
+			GlobalConfig.Database.Port,
+			GlobalConfig.Database.DBName)
+	}
 	log.Printf("  邮件服务: %v", GlobalConfig.Email.Enabled)
 	log.Printf("  飞书扫码登录: %v", GlobalConfig.Feishu.Enabled)
 }
+
+// normalize 币种代码统一大写去空格，本位币默认 CNY 且总在允许列表中（排在首位）
+func (c *CurrencyConfig) normalize() {
+	c.Base = strings.ToUpper(strings.TrimSpace(c.Base))
+	if c.Base == "" {
+		c.Base = DefaultBaseCurrency
+	}
+	allowed := []string{c.Base}
+	seen := map[string]bool{c.Base: true}
+	for _, code := range c.Allowed {
+		code = strings.ToUpper(strings.TrimSpace(code))
+		if code == "" || seen[code] {
+			continue
+		}
+		seen[code] = true
+		allowed = append(allowed, code)
+	}
+	c.Allowed = allowed
+}
@@ -0,0 +1,96 @@
+package config
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+// Validate 校验必填项与依赖项（启用邮件需 SMTP 配置齐全、启用飞书需 app_id/app_secret 等），
+// 一次性列出所有问题；应在 LoadConfig 及命令行覆盖之后、初始化其他组件之前调用
+func (c *Config) Validate() error {
+	var problems []string
+	require := func(value, key string) {
+		if strings.TrimSpace(value) == "" {
+			problems = append(problems, key+" 不能为空")
+		}
+	}
+
+	// 服务器
+	require(c.Server.Port, "server.port")
+	switch c.Server.Mode {
+	case "", "debug", "release", "test":
+	default:
+		problems = append(problems, "server.mode 无效: "+c.Server.Mode+"，可选值: debug/release/test")
+	}
+	if c.Server.BaseURL != "" {
+		if u, err := url.Parse(c.Server.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "server.base_url 格式错误，应形如 https://finance.example.com")
+		}
+	}
+
+	// 数据库：SQLite 只需要文件路径
+	switch c.Database.Driver {
+	case "", DatabaseDriverMySQL:
+		require(c.Database.Host, "database.host")
+		require(c.Database.Port, "database.port")
+		require(c.Database.Username, "database.username")
+		require(c.Database.DBName, "database.dbname")
+	case DatabaseDriverSQLite:
+		require(c.Database.Path, "database.path（database.driver=sqlite 时）")
+	default:
+		problems = append(problems, "database.driver 无效: "+c.Database.Driver+"，可选值: mysql/sqlite")
+	}
+
+	// JWT：空密钥签出的 token 可被任意伪造
+	require(c.JWT.Secret, "jwt.secret")
+
+	// 邮件
+	if c.Email.Enabled {
+		require(c.Email.Host, "email.host（email.enabled=true 时）")
+		if c.Email.Port <= 0 || c.Email.Port > 65535 {
+			problems = append(problems, "email.port 无效（email.enabled=true 时需为 1-65535）")
+		}
+		require(c.Email.Username, "email.username（email.enabled=true 时）")
+		require(c.Email.Password, "email.password（email.enabled=true 时）")
+	}
+
+	// 飞书
+	if c.Feishu.Enabled {
+		require(c.Feishu.AppID, "feishu.app_id（feishu.enabled=true 时）")
+		require(c.Feishu.AppSecret, "feishu.app_secret（feishu.enabled=true 时）")
+	}
+
+	// 币种：ISO 4217 三位字母代码
+	for _, code := range append([]string{c.Currency.Base}, c.Currency.Allowed...) {
+		if !isCurrencyCode(code) {
+			problems = append(problems, "currency 中的币种代码无效: "+code+"，应为 ISO 4217 三位字母代码（如 CNY）")
+		}
+	}
+
+	// AI 全局代理
+	if p := strings.TrimSpace(c.AI.ProxyURL); p != "" {
+		u, err := url.Parse(p)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") || u.Hostname() == "" || u.Port() == "" {
+			problems = append(problems, "ai.proxy_url 格式错误，应形如 http://127.0.0.1:7890（支持 http/https/socks5）")
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return errors.New("配置校验失败:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+// isCurrencyCode 是否为三位大写字母的币种代码
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,104 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func validConfig() *Config {
+	return &Config{
+		Server:   ServerConfig{Port: ":8811", Mode: "release", BaseURL: "http://localhost:8811"},
+		Database: DatabaseConfig{Host: "127.0.0.1", Port: "3306", Username: "root", DBName: "finance"},
+		JWT:      JWTConfig{Secret: "secret"},
+		Currency: CurrencyConfig{Base: "CNY", Allowed: []string{"CNY", "USD"}},
+	}
+}
+
+func TestValidate_OK(t *testing.T) {
+	assert.NoError(t, validConfig().Validate())
+
+	cfg := validConfig()
+	cfg.Email = EmailConfig{Enabled: true, Host: "smtp.qq.com", Port: 465, Username: "a@qq.com", Password: "p"}
+	cfg.Feishu = FeishuConfig{Enabled: true, AppID: "cli_x", AppSecret: "s"}
+	cfg.AI.ProxyURL = "socks5://127.0.0.1:1080"
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_Required(t *testing.T) {
+	cfg := validConfig()
+	cfg.JWT.Secret = " "
+	cfg.Database.Host = ""
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "jwt.secret 不能为空")
+	assert.Contains(t, err.Error(), "database.host 不能为空")
+}
+
+func TestValidate_DatabaseDriver(t *testing.T) {
+	// SQLite 只需要文件路径，不校验 MySQL 连接参数
+	cfg := validConfig()
+	cfg.Database = DatabaseConfig{Driver: DatabaseDriverSQLite, Path: "finance.db"}
+	assert.NoError(t, cfg.Validate())
+
+	cfg.Database.Path = ""
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.path")
+	assert.NotContains(t, err.Error(), "database.host")
+
+	cfg.Database = DatabaseConfig{Driver: "postgres"}
+	err = cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "database.driver 无效")
+}
+
+func TestValidate_EmailDependencies(t *testing.T) {
+	cfg := validConfig()
+	cfg.Email = EmailConfig{Enabled: true, Port: 0, Username: "a@qq.com"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "email.host")
+	assert.Contains(t, err.Error(), "email.port")
+	assert.Contains(t, err.Error(), "email.password")
+	assert.NotContains(t, err.Error(), "email.username")
+
+	// 未启用邮件时不校验 SMTP 配置
+	cfg.Email.Enabled = false
+	assert.NoError(t, cfg.Validate())
+}
+
+func TestValidate_FeishuDependencies(t *testing.T) {
+	cfg := validConfig()
+	cfg.Feishu = FeishuConfig{Enabled: true, AppID: "cli_x"}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "feishu.app_secret")
+	assert.NotContains(t, err.Error(), "feishu.app_id")
+}
+
+func TestValidate_Formats(t *testing.T) {
+	cfg := validConfig()
+	cfg.Server.Mode = "prod"
+	cfg.Server.BaseURL = "localhost:8811"
+	cfg.AI.ProxyURL = "ftp://127.0.0.1:21"
+	cfg.Currency = CurrencyConfig{Base: "CNY", Allowed: []string{"CNY", "US$"}}
+
+	err := cfg.Validate()
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "server.mode")
+	assert.Contains(t, err.Error(), "server.base_url")
+	assert.Contains(t, err.Error(), "ai.proxy_url")
+	assert.Contains(t, err.Error(), "US$")
+}
+
+func TestCurrencyConfig_Normalize(t *testing.T) {
+	c := CurrencyConfig{Allowed: []string{" usd", "CNY", "", "usd", "eur"}}
+	c.normalize()
+	assert.Equal(t, DefaultBaseCurrency, c.Base)
+	assert.Equal(t, []string{"CNY", "USD", "EUR"}, c.Allowed)
+}
@@ -8,6 +8,7 @@ import (
 	"finance/config"
 	"finance/models"
 
+	"github.com/glebarez/sqlite"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -23,20 +24,45 @@ func splitMethodPath(s string) (method, path string) {
 
 var DB *gorm.DB
 
+// openDialector 按 database.driver 选择数据库驱动：mysql 使用 TCP DSN，sqlite 使用文件路径
+func openDialector(cfg config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Driver {
+	case "", config.DatabaseDriverMySQL:
+		// 构建 MySQL DSN 连接字符串
+		dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
+			cfg.Username,
+			cfg.Password,
+			cfg.Host,
+			cfg.Port,
+			cfg.DBName,
+			cfg.Charset,
+		)
+		return mysql.Open(dsn), nil
+	case config.DatabaseDriverSQLite:
+		// 纯 Go 实现（无需 CGO），时间带时区偏移存储；并发写入时等待锁而不是立即报 database is locked
+		return sqlite.Open(sqliteDSN(cfg.Path)), nil
+	default:
+		return nil, fmt.Errorf("不支持的数据库驱动: %s", cfg.Driver)
+	}
+}
+
+// sqliteDSN SQLite 文件路径附加连接参数；path 本身带参数（如 file::memory:?cache=shared）时追加在其后
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return path + sep + "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite"
+}
+
 // Init 初始化数据库连接
 func Init(cfg *config.Config) error {
-	// 构建 MySQL DSN 连接字符串
-	dsn := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?charset=%s&parseTime=True&loc=Local",
-		cfg.Database.Username,
-		cfg.Database.Password,
-		cfg.Database.Host,
-		cfg.Database.Port,
-		cfg.Database.DBName,
-		cfg.Database.Charset,
-	)
+	dialector, err := openDialector(cfg.Database)
+	if err != nil {
+		return err
+	}
 
-	var err error
-	DB, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	DB, err = gorm.Open(dialector, &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Info),
 		DisableForeignKeyConstraintWhenMigrating: true, // 禁止迁移时创建外键
 	})
@@ -65,12 +91,32 @@ func Init(cfg *config.Config) error {
 		&models.EmailVerification{},
 		&models.AIModel{},
 		&models.AIChatMessage{},
+		&models.AIConversation{},
+		&models.PromptTemplate{},
 		&models.AIAnalysisHistory{},
 		&models.Role{},
 		&models.Menu{},
 		&models.APIPermission{},
 		&models.RoleMenu{},
 		&models.MenuAPI{},
+		&models.Budget{},
+		&models.Session{},
+		&models.GeoRule{},
+		&models.Merchant{},
+		&models.EmailLog{},
+		&models.BalanceSnapshot{},
+		&models.FeatureFlag{},
+		&models.Ledger{},
+		&models.LedgerMember{},
+		&models.AIQuota{},
+		&models.ExchangeRate{},
+		&models.RecurringExpense{},
+		&models.Receipt{},
+		&models.Tag{},
+		&models.ExpenseTag{},
+		&models.AuditLog{},
+		&models.Webhook{},
+		&models.IdempotencyKey{},
 	); err != nil {
 		return err
 	}
@@ -80,6 +126,12 @@ func Init(cfg *config.Config) error {
 		Where("status IS NULL OR status = ''").
 		Update("status", models.UserStatusActive).Error
 
+	// 兼容历史数据：老记录没有录入人，视为用户本人录入
+	_ = DB.Unscoped().Model(&models.Expense{}).Where("created_by = 0").
+		UpdateColumn("created_by", gorm.Expr("user_id")).Error
+	_ = DB.Unscoped().Model(&models.Income{}).Where("created_by = 0").
+		UpdateColumn("created_by", gorm.Expr("user_id")).Error
+
 	// 兼容历史数据：当所有 AIModel 的 sort_order 均为 0 且有多条时，按 id 赋 0,1,2,...
 	var total, zeroCnt int64
 	DB.Model(&models.AIModel{}).Count(&total)
@@ -115,6 +167,7 @@ func Init(cfg *config.Config) error {
 			if color == "" {
 				color = "#64748b" // 默认灰色
 			}
+			// 默认类别均不标记为内部转账类
 			cats = append(cats, models.ExpenseCategory{
 				Name:  name,
 				Sort:  (i + 1) * 10,
@@ -213,10 +266,22 @@ func initRoleMenuAPI() {
 		{Method: "GET", Path: "/admin/feishu/bind-token", Desc: "飞书绑定Token"},
 		{Method: "GET", Path: "/admin/expenses", Desc: "消费记录列表"},
 		{Method: "POST", Path: "/admin/expenses", Desc: "创建消费记录"},
+		{Method: "POST", Path: "/admin/expenses/import", Desc: "导入消费记录"},
 		{Method: "PUT", Path: "/admin/expenses/:id", Desc: "更新消费记录"},
 		{Method: "DELETE", Path: "/admin/expenses/:id", Desc: "删除消费记录"},
 		{Method: "GET", Path: "/admin/expenses/detailed-statistics", Desc: "消费详细统计"},
+		{Method: "GET", Path: "/admin/expenses/trash", Desc: "已删除消费记录"},
+		{Method: "GET", Path: "/admin/exchange-rates", Desc: "汇率列表"},
+		{Method: "PUT", Path: "/admin/exchange-rates/:currency", Desc: "设置汇率"},
+		{Method: "DELETE", Path: "/admin/exchange-rates/:currency", Desc: "删除汇率"},
+		{Method: "POST", Path: "/admin/expenses/:id/restore", Desc: "恢复消费记录"},
+		{Method: "DELETE", Path: "/admin/expenses/:id/purge", Desc: "彻底删除消费记录"},
+		{Method: "GET", Path: "/admin/recurring-expenses", Desc: "周期消费模板列表"},
+		{Method: "POST", Path: "/admin/recurring-expenses", Desc: "创建周期消费模板"},
+		{Method: "PUT", Path: "/admin/recurring-expenses/:id", Desc: "更新周期消费模板"},
+		{Method: "DELETE", Path: "/admin/recurring-expenses/:id", Desc: "删除周期消费模板"},
 		{Method: "GET", Path: "/admin/statistics/summary", Desc: "收支汇总"},
+		{Method: "GET", Path: "/admin/statistics/cashflow", Desc: "年度现金流"},
 		{Method: "GET", Path: "/admin/categories", Desc: "消费类别列表"},
 		{Method: "POST", Path: "/admin/categories", Desc: "创建消费类别"},
 		{Method: "PUT", Path: "/admin/categories/:id", Desc: "更新消费类别"},
@@ -226,6 +291,7 @@ func initRoleMenuAPI() {
 		{Method: "PUT", Path: "/admin/income-categories/:id", Desc: "更新收入类别"},
 		{Method: "DELETE", Path: "/admin/income-categories/:id", Desc: "删除收入类别"},
 		{Method: "GET", Path: "/admin/users", Desc: "用户列表"},
+		{Method: "GET", Path: "/admin/users/:id/profile", Desc: "用户画像"},
 		{Method: "POST", Path: "/admin/users/email/send-code", Desc: "发送绑定邮箱验证码"},
 		{Method: "PUT", Path: "/admin/users/:id/password", Desc: "更新用户密码"},
 		{Method: "PUT", Path: "/admin/users/:id/email", Desc: "更新用户邮箱"},
@@ -233,24 +299,46 @@ func initRoleMenuAPI() {
 		{Method: "PUT", Path: "/admin/users/:id/admin", Desc: "设置管理员"},
 		{Method: "PUT", Path: "/admin/users/:id/status", Desc: "更新用户状态"},
 		{Method: "PUT", Path: "/admin/users/:id/feishu", Desc: "更新飞书绑定"},
+		{Method: "GET", Path: "/admin/users/:id/ai-quota", Desc: "用户AI配额"},
+		{Method: "PUT", Path: "/admin/users/:id/ai-quota", Desc: "调整用户AI配额"},
 		{Method: "POST", Path: "/admin/users/impersonate", Desc: "模拟登录"},
 		{Method: "POST", Path: "/admin/users/exit-impersonation", Desc: "退出模拟"},
+		{Method: "POST", Path: "/admin/users/import", Desc: "批量导入用户"},
 		{Method: "GET", Path: "/admin/statistics", Desc: "统计数据"},
 		{Method: "GET", Path: "/admin/incomes", Desc: "收入列表"},
+		{Method: "GET", Path: "/admin/incomes/detailed-statistics", Desc: "收入详细统计"},
 		{Method: "POST", Path: "/admin/incomes", Desc: "创建收入"},
 		{Method: "PUT", Path: "/admin/incomes/:id", Desc: "更新收入"},
 		{Method: "DELETE", Path: "/admin/incomes/:id", Desc: "删除收入"},
 		{Method: "GET", Path: "/admin/export/excel", Desc: "导出Excel"},
+		{Method: "GET", Path: "/admin/export/pdf", Desc: "导出PDF报表"},
+		{Method: "GET", Path: "/admin/export/incomes/excel", Desc: "导出收入Excel"},
+		{Method: "GET", Path: "/admin/export/workbook", Desc: "导出收支工作簿"},
 		{Method: "POST", Path: "/admin/password/admin-reset", Desc: "管理员重置密码"},
 		{Method: "POST", Path: "/admin/password/send-reset-email", Desc: "发送重置邮件"},
 		{Method: "GET", Path: "/admin/email-config", Desc: "邮件配置"},
+		{Method: "GET", Path: "/admin/email-logs", Desc: "邮件发送日志"},
+		{Method: "GET", Path: "/admin/audit-logs", Desc: "操作审计日志"},
+		{Method: "GET", Path: "/admin/webhooks", Desc: "Webhook列表"},
+		{Method: "POST", Path: "/admin/webhooks", Desc: "创建Webhook"},
+		{Method: "PUT", Path: "/admin/webhooks/:id", Desc: "更新Webhook"},
+		{Method: "DELETE", Path: "/admin/webhooks/:id", Desc: "删除Webhook"},
+		{Method: "POST", Path: "/admin/webhooks/:id/test", Desc: "测试Webhook"},
+		{Method: "POST", Path: "/admin/balance-snapshots/rebuild", Desc: "补算结余快照"},
+		{Method: "GET", Path: "/admin/metrics/cache", Desc: "缓存命中率指标"},
+		{Method: "GET", Path: "/admin/feature-flags", Desc: "功能开关列表"},
+		{Method: "PUT", Path: "/admin/feature-flags", Desc: "修改功能开关"},
 		{Method: "GET", Path: "/admin/ai-models", Desc: "AI模型列表"},
 		{Method: "PUT", Path: "/admin/ai-models/reorder", Desc: "AI模型排序"},
 		{Method: "GET", Path: "/admin/ai-models/:id", Desc: "AI模型详情"},
+		{Method: "GET", Path: "/admin/ai-models/:id/history-count", Desc: "AI模型关联历史条数"},
 		{Method: "POST", Path: "/admin/ai-models", Desc: "创建AI模型"},
 		{Method: "POST", Path: "/admin/ai-models/:id/test", Desc: "测试AI模型"},
 		{Method: "PUT", Path: "/admin/ai-models/:id", Desc: "更新AI模型"},
 		{Method: "DELETE", Path: "/admin/ai-models/:id", Desc: "删除AI模型"},
+		{Method: "GET", Path: "/admin/prompt-templates", Desc: "提示词模板列表"},
+		{Method: "PUT", Path: "/admin/prompt-templates/:key", Desc: "保存提示词模板"},
+		{Method: "DELETE", Path: "/admin/prompt-templates/:key", Desc: "恢复默认提示词"},
 		{Method: "POST", Path: "/admin/ai-analysis", Desc: "AI分析"},
 		{Method: "GET", Path: "/admin/ai-analysis/history", Desc: "AI分析历史"},
 		{Method: "DELETE", Path: "/admin/ai-analysis/history/:id", Desc: "删除AI分析历史"},
@@ -282,14 +370,14 @@ func initRoleMenuAPI() {
 	// 菜单与接口绑定（按功能模块，通过 method+path 查询 api_id）
 	menuPathToPaths := map[string][]string{
 		"dashboard":  {"GET:/admin/current-user", "GET:/admin/statistics/summary", "GET:/admin/statistics"},
-		"expenses":   {"GET:/admin/expenses", "POST:/admin/expenses", "PUT:/admin/expenses/:id", "DELETE:/admin/expenses/:id", "GET:/admin/expenses/detailed-statistics"},
-		"statistics": {"GET:/admin/statistics/summary", "GET:/admin/statistics"},
-		"users":      {"GET:/admin/users", "POST:/admin/users/email/send-code", "PUT:/admin/users/:id/password", "PUT:/admin/users/:id/email", "DELETE:/admin/users/:id", "PUT:/admin/users/:id/admin", "PUT:/admin/users/:id/status", "PUT:/admin/users/:id/feishu", "POST:/admin/users/impersonate", "POST:/admin/users/exit-impersonation", "PUT:/admin/users/:id/role"},
+		"expenses":   {"GET:/admin/expenses", "POST:/admin/expenses", "POST:/admin/expenses/import", "PUT:/admin/expenses/:id", "DELETE:/admin/expenses/:id", "GET:/admin/expenses/detailed-statistics", "GET:/admin/expenses/trash", "POST:/admin/expenses/:id/restore", "DELETE:/admin/expenses/:id/purge", "GET:/admin/recurring-expenses", "POST:/admin/recurring-expenses", "PUT:/admin/recurring-expenses/:id", "DELETE:/admin/recurring-expenses/:id"},
+		"statistics": {"GET:/admin/statistics/summary", "GET:/admin/statistics/cashflow", "GET:/admin/statistics", "GET:/admin/exchange-rates", "PUT:/admin/exchange-rates/:currency", "DELETE:/admin/exchange-rates/:currency"},
+		"users":      {"GET:/admin/users", "GET:/admin/users/:id/profile", "POST:/admin/users/email/send-code", "PUT:/admin/users/:id/password", "PUT:/admin/users/:id/email", "DELETE:/admin/users/:id", "PUT:/admin/users/:id/admin", "PUT:/admin/users/:id/status", "PUT:/admin/users/:id/feishu", "GET:/admin/users/:id/ai-quota", "PUT:/admin/users/:id/ai-quota", "POST:/admin/users/impersonate", "POST:/admin/users/exit-impersonation", "PUT:/admin/users/:id/role", "POST:/admin/users/import"},
 		"categories": {"GET:/admin/categories", "POST:/admin/categories", "PUT:/admin/categories/:id", "DELETE:/admin/categories/:id"},
 		"income-categories": {"GET:/admin/income-categories", "POST:/admin/income-categories", "PUT:/admin/income-categories/:id", "DELETE:/admin/income-categories/:id"},
-		"export":    {"GET:/admin/export/excel"},
-		"incomes":   {"GET:/admin/incomes", "POST:/admin/incomes", "PUT:/admin/incomes/:id", "DELETE:/admin/incomes/:id"},
-		"ai-models": {"GET:/admin/ai-models", "PUT:/admin/ai-models/reorder", "GET:/admin/ai-models/:id", "POST:/admin/ai-models", "POST:/admin/ai-models/:id/test", "PUT:/admin/ai-models/:id", "DELETE:/admin/ai-models/:id"},
+		"export":    {"GET:/admin/export/excel", "GET:/admin/export/pdf", "GET:/admin/export/incomes/excel", "GET:/admin/export/workbook"},
+		"incomes":   {"GET:/admin/incomes", "GET:/admin/incomes/detailed-statistics", "POST:/admin/incomes", "PUT:/admin/incomes/:id", "DELETE:/admin/incomes/:id"},
+		"ai-models": {"GET:/admin/ai-models", "PUT:/admin/ai-models/reorder", "GET:/admin/ai-models/:id", "GET:/admin/ai-models/:id/history-count", "POST:/admin/ai-models", "POST:/admin/ai-models/:id/test", "PUT:/admin/ai-models/:id", "DELETE:/admin/ai-models/:id", "GET:/admin/prompt-templates", "PUT:/admin/prompt-templates/:key", "DELETE:/admin/prompt-templates/:key"},
 		"ai-analysis": {"POST:/admin/ai-analysis", "GET:/admin/ai-analysis/history", "DELETE:/admin/ai-analysis/history/:id"},
 		"ai-chat":    {"POST:/admin/ai-chat", "GET:/admin/ai-chat/history", "DELETE:/admin/ai-chat/history/:id"},
 		"roles":      {"GET:/admin/roles", "GET:/admin/roles/:id", "POST:/admin/roles", "PUT:/admin/roles/:id", "DELETE:/admin/roles/:id", "PUT:/admin/roles/:id/menus"},
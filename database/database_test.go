@@ -0,0 +1,49 @@
+package database
+
+import (
+	"testing"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSqliteDSN(t *testing.T) {
+	assert.Equal(t, "finance.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite", sqliteDSN("finance.db"))
+	assert.Equal(t, "file:x?mode=memory&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)&_time_format=sqlite", sqliteDSN("file:x?mode=memory"))
+}
+
+// TestInit_SQLite 在内存 SQLite 上跑完整的 Init：全部 AutoMigrate 与默认数据初始化都要成功
+func TestInit_SQLite(t *testing.T) {
+	oldDB := DB
+	defer func() { DB = oldDB }()
+
+	cfg := &config.Config{}
+	cfg.Database.Driver = config.DatabaseDriverSQLite
+	// 共享缓存让连接池中的多个连接看到同一个内存库
+	cfg.Database.Path = "file:init_test?mode=memory&cache=shared"
+	require.NoError(t, Init(cfg))
+	sqlDB, err := DB.DB()
+	require.NoError(t, err)
+	defer sqlDB.Close()
+
+	var count int64
+	require.NoError(t, DB.Model(&models.ExpenseCategory{}).Count(&count).Error)
+	assert.Equal(t, int64(len(models.GetCategories())), count)
+	require.NoError(t, DB.Model(&models.IncomeCategory{}).Count(&count).Error)
+	assert.Equal(t, int64(5), count)
+	require.NoError(t, DB.Model(&models.Role{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+	require.NoError(t, DB.Model(&models.MenuAPI{}).Count(&count).Error)
+	assert.Positive(t, count)
+
+	// 再次初始化（模拟重启）不重复写入默认数据
+	require.NoError(t, Init(cfg))
+	restarted, err := DB.DB()
+	require.NoError(t, err)
+	defer restarted.Close()
+	require.NoError(t, DB.Model(&models.Role{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+}
@@ -4,11 +4,13 @@ import (
 	"flag"
 	"log"
 	"strings"
+	"time"
 
 	"finance/config"
 	"finance/database"
 	"finance/middleware"
 	"finance/router"
+	"finance/service"
 )
 
 // @title 记账系统 API
@@ -59,6 +61,11 @@ func main() {
 		log.Printf("命令行指定端口: %s", port)
 	}
 
+	// 校验关键配置，缺项时直接退出，避免运行到具体请求才报错
+	if err := cfg.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
 	// 打印配置信息
 	config.PrintConfig()
 
@@ -66,10 +73,37 @@ func main() {
 	if err := database.Init(cfg); err != nil {
 		log.Fatalf("数据库初始化失败: %v", err)
 	}
+	if n, err := service.EncryptLegacyAIModelKeys(database.DB); err != nil {
+		log.Fatalf("加密 AI 模型 API 密钥失败: %v", err)
+	} else if n > 0 {
+		log.Printf("已加密 %d 个 AI 模型的明文 API 密钥", n)
+	}
+	if err := service.RegisterCacheInvalidation(database.DB); err != nil {
+		log.Fatalf("注册缓存失效回调失败: %v", err)
+	}
+	if err := service.LoadFeatureFlags(database.DB); err != nil {
+		log.Fatalf("加载功能开关失败: %v", err)
+	}
+	service.StartFeatureFlagSync(database.DB, 30*time.Second)
 
 	// 初始化 JWT
 	middleware.InitJWT(cfg)
 
+	// 月末结余快照定时任务
+	service.StartBalanceSnapshotScheduler()
+
+	// 软删除记录过期清理
+	service.StartSoftDeletePurgeScheduler(cfg.Retention.DeletedDays)
+
+	// 周期消费模板：每小时生成到期的消费记录
+	service.StartRecurringExpenseScheduler()
+
+	// 每日消费汇总邮件（邮件服务启用时）
+	service.StartDailyDigestScheduler(&cfg.Email)
+
+	// 创建接口的幂等键：超过 24 小时清理
+	service.StartIdempotencyKeyCleanup()
+
 	// 设置路由
 	r := router.SetupRouter(cfg)
 
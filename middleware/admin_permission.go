@@ -1,20 +1,26 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 	"strings"
 
 	"finance/adminauth"
 	"finance/database"
 	"finance/models"
+	"finance/service"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
 // noPermissionCheckPaths 无需权限校验的路径（登录后获取身份/配置等）
 var noPermissionCheckPaths = map[string]bool{
-	"/admin/current-user":     true,
+	"/admin/current-user":      true,
 	"/admin/feishu/bind-token": true,
+	"/admin/2fa/setup":         true,
+	"/admin/2fa/enable":        true,
+	"/admin/2fa/disable":       true,
 }
 
 // AdminPermissionMiddleware 后台管理接口权限校验中间件
@@ -47,14 +53,22 @@ func AdminPermissionMiddleware() gin.HandlerFunc {
 		}
 
 		// 获取用户可访问的接口集合
-		allowed := getUserAllowedAPIs(user.RoleID)
-		if allowed == nil {
-			allowed = getUserAllowedAPIs(getViewerRoleID())
+		allowed, err := roleAllowedAPIs(user.RoleID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"success": false, "message": "权限校验失败"})
+			c.Abort()
+			return
 		}
 
+		// 优先按 gin 路由模式匹配（如 /admin/users/:id），避免 /admin/expenses/trash 被 /admin/expenses/:id 误放行；
+		// 未匹配到路由时退回按实际路径匹配
 		method := c.Request.Method
-		path := c.Request.URL.Path
-		if matchAPIPermission(method, path, allowed) {
+		if route := c.FullPath(); route != "" {
+			if matchAPIRoute(method, route, allowed) {
+				c.Next()
+				return
+			}
+		} else if matchAPIPermission(method, c.Request.URL.Path, allowed) {
 			c.Next()
 			return
 		}
@@ -67,36 +81,87 @@ func AdminPermissionMiddleware() gin.HandlerFunc {
 	}
 }
 
+// roleAllowedAPIs 角色可访问的接口集合（带缓存）；角色未分配或未绑定任何接口时按只读角色（viewer）处理。
+// 查询出错时不写缓存
+func roleAllowedAPIs(roleID *uint) (map[string]bool, error) {
+	key := service.RolePermissionCacheKey(roleID)
+	if v, ok := service.RolePermissionCache.Get(key); ok {
+		return v.(map[string]bool), nil
+	}
+	allowed, err := getUserAllowedAPIs(roleID)
+	if err != nil {
+		return nil, err
+	}
+	if allowed == nil {
+		viewerID, err := getViewerRoleID()
+		if err != nil {
+			return nil, err
+		}
+		if allowed, err = getUserAllowedAPIs(viewerID); err != nil {
+			return nil, err
+		}
+	}
+	service.RolePermissionCache.Set(key, allowed)
+	return allowed, nil
+}
+
 // getUserAllowedAPIs 根据角色ID获取可访问的 (method, pathPattern) 集合
-func getUserAllowedAPIs(roleID *uint) map[string]bool {
+func getUserAllowedAPIs(roleID *uint) (map[string]bool, error) {
 	if roleID == nil {
-		return nil
+		return nil, nil
 	}
 	var menuIDs []uint
-	database.DB.Model(&models.RoleMenu{}).Where("role_id = ?", *roleID).Pluck("menu_id", &menuIDs)
+	if err := database.DB.Model(&models.RoleMenu{}).Where("role_id = ?", *roleID).Pluck("menu_id", &menuIDs).Error; err != nil {
+		return nil, err
+	}
 	if len(menuIDs) == 0 {
-		return nil
+		return nil, nil
 	}
 	var apiIDs []uint
-	database.DB.Model(&models.MenuAPI{}).Where("menu_id IN ?", menuIDs).Distinct("api_id").Pluck("api_id", &apiIDs)
+	if err := database.DB.Model(&models.MenuAPI{}).Where("menu_id IN ?", menuIDs).Distinct("api_id").Pluck("api_id", &apiIDs).Error; err != nil {
+		return nil, err
+	}
 	if len(apiIDs) == 0 {
-		return nil
+		return nil, nil
 	}
 	var apis []models.APIPermission
-	database.DB.Where("id IN ?", apiIDs).Find(&apis)
+	if err := database.DB.Where("id IN ?", apiIDs).Find(&apis).Error; err != nil {
+		return nil, err
+	}
 	allowed := make(map[string]bool)
 	for _, a := range apis {
 		allowed[a.Method+" "+a.Path] = true
 	}
-	return allowed
+	return allowed, nil
 }
 
-func getViewerRoleID() *uint {
+// getViewerRoleID 只读角色ID；角色不存在时返回 nil
+func getViewerRoleID() (*uint, error) {
 	var role models.Role
 	if err := database.DB.Where("code = ?", "viewer").First(&role).Error; err != nil {
-		return nil
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role.ID, nil
+}
+
+// matchAPIRoute 检查 method + gin 路由模式是否在允许集合中；占位符按位置匹配，不要求参数名一致
+func matchAPIRoute(method, route string, allowed map[string]bool) bool {
+	if allowed[method+" "+route] {
+		return true
+	}
+	for key := range allowed {
+		parts := strings.SplitN(key, " ", 2)
+		if len(parts) != 2 || parts[0] != method {
+			continue
+		}
+		if matchRoutePattern(route, parts[1]) {
+			return true
+		}
 	}
-	return &role.ID
+	return false
 }
 
 // matchAPIPermission 检查 method+path 是否匹配任一允许的 pattern
@@ -121,6 +186,24 @@ func matchAPIPermission(method, path string, allowed map[string]bool) bool {
 	return false
 }
 
+// matchRoutePattern 比较两个路由模式：静态段须相同，占位符只与占位符匹配
+// /admin/users/:id 匹配 /admin/users/:uid，/admin/expenses/trash 不匹配 /admin/expenses/:id
+func matchRoutePattern(route, pattern string) bool {
+	r := splitPath(normalizePath(route))
+	p := splitPath(normalizePath(pattern))
+	if len(r) != len(p) {
+		return false
+	}
+	for i := range r {
+		rParam := strings.HasPrefix(r[i], ":") || strings.HasPrefix(r[i], "*")
+		pParam := strings.HasPrefix(p[i], ":") || strings.HasPrefix(p[i], "*")
+		if rParam != pParam || (!rParam && r[i] != p[i]) {
+			return false
+		}
+	}
+	return true
+}
+
 func normalizePath(p string) string {
 	if p == "" {
 		return "/"
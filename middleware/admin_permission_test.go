@@ -3,7 +3,11 @@ package middleware
 import (
 	"testing"
 
+	"finance/service"
+
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestMatchPath(t *testing.T) {
@@ -30,3 +34,59 @@ func TestMatchPath(t *testing.T) {
 		assert.Equalf(t, tt.expected, got, "matchPath(%q, %q)", tt.actual, tt.pattern)
 	}
 }
+
+func TestMatchRoutePattern(t *testing.T) {
+	assert.True(t, matchRoutePattern("/admin/users/:id", "/admin/users/:id"))
+	assert.True(t, matchRoutePattern("/admin/users/:id", "/admin/users/:uid"))
+	assert.True(t, matchRoutePattern("/admin/roles/:id/menus", "/admin/roles/:id/menus"))
+	assert.False(t, matchRoutePattern("/admin/expenses/trash", "/admin/expenses/:id"))
+	assert.False(t, matchRoutePattern("/admin/expenses/:id", "/admin/expenses/trash"))
+	assert.False(t, matchRoutePattern("/admin/expenses/:id/restore", "/admin/expenses/:id"))
+}
+
+func TestMatchAPIRoute(t *testing.T) {
+	allowed := map[string]bool{"GET /admin/expenses/:id": true, "PUT /admin/users/:id/role": true}
+	assert.True(t, matchAPIRoute("GET", "/admin/expenses/:id", allowed))
+	assert.False(t, matchAPIRoute("DELETE", "/admin/expenses/:id", allowed))
+	assert.False(t, matchAPIRoute("GET", "/admin/expenses/detailed-statistics", allowed))
+	assert.True(t, matchAPIRoute("PUT", "/admin/users/:id/role", allowed))
+	assert.False(t, matchAPIRoute("GET", "/admin/users", nil))
+}
+
+func TestRoleAllowedAPIs_Cache(t *testing.T) {
+	mock, cleanup := setupUserStateDB(t)
+	defer cleanup()
+	service.InvalidateRolePermissions()
+	defer service.InvalidateRolePermissions()
+
+	roleID := uint(2)
+	expectRoleAPIs := func() {
+		mock.ExpectQuery("SELECT `menu_id` FROM `role_menus`").
+			WithArgs(roleID).
+			WillReturnRows(sqlmock.NewRows([]string{"menu_id"}).AddRow(5))
+		mock.ExpectQuery("SELECT DISTINCT `api_id` FROM `menu_apis`").
+			WithArgs(5).
+			WillReturnRows(sqlmock.NewRows([]string{"api_id"}).AddRow(7))
+		mock.ExpectQuery("SELECT \\* FROM `api_permissions`").
+			WithArgs(7).
+			WillReturnRows(sqlmock.NewRows([]string{"id", "method", "path"}).AddRow(7, "GET", "/admin/expenses"))
+	}
+
+	expectRoleAPIs()
+	allowed, err := roleAllowedAPIs(&roleID)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"GET /admin/expenses": true}, allowed)
+
+	// 命中缓存，不再查库
+	allowed, err = roleAllowedAPIs(&roleID)
+	require.NoError(t, err)
+	assert.True(t, allowed["GET /admin/expenses"])
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// 分配菜单/接口后清空缓存，重新查库
+	service.InvalidateRolePermissions()
+	expectRoleAPIs()
+	_, err = roleAllowedAPIs(&roleID)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Semaphore 计数信号量，TryAcquire 不阻塞
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore 创建容量为 n 的信号量（n < 1 时按 1 处理）
+func NewSemaphore(n int) *Semaphore {
+	if n < 1 {
+		n = 1
+	}
+	return &Semaphore{slots: make(chan struct{}, n)}
+}
+
+// TryAcquire 尝试占用一个名额，已满时立即返回 false
+func (s *Semaphore) TryAcquire() bool {
+	select {
+	case s.slots <- struct{}{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// Acquire 阻塞等待一个名额，ctx 取消或超时时放弃排队并返回 ctx.Err()
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release 释放一个名额
+func (s *Semaphore) Release() {
+	<-s.slots
+}
+
+// ConcurrencyLimit 并发上限中间件：多个路由共享同一个 sem 时合并计数，
+// 名额已满的请求直接返回 429，而不是排队等待占用资源
+func ConcurrencyLimit(sem *Semaphore, message string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !sem.TryAcquire() {
+			c.Header("Retry-After", "10")
+			// 同时用于 App（code）与后台（success）接口
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"code":    http.StatusTooManyRequests,
+				"success": false,
+				"message": message,
+			})
+			c.Abort()
+			return
+		}
+		defer sem.Release()
+		c.Next()
+	}
+}
@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSemaphore_TryAcquire(t *testing.T) {
+	sem := NewSemaphore(2)
+	assert.True(t, sem.TryAcquire())
+	assert.True(t, sem.TryAcquire())
+	assert.False(t, sem.TryAcquire())
+
+	sem.Release()
+	assert.True(t, sem.TryAcquire())
+}
+
+func TestSemaphore_Acquire(t *testing.T) {
+	sem := NewSemaphore(1)
+	assert.NoError(t, sem.Acquire(context.Background()))
+
+	// 名额已满：超时后放弃排队，不占用名额
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, sem.Acquire(ctx), context.DeadlineExceeded)
+
+	// 释放后排队者拿到名额
+	done := make(chan error, 1)
+	go func() { done <- sem.Acquire(context.Background()) }()
+	sem.Release()
+	assert.NoError(t, <-done)
+	assert.False(t, sem.TryAcquire())
+}
+
+func TestConcurrencyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	// 两个路由共享 1 个名额；处理中的请求阻塞在 release 上
+	sem := NewSemaphore(1)
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	router := gin.New()
+	limit := ConcurrencyLimit(sem, "导出任务较多，请稍后再试")
+	router.GET("/export/a", limit, func(c *gin.Context) {
+		close(entered)
+		<-release
+		c.String(200, "ok")
+	})
+	router.GET("/export/b", limit, func(c *gin.Context) {
+		c.String(200, "ok")
+	})
+
+	var wg sync.WaitGroup
+	first := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		router.ServeHTTP(first, httptest.NewRequest("GET", "/export/a", nil))
+	}()
+	<-entered
+
+	// 名额被占用，另一个导出立即返回 429
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export/b", nil))
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "10", w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "稍后再试")
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, 200, first.Code)
+
+	// 释放后可再次导出
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/export/b", nil))
+	assert.Equal(t, 200, w.Code)
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"net/http"
+
+	"finance/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequireFeature 功能开关中间件：开关关闭时直接返回 403“功能未开放”，不进入 handler
+func RequireFeature(key string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !service.FeatureEnabled(key) {
+			// 同时用于 App（code）与后台（success）接口
+			c.JSON(http.StatusForbidden, gin.H{
+				"code":    http.StatusForbidden,
+				"success": false,
+				"message": "功能未开放",
+			})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
@@ -14,8 +14,10 @@ import (
 
 // Claims JWT claims 结构
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Username string `json:"username"`
+	UserID       uint   `json:"user_id"`
+	Username     string `json:"username"`
+	SessionID    uint   `json:"sid,omitempty"` // 登录会话ID（App 端多会话管理）
+	TokenVersion int    `json:"tv"`            // 签发时用户的 token_version，与当前值不一致即失效
 	jwt.RegisteredClaims
 }
 
@@ -26,11 +28,18 @@ func InitJWT(cfg *config.Config) {
 	jwtSecret = []byte(cfg.JWT.Secret)
 }
 
-// GenerateToken 生成 JWT token
+// GenerateToken 生成 JWT token（不绑定会话，token_version 为 0）
 func GenerateToken(userID uint, username string, expireTime time.Duration) (string, error) {
+	return GenerateSessionToken(userID, username, 0, 0, expireTime)
+}
+
+// GenerateSessionToken 生成绑定登录会话的 JWT token；tokenVersion 为用户当前的 token_version
+func GenerateSessionToken(userID uint, username string, sessionID uint, tokenVersion int, expireTime time.Duration) (string, error) {
 	claims := Claims{
-		UserID:   userID,
-		Username: username,
+		UserID:       userID,
+		Username:     username,
+		SessionID:    sessionID,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expireTime)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -95,9 +104,46 @@ func JWTAuth() gin.HandlerFunc {
 			return
 		}
 
+		// 每次请求校验用户当前状态与 token 版本：锁定或版本变化后旧 token 立即失效
+		user, err := loadUserState(claims.UserID)
+		if err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, ErrUserLocked) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, gin.H{
+				"code":    status,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if user.TokenVersion != claims.TokenVersion {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"code":    401,
+				"message": ErrTokenRevoked.Error(),
+			})
+			c.Abort()
+			return
+		}
+		if claims.SessionID != 0 {
+			if err := checkSession(claims.SessionID, claims.UserID); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"code":    401,
+					"message": err.Error(),
+				})
+				c.Abort()
+				return
+			}
+		}
+
 		// 将用户信息存入上下文
 		c.Set("userID", claims.UserID)
 		c.Set("username", claims.Username)
+		c.Set("sessionID", claims.SessionID)
+		if user.CurrentLedgerID != nil {
+			c.Set("ledgerID", *user.CurrentLedgerID)
+		}
 		c.Next()
 	}
 }
@@ -111,3 +157,20 @@ func GetCurrentUserID(c *gin.Context) uint {
 	return userID.(uint)
 }
 
+// GetCurrentLedgerID 从上下文获取当前共享账本ID（个人账本时返回 0）
+func GetCurrentLedgerID(c *gin.Context) uint {
+	ledgerID, exists := c.Get("ledgerID")
+	if !exists {
+		return 0
+	}
+	return ledgerID.(uint)
+}
+
+// GetCurrentSessionID 从上下文获取当前登录会话ID（旧 token 无会话时返回 0）
+func GetCurrentSessionID(c *gin.Context) uint {
+	sessionID, exists := c.Get("sessionID")
+	if !exists {
+		return 0
+	}
+	return sessionID.(uint)
+}
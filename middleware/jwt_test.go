@@ -7,12 +7,39 @@ import (
 	"time"
 
 	"finance/config"
+	"finance/database"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
 )
 
+// setupUserStateDB 用 sqlmock 替换 database.DB，供 JWTAuth 校验用户状态
+func setupUserStateDB(t *testing.T) (sqlmock.Sqlmock, func()) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	oldDB := database.DB
+	database.DB = gormDB
+	return mock, func() {
+		database.DB = oldDB
+		sqlDB.Close()
+	}
+}
+
+func expectUserState(mock sqlmock.Sqlmock, userID uint, status string, tokenVersion int) {
+	mock.ExpectQuery("SELECT `id`,`status`,`token_version`,`current_ledger_id` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "token_version"}).AddRow(userID, status, tokenVersion))
+}
+
 func initJWTTestConfig() {
 	config.GlobalConfig = &config.Config{
 		Server: config.ServerConfig{Mode: "debug"},
@@ -98,6 +125,9 @@ func TestJWTAuth(t *testing.T) {
 	assert.Equal(t, http.StatusUnauthorized, w3.Code)
 
 	// 有效 token
+	mock, cleanup := setupUserStateDB(t)
+	defer cleanup()
+	expectUserState(mock, 42, "active", 0)
 	token, _ := GenerateToken(42, "user42", time.Hour)
 	req4 := httptest.NewRequest("GET", "/protected", nil)
 	req4.Header.Set("Authorization", "Bearer "+token)
@@ -105,6 +135,112 @@ func TestJWTAuth(t *testing.T) {
 	router.ServeHTTP(w4, req4)
 	assert.Equal(t, 200, w4.Code)
 	assert.Equal(t, "id:42", w4.Body.String())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJWTAuth_LockedUserRejected(t *testing.T) {
+	initJWTTestConfig()
+	defer func() { config.GlobalConfig = nil }()
+	InitJWT(config.GlobalConfig)
+	gin.SetMode(gin.TestMode)
+
+	mock, cleanup := setupUserStateDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(JWTAuth())
+	router.GET("/protected", func(c *gin.Context) { c.String(200, "ok") })
+
+	// 锁定前签发的 token（版本 0）
+	token, err := GenerateToken(7, "user7", time.Hour)
+	require.NoError(t, err)
+
+	// 锁定后：状态为 locked 且版本已自增，持旧 token 访问被拒
+	expectUserState(mock, 7, "locked", 1)
+	req := httptest.NewRequest("GET", "/protected", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), ErrUserLocked.Error())
+
+	// 解锁后：状态恢复 active，但旧 token 版本已过期，仍需重新登录
+	expectUserState(mock, 7, "active", 1)
+	req2 := httptest.NewRequest("GET", "/protected", nil)
+	req2.Header.Set("Authorization", "Bearer "+token)
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, req2)
+	assert.Equal(t, http.StatusUnauthorized, w2.Code)
+	assert.Contains(t, w2.Body.String(), ErrTokenRevoked.Error())
+
+	// 用户已被删除
+	mock.ExpectQuery("SELECT `id`,`status`,`token_version`,`current_ledger_id` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "status", "token_version"}))
+	req3 := httptest.NewRequest("GET", "/protected", nil)
+	req3.Header.Set("Authorization", "Bearer "+token)
+	w3 := httptest.NewRecorder()
+	router.ServeHTTP(w3, req3)
+	assert.Equal(t, http.StatusUnauthorized, w3.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJWTAuth_RevokedSessionRejected(t *testing.T) {
+	initJWTTestConfig()
+	defer func() { config.GlobalConfig = nil }()
+	InitJWT(config.GlobalConfig)
+	gin.SetMode(gin.TestMode)
+
+	mock, cleanup := setupUserStateDB(t)
+	defer cleanup()
+
+	router := gin.New()
+	router.Use(JWTAuth())
+	router.GET("/protected", func(c *gin.Context) { c.String(200, "sid:%d", GetCurrentSessionID(c)) })
+
+	token, err := GenerateSessionToken(9, "user9", 3, 0, time.Hour)
+	require.NoError(t, err)
+	sessionCols := []string{"id", "revoked_at", "expires_at"}
+	do := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/protected", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// 会话有效
+	expectUserState(mock, 9, "active", 0)
+	mock.ExpectQuery("SELECT `id`,`revoked_at`,`expires_at` FROM `sessions`").
+		WithArgs(3, 9).
+		WillReturnRows(sqlmock.NewRows(sessionCols).AddRow(3, nil, time.Now().Add(24*time.Hour)))
+	w := do()
+	assert.Equal(t, 200, w.Code)
+	assert.Equal(t, "sid:3", w.Body.String())
+
+	// 会话已下线/登出（或 refresh token 重复使用被自动吊销）
+	expectUserState(mock, 9, "active", 0)
+	mock.ExpectQuery("SELECT `id`,`revoked_at`,`expires_at` FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionCols).AddRow(3, time.Now(), time.Now().Add(24*time.Hour)))
+	w = do()
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Body.String(), ErrTokenRevoked.Error())
+
+	// 会话已过期
+	expectUserState(mock, 9, "active", 0)
+	mock.ExpectQuery("SELECT `id`,`revoked_at`,`expires_at` FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionCols).AddRow(3, nil, time.Now().Add(-time.Minute)))
+	w = do()
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	// 会话不存在
+	expectUserState(mock, 9, "active", 0)
+	mock.ExpectQuery("SELECT `id`,`revoked_at`,`expires_at` FROM `sessions`").
+		WillReturnRows(sqlmock.NewRows(sessionCols))
+	w = do()
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestGetCurrentUserID(t *testing.T) {
@@ -0,0 +1,98 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestIDHeader 请求 ID 的请求/响应头
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength 接受上游（如负载均衡）传入的请求 ID 的最大长度
+const maxRequestIDLength = 64
+
+// RequestLogger 请求日志中间件：为每个请求分配请求 ID（上游已带合法 X-Request-ID 时沿用），
+// 写入上下文与响应头，请求结束后以 JSON 输出方法、路径、状态码、耗时和当前用户 ID
+func RequestLogger(w io.Writer) gin.HandlerFunc {
+	logger := slog.New(slog.NewJSONHandler(w, nil))
+	return func(c *gin.Context) {
+		start := time.Now()
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		c.Set("requestID", requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			slog.String("request_id", requestID),
+			slog.String("method", c.Request.Method),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("client_ip", c.ClientIP()),
+		}
+		if userID := requestUserID(c); userID > 0 {
+			attrs = append(attrs, slog.Uint64("user_id", uint64(userID)))
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("error", c.Errors.String()))
+		}
+
+		level := slog.LevelInfo
+		if status >= 500 {
+			level = slog.LevelError
+		}
+		logger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// GetRequestID 从上下文获取当前请求 ID（未经过 RequestLogger 时返回空）
+func GetRequestID(c *gin.Context) string {
+	return c.GetString("requestID")
+}
+
+// requestUserID 当前请求的用户：App 接口取 JWT 中的用户，后台接口取 Cookie 中的用户
+func requestUserID(c *gin.Context) uint {
+	if id := GetCurrentUserID(c); id > 0 {
+		return id
+	}
+	if id, ok := c.Get("adminUserID"); ok {
+		if uid, ok := id.(uint); ok {
+			return uid
+		}
+	}
+	return 0
+}
+
+// newRequestID 生成 UUID v4
+func newRequestID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// validRequestID 上游传入的请求 ID 只接受字母、数字和 -_.，避免日志注入
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
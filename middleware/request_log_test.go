@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestRequestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	router := gin.New()
+	router.Use(RequestLogger(&buf))
+	router.GET("/items", func(c *gin.Context) {
+		c.Set("userID", uint(7))
+		c.JSON(404, gin.H{"request_id": GetRequestID(c)})
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/items?page=1", nil))
+
+	requestID := w.Header().Get(RequestIDHeader)
+	assert.Regexp(t, uuidPattern, requestID)
+	assert.Contains(t, w.Body.String(), requestID)
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, requestID, entry["request_id"])
+	assert.Equal(t, "GET", entry["method"])
+	assert.Equal(t, "/items", entry["path"])
+	assert.Equal(t, float64(404), entry["status"])
+	assert.Equal(t, float64(7), entry["user_id"])
+	assert.Contains(t, entry, "latency_ms")
+}
+
+func TestRequestLogger_AdminUserAndIncomingID(t *testing.T) {
+	var buf bytes.Buffer
+	router := gin.New()
+	router.Use(RequestLogger(&buf))
+	router.GET("/admin/x", func(c *gin.Context) {
+		c.Set("adminUserID", uint(3))
+		c.Status(200)
+	})
+
+	// 上游传入的合法请求 ID 沿用
+	req := httptest.NewRequest("GET", "/admin/x", nil)
+	req.Header.Set(RequestIDHeader, "lb-abc.123")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, "lb-abc.123", w.Header().Get(RequestIDHeader))
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, float64(3), entry["user_id"])
+
+	// 非法请求 ID 重新生成
+	req = httptest.NewRequest("GET", "/admin/x", nil)
+	req.Header.Set(RequestIDHeader, "bad id\n{}")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Regexp(t, uuidPattern, w.Header().Get(RequestIDHeader))
+}
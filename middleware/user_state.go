@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"finance/database"
+	"finance/models"
+)
+
+// 用户状态校验错误
+var (
+	ErrUserNotFound = errors.New("用户不存在")
+	ErrUserLocked   = errors.New("账号已锁定，请联系管理员")
+	ErrTokenRevoked = errors.New("登录已失效，请重新登录")
+)
+
+// CheckUserState 校验用户仍存在且状态为 active，返回其当前 token_version。
+// 后台 Cookie 认证在每次请求时调用，使锁定立即生效
+func CheckUserState(userID uint) (int, error) {
+	user, err := loadUserState(userID)
+	if err != nil {
+		return 0, err
+	}
+	return user.TokenVersion, nil
+}
+
+// loadUserState 读取并校验用户状态，同时带出当前账本，供 JWTAuth 每次请求使用而不必再查一次
+func loadUserState(userID uint) (models.User, error) {
+	var user models.User
+	if err := database.DB.Select("id", "status", "token_version", "current_ledger_id").First(&user, userID).Error; err != nil {
+		return user, ErrUserNotFound
+	}
+	if user.Status != models.UserStatusActive {
+		return user, ErrUserLocked
+	}
+	return user, nil
+}
+
+// checkSession 校验 token 绑定的登录会话未被下线/登出且未过期，
+// 使吊销会话（含 refresh token 被重复使用时的自动吊销）后其 access token 立即失效
+func checkSession(sessionID, userID uint) error {
+	var session models.Session
+	if err := database.DB.Select("id", "revoked_at", "expires_at").
+		Where("id = ? AND user_id = ?", sessionID, userID).First(&session).Error; err != nil {
+		return ErrTokenRevoked
+	}
+	if session.RevokedAt != nil || !session.ExpiresAt.After(time.Now()) {
+		return ErrTokenRevoked
+	}
+	return nil
+}
@@ -8,14 +8,15 @@ import (
 
 // AIAnalysisHistory AI分析历史记录（单次分析）
 type AIAnalysisHistory struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	AIModelID uint           `json:"ai_model_id" gorm:"index;not null"`
-	UserID    uint           `json:"user_id" gorm:"index;default:0"`     // 发起分析的用户ID（App端按用户隔离）
-	StartDate string         `json:"start_date" gorm:"size:10;not null"` // YYYY-MM-DD
-	EndDate   string         `json:"end_date" gorm:"size:10;not null"`   // YYYY-MM-DD
-	Result    string         `json:"result" gorm:"type:longtext;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	AIModelID   uint           `json:"ai_model_id" gorm:"index;not null"`
+	AIModelName string         `json:"ai_model_name" gorm:"size:100;default:''"` // 冗余保存模型名称，模型删除后历史仍可展示
+	UserID      uint           `json:"user_id" gorm:"index;default:0"`           // 发起分析的用户ID（App端按用户隔离）
+	StartDate   string         `json:"start_date" gorm:"size:10;not null"`       // YYYY-MM-DD
+	EndDate     string         `json:"end_date" gorm:"size:10;not null"`         // YYYY-MM-DD
+	Result      string         `json:"result" gorm:"type:longtext;not null"`
+	CreatedAt   time.Time      `json:"created_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
 
 	AIModel AIModel `json:"-" gorm:"foreignKey:AIModelID"`
 }
@@ -8,13 +8,15 @@ import (
 
 // AIChatMessage AI聊天记录（单轮：用户输入 + AI输出）
 type AIChatMessage struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	AIModelID uint           `json:"ai_model_id" gorm:"index;not null"`
-	UserID    uint           `json:"user_id" gorm:"index;default:0"` // 发起聊天的用户ID（App端按用户隔离）
-	UserText  string         `json:"user_text" gorm:"type:text;not null"`
-	AIText    string         `json:"ai_text" gorm:"type:longtext;not null"`
-	CreatedAt time.Time      `json:"created_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	ConversationID uint           `json:"conversation_id" gorm:"index;default:0"` // 所属会话，0 表示不属于任何会话（单轮聊天）
+	AIModelID      uint           `json:"ai_model_id" gorm:"index;not null"`
+	AIModelName    string         `json:"ai_model_name" gorm:"size:100;default:''"` // 冗余保存模型名称，模型删除后历史仍可展示
+	UserID         uint           `json:"user_id" gorm:"index;default:0"`           // 发起聊天的用户ID（App端按用户隔离）
+	UserText       string         `json:"user_text" gorm:"type:text;not null"`
+	AIText         string         `json:"ai_text" gorm:"type:longtext;not null"`
+	CreatedAt      time.Time      `json:"created_at"`
+	DeletedAt      gorm.DeletedAt `json:"-" gorm:"index"`
 
 	AIModel AIModel `json:"-" gorm:"foreignKey:AIModelID"`
 }
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// AIConversation AI多轮对话会话，聊天记录通过 ConversationID 归属到会话
+type AIConversation struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"index;not null"` // 会话所属用户
+	Title     string         `json:"title" gorm:"size:100"`         // 会话标题，未指定时取首条提问的前若干字
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"` // 最近一次对话时间，列表按此倒序
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (AIConversation) TableName() string {
+	return "ai_conversations"
+}
@@ -6,20 +6,54 @@ import (
 	"gorm.io/gorm"
 )
 
+// DefaultAITemperature 模型未配置温度时使用的采样温度
+const DefaultAITemperature = 0.3
+
 // AIModel AI模型配置
 type AIModel struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"size:100;not null;uniqueIndex"` // 模型名称
-	BaseURL   string         `json:"base_url" gorm:"size:255;not null"`         // 调用地址
-	APIKey    string         `json:"-" gorm:"size:255;not null"`                 // API密钥（不返回给前端）
-	SortOrder int            `json:"sort_order" gorm:"default:0;not null"`        // 排序序号，越小越靠前
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	Name            string         `json:"name" gorm:"size:100;not null;uniqueIndex"`   // 模型名称
+	BaseURL         string         `json:"base_url" gorm:"size:255;not null"`           // 调用地址
+	APIKey          string         `json:"-" gorm:"size:512;not null"`                  // API密钥（AES-GCM 加密存储，不返回给前端）
+	APIKeyHint      string         `json:"api_key,omitempty" gorm:"size:50;default:''"` // 脱敏后的API密钥（如 sk-****abcd），仅供后台展示
+	SortOrder       int            `json:"sort_order" gorm:"default:0;not null"`        // 排序序号，越小越靠前
+	FallbackModelID *uint          `json:"fallback_model_id" gorm:"index"`              // 备用模型ID，主模型失败时切换（仅一跳）
+	ProxyURL        string         `json:"proxy_url" gorm:"size:255"`                   // 代理地址（http/https/socks5），为空时使用全局 ai.proxy_url
+	MaxConcurrent   int            `json:"max_concurrent" gorm:"default:0;not null"`    // 并发上限，0 表示使用全局 ai.max_concurrent_per_model
+	ModelName       string         `json:"model_name" gorm:"size:100"`                  // 上游请求中的 model 参数，为空时使用 Name
+	Temperature     *float64       `json:"temperature"`                                 // 采样温度，为空时使用 DefaultAITemperature
+	MaxTokens       int            `json:"max_tokens" gorm:"default:0;not null"`        // 单次回复的最大 token 数，0 表示不限制（不传给上游）
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// RequestModelName 上游请求使用的模型标识：配置了 ModelName 时用它，否则用 Name
+func (m AIModel) RequestModelName() string {
+	if m.ModelName != "" {
+		return m.ModelName
+	}
+	return m.Name
+}
+
+// RequestTemperature 上游请求使用的采样温度，未配置时为 DefaultAITemperature
+func (m AIModel) RequestTemperature() float64 {
+	if m.Temperature != nil {
+		return *m.Temperature
+	}
+	return DefaultAITemperature
+}
+
+// MaskAPIKey 生成 API 密钥的脱敏展示：保留前 3 位与后 4 位，过短的密钥只显示 ****
+func MaskAPIKey(key string) string {
+	r := []rune(key)
+	if len(r) < 12 {
+		return "****"
+	}
+	return string(r[:3]) + "****" + string(r[len(r)-4:])
 }
 
 // TableName 设置表名
 func (AIModel) TableName() string {
 	return "ai_models"
 }
-
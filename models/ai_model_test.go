@@ -0,0 +1,13 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaskAPIKey(t *testing.T) {
+	assert.Equal(t, "sk-****cdef", MaskAPIKey("sk-test-0123456789abcdef"))
+	assert.Equal(t, "****", MaskAPIKey("sk-short"))
+	assert.Equal(t, "****", MaskAPIKey(""))
+}
@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// AIQuota 用户每日 AI 调用配额（聊天、分析合并计数，每次发起请求计 1 次）
+type AIQuota struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	UserID     uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	DailyLimit *int      `json:"daily_limit"` // 管理员为该用户单独设置的每日上限，NULL 表示跟随全局配置 ai.daily_quota，0 表示禁止使用
+	UsedToday  int       `json:"used_today" gorm:"not null;default:0"`
+	ResetDate  string    `json:"reset_date" gorm:"size:10"` // used_today 所属日期（YYYY-MM-DD），与今天不同时视为已重置
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (AIQuota) TableName() string {
+	return "ai_quotas"
+}
@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// 审计操作类型
+const (
+	AuditActionSetAdmin           = "user.set_admin"       // 设置/取消管理员
+	AuditActionDeleteUser         = "user.delete"          // 删除用户
+	AuditActionUpdateUserStatus   = "user.update_status"   // 锁定/解锁用户
+	AuditActionUpdateUserPassword = "user.update_password" // 管理员修改用户密码
+	AuditActionImpersonate        = "user.impersonate"     // 模拟登录
+	AuditActionEnable2FA          = "user.2fa_enable"      // 开启两步验证
+	AuditActionDisable2FA         = "user.2fa_disable"     // 关闭两步验证
+	AuditActionAIModelKeyChange   = "ai_model.key_change"  // 新建模型或修改 API Key
+	AuditActionAIModelDelete      = "ai_model.delete"      // 删除模型（连同其 API Key）
+)
+
+// 审计对象类型
+const (
+	AuditTargetUser    = "user"
+	AuditTargetAIModel = "ai_model"
+)
+
+// AuditLog 后台敏感操作审计日志（只追加，不修改不删除）
+type AuditLog struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ActorUserID uint      `json:"actor_user_id" gorm:"index;not null"`  // 操作的管理员
+	Action      string    `json:"action" gorm:"size:50;index;not null"` // 操作类型，见 AuditAction* 常量
+	TargetType  string    `json:"target_type" gorm:"size:30"`           // 操作对象类型，见 AuditTarget* 常量
+	TargetID    uint      `json:"target_id" gorm:"index"`               // 操作对象ID
+	Detail      JSONMap   `json:"detail,omitempty"`                     // 操作详情（变更前后的值等，不含密码、密钥明文）
+	IP          string    `json:"ip" gorm:"size:64"`                    // 操作来源 IP
+	CreatedAt   time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 设置表名
+func (AuditLog) TableName() string {
+	return "audit_logs"
+}
@@ -0,0 +1,20 @@
+package models
+
+import "time"
+
+// BalanceSnapshot 用户月末结余快照（每用户每月一条，重复生成时覆盖）
+type BalanceSnapshot struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	UserID       uint      `json:"user_id" gorm:"uniqueIndex:idx_balance_user_period;not null"`
+	Period       string    `json:"period" gorm:"size:7;uniqueIndex:idx_balance_user_period;not null"` // YYYY-MM
+	TotalIncome  float64   `json:"total_income" gorm:"type:decimal(14,2);not null;default:0"`         // 当月收入
+	TotalExpense float64   `json:"total_expense" gorm:"type:decimal(14,2);not null;default:0"`        // 当月支出（不含内部转账类别）
+	Balance      float64   `json:"balance" gorm:"type:decimal(14,2);not null;default:0"`              // 截至当月月末的累计结余
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (BalanceSnapshot) TableName() string {
+	return "balance_snapshots"
+}
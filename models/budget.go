@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultBudgetWarnPercent 默认提醒阈值（使用率百分比）
+const DefaultBudgetWarnPercent = 80
+
+// 预算使用等级
+const (
+	BudgetLevelNormal   = "normal"   // 未达到提醒阈值
+	BudgetLevelWarning  = "warning"  // 达到提醒阈值（软）
+	BudgetLevelExceeded = "exceeded" // 达到或超过预算（硬）
+)
+
+// Budget 类别月度预算
+type Budget struct {
+	ID          uint           `json:"id" gorm:"primaryKey"`
+	UserID      uint           `json:"user_id" gorm:"index:idx_budget_user_month;not null"`
+	Category    string         `json:"category" gorm:"size:50;not null"`
+	Month       string         `json:"month" gorm:"size:7;index:idx_budget_user_month;not null"` // YYYY-MM
+	LimitAmount float64        `json:"limit_amount" gorm:"type:decimal(10,2);not null"`
+	WarnPercent int            `json:"warn_percent" gorm:"not null;default:80"` // 使用率达到该百分比时提醒
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Budget) TableName() string {
+	return "budgets"
+}
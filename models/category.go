@@ -8,13 +8,15 @@ import (
 
 // ExpenseCategory 消费类别（后台维护）
 type ExpenseCategory struct {
-	ID        uint           `json:"id" gorm:"primaryKey"`
-	Name      string         `json:"name" gorm:"size:50;not null;uniqueIndex"`
-	Sort      int            `json:"sort" gorm:"default:0;index"`
-	Color     string         `json:"color" gorm:"size:20;default:#64748b"` // 颜色代码，如 #ef4444
-	CreatedAt time.Time      `json:"created_at"`
-	UpdatedAt time.Time      `json:"updated_at"`
-	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+	ID         uint           `json:"id" gorm:"primaryKey"`
+	Name       string         `json:"name" gorm:"size:50;not null;uniqueIndex"`
+	Sort       int            `json:"sort" gorm:"default:0;index"`
+	Color      string         `json:"color" gorm:"size:20;default:#64748b"`      // 颜色代码，如 #ef4444
+	IsTransfer bool           `json:"is_transfer" gorm:"default:false;not null"` // 内部转账类（如还信用卡），统计和导出默认排除
+	ParentID   *uint          `json:"parent_id" gorm:"index"`                    // 父类别，为空表示顶层类别
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
 }
 
 func (ExpenseCategory) TableName() string {
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+	"unicode/utf8"
+)
+
+// 邮件类型
+const (
+	EmailTypePasswordReset    = "password_reset"     // 后台密码重置链接
+	EmailTypeAppPasswordReset = "app_password_reset" // App 端密码重置验证码
+	EmailTypeVerification     = "verification"       // 邮箱验证码
+	EmailTypeInitialPassword  = "initial_password"   // 批量导入用户的初始密码
+	EmailTypeTest             = "test"               // 邮件配置测试
+	EmailTypeNotification     = "notification"       // 业务通知（如超支提醒），经 Notifier 发送
+	EmailTypeDailyDigest      = "daily_digest"       // 每日消费汇总
+)
+
+// 邮件发送状态
+const (
+	EmailStatusSent   = "sent"
+	EmailStatusFailed = "failed"
+)
+
+// maxEmailLogErrorLength 错误信息最大保存字符数
+const maxEmailLogErrorLength = 500
+
+// EmailLog 邮件发送日志（每次发送一条，记录成功或失败原因）
+type EmailLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	To        string    `json:"to" gorm:"column:to_email;size:100;not null;index"`
+	Subject   string    `json:"subject" gorm:"size:200"`
+	Type      string    `json:"type" gorm:"size:30;index"`   // 邮件类型，见 EmailType* 常量
+	Status    string    `json:"status" gorm:"size:20;index"` // sent/failed
+	Error     string    `json:"error" gorm:"size:500"`       // 失败原因，成功为空
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 设置表名
+func (EmailLog) TableName() string {
+	return "email_logs"
+}
+
+// NewEmailLog 根据发送结果构造日志，err 非空视为失败
+func NewEmailLog(to, subject, emailType string, err error) EmailLog {
+	l := EmailLog{To: to, Subject: subject, Type: emailType, Status: EmailStatusSent}
+	if err != nil {
+		l.Status = EmailStatusFailed
+		l.Error = truncateRunes(err.Error(), maxEmailLogErrorLength)
+	}
+	return l
+}
+
+// truncateRunes 按字符截断字符串
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewEmailLog(t *testing.T) {
+	ok := NewEmailLog("a@x.com", "主题", EmailTypeTest, nil)
+	assert.Equal(t, EmailStatusSent, ok.Status)
+	assert.Empty(t, ok.Error)
+
+	failed := NewEmailLog("a@x.com", "主题", EmailTypeTest, errors.New(strings.Repeat("错", 600)))
+	assert.Equal(t, EmailStatusFailed, failed.Status)
+	assert.Equal(t, maxEmailLogErrorLength, len([]rune(failed.Error)))
+}
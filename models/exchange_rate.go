@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// ExchangeRate 汇率：1 单位 Currency 折合多少本位币（currency.base），由管理员在后台维护
+type ExchangeRate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Currency  string    `json:"currency" gorm:"size:3;uniqueIndex;not null"`
+	Rate      float64   `json:"rate" gorm:"type:decimal(18,8);not null"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,16 +11,30 @@ import (
 
 // Expense 消费记录模型
 type Expense struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	UserID      uint           `json:"user_id" gorm:"index;not null"`
-	Amount      float64        `json:"amount" gorm:"type:decimal(10,2);not null"`
-	Category    string         `json:"category" gorm:"size:50;not null"`
-	Description string         `json:"description" gorm:"size:255"`
-	ExpenseTime time.Time      `json:"expense_time" gorm:"not null"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
-	User        User           `json:"-" gorm:"foreignKey:UserID"`
+	ID                 uint           `json:"id" gorm:"primaryKey"`
+	UserID             uint           `json:"user_id" gorm:"index;not null"`
+	Amount             float64        `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Currency           string         `json:"currency" gorm:"size:3;not null;default:CNY"` // 币种（ISO 4217），统计时按汇率折算为本位币
+	Category           string         `json:"category" gorm:"size:50;not null"`
+	Description        string         `json:"description" gorm:"size:255"`
+	ExpenseTime        time.Time      `json:"expense_time" gorm:"not null"`
+	InstallmentGroupID *string        `json:"installment_group_id,omitempty" gorm:"size:32;index"` // 分期组 ID，同一笔分期的各期共享
+	InstallmentIndex   int            `json:"installment_index,omitempty"`                         // 第几期（从 1 开始）
+	InstallmentTotal   int            `json:"installment_total,omitempty"`                         // 总期数
+	Extra              JSONMap        `json:"extra,omitempty"`                                     // 用户自定义扩展字段
+	Latitude           *float64       `json:"latitude,omitempty" gorm:"type:decimal(10,7)"`        // 消费地点纬度
+	Longitude          *float64       `json:"longitude,omitempty" gorm:"type:decimal(10,7)"`       // 消费地点经度
+	MerchantID         *uint          `json:"merchant_id,omitempty" gorm:"index"`                  // 关联商户（商户字典）
+	LedgerID           *uint          `json:"ledger_id,omitempty" gorm:"index"`                    // 所属共享账本，NULL 表示个人账本
+	CreatedBy          uint           `json:"created_by"`                                          // 录入人用户ID：本人录入为 user_id，管理员代录为管理员ID
+	CreatedAt          time.Time      `json:"created_at"`
+	UpdatedAt          time.Time      `json:"updated_at"`
+	DeletedAt          gorm.DeletedAt `json:"-" gorm:"index"`
+	DeletedBy          *uint          `json:"-"`                 // 执行软删除的用户ID，供管理员审计导出
+	AdminNote          string         `json:"-" gorm:"size:500"` // 管理员内部备注，仅后台管理员可读写，不返回给 App
+	RecordSource                      // 提交来源 IP 与 User-Agent，仅后台管理员可见
+	Tags               []string       `json:"tags,omitempty" gorm:"-"` // 标签名，存于 expense_tags 关联表，由接口按需带出
+	User               User           `json:"-" gorm:"foreignKey:UserID"`
 }
 
 // TableName 设置表名
@@ -25,6 +42,31 @@ func (Expense) TableName() string {
 	return "expenses"
 }
 
+// MarshalJSON 时间字段统一输出为 TimeLayout（带时区的 RFC3339）
+func (e Expense) MarshalJSON() ([]byte, error) {
+	type alias Expense
+	return json.Marshal(struct {
+		alias
+		ExpenseTime string `json:"expense_time"`
+		CreatedAt   string `json:"created_at"`
+		UpdatedAt   string `json:"updated_at"`
+	}{
+		alias:       alias(e),
+		ExpenseTime: FormatTime(e.ExpenseTime),
+		CreatedAt:   FormatTime(e.CreatedAt),
+		UpdatedAt:   FormatTime(e.UpdatedAt),
+	})
+}
+
+// SoftDeleteExpenses 软删除 query 匹配的消费记录并记录删除者；query 需已通过 Model 指定记录或条件。
+// 与 Delete 一样只作用于未删除的记录，且不改动 updated_at
+func SoftDeleteExpenses(query *gorm.DB, deletedBy uint) *gorm.DB {
+	return query.UpdateColumns(map[string]interface{}{
+		"deleted_at": time.Now(),
+		"deleted_by": deletedBy,
+	})
+}
+
 // Category 消费类别常量
 const (
 	CategoryFood          = "餐饮"
@@ -51,3 +93,11 @@ func GetCategories() []string {
 	}
 }
 
+// NewInstallmentGroupID 生成分期组 ID（32 位十六进制）
+func NewInstallmentGroupID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// FeatureFlag 功能开关；表中只保存管理员改动过的开关，未出现的开关取代码中的默认值
+type FeatureFlag struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"size:64;uniqueIndex;not null"`
+	Enabled   bool      `json:"enabled" gorm:"not null"`
+	UpdatedBy *uint     `json:"updated_by,omitempty"` // 最后修改的管理员
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (FeatureFlag) TableName() string {
+	return "feature_flags"
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 地理围栏半径范围（米）
+const (
+	MinGeoRuleRadius = 10
+	MaxGeoRuleRadius = 50000
+)
+
+// GeoRule 地理围栏自动归类规则：消费坐标落在圆形围栏内且未指定类别时，自动归到 Category
+type GeoRule struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	UserID    uint           `json:"user_id" gorm:"index;not null"`
+	Name      string         `json:"name" gorm:"size:50"`
+	CenterLat float64        `json:"center_lat" gorm:"type:decimal(10,7);not null"`
+	CenterLng float64        `json:"center_lng" gorm:"type:decimal(10,7);not null"`
+	Radius    float64        `json:"radius" gorm:"not null"` // 半径（米）
+	Category  string         `json:"category" gorm:"size:50;not null"`
+	Priority  int            `json:"priority" gorm:"not null;default:0"` // 多条命中时优先级高的生效
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (GeoRule) TableName() string {
+	return "geo_rules"
+}
@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// IdempotencyKeyTTL 幂等键有效期，过期后同一个键按新请求处理
+const IdempotencyKeyTTL = 24 * time.Hour
+
+// 幂等键作用范围：同一用户在不同接口上的相同键互不影响
+const (
+	IdempotencyScopeExpense = "expense" // 创建消费记录
+	IdempotencyScopeIncome  = "income"  // 创建收入
+)
+
+// IdempotencyKey 已处理的创建请求（客户端 Idempotency-Key 请求头），用于网络重试时返回原记录而不重复创建
+type IdempotencyKey struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_idempotency_user_scope_key"`
+	Scope     string    `json:"scope" gorm:"size:20;not null;uniqueIndex:idx_idempotency_user_scope_key"`                       // 见 IdempotencyScope* 常量
+	Key       string    `json:"key" gorm:"column:idempotency_key;size:100;not null;uniqueIndex:idx_idempotency_user_scope_key"` // 客户端生成的键
+	RecordID  uint      `json:"record_id" gorm:"not null"`                                                                      // 首次请求创建的记录ID（分期消费为第一期）
+	CreatedAt time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName 设置表名
+func (IdempotencyKey) TableName() string {
+	return "idempotency_keys"
+}
+
+// Expired 幂等键是否已超过有效期
+func (k *IdempotencyKey) Expired(now time.Time) bool {
+	return now.Sub(k.CreatedAt) >= IdempotencyKeyTTL
+}
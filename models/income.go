@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"gorm.io/gorm"
@@ -11,11 +12,16 @@ type Income struct {
 	ID         uint           `json:"id" gorm:"primaryKey"`
 	UserID     uint           `json:"user_id" gorm:"index;not null"`
 	Amount     float64        `json:"amount" gorm:"type:decimal(10,2);not null"`
-	Type       string         `json:"type" gorm:"size:50;not null"` // 收入类型
+	Currency   string         `json:"currency" gorm:"size:3;not null;default:CNY"` // 币种（ISO 4217），统计时按汇率折算为本位币
+	Type       string         `json:"type" gorm:"size:50;not null"`                // 收入类型
 	IncomeTime time.Time      `json:"income_time" gorm:"not null"`
+	LedgerID   *uint          `json:"ledger_id,omitempty" gorm:"index"` // 所属共享账本，NULL 表示个人账本
+	CreatedBy  uint           `json:"created_by"`                       // 录入人用户ID：本人录入为 user_id，管理员代录为管理员ID
 	CreatedAt  time.Time      `json:"created_at"`
 	UpdatedAt  time.Time      `json:"updated_at"`
 	DeletedAt  gorm.DeletedAt `json:"-" gorm:"index"`
+	AdminNote  string         `json:"-" gorm:"size:500"` // 管理员内部备注，仅后台管理员可读写，不返回给 App
+	RecordSource                // 提交来源 IP 与 User-Agent，仅后台管理员可见
 	User       User           `json:"-" gorm:"foreignKey:UserID"`
 }
 
@@ -23,4 +29,20 @@ func (Income) TableName() string {
 	return "incomes"
 }
 
+// MarshalJSON 时间字段统一输出为 TimeLayout（带时区的 RFC3339）
+func (i Income) MarshalJSON() ([]byte, error) {
+	type alias Income
+	return json.Marshal(struct {
+		alias
+		IncomeTime string `json:"income_time"`
+		CreatedAt  string `json:"created_at"`
+		UpdatedAt  string `json:"updated_at"`
+	}{
+		alias:      alias(i),
+		IncomeTime: FormatTime(i.IncomeTime),
+		CreatedAt:  FormatTime(i.CreatedAt),
+		UpdatedAt:  FormatTime(i.UpdatedAt),
+	})
+}
+
 
@@ -0,0 +1,131 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// 扩展字段限制
+const (
+	MaxExtraSize  = 2048 // 序列化后的最大字节数
+	MaxExtraDepth = 3    // 最大嵌套层数（顶层对象为第 1 层）
+	MaxExtraKeys  = 20   // 顶层最多键数
+)
+
+// extraKeyPattern 扩展字段键名：字母/数字/下划线/连字符，1-32 位。
+// 键名会拼入 JSON 路径（$."key" / ->>'key'），必须严格限制字符集，不能含引号
+var extraKeyPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,32}$`)
+
+// JSONMap 存储为数据库 JSON 列的键值对（MySQL JSON / PostgreSQL JSONB / SQLite TEXT）
+type JSONMap map[string]interface{}
+
+// Value 实现 driver.Valuer；空 map 存为 NULL
+func (m JSONMap) Value() (driver.Value, error) {
+	if len(m) == 0 {
+		return nil, nil
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan 实现 sql.Scanner
+func (m *JSONMap) Scan(value interface{}) error {
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("无法将 %T 转换为 JSONMap", value)
+	}
+	if len(b) == 0 {
+		*m = nil
+		return nil
+	}
+	var out map[string]interface{}
+	if err := json.Unmarshal(b, &out); err != nil {
+		return err
+	}
+	*m = out
+	return nil
+}
+
+// GormDataType 通用数据类型
+func (JSONMap) GormDataType() string {
+	return "json"
+}
+
+// GormDBDataType 按数据库方言返回列类型
+func (JSONMap) GormDBDataType(db *gorm.DB, field *schema.Field) string {
+	switch db.Dialector.Name() {
+	case "postgres":
+		return "JSONB"
+	case "sqlite":
+		return "TEXT"
+	default:
+		return "JSON"
+	}
+}
+
+// IsValidExtraKey 判断扩展字段键名是否合法
+func IsValidExtraKey(key string) bool {
+	return extraKeyPattern.MatchString(key)
+}
+
+// ValidateExtra 校验扩展字段的键名、键数、嵌套深度和序列化大小
+func ValidateExtra(m JSONMap) error {
+	if len(m) > MaxExtraKeys {
+		return fmt.Errorf("扩展字段最多 %d 个键", MaxExtraKeys)
+	}
+	for k := range m {
+		if !IsValidExtraKey(k) {
+			return fmt.Errorf("扩展字段键名 %q 不合法，只能包含字母、数字、下划线、连字符，长度 1-32", k)
+		}
+	}
+	if jsonDepth(map[string]interface{}(m)) > MaxExtraDepth {
+		return fmt.Errorf("扩展字段嵌套不能超过 %d 层", MaxExtraDepth)
+	}
+	b, err := json.Marshal(m)
+	if err != nil {
+		return errors.New("扩展字段不是合法的 JSON")
+	}
+	if len(b) > MaxExtraSize {
+		return fmt.Errorf("扩展字段不能超过 %d 字节", MaxExtraSize)
+	}
+	return nil
+}
+
+// jsonDepth 计算 JSON 值的嵌套层数，标量为 0
+func jsonDepth(v interface{}) int {
+	max := 0
+	switch val := v.(type) {
+	case map[string]interface{}:
+		for _, child := range val {
+			if d := jsonDepth(child); d > max {
+				max = d
+			}
+		}
+	case []interface{}:
+		for _, child := range val {
+			if d := jsonDepth(child); d > max {
+				max = d
+			}
+		}
+	default:
+		return 0
+	}
+	return max + 1
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONMap_ValueScan(t *testing.T) {
+	v, err := JSONMap{}.Value()
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	v, err = JSONMap{"project": "装修"}.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"project":"装修"}`, v)
+
+	var m JSONMap
+	require.NoError(t, m.Scan([]byte(`{"project":"装修","tags":["a"]}`)))
+	assert.Equal(t, "装修", m["project"])
+
+	require.NoError(t, m.Scan(nil))
+	assert.Nil(t, m)
+
+	assert.Error(t, m.Scan(123))
+}
+
+func TestValidateExtra(t *testing.T) {
+	assert.NoError(t, ValidateExtra(nil))
+	assert.NoError(t, ValidateExtra(JSONMap{"project": "装修", "meta": map[string]interface{}{"a": []interface{}{1}}}))
+
+	assert.Error(t, ValidateExtra(JSONMap{"bad key": 1}))
+	assert.Error(t, ValidateExtra(JSONMap{"a'b": 1}))
+	assert.Error(t, ValidateExtra(JSONMap{`a"b`: 1}))
+	assert.NoError(t, ValidateExtra(JSONMap{"123": 1, "order-no": "A-1"}))
+
+	// 顶层对象算第 1 层：恰好 MaxExtraDepth 层允许，再多一层拒绝
+	atLimit := JSONMap{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1}}}
+	assert.NoError(t, ValidateExtra(atLimit))
+	deep := JSONMap{"a": map[string]interface{}{"b": map[string]interface{}{"c": map[string]interface{}{"d": 1}}}}
+	assert.Error(t, ValidateExtra(deep))
+
+	assert.Error(t, ValidateExtra(JSONMap{"note": strings.Repeat("x", MaxExtraSize)}))
+
+	many := JSONMap{}
+	for i := 0; i <= MaxExtraKeys; i++ {
+		many[string(rune('a'+i))] = i
+	}
+	assert.Error(t, ValidateExtra(many))
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 账本成员角色
+const (
+	LedgerRoleOwner  = "owner"  // 创建者：可邀请、移除成员
+	LedgerRoleMember = "member" // 成员：可在账本中记账，只能修改自己记的账
+)
+
+// Ledger 共享账本（如家庭账本），成员各自记账、合并统计
+type Ledger struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	Name      string         `json:"name" gorm:"size:50;not null"`
+	OwnerID   uint           `json:"owner_id" gorm:"index;not null"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName 设置表名
+func (Ledger) TableName() string {
+	return "ledgers"
+}
+
+// LedgerMember 账本成员
+type LedgerMember struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	LedgerID  uint      `json:"ledger_id" gorm:"uniqueIndex:idx_ledger_member;not null"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_ledger_member;index;not null"`
+	Role      string    `json:"role" gorm:"size:10;not null;default:member"` // owner/member
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName 设置表名
+func (LedgerMember) TableName() string {
+	return "ledger_members"
+}
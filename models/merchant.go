@@ -0,0 +1,41 @@
+package models
+
+import (
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Merchant 用户维护的商户字典：创建消费时关联商户可自动带出默认类别
+type Merchant struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	UserID          uint           `json:"user_id" gorm:"index:idx_merchant_user_name;not null"`
+	Name            string         `json:"name" gorm:"size:50;not null"`
+	NormalizedName  string         `json:"-" gorm:"size:50;index:idx_merchant_user_name;not null"` // 去重用：去掉空白并转小写
+	DefaultCategory string         `json:"default_category" gorm:"size:50"`
+	UsageCount      int64          `json:"usage_count" gorm:"->;-:migration"` // 关联的消费笔数，仅列表查询时统计
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+func (Merchant) TableName() string {
+	return "merchants"
+}
+
+// CleanMerchantName 去掉首尾空白并将连续空白合并为一个空格
+func CleanMerchantName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizeMerchantName 商户名去重键："Star bucks" 与 "starbucks" 视为同一商户
+func NormalizeMerchantName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, name)
+}
@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerchantName(t *testing.T) {
+	assert.Equal(t, "Star bucks", CleanMerchantName("  Star \t bucks "))
+	assert.Equal(t, "starbucks", NormalizeMerchantName("Star bucks"))
+	assert.Equal(t, NormalizeMerchantName("星巴克 "), NormalizeMerchantName(" 星 巴克"))
+	assert.NotEqual(t, NormalizeMerchantName("星巴克"), NormalizeMerchantName("星巴克咖啡"))
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// PromptTemplate AI提示词模板，按 Key 覆盖内置默认提示词；删除记录即恢复默认
+type PromptTemplate struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Key       string    `json:"key" gorm:"size:50;not null;uniqueIndex"` // 模板标识，如 ai_system、ai_analysis
+	Content   string    `json:"content" gorm:"type:text;not null"`       // 模板内容，支持 {{占位符}}
+	UpdatedBy uint      `json:"updated_by" gorm:"default:0"`             // 最后修改的管理员ID
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (PromptTemplate) TableName() string {
+	return "prompt_templates"
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// 附件分类，便于前端分别展示
+const (
+	ReceiptKindImage = "image" // 小票图片：JPEG/PNG
+	ReceiptKindAudio = "audio" // 语音备忘：MP3/M4A
+	ReceiptKindText  = "text"  // 文本说明：UTF-8 纯文本
+)
+
+// Receipt 消费记录的附件（小票图片、语音备忘或文本说明），文件保存在 storage.receipt_dir 下
+type Receipt struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	ExpenseID   uint      `json:"expense_id" gorm:"index;not null"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`                // 上传人，与所属消费记录的用户一致
+	Path        string    `json:"-" gorm:"size:255;not null"`                   // 相对 storage.receipt_dir 的文件路径，不返回给客户端
+	Kind        string    `json:"kind" gorm:"size:10;not null;default:'image'"` // 附件分类：image/audio/text
+	ContentType string    `json:"content_type" gorm:"size:50;not null"`         // 按文件内容识别：image/jpeg、image/png、audio/mpeg、audio/mp4、text/plain; charset=utf-8
+	Size        int64     `json:"size"`                                         // 字节数
+	Duration    int       `json:"duration,omitempty"`                           // 音频时长（秒），非音频或无法解析时为 0
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+func (Receipt) TableName() string {
+	return "receipts"
+}
+
+// MarshalJSON 时间字段统一输出为 TimeLayout（带时区的 RFC3339）
+func (r Receipt) MarshalJSON() ([]byte, error) {
+	type alias Receipt
+	return json.Marshal(struct {
+		alias
+		CreatedAt string `json:"created_at"`
+	}{
+		alias:     alias(r),
+		CreatedAt: FormatTime(r.CreatedAt),
+	})
+}
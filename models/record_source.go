@@ -0,0 +1,8 @@
+package models
+
+// RecordSource 记录的提交来源，由服务端在创建时采集（audit.record_source 关闭时为空）。
+// 不参与模型序列化，只在后台管理员请求的响应中返回
+type RecordSource struct {
+	SourceIP  string `json:"-" gorm:"size:45"`  // 提交请求的客户端 IP（IPv6 最长 45 字符）
+	UserAgent string `json:"-" gorm:"size:255"` // 提交请求的 User-Agent，超长时截断
+}
@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// 周期消费的重复频率
+const (
+	RecurringDaily   = "daily"
+	RecurringWeekly  = "weekly"
+	RecurringMonthly = "monthly"
+)
+
+// RecurringExpense 周期消费模板：到达 NextRun 当天由定时任务生成一条消费记录并推进 NextRun
+type RecurringExpense struct {
+	ID              uint           `json:"id" gorm:"primaryKey"`
+	UserID          uint           `json:"user_id" gorm:"index;not null"`
+	Amount          float64        `json:"amount" gorm:"type:decimal(10,2);not null"`
+	Currency        string         `json:"currency" gorm:"size:3;not null;default:CNY"`
+	Category        string         `json:"category" gorm:"size:50;not null"`
+	Description     string         `json:"description" gorm:"size:255"`
+	Frequency       string         `json:"frequency" gorm:"size:10;not null"`      // daily/weekly/monthly
+	StartDate       string         `json:"start_date" gorm:"size:10;not null"`     // 首次执行日期（YYYY-MM-DD），按月重复时以其日为准
+	NextRun         string         `json:"next_run" gorm:"size:10;index;not null"` // 下次生成日期（YYYY-MM-DD）
+	LastCreatedDate *string        `json:"last_created_date" gorm:"size:10"`       // 最近一次已生成的日期，防止重启或多实例重复生成
+	Active          bool           `json:"active" gorm:"not null"`                 // 停用后不再生成
+	CreatedBy       uint           `json:"created_by"`                             // 创建人用户ID，也作为生成记录的录入人
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+	DeletedAt       gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// TableName 设置表名
+func (RecurringExpense) TableName() string {
+	return "recurring_expenses"
+}
+
+// ValidRecurringFrequency 是否为支持的重复频率
+func ValidRecurringFrequency(f string) bool {
+	return f == RecurringDaily || f == RecurringWeekly || f == RecurringMonthly
+}
+
+// RunAfter 返回 date（YYYY-MM-DD）之后的下一个执行日期。
+// 按月重复时取下个月中与 StartDate 同日的日期，该月没有这一天时取月末（1-31 之后依次为 2-29、3-31）
+func (r RecurringExpense) RunAfter(date string) (string, error) {
+	d, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return "", err
+	}
+	switch r.Frequency {
+	case RecurringDaily:
+		d = d.AddDate(0, 0, 1)
+	case RecurringWeekly:
+		d = d.AddDate(0, 0, 7)
+	default:
+		day := d.Day()
+		if start, err := time.ParseInLocation("2006-01-02", r.StartDate, time.Local); err == nil {
+			day = start.Day()
+		}
+		first := time.Date(d.Year(), d.Month()+1, 1, 0, 0, 0, 0, time.Local)
+		if last := first.AddDate(0, 1, -1).Day(); day > last {
+			day = last
+		}
+		d = first.AddDate(0, 0, day-1)
+	}
+	return d.Format("2006-01-02"), nil
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecurringExpense_RunAfter(t *testing.T) {
+	cases := []struct {
+		freq, start, date, want string
+	}{
+		{RecurringDaily, "2024-02-28", "2024-02-28", "2024-02-29"},
+		{RecurringWeekly, "2024-12-28", "2024-12-28", "2025-01-04"},
+		{RecurringMonthly, "2024-01-15", "2024-12-15", "2025-01-15"},
+		// 月末按 StartDate 的日取当月最后一天，不会逐月漂移
+		{RecurringMonthly, "2024-01-31", "2024-01-31", "2024-02-29"},
+		{RecurringMonthly, "2024-01-31", "2024-02-29", "2024-03-31"},
+		{RecurringMonthly, "2024-01-30", "2024-03-30", "2024-04-30"},
+	}
+	for _, tc := range cases {
+		r := RecurringExpense{Frequency: tc.freq, StartDate: tc.start}
+		got, err := r.RunAfter(tc.date)
+		require.NoError(t, err)
+		assert.Equalf(t, tc.want, got, "%s %s from %s", tc.freq, tc.date, tc.start)
+	}
+
+	_, err := RecurringExpense{Frequency: RecurringDaily}.RunAfter("2024/01/01")
+	assert.Error(t, err)
+}
+
+func TestValidRecurringFrequency(t *testing.T) {
+	assert.True(t, ValidRecurringFrequency(RecurringMonthly))
+	assert.False(t, ValidRecurringFrequency("yearly"))
+}
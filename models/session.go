@@ -0,0 +1,41 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// SessionRefreshTTL refresh token 默认有效期（未配置 jwt.refresh_expire_days 时使用）
+const SessionRefreshTTL = 30 * 24 * time.Hour
+
+// Session App 端登录会话（每次登录一条，持有一个 refresh token）
+type Session struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	UserID           uint       `json:"user_id" gorm:"index;not null"`
+	Device           string     `json:"device" gorm:"size:255"`                // 设备描述（客户端上报或 User-Agent）
+	IP               string     `json:"ip" gorm:"size:64"`                     // 最近一次登录/刷新时的 IP
+	LastActive       time.Time  `json:"last_active"`                           // 最近一次登录/刷新时间
+	RefreshTokenHash string     `json:"-" gorm:"size:64;uniqueIndex;not null"` // refresh token 的 SHA-256，不存明文
+	PreviousHash     string     `json:"-" gorm:"size:64;index"`                // 上一次轮换前的 refresh token 哈希，再次出现即视为被盗用
+	ExpiresAt        time.Time  `json:"expires_at" gorm:"not null"`            // refresh token 过期时间
+	RevokedAt        *time.Time `json:"revoked_at,omitempty"`                  // 下线/登出时间，非空表示已吊销
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// HashRefreshToken 计算 refresh token 的哈希（用于存储与查找）
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsActive 会话是否仍有效（未吊销且未过期）
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
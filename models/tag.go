@@ -0,0 +1,47 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// 标签限制
+const (
+	MaxTagNameLength  = 20 // 标签名最大字符数
+	MaxTagsPerExpense = 10 // 单条消费记录最多的标签数
+)
+
+// Tag 用户自己的消费标签：一条消费记录只有一个类别，但可以打多个标签（如"出差""报销"）
+type Tag struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"uniqueIndex:idx_tag_user_name;not null"`
+	Name           string    `json:"name" gorm:"size:20;not null"`
+	NormalizedName string    `json:"-" gorm:"size:20;uniqueIndex:idx_tag_user_name;not null"` // 去重用：合并空白并转小写
+	UsageCount     int64     `json:"usage_count" gorm:"->;-:migration"`                       // 打了该标签的消费笔数，仅列表查询时统计
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// ExpenseTag 消费记录与标签的多对多关联
+type ExpenseTag struct {
+	ExpenseID uint `gorm:"primaryKey;autoIncrement:false"`
+	TagID     uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
+
+func (ExpenseTag) TableName() string {
+	return "expense_tags"
+}
+
+// CleanTagName 去掉首尾空白并将连续空白合并为一个空格
+func CleanTagName(name string) string {
+	return strings.Join(strings.Fields(name), " ")
+}
+
+// NormalizeTagName 标签去重键："Travel" 与 "travel" 视为同一标签
+func NormalizeTagName(name string) string {
+	return strings.ToLower(CleanTagName(name))
+}
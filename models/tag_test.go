@@ -0,0 +1,14 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagName(t *testing.T) {
+	assert.Equal(t, "出差 上海", CleanTagName("  出差 \t 上海 "))
+	assert.Equal(t, "travel", NormalizeTagName(" Travel "))
+	assert.Equal(t, NormalizeTagName("Road  Trip"), NormalizeTagName("road trip"))
+	assert.NotEqual(t, NormalizeTagName("roadtrip"), NormalizeTagName("road trip"))
+}
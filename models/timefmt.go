@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+// TimeLayout 接口返回时间的统一格式：带时区的 RFC3339（精确到秒），如 2024-01-15T12:30:00+08:00
+const TimeLayout = time.RFC3339
+
+// FormatTime 按统一格式输出时间（转换到服务器本地时区）
+func FormatTime(t time.Time) string {
+	return t.In(time.Local).Format(TimeLayout)
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func withLocal(t *testing.T, loc *time.Location) {
+	old := time.Local
+	time.Local = loc
+	t.Cleanup(func() { time.Local = old })
+}
+
+func TestFormatTime(t *testing.T) {
+	withLocal(t, time.FixedZone("CST", 8*3600))
+
+	ts := time.Date(2024, 1, 15, 4, 30, 0, 123456789, time.UTC)
+	assert.Equal(t, "2024-01-15T12:30:00+08:00", FormatTime(ts))
+}
+
+func TestExpense_MarshalJSON(t *testing.T) {
+	withLocal(t, time.FixedZone("CST", 8*3600))
+
+	ts := time.Date(2024, 1, 15, 12, 30, 0, 500000000, time.Local)
+	data, err := json.Marshal(Expense{ID: 1, UserID: 2, Amount: 9.9, Category: "餐饮", ExpenseTime: ts, CreatedAt: ts, UpdatedAt: ts})
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "2024-01-15T12:30:00+08:00", m["expense_time"])
+	assert.Equal(t, "2024-01-15T12:30:00+08:00", m["created_at"])
+	assert.Equal(t, "2024-01-15T12:30:00+08:00", m["updated_at"])
+	assert.Equal(t, "餐饮", m["category"])
+	assert.NotContains(t, m, "deleted_at")
+}
+
+func TestIncome_MarshalJSON(t *testing.T) {
+	withLocal(t, time.FixedZone("CST", 8*3600))
+
+	ts := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	data, err := json.Marshal(Income{ID: 1, Amount: 100, Type: "工资", IncomeTime: ts})
+	require.NoError(t, err)
+
+	var m map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &m))
+	assert.Equal(t, "2024-03-01T08:00:00+08:00", m["income_time"])
+	assert.Equal(t, "工资", m["type"])
+}
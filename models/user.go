@@ -1,6 +1,9 @@
 package models
 
 import (
+	"errors"
+	"regexp"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,17 +16,71 @@ const (
 	UserStatusActive = "active"
 )
 
+// 通知渠道偏好
+const (
+	NotifyChannelAuto   = ""       // 自动：已绑定飞书时发飞书，否则发邮件
+	NotifyChannelEmail  = "email"  // 只发邮件
+	NotifyChannelFeishu = "feishu" // 只发飞书
+	NotifyChannelNone   = "none"   // 不接收通知
+)
+
+// ValidNotifyChannel 是否为合法的通知渠道偏好
+func ValidNotifyChannel(ch string) bool {
+	switch ch {
+	case NotifyChannelAuto, NotifyChannelEmail, NotifyChannelFeishu, NotifyChannelNone:
+		return true
+	}
+	return false
+}
+
+// 周起始日偏好
+const (
+	WeekStartDefault = ""    // 跟随系统配置 stats.week_start
+	WeekStartMonday  = "mon" // 周一
+	WeekStartSunday  = "sun" // 周日
+)
+
+// ValidWeekStart 是否为合法的周起始日偏好
+func ValidWeekStart(ws string) bool {
+	switch ws {
+	case WeekStartDefault, WeekStartMonday, WeekStartSunday:
+		return true
+	}
+	return false
+}
+
+// 每日消费汇总邮件的发送时刻（服务器时区整点），汇总当天 0 点到发送时的消费
+const (
+	DigestHourMin     = 12
+	DigestHourMax     = 23
+	DigestHourDefault = 21
+)
+
 // User 用户模型
 type User struct {
 	ID           uint           `json:"id" gorm:"primaryKey"`
 	Username     string         `json:"username" gorm:"uniqueIndex;size:50;not null"`
 	Password     string         `json:"-" gorm:"size:255;not null"`
 	Email        string         `json:"email" gorm:"size:100"`
+	Phone        *string        `json:"phone,omitempty" gorm:"size:20;uniqueIndex"` // 手机号（规范化后存储），NULL 表示未填写
 	IsAdmin      bool           `json:"is_admin" gorm:"default:false;index"`        // 超级管理员，bypass 角色权限校验
 	RoleID       *uint          `json:"role_id" gorm:"index"`                      // 角色ID，空则沿用 is_admin 逻辑
 	Status       string         `json:"status" gorm:"size:20;default:locked;index"` // 用户状态：locked/active
 	FeishuOpenID  *string `json:"feishu_open_id,omitempty" gorm:"size:64;uniqueIndex"` // 飞书 open_id，NULL 表示未绑定
 	FeishuUnionID string  `json:"-" gorm:"size:64;index;default:''"`                   // 飞书 union_id
+	TokenVersion int            `json:"-" gorm:"not null;default:0"`                // 自增后该用户已签发的 JWT 全部失效
+	NotifyChannel string        `json:"notify_channel" gorm:"size:20;default:''"`    // 通知渠道偏好，见 NotifyChannel* 常量
+	WeekStart     string        `json:"week_start" gorm:"size:3;default:''"`         // 周起始日偏好，见 WeekStart* 常量
+	CurrentLedgerID *uint       `json:"current_ledger_id"`                          // 当前使用的共享账本，NULL 表示个人账本
+	TOTPSecret    string        `json:"-" gorm:"column:totp_secret;size:255;default:''"` // 两步验证密钥（加密存储），设置后未启用前也可能有值
+	TOTPEnabled   bool          `json:"totp_enabled" gorm:"column:totp_enabled;default:false"` // 是否已启用两步验证
+	TOTPLastStep  int64         `json:"-" gorm:"column:totp_last_step;not null;default:0"`  // 上次成功使用的验证码周期，防止同一验证码重放
+	LargeExpenseThreshold float64 `json:"large_expense_threshold" gorm:"type:decimal(10,2);not null;default:0"` // 大额消费阈值（本位币），单笔超过时推送 Webhook，0 表示不推送
+	EmailVerified  bool         `json:"email_verified" gorm:"default:false"`         // 邮箱是否经验证码确认，每日汇总邮件只发给已验证的邮箱
+	DigestEnabled  bool         `json:"digest_enabled" gorm:"default:false"`         // 是否订阅每日消费汇总邮件
+	DigestHour     int          `json:"digest_hour" gorm:"not null;default:21"`      // 每日汇总邮件发送时刻（DigestTimezone 时区的整点，12–23）
+	DigestTimezone string       `json:"digest_timezone" gorm:"size:64;default:''"`   // 每日汇总所用时区（IANA，如 Asia/Shanghai），空为服务器时区
+	DigestLastDate string       `json:"-" gorm:"size:10;default:''"`                 // 上次发送汇总的日期（2006-01-02），同一天不重复发送
 	CreatedAt    time.Time      `json:"created_at"`
 	UpdatedAt    time.Time      `json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
@@ -33,3 +90,31 @@ type User struct {
 func (User) TableName() string {
 	return "users"
 }
+
+var (
+	mainlandPhonePattern      = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	internationalPhonePattern = regexp.MustCompile(`^\+[1-9]\d{6,14}$`)
+)
+
+// ErrInvalidPhone 手机号格式错误
+var ErrInvalidPhone = errors.New("手机号格式错误")
+
+// NormalizePhone 规范化手机号，保证同一号码的不同写法得到相同的唯一键：
+// 去掉空格、短横线和括号，00 前缀视为 +；中国大陆号码（可带 +86）存为 11 位数字，其他地区存为 E.164（+国家码+号码）
+func NormalizePhone(phone string) (string, error) {
+	p := strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '-', '(', ')':
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(phone))
+	if strings.HasPrefix(p, "00") {
+		p = "+" + p[2:]
+	}
+	p = strings.TrimPrefix(p, "+86")
+	if mainlandPhonePattern.MatchString(p) || internationalPhonePattern.MatchString(p) {
+		return p, nil
+	}
+	return "", ErrInvalidPhone
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	for _, in := range []string{"13800138000", " 138-0013-8000 ", "+8613800138000", "+86 138 0013 8000", "0086 13800138000"} {
+		p, err := NormalizePhone(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, "13800138000", p, in)
+	}
+
+	p, err := NormalizePhone("+1 (415) 555-2671")
+	require.NoError(t, err)
+	assert.Equal(t, "+14155552671", p)
+	p, err = NormalizePhone("001 415 555 2671")
+	require.NoError(t, err)
+	assert.Equal(t, "+14155552671", p)
+
+	for _, in := range []string{"", "12345", "23800138000", "1380013800a", "testuser", "a@b.com", "+0123456789"} {
+		_, err := NormalizePhone(in)
+		assert.ErrorIs(t, err, ErrInvalidPhone, in)
+	}
+}
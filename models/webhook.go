@@ -0,0 +1,55 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Webhook 事件类型
+const (
+	WebhookEventLargeExpense = "expense.large" // 单笔消费金额超过用户设置的大额阈值
+)
+
+// ValidWebhookEvent 是否为支持的 Webhook 事件类型
+func ValidWebhookEvent(event string) bool {
+	return event == WebhookEventLargeExpense
+}
+
+// Webhook 事件推送地址（管理员配置，对所有用户的事件生效）
+type Webhook struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Name      string    `json:"name" gorm:"size:100;not null"`
+	URL       string    `json:"url" gorm:"size:500;not null"`
+	Events    string    `json:"events" gorm:"size:255;not null"` // 订阅的事件类型，逗号分隔，见 WebhookEvent* 常量
+	Secret    string    `json:"-" gorm:"size:512;default:''"`    // 签名密钥（加密存储），为空时不签名
+	HasSecret bool      `json:"has_secret" gorm:"-"`             // 是否配置了签名密钥，仅用于返回
+	Active    bool      `json:"active" gorm:"not null;index"`    // 停用后不再推送
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName 设置表名
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// EventList 订阅的事件类型列表
+func (w *Webhook) EventList() []string {
+	var list []string
+	for _, e := range strings.Split(w.Events, ",") {
+		if e = strings.TrimSpace(e); e != "" {
+			list = append(list, e)
+		}
+	}
+	return list
+}
+
+// Subscribes 是否订阅了该事件
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,10 @@
 package router
 
 import (
+	"errors"
 	"io/fs"
 	"net/http"
+	"os"
 	"time"
 
 	"finance/adminauth"
@@ -10,6 +12,7 @@ import (
 	"finance/config"
 	_ "finance/docs"
 	"finance/middleware"
+	"finance/service"
 	"finance/web"
 
 	"github.com/gin-gonic/gin"
@@ -22,7 +25,9 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	// 设置运行模式
 	gin.SetMode(cfg.Server.Mode)
 
-	r := gin.Default()
+	// 请求日志在最外层，panic 恢复后的 500 也会被记录
+	r := gin.New()
+	r.Use(middleware.RequestLogger(os.Stdout), gin.Recovery())
 
 	// CORS 中间件
 	r.Use(CORSMiddleware())
@@ -38,6 +43,14 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		c.Data(http.StatusOK, "text/html; charset=utf-8", content)
 	})
 
+	// 导出类接口共享并发名额，避免多人同时大范围导出耗尽 CPU/内存
+	exportLimit := middleware.ConcurrencyLimit(middleware.NewSemaphore(cfg.Export.MaxConcurrent), "当前导出任务较多，请稍后再试")
+
+	// 功能开关：关闭的功能在入口直接返回“功能未开放”
+	exportFeature := middleware.RequireFeature(service.FeatureExport)
+	aiChatFeature := middleware.RequireFeature(service.FeatureAIChat)
+	aiAnalysisFeature := middleware.RequireFeature(service.FeatureAIAnalysis)
+
 	// 后台管理 API
 	adminHandler := api.NewAdminHandler()
 	passwordResetHandler := api.NewPasswordResetHandler(cfg)
@@ -45,7 +58,9 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 	admin := r.Group("/admin")
 	{
 		admin.POST("/login", middleware.LoginRateLimit(5, time.Minute), adminHandler.AdminLogin)
+		admin.POST("/2fa/verify", middleware.LoginRateLimit(5, time.Minute), adminHandler.VerifyTwoFactor)
 		admin.POST("/logout", adminHandler.AdminLogout)
+		admin.GET("/captcha", adminHandler.GetCaptcha)
 		admin.GET("/feishu/config", feishuAuthHandler.GetFeishuConfig)
 		admin.GET("/feishu/callback", feishuAuthHandler.FeishuCallback)
 
@@ -59,13 +74,28 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		{
 			adminAuth.GET("/feishu/bind-token", feishuAuthHandler.GetFeishuBindToken)
 			adminAuth.GET("/current-user", adminHandler.GetCurrentUserInfo)
+			adminAuth.POST("/2fa/setup", adminHandler.SetupTwoFactor)
+			adminAuth.POST("/2fa/enable", adminHandler.EnableTwoFactor)
+			adminAuth.POST("/2fa/disable", adminHandler.DisableTwoFactor)
 			adminAuth.GET("/expenses", adminHandler.GetAllExpenses)
 			adminAuth.POST("/expenses", adminHandler.CreateExpense)
+			adminAuth.POST("/expenses/import", middleware.RequireFeature(service.FeatureExpenseImport), adminHandler.ImportExpenses)
 			adminAuth.PUT("/expenses/:id", adminHandler.UpdateExpense)
 			adminAuth.DELETE("/expenses/:id", adminHandler.DeleteExpense)
+			adminAuth.GET("/expenses/trash", adminHandler.GetTrashedExpenses)
+			adminAuth.GET("/exchange-rates", adminHandler.GetExchangeRates)
+			adminAuth.PUT("/exchange-rates/:currency", adminHandler.UpsertExchangeRate)
+			adminAuth.DELETE("/exchange-rates/:currency", adminHandler.DeleteExchangeRate)
+			adminAuth.POST("/expenses/:id/restore", adminHandler.RestoreExpense)
+			adminAuth.DELETE("/expenses/:id/purge", adminHandler.PurgeExpense)
+			adminAuth.GET("/recurring-expenses", adminHandler.GetRecurringExpenses)
+			adminAuth.POST("/recurring-expenses", adminHandler.CreateRecurringExpense)
+			adminAuth.PUT("/recurring-expenses/:id", adminHandler.UpdateRecurringExpense)
+			adminAuth.DELETE("/recurring-expenses/:id", adminHandler.DeleteRecurringExpense)
 			adminAuth.GET("/expenses/detailed-statistics", adminHandler.GetDetailedStatistics)
 			// 支出/收入汇总（按时间，可选 user_id 仅管理员）
 			adminAuth.GET("/statistics/summary", adminHandler.AdminIncomeExpenseSummary)
+			adminAuth.GET("/statistics/cashflow", adminHandler.AdminCashFlow)
 			categoryHandler := api.NewCategoryHandler()
 			adminAuth.GET("/categories", categoryHandler.List)
 			adminAuth.POST("/categories", categoryHandler.Create)
@@ -77,6 +107,8 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			adminAuth.PUT("/income-categories/:id", incomeCategoryHandler.Update)
 			adminAuth.DELETE("/income-categories/:id", incomeCategoryHandler.Delete)
 			adminAuth.GET("/users", adminHandler.GetAllUsers)
+			adminAuth.GET("/users/:id/profile", adminHandler.GetUserProfile)
+			adminAuth.POST("/users/import", adminHandler.ImportUsers)
 			adminAuth.POST("/users/email/send-code", passwordResetHandler.AdminSendBindEmailCode)
 			adminAuth.PUT("/users/:id/password", adminHandler.UpdateUserPassword)
 			adminAuth.PUT("/users/:id/email", adminHandler.UpdateUserEmail)
@@ -84,41 +116,65 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			adminAuth.PUT("/users/:id/admin", adminHandler.SetAdmin)
 			adminAuth.PUT("/users/:id/status", adminHandler.UpdateUserStatus)
 			adminAuth.PUT("/users/:id/feishu", adminHandler.UpdateUserFeishu)
+			adminAuth.GET("/users/:id/ai-quota", adminHandler.GetUserAIQuota)
+			adminAuth.PUT("/users/:id/ai-quota", adminHandler.UpdateUserAIQuota)
 			adminAuth.PUT("/users/:id/role", adminHandler.UpdateUserRole)
 			adminAuth.POST("/users/impersonate", adminHandler.ImpersonateUser)
 			adminAuth.POST("/users/exit-impersonation", adminHandler.ExitImpersonation)
 			adminAuth.GET("/statistics", adminHandler.GetStatistics)
 			// 收入管理
 			adminAuth.GET("/incomes", adminHandler.GetAllIncomes)
+			adminAuth.GET("/incomes/detailed-statistics", adminHandler.GetIncomeDetailedStatistics)
 			adminAuth.POST("/incomes", adminHandler.CreateIncome)
 			adminAuth.PUT("/incomes/:id", adminHandler.UpdateIncome)
 			adminAuth.DELETE("/incomes/:id", adminHandler.DeleteIncome)
-			adminAuth.GET("/export/excel", adminHandler.ExportExcel)
+			adminAuth.GET("/export/excel", exportFeature, exportLimit, adminHandler.ExportExcel)
+			adminAuth.GET("/export/pdf", exportFeature, exportLimit, adminHandler.ExportPDF)
+			adminAuth.GET("/export/incomes/excel", exportFeature, exportLimit, adminHandler.ExportIncomesExcel)
+			adminAuth.GET("/export/workbook", exportFeature, exportLimit, adminHandler.ExportWorkbook)
 
 			// 管理员密码重置功能
 			adminAuth.POST("/password/admin-reset", passwordResetHandler.AdminResetPassword)
 			adminAuth.POST("/password/send-reset-email", passwordResetHandler.SendPasswordResetEmail)
 			adminAuth.GET("/email-config", passwordResetHandler.GetEmailConfig)
+			adminAuth.GET("/email-logs", adminHandler.GetEmailLogs)
+			adminAuth.GET("/audit-logs", adminHandler.GetAuditLogs)
+			adminAuth.GET("/webhooks", adminHandler.GetWebhooks)
+			adminAuth.POST("/webhooks", adminHandler.CreateWebhook)
+			adminAuth.PUT("/webhooks/:id", adminHandler.UpdateWebhook)
+			adminAuth.DELETE("/webhooks/:id", adminHandler.DeleteWebhook)
+			adminAuth.POST("/webhooks/:id/test", adminHandler.TestWebhook)
+			adminAuth.POST("/balance-snapshots/rebuild", api.NewBalanceHandler().AdminRebuild)
+			adminAuth.GET("/metrics/cache", adminHandler.GetCacheMetrics)
+			adminAuth.GET("/feature-flags", adminHandler.GetFeatureFlags)
+			adminAuth.PUT("/feature-flags", adminHandler.UpdateFeatureFlag)
 
 			// AI模型管理
 			aiModelHandler := api.NewAIModelHandler()
 			adminAuth.GET("/ai-models", aiModelHandler.GetAllAIModels)
 			adminAuth.PUT("/ai-models/reorder", aiModelHandler.ReorderAIModels)
 			adminAuth.GET("/ai-models/:id", aiModelHandler.GetAIModel)
+			adminAuth.GET("/ai-models/:id/history-count", aiModelHandler.GetAIModelHistoryCount)
 			adminAuth.POST("/ai-models", aiModelHandler.CreateAIModel)
 			adminAuth.POST("/ai-models/:id/test", aiModelHandler.TestAIModel)
 			adminAuth.PUT("/ai-models/:id", aiModelHandler.UpdateAIModel)
 			adminAuth.DELETE("/ai-models/:id", aiModelHandler.DeleteAIModel)
 
+			// AI提示词模板
+			promptTemplateHandler := api.NewPromptTemplateHandler()
+			adminAuth.GET("/prompt-templates", promptTemplateHandler.ListPromptTemplates)
+			adminAuth.PUT("/prompt-templates/:key", promptTemplateHandler.UpdatePromptTemplate)
+			adminAuth.DELETE("/prompt-templates/:key", promptTemplateHandler.ResetPromptTemplate)
+
 			// AI分析
 			aiAnalysisHandler := api.NewAIAnalysisHandler()
-			adminAuth.POST("/ai-analysis", aiAnalysisHandler.AnalyzeExpenses)
+			adminAuth.POST("/ai-analysis", aiAnalysisFeature, aiAnalysisHandler.AnalyzeExpenses)
 			adminAuth.GET("/ai-analysis/history", aiAnalysisHandler.ListAnalysisHistory)
 			adminAuth.DELETE("/ai-analysis/history/:id", aiAnalysisHandler.DeleteAnalysisHistory)
 
 			// AI聊天（流式 + 历史）
 			aiChatHandler := api.NewAIChatHandler()
-			adminAuth.POST("/ai-chat", aiChatHandler.ChatStream)
+			adminAuth.POST("/ai-chat", aiChatFeature, aiChatHandler.ChatStream)
 			adminAuth.GET("/ai-chat/history", aiChatHandler.ChatHistory)
 			adminAuth.DELETE("/ai-chat/history/:id", aiChatHandler.DeleteChatHistory)
 
@@ -162,6 +218,7 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", middleware.LoginRateLimit(5, time.Minute), authHandler.Login)
+			auth.POST("/refresh", authHandler.Refresh)
 
 			// 邮箱验证相关
 			auth.POST("/send-code", authHandler.SendVerificationCode)
@@ -187,62 +244,169 @@ func SetupRouter(cfg *config.Config) *gin.Engine {
 			// 用户相关
 			authorized.GET("/auth/profile", authHandler.GetProfile)
 			authorized.PUT("/auth/password", authHandler.ChangePassword)
+			authorized.PUT("/auth/notify-channel", authHandler.UpdateNotifyChannel)
+			authorized.PUT("/auth/week-start", authHandler.UpdateWeekStart)
+			authorized.PUT("/auth/large-expense-threshold", authHandler.UpdateLargeExpenseThreshold)
+			authorized.PUT("/auth/daily-digest", authHandler.UpdateDailyDigest)
+			authorized.POST("/auth/logout", authHandler.Logout)
+			authorized.GET("/auth/sessions", authHandler.ListSessions)
+			authorized.DELETE("/auth/sessions/:id", authHandler.DeleteSession)
 
 			// 消费记录相关
+			receiptHandler := api.NewReceiptHandler()
 			expenses := authorized.Group("/expenses")
 			{
 				expenses.POST("", expenseHandler.Create)
 				expenses.GET("", expenseHandler.List)
+				expenses.POST("/import", middleware.RequireFeature(service.FeatureExpenseImport), expenseHandler.Import)
 				expenses.GET("/statistics", expenseHandler.GetStatistics)
+				expenses.GET("/statistics/chart.png", expenseHandler.GetStatisticsChart)
 				expenses.GET("/detailed-statistics", expenseHandler.GetDetailedStatistics)
 				expenses.GET("/:id", expenseHandler.Get)
+				expenses.POST("/:id/duplicate", expenseHandler.Duplicate)
 				expenses.PUT("/:id", expenseHandler.Update)
 				expenses.DELETE("/:id", expenseHandler.Delete)
+				expenses.DELETE("/installments/:group_id", expenseHandler.CancelInstallments)
+				expenses.POST("/:id/receipt", receiptHandler.Upload)
+				expenses.GET("/:id/receipts", receiptHandler.List)
 			}
 
+			// 消费附件（小票图片、语音、文本）
+			authorized.GET("/receipts/:id", receiptHandler.Get)
+			authorized.DELETE("/receipts/:id", receiptHandler.Delete)
+
 			// 统计相关（支出/收入汇总）
 			authorized.GET("/statistics/summary", expenseHandler.GetIncomeExpenseSummary)
+			authorized.GET("/statistics/cashflow", expenseHandler.GetCashFlow)
 
 			// 收入相关
 			incomes := authorized.Group("/incomes")
 			{
 				incomes.POST("", incomeHandler.Create)
+				incomes.POST("/batch", incomeHandler.BatchCreate)
+				incomes.POST("/import", incomeHandler.Import)
 				incomes.GET("", incomeHandler.List)
+				incomes.GET("/anomalies", incomeHandler.Anomalies)
+				incomes.GET("/trend", incomeHandler.Trend)
+				incomes.GET("/compare", incomeHandler.Compare)
+				incomes.GET("/statistics", incomeHandler.GetStatistics)
+				incomes.GET("/detailed-statistics", incomeHandler.GetDetailedStatistics)
 				incomes.GET("/:id", incomeHandler.Get)
 				incomes.PUT("/:id", incomeHandler.Update)
 				incomes.DELETE("/:id", incomeHandler.Delete)
 			}
 
+			// 预算相关
+			budgetHandler := api.NewBudgetHandler()
+			budgets := authorized.Group("/budgets")
+			{
+				budgets.GET("", budgetHandler.List)
+				budgets.GET("/export", exportFeature, exportLimit, budgetHandler.Export)
+				budgets.GET("/status", budgetHandler.Status)
+				budgets.GET("/daily-allowance", budgetHandler.DailyAllowance)
+				budgets.GET("/trend", budgetHandler.Trend)
+				budgets.POST("", budgetHandler.Create)
+				budgets.PUT("/:id", budgetHandler.Update)
+				budgets.DELETE("/:id", budgetHandler.Delete)
+			}
+
+			// 周期消费模板
+			recurringHandler := api.NewRecurringExpenseHandler()
+			recurring := authorized.Group("/recurring-expenses")
+			{
+				recurring.GET("", recurringHandler.List)
+				recurring.POST("", recurringHandler.Create)
+				recurring.PUT("/:id", recurringHandler.Update)
+				recurring.DELETE("/:id", recurringHandler.Delete)
+			}
+
+			// 月末结余快照
+			balanceHandler := api.NewBalanceHandler()
+			authorized.GET("/balance/history", balanceHandler.History)
+
+			// 地理围栏自动归类规则
+			geoRuleHandler := api.NewGeoRuleHandler()
+			geoRules := authorized.Group("/geo-rules")
+			{
+				geoRules.GET("", geoRuleHandler.List)
+				geoRules.POST("", geoRuleHandler.Create)
+				geoRules.PUT("/:id", geoRuleHandler.Update)
+				geoRules.DELETE("/:id", geoRuleHandler.Delete)
+			}
+
+			// 商户字典
+			merchantHandler := api.NewMerchantHandler()
+			merchants := authorized.Group("/merchants")
+			{
+				merchants.GET("", merchantHandler.List)
+				merchants.POST("", merchantHandler.Create)
+				merchants.PUT("/:id", merchantHandler.Update)
+				merchants.DELETE("/:id", merchantHandler.Delete)
+				merchants.POST("/:id/merge", merchantHandler.Merge)
+			}
+
+			// 消费标签（创建消费时自动创建，此处维护：重命名、合并、删除）
+			tagHandler := api.NewTagHandler()
+			tags := authorized.Group("/tags")
+			{
+				tags.GET("", tagHandler.List)
+				tags.PUT("/:id", tagHandler.Rename)
+				tags.DELETE("/:id", tagHandler.Delete)
+				tags.POST("/:id/merge", tagHandler.Merge)
+			}
+
+			// 共享账本
+			ledgerHandler := api.NewLedgerHandler()
+			ledgers := authorized.Group("/ledgers")
+			{
+				ledgers.GET("", ledgerHandler.List)
+				ledgers.POST("", ledgerHandler.Create)
+				ledgers.PUT("/current", ledgerHandler.Switch)
+				ledgers.GET("/:id/members", ledgerHandler.Members)
+				ledgers.POST("/:id/members", ledgerHandler.AddMember)
+				ledgers.DELETE("/:id/members/:user_id", ledgerHandler.RemoveMember)
+			}
+
+			// 全局搜索
+			searchHandler := api.NewSearchHandler()
+			authorized.GET("/search", searchHandler.Search)
+
 			// 导出相关
 			exportHandler := api.NewExportHandler()
 			export := authorized.Group("/export")
+			export.Use(exportFeature, exportLimit)
 			{
 				export.GET("/csv", exportHandler.ExportCSV)
 				export.GET("/json", exportHandler.ExportJSON)
+				export.GET("/pdf", exportHandler.ExportPDF)
+				export.GET("/incomes/csv", exportHandler.ExportIncomesCSV)
 			}
 
+			// 币种与汇率
+			authorized.GET("/currencies", api.NewExchangeRateHandler().GetCurrencies)
+
 			// AI（供 App/前端使用，JWT，按用户隔离历史）
 			aiModelHandlerV1 := api.NewAIModelHandler()
 			authorized.GET("/ai-models", aiModelHandlerV1.ListAIModelsApp)
+			authorized.GET("/ai-quota", api.NewAIQuotaHandler().GetQuota)
 
 			aiAnalysisHandlerV1 := api.NewAIAnalysisHandler()
-			authorized.POST("/ai-analysis", aiAnalysisHandlerV1.AnalyzeExpensesApp)
+			authorized.POST("/ai-analysis", aiAnalysisFeature, aiAnalysisHandlerV1.AnalyzeExpensesApp)
 			authorized.GET("/ai-analysis/history", aiAnalysisHandlerV1.ListAnalysisHistoryApp)
 			authorized.DELETE("/ai-analysis/history/:id", aiAnalysisHandlerV1.DeleteAnalysisHistoryApp)
+			authorized.POST("/ai-analysis/history/:id/to-feishu-doc", aiAnalysisHandlerV1.ExportAnalysisToFeishuDocApp)
 
 			aiChatHandlerV1 := api.NewAIChatHandler()
-			authorized.POST("/ai-chat", aiChatHandlerV1.ChatStreamApp)
+			authorized.POST("/ai-chat", aiChatFeature, aiChatHandlerV1.ChatStreamApp)
 			authorized.GET("/ai-chat/history", aiChatHandlerV1.ChatHistoryApp)
+			authorized.POST("/ai-chat/conversations", aiChatHandlerV1.CreateConversationApp)
+			authorized.GET("/ai-chat/conversations", aiChatHandlerV1.ListConversationsApp)
 			authorized.DELETE("/ai-chat/history/:id", aiChatHandlerV1.DeleteChatHistoryApp)
 		}
 	}
 
 	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(200, gin.H{
-			"status": "ok",
-		})
-	})
+	r.GET("/health", api.HealthCheck)
 
 	return r
 }
@@ -252,8 +416,10 @@ func CORSMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
 		c.Writer.Header().Set("Access-Control-Allow-Credentials", "true")
-		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Writer.Header().Set("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, Idempotency-Key")
 		c.Writer.Header().Set("Access-Control-Allow-Methods", "POST, OPTIONS, GET, PUT, DELETE, PATCH")
+		// 导出与统计图通过响应头回显实际使用的时间范围；创建接口通过 Idempotent-Replayed 标识重放结果
+		c.Writer.Header().Set("Access-Control-Expose-Headers", "X-Range-Start, X-Range-End, X-Request-ID, Idempotent-Replayed")
 
 		if c.Request.Method == "OPTIONS" {
 			c.AbortWithStatus(204)
@@ -265,9 +431,10 @@ func CORSMiddleware() gin.HandlerFunc {
 }
 
 // AdminAuthMiddleware 后台管理 Cookie 认证中间件（验证签名，防止 Cookie 篡改越权）
+// 每次请求同时校验用户状态，被锁定的用户持旧 Cookie 立即被拒
 func AdminAuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		_, err := adminauth.GetVerifiedAdminUserID(c)
+		userID, err := adminauth.GetVerifiedAdminUserID(c)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -276,6 +443,19 @@ func AdminAuthMiddleware() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if _, err := middleware.CheckUserState(userID); err != nil {
+			status := http.StatusUnauthorized
+			if errors.Is(err, middleware.ErrUserLocked) {
+				status = http.StatusForbidden
+			}
+			c.JSON(status, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			c.Abort()
+			return
+		}
+		c.Set("adminUserID", userID)
 		c.Next()
 	}
 }
@@ -0,0 +1,37 @@
+package service
+
+import (
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// SealAIModelKey 加密 API 密钥并生成脱敏展示，返回写入 api_key 与 api_key_hint 的值
+func SealAIModelKey(plain string) (encrypted, hint string, err error) {
+	encrypted, err = EncryptSecret(plain)
+	if err != nil {
+		return "", "", err
+	}
+	return encrypted, models.MaskAPIKey(plain), nil
+}
+
+// EncryptLegacyAIModelKeys 启动时把加密前保存的明文 API 密钥（含已删除的模型）改为密文，返回处理的模型数
+func EncryptLegacyAIModelKeys(db *gorm.DB) (int, error) {
+	var list []models.AIModel
+	if err := db.Unscoped().Select("id", "api_key").
+		Where("api_key <> '' AND api_key NOT LIKE ?", encryptedSecretPrefix+"%").
+		Find(&list).Error; err != nil {
+		return 0, err
+	}
+	for _, m := range list {
+		encrypted, hint, err := SealAIModelKey(m.APIKey)
+		if err != nil {
+			return 0, err
+		}
+		if err := db.Unscoped().Model(&models.AIModel{}).Where("id = ?", m.ID).
+			UpdateColumns(map[string]interface{}{"api_key": encrypted, "api_key_hint": hint}).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(list), nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"database/sql/driver"
+	"testing"
+
+	"finance/config"
+	"finance/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type capturedArg struct{ dest *string }
+
+func (a capturedArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	*a.dest = s
+	return ok
+}
+
+func TestEncryptLegacyAIModelKeys(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	config.GlobalConfig = &config.Config{Security: config.SecurityConfig{EncryptionKey: "k"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	// 已加密的密钥不再处理，已删除的模型同样加密
+	mock.ExpectQuery("SELECT `id`,`api_key` FROM `ai_models` WHERE api_key <> '' AND api_key NOT LIKE \\?$").
+		WithArgs("enc:v1:%").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "api_key"}).AddRow(4, "sk-legacy-abcd1234"))
+	var stored string
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `ai_models` SET `api_key`=\\?,`api_key_hint`=\\? WHERE id = \\?$").
+		WithArgs(capturedArg{&stored}, "sk-****1234", 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := EncryptLegacyAIModelKeys(database.DB)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	plain, err := DecryptSecret(stored)
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy-abcd1234", plain)
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"log"
+	"math"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"gorm.io/gorm/clause"
+)
+
+// balanceSnapshotRunAt 每月 1 日该时刻（本地时间）生成上月快照
+const balanceSnapshotRunAt = 10 * time.Minute
+
+// MonthStart 返回 t 所在月份的第一天 0 点（本地时区）
+func MonthStart(t time.Time) time.Time {
+	t = t.In(time.Local)
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.Local)
+}
+
+// periodSums 按用户汇总的当月金额与截至月末的累计金额（本位币）
+type periodSums struct {
+	Period     float64
+	Cumulative float64
+}
+
+// periodCurrencyRow 按用户与币种分组的原币汇总
+type periodCurrencyRow struct {
+	UserID     uint
+	Currency   string
+	Period     float64
+	Cumulative float64
+}
+
+// sumByUser 汇总 [start, end) 内的金额及 end 之前的累计金额，按汇率折算为本位币；
+// 缺少汇率的币种不计入
+func sumByUser(model interface{}, timeColumn string, start, end time.Time, excludeTransfer bool, rates ExchangeRates) (map[uint]periodSums, error) {
+	query := database.DB.Model(model).
+		Select("user_id, currency, COALESCE(SUM(CASE WHEN "+timeColumn+" >= ? THEN amount ELSE 0 END), 0) AS period, COALESCE(SUM(amount), 0) AS cumulative", start).
+		Where(timeColumn+" < ?", end)
+	if excludeTransfer {
+		transferNames := database.DB.Model(&models.ExpenseCategory{}).Select("name").Where("is_transfer = ?", true)
+		query = query.Where("category NOT IN (?)", transferNames)
+	}
+	var rows []periodCurrencyRow
+	if err := query.Group("user_id, currency").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	result := make(map[uint]periodSums, len(rows))
+	for _, r := range rows {
+		period, ok := rates.ToBase(r.Period, r.Currency)
+		if !ok {
+			continue
+		}
+		cumulative, _ := rates.ToBase(r.Cumulative, r.Currency)
+		sums := result[r.UserID]
+		sums.Period += period
+		sums.Cumulative += cumulative
+		result[r.UserID] = sums
+	}
+	return result, nil
+}
+
+// GenerateBalanceSnapshots 为 month 所在月份生成（或覆盖）所有用户的结余快照，返回快照条数。
+// 金额按汇率折算为本位币；当月支出不含内部转账类别；结余为截至月末的累计收入减累计支出
+func GenerateBalanceSnapshots(month time.Time) (int, error) {
+	start := MonthStart(month)
+	end := start.AddDate(0, 1, 0)
+	period := start.Format("2006-01")
+
+	var userIDs []uint
+	if err := database.DB.Model(&models.User{}).Where("created_at < ?", end).Pluck("id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+	rates, err := LoadExchangeRates()
+	if err != nil {
+		return 0, err
+	}
+	expenses, err := sumByUser(&models.Expense{}, "expense_time", start, end, true, rates)
+	if err != nil {
+		return 0, err
+	}
+	incomes, err := sumByUser(&models.Income{}, "income_time", start, end, false, rates)
+	if err != nil {
+		return 0, err
+	}
+
+	snapshots := make([]models.BalanceSnapshot, len(userIDs))
+	for i, id := range userIDs {
+		e, in := expenses[id], incomes[id]
+		snapshots[i] = models.BalanceSnapshot{
+			UserID:       id,
+			Period:       period,
+			TotalIncome:  math.Round(in.Period*100) / 100,
+			TotalExpense: math.Round(e.Period*100) / 100,
+			Balance:      math.Round((in.Cumulative-e.Cumulative)*100) / 100,
+		}
+	}
+	err = database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "period"}},
+		DoUpdates: clause.AssignmentColumns([]string{"total_income", "total_expense", "balance", "updated_at"}),
+	}).CreateInBatches(&snapshots, 500).Error
+	if err != nil {
+		return 0, err
+	}
+	return len(snapshots), nil
+}
+
+// nextBalanceSnapshotRun 下一次生成快照的时间：下月 1 日 balanceSnapshotRunAt
+func nextBalanceSnapshotRun(now time.Time) time.Time {
+	return MonthStart(now).AddDate(0, 1, 0).Add(balanceSnapshotRunAt)
+}
+
+// StartBalanceSnapshotScheduler 启动月末快照定时任务：启动时先补生成上月快照（防止停机错过），
+// 之后每月 1 日生成上月快照。快照按 用户+月份 覆盖，多实例或重复执行不会产生重复数据
+func StartBalanceSnapshotScheduler() {
+	go func() {
+		run := func(now time.Time) {
+			month := MonthStart(now).AddDate(0, -1, 0)
+			if n, err := GenerateBalanceSnapshots(month); err != nil {
+				log.Printf("生成 %s 结余快照失败: %v", month.Format("2006-01"), err)
+			} else {
+				log.Printf("已生成 %s 结余快照 %d 条", month.Format("2006-01"), n)
+			}
+		}
+		run(time.Now())
+		for {
+			next := nextBalanceSnapshotRun(time.Now())
+			time.Sleep(time.Until(next))
+			run(time.Now())
+		}
+	}()
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"finance/database"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+)
+
+func setupMockDB(t *testing.T) (sqlmock.Sqlmock, func()) {
+	sqlDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	gormDB, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sqlDB,
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{})
+	require.NoError(t, err)
+
+	oldDB := database.DB
+	database.DB = gormDB
+	return mock, func() {
+		database.DB = oldDB
+		sqlDB.Close()
+	}
+}
+
+func TestNextBalanceSnapshotRun(t *testing.T) {
+	now := time.Date(2024, 1, 31, 23, 0, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 10, 0, 0, time.Local), nextBalanceSnapshotRun(now))
+
+	now = time.Date(2024, 12, 1, 0, 20, 0, 0, time.Local)
+	assert.Equal(t, time.Date(2025, 1, 1, 0, 10, 0, 0, time.Local), nextBalanceSnapshotRun(now))
+}
+
+func TestGenerateBalanceSnapshots(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+	end := time.Date(2024, 4, 1, 0, 0, 0, 0, time.Local)
+
+	mock.ExpectQuery("SELECT `id` FROM `users` WHERE created_at < \\?").
+		WithArgs(end).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1).AddRow(2))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+	mock.ExpectQuery("SELECT user_id, currency, .* FROM `expenses` WHERE expense_time < \\? AND category NOT IN \\(SELECT `name` FROM `expense_categories`.*GROUP BY .*user_id.*currency").
+		WithArgs(start, end, true).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "currency", "period", "cumulative"}).
+			AddRow(1, "CNY", 300, 1000).
+			AddRow(1, "USD", 10, 20).
+			AddRow(1, "JPY", 500, 500))
+	mock.ExpectQuery("SELECT user_id, currency, .* FROM `incomes` WHERE income_time < \\?.*GROUP BY .*user_id.*currency").
+		WithArgs(start, end).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "currency", "period", "cumulative"}).AddRow(1, "", 5000, 15000))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `balance_snapshots` .* ON DUPLICATE KEY UPDATE `total_income`=VALUES\\(`total_income`\\)").
+		WithArgs(
+			1, "2024-03", 5000.0, 370.0, 13860.0, sqlmock.AnyArg(), sqlmock.AnyArg(),
+			2, "2024-03", 0.0, 0.0, 0.0, sqlmock.AnyArg(), sqlmock.AnyArg(),
+		).
+		WillReturnResult(sqlmock.NewResult(1, 2))
+	mock.ExpectCommit()
+
+	n, err := GenerateBalanceSnapshots(time.Date(2024, 3, 15, 12, 0, 0, 0, time.Local))
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
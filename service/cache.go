@@ -0,0 +1,183 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// cacheEntryOverhead 每个条目除键和值之外的估算开销（map 槽位、过期时间等），单位字节
+const cacheEntryOverhead = 64
+
+// cacheEntry 缓存条目
+type cacheEntry struct {
+	value     interface{}
+	size      int // 估算占用字节数
+	expiresAt time.Time
+}
+
+// Cache 带过期时间的进程内缓存，记录命中/未命中次数
+type Cache struct {
+	name       string
+	ttl        time.Duration
+	maxEntries int
+
+	mu    sync.Mutex
+	items map[string]cacheEntry
+	bytes int
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+// CacheStats 缓存运行指标
+type CacheStats struct {
+	Name           string  `json:"name"`
+	Hits           int64   `json:"hits"`
+	Misses         int64   `json:"misses"`
+	HitRate        float64 `json:"hit_rate"`        // 命中次数 / 查询次数（0-1），尚无查询时为 0
+	Invalidations  int64   `json:"invalidations"`   // 因数据变更被清除的条目数
+	Entries        int     `json:"entries"`         // 当前未过期的条目数
+	EstimatedBytes int     `json:"estimated_bytes"` // 按键长 + 值的 JSON 长度估算的内存占用
+	MaxEntries     int     `json:"max_entries"`
+	TTLSeconds     int     `json:"ttl_seconds"`
+}
+
+var (
+	cacheRegistryMu sync.Mutex
+	cacheRegistry   = map[string]*Cache{}
+)
+
+// NewCache 创建缓存并登记到全局，供指标接口汇总；同名缓存后创建的覆盖先创建的
+func NewCache(name string, ttl time.Duration, maxEntries int) *Cache {
+	c := &Cache{name: name, ttl: ttl, maxEntries: maxEntries, items: map[string]cacheEntry{}}
+	cacheRegistryMu.Lock()
+	cacheRegistry[name] = c
+	cacheRegistryMu.Unlock()
+	return c
+}
+
+// Get 读取未过期的条目，并计入命中/未命中
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mu.Lock()
+	e, ok := c.items[key]
+	if ok && !time.Now().Before(e.expiresAt) {
+		c.removeLocked(key, e)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.hits.Add(1)
+		return e.value, true
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+// Set 写入条目；已满时先清理过期条目，仍满则放弃写入
+func (c *Cache) Set(key string, value interface{}) {
+	size := len(key) + cacheEntryOverhead
+	if b, err := json.Marshal(value); err == nil {
+		size += len(b)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, ok := c.items[key]; ok {
+		c.removeLocked(key, old)
+	}
+	if c.maxEntries > 0 && len(c.items) >= c.maxEntries {
+		c.purgeExpiredLocked(time.Now())
+		if len(c.items) >= c.maxEntries {
+			return
+		}
+	}
+	c.items[key] = cacheEntry{value: value, size: size, expiresAt: time.Now().Add(c.ttl)}
+	c.bytes += size
+}
+
+// InvalidatePrefix 清除以 prefix 开头的条目，返回清除数量
+func (c *Cache) InvalidatePrefix(prefix string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := 0
+	for k, e := range c.items {
+		if strings.HasPrefix(k, prefix) {
+			c.removeLocked(k, e)
+			n++
+		}
+	}
+	c.invalidations.Add(int64(n))
+	return n
+}
+
+// InvalidateAll 清空缓存，返回清除数量
+func (c *Cache) InvalidateAll() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n := len(c.items)
+	c.items = map[string]cacheEntry{}
+	c.bytes = 0
+	c.invalidations.Add(int64(n))
+	return n
+}
+
+// Stats 返回当前指标；先清理过期条目，保证条目数与内存估算不含已过期的部分
+func (c *Cache) Stats() CacheStats {
+	c.mu.Lock()
+	c.purgeExpiredLocked(time.Now())
+	entries, bytes := len(c.items), c.bytes
+	c.mu.Unlock()
+
+	hits, misses := c.hits.Load(), c.misses.Load()
+	var rate float64
+	if total := hits + misses; total > 0 {
+		rate = float64(hits) / float64(total)
+	}
+	return CacheStats{
+		Name:           c.name,
+		Hits:           hits,
+		Misses:         misses,
+		HitRate:        rate,
+		Invalidations:  c.invalidations.Load(),
+		Entries:        entries,
+		EstimatedBytes: bytes,
+		MaxEntries:     c.maxEntries,
+		TTLSeconds:     int(c.ttl / time.Second),
+	}
+}
+
+func (c *Cache) removeLocked(key string, e cacheEntry) {
+	delete(c.items, key)
+	c.bytes -= e.size
+}
+
+func (c *Cache) purgeExpiredLocked(now time.Time) {
+	for k, e := range c.items {
+		if !now.Before(e.expiresAt) {
+			c.removeLocked(k, e)
+		}
+	}
+}
+
+// AllCacheStats 所有已登记缓存的指标，按名称排序
+func AllCacheStats() []CacheStats {
+	cacheRegistryMu.Lock()
+	caches := make([]*Cache, 0, len(cacheRegistry))
+	for _, c := range cacheRegistry {
+		caches = append(caches, c)
+	}
+	cacheRegistryMu.Unlock()
+
+	stats := make([]CacheStats, 0, len(caches))
+	for _, c := range caches {
+		stats = append(stats, c.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
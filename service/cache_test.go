@@ -0,0 +1,147 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCache_HitMissAndExpiry(t *testing.T) {
+	c := NewCache("test_hit_miss", 50*time.Millisecond, 0)
+
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	c.Set("a", 1)
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	assert.Equal(t, 1, v)
+
+	s := c.Stats()
+	assert.Equal(t, int64(1), s.Hits)
+	assert.Equal(t, int64(1), s.Misses)
+	assert.Equal(t, 0.5, s.HitRate)
+	assert.Equal(t, 1, s.Entries)
+	assert.Greater(t, s.EstimatedBytes, 0)
+
+	// 过期后条目数与内存估算归零
+	time.Sleep(60 * time.Millisecond)
+	s = c.Stats()
+	assert.Equal(t, 0, s.Entries)
+	assert.Equal(t, 0, s.EstimatedBytes)
+	_, ok = c.Get("a")
+	assert.False(t, ok)
+}
+
+func TestCache_InvalidateAndMaxEntries(t *testing.T) {
+	c := NewCache("test_invalidate", time.Minute, 3)
+	c.Set("u1:a", "x")
+	c.Set("u1:b", "y")
+	c.Set("u2:a", "z")
+	c.Set("u3:a", "full") // 已满，放弃写入
+
+	assert.Equal(t, 3, c.Stats().Entries)
+	assert.Equal(t, 2, c.InvalidatePrefix("u1:"))
+	s := c.Stats()
+	assert.Equal(t, 1, s.Entries)
+	assert.Equal(t, int64(2), s.Invalidations)
+
+	// 覆盖同一个键不重复计算内存
+	before := c.Stats().EstimatedBytes
+	c.Set("u2:a", "z")
+	assert.Equal(t, before, c.Stats().EstimatedBytes)
+
+	assert.Equal(t, 1, c.InvalidateAll())
+	assert.Equal(t, 0, c.Stats().EstimatedBytes)
+}
+
+func TestCache_ConcurrentCounting(t *testing.T) {
+	c := NewCache("test_concurrent", time.Minute, 0)
+	c.Set("hot", 1)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Get("hot")
+				c.Get(fmt.Sprintf("cold-%d-%d", i, j))
+				c.Set(fmt.Sprintf("k-%d", i), j)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	s := c.Stats()
+	assert.Equal(t, int64(2000), s.Hits)
+	assert.Equal(t, int64(2000), s.Misses)
+	assert.Equal(t, 21, s.Entries)
+}
+
+func TestAllCacheStats_IncludesStatisticsCache(t *testing.T) {
+	var names []string
+	for _, s := range AllCacheStats() {
+		names = append(names, s.Name)
+	}
+	assert.Contains(t, names, "expense_statistics")
+}
+
+func TestRegisterCacheInvalidation(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	require.NoError(t, RegisterCacheInvalidation(database.DB))
+	ExpenseStatisticsCache.InvalidateAll()
+
+	ExpenseStatisticsCache.Set(UserCachePrefix(1)+"all", 1)
+	ExpenseStatisticsCache.Set(UserCachePrefix(2)+"all", 2)
+
+	// 新增消费只清除该用户的统计
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, database.DB.Create(&models.Expense{UserID: 1, Amount: 10, Category: "餐饮", ExpenseTime: time.Now()}).Error)
+
+	_, ok := ExpenseStatisticsCache.Get(UserCachePrefix(1) + "all")
+	assert.False(t, ok)
+	_, ok = ExpenseStatisticsCache.Get(UserCachePrefix(2) + "all")
+	assert.True(t, ok)
+
+	// 按条件批量更新无法确定用户，清空全部
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `expenses`").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+	require.NoError(t, database.DB.Model(&models.Expense{}).Where("category = ?", "餐饮").Update("category", "吃饭").Error)
+	assert.Equal(t, 0, ExpenseStatisticsCache.Stats().Entries)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRegisterCacheInvalidation_Ledger(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	require.NoError(t, RegisterCacheInvalidation(database.DB))
+	ExpenseStatisticsCache.InvalidateAll()
+
+	ExpenseStatisticsCache.Set(LedgerCachePrefix(7)+"all", 1)
+	ExpenseStatisticsCache.Set(LedgerCachePrefix(8)+"all", 2)
+
+	// 成员在共享账本中记账，清除该账本的统计
+	ledgerID := uint(7)
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `expenses`").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	require.NoError(t, database.DB.Create(&models.Expense{UserID: 2, LedgerID: &ledgerID, Amount: 10, Category: "餐饮", ExpenseTime: time.Now()}).Error)
+
+	_, ok := ExpenseStatisticsCache.Get(LedgerCachePrefix(7) + "all")
+	assert.False(t, ok)
+	_, ok = ExpenseStatisticsCache.Get(LedgerCachePrefix(8) + "all")
+	assert.True(t, ok)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
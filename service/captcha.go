@@ -0,0 +1,147 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// 图形验证码参数
+const (
+	captchaLength = 4
+	captchaTTL    = 5 * time.Minute
+	captchaScale  = 3    // 基础字体 7x13，放大后更易辨认
+	captchaMaxLen = 5000 // 同时有效的验证码上限，防止被刷爆内存
+)
+
+// captchaChars 去掉 0/O、1/I/L 等易混字符
+const captchaChars = "23456789ABCDEFGHJKMNPQRSTUVWXYZ"
+
+// ErrCaptchaBusy 有效验证码过多
+var ErrCaptchaBusy = errors.New("验证码请求过多，请稍后再试")
+
+type captchaEntry struct {
+	code    string
+	expires time.Time
+}
+
+var (
+	captchaMu    sync.Mutex
+	captchaStore = map[string]captchaEntry{}
+)
+
+// randIndex 返回 [0, n) 的随机数
+func randIndex(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		return 0
+	}
+	return int(v.Int64())
+}
+
+// NewCaptcha 生成图形验证码，返回验证码 ID 与 PNG 图片；验证码 5 分钟内有效且只能校验一次
+func NewCaptcha() (string, []byte, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", nil, err
+	}
+	id := hex.EncodeToString(buf)
+
+	code := make([]byte, captchaLength)
+	for i := range code {
+		code[i] = captchaChars[randIndex(len(captchaChars))]
+	}
+
+	img, err := renderCaptcha(string(code))
+	if err != nil {
+		return "", nil, err
+	}
+
+	now := time.Now()
+	captchaMu.Lock()
+	defer captchaMu.Unlock()
+	if len(captchaStore) >= captchaMaxLen {
+		for k, e := range captchaStore {
+			if now.After(e.expires) {
+				delete(captchaStore, k)
+			}
+		}
+		if len(captchaStore) >= captchaMaxLen {
+			return "", nil, ErrCaptchaBusy
+		}
+	}
+	captchaStore[id] = captchaEntry{code: string(code), expires: now.Add(captchaTTL)}
+	return id, img, nil
+}
+
+// VerifyCaptcha 校验验证码（忽略大小写）；无论对错都会作废该验证码，防止逐个猜测
+func VerifyCaptcha(id, code string) bool {
+	if id == "" || code == "" {
+		return false
+	}
+	captchaMu.Lock()
+	e, ok := captchaStore[id]
+	delete(captchaStore, id)
+	captchaMu.Unlock()
+	if !ok || time.Now().After(e.expires) {
+		return false
+	}
+	return strings.EqualFold(e.code, strings.TrimSpace(code))
+}
+
+// renderCaptcha 绘制验证码：逐字符随机上下偏移后放大，再叠加干扰点和干扰线
+func renderCaptcha(code string) ([]byte, error) {
+	face := basicfont.Face7x13
+	const charW, charH = 9, 16
+	small := image.NewRGBA(image.Rect(0, 0, charW*len(code)+4, charH+4))
+	draw.Draw(small, small.Bounds(), image.White, image.Point{}, draw.Src)
+	for i, r := range code {
+		col := color.RGBA{uint8(randIndex(120)), uint8(randIndex(120)), uint8(randIndex(120)), 255}
+		d := &font.Drawer{
+			Dst:  small,
+			Src:  image.NewUniform(col),
+			Face: face,
+			Dot:  fixed.P(2+i*charW+randIndex(2), 13+randIndex(4)),
+		}
+		d.DrawString(string(r))
+	}
+
+	b := small.Bounds()
+	img := image.NewRGBA(image.Rect(0, 0, b.Dx()*captchaScale, b.Dy()*captchaScale))
+	for y := 0; y < img.Bounds().Dy(); y++ {
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			img.Set(x, y, small.At(x/captchaScale, y/captchaScale))
+		}
+	}
+
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	for i := 0; i < w*h/20; i++ {
+		img.Set(randIndex(w), randIndex(h), color.RGBA{uint8(randIndex(256)), uint8(randIndex(256)), uint8(randIndex(256)), 255})
+	}
+	for i := 0; i < 3; i++ {
+		col := color.RGBA{uint8(randIndex(160)), uint8(randIndex(160)), uint8(randIndex(160)), 255}
+		y0, y1 := randIndex(h), randIndex(h)
+		for x := 0; x < w; x++ {
+			img.Set(x, y0+(y1-y0)*x/w, col)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := png.Encode(&out, img); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func captchaCode(t *testing.T, id string) string {
+	captchaMu.Lock()
+	defer captchaMu.Unlock()
+	e, ok := captchaStore[id]
+	require.True(t, ok)
+	return e.code
+}
+
+func TestNewCaptcha_PNG(t *testing.T) {
+	id, img, err := NewCaptcha()
+	require.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	decoded, err := png.Decode(bytes.NewReader(img))
+	require.NoError(t, err)
+	assert.Greater(t, decoded.Bounds().Dx(), 100)
+
+	code := captchaCode(t, id)
+	assert.Len(t, code, captchaLength)
+	for _, r := range code {
+		assert.True(t, strings.ContainsRune(captchaChars, r))
+	}
+}
+
+func TestVerifyCaptcha(t *testing.T) {
+	id, _, err := NewCaptcha()
+	require.NoError(t, err)
+	code := captchaCode(t, id)
+
+	// 忽略大小写，且只能用一次
+	assert.True(t, VerifyCaptcha(id, strings.ToLower(code)))
+	assert.False(t, VerifyCaptcha(id, code))
+
+	// 猜错一次即作废
+	id, _, err = NewCaptcha()
+	require.NoError(t, err)
+	code = captchaCode(t, id)
+	assert.False(t, VerifyCaptcha(id, "0000"))
+	assert.False(t, VerifyCaptcha(id, code))
+
+	assert.False(t, VerifyCaptcha("", ""))
+	assert.False(t, VerifyCaptcha("missing", "ABCD"))
+}
+
+func TestVerifyCaptcha_Expired(t *testing.T) {
+	id, _, err := NewCaptcha()
+	require.NoError(t, err)
+	captchaMu.Lock()
+	e := captchaStore[id]
+	e.expires = time.Now().Add(-time.Second)
+	captchaStore[id] = e
+	captchaMu.Unlock()
+
+	assert.False(t, VerifyCaptcha(id, e.code))
+}
+
+func TestLoginGuard(t *testing.T) {
+	g := NewLoginGuard(2, time.Minute)
+	assert.False(t, g.CaptchaRequired("alice", "1.1.1.1"))
+
+	assert.False(t, g.RecordFailure("Alice", "1.1.1.1"))
+	assert.True(t, g.RecordFailure("alice", "2.2.2.2"))
+	// 账号达到阈值：换 IP 也需要验证码（用户名不区分大小写）
+	assert.True(t, g.CaptchaRequired("ALICE", "3.3.3.3"))
+	assert.False(t, g.CaptchaRequired("bob", "3.3.3.3"))
+
+	g.Reset("alice", "2.2.2.2")
+	assert.False(t, g.CaptchaRequired("alice", "3.3.3.3"))
+}
+
+func TestLoginGuard_WindowExpires(t *testing.T) {
+	g := NewLoginGuard(1, time.Minute)
+	g.RecordFailure("carol", "1.1.1.1")
+	require.True(t, g.CaptchaRequired("carol", "9.9.9.9"))
+
+	g.mu.Lock()
+	for k, f := range g.failures {
+		f.last = time.Now().Add(-2 * time.Minute)
+		g.failures[k] = f
+	}
+	g.mu.Unlock()
+	assert.False(t, g.CaptchaRequired("carol", "9.9.9.9"))
+}
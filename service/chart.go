@@ -0,0 +1,365 @@
+package service
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"io"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// 统计图类型
+const (
+	ChartTypePie = "pie"
+	ChartTypeBar = "bar"
+)
+
+// 统计图尺寸（像素）
+const (
+	DefaultChartWidth  = 800
+	DefaultChartHeight = 600
+	MinChartSize       = 200
+	MaxChartSize       = 2000
+)
+
+const (
+	maxChartItems   = 10 // 超出部分合并为"其他"
+	chartMargin     = 20
+	chartFontSize   = 14
+	chartSwatchSize = 12
+	chartOtherLabel = "其他"
+	chartOtherColor = "#94a3b8"
+)
+
+// defaultChartPalette 类别未配置颜色或颜色无效时按序取用
+var defaultChartPalette = []string{
+	"#ef4444", "#f97316", "#eab308", "#22c55e", "#06b6d4",
+	"#3b82f6", "#8b5cf6", "#ec4899", "#14b8a6", "#64748b",
+}
+
+var (
+	chartTextColor = color.RGBA{0x33, 0x41, 0x55, 0xff}
+	chartGrayColor = color.RGBA{0xe2, 0xe8, 0xf0, 0xff}
+)
+
+// ChartItem 统计图中的一项
+type ChartItem struct {
+	Label string
+	Value float64
+	Color string // 十六进制颜色，如 #ef4444
+}
+
+// ChartOptions 统计图渲染参数
+type ChartOptions struct {
+	Type     string // pie / bar
+	Width    int
+	Height   int
+	Title    string
+	FontPath string // TTF/OTF 字体文件；为空时使用内置 ASCII 字体，无法显示的类别名以序号代替
+}
+
+var (
+	chartFontsMu sync.Mutex
+	chartFonts   = map[string]*opentype.Font{}
+)
+
+// chartFontFace 加载字体（解析结果按路径缓存，加载失败时回退到内置字体）
+func chartFontFace(path string) font.Face {
+	if path == "" {
+		return basicfont.Face7x13
+	}
+	chartFontsMu.Lock()
+	f, ok := chartFonts[path]
+	if !ok {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			f, err = opentype.Parse(data)
+		}
+		if err != nil {
+			log.Printf("加载统计图字体失败，使用内置字体: %v", err)
+			f = nil
+		}
+		chartFonts[path] = f
+	}
+	chartFontsMu.Unlock()
+	if f == nil {
+		return basicfont.Face7x13
+	}
+
+	// Face 内部有缓冲区，不可并发使用，每次渲染新建
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{Size: chartFontSize, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return basicfont.Face7x13
+	}
+	return face
+}
+
+// canRender 字体是否包含文本中的全部字符
+func canRender(face font.Face, s string) bool {
+	for _, r := range s {
+		if _, _, _, _, ok := face.Glyph(fixed.Point26_6{}, r); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHexColor 解析 #rrggbb / #rgb 颜色
+func parseHexColor(s string) (color.RGBA, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, true
+}
+
+// chartSlice 渲染用的一项（已确定颜色和显示名）
+type chartSlice struct {
+	label string
+	value float64
+	color color.RGBA
+}
+
+// prepareChartSlices 过滤非正数项，超出上限的合并为"其他"，并确定颜色和显示名
+func prepareChartSlices(items []ChartItem, face font.Face) []chartSlice {
+	var positive []ChartItem
+	for _, it := range items {
+		if it.Value > 0 {
+			positive = append(positive, it)
+		}
+	}
+	if len(positive) > maxChartItems {
+		other := ChartItem{Label: chartOtherLabel, Color: chartOtherColor}
+		for _, it := range positive[maxChartItems-1:] {
+			other.Value += it.Value
+		}
+		positive = append(positive[:maxChartItems-1:maxChartItems-1], other)
+	}
+
+	slices := make([]chartSlice, len(positive))
+	for i, it := range positive {
+		col, ok := parseHexColor(it.Color)
+		if !ok {
+			col, _ = parseHexColor(defaultChartPalette[i%len(defaultChartPalette)])
+		}
+		label := it.Label
+		if !canRender(face, label) {
+			if label == chartOtherLabel {
+				label = "Others"
+			} else {
+				label = "#" + strconv.Itoa(i+1)
+			}
+		}
+		slices[i] = chartSlice{label: label, value: it.Value, color: col}
+	}
+	return slices
+}
+
+// RenderChartPNG 渲染饼图或柱状图并以 PNG 写出；没有可绘制的数据时输出"暂无数据"占位图
+func RenderChartPNG(w io.Writer, items []ChartItem, opts ChartOptions) error {
+	if opts.Width <= 0 {
+		opts.Width = DefaultChartWidth
+	}
+	if opts.Height <= 0 {
+		opts.Height = DefaultChartHeight
+	}
+	face := chartFontFace(opts.FontPath)
+	slices := prepareChartSlices(items, face)
+
+	img := image.NewRGBA(image.Rect(0, 0, opts.Width, opts.Height))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+
+	lineHeight := face.Metrics().Height.Ceil()
+	top := chartMargin
+	if opts.Title != "" && canRender(face, opts.Title) {
+		drawChartText(img, face, chartMargin, top+face.Metrics().Ascent.Ceil(), opts.Title, chartTextColor)
+		top += lineHeight + 10
+	}
+	plot := image.Rect(chartMargin, top, opts.Width-chartMargin, opts.Height-chartMargin)
+
+	if len(slices) == 0 {
+		drawChartEmpty(img, face, plot, opts.Type)
+		return png.Encode(w, img)
+	}
+
+	var total float64
+	for _, s := range slices {
+		total += s.value
+	}
+	legend := make([]string, len(slices))
+	legendWidth := 0
+	for i, s := range slices {
+		legend[i] = s.label + "  " + formatChartAmount(s.value) + " (" + strconv.FormatFloat(s.value/total*100, 'f', 1, 64) + "%)"
+		if tw := font.MeasureString(face, legend[i]).Ceil(); tw > legendWidth {
+			legendWidth = tw
+		}
+	}
+	legendWidth += chartSwatchSize + 6
+	if legendWidth > opts.Width/2 {
+		legendWidth = opts.Width / 2
+	}
+	plot.Max.X -= legendWidth + chartMargin
+
+	if opts.Type == ChartTypeBar {
+		drawBarChart(img, face, plot, slices)
+	} else {
+		drawPieChart(img, face, plot, slices, total)
+	}
+
+	// 图例：色块 + 类别 金额 (占比)
+	rowHeight := lineHeight
+	if rowHeight < chartSwatchSize {
+		rowHeight = chartSwatchSize
+	}
+	rowHeight += 6
+	x := plot.Max.X + chartMargin
+	for i, s := range slices {
+		y := top + i*rowHeight
+		if y+rowHeight > opts.Height-chartMargin {
+			break
+		}
+		draw.Draw(img, image.Rect(x, y, x+chartSwatchSize, y+chartSwatchSize), image.NewUniform(s.color), image.Point{}, draw.Src)
+		drawChartText(img, face, x+chartSwatchSize+6, y+chartSwatchSize-1, legend[i], chartTextColor)
+	}
+
+	return png.Encode(w, img)
+}
+
+// drawPieChart 从 12 点方向顺时针绘制饼图，占比不低于 5% 的扇区标注百分比
+func drawPieChart(img *image.RGBA, face font.Face, plot image.Rectangle, slices []chartSlice, total float64) {
+	cx, cy := (plot.Min.X+plot.Max.X)/2, (plot.Min.Y+plot.Max.Y)/2
+	r := plot.Dx()
+	if plot.Dy() < r {
+		r = plot.Dy()
+	}
+	r /= 2
+
+	// 各扇区的累计占比上界
+	bounds := make([]float64, len(slices))
+	var acc float64
+	for i, s := range slices {
+		acc += s.value / total
+		bounds[i] = acc
+	}
+	bounds[len(bounds)-1] = 1
+
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			dx, dy := float64(x-cx), float64(y-cy)
+			if dx*dx+dy*dy > float64(r*r) {
+				continue
+			}
+			angle := math.Atan2(dx, -dy)
+			if angle < 0 {
+				angle += 2 * math.Pi
+			}
+			frac := angle / (2 * math.Pi)
+			for i, b := range bounds {
+				if frac <= b {
+					img.SetRGBA(x, y, slices[i].color)
+					break
+				}
+			}
+		}
+	}
+
+	start := 0.0
+	for i, s := range slices {
+		pct := s.value / total
+		if pct >= 0.05 {
+			mid := (start + bounds[i]) / 2 * 2 * math.Pi
+			label := strconv.FormatFloat(pct*100, 'f', 0, 64) + "%"
+			lx := cx + int(0.65*float64(r)*math.Sin(mid)) - font.MeasureString(face, label).Ceil()/2
+			ly := cy - int(0.65*float64(r)*math.Cos(mid)) + face.Metrics().Ascent.Ceil()/2
+			drawChartText(img, face, lx, ly, label, color.White)
+		}
+		start = bounds[i]
+	}
+}
+
+// drawBarChart 按金额绘制柱状图，柱顶标注金额
+func drawBarChart(img *image.RGBA, face font.Face, plot image.Rectangle, slices []chartSlice) {
+	maxValue := 0.0
+	for _, s := range slices {
+		if s.value > maxValue {
+			maxValue = s.value
+		}
+	}
+	lineHeight := face.Metrics().Height.Ceil()
+	usable := plot.Dy() - lineHeight - 4 // 预留柱顶金额的高度
+	slot := plot.Dx() / len(slices)
+	barWidth := slot * 3 / 5
+	if barWidth < 1 {
+		barWidth = 1
+	}
+
+	for i, s := range slices {
+		h := int(s.value / maxValue * float64(usable))
+		x := plot.Min.X + i*slot + (slot-barWidth)/2
+		draw.Draw(img, image.Rect(x, plot.Max.Y-h, x+barWidth, plot.Max.Y), image.NewUniform(s.color), image.Point{}, draw.Src)
+
+		label := formatChartAmount(s.value)
+		if tw := font.MeasureString(face, label).Ceil(); tw <= slot {
+			drawChartText(img, face, x+barWidth/2-tw/2, plot.Max.Y-h-4, label, chartTextColor)
+		}
+	}
+	// 横轴
+	draw.Draw(img, image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), image.NewUniform(chartTextColor), image.Point{}, draw.Src)
+}
+
+// drawChartEmpty 无数据占位：灰色圆（饼图）或横轴（柱状图），居中提示文字
+func drawChartEmpty(img *image.RGBA, face font.Face, plot image.Rectangle, chartType string) {
+	cx, cy := (plot.Min.X+plot.Max.X)/2, (plot.Min.Y+plot.Max.Y)/2
+	if chartType == ChartTypeBar {
+		draw.Draw(img, image.Rect(plot.Min.X, plot.Max.Y, plot.Max.X, plot.Max.Y+1), image.NewUniform(chartGrayColor), image.Point{}, draw.Src)
+	} else {
+		r := plot.Dx()
+		if plot.Dy() < r {
+			r = plot.Dy()
+		}
+		r /= 2
+		for y := cy - r; y <= cy+r; y++ {
+			for x := cx - r; x <= cx+r; x++ {
+				if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r {
+					img.SetRGBA(x, y, chartGrayColor)
+				}
+			}
+		}
+	}
+
+	text := "暂无数据"
+	if !canRender(face, text) {
+		text = "No data"
+	}
+	tw := font.MeasureString(face, text).Ceil()
+	drawChartText(img, face, cx-tw/2, cy+face.Metrics().Ascent.Ceil()/2, text, chartTextColor)
+}
+
+// drawChartText 在基线 (x, y) 处绘制文字
+func drawChartText(img draw.Image, face font.Face, x, y int, s string, col color.Color) {
+	d := &font.Drawer{Dst: img, Src: image.NewUniform(col), Face: face, Dot: fixed.P(x, y)}
+	d.DrawString(s)
+}
+
+// formatChartAmount 金额保留两位小数
+func formatChartAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
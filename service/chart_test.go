@@ -0,0 +1,110 @@
+package service
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/basicfont"
+)
+
+func TestParseHexColor(t *testing.T) {
+	c, ok := parseHexColor("#ef4444")
+	assert.True(t, ok)
+	assert.Equal(t, color.RGBA{0xef, 0x44, 0x44, 0xff}, c)
+
+	c, ok = parseHexColor("#0f0")
+	assert.True(t, ok)
+	assert.Equal(t, color.RGBA{0x00, 0xff, 0x00, 0xff}, c)
+
+	for _, s := range []string{"", "#12345", "red", "#gggggg"} {
+		_, ok := parseHexColor(s)
+		assert.Falsef(t, ok, "parseHexColor(%q)", s)
+	}
+}
+
+func TestPrepareChartSlices(t *testing.T) {
+	var items []ChartItem
+	for i := 0; i < 12; i++ {
+		items = append(items, ChartItem{Label: "c" + strconv.Itoa(i), Value: float64(100 - i), Color: "#112233"})
+	}
+	items[0].Label = "餐饮"
+	items[1].Color = "invalid"
+	items = append(items, ChartItem{Label: "refund", Value: -5})
+
+	slices := prepareChartSlices(items, basicfont.Face7x13)
+	require.Len(t, slices, maxChartItems)
+
+	// 内置字体无中文字形，以序号代替
+	assert.Equal(t, "#1", slices[0].label)
+	assert.Equal(t, "c2", slices[2].label)
+	// 无效颜色取默认色板
+	palette, _ := parseHexColor(defaultChartPalette[1])
+	assert.Equal(t, palette, slices[1].color)
+	// 第 10 项起合并为"其他"，负数项被忽略
+	assert.Equal(t, "Others", slices[9].label)
+	assert.Equal(t, float64(91+90+89), slices[9].value)
+}
+
+// countColor 统计图片中指定颜色的像素数
+func countColor(img image.Image, c color.RGBA) int {
+	n := 0
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if color.RGBAModel.Convert(img.At(x, y)) == c {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+func renderChart(t *testing.T, items []ChartItem, opts ChartOptions) image.Image {
+	var buf bytes.Buffer
+	require.NoError(t, RenderChartPNG(&buf, items, opts))
+	img, err := png.Decode(&buf)
+	require.NoError(t, err)
+	assert.Equal(t, opts.Width, img.Bounds().Dx())
+	assert.Equal(t, opts.Height, img.Bounds().Dy())
+	return img
+}
+
+func TestRenderChartPNG_Pie(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	img := renderChart(t, []ChartItem{
+		{Label: "food", Value: 300, Color: "#ff0000"},
+		{Label: "taxi", Value: 100, Color: "#0000ff"},
+	}, ChartOptions{Type: ChartTypePie, Width: 400, Height: 300, Title: "Expenses"})
+
+	// 扇区面积与金额成比例（3:1）
+	ratio := float64(countColor(img, red)) / float64(countColor(img, blue))
+	assert.InDelta(t, 3.0, ratio, 0.3)
+}
+
+func TestRenderChartPNG_Bar(t *testing.T) {
+	red := color.RGBA{0xff, 0, 0, 0xff}
+	blue := color.RGBA{0, 0, 0xff, 0xff}
+	img := renderChart(t, []ChartItem{
+		{Label: "food", Value: 300, Color: "#ff0000"},
+		{Label: "taxi", Value: 100, Color: "#0000ff"},
+	}, ChartOptions{Type: ChartTypeBar, Width: 500, Height: 300})
+
+	assert.Greater(t, countColor(img, red), 2*countColor(img, blue))
+	assert.Greater(t, countColor(img, blue), 0)
+}
+
+func TestRenderChartPNG_Empty(t *testing.T) {
+	img := renderChart(t, nil, ChartOptions{Type: ChartTypePie, Width: 300, Height: 200})
+	assert.Greater(t, countColor(img, chartGrayColor), 0)
+
+	// 仅有非正数项时同样输出占位图
+	img = renderChart(t, []ChartItem{{Label: "refund", Value: -10, Color: "#ff0000"}}, ChartOptions{Type: ChartTypeBar, Width: 300, Height: 200})
+	assert.Equal(t, 0, countColor(img, color.RGBA{0xff, 0, 0, 0xff}))
+}
@@ -0,0 +1,185 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+)
+
+// dailyDigestInterval 扫描间隔：用户设定的发送时刻到达后，在下一次扫描时发送
+const dailyDigestInterval = 5 * time.Minute
+
+// digestSender 发送每日汇总邮件，由 EmailService 实现
+type digestSender interface {
+	SendDailyDigest(toEmail string, d DailyDigest) error
+}
+
+// digestCategoryRow 按类别与币种分组的原币汇总
+type digestCategoryRow struct {
+	Category string
+	Currency string
+	Total    float64
+	Count    int
+}
+
+// sumDigestExpenses 汇总用户 [start, end) 内的消费（不含内部转账类别），按类别折算为本位币；
+// 缺少汇率的币种不计入
+func sumDigestExpenses(userID uint, start, end time.Time, rates ExchangeRates) (map[string]float64, int, error) {
+	transferNames := database.DB.Model(&models.ExpenseCategory{}).Select("name").Where("is_transfer = ?", true)
+	var rows []digestCategoryRow
+	if err := database.DB.Model(&models.Expense{}).
+		Select("category, currency, COALESCE(SUM(amount), 0) AS total, COUNT(*) AS count").
+		Where("user_id = ? AND expense_time >= ? AND expense_time < ?", userID, start, end).
+		Where("category NOT IN (?)", transferNames).
+		Group("category, currency").Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	byCategory := map[string]float64{}
+	count := 0
+	for _, r := range rows {
+		amount, ok := rates.ToBase(r.Total, r.Currency)
+		if !ok {
+			continue
+		}
+		byCategory[r.Category] += amount
+		count += r.Count
+	}
+	return byCategory, count, nil
+}
+
+// digestLocation 用户每日汇总所用时区；未设置或无法解析时为服务器时区
+func digestLocation(user models.User) *time.Location {
+	if user.DigestTimezone == "" {
+		return time.Local
+	}
+	loc, err := time.LoadLocation(user.DigestTimezone)
+	if err != nil {
+		return time.Local
+	}
+	return loc
+}
+
+// BuildDailyDigest 汇总用户当天 0 点到 now 的消费、消费最多的类别，以及前 7 天的日均消费，
+// “当天”按用户的汇总时区划分
+func BuildDailyDigest(user models.User, now time.Time, rates ExchangeRates) (DailyDigest, error) {
+	now = now.In(digestLocation(user))
+	dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	d := DailyDigest{
+		Username: user.Username,
+		Date:     dayStart.Format("2006-01-02"),
+		Currency: BaseCurrency(),
+	}
+
+	today, count, err := sumDigestExpenses(user.ID, dayStart, now, rates)
+	if err != nil {
+		return d, err
+	}
+	d.Count = count
+	for category, amount := range today {
+		d.Total += amount
+		if amount > d.TopCategoryAmount || (amount == d.TopCategoryAmount && category < d.TopCategory) {
+			d.TopCategory, d.TopCategoryAmount = category, amount
+		}
+	}
+
+	week, _, err := sumDigestExpenses(user.ID, dayStart.AddDate(0, 0, -7), dayStart, rates)
+	if err != nil {
+		return d, err
+	}
+	for _, amount := range week {
+		d.WeekAverage += amount
+	}
+	d.WeekAverage /= 7
+	return d, nil
+}
+
+// dueDigestUsers 当前应发送汇总的用户：已订阅、状态正常、邮箱已验证、已到设定时刻且今天尚未发送。
+// 发送时刻与“今天”按各用户的汇总时区判断，因此在查出订阅用户后逐个筛选
+func dueDigestUsers(now time.Time) ([]models.User, error) {
+	var users []models.User
+	if err := database.DB.Select("id", "username", "email", "digest_hour", "digest_timezone", "digest_last_date").
+		Where("digest_enabled = ? AND email_verified = ? AND email <> '' AND status = ?", true, true, models.UserStatusActive).
+		Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+	due := users[:0]
+	for _, user := range users {
+		local := now.In(digestLocation(user))
+		if local.Hour() >= user.DigestHour && user.DigestLastDate != local.Format("2006-01-02") {
+			due = append(due, user)
+		}
+	}
+	return due, nil
+}
+
+// claimDigest 把用户今天的汇总标记为已发送；返回 false 表示已被其他实例或上一轮扫描处理。
+// 发送前标记，投递失败也不在当天重试，失败原因见邮件发送日志
+func claimDigest(userID uint, date string) (bool, error) {
+	res := database.DB.Model(&models.User{}).
+		Where("id = ? AND digest_last_date <> ?", userID, date).
+		UpdateColumn("digest_last_date", date)
+	return res.RowsAffected > 0, res.Error
+}
+
+// RunDailyDigests 给到达发送时刻的用户发送每日消费汇总，每发送 batchSize 封暂停 batchInterval，
+// 避免触发 SMTP 服务商的频率限制；返回成功发送的封数。单个用户失败只记日志，不影响其他用户
+func RunDailyDigests(now time.Time, sender digestSender, batchSize int, batchInterval time.Duration) (int, error) {
+	users, err := dueDigestUsers(now)
+	if err != nil || len(users) == 0 {
+		return 0, err
+	}
+	rates, err := LoadExchangeRates()
+	if err != nil {
+		return 0, err
+	}
+
+	sent, attempted := 0, 0
+	for _, user := range users {
+		ok, err := claimDigest(user.ID, now.In(digestLocation(user)).Format("2006-01-02"))
+		if err != nil {
+			log.Printf("标记用户 %d 的每日汇总失败: %v", user.ID, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		digest, err := BuildDailyDigest(user, now, rates)
+		if err != nil {
+			log.Printf("生成用户 %d 的每日汇总失败: %v", user.ID, err)
+			continue
+		}
+		if attempted > 0 && batchSize > 0 && attempted%batchSize == 0 {
+			time.Sleep(batchInterval)
+		}
+		attempted++
+		if err := sender.SendDailyDigest(user.Email, digest); err != nil {
+			log.Printf("发送用户 %d 的每日汇总失败: %v", user.ID, err)
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+// StartDailyDigestScheduler 启动每日消费汇总定时任务，邮件服务未启用时不启动
+func StartDailyDigestScheduler(cfg *config.EmailConfig) {
+	if !cfg.Enabled {
+		return
+	}
+	email := NewEmailService(cfg)
+	interval := time.Duration(cfg.DigestBatchIntervalSeconds) * time.Second
+	go func() {
+		ticker := time.NewTicker(dailyDigestInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if n, err := RunDailyDigests(time.Now(), email, cfg.DigestBatchSize, interval); err != nil {
+				log.Printf("发送每日消费汇总失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已发送每日消费汇总 %d 封", n)
+			}
+		}
+	}()
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeDigestSender struct {
+	sent map[string]DailyDigest
+}
+
+func (f *fakeDigestSender) SendDailyDigest(toEmail string, d DailyDigest) error {
+	f.sent[toEmail] = d
+	return nil
+}
+
+// sameInstant 按时刻（忽略时区表示）匹配时间参数
+type sameInstant time.Time
+
+func (s sameInstant) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	return ok && t.Equal(time.Time(s))
+}
+
+func TestDigestComparison(t *testing.T) {
+	assert.Equal(t, "今天和过去 7 天都没有消费记录", digestComparison(DailyDigest{}))
+	assert.Equal(t, "过去 7 天没有消费记录", digestComparison(DailyDigest{Total: 20}))
+	assert.Equal(t, "比近 7 天日均（100.00）高 50%", digestComparison(DailyDigest{Total: 150, WeekAverage: 100}))
+	assert.Equal(t, "比近 7 天日均（100.00）低 25%", digestComparison(DailyDigest{Total: 75, WeekAverage: 100}))
+	assert.Equal(t, "与近 7 天日均（100.00）基本持平", digestComparison(DailyDigest{Total: 100.2, WeekAverage: 100}))
+}
+
+func TestGenerateDailyDigestBody(t *testing.T) {
+	s := NewEmailService(nil)
+	body := s.generateDailyDigestBody(DailyDigest{
+		Username: "<alice>", Date: "2024-01-15", Currency: "CNY",
+		Total: 130, Count: 3, TopCategory: "餐饮", TopCategoryAmount: 88, WeekAverage: 100,
+	})
+	assert.Contains(t, body, "&lt;alice&gt;")
+	assert.Contains(t, body, "130.00 CNY")
+	assert.Contains(t, body, "共 3 笔消费")
+	assert.Contains(t, body, "餐饮（88.00）")
+	assert.Contains(t, body, "高 30%")
+	assert.NotContains(t, body, "%!")
+}
+
+func TestRunDailyDigests(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2024, 1, 15, 21, 3, 0, 0, time.Local)
+	// carol 所在时区尚未到发送时刻，dave 今天已发送
+	mock.ExpectQuery("SELECT `id`,`username`,`email`,`digest_hour`,`digest_timezone`,`digest_last_date` FROM `users` WHERE .*digest_enabled = \\? AND email_verified = \\?").
+		WithArgs(true, true, models.UserStatusActive).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "digest_hour", "digest_timezone", "digest_last_date"}).
+			AddRow(1, "alice", "alice@example.com", 21, "", "2024-01-14").
+			AddRow(2, "bob", "bob@example.com", 20, "", "").
+			AddRow(3, "carol", "carol@example.com", 21, "Pacific/Honolulu", "").
+			AddRow(4, "dave", "dave@example.com", 12, "", "2024-01-15"))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}).AddRow("USD", 7.0))
+
+	// alice：标记成功后汇总当天与前 7 天
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `digest_last_date`=\\? WHERE \\(id = \\? AND digest_last_date <> \\?\\)").
+		WithArgs("2024-01-15", 1, "2024-01-15").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT category, currency, COALESCE\\(SUM\\(amount\\), 0\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`").
+		WithArgs(1, time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local), now, true).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("餐饮", "CNY", 60, 2).
+			AddRow("购物", "USD", 10, 1).
+			AddRow("交通", "JPY", 1000, 1)) // 没有汇率，不计入
+	mock.ExpectQuery("SELECT category, currency, COALESCE\\(SUM\\(amount\\), 0\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`").
+		WithArgs(1, time.Date(2024, 1, 8, 0, 0, 0, 0, time.Local), time.Date(2024, 1, 15, 0, 0, 0, 0, time.Local), true).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}).
+			AddRow("餐饮", "CNY", 700, 20))
+
+	// bob：已被其他实例处理
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `digest_last_date`=\\?").
+		WithArgs("2024-01-15", 2, "2024-01-15").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	sender := &fakeDigestSender{sent: map[string]DailyDigest{}}
+	n, err := RunDailyDigests(now, sender, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, sender.sent, 1)
+	d := sender.sent["alice@example.com"]
+	assert.Equal(t, "2024-01-15", d.Date)
+	assert.Equal(t, 130.0, d.Total)
+	assert.Equal(t, 3, d.Count)
+	assert.Equal(t, "购物", d.TopCategory)
+	assert.Equal(t, 70.0, d.TopCategoryAmount)
+	assert.Equal(t, 100.0, d.WeekAverage)
+}
+
+func TestRunDailyDigests_NoDueUsers(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id`,`username`,`email`,`digest_hour`,`digest_timezone`,`digest_last_date` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "digest_hour", "digest_timezone", "digest_last_date"}))
+
+	sender := &fakeDigestSender{sent: map[string]DailyDigest{}}
+	n, err := RunDailyDigests(time.Now(), sender, 20, time.Minute)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDailyDigests_UserTimezone(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	tokyo, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	// UTC 1 月 15 日 14:03 即东京 1 月 15 日 23:03
+	now := time.Date(2024, 1, 15, 14, 3, 0, 0, time.UTC)
+	mock.ExpectQuery("SELECT `id`,`username`,`email`,`digest_hour`,`digest_timezone`,`digest_last_date` FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "digest_hour", "digest_timezone", "digest_last_date"}).
+			AddRow(1, "alice", "alice@example.com", 23, "Asia/Tokyo", "2024-01-14"))
+	mock.ExpectQuery("SELECT \\* FROM `exchange_rates`").
+		WillReturnRows(sqlmock.NewRows([]string{"currency", "rate"}))
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `users` SET `digest_last_date`=\\?").
+		WithArgs("2024-01-15", 1, "2024-01-15").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	dayStart := time.Date(2024, 1, 15, 0, 0, 0, 0, tokyo)
+	mock.ExpectQuery("SELECT category, currency, COALESCE\\(SUM\\(amount\\), 0\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`").
+		WithArgs(1, sameInstant(dayStart), sameInstant(now), true).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}))
+	mock.ExpectQuery("SELECT category, currency, COALESCE\\(SUM\\(amount\\), 0\\) AS total, COUNT\\(\\*\\) AS count FROM `expenses`").
+		WithArgs(1, sameInstant(dayStart.AddDate(0, 0, -7)), sameInstant(dayStart), true).
+		WillReturnRows(sqlmock.NewRows([]string{"category", "currency", "total", "count"}))
+
+	sender := &fakeDigestSender{sent: map[string]DailyDigest{}}
+	n, err := RunDailyDigests(now, sender, 20, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, "2024-01-15", sender.sent["alice@example.com"].Date)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
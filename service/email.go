@@ -2,8 +2,12 @@ package service
 
 import (
 	"fmt"
+	"html"
+	"log"
 
 	"finance/config"
+	"finance/database"
+	"finance/models"
 
 	"gopkg.in/gomail.v2"
 )
@@ -20,14 +24,10 @@ func NewEmailService(cfg *config.EmailConfig) *EmailService {
 
 // SendPasswordResetEmail 发送密码重置邮件
 func (s *EmailService) SendPasswordResetEmail(toEmail, username, resetLink string) error {
-	if !s.cfg.Enabled {
-		return fmt.Errorf("邮件服务未启用，请配置 EMAIL_ENABLED=true")
-	}
-
 	subject := "【记账系统】密码重置"
 	body := s.generateResetEmailBody(username, resetLink)
 
-	return s.sendEmail(toEmail, subject, body)
+	return s.sendEmail(toEmail, subject, models.EmailTypePasswordReset, body)
 }
 
 // generateResetEmailBody 生成重置邮件内容
@@ -80,8 +80,19 @@ func (s *EmailService) generateResetEmailBody(username, resetLink string) string
 `, username, resetLink, resetLink)
 }
 
-// sendEmail 发送邮件
-func (s *EmailService) sendEmail(to, subject, body string) error {
+// sendEmail 发送邮件并记录发送日志（成功或失败原因），emailType 见 models.EmailType* 常量
+func (s *EmailService) sendEmail(to, subject, emailType, body string) error {
+	err := s.deliver(to, subject, body)
+	recordEmailLog(models.NewEmailLog(to, subject, emailType, err))
+	return err
+}
+
+// deliver 通过 SMTP 投递邮件
+func (s *EmailService) deliver(to, subject, body string) error {
+	if !s.cfg.Enabled {
+		return fmt.Errorf("邮件服务未启用，请配置 EMAIL_ENABLED=true")
+	}
+
 	m := gomail.NewMessage()
 	m.SetHeader("From", m.FormatAddress(s.cfg.Username, s.cfg.From))
 	m.SetHeader("To", to)
@@ -97,12 +108,19 @@ func (s *EmailService) sendEmail(to, subject, body string) error {
 	return nil
 }
 
-// SendTestEmail 发送测试邮件
-func (s *EmailService) SendTestEmail(toEmail string) error {
-	if !s.cfg.Enabled {
-		return fmt.Errorf("邮件服务未启用")
+// recordEmailLog 保存发送日志；不依赖请求上下文，异步发送时同样在投递结束后记录实际结果。
+// 日志写入失败只打印，不影响发送结果
+var recordEmailLog = func(l models.EmailLog) {
+	if database.DB == nil {
+		return
+	}
+	if err := database.DB.Create(&l).Error; err != nil {
+		log.Printf("记录邮件发送日志失败: %v", err)
 	}
+}
 
+// SendTestEmail 发送测试邮件
+func (s *EmailService) SendTestEmail(toEmail string) error {
 	subject := "【记账系统】邮件配置测试"
 	body := `
 <!DOCTYPE html>
@@ -115,19 +133,15 @@ func (s *EmailService) SendTestEmail(toEmail string) error {
 </body>
 </html>
 `
-	return s.sendEmail(toEmail, subject, body)
+	return s.sendEmail(toEmail, subject, models.EmailTypeTest, body)
 }
 
 // SendVerificationEmail 发送邮箱验证码邮件
 func (s *EmailService) SendVerificationEmail(toEmail, code, purpose string) error {
-	if !s.cfg.Enabled {
-		return fmt.Errorf("邮件服务未启用，请配置 EMAIL_ENABLED=true")
-	}
-
 	subject := "【记账系统】邮箱验证码"
 	body := s.generateVerificationEmailBody(code, purpose)
 
-	return s.sendEmail(toEmail, subject, body)
+	return s.sendEmail(toEmail, subject, models.EmailTypeVerification, body)
 }
 
 // generateVerificationEmailBody 生成验证码邮件内容
@@ -186,14 +200,10 @@ func (s *EmailService) generateVerificationEmailBody(code, purpose string) strin
 
 // SendAppPasswordResetEmail 发送 App 端密码重置验证码邮件
 func (s *EmailService) SendAppPasswordResetEmail(toEmail, username, code string) error {
-	if !s.cfg.Enabled {
-		return fmt.Errorf("邮件服务未启用，请配置 EMAIL_ENABLED=true")
-	}
-
 	subject := "【记账系统】密码重置验证码"
 	body := s.generateAppResetEmailBody(username, code)
 
-	return s.sendEmail(toEmail, subject, body)
+	return s.sendEmail(toEmail, subject, models.EmailTypeAppPasswordReset, body)
 }
 
 // generateAppResetEmailBody 生成 App 端密码重置邮件内容
@@ -243,3 +253,131 @@ func (s *EmailService) generateAppResetEmailBody(username, code string) string {
 `, username, code)
 }
 
+// SendInitialPasswordEmail 发送账号开通及初始密码邮件（后台批量导入用户时使用）
+func (s *EmailService) SendInitialPasswordEmail(toEmail, username, password string) error {
+	subject := "【记账系统】账号开通通知"
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: 'Microsoft YaHei', Arial, sans-serif; padding: 20px;">
+    <h2>💰 记账系统账号已开通</h2>
+    <p>您好，管理员已为您创建账号：</p>
+    <p>用户名：<strong>%s</strong></p>
+    <p>初始密码：<strong style="font-family: 'Courier New', monospace;">%s</strong></p>
+    <p style="color: #856404;">⚠️ 请登录后尽快修改密码。</p>
+    <p style="color: #666;">—— 记账系统</p>
+</body>
+</html>
+`, html.EscapeString(username), html.EscapeString(password))
+
+	return s.sendEmail(toEmail, subject, models.EmailTypeInitialPassword, body)
+}
+
+// SendNotificationEmail 发送业务通知邮件（由 EmailNotifier 调用），content 按纯文本处理，换行保留
+func (s *EmailService) SendNotificationEmail(toEmail, title, content string) error {
+	subject := "【记账系统】" + title
+	body := fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head><meta charset="UTF-8"></head>
+<body style="font-family: 'Microsoft YaHei', Arial, sans-serif; padding: 20px;">
+    <h2>%s</h2>
+    <p style="white-space: pre-line; line-height: 1.8;">%s</p>
+    <p style="color: #666;">—— 记账系统</p>
+</body>
+</html>
+`, html.EscapeString(title), html.EscapeString(content))
+
+	return s.sendEmail(toEmail, subject, models.EmailTypeNotification, body)
+}
+
+// DailyDigest 每日消费汇总邮件内容，金额均已折算为本位币
+type DailyDigest struct {
+	Username          string
+	Date              string // 汇总日期（2006-01-02）
+	Currency          string // 本位币
+	Total             float64
+	Count             int
+	TopCategory       string // 当天消费最多的类别，没有消费时为空
+	TopCategoryAmount float64
+	WeekAverage       float64 // 前 7 天（不含当天）的日均消费
+}
+
+// digestComparison 与近 7 天日均相比的文案
+func digestComparison(d DailyDigest) string {
+	if d.WeekAverage <= 0 {
+		if d.Total <= 0 {
+			return "今天和过去 7 天都没有消费记录"
+		}
+		return "过去 7 天没有消费记录"
+	}
+	pct := (d.Total - d.WeekAverage) / d.WeekAverage * 100
+	switch {
+	case pct >= 0.5:
+		return fmt.Sprintf("比近 7 天日均（%.2f）高 %.0f%%", d.WeekAverage, pct)
+	case pct <= -0.5:
+		return fmt.Sprintf("比近 7 天日均（%.2f）低 %.0f%%", d.WeekAverage, -pct)
+	default:
+		return fmt.Sprintf("与近 7 天日均（%.2f）基本持平", d.WeekAverage)
+	}
+}
+
+// SendDailyDigest 发送每日消费汇总邮件
+func (s *EmailService) SendDailyDigest(toEmail string, d DailyDigest) error {
+	subject := fmt.Sprintf("【记账系统】%s 消费汇总", d.Date)
+	return s.sendEmail(toEmail, subject, models.EmailTypeDailyDigest, s.generateDailyDigestBody(d))
+}
+
+// generateDailyDigestBody 生成每日消费汇总邮件内容
+func (s *EmailService) generateDailyDigestBody(d DailyDigest) string {
+	topCategory := "—"
+	if d.TopCategory != "" {
+		topCategory = fmt.Sprintf("%s（%.2f）", html.EscapeString(d.TopCategory), d.TopCategoryAmount)
+	}
+	return fmt.Sprintf(`
+<!DOCTYPE html>
+<html>
+<head>
+    <meta charset="UTF-8">
+    <style>
+        body { font-family: 'Microsoft YaHei', Arial, sans-serif; background: #f5f5f5; margin: 0; padding: 20px; }
+        .container { max-width: 600px; margin: 0 auto; background: #fff; border-radius: 12px; overflow: hidden; box-shadow: 0 4px 20px rgba(0,0,0,0.1); }
+        .header { background: linear-gradient(135deg, #2563eb, #1d4ed8); color: white; padding: 30px; text-align: center; }
+        .header h1 { margin: 0; font-size: 24px; }
+        .content { padding: 40px 30px; }
+        .content p { color: #333; line-height: 1.8; margin: 0 0 20px; }
+        .total-box { background: linear-gradient(135deg, #eff6ff, #dbeafe); border-radius: 12px; padding: 30px; text-align: center; margin: 30px 0; }
+        .total { font-size: 36px; font-weight: bold; color: #1d4ed8; font-family: 'Courier New', monospace; }
+        .total-label { color: #6c757d; font-size: 14px; margin-top: 8px; }
+        .stats { width: 100%%; border-collapse: collapse; margin: 20px 0; }
+        .stats td { padding: 12px 0; border-bottom: 1px solid #eee; color: #333; }
+        .stats td.label { color: #6c757d; width: 40%%; }
+        .footer { background: #f8f9fa; padding: 20px 30px; text-align: center; color: #6c757d; font-size: 12px; }
+    </style>
+</head>
+<body>
+    <div class="container">
+        <div class="header">
+            <h1>💰 记账系统</h1>
+        </div>
+        <div class="content">
+            <p>尊敬的 <strong>%s</strong>，您好！以下是您 %s 的消费汇总：</p>
+            <div class="total-box">
+                <div class="total">%.2f %s</div>
+                <div class="total-label">共 %d 笔消费</div>
+            </div>
+            <table class="stats">
+                <tr><td class="label">消费最多的类别</td><td>%s</td></tr>
+                <tr><td class="label">与近 7 天相比</td><td>%s</td></tr>
+            </table>
+        </div>
+        <div class="footer">
+            <p>此邮件由系统自动发送，请勿回复；可在 App 设置中关闭每日汇总</p>
+            <p>© 记账系统 - 您的个人财务管理助手</p>
+        </div>
+    </div>
+</body>
+</html>
+`, html.EscapeString(d.Username), d.Date, d.Total, html.EscapeString(d.Currency), d.Count, topCategory, digestComparison(d))
+}
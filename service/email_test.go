@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"finance/config"
+	"finance/models"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -51,3 +52,19 @@ func TestGenerateAppResetEmailBody(t *testing.T) {
 	assert.Contains(t, body, "888999")
 	assert.Contains(t, body, "密码重置")
 }
+
+func TestSendEmail_RecordsFailure(t *testing.T) {
+	var logs []models.EmailLog
+	old := recordEmailLog
+	recordEmailLog = func(l models.EmailLog) { logs = append(logs, l) }
+	defer func() { recordEmailLog = old }()
+
+	err := newTestEmailService().SendVerificationEmail("alice@example.com", "123456", "register")
+	assert.Error(t, err)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, "alice@example.com", logs[0].To)
+		assert.Equal(t, models.EmailTypeVerification, logs[0].Type)
+		assert.Equal(t, models.EmailStatusFailed, logs[0].Status)
+		assert.Contains(t, logs[0].Error, "邮件服务未启用")
+	}
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"finance/config"
+	"finance/database"
+	"finance/models"
+)
+
+// BaseCurrency 本位币（currency.base）
+func BaseCurrency() string {
+	if config.GlobalConfig == nil || config.GlobalConfig.Currency.Base == "" {
+		return config.DefaultBaseCurrency
+	}
+	return config.GlobalConfig.Currency.Base
+}
+
+// ExchangeRates 币种 → 1 单位折合多少本位币，本位币恒为 1
+type ExchangeRates map[string]float64
+
+// LoadExchangeRates 读取汇率表
+func LoadExchangeRates() (ExchangeRates, error) {
+	var list []models.ExchangeRate
+	if err := database.DB.Find(&list).Error; err != nil {
+		return nil, err
+	}
+	rates := ExchangeRates{}
+	for _, r := range list {
+		rates[r.Currency] = r.Rate
+	}
+	rates[BaseCurrency()] = 1
+	return rates, nil
+}
+
+// ToBase 把原币金额折算为本位币；没有该币种汇率时 ok 为 false。
+// 升级前的记录币种为空，按本位币处理
+func (r ExchangeRates) ToBase(amount float64, currency string) (float64, bool) {
+	if currency == "" {
+		currency = BaseCurrency()
+	}
+	rate, ok := r[currency]
+	if !ok {
+		return 0, false
+	}
+	return amount * rate, true
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ExpenseReportRow PDF 报表中的一条消费记录
+type ExpenseReportRow struct {
+	Time        time.Time
+	Username    string
+	Category    string
+	Description string
+	Amount      float64
+	Currency    string
+}
+
+// ExpenseReportSubtotal 类别小计（已折算为本位币）
+type ExpenseReportSubtotal struct {
+	Category string
+	Count    int64
+	Total    float64
+}
+
+// ExpenseReport 消费报表内容
+type ExpenseReport struct {
+	Title        string
+	RangeLabel   string // 统计区间，如 "2024-01-01 至 2024-01-31"
+	BaseCurrency string
+	ShowUsername bool // 是否显示用户名列（管理员导出全部用户时）
+	Rows         []ExpenseReportRow
+	Subtotals    []ExpenseReportSubtotal // 按金额从大到小
+	Total        float64                 // 总计（本位币）
+	Note         string                  // 表尾备注，如缺少汇率未计入合计的币种
+}
+
+// 报表版式（pt）
+const (
+	reportMargin      = 40.0
+	reportTitleSize   = 18.0
+	reportTextSize    = 9.0
+	reportHeadingSize = 12.0
+	reportRowHeight   = 16.0
+	reportCellPadding = 4.0
+	reportHeaderGray  = 0.88
+	reportLineGray    = 0.75
+)
+
+// reportColumn 表格列；width 为 0 的列占用剩余宽度
+type reportColumn struct {
+	title string
+	width float64
+	right bool
+}
+
+// reportLayout 逐行排版，超出页面底部时自动换页并重复表头
+type reportLayout struct {
+	doc     *pdfDocument
+	y       float64
+	pageNo  int
+	columns []reportColumn
+}
+
+func (l *reportLayout) newPage() {
+	l.doc.addPage()
+	l.pageNo++
+	l.y = reportMargin
+	footer := fmt.Sprintf("第 %d 页", l.pageNo)
+	l.doc.text((pdfPageWidth-l.doc.textWidth(footer, reportTextSize))/2, pdfPageHeight-reportMargin/2, reportTextSize, footer, 0.4)
+}
+
+// ensure 剩余空间不足 h 时换页；返回是否换页
+func (l *reportLayout) ensure(h float64) bool {
+	if l.pageNo > 0 && l.y+h <= pdfPageHeight-reportMargin {
+		return false
+	}
+	l.newPage()
+	return true
+}
+
+// setColumns 设置表格列并计算弹性列宽度
+func (l *reportLayout) setColumns(columns []reportColumn) {
+	fixedWidth, flex := 0.0, 0
+	for _, c := range columns {
+		if c.width == 0 {
+			flex++
+		}
+		fixedWidth += c.width
+	}
+	if flex > 0 {
+		each := (pdfPageWidth - 2*reportMargin - fixedWidth) / float64(flex)
+		for i := range columns {
+			if columns[i].width == 0 {
+				columns[i].width = each
+			}
+		}
+	}
+	l.columns = columns
+}
+
+// row 绘制一行表格；bg 大于 0 时填充背景
+func (l *reportLayout) row(cells []string, bg float64) {
+	if bg > 0 {
+		l.doc.fillRect(reportMargin, l.y, pdfPageWidth-2*reportMargin, reportRowHeight, bg)
+	}
+	baseline := l.y + reportRowHeight - 4.5
+	x := reportMargin
+	for i, c := range l.columns {
+		text := l.doc.truncate(cells[i], reportTextSize, c.width-2*reportCellPadding)
+		tx := x + reportCellPadding
+		if c.right {
+			tx = x + c.width - reportCellPadding - l.doc.textWidth(text, reportTextSize)
+		}
+		l.doc.text(tx, baseline, reportTextSize, text, 0)
+		x += c.width
+	}
+	l.y += reportRowHeight
+	l.doc.line(reportMargin, l.y, pdfPageWidth-reportMargin, l.y, 0.5, reportLineGray)
+}
+
+// table 绘制带表头的表格，换页时重复表头
+func (l *reportLayout) table(columns []reportColumn, rows [][]string) {
+	l.setColumns(columns)
+	header := make([]string, len(columns))
+	for i, c := range columns {
+		header[i] = c.title
+	}
+	l.ensure(2 * reportRowHeight)
+	l.row(header, reportHeaderGray)
+	for _, r := range rows {
+		if l.ensure(reportRowHeight) {
+			l.row(header, reportHeaderGray)
+		}
+		l.row(r, 0)
+	}
+}
+
+// heading 小节标题
+func (l *reportLayout) heading(s string) {
+	l.ensure(reportHeadingSize + 2*reportRowHeight)
+	l.y += reportHeadingSize + 8
+	l.doc.text(reportMargin, l.y, reportHeadingSize, s, 0)
+	l.y += 8
+}
+
+// formatReportAmount 报表金额：两位小数、千分位分隔
+func formatReportAmount(v float64) string {
+	s := strconv.FormatFloat(v, 'f', 2, 64)
+	sign := ""
+	if s[0] == '-' {
+		sign, s = "-", s[1:]
+	}
+	intPart, frac := s[:len(s)-3], s[len(s)-3:]
+	for i := len(intPart) - 3; i > 0; i -= 3 {
+		intPart = intPart[:i] + "," + intPart[i:]
+	}
+	return sign + intPart + frac
+}
+
+// RenderExpenseReportPDF 生成消费报表 PDF：标题、统计区间、消费明细表、类别小计与总计。
+// fontPath 为 TrueType 字体文件（需含中文字形），会完整嵌入 PDF；为空或加载失败时使用阅读器内置宋体
+func RenderExpenseReportPDF(r ExpenseReport, fontPath string, now time.Time) ([]byte, error) {
+	doc := newPDFDocument(fontPath, r.Title)
+	l := &reportLayout{doc: doc}
+	l.newPage()
+
+	l.y += reportTitleSize
+	doc.text(reportMargin, l.y, reportTitleSize, r.Title, 0)
+	l.y += 18
+	doc.text(reportMargin, l.y, reportTextSize+1, "统计区间："+r.RangeLabel, 0.3)
+	l.y += 14
+	doc.text(reportMargin, l.y, reportTextSize+1, "生成时间："+now.Format("2006-01-02 15:04:05"), 0.3)
+	l.y += 8
+
+	// 消费明细
+	l.heading(fmt.Sprintf("消费明细（%d 笔）", len(r.Rows)))
+	columns := []reportColumn{{title: "消费时间", width: 88}}
+	if r.ShowUsername {
+		columns = append(columns, reportColumn{title: "用户", width: 64})
+	}
+	columns = append(columns,
+		reportColumn{title: "类别", width: 72},
+		reportColumn{title: "描述"},
+		reportColumn{title: "金额", width: 76, right: true},
+		reportColumn{title: "币种", width: 34},
+	)
+	rows := make([][]string, 0, len(r.Rows))
+	for _, e := range r.Rows {
+		row := []string{e.Time.Format("2006-01-02 15:04")}
+		if r.ShowUsername {
+			row = append(row, e.Username)
+		}
+		currency := e.Currency
+		if currency == "" {
+			currency = r.BaseCurrency
+		}
+		rows = append(rows, append(row, e.Category, e.Description, formatReportAmount(e.Amount), currency))
+	}
+	l.table(columns, rows)
+
+	// 类别小计
+	l.heading(fmt.Sprintf("类别小计（%s）", r.BaseCurrency))
+	subtotals := make([][]string, 0, len(r.Subtotals)+1)
+	for _, s := range r.Subtotals {
+		share := 0.0
+		if r.Total > 0 {
+			share = s.Total / r.Total * 100
+		}
+		subtotals = append(subtotals, []string{s.Category, strconv.FormatInt(s.Count, 10), formatReportAmount(s.Total), fmt.Sprintf("%.1f%%", share)})
+	}
+	l.table([]reportColumn{
+		{title: "类别"},
+		{title: "笔数", width: 60, right: true},
+		{title: "金额", width: 100, right: true},
+		{title: "占比", width: 60, right: true},
+	}, subtotals)
+
+	// 总计
+	l.ensure(3 * reportRowHeight)
+	l.y += reportRowHeight + reportHeadingSize
+	total := fmt.Sprintf("总计：%s %s（共 %d 笔）", formatReportAmount(r.Total), r.BaseCurrency, len(r.Rows))
+	doc.text(pdfPageWidth-reportMargin-doc.textWidth(total, reportHeadingSize), l.y, reportHeadingSize, total, 0)
+	if r.Note != "" {
+		l.y += reportRowHeight
+		doc.text(reportMargin, l.y, reportTextSize, doc.truncate(r.Note, reportTextSize, pdfPageWidth-2*reportMargin), 0.4)
+	}
+
+	return doc.bytes(now)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"errors"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// 功能开关键；新增灰度功能时在此登记并加入 featureDefinitions
+const (
+	FeatureAIChat        = "ai_chat"        // AI 对话
+	FeatureAIAnalysis    = "ai_analysis"    // AI 消费分析
+	FeatureExpenseImport = "expense_import" // 消费记录 CSV 导入
+	FeatureExport        = "export"         // 数据导出（CSV/JSON/Excel/预算）
+)
+
+// FeatureDefinition 功能开关定义
+type FeatureDefinition struct {
+	Key         string
+	Description string
+	Default     bool // 表中没有记录时的取值
+}
+
+var featureDefinitions = []FeatureDefinition{
+	{Key: FeatureAIChat, Description: "AI 对话", Default: true},
+	{Key: FeatureAIAnalysis, Description: "AI 消费分析", Default: true},
+	{Key: FeatureExpenseImport, Description: "消费记录 CSV 导入", Default: true},
+	{Key: FeatureExport, Description: "数据导出（CSV/JSON/Excel/预算）", Default: true},
+}
+
+// FeatureFlagState 功能开关当前状态
+type FeatureFlagState struct {
+	Key         string `json:"key"`
+	Description string `json:"description"`
+	Enabled     bool   `json:"enabled"`
+	Default     bool   `json:"default"`
+	Overridden  bool   `json:"overridden"` // 是否被管理员改动过（表中有记录）
+}
+
+// ErrUnknownFeature 未登记的功能开关
+var ErrUnknownFeature = errors.New("未知的功能开关")
+
+var (
+	// featureFlags 当前生效的开关快照（键 → 是否开启），只读共享，变更时整体替换
+	featureFlags atomic.Pointer[map[string]bool]
+	// featureOverrides 表中存在记录的开关
+	featureOverrides atomic.Pointer[map[string]bool]
+	// featureWriteMu 串行化开关的写入与重新加载
+	featureWriteMu sync.Mutex
+)
+
+func init() {
+	storeFeatureFlags(nil)
+}
+
+// storeFeatureFlags 以默认值为基础合并表中记录，替换当前快照
+func storeFeatureFlags(rows []models.FeatureFlag) {
+	flags := make(map[string]bool, len(featureDefinitions))
+	for _, d := range featureDefinitions {
+		flags[d.Key] = d.Default
+	}
+	overrides := map[string]bool{}
+	for _, r := range rows {
+		if _, ok := flags[r.Key]; ok {
+			flags[r.Key] = r.Enabled
+			overrides[r.Key] = true
+		}
+	}
+	featureFlags.Store(&flags)
+	featureOverrides.Store(&overrides)
+}
+
+// FeatureEnabled 功能是否开启；只读内存快照，不查库，未登记的开关视为关闭
+func FeatureEnabled(key string) bool {
+	return (*featureFlags.Load())[key]
+}
+
+// LoadFeatureFlags 从数据库重新加载功能开关
+func LoadFeatureFlags(db *gorm.DB) error {
+	featureWriteMu.Lock()
+	defer featureWriteMu.Unlock()
+	var rows []models.FeatureFlag
+	if err := db.Find(&rows).Error; err != nil {
+		return err
+	}
+	storeFeatureFlags(rows)
+	return nil
+}
+
+// SetFeatureFlag 修改功能开关：先写库，成功后立即替换内存快照，本实例后续请求即按新值处理
+func SetFeatureFlag(db *gorm.DB, key string, enabled bool, updatedBy uint) error {
+	if _, ok := findFeatureDefinition(key); !ok {
+		return ErrUnknownFeature
+	}
+	featureWriteMu.Lock()
+	defer featureWriteMu.Unlock()
+
+	flag := models.FeatureFlag{Key: key, Enabled: enabled, UpdatedBy: &updatedBy}
+	if err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "updated_by", "updated_at"}),
+	}).Create(&flag).Error; err != nil {
+		return err
+	}
+
+	current := *featureFlags.Load()
+	flags := make(map[string]bool, len(current))
+	for k, v := range current {
+		flags[k] = v
+	}
+	flags[key] = enabled
+	overrides := map[string]bool{key: true}
+	for k := range *featureOverrides.Load() {
+		overrides[k] = true
+	}
+	featureFlags.Store(&flags)
+	featureOverrides.Store(&overrides)
+	return nil
+}
+
+// ListFeatureFlags 所有已登记开关的当前状态，按登记顺序
+func ListFeatureFlags() []FeatureFlagState {
+	flags, overrides := *featureFlags.Load(), *featureOverrides.Load()
+	list := make([]FeatureFlagState, 0, len(featureDefinitions))
+	for _, d := range featureDefinitions {
+		list = append(list, FeatureFlagState{
+			Key:         d.Key,
+			Description: d.Description,
+			Enabled:     flags[d.Key],
+			Default:     d.Default,
+			Overridden:  overrides[d.Key],
+		})
+	}
+	return list
+}
+
+func findFeatureDefinition(key string) (FeatureDefinition, bool) {
+	for _, d := range featureDefinitions {
+		if d.Key == key {
+			return d, true
+		}
+	}
+	return FeatureDefinition{}, false
+}
+
+// StartFeatureFlagSync 定期从数据库重新加载开关，使多实例部署时其他实例的修改在 interval 内生效
+func StartFeatureFlagSync(db *gorm.DB, interval time.Duration) {
+	go func() {
+		for {
+			time.Sleep(interval)
+			if err := LoadFeatureFlags(db); err != nil {
+				log.Printf("同步功能开关失败: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+
+	"finance/database"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFeatureEnabled_DefaultsAndOverrides(t *testing.T) {
+	defer storeFeatureFlags(nil)
+
+	assert.True(t, FeatureEnabled(FeatureAIChat))
+	assert.False(t, FeatureEnabled("not_registered"))
+
+	storeFeatureFlags([]models.FeatureFlag{
+		{Key: FeatureAIChat, Enabled: false},
+		{Key: "not_registered", Enabled: true},
+	})
+	assert.False(t, FeatureEnabled(FeatureAIChat))
+	assert.True(t, FeatureEnabled(FeatureExport))
+	assert.False(t, FeatureEnabled("not_registered"), "表中未登记的键不生效")
+
+	for _, s := range ListFeatureFlags() {
+		assert.Equal(t, s.Key == FeatureAIChat, s.Overridden, s.Key)
+	}
+}
+
+func TestSetFeatureFlag(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	defer storeFeatureFlags(nil)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO `feature_flags` .* ON DUPLICATE KEY UPDATE").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, SetFeatureFlag(database.DB, FeatureExpenseImport, false, 1))
+	assert.False(t, FeatureEnabled(FeatureExpenseImport), "写库成功后立即生效")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.ErrorIs(t, SetFeatureFlag(database.DB, "not_registered", true, 1), ErrUnknownFeature)
+}
+
+func TestLoadFeatureFlags(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+	defer storeFeatureFlags(nil)
+
+	mock.ExpectQuery("SELECT \\* FROM `feature_flags`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "key", "enabled"}).AddRow(1, FeatureExport, false))
+
+	require.NoError(t, LoadFeatureFlags(database.DB))
+	assert.False(t, FeatureEnabled(FeatureExport))
+	assert.True(t, FeatureEnabled(FeatureAIAnalysis))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,248 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 飞书开放平台（云文档）接口；与扫码登录的 passport 接口不同，使用应用身份 tenant_access_token
+const (
+	feishuOpenBaseURL   = "https://open.feishu.cn/open-apis"
+	feishuDocURLPrefix  = "https://feishu.cn/docx/"
+	feishuTokenEarly    = 5 * time.Minute // token 提前刷新的余量
+	feishuMaxBlockBatch = 50              // 单次创建子块的上限
+)
+
+// 飞书文档块类型
+const (
+	feishuBlockText     = 2
+	feishuBlockHeading1 = 3
+	feishuBlockHeading2 = 4
+	feishuBlockHeading3 = 5
+	feishuBlockBullet   = 12
+	feishuBlockOrdered  = 13
+)
+
+// FeishuDocClient 飞书云文档客户端，缓存并自动刷新 tenant_access_token
+type FeishuDocClient struct {
+	AppID      string
+	AppSecret  string
+	BaseURL    string // 默认 open.feishu.cn，测试时可替换
+	HTTPClient *http.Client
+
+	mu       sync.Mutex
+	token    string
+	expireAt time.Time
+}
+
+var (
+	feishuDocClientsMu sync.Mutex
+	feishuDocClients   = map[string]*FeishuDocClient{}
+)
+
+// GetFeishuDocClient 按 AppID 复用客户端，使 token 缓存在多次请求间共享
+func GetFeishuDocClient(appID, appSecret string) *FeishuDocClient {
+	feishuDocClientsMu.Lock()
+	defer feishuDocClientsMu.Unlock()
+	if cli, ok := feishuDocClients[appID]; ok && cli.AppSecret == appSecret {
+		return cli
+	}
+	cli := &FeishuDocClient{
+		AppID:      appID,
+		AppSecret:  appSecret,
+		BaseURL:    feishuOpenBaseURL,
+		HTTPClient: &http.Client{Timeout: 15 * time.Second},
+	}
+	feishuDocClients[appID] = cli
+	return cli
+}
+
+// feishuOpenResponse 开放平台统一响应包装
+type feishuOpenResponse struct {
+	Code int             `json:"code"`
+	Msg  string          `json:"msg"`
+	Data json.RawMessage `json:"data"`
+}
+
+// tenantAccessToken 获取应用 token，过期前 5 分钟自动刷新
+func (cli *FeishuDocClient) tenantAccessToken() (string, error) {
+	cli.mu.Lock()
+	defer cli.mu.Unlock()
+	if cli.token != "" && time.Now().Before(cli.expireAt) {
+		return cli.token, nil
+	}
+
+	body, _ := json.Marshal(map[string]string{"app_id": cli.AppID, "app_secret": cli.AppSecret})
+	resp, err := cli.HTTPClient.Post(cli.BaseURL+"/auth/v3/tenant_access_token/internal", "application/json; charset=utf-8", bytes.NewReader(body))
+	if err != nil {
+		return "", fmt.Errorf("请求飞书服务器失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Code              int    `json:"code"`
+		Msg               string `json:"msg"`
+		TenantAccessToken string `json:"tenant_access_token"`
+		Expire            int    `json:"expire"` // 秒
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("解析响应失败: %w", err)
+	}
+	if out.Code != 0 || out.TenantAccessToken == "" {
+		return "", fmt.Errorf("获取飞书应用凭证失败: %s", out.Msg)
+	}
+
+	cli.token = out.TenantAccessToken
+	cli.expireAt = time.Now().Add(time.Duration(out.Expire)*time.Second - feishuTokenEarly)
+	return cli.token, nil
+}
+
+// call 以应用身份调用开放平台接口，data 非 nil 时解析 data 字段
+func (cli *FeishuDocClient) call(method, path string, payload, data interface{}) error {
+	token, err := cli.tenantAccessToken()
+	if err != nil {
+		return err
+	}
+
+	var reqBody io.Reader
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, cli.BaseURL+path, reqBody)
+	if err != nil {
+		return fmt.Errorf("创建请求失败: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	resp, err := cli.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("请求飞书服务器失败: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var out feishuOpenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return fmt.Errorf("解析响应失败: %w", err)
+	}
+	if out.Code != 0 {
+		// token 被提前吊销时清空缓存，下次请求重新获取
+		if out.Code == 99991663 || out.Code == 99991661 {
+			cli.mu.Lock()
+			cli.token = ""
+			cli.mu.Unlock()
+		}
+		return fmt.Errorf("飞书返回错误: %s", out.Msg)
+	}
+	if data != nil && len(out.Data) > 0 {
+		return json.Unmarshal(out.Data, data)
+	}
+	return nil
+}
+
+// CreateDocument 在 folderToken 目录（为空时为应用根目录）下创建文档，写入 markdown 内容，
+// 并将完全访问权限授予 ownerOpenID 对应的飞书用户。返回文档链接
+func (cli *FeishuDocClient) CreateDocument(title, markdown, folderToken, ownerOpenID string) (string, error) {
+	var created struct {
+		Document struct {
+			DocumentID string `json:"document_id"`
+		} `json:"document"`
+	}
+	payload := map[string]string{"title": title}
+	if folderToken != "" {
+		payload["folder_token"] = folderToken
+	}
+	if err := cli.call(http.MethodPost, "/docx/v1/documents", payload, &created); err != nil {
+		return "", err
+	}
+	docID := created.Document.DocumentID
+	if docID == "" {
+		return "", fmt.Errorf("飞书未返回文档 ID")
+	}
+
+	// 文档根块 ID 与文档 ID 相同；按批追加到末尾
+	blocks := MarkdownToFeishuBlocks(markdown)
+	for start := 0; start < len(blocks); start += feishuMaxBlockBatch {
+		end := start + feishuMaxBlockBatch
+		if end > len(blocks) {
+			end = len(blocks)
+		}
+		path := fmt.Sprintf("/docx/v1/documents/%s/blocks/%s/children", docID, docID)
+		if err := cli.call(http.MethodPost, path, map[string]interface{}{"children": blocks[start:end], "index": -1}, nil); err != nil {
+			return "", err
+		}
+	}
+
+	if ownerOpenID != "" {
+		path := fmt.Sprintf("/drive/v1/permissions/%s/members?type=docx&need_notification=false", docID)
+		member := map[string]string{"member_type": "openid", "member_id": ownerOpenID, "perm": "full_access"}
+		if err := cli.call(http.MethodPost, path, member, nil); err != nil {
+			return "", err
+		}
+	}
+
+	return feishuDocURLPrefix + docID, nil
+}
+
+// SendTextMessage 以应用机器人身份给 openID 对应的飞书用户发送文本消息（需开通 im:message 权限，且用户在应用可用范围内）
+func (cli *FeishuDocClient) SendTextMessage(openID, text string) error {
+	content, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return err
+	}
+	return cli.call(http.MethodPost, "/im/v1/messages?receive_id_type=open_id", map[string]string{
+		"receive_id": openID,
+		"msg_type":   "text",
+		"content":    string(content),
+	}, nil)
+}
+
+var orderedLinePattern = regexp.MustCompile(`^\d+[.)]\s+`)
+
+// MarkdownToFeishuBlocks 将 AI 分析的 markdown 文本按行转换为飞书文档块。
+// 支持 1-3 级标题、无序/有序列表，其余行作为普通文本；行内加粗等标记去掉符号保留文字
+func MarkdownToFeishuBlocks(markdown string) []map[string]interface{} {
+	var blocks []map[string]interface{}
+	for _, line := range strings.Split(strings.ReplaceAll(markdown, "\r\n", "\n"), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || line == "---" || strings.HasPrefix(line, "```") {
+			continue
+		}
+
+		blockType, key := feishuBlockText, "text"
+		switch {
+		case strings.HasPrefix(line, "### "):
+			blockType, key, line = feishuBlockHeading3, "heading3", line[4:]
+		case strings.HasPrefix(line, "## "):
+			blockType, key, line = feishuBlockHeading2, "heading2", line[3:]
+		case strings.HasPrefix(line, "# "):
+			blockType, key, line = feishuBlockHeading1, "heading1", line[2:]
+		case strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* "):
+			blockType, key, line = feishuBlockBullet, "bullet", line[2:]
+		case orderedLinePattern.MatchString(line):
+			blockType, key, line = feishuBlockOrdered, "ordered", orderedLinePattern.ReplaceAllString(line, "")
+		}
+		line = strings.NewReplacer("**", "", "__", "", "`", "").Replace(line)
+
+		blocks = append(blocks, map[string]interface{}{
+			"block_type": blockType,
+			key: map[string]interface{}{
+				"elements": []map[string]interface{}{
+					{"text_run": map[string]string{"content": line}},
+				},
+			},
+		})
+	}
+	return blocks
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarkdownToFeishuBlocks(t *testing.T) {
+	md := "# 总结\n\n## 支出概览\n- **餐饮** 占比最高\n1. 减少外卖\n普通段落\n---\n"
+	blocks := MarkdownToFeishuBlocks(md)
+	require.Len(t, blocks, 5)
+
+	types := []int{feishuBlockHeading1, feishuBlockHeading2, feishuBlockBullet, feishuBlockOrdered, feishuBlockText}
+	for i, b := range blocks {
+		assert.Equal(t, types[i], b["block_type"])
+	}
+	bullet := blocks[2]["bullet"].(map[string]interface{})["elements"].([]map[string]interface{})
+	assert.Equal(t, "餐饮 占比最高", bullet[0]["text_run"].(map[string]string)["content"])
+}
+
+func TestFeishuDocClient_CreateDocument(t *testing.T) {
+	tokenCalls := 0
+	var paths []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		switch {
+		case r.URL.Path == "/auth/v3/tenant_access_token/internal":
+			tokenCalls++
+			w.Write([]byte(`{"code":0,"msg":"ok","tenant_access_token":"t-1","expire":7200}`))
+		case r.URL.Path == "/docx/v1/documents":
+			assert.Equal(t, "Bearer t-1", r.Header.Get("Authorization"))
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "报告", body["title"])
+			assert.Equal(t, "fld", body["folder_token"])
+			w.Write([]byte(`{"code":0,"msg":"ok","data":{"document":{"document_id":"doc123"}}}`))
+		case strings.HasPrefix(r.URL.Path, "/drive/v1/permissions/doc123/members"):
+			assert.Equal(t, "docx", r.URL.Query().Get("type"))
+			w.Write([]byte(`{"code":0,"msg":"ok","data":{}}`))
+		default:
+			w.Write([]byte(`{"code":0,"msg":"ok","data":{}}`))
+		}
+	}))
+	defer srv.Close()
+
+	cli := &FeishuDocClient{AppID: "cli", AppSecret: "sec", BaseURL: srv.URL, HTTPClient: srv.Client()}
+	u, err := cli.CreateDocument("报告", "# 标题\n内容", "fld", "ou_1")
+	require.NoError(t, err)
+	assert.Equal(t, "https://feishu.cn/docx/doc123", u)
+	assert.Contains(t, paths, "POST /docx/v1/documents/doc123/blocks/doc123/children")
+
+	// token 在有效期内复用
+	_, err = cli.CreateDocument("报告", "", "fld", "")
+	require.NoError(t, err)
+	assert.Equal(t, 1, tokenCalls)
+}
+
+func TestFeishuDocClient_TokenError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"code":10003,"msg":"invalid app_secret"}`))
+	}))
+	defer srv.Close()
+
+	cli := &FeishuDocClient{AppID: "cli", AppSecret: "bad", BaseURL: srv.URL, HTTPClient: srv.Client()}
+	_, err := cli.CreateDocument("报告", "内容", "", "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "invalid app_secret")
+}
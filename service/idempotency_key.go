@@ -0,0 +1,32 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"finance/database"
+	"finance/models"
+)
+
+// idempotencyKeyCleanupInterval 过期幂等键清理间隔
+const idempotencyKeyCleanupInterval = time.Hour
+
+// PurgeExpiredIdempotencyKeys 删除创建时间早于 now 减有效期的幂等键，返回删除条数
+func PurgeExpiredIdempotencyKeys(now time.Time) (int64, error) {
+	res := database.DB.Where("created_at < ?", now.Add(-models.IdempotencyKeyTTL)).Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
+
+// StartIdempotencyKeyCleanup 启动过期幂等键清理任务：启动时执行一次，之后每小时执行一次
+func StartIdempotencyKeyCleanup() {
+	go func() {
+		for {
+			if n, err := PurgeExpiredIdempotencyKeys(time.Now()); err != nil {
+				log.Printf("清理过期幂等键失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已清理过期幂等键 %d 条", n)
+			}
+			time.Sleep(idempotencyKeyCleanupInterval)
+		}
+	}()
+}
@@ -0,0 +1,27 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeExpiredIdempotencyKeys(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local)
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM `idempotency_keys` WHERE created_at < \\?").
+		WithArgs(now.Add(-24 * time.Hour)).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	n, err := PurgeExpiredIdempotencyKeys(now)
+	require.NoError(t, err)
+	assert.EqualValues(t, 3, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// LoginGuard 登录失败计数：按账号和 IP 分别统计窗口内的连续失败次数，
+// 任一计数达到阈值后要求验证码
+type LoginGuard struct {
+	threshold int
+	window    time.Duration
+
+	mu       sync.Mutex
+	failures map[string]loginFailure
+}
+
+type loginFailure struct {
+	count int
+	last  time.Time
+}
+
+// NewLoginGuard 创建登录失败计数器
+func NewLoginGuard(threshold int, window time.Duration) *LoginGuard {
+	return &LoginGuard{
+		threshold: threshold,
+		window:    window,
+		failures:  make(map[string]loginFailure),
+	}
+}
+
+func loginGuardKeys(username, ip string) []string {
+	keys := []string{"ip:" + ip}
+	if key := loginUserKey(username); key != "" {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// loginUserKey 账号维度的计数键，用户名为空时返回空串
+func loginUserKey(username string) string {
+	if u := strings.ToLower(strings.TrimSpace(username)); u != "" {
+		return "user:" + u
+	}
+	return ""
+}
+
+// count 返回未过期的失败次数，过期的顺带清理（调用方持有锁）
+func (g *LoginGuard) count(key string, now time.Time) int {
+	f, ok := g.failures[key]
+	if !ok {
+		return 0
+	}
+	if now.Sub(f.last) > g.window {
+		delete(g.failures, key)
+		return 0
+	}
+	return f.count
+}
+
+// CaptchaRequired 该账号或 IP 的失败次数是否已达到阈值
+func (g *LoginGuard) CaptchaRequired(username, ip string) bool {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range loginGuardKeys(username, ip) {
+		if g.count(key, now) >= g.threshold {
+			return true
+		}
+	}
+	return false
+}
+
+// RecordFailure 记录一次登录失败，返回此后是否需要验证码
+func (g *LoginGuard) RecordFailure(username, ip string) bool {
+	now := time.Now()
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	required := false
+	for _, key := range loginGuardKeys(username, ip) {
+		n := g.count(key, now) + 1
+		g.failures[key] = loginFailure{count: n, last: now}
+		if n >= g.threshold {
+			required = true
+		}
+	}
+	return required
+}
+
+// Reset 登录成功后清零该账号和 IP 的失败次数
+func (g *LoginGuard) Reset(username, ip string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for _, key := range loginGuardKeys(username, ip) {
+		delete(g.failures, key)
+	}
+}
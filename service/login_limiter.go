@@ -0,0 +1,124 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+// LoginLimiter 登录失败锁定：按账号和 IP 分别统计窗口内的失败次数，任一计数达到上限后
+// 锁定一段时间，锁定期间该账号/IP 的登录请求直接拒绝（不查库、不校验密码）。
+// 账号不存在时同样计数，锁定与否不暴露账号是否存在
+type LoginLimiter struct {
+	maxFailures int
+	window      time.Duration
+	lockout     time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*loginLimitEntry
+}
+
+type loginLimitEntry struct {
+	count       int
+	last        time.Time // 最近一次失败时间
+	lockedUntil time.Time // 零值表示未锁定
+}
+
+// NewLoginLimiter 创建登录失败锁定器：window 内失败 maxFailures 次后锁定 lockout
+func NewLoginLimiter(maxFailures int, window, lockout time.Duration) *LoginLimiter {
+	return &LoginLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		lockout:     lockout,
+		entries:     make(map[string]*loginLimitEntry),
+	}
+}
+
+// expired 记录是否已失效：锁定已结束且最近一次失败已超出统计窗口
+func (l *LoginLimiter) expired(e *loginLimitEntry, now time.Time) bool {
+	return !now.Before(e.lockedUntil) && now.Sub(e.last) > l.window
+}
+
+// Locked 该账号或 IP 是否处于锁定中，返回剩余锁定时间（取较长者）
+func (l *LoginLimiter) Locked(username, ip string) (time.Duration, bool) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var remaining time.Duration
+	for _, key := range loginGuardKeys(username, ip) {
+		if e, ok := l.entries[key]; ok && now.Before(e.lockedUntil) {
+			if d := e.lockedUntil.Sub(now); d > remaining {
+				remaining = d
+			}
+		}
+	}
+	return remaining, remaining > 0
+}
+
+// RecordFailure 记录一次登录失败；本次失败触发锁定时返回锁定时长
+func (l *LoginLimiter) RecordFailure(username, ip string) (time.Duration, bool) {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	locked := false
+	for _, key := range loginGuardKeys(username, ip) {
+		e, ok := l.entries[key]
+		if !ok || l.expired(e, now) {
+			e = &loginLimitEntry{}
+			l.entries[key] = e
+		}
+		e.count++
+		e.last = now
+		if e.count >= l.maxFailures && !now.Before(e.lockedUntil) {
+			// 锁定后重新计数，解锁后再失败 maxFailures 次才会再次锁定
+			e.lockedUntil = now.Add(l.lockout)
+			e.count = 0
+			locked = true
+		}
+	}
+	return l.lockout, locked
+}
+
+// ResetUser 登录成功后清零该账号的失败次数。IP 的计数不清零、等其自然过期，
+// 否则攻击者可以用自己的账号登录成功来重置同一 IP 对其他账号的尝试次数
+func (l *LoginLimiter) ResetUser(username string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if key := loginUserKey(username); key != "" {
+		delete(l.entries, key)
+	}
+}
+
+// Reset 清零该账号和 IP 的失败次数
+func (l *LoginLimiter) Reset(username, ip string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range loginGuardKeys(username, ip) {
+		delete(l.entries, key)
+	}
+}
+
+// Cleanup 清理已失效的记录，返回清理条数
+func (l *LoginLimiter) Cleanup() int {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	n := 0
+	for key, e := range l.entries {
+		if l.expired(e, now) {
+			delete(l.entries, key)
+			n++
+		}
+	}
+	return n
+}
+
+// StartCleanup 启动后台清理协程，每隔 interval 清理一次已失效的记录
+func (l *LoginLimiter) StartCleanup(interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			l.Cleanup()
+		}
+	}()
+}
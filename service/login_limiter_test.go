@@ -0,0 +1,85 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLimiter_LockAfterMaxFailures(t *testing.T) {
+	l := NewLoginLimiter(3, time.Minute, 15*time.Minute)
+
+	for i := 0; i < 2; i++ {
+		_, locked := l.RecordFailure("alice", "1.1.1.1")
+		assert.False(t, locked)
+	}
+	_, locked := l.Locked("alice", "1.1.1.1")
+	assert.False(t, locked)
+
+	lockout, locked := l.RecordFailure("Alice", "1.1.1.1")
+	assert.True(t, locked)
+	assert.Equal(t, 15*time.Minute, lockout)
+
+	// 账号锁定：换 IP 同样拒绝；IP 锁定：换账号同样拒绝
+	remaining, locked := l.Locked("ALICE", "9.9.9.9")
+	assert.True(t, locked)
+	assert.InDelta(t, float64(15*time.Minute), float64(remaining), float64(time.Second))
+	_, locked = l.Locked("bob", "1.1.1.1")
+	assert.True(t, locked)
+	_, locked = l.Locked("bob", "9.9.9.9")
+	assert.False(t, locked)
+}
+
+func TestLoginLimiter_Reset(t *testing.T) {
+	l := NewLoginLimiter(2, time.Minute, time.Minute)
+	l.RecordFailure("carol", "1.1.1.1")
+	l.Reset("carol", "1.1.1.1")
+
+	// 成功登录清零后重新计数
+	_, locked := l.RecordFailure("carol", "1.1.1.1")
+	assert.False(t, locked)
+}
+
+func TestLoginLimiter_ResetUserKeepsIP(t *testing.T) {
+	l := NewLoginLimiter(2, time.Minute, time.Minute)
+	l.RecordFailure("victim", "1.1.1.1")
+
+	// 同一 IP 用自己的账号登录成功，只清零该账号，IP 的失败次数仍在
+	l.ResetUser("Attacker")
+	_, locked := l.RecordFailure("victim2", "1.1.1.1")
+	assert.True(t, locked)
+
+	l.ResetUser("victim")
+	_, locked = l.RecordFailure("victim", "2.2.2.2")
+	assert.False(t, locked)
+}
+
+func TestLoginLimiter_WindowAndLockoutExpire(t *testing.T) {
+	l := NewLoginLimiter(2, time.Minute, time.Minute)
+	l.RecordFailure("dave", "1.1.1.1")
+
+	// 超出统计窗口后计数清零
+	l.mu.Lock()
+	for _, e := range l.entries {
+		e.last = time.Now().Add(-2 * time.Minute)
+	}
+	l.mu.Unlock()
+	_, locked := l.RecordFailure("dave", "1.1.1.1")
+	assert.False(t, locked)
+
+	_, locked = l.RecordFailure("dave", "1.1.1.1")
+	require.True(t, locked)
+
+	// 锁定到期后自动解锁，清理协程可回收记录
+	l.mu.Lock()
+	for _, e := range l.entries {
+		e.lockedUntil = time.Now().Add(-time.Second)
+		e.last = time.Now().Add(-2 * time.Minute)
+	}
+	l.mu.Unlock()
+	_, locked = l.Locked("dave", "1.1.1.1")
+	assert.False(t, locked)
+	assert.Equal(t, 2, l.Cleanup())
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"errors"
+
+	"finance/config"
+	"finance/database"
+	"finance/models"
+)
+
+// Notifier 向用户发送通知；业务代码（如超支提醒）只依赖该接口，不关心具体渠道
+type Notifier interface {
+	Send(userID uint, title, content string) error
+}
+
+// ErrNoNotifyChannel 用户没有可用的通知渠道（未绑定邮箱/飞书、关闭了通知或渠道未配置）
+var ErrNoNotifyChannel = errors.New("没有可用的通知渠道")
+
+// loadNotifyUser 查询通知接收人
+func loadNotifyUser(userID uint) (*models.User, error) {
+	var user models.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// EmailNotifier 通过邮件发送通知，发送结果记入邮件日志
+type EmailNotifier struct {
+	Email *EmailService
+}
+
+// Send 实现 Notifier
+func (n *EmailNotifier) Send(userID uint, title, content string) error {
+	user, err := loadNotifyUser(userID)
+	if err != nil {
+		return err
+	}
+	return n.sendTo(user, title, content)
+}
+
+func (n *EmailNotifier) sendTo(user *models.User, title, content string) error {
+	if user.Email == "" {
+		return ErrNoNotifyChannel
+	}
+	return n.Email.SendNotificationEmail(user.Email, title, content)
+}
+
+// FeishuNotifier 通过飞书机器人消息发送通知，接收人为用户绑定的飞书账号
+type FeishuNotifier struct {
+	Client *FeishuDocClient
+}
+
+// Send 实现 Notifier
+func (n *FeishuNotifier) Send(userID uint, title, content string) error {
+	user, err := loadNotifyUser(userID)
+	if err != nil {
+		return err
+	}
+	return n.sendTo(user, title, content)
+}
+
+func (n *FeishuNotifier) sendTo(user *models.User, title, content string) error {
+	if user.FeishuOpenID == nil || *user.FeishuOpenID == "" {
+		return ErrNoNotifyChannel
+	}
+	return n.Client.SendTextMessage(*user.FeishuOpenID, title+"\n"+content)
+}
+
+// PreferenceNotifier 按用户的通知渠道偏好选择邮件或飞书；未配置的渠道为 nil
+type PreferenceNotifier struct {
+	Email  *EmailNotifier
+	Feishu *FeishuNotifier
+}
+
+// NewNotifier 按配置创建通知器：启用邮件服务时可发邮件，配置了飞书应用凭证时可发飞书消息
+func NewNotifier(cfg *config.Config) *PreferenceNotifier {
+	n := &PreferenceNotifier{}
+	if cfg.Email.Enabled {
+		n.Email = &EmailNotifier{Email: NewEmailService(&cfg.Email)}
+	}
+	if cfg.Feishu.AppID != "" && cfg.Feishu.AppSecret != "" {
+		n.Feishu = &FeishuNotifier{Client: GetFeishuDocClient(cfg.Feishu.AppID, cfg.Feishu.AppSecret)}
+	}
+	return n
+}
+
+// notifyChannels 按偏好列出本次依次尝试的渠道：指定渠道时只用该渠道；
+// 自动时优先飞书（即时性更好），飞书不可用或发送失败再发邮件
+func notifyChannels(user *models.User, feishuAvailable, emailAvailable bool) []string {
+	canFeishu := feishuAvailable && user.FeishuOpenID != nil && *user.FeishuOpenID != ""
+	canEmail := emailAvailable && user.Email != ""
+
+	var channels []string
+	switch user.NotifyChannel {
+	case models.NotifyChannelNone:
+	case models.NotifyChannelEmail:
+		if canEmail {
+			channels = append(channels, models.NotifyChannelEmail)
+		}
+	case models.NotifyChannelFeishu:
+		if canFeishu {
+			channels = append(channels, models.NotifyChannelFeishu)
+		}
+	default:
+		if canFeishu {
+			channels = append(channels, models.NotifyChannelFeishu)
+		}
+		if canEmail {
+			channels = append(channels, models.NotifyChannelEmail)
+		}
+	}
+	return channels
+}
+
+// Send 实现 Notifier：依次尝试可用渠道，任一成功即返回；全部失败时返回最后一个错误
+func (n *PreferenceNotifier) Send(userID uint, title, content string) error {
+	user, err := loadNotifyUser(userID)
+	if err != nil {
+		return err
+	}
+	channels := notifyChannels(user, n.Feishu != nil, n.Email != nil)
+	if len(channels) == 0 {
+		return ErrNoNotifyChannel
+	}
+	for _, ch := range channels {
+		if ch == models.NotifyChannelFeishu {
+			err = n.Feishu.sendTo(user, title, content)
+		} else {
+			err = n.Email.sendTo(user, title, content)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifyChannels(t *testing.T) {
+	openID := "ou_1"
+	both := &models.User{Email: "a@example.com", FeishuOpenID: &openID}
+	emailOnly := &models.User{Email: "a@example.com"}
+
+	cases := []struct {
+		name          string
+		user          *models.User
+		pref          string
+		feishu, email bool
+		want          []string
+	}{
+		{"自动：飞书优先，邮件兜底", both, models.NotifyChannelAuto, true, true, []string{"feishu", "email"}},
+		{"自动：未绑定飞书", emailOnly, models.NotifyChannelAuto, true, true, []string{"email"}},
+		{"自动：飞书未配置", both, models.NotifyChannelAuto, false, true, []string{"email"}},
+		{"只发邮件", both, models.NotifyChannelEmail, true, true, []string{"email"}},
+		{"只发飞书", both, models.NotifyChannelFeishu, true, true, []string{"feishu"}},
+		{"只发飞书但未绑定", emailOnly, models.NotifyChannelFeishu, true, true, nil},
+		{"不接收通知", both, models.NotifyChannelNone, true, true, nil},
+		{"邮件未启用且无飞书", emailOnly, models.NotifyChannelAuto, true, false, nil},
+	}
+	for _, tc := range cases {
+		u := *tc.user
+		u.NotifyChannel = tc.pref
+		assert.Equal(t, tc.want, notifyChannels(&u, tc.feishu, tc.email), tc.name)
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	n := NewNotifier(&config.Config{})
+	assert.Nil(t, n.Email)
+	assert.Nil(t, n.Feishu)
+
+	n = NewNotifier(&config.Config{
+		Email:  config.EmailConfig{Enabled: true},
+		Feishu: config.FeishuConfig{AppID: "cli_notifier", AppSecret: "sec"},
+	})
+	assert.NotNil(t, n.Email)
+	assert.NotNil(t, n.Feishu)
+}
+
+func TestPreferenceNotifier_FallbackToEmail(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "feishu_open_id", "notify_channel"}).
+			AddRow(1, "alice", "a@example.com", "ou_1", ""))
+
+	var sent map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/auth/v3/tenant_access_token/internal":
+			w.Write([]byte(`{"code":0,"msg":"ok","tenant_access_token":"t-1","expire":7200}`))
+		case "/im/v1/messages":
+			assert.Equal(t, "open_id", r.URL.Query().Get("receive_id_type"))
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&sent))
+			w.Write([]byte(`{"code":230013,"msg":"bot has no availability to this user"}`))
+		}
+	}))
+	defer srv.Close()
+
+	var logs []models.EmailLog
+	old := recordEmailLog
+	recordEmailLog = func(l models.EmailLog) { logs = append(logs, l) }
+	defer func() { recordEmailLog = old }()
+
+	n := &PreferenceNotifier{
+		Feishu: &FeishuNotifier{Client: &FeishuDocClient{AppID: "cli", AppSecret: "sec", BaseURL: srv.URL, HTTPClient: srv.Client()}},
+		// 邮件服务未启用，投递必然失败，但会记录日志，用于确认确实尝试了邮件渠道
+		Email: &EmailNotifier{Email: NewEmailService(&config.EmailConfig{})},
+	}
+	err := n.Send(1, "餐饮超支", "本月餐饮已超出预算")
+	assert.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "ou_1", sent["receive_id"])
+	assert.Contains(t, sent["content"], "餐饮超支")
+	require.Len(t, logs, 1)
+	assert.Equal(t, models.EmailTypeNotification, logs[0].Type)
+	assert.Equal(t, "【记账系统】餐饮超支", logs[0].Subject)
+}
+
+func TestPreferenceNotifier_NoChannel(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `users`").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "username", "email", "notify_channel"}).
+			AddRow(1, "alice", "a@example.com", models.NotifyChannelNone))
+
+	n := &PreferenceNotifier{Email: &EmailNotifier{Email: NewEmailService(&config.EmailConfig{Enabled: true})}}
+	assert.ErrorIs(t, n.Send(1, "t", "c"), ErrNoNotifyChannel)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
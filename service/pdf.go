@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf16"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/sfnt"
+	"golang.org/x/image/math/fixed"
+)
+
+// A4 纵向页面尺寸（pt）
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+// pdfFallbackFont 未配置字体时使用的阅读器内置宋体（Adobe-GB1，不嵌入，依赖阅读器自带的中文字体）
+const pdfFallbackFont = "STSong-Light"
+
+// pdfFontFile 解析后的字体文件，按路径缓存；sfnt.Font 可并发使用（各自持有 Buffer）
+type pdfFontFile struct {
+	data []byte
+	font *sfnt.Font
+}
+
+var (
+	pdfFontsMu sync.Mutex
+	pdfFonts   = map[string]*pdfFontFile{}
+)
+
+// loadPDFFont 读取并解析 PDF 嵌入字体；仅支持 TrueType 轮廓（.ttf），CFF 轮廓的 OTF 与 TTC 字体集合不支持
+func loadPDFFont(path string) (*pdfFontFile, error) {
+	pdfFontsMu.Lock()
+	defer pdfFontsMu.Unlock()
+	if f, ok := pdfFonts[path]; ok {
+		if f == nil {
+			return nil, errors.New("字体加载失败")
+		}
+		return f, nil
+	}
+	data, err := os.ReadFile(path)
+	if err == nil && len(data) >= 4 {
+		switch string(data[:4]) {
+		case "OTTO":
+			err = errors.New("不支持 CFF 轮廓的 OTF 字体，请使用 TTF 字体")
+		case "ttcf":
+			err = errors.New("不支持 TTC 字体集合，请使用单个 TTF 字体")
+		}
+	}
+	var f *sfnt.Font
+	if err == nil {
+		f, err = sfnt.Parse(data)
+	}
+	if err != nil {
+		pdfFonts[path] = nil
+		return nil, err
+	}
+	ff := &pdfFontFile{data: data, font: f}
+	pdfFonts[path] = ff
+	return ff, nil
+}
+
+// pdfFont 单个文档使用的字体：记录用到的字形，生成文档时写出宽度表与 ToUnicode
+type pdfFont struct {
+	file   *pdfFontFile // nil 表示使用内置宋体
+	buf    sfnt.Buffer
+	ppem   fixed.Int26_6
+	upem   float64
+	glyphs map[rune]sfnt.GlyphIndex
+	widths map[sfnt.GlyphIndex]int // 千分之一 em
+	runes  map[sfnt.GlyphIndex]rune
+}
+
+func newPDFFont(file *pdfFontFile) *pdfFont {
+	f := &pdfFont{
+		file:   file,
+		glyphs: map[rune]sfnt.GlyphIndex{},
+		widths: map[sfnt.GlyphIndex]int{},
+		runes:  map[sfnt.GlyphIndex]rune{},
+	}
+	if file != nil {
+		f.upem = float64(file.font.UnitsPerEm())
+		f.ppem = fixed.I(int(file.font.UnitsPerEm()))
+	}
+	return f
+}
+
+// toEm 字体单位（26.6 定点）换算为千分之一 em
+func (f *pdfFont) toEm(v fixed.Int26_6) int {
+	return int(float64(v) / 64 * 1000 / f.upem)
+}
+
+// glyph 查找字符的字形与宽度；字体缺字时使用 .notdef（0 号字形）
+func (f *pdfFont) glyph(r rune) (sfnt.GlyphIndex, int) {
+	if gid, ok := f.glyphs[r]; ok {
+		return gid, f.widths[gid]
+	}
+	gid, err := f.file.font.GlyphIndex(&f.buf, r)
+	if err != nil {
+		gid = 0
+	}
+	adv, err := f.file.font.GlyphAdvance(&f.buf, gid, f.ppem, font.HintingNone)
+	width := 0
+	if err == nil {
+		width = f.toEm(adv)
+	}
+	f.glyphs[r] = gid
+	f.widths[gid] = width
+	if _, ok := f.runes[gid]; !ok && gid != 0 {
+		f.runes[gid] = r
+	}
+	return gid, width
+}
+
+// fallbackRune 内置宋体按 UCS-2 编码，超出基本平面的字符以问号代替
+func fallbackRune(r rune) rune {
+	if r > 0xFFFF || (r >= 0xD800 && r <= 0xDFFF) {
+		return '?'
+	}
+	return r
+}
+
+// width 文本宽度（pt）
+func (f *pdfFont) width(s string, size float64) float64 {
+	total := 0
+	for _, r := range s {
+		if f.file == nil {
+			// 内置宋体：ASCII 为半角，其余为全角（与字体宽度表 /W [1 95 500] 一致）
+			if r = fallbackRune(r); r >= 0x20 && r < 0x7F {
+				total += 500
+			} else {
+				total += 1000
+			}
+			continue
+		}
+		_, w := f.glyph(r)
+		total += w
+	}
+	return float64(total) * size / 1000
+}
+
+// encode 文本编码为 PDF 十六进制字符串：嵌入字体为字形编号（Identity-H），内置宋体为 UCS-2
+func (f *pdfFont) encode(s string) string {
+	var b strings.Builder
+	b.WriteByte('<')
+	for _, r := range s {
+		var code uint16
+		if f.file == nil {
+			code = uint16(fallbackRune(r))
+		} else {
+			gid, _ := f.glyph(r)
+			code = uint16(gid)
+		}
+		fmt.Fprintf(&b, "%04X", code)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfDocument 极简 PDF 生成器：A4 纵向、单一字体，支持文字、直线和填充矩形，坐标原点在页面左上角
+type pdfDocument struct {
+	font  *pdfFont
+	title string
+	pages []*bytes.Buffer
+}
+
+// newPDFDocument 创建文档；fontPath 为空或加载失败时使用内置宋体
+func newPDFDocument(fontPath, title string) *pdfDocument {
+	var file *pdfFontFile
+	if fontPath != "" {
+		f, err := loadPDFFont(fontPath)
+		if err != nil {
+			log.Printf("加载 PDF 字体失败，使用阅读器内置字体: %v", err)
+		} else {
+			file = f
+		}
+	}
+	return &pdfDocument{font: newPDFFont(file), title: title}
+}
+
+// addPage 新建一页，之后的绘制都在该页上
+func (d *pdfDocument) addPage() {
+	d.pages = append(d.pages, new(bytes.Buffer))
+}
+
+func (d *pdfDocument) page() *bytes.Buffer {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+	return d.pages[len(d.pages)-1]
+}
+
+// textWidth 文本宽度（pt）
+func (d *pdfDocument) textWidth(s string, size float64) float64 {
+	return d.font.width(s, size)
+}
+
+// text 在 (x, y) 处绘制文本，y 为基线位置；gray 为灰度（0 黑、1 白）
+func (d *pdfDocument) text(x, y, size float64, s string, gray float64) {
+	if s == "" {
+		return
+	}
+	fmt.Fprintf(d.page(), "BT %.3f g /F1 %.2f Tf %.2f %.2f Td %s Tj ET\n",
+		gray, size, x, pdfPageHeight-y, d.font.encode(s))
+}
+
+// line 绘制直线
+func (d *pdfDocument) line(x1, y1, x2, y2, width, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f G %.2f w %.2f %.2f m %.2f %.2f l S\n",
+		gray, width, x1, pdfPageHeight-y1, x2, pdfPageHeight-y2)
+}
+
+// fillRect 填充矩形，(x, y) 为左上角
+func (d *pdfDocument) fillRect(x, y, w, h, gray float64) {
+	fmt.Fprintf(d.page(), "%.3f g %.2f %.2f %.2f %.2f re f\n", gray, x, pdfPageHeight-y-h, w, h)
+}
+
+// truncate 截断文本使其宽度不超过 maxWidth，截断时末尾加省略号
+func (d *pdfDocument) truncate(s string, size, maxWidth float64) string {
+	if d.textWidth(s, size) <= maxWidth {
+		return s
+	}
+	runes := []rune(s)
+	for len(runes) > 0 {
+		runes = runes[:len(runes)-1]
+		if t := string(runes) + "…"; d.textWidth(t, size) <= maxWidth {
+			return t
+		}
+	}
+	return ""
+}
+
+// pdfString 文档信息中的文本字段：UTF-16BE（带 BOM）十六进制字符串
+func pdfString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfName 字体名只保留字母、数字和连字符
+func pdfName(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r < 0x80 && (r == '-' || ('0' <= r && r <= '9') || ('a' <= r && r <= 'z') || ('A' <= r && r <= 'Z')) {
+			b.WriteRune(r)
+		}
+	}
+	if b.Len() == 0 {
+		return "EmbeddedFont"
+	}
+	return b.String()
+}
+
+// pdfWriter 按对象编号顺序写出 PDF 并记录 xref 偏移
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) object(body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+// stream 写出 FlateDecode 压缩的流对象，extra 为附加的字典项
+func (w *pdfWriter) stream(data []byte, extra string) error {
+	var z bytes.Buffer
+	zw := zlib.NewWriter(&z)
+	if _, err := zw.Write(data); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode%s >>\nstream\n", len(w.offsets), z.Len(), extra)
+	w.buf.Write(z.Bytes())
+	w.buf.WriteString("\nendstream\nendobj\n")
+	return nil
+}
+
+// fontObjects 字体相关的 5 个对象（编号 3-7）：Type0 字体、CID 字体、字体描述、字体文件、ToUnicode
+func (d *pdfDocument) fontObjects(w *pdfWriter) error {
+	f := d.font
+	if f.file == nil {
+		w.object("<< /Type /Font /Subtype /Type0 /BaseFont /" + pdfFallbackFont + " /Encoding /UniGB-UCS2-H /DescendantFonts [4 0 R] >>")
+		w.object("<< /Type /Font /Subtype /CIDFontType0 /BaseFont /" + pdfFallbackFont +
+			" /CIDSystemInfo << /Registry (Adobe) /Ordering (GB1) /Supplement 2 >> /FontDescriptor 5 0 R /DW 1000 /W [1 95 500] >>")
+		w.object("<< /Type /FontDescriptor /FontName /" + pdfFallbackFont +
+			" /Flags 6 /FontBBox [-25 -254 1000 880] /ItalicAngle 0 /Ascent 880 /Descent -120 /CapHeight 880 /StemV 93 >>")
+		// 内置字体没有字体文件和 ToUnicode，占位以保持对象编号一致
+		w.object("null")
+		w.object("null")
+		return nil
+	}
+
+	name := "EmbeddedFont"
+	if n, err := f.file.font.Name(&f.buf, sfnt.NameIDPostScript); err == nil {
+		name = pdfName(n)
+	}
+	bbox := [4]int{0, -200, 1000, 900}
+	if b, err := f.file.font.Bounds(&f.buf, f.ppem, font.HintingNone); err == nil {
+		// sfnt 的 y 轴向下，PDF 向上
+		bbox = [4]int{f.toEm(b.Min.X), -f.toEm(b.Max.Y), f.toEm(b.Max.X), -f.toEm(b.Min.Y)}
+	}
+	ascent, descent := 880, -120
+	if m, err := f.file.font.Metrics(&f.buf, f.ppem, font.HintingNone); err == nil {
+		ascent, descent = f.toEm(m.Ascent), -f.toEm(m.Descent)
+	}
+
+	gids := make([]int, 0, len(f.widths))
+	for gid := range f.widths {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+	var widths strings.Builder
+	for _, gid := range gids {
+		fmt.Fprintf(&widths, "%d [%d] ", gid, f.widths[sfnt.GlyphIndex(gid)])
+	}
+
+	w.object(fmt.Sprintf("<< /Type /Font /Subtype /Type0 /BaseFont /%s /Encoding /Identity-H /DescendantFonts [4 0 R] /ToUnicode 7 0 R >>", name))
+	w.object(fmt.Sprintf("<< /Type /Font /Subtype /CIDFontType2 /BaseFont /%s /CIDSystemInfo << /Registry (Adobe) /Ordering (Identity) /Supplement 0 >> "+
+		"/FontDescriptor 5 0 R /DW 1000 /W [%s] /CIDToGIDMap /Identity >>", name, widths.String()))
+	w.object(fmt.Sprintf("<< /Type /FontDescriptor /FontName /%s /Flags 4 /FontBBox [%d %d %d %d] /ItalicAngle 0 /Ascent %d /Descent %d /CapHeight %d /StemV 80 /FontFile2 6 0 R >>",
+		name, bbox[0], bbox[1], bbox[2], bbox[3], ascent, descent, ascent))
+	if err := w.stream(f.file.data, fmt.Sprintf(" /Length1 %d", len(f.file.data))); err != nil {
+		return err
+	}
+	return w.stream(d.toUnicode(), "")
+}
+
+// toUnicode 字形编号到 Unicode 的映射，用于复制和搜索文本
+func (d *pdfDocument) toUnicode() []byte {
+	gids := make([]int, 0, len(d.font.runes))
+	for gid := range d.font.runes {
+		gids = append(gids, int(gid))
+	}
+	sort.Ints(gids)
+
+	var b bytes.Buffer
+	b.WriteString("/CIDInit /ProcSet findresource begin\n12 dict begin\nbegincmap\n" +
+		"/CIDSystemInfo << /Registry (Adobe) /Ordering (UCS) /Supplement 0 >> def\n" +
+		"/CMapName /Adobe-Identity-UCS def\n/CMapType 2 def\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n")
+	// 每个 bfchar 段最多 100 项
+	for start := 0; start < len(gids); start += 100 {
+		end := start + 100
+		if end > len(gids) {
+			end = len(gids)
+		}
+		fmt.Fprintf(&b, "%d beginbfchar\n", end-start)
+		for _, gid := range gids[start:end] {
+			fmt.Fprintf(&b, "<%04X> <", gid)
+			for _, u := range utf16.Encode([]rune{d.font.runes[sfnt.GlyphIndex(gid)]}) {
+				fmt.Fprintf(&b, "%04X", u)
+			}
+			b.WriteString(">\n")
+		}
+		b.WriteString("endbfchar\n")
+	}
+	b.WriteString("endcmap\nCMapName currentdict /CMap defineresource pop\nend\nend\n")
+	return b.Bytes()
+}
+
+// bytes 生成 PDF 文件内容。对象编号：1 目录、2 页面树、3-7 字体、之后每页依次为页面与内容流，最后是文档信息
+func (d *pdfDocument) bytes(now time.Time) ([]byte, error) {
+	if len(d.pages) == 0 {
+		d.addPage()
+	}
+	w := &pdfWriter{}
+	w.buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+
+	const firstPageObj = 8
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPageObj+2*i)
+	}
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	if err := d.fontObjects(w); err != nil {
+		return nil, err
+	}
+	for i, content := range d.pages {
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %.2f %.2f] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, firstPageObj+2*i+1))
+		if err := w.stream(content.Bytes(), ""); err != nil {
+			return nil, err
+		}
+	}
+	_, offset := now.Zone()
+	sign := '+'
+	if offset < 0 {
+		sign, offset = '-', -offset
+	}
+	w.object(fmt.Sprintf("<< /Title %s /CreationDate (D:%s%c%02d'%02d') >>",
+		pdfString(d.title), now.Format("20060102150405"), sign, offset/3600, offset%3600/60))
+	infoObj := len(w.offsets)
+
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, off := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R /Info %d 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, infoObj, xref)
+	return w.buf.Bytes(), nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// checkPDFXref 校验 xref 表中记录的偏移都指向对应的对象
+func checkPDFXref(t *testing.T, data []byte) {
+	m := regexp.MustCompile(`startxref\n(\d+)\n%%EOF\n$`).FindSubmatch(data)
+	require.NotNil(t, m)
+	xref, _ := strconv.Atoi(string(m[1]))
+	require.True(t, bytes.HasPrefix(data[xref:], []byte("xref\n")))
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	require.NotEmpty(t, entries)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		assert.True(t, bytes.HasPrefix(data[off:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "对象 %d", i+1)
+	}
+}
+
+func sampleExpenseReport(rows int) ExpenseReport {
+	r := ExpenseReport{
+		Title:        "消费报表",
+		RangeLabel:   "2024-01-01 至 2024-01-31",
+		BaseCurrency: "CNY",
+		ShowUsername: true,
+		Subtotals:    []ExpenseReportSubtotal{{Category: "餐饮", Count: int64(rows), Total: float64(rows) * 12.5}},
+		Total:        float64(rows) * 12.5,
+	}
+	for i := 0; i < rows; i++ {
+		r.Rows = append(r.Rows, ExpenseReportRow{
+			Time: time.Date(2024, 1, 15, 12, 0, 0, 0, time.Local), Username: "alice",
+			Category: "餐饮", Description: "午餐 lunch", Amount: 12.5, Currency: "CNY",
+		})
+	}
+	return r
+}
+
+func TestRenderExpenseReportPDF_Fallback(t *testing.T) {
+	data, err := RenderExpenseReportPDF(sampleExpenseReport(3), "", time.Now())
+	require.NoError(t, err)
+
+	assert.True(t, bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	assert.Contains(t, string(data), "/BaseFont /STSong-Light /Encoding /UniGB-UCS2-H")
+	assert.Contains(t, string(data), "/Count 1")
+	assert.NotContains(t, string(data), "/FontFile2")
+	checkPDFXref(t, data)
+}
+
+func TestRenderExpenseReportPDF_Paging(t *testing.T) {
+	data, err := RenderExpenseReportPDF(sampleExpenseReport(120), "", time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "/Count 3")
+	checkPDFXref(t, data)
+}
+
+func TestRenderExpenseReportPDF_EmbeddedFont(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "font.ttf")
+	require.NoError(t, os.WriteFile(path, goregular.TTF, 0o644))
+
+	data, err := RenderExpenseReportPDF(sampleExpenseReport(2), path, time.Now())
+	require.NoError(t, err)
+
+	s := string(data)
+	assert.Contains(t, s, "/Subtype /CIDFontType2")
+	assert.Contains(t, s, "/Encoding /Identity-H")
+	assert.Contains(t, s, "/FontFile2 6 0 R")
+	assert.Contains(t, s, "/Length1 "+strconv.Itoa(len(goregular.TTF)))
+	checkPDFXref(t, data)
+
+	// ToUnicode 中含用到的字形（如 "l" → U+006C）
+	m := regexp.MustCompile(`7 0 obj\n<< /Length (\d+) /Filter /FlateDecode >>\nstream\n`).FindSubmatchIndex(data)
+	require.NotNil(t, m)
+	n, _ := strconv.Atoi(string(data[m[2]:m[3]]))
+	zr, err := zlib.NewReader(bytes.NewReader(data[m[1] : m[1]+n]))
+	require.NoError(t, err)
+	cmap, err := io.ReadAll(zr)
+	require.NoError(t, err)
+	assert.Contains(t, string(cmap), "> <006C>")
+}
+
+func TestLoadPDFFont_Unsupported(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "font.otf")
+	require.NoError(t, os.WriteFile(path, []byte("OTTO\x00\x00\x00\x00"), 0o644))
+	_, err := loadPDFFont(path)
+	assert.Error(t, err)
+
+	// 加载失败时回退到内置字体
+	doc := newPDFDocument(path, "t")
+	assert.Nil(t, doc.font.file)
+}
+
+func TestPDFDocument_Truncate(t *testing.T) {
+	doc := newPDFDocument("", "t")
+	assert.Equal(t, "abc", doc.truncate("abc", 10, 100))
+	// 内置字体：ASCII 半角 5pt、中文全角 10pt（字号 10）
+	assert.Equal(t, 20.0, doc.textWidth("ab中", 10))
+	assert.Equal(t, "一二…", doc.truncate("一二三四五", 10, 30))
+}
+
+func TestFormatReportAmount(t *testing.T) {
+	assert.Equal(t, "0.00", formatReportAmount(0))
+	assert.Equal(t, "999.50", formatReportAmount(999.5))
+	assert.Equal(t, "1,234,567.89", formatReportAmount(1234567.891))
+	assert.Equal(t, "-1,000.00", formatReportAmount(-1000))
+}
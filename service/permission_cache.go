@@ -0,0 +1,29 @@
+package service
+
+import (
+	"fmt"
+	"time"
+)
+
+// 角色接口权限缓存参数
+const (
+	rolePermissionCacheTTL        = 10 * time.Minute
+	rolePermissionCacheMaxEntries = 1000
+)
+
+// RolePermissionCache 角色可访问的接口集合（"METHOD /path" → true）缓存，键见 RolePermissionCacheKey；
+// 角色菜单、菜单接口或接口定义变更时调用 InvalidateRolePermissions 清空
+var RolePermissionCache = NewCache("role_permissions", rolePermissionCacheTTL, rolePermissionCacheMaxEntries)
+
+// RolePermissionCacheKey 角色权限缓存键；roleID 为 nil 表示未分配角色（按只读角色处理）
+func RolePermissionCacheKey(roleID *uint) string {
+	if roleID == nil {
+		return "role:none"
+	}
+	return fmt.Sprintf("role:%d", *roleID)
+}
+
+// InvalidateRolePermissions 清空角色权限缓存；一次变更可能影响多个角色，直接全部清除
+func InvalidateRolePermissions() {
+	RolePermissionCache.InvalidateAll()
+}
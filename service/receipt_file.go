@@ -0,0 +1,24 @@
+package service
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+
+	"finance/config"
+)
+
+// ReceiptDir 小票图片保存目录
+func ReceiptDir() string {
+	if cfg := config.GlobalConfig; cfg != nil && cfg.Storage.ReceiptDir != "" {
+		return cfg.Storage.ReceiptDir
+	}
+	return config.DefaultReceiptDir
+}
+
+// RemoveReceiptFile 删除小票图片文件，文件已不存在时忽略
+func RemoveReceiptFile(rel string) {
+	if err := os.Remove(filepath.Join(ReceiptDir(), rel)); err != nil && !os.IsNotExist(err) {
+		log.Printf("删除小票图片 %s 失败: %v", rel, err)
+	}
+}
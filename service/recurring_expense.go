@@ -0,0 +1,123 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// recurringExpenseInterval 周期消费定时任务的扫描间隔
+const recurringExpenseInterval = time.Hour
+
+// maxRecurringCatchUp 单个模板一次最多补生成的期数，避免停机很久或日期填错时一次写入过多记录
+const maxRecurringCatchUp = 400
+
+// createRecurringOccurrence 为模板生成 runDate 当天的消费记录并把 next_run 推进到 next。
+// 推进 next_run 与写入消费记录在同一事务中：只有 next_run 仍为 runDate 且当天尚未生成过
+// （last_created_date 不等于 runDate）时才会写入，重启或多实例同时扫描时同一天只会生成一条。
+// 返回是否实际生成
+func createRecurringOccurrence(tpl *models.RecurringExpense, runDate, next string) (bool, error) {
+	expenseTime, err := time.ParseInLocation("2006-01-02", runDate, time.Local)
+	if err != nil {
+		return false, err
+	}
+	created := false
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.RecurringExpense{}).
+			Where("id = ? AND next_run = ? AND (last_created_date IS NULL OR last_created_date <> ?)", tpl.ID, runDate, runDate).
+			UpdateColumns(map[string]interface{}{
+				"next_run":          next,
+				"last_created_date": runDate,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return nil
+		}
+		expense := models.Expense{
+			UserID:      tpl.UserID,
+			Amount:      tpl.Amount,
+			Currency:    tpl.Currency,
+			Category:    tpl.Category,
+			Description: tpl.Description,
+			ExpenseTime: expenseTime,
+			CreatedBy:   tpl.CreatedBy,
+		}
+		if err := tx.Create(&expense).Error; err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	return created, err
+}
+
+// materializeRecurringExpense 逐期生成模板截至 today 已到期的消费记录（停机期间错过的按原日期补齐），返回生成条数
+func materializeRecurringExpense(tpl models.RecurringExpense, today string) (int, error) {
+	n := 0
+	for i := 0; i < maxRecurringCatchUp && tpl.NextRun <= today; i++ {
+		runDate := tpl.NextRun
+		next, err := tpl.RunAfter(runDate)
+		if err != nil {
+			return n, err
+		}
+		ok, err := createRecurringOccurrence(&tpl, runDate, next)
+		if err != nil {
+			return n, err
+		}
+		if !ok {
+			// 已被其他进程处理或模板已变更，留待下一轮扫描
+			return n, nil
+		}
+		n++
+		tpl.NextRun = next
+		tpl.LastCreatedDate = &runDate
+	}
+	return n, nil
+}
+
+// RunDueRecurringExpenses 扫描已到期（next_run 不晚于 now 当天）的启用模板并生成消费记录，返回生成条数。
+// 所属用户已删除的模板跳过；单个模板失败只记日志，不影响其他模板
+func RunDueRecurringExpenses(now time.Time) (int, error) {
+	today := now.In(time.Local).Format("2006-01-02")
+	var due []models.RecurringExpense
+	if err := database.DB.
+		Where("active = ? AND next_run <= ?", true, today).
+		Where("user_id IN (?)", database.DB.Model(&models.User{}).Select("id")).
+		Order("id").Find(&due).Error; err != nil {
+		return 0, err
+	}
+	total := 0
+	for _, tpl := range due {
+		n, err := materializeRecurringExpense(tpl, today)
+		total += n
+		if err != nil {
+			log.Printf("周期消费模板 %d 生成消费记录失败: %v", tpl.ID, err)
+		}
+	}
+	return total, nil
+}
+
+// StartRecurringExpenseScheduler 启动周期消费定时任务：启动时执行一次，之后每小时扫描一次到期模板
+func StartRecurringExpenseScheduler() {
+	go func() {
+		run := func() {
+			if n, err := RunDueRecurringExpenses(time.Now()); err != nil {
+				log.Printf("扫描周期消费模板失败: %v", err)
+			} else if n > 0 {
+				log.Printf("已按周期消费模板生成消费记录 %d 条", n)
+			}
+		}
+		run()
+		ticker := time.NewTicker(recurringExpenseInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			run()
+		}
+	}()
+}
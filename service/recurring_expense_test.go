@@ -0,0 +1,62 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var recurringExpenseColumns = []string{"id", "user_id", "amount", "currency", "category", "description", "frequency", "start_date", "next_run", "last_created_date", "active", "created_by"}
+
+func TestRunDueRecurringExpenses_CatchUp(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+	mock.ExpectQuery("SELECT \\* FROM `recurring_expenses` WHERE .*active = \\? AND next_run <= \\?.*user_id IN \\(SELECT `id` FROM `users`").
+		WithArgs(true, "2024-03-15").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow(3, 7, 30, "CNY", "娱乐", "视频会员", "daily", "2024-03-01", "2024-03-14", "2024-03-13", true, 7))
+
+	// 停机错过的 3-14 与今天各生成一条，每条与推进 next_run 在同一事务
+	for _, d := range [][2]string{{"2024-03-14", "2024-03-15"}, {"2024-03-15", "2024-03-16"}} {
+		mock.ExpectBegin()
+		mock.ExpectExec("UPDATE `recurring_expenses` SET `last_created_date`=\\?,`next_run`=\\? WHERE \\(id = \\? AND next_run = \\? AND \\(last_created_date IS NULL OR last_created_date <> \\?\\)\\)").
+			WithArgs(d[0], d[1], 3, d[0], d[0]).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("INSERT INTO `expenses`").
+			WillReturnResult(sqlmock.NewResult(100, 1))
+		mock.ExpectCommit()
+	}
+
+	n, err := RunDueRecurringExpenses(now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunDueRecurringExpenses_AlreadyCreated(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	now := time.Date(2024, 3, 15, 10, 0, 0, 0, time.Local)
+	mock.ExpectQuery("SELECT \\* FROM `recurring_expenses`").
+		WithArgs(true, "2024-03-15").
+		WillReturnRows(sqlmock.NewRows(recurringExpenseColumns).
+			AddRow(3, 7, 3000, "CNY", "住房", "房租", "monthly", "2024-01-15", "2024-03-15", nil, true, 7))
+
+	// 另一个进程已生成当天的记录：条件更新影响 0 行，不写入消费记录
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE `recurring_expenses`").
+		WithArgs("2024-03-15", "2024-04-15", 3, "2024-03-15", "2024-03-15").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	n, err := RunDueRecurringExpenses(now)
+	require.NoError(t, err)
+	assert.Equal(t, 0, n)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
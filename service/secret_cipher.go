@@ -0,0 +1,85 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	"finance/config"
+)
+
+// encryptedSecretPrefix 密文前缀，用于区分加密前写入的历史明文
+const encryptedSecretPrefix = "enc:v1:"
+
+// ErrSecretDecrypt 密文损坏或加密密钥已更换
+var ErrSecretDecrypt = errors.New("敏感数据解密失败，请检查 security.encryption_key 是否被更换")
+
+// secretKey 由 security.encryption_key（为空时用 jwt.secret）经 SHA-256 派生 AES-256 密钥
+func secretKey() []byte {
+	var secret string
+	if cfg := config.GlobalConfig; cfg != nil {
+		secret = cfg.Security.EncryptionKey
+		if secret == "" {
+			secret = cfg.JWT.Secret
+		}
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return sum[:]
+}
+
+func secretAEAD() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(secretKey())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncryptedSecret 是否为 EncryptSecret 生成的密文
+func IsEncryptedSecret(s string) bool {
+	return strings.HasPrefix(s, encryptedSecretPrefix)
+}
+
+// EncryptSecret 使用 AES-GCM 加密敏感字段，返回带前缀的 base64 密文；空串原样返回
+func EncryptSecret(plain string) (string, error) {
+	if plain == "" {
+		return "", nil
+	}
+	aead, err := secretAEAD()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plain), nil)
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptSecret 解密 EncryptSecret 生成的密文；不带密文前缀的值视为历史明文原样返回
+func DecryptSecret(s string) (string, error) {
+	if !IsEncryptedSecret(s) {
+		return s, nil
+	}
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(s, encryptedSecretPrefix))
+	if err != nil {
+		return "", ErrSecretDecrypt
+	}
+	aead, err := secretAEAD()
+	if err != nil {
+		return "", err
+	}
+	if len(raw) < aead.NonceSize() {
+		return "", ErrSecretDecrypt
+	}
+	plain, err := aead.Open(nil, raw[:aead.NonceSize()], raw[aead.NonceSize():], nil)
+	if err != nil {
+		return "", ErrSecretDecrypt
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"testing"
+
+	"finance/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncryptSecret_RoundTrip(t *testing.T) {
+	config.GlobalConfig = &config.Config{Security: config.SecurityConfig{EncryptionKey: "k1"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	enc, err := EncryptSecret("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.True(t, IsEncryptedSecret(enc))
+	assert.NotContains(t, enc, "JBSWY3DPEHPK3PXP")
+
+	// 每次加密使用新的随机 nonce
+	enc2, err := EncryptSecret("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+	assert.NotEqual(t, enc, enc2)
+
+	plain, err := DecryptSecret(enc)
+	require.NoError(t, err)
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", plain)
+
+	// 更换密钥后无法解密
+	config.GlobalConfig.Security.EncryptionKey = "k2"
+	_, err = DecryptSecret(enc)
+	assert.ErrorIs(t, err, ErrSecretDecrypt)
+}
+
+func TestDecryptSecret_PlaintextPassthrough(t *testing.T) {
+	config.GlobalConfig = &config.Config{JWT: config.JWTConfig{Secret: "jwt"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	plain, err := DecryptSecret("sk-legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "sk-legacy", plain)
+
+	enc, err := EncryptSecret("")
+	require.NoError(t, err)
+	assert.Empty(t, enc)
+}
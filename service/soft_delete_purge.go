@@ -0,0 +1,102 @@
+package service
+
+import (
+	"log"
+	"time"
+
+	"finance/database"
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// softDeletePurgeBatch 每批物理删除的记录数，分批提交避免长事务锁表
+const softDeletePurgeBatch = 500
+
+// purgeSoftDeleted 分批物理删除 model 中软删除时间早于 before 的记录，返回删除条数。
+// 每批先查出 ID 写日志留痕，再调用 remove 按 ID 删除，每批是一个独立的短事务
+func purgeSoftDeleted(model interface{}, table string, before time.Time, remove func(ids []uint) (int64, error)) (int64, error) {
+	var total int64
+	for {
+		var ids []uint
+		if err := database.DB.Unscoped().Model(model).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", before).
+			Order("id").Limit(softDeletePurgeBatch).Pluck("id", &ids).Error; err != nil {
+			return total, err
+		}
+		if len(ids) == 0 {
+			return total, nil
+		}
+		log.Printf("[审计] 清理软删除超过保留期的 %s 记录 %d 条，ID %d-%d", table, len(ids), ids[0], ids[len(ids)-1])
+		n, err := remove(ids)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if len(ids) < softDeletePurgeBatch {
+			return total, nil
+		}
+	}
+}
+
+// PurgeExpenses 物理删除指定消费记录，连同其小票与标签关联在同一事务中删除，返回删除的消费记录条数。
+// 小票文件在事务提交后删除，事务失败时文件保留
+func PurgeExpenses(ids []uint) (int64, error) {
+	var (
+		deleted int64
+		files   []string
+	)
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Receipt{}).Where("expense_id IN ?", ids).Pluck("path", &files).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expense_id IN ?", ids).Delete(&models.Receipt{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("expense_id IN ?", ids).Delete(&models.ExpenseTag{}).Error; err != nil {
+			return err
+		}
+		res := tx.Unscoped().Where("id IN ?", ids).Delete(&models.Expense{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	for _, f := range files {
+		RemoveReceiptFile(f)
+	}
+	return deleted, nil
+}
+
+// purgeIncomes 物理删除指定收入记录
+func purgeIncomes(ids []uint) (int64, error) {
+	res := database.DB.Unscoped().Where("id IN ?", ids).Delete(&models.Income{})
+	return res.RowsAffected, res.Error
+}
+
+// PurgeSoftDeletedRecords 物理删除软删除时间早于 before 的消费与收入记录，返回各自删除条数
+func PurgeSoftDeletedRecords(before time.Time) (expenses, incomes int64, err error) {
+	if expenses, err = purgeSoftDeleted(&models.Expense{}, "expenses", before, PurgeExpenses); err != nil {
+		return expenses, 0, err
+	}
+	incomes, err = purgeSoftDeleted(&models.Income{}, "incomes", before, purgeIncomes)
+	return expenses, incomes, err
+}
+
+// StartSoftDeletePurgeScheduler 启动软删除清理定时任务：启动时执行一次，之后每 24 小时执行一次，
+// 物理删除软删除超过 retentionDays 天的消费与收入记录
+func StartSoftDeletePurgeScheduler(retentionDays int) {
+	go func() {
+		for {
+			before := time.Now().AddDate(0, 0, -retentionDays)
+			expenses, incomes, err := PurgeSoftDeletedRecords(before)
+			if err != nil {
+				log.Printf("清理软删除记录失败: %v", err)
+			} else if expenses+incomes > 0 {
+				log.Printf("已清理软删除超过 %d 天的消费记录 %d 条、收入记录 %d 条", retentionDays, expenses, incomes)
+			}
+			time.Sleep(24 * time.Hour)
+		}
+	}()
+}
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"finance/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPurgeSoftDeletedRecords_Batches(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	before := time.Date(2024, 3, 1, 0, 0, 0, 0, time.Local)
+
+	// 第一批满 softDeletePurgeBatch 条，继续查下一批
+	full := sqlmock.NewRows([]string{"id"})
+	args := make([]driver.Value, 0, softDeletePurgeBatch)
+	for i := 1; i <= softDeletePurgeBatch; i++ {
+		full.AddRow(i)
+		args = append(args, i)
+	}
+	mock.ExpectQuery("SELECT `id` FROM `expenses` WHERE deleted_at IS NOT NULL AND deleted_at < \\? ORDER BY id LIMIT 500").
+		WithArgs(before).
+		WillReturnRows(full)
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `path` FROM `receipts` WHERE expense_id IN").
+		WithArgs(args...).
+		WillReturnRows(sqlmock.NewRows([]string{"path"}))
+	mock.ExpectExec("DELETE FROM `receipts` WHERE expense_id IN").
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE expense_id IN").
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM `expenses` WHERE id IN").
+		WithArgs(args...).
+		WillReturnResult(sqlmock.NewResult(0, softDeletePurgeBatch))
+	mock.ExpectCommit()
+
+	// 第二批不足一批，连同小票与标签关联删除后结束
+	mock.ExpectQuery("SELECT `id` FROM `expenses`").
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(700).AddRow(701))
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `path` FROM `receipts` WHERE expense_id IN \\(\\?,\\?\\)").
+		WithArgs(700, 701).
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("1/a.jpg"))
+	mock.ExpectExec("DELETE FROM `receipts` WHERE expense_id IN \\(\\?,\\?\\)").
+		WithArgs(700, 701).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE expense_id IN \\(\\?,\\?\\)").
+		WithArgs(700, 701).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM `expenses` WHERE id IN \\(\\?,\\?\\)").
+		WithArgs(700, 701).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	// 收入没有需要清理的记录
+	mock.ExpectQuery("SELECT `id` FROM `incomes`").
+		WithArgs(before).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	expenses, incomes, err := PurgeSoftDeletedRecords(before)
+	require.NoError(t, err)
+	assert.Equal(t, int64(softDeletePurgeBatch+2), expenses)
+	assert.Equal(t, int64(0), incomes)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeExpenses_RemovesReceiptFiles(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.Storage.ReceiptDir = dir
+	defer func() { config.GlobalConfig = oldCfg }()
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1"), 0o755))
+	file := filepath.Join(dir, "1", "a.jpg")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `path` FROM `receipts` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow(filepath.Join("1", "a.jpg")))
+	mock.ExpectExec("DELETE FROM `receipts` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `expense_tags` WHERE expense_id IN \\(\\?\\)").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `expenses` WHERE id IN \\(\\?\\)").
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	n, err := PurgeExpenses([]uint{7})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeExpenses_RollbackKeepsFiles(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	oldCfg := config.GlobalConfig
+	config.GlobalConfig = &config.Config{}
+	config.GlobalConfig.Storage.ReceiptDir = dir
+	defer func() { config.GlobalConfig = oldCfg }()
+
+	file := filepath.Join(dir, "a.jpg")
+	require.NoError(t, os.WriteFile(file, []byte("x"), 0o644))
+
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT `path` FROM `receipts`").
+		WillReturnRows(sqlmock.NewRows([]string{"path"}).AddRow("a.jpg"))
+	mock.ExpectExec("DELETE FROM `receipts`").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM `expense_tags`").WillReturnError(fmt.Errorf("lock timeout"))
+	mock.ExpectRollback()
+
+	_, err := PurgeExpenses([]uint{7})
+	assert.Error(t, err)
+	_, err = os.Stat(file)
+	assert.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgeSoftDeletedRecords_Error(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT `id` FROM `expenses`").WillReturnError(fmt.Errorf("db down"))
+
+	_, _, err := PurgeSoftDeletedRecords(time.Now())
+	assert.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
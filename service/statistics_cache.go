@@ -0,0 +1,122 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"finance/models"
+
+	"gorm.io/gorm"
+)
+
+// 消费统计缓存参数
+const (
+	expenseStatisticsCacheTTL        = 5 * time.Minute
+	expenseStatisticsCacheMaxEntries = 10000
+)
+
+// ExpenseStatisticsCache 消费统计结果缓存，键以 UserCachePrefix 开头；消费记录或类别变更时自动失效
+var ExpenseStatisticsCache = NewCache("expense_statistics", expenseStatisticsCacheTTL, expenseStatisticsCacheMaxEntries)
+
+// UserCachePrefix 按用户划分的缓存键前缀
+func UserCachePrefix(userID uint) string {
+	return fmt.Sprintf("u%d:", userID)
+}
+
+// LedgerCachePrefix 按共享账本划分的缓存键前缀
+func LedgerCachePrefix(ledgerID uint) string {
+	return fmt.Sprintf("l%d:", ledgerID)
+}
+
+// RegisterCacheInvalidation 注册 GORM 回调：消费记录写入后清除对应用户的统计缓存，
+// 类别变更（如调整内部转账标记）、汇率变更或无法确定用户时清空全部统计缓存
+func RegisterCacheInvalidation(db *gorm.DB) error {
+	const name = "finance:invalidate_statistics_cache"
+	if err := db.Callback().Create().After("gorm:create").Register(name, invalidateStatisticsCache); err != nil {
+		return err
+	}
+	if err := db.Callback().Update().After("gorm:update").Register(name, invalidateStatisticsCache); err != nil {
+		return err
+	}
+	return db.Callback().Delete().After("gorm:delete").Register(name, invalidateStatisticsCache)
+}
+
+func invalidateStatisticsCache(db *gorm.DB) {
+	if db.Error != nil || db.Statement == nil {
+		return
+	}
+	switch db.Statement.Table {
+	case "expenses":
+		userIDs, ok := expenseUserIDs(db.Statement.Model)
+		if !ok {
+			userIDs, ok = expenseUserIDs(db.Statement.Dest)
+		}
+		if !ok {
+			ExpenseStatisticsCache.InvalidateAll()
+			return
+		}
+		for _, id := range userIDs {
+			ExpenseStatisticsCache.InvalidatePrefix(UserCachePrefix(id))
+		}
+		// 共享账本的统计包含全体成员的记录，任一成员写入都要失效
+		for _, id := range expenseLedgerIDs(db.Statement.Model, db.Statement.Dest) {
+			ExpenseStatisticsCache.InvalidatePrefix(LedgerCachePrefix(id))
+		}
+	case "expense_categories", "exchange_rates":
+		// 类别标记或汇率变化影响所有用户的统计
+		ExpenseStatisticsCache.InvalidateAll()
+	}
+}
+
+// expenseUserIDs 从写入的模型中取出涉及的用户；按条件批量更新/删除时模型不带用户，返回 false
+func expenseUserIDs(v interface{}) ([]uint, bool) {
+	var ids []uint
+	switch e := v.(type) {
+	case *models.Expense:
+		ids = []uint{e.UserID}
+	case []models.Expense:
+		for _, x := range e {
+			ids = append(ids, x.UserID)
+		}
+	case *[]models.Expense:
+		for _, x := range *e {
+			ids = append(ids, x.UserID)
+		}
+	default:
+		return nil, false
+	}
+	if len(ids) == 0 {
+		return nil, false
+	}
+	for _, id := range ids {
+		if id == 0 {
+			return nil, false
+		}
+	}
+	return ids, true
+}
+
+// expenseLedgerIDs 从写入的模型中取出涉及的共享账本
+func expenseLedgerIDs(values ...interface{}) []uint {
+	var ids []uint
+	add := func(e models.Expense) {
+		if e.LedgerID != nil {
+			ids = append(ids, *e.LedgerID)
+		}
+	}
+	for _, v := range values {
+		switch e := v.(type) {
+		case *models.Expense:
+			add(*e)
+		case []models.Expense:
+			for _, x := range e {
+				add(x)
+			}
+		case *[]models.Expense:
+			for _, x := range *e {
+				add(x)
+			}
+		}
+	}
+	return ids
+}
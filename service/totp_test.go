@@ -0,0 +1,58 @@
+package service
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rfc6238Secret RFC 6238 附录 B 的 SHA1 测试密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestVerifyTOTP_RFCVector(t *testing.T) {
+	// T=59s 时 8 位验证码为 94287082，取后 6 位
+	step, ok := VerifyTOTP(rfc6238Secret, "287082", time.Unix(59, 0), 0)
+	require.True(t, ok)
+	assert.EqualValues(t, 1, step)
+
+	_, ok = VerifyTOTP(rfc6238Secret, "287083", time.Unix(59, 0), 0)
+	assert.False(t, ok)
+	_, ok = VerifyTOTP(rfc6238Secret, "28708", time.Unix(59, 0), 0)
+	assert.False(t, ok)
+}
+
+func TestVerifyTOTP_SkewAndReplay(t *testing.T) {
+	// 下一个周期内仍接受上一周期的验证码
+	step, ok := VerifyTOTP(rfc6238Secret, "287082", time.Unix(75, 0), 0)
+	require.True(t, ok)
+	assert.EqualValues(t, 1, step)
+
+	// 已使用过的周期不再接受
+	_, ok = VerifyTOTP(rfc6238Secret, "287082", time.Unix(59, 0), 1)
+	assert.False(t, ok)
+
+	// 超出允许偏差
+	_, ok = VerifyTOTP(rfc6238Secret, "287082", time.Unix(120, 0), 0)
+	assert.False(t, ok)
+}
+
+func TestGenerateTOTPSecret_URI(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	require.NoError(t, err)
+	assert.Len(t, secret, 32)
+
+	uri := TOTPURI("admin", secret)
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/"))
+	assert.Contains(t, uri, "secret="+secret)
+	assert.Contains(t, uri, "digits=6")
+
+	// 生成的密钥能校验自己算出的验证码
+	key, err := totpEncoding.DecodeString(secret)
+	require.NoError(t, err)
+	now := time.Now()
+	_, ok := VerifyTOTP(secret, totpCode(key, now.Unix()/totpPeriod), now, 0)
+	assert.True(t, ok)
+}
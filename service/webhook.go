@@ -0,0 +1,152 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"finance/database"
+	"finance/models"
+)
+
+// Webhook 推送的请求头
+const (
+	WebhookSignatureHeader = "X-Finance-Signature" // "sha256=" + 请求体的 HMAC-SHA256 十六进制值，未配置密钥时不带
+	WebhookEventHeader     = "X-Finance-Event"     // 事件类型
+	WebhookDeliveryHeader  = "X-Finance-Delivery"  // 事件ID，重试时不变，接收方可据此去重
+)
+
+// WebhookPayload Webhook 推送的 JSON 请求体
+type WebhookPayload struct {
+	ID        string      `json:"id"`             // 事件ID
+	Event     string      `json:"event"`          // 事件类型，见 models.WebhookEvent* 常量
+	Test      bool        `json:"test,omitempty"` // 后台测试发送的样例事件
+	Text      string      `json:"text"`           // 可读摘要，Slack 等 incoming webhook 可直接展示
+	CreatedAt int64       `json:"created_at"`     // 事件时间（Unix 秒）
+	Data      interface{} `json:"data"`
+}
+
+// NewWebhookPayload 生成带随机事件ID的请求体
+func NewWebhookPayload(event, text string, data interface{}) WebhookPayload {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	return WebhookPayload{
+		ID:        hex.EncodeToString(buf),
+		Event:     event,
+		Text:      text,
+		CreatedAt: time.Now().Unix(),
+		Data:      data,
+	}
+}
+
+// webhookRetryDelays 推送失败后依次等待的重试间隔（指数退避），全部用完仍失败则放弃
+var webhookRetryDelays = []time.Duration{2 * time.Second, 8 * time.Second, 32 * time.Second}
+
+var webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookStatusError 接收方返回了非 2xx 状态码
+type WebhookStatusError struct {
+	StatusCode int
+}
+
+func (e *WebhookStatusError) Error() string {
+	return fmt.Sprintf("Webhook 返回状态码 %d", e.StatusCode)
+}
+
+// webhookRetryable 网络错误、429 与 5xx 值得重试；其它 4xx 说明请求被拒绝，重试也不会成功
+func webhookRetryable(err error) bool {
+	var se *WebhookStatusError
+	if errors.As(err, &se) {
+		return se.StatusCode == http.StatusTooManyRequests || se.StatusCode >= 500
+	}
+	return !errors.Is(err, ErrSecretDecrypt)
+}
+
+// SignWebhookPayload 计算签名请求头的值
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// SendWebhook 推送一次，接收方返回 2xx 视为成功
+func SendWebhook(hook *models.Webhook, payload WebhookPayload) error {
+	secret, err := DecryptSecret(hook.Secret)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, payload.Event)
+	req.Header.Set(WebhookDeliveryHeader, payload.ID)
+	if secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(secret, body))
+	}
+
+	resp, err := webhookHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &WebhookStatusError{StatusCode: resp.StatusCode}
+	}
+	return nil
+}
+
+// DeliverWebhook 推送并在可重试的失败后按 webhookRetryDelays 退避重试，返回最后一次的错误
+func DeliverWebhook(hook *models.Webhook, payload WebhookPayload) error {
+	err := SendWebhook(hook, payload)
+	for _, delay := range webhookRetryDelays {
+		if err == nil || !webhookRetryable(err) {
+			break
+		}
+		time.Sleep(delay)
+		err = SendWebhook(hook, payload)
+	}
+	return err
+}
+
+// DispatchWebhooks 在后台并发推送给各 Webhook（各自重试），失败只打印日志
+func DispatchWebhooks(hooks []models.Webhook, payload WebhookPayload) {
+	for i := range hooks {
+		hook := hooks[i]
+		go func() {
+			if err := DeliverWebhook(&hook, payload); err != nil {
+				log.Printf("Webhook 推送失败（%d %s，事件 %s）: %v", hook.ID, hook.Name, payload.Event, err)
+			}
+		}()
+	}
+}
+
+// ActiveWebhooks 查询启用中且订阅了该事件的 Webhook
+func ActiveWebhooks(event string) ([]models.Webhook, error) {
+	var list []models.Webhook
+	if err := database.DB.Where("active = ?", true).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	matched := list[:0]
+	for _, w := range list {
+		if w.Subscribes(event) {
+			matched = append(matched, w)
+		}
+	}
+	return matched, nil
+}
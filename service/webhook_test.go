@@ -0,0 +1,121 @@
+package service
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"finance/config"
+	"finance/models"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendWebhook_SignsBody(t *testing.T) {
+	config.GlobalConfig = &config.Config{Security: config.SecurityConfig{EncryptionKey: "k1"}}
+	defer func() { config.GlobalConfig = nil }()
+
+	var gotBody []byte
+	var gotHeader http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotBody, _ = io.ReadAll(r.Body)
+		gotHeader = r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	secret, err := EncryptSecret("s3cret")
+	require.NoError(t, err)
+	hook := &models.Webhook{URL: srv.URL, Secret: secret}
+	payload := NewWebhookPayload(models.WebhookEventLargeExpense, "大额消费", map[string]interface{}{"amount": 1200})
+
+	require.NoError(t, SendWebhook(hook, payload))
+
+	assert.Equal(t, SignWebhookPayload("s3cret", gotBody), gotHeader.Get(WebhookSignatureHeader))
+	assert.Equal(t, models.WebhookEventLargeExpense, gotHeader.Get(WebhookEventHeader))
+	assert.Equal(t, payload.ID, gotHeader.Get(WebhookDeliveryHeader))
+	var got WebhookPayload
+	require.NoError(t, json.Unmarshal(gotBody, &got))
+	assert.Equal(t, payload.ID, got.ID)
+	assert.Equal(t, "大额消费", got.Text)
+}
+
+func TestSendWebhook_NoSecretNoSignature(t *testing.T) {
+	var signature string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(WebhookSignatureHeader)
+	}))
+	defer srv.Close()
+
+	require.NoError(t, SendWebhook(&models.Webhook{URL: srv.URL}, NewWebhookPayload(models.WebhookEventLargeExpense, "", nil)))
+	assert.Empty(t, signature)
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '{"a":1}' | openssl dgst -sha256 -hmac key
+	assert.Equal(t, "sha256=88a67f24bbcdaed0e6c997404bb79a743baf44c6bab2f4c27328e3009d22e342", SignWebhookPayload("key", []byte(`{"a":1}`)))
+}
+
+func TestDeliverWebhook_RetriesServerErrors(t *testing.T) {
+	old := webhookRetryDelays
+	webhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond, time.Millisecond}
+	defer func() { webhookRetryDelays = old }()
+
+	var calls int32
+	var deliveries []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveries = append(deliveries, r.Header.Get(WebhookDeliveryHeader))
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	require.NoError(t, DeliverWebhook(&models.Webhook{URL: srv.URL}, NewWebhookPayload(models.WebhookEventLargeExpense, "", nil)))
+	assert.EqualValues(t, 3, calls)
+	// 重试沿用同一个事件ID
+	assert.Equal(t, deliveries[0], deliveries[2])
+}
+
+func TestDeliverWebhook_NoRetryOnClientError(t *testing.T) {
+	old := webhookRetryDelays
+	webhookRetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer func() { webhookRetryDelays = old }()
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	err := DeliverWebhook(&models.Webhook{URL: srv.URL}, NewWebhookPayload(models.WebhookEventLargeExpense, "", nil))
+	var se *WebhookStatusError
+	require.ErrorAs(t, err, &se)
+	assert.Equal(t, http.StatusNotFound, se.StatusCode)
+	assert.EqualValues(t, 1, calls)
+}
+
+func TestActiveWebhooks_FiltersByEvent(t *testing.T) {
+	mock, cleanup := setupMockDB(t)
+	defer cleanup()
+
+	mock.ExpectQuery("SELECT \\* FROM `webhooks` WHERE active = \\?").
+		WithArgs(true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "url", "events", "active"}).
+			AddRow(1, "a", "https://a.example", "expense.large", true).
+			AddRow(2, "b", "https://b.example", "other.event", true).
+			AddRow(3, "c", "https://c.example", " other.event , expense.large", true))
+
+	hooks, err := ActiveWebhooks(models.WebhookEventLargeExpense)
+	require.NoError(t, err)
+	require.Len(t, hooks, 2)
+	assert.Equal(t, uint(1), hooks[0].ID)
+	assert.Equal(t, uint(3), hooks[1].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}